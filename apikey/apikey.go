@@ -0,0 +1,101 @@
+package apikey
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/ory/x/randx"
+)
+
+// prefix is the first part of every API key. It makes leaked keys easy to detect for secret scanners.
+const prefix = "kratos"
+
+// API Key
+//
+// swagger:model apiKey
+type Key struct {
+	// ID is the public part of the API key. It is also the identifier of the `api_key` credentials.
+	//
+	// required: true
+	ID string `json:"id"`
+
+	// Name is a human readable name of the key.
+	//
+	// required: true
+	Name string `json:"name"`
+
+	// Scopes are returned when the key is verified. ORY Kratos does not interpret them.
+	//
+	// required: true
+	Scopes []string `json:"scopes"`
+
+	// required: true
+	CreatedAt time.Time `json:"created_at"`
+
+	// LastUsedAt is the time the key was verified successfully last.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	// RevokedAt is the time the key was revoked. Revoked keys do not verify.
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CredentialsConfig is the configuration of the `api_key` credentials.
+//
+// swagger:ignore
+type CredentialsConfig struct {
+	Keys []CredentialsKey `json:"keys"`
+}
+
+// CredentialsKey is a key as stored in the credentials. The secret is only stored as a SHA-256 hash because it is
+// random and long enough to not be guessable.
+//
+// swagger:ignore
+type CredentialsKey struct {
+	Key
+	HashedSecret string `json:"hashed_secret"`
+}
+
+// IsRevoked returns true if the key was revoked.
+func (k *Key) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// newKey generates a key and returns it along with the API key which is shown to the caller once.
+func newKey(name string, scopes []string, now time.Time) (*CredentialsKey, string) {
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	id, secret := randx.MustString(16, randx.AlphaNum), randx.MustString(32, randx.AlphaNum)
+	return &CredentialsKey{
+		Key: Key{
+			ID:        id,
+			Name:      name,
+			Scopes:    scopes,
+			CreatedAt: now.Round(time.Second),
+		},
+		HashedSecret: hashSecret(secret),
+	}, strings.Join([]string{prefix, id, secret}, "_")
+}
+
+// parse returns the ID and the secret of an API key.
+func parse(apiKey string) (id, secret string, ok bool) {
+	parts := strings.Split(apiKey, "_")
+	if len(parts) != 3 || parts[0] != prefix || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+func hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// compare returns true if the secret matches the hashed secret of the key.
+func (k *CredentialsKey) compare(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(k.HashedSecret), []byte(hashSecret(secret))) == 1
+}
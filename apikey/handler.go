@@ -0,0 +1,274 @@
+package apikey
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteCollection = identity.RouteBase + "/:id/api-keys"
+	RouteKey        = RouteCollection + "/:key_id"
+	RouteVerify     = "/api-keys/verify"
+)
+
+type (
+	handlerDependencies interface {
+		x.WriterProvider
+		ManagementProvider
+	}
+	HandlerProvider interface {
+		APIKeyHandler() *Handler
+	}
+	Handler struct {
+		d handlerDependencies
+	}
+)
+
+func NewHandler(d handlerDependencies) *Handler {
+	return &Handler{d: d}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteCollection, h.list)
+	admin.POST(RouteCollection, h.create)
+	admin.DELETE(RouteKey, h.revoke)
+	admin.POST(RouteVerify, h.verify)
+}
+
+// nolint:deadcode,unused
+// swagger:parameters listAPIKeys
+type listAPIKeysParameters struct {
+	// ID is the ID of the identity.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// A list of API keys.
+// swagger:model apiKeyList
+// nolint:deadcode,unused
+type apiKeyList []Key
+
+// swagger:route GET /identities/{id}/api-keys admin listAPIKeys
+//
+// List the API Keys of an Identity
+//
+// Lists the API keys of an identity including revoked ones. The secrets are never returned.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: apiKeyList
+//       404: genericError
+//       500: genericError
+func (h *Handler) list(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	keys, err := h.d.APIKeyManager().List(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, keys)
+}
+
+// nolint:deadcode,unused
+// swagger:parameters createAPIKey
+type createAPIKeyParameters struct {
+	// ID is the ID of the identity.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// in: body
+	// required: true
+	Body createAPIKey
+}
+
+type createAPIKey struct {
+	// Name is a human readable name of the key.
+	//
+	// required: true
+	Name string `json:"name"`
+
+	// Scopes are returned when the key is verified. ORY Kratos does not interpret them.
+	Scopes []string `json:"scopes"`
+}
+
+// Created API Key
+//
+// swagger:model createdAPIKey
+type createdAPIKey struct {
+	Key
+
+	// APIKey is the API key including its secret. It is only returned once.
+	//
+	// required: true
+	APIKey string `json:"api_key"`
+}
+
+// swagger:route POST /identities/{id}/api-keys admin createAPIKey
+//
+// Create an API Key for an Identity
+//
+// Creates a long-lived API key for an identity, for example a service account. The response contains the API
+// key including its secret which can not be retrieved again.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       201: createdAPIKey
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) create(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var p createAPIKey
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+		return
+	}
+
+	if strings.TrimSpace(p.Name) == "" {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The name of the API key must be set.")))
+		return
+	}
+
+	key, apiKey, err := h.d.APIKeyManager().Create(r.Context(), x.ParseUUID(ps.ByName("id")), p.Name, p.Scopes)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().WriteCode(w, r, http.StatusCreated, &createdAPIKey{Key: *key, APIKey: apiKey})
+}
+
+// nolint:deadcode,unused
+// swagger:parameters revokeAPIKey
+type revokeAPIKeyParameters struct {
+	// ID is the ID of the identity.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// KeyID is the ID of the API key.
+	//
+	// required: true
+	// in: path
+	KeyID string `json:"key_id"`
+}
+
+// swagger:route DELETE /identities/{id}/api-keys/{key_id} admin revokeAPIKey
+//
+// Revoke an API Key
+//
+// Revokes an API key of an identity. Revoked keys are still listed but do not verify any more.
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       404: genericError
+//       500: genericError
+func (h *Handler) revoke(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.d.APIKeyManager().Revoke(r.Context(), x.ParseUUID(ps.ByName("id")), ps.ByName("key_id")); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:parameters verifyAPIKey
+// nolint:deadcode,unused
+type verifyAPIKeyParameters struct {
+	// in: body
+	// required: true
+	Body verifyAPIKey
+}
+
+type verifyAPIKey struct {
+	// The API Key
+	//
+	// required: true
+	APIKey string `json:"api_key"`
+}
+
+// API Key Verification
+//
+// swagger:model apiKeyVerification
+type apiKeyVerification struct {
+	// Active is true if the API key is valid. All other fields are omitted otherwise.
+	//
+	// required: true
+	Active bool `json:"active"`
+
+	// Identity is the identity the key belongs to.
+	Identity *identity.Identity `json:"identity,omitempty"`
+
+	// Key is the verified key.
+	Key *Key `json:"key,omitempty"`
+}
+
+// swagger:route POST /api-keys/verify admin verifyAPIKey
+//
+// Verify an API Key
+//
+// Verifies an API key and returns the key, including its scopes, and the identity it belongs to. Similar to
+// session introspection, malformed, unknown, and revoked keys, as well as keys of inactive, expired, or quarantined
+// identities, are not an error but result in `{"active": false}`.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: apiKeyVerification
+//       400: genericError
+//       500: genericError
+func (h *Handler) verify(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var p verifyAPIKey
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+		return
+	}
+
+	if p.APIKey == "" {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The API key must be set.")))
+		return
+	}
+
+	i, key, err := h.d.APIKeyManager().Verify(r.Context(), p.APIKey)
+	if errors.Is(err, ErrInvalidAPIKey) {
+		h.d.Writer().Write(w, r, &apiKeyVerification{Active: false})
+		return
+	} else if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, &apiKeyVerification{Active: true, Identity: i, Key: key})
+}
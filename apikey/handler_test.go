@@ -0,0 +1,154 @@
+package apikey_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/apikey"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/x"
+)
+
+func TestHandler(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+	_, adminTS := testhelpers.NewKratosServer(t, reg)
+
+	do := func(t *testing.T, method, path string, body interface{}, expectedStatus int) gjson.Result {
+		var b bytes.Buffer
+		require.NoError(t, json.NewEncoder(&b).Encode(body))
+		req, err := http.NewRequest(method, adminTS.URL+path, &b)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		res, err := adminTS.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		var out json.RawMessage
+		if res.StatusCode != http.StatusNoContent {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&out))
+		}
+		require.Equal(t, expectedStatus, res.StatusCode, "%s", out)
+		return gjson.ParseBytes(out)
+	}
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	i.Traits = identity.Traits(`{"name":"ci"}`)
+	require.NoError(t, reg.IdentityManager().Create(context.Background(), i))
+	keys := "/identities/" + i.ID.String() + "/api-keys"
+
+	t.Run("case=requires a name", func(t *testing.T) {
+		do(t, "POST", keys, map[string]interface{}{"scopes": []string{"read"}}, http.StatusBadRequest)
+	})
+
+	t.Run("case=identity does not exist", func(t *testing.T) {
+		do(t, "POST", "/identities/"+x.NewUUID().String()+"/api-keys", map[string]interface{}{"name": "ci"}, http.StatusNotFound)
+		do(t, "GET", "/identities/"+x.NewUUID().String()+"/api-keys", nil, http.StatusNotFound)
+	})
+
+	t.Run("case=lists no keys", func(t *testing.T) {
+		assert.Equal(t, "[]", do(t, "GET", keys, nil, http.StatusOK).Raw)
+	})
+
+	created := do(t, "POST", keys, map[string]interface{}{"name": "deploy", "scopes": []string{"deploy", "read"}}, http.StatusCreated)
+	apiKey := created.Get("api_key").String()
+	keyID := created.Get("id").String()
+
+	t.Run("case=returns the secret once", func(t *testing.T) {
+		assert.Contains(t, apiKey, keyID)
+		assert.Equal(t, "deploy", created.Get("name").String())
+
+		listed := do(t, "GET", keys, nil, http.StatusOK)
+		require.Len(t, listed.Array(), 1)
+		assert.Equal(t, keyID, listed.Get("0.id").String())
+		assert.False(t, listed.Get("0.api_key").Exists())
+		assert.False(t, listed.Get("0.hashed_secret").Exists())
+		assert.False(t, listed.Get("0.last_used_at").Exists())
+	})
+
+	t.Run("case=verifies the key", func(t *testing.T) {
+		do(t, "POST", apikey.RouteVerify, map[string]interface{}{}, http.StatusBadRequest)
+
+		res := do(t, "POST", apikey.RouteVerify, map[string]interface{}{"api_key": apiKey}, http.StatusOK)
+		assert.True(t, res.Get("active").Bool())
+		assert.Equal(t, i.ID.String(), res.Get("identity.id").String())
+		assert.Equal(t, "ci", res.Get("identity.traits.name").String())
+		assert.Equal(t, `["deploy","read"]`, res.Get("key.scopes").Raw)
+		assert.False(t, res.Get("identity.credentials").Exists())
+
+		assert.True(t, do(t, "GET", keys, nil, http.StatusOK).Get("0.last_used_at").Exists())
+	})
+
+	t.Run("case=does not verify invalid keys", func(t *testing.T) {
+		for _, invalid := range []string{
+			"not-a-key",
+			"kratos_" + keyID + "_wrongsecret",
+			"kratos_unknownkeyid_secret",
+			"other_" + apiKey[len("kratos_"):],
+		} {
+			res := do(t, "POST", apikey.RouteVerify, map[string]interface{}{"api_key": invalid}, http.StatusOK)
+			assert.Equal(t, `{"active":false}`, res.Raw, invalid)
+		}
+	})
+
+	t.Run("case=does not verify keys of identities which may not sign in", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		for name, restrict := range map[string]func(*identity.Identity){
+			"inactive":    func(i *identity.Identity) { i.State = identity.StateInactive },
+			"expired":     func(i *identity.Identity) { i.ExpiresAt = &past },
+			"quarantined": func(i *identity.Identity) { i.Quarantine("suspicious sign up") },
+		} {
+			t.Run("state="+name, func(t *testing.T) {
+				restricted := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+				restricted.Traits = identity.Traits(`{"name":"` + name + `"}`)
+				require.NoError(t, reg.IdentityManager().Create(context.Background(), restricted))
+				restrictedKeys := "/identities/" + restricted.ID.String() + "/api-keys"
+				key := do(t, "POST", restrictedKeys, map[string]interface{}{"name": name}, http.StatusCreated).Get("api_key").String()
+
+				restricted, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), restricted.ID)
+				require.NoError(t, err)
+				restrict(restricted)
+				require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentity(context.Background(), restricted))
+
+				res := do(t, "POST", apikey.RouteVerify, map[string]interface{}{"api_key": key}, http.StatusOK)
+				assert.Equal(t, `{"active":false}`, res.Raw)
+				assert.False(t, do(t, "GET", restrictedKeys, nil, http.StatusOK).Get("0.last_used_at").Exists())
+			})
+		}
+	})
+
+	t.Run("case=revokes the key", func(t *testing.T) {
+		other := do(t, "POST", keys, map[string]interface{}{"name": "backup"}, http.StatusCreated)
+
+		do(t, "DELETE", keys+"/"+keyID, nil, http.StatusNoContent)
+		do(t, "DELETE", keys+"/"+keyID, nil, http.StatusNoContent)
+		do(t, "DELETE", keys+"/unknown", nil, http.StatusNotFound)
+
+		res := do(t, "POST", apikey.RouteVerify, map[string]interface{}{"api_key": apiKey}, http.StatusOK)
+		assert.False(t, res.Get("active").Bool())
+
+		res = do(t, "POST", apikey.RouteVerify, map[string]interface{}{"api_key": other.Get("api_key").String()}, http.StatusOK)
+		assert.True(t, res.Get("active").Bool(), "revoking a key must not revoke the other keys of the identity")
+
+		listed := do(t, "GET", keys, nil, http.StatusOK)
+		require.Len(t, listed.Array(), 2)
+		assert.True(t, listed.Get("0.revoked_at").Exists())
+		assert.False(t, listed.Get("1.revoked_at").Exists())
+	})
+
+	t.Run("case=keeps the keys when the identity is updated", func(t *testing.T) {
+		do(t, "PUT", "/identities/"+i.ID.String(), map[string]interface{}{"traits": map[string]interface{}{"name": "ci-2"}}, http.StatusOK)
+		assert.Len(t, do(t, "GET", keys, nil, http.StatusOK).Array(), 2)
+	})
+}
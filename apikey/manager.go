@@ -0,0 +1,141 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+// ErrInvalidAPIKey is returned if an API key is malformed, unknown, revoked, its secret does not match, or its
+// identity may not sign in.
+var ErrInvalidAPIKey = herodot.ErrUnauthorized.WithReason("The API key is invalid or was revoked.")
+
+type (
+	managerDependencies interface {
+		x.LoggingProvider
+		identity.PrivilegedPoolProvider
+		PersistenceProvider
+		x.ClockProvider
+	}
+	ManagementProvider interface {
+		APIKeyManager() *Manager
+	}
+	Manager struct {
+		d managerDependencies
+	}
+)
+
+func NewManager(d managerDependencies) *Manager {
+	return &Manager{d: d}
+}
+
+// Create adds a key to the identity and returns it along with the API key. The API key can not be retrieved again.
+func (m *Manager) Create(ctx context.Context, identityID uuid.UUID, name string, scopes []string) (*Key, string, error) {
+	key, apiKey := newKey(name, scopes, m.d.Clock().Now())
+	if err := m.d.APIKeyPersister().UpdateAPIKeys(ctx, identityID, func(keys []CredentialsKey) ([]CredentialsKey, error) {
+		return append(keys, *key), nil
+	}); err != nil {
+		return nil, "", err
+	}
+	return &key.Key, apiKey, nil
+}
+
+// List returns the keys of the identity including revoked ones.
+func (m *Manager) List(ctx context.Context, identityID uuid.UUID) ([]Key, error) {
+	i, err := m.d.PrivilegedIdentityPool().GetIdentityConfidential(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]Key, 0)
+	creds, ok := i.GetCredentials(identity.CredentialsTypeAPIKey)
+	if !ok {
+		return keys, nil
+	}
+
+	var conf CredentialsConfig
+	if err := json.Unmarshal(creds.Config, &conf); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode the API key credentials: %s", err))
+	}
+
+	for _, k := range conf.Keys {
+		keys = append(keys, k.Key)
+	}
+	return keys, nil
+}
+
+// Revoke revokes a key of the identity. Revoking a revoked key is a no-op. Returns sqlcon.ErrNoRows if the
+// identity has no such key.
+func (m *Manager) Revoke(ctx context.Context, identityID uuid.UUID, keyID string) error {
+	return m.d.APIKeyPersister().UpdateAPIKeys(ctx, identityID, func(keys []CredentialsKey) ([]CredentialsKey, error) {
+		for k := range keys {
+			if keys[k].ID != keyID {
+				continue
+			}
+			if !keys[k].IsRevoked() {
+				now := m.d.Clock().Now().Round(time.Second)
+				keys[k].RevokedAt = &now
+			}
+			return keys, nil
+		}
+		return nil, errors.WithStack(sqlcon.ErrNoRows)
+	})
+}
+
+// Verify returns the identity and the key an API key belongs to and records that the key was used. Returns
+// ErrInvalidAPIKey if the API key does not verify or its identity is inactive, expired, or quarantined.
+func (m *Manager) Verify(ctx context.Context, apiKey string) (*identity.Identity, *Key, error) {
+	id, secret, ok := parse(apiKey)
+	if !ok {
+		return nil, nil, errors.WithStack(ErrInvalidAPIKey)
+	}
+
+	i, _, err := m.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(ctx, identity.CredentialsTypeAPIKey, id)
+	if errors.Is(err, herodot.ErrNotFound) {
+		return nil, nil, errors.WithStack(ErrInvalidAPIKey)
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	expired := i.IsExpired(m.d.Clock().Now())
+	if !i.IsActive() || expired || i.IsQuarantined() {
+		m.d.Audit().
+			WithField("identity_id", i.ID).
+			WithField("api_key_id", id).
+			WithField("identity_state", i.State).
+			WithField("identity_expired", expired).
+			WithField("identity_quarantined", i.IsQuarantined()).
+			Info("An API key of an identity which may not sign in was rejected.")
+		return nil, nil, errors.WithStack(ErrInvalidAPIKey)
+	}
+
+	var verified *Key
+	if err := m.d.APIKeyPersister().UpdateAPIKeys(ctx, i.ID, func(keys []CredentialsKey) ([]CredentialsKey, error) {
+		for k := range keys {
+			if keys[k].ID != id {
+				continue
+			}
+			if keys[k].IsRevoked() || !keys[k].compare(secret) {
+				return nil, errors.WithStack(ErrInvalidAPIKey)
+			}
+			now := m.d.Clock().Now().Round(time.Second)
+			keys[k].LastUsedAt = &now
+			verified = &keys[k].Key
+			return keys, nil
+		}
+		return nil, errors.WithStack(ErrInvalidAPIKey)
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return i, verified, nil
+}
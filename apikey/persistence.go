@@ -0,0 +1,101 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	Persister interface {
+		// UpdateAPIKeys passes the keys of the identity's `api_key` credentials to update and stores the keys it
+		// returns in a single transaction. The credentials are created if the identity has none. Returns
+		// sqlcon.ErrNoRows if the identity does not exist.
+		UpdateAPIKeys(ctx context.Context, identityID uuid.UUID, update func(keys []CredentialsKey) ([]CredentialsKey, error)) error
+	}
+
+	PersistenceProvider interface {
+		APIKeyPersister() Persister
+	}
+)
+
+func TestPersister(conf *config.Provider, p interface {
+	Persister
+	identity.PrivilegedPool
+}) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		require.NoError(t, p.CreateIdentity(ctx, i))
+
+		first, _ := newKey("first", []string{"read"}, time.Now().UTC())
+		second, _ := newKey("second", nil, time.Now().UTC())
+
+		t.Run("case=identity does not exist", func(t *testing.T) {
+			err := p.UpdateAPIKeys(ctx, x.NewUUID(), func(keys []CredentialsKey) ([]CredentialsKey, error) {
+				return append(keys, *first), nil
+			})
+			assert.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+		})
+
+		t.Run("case=creates the credentials", func(t *testing.T) {
+			require.NoError(t, p.UpdateAPIKeys(ctx, i.ID, func(keys []CredentialsKey) ([]CredentialsKey, error) {
+				assert.Empty(t, keys)
+				return append(keys, *first), nil
+			}))
+
+			_, creds, err := p.FindByCredentialsIdentifier(ctx, identity.CredentialsTypeAPIKey, first.ID)
+			require.NoError(t, err)
+			assert.Equal(t, []string{first.ID}, creds.Identifiers)
+		})
+
+		t.Run("case=updates the credentials", func(t *testing.T) {
+			now := time.Now().UTC().Round(time.Second)
+			require.NoError(t, p.UpdateAPIKeys(ctx, i.ID, func(keys []CredentialsKey) ([]CredentialsKey, error) {
+				require.Len(t, keys, 1)
+				keys[0].LastUsedAt = &now
+				return append(keys, *second), nil
+			}))
+
+			actual, creds, err := p.FindByCredentialsIdentifier(ctx, identity.CredentialsTypeAPIKey, second.ID)
+			require.NoError(t, err)
+			assert.Equal(t, i.ID, actual.ID)
+			assert.ElementsMatch(t, []string{first.ID, second.ID}, creds.Identifiers)
+
+			require.NoError(t, p.UpdateAPIKeys(ctx, i.ID, func(keys []CredentialsKey) ([]CredentialsKey, error) {
+				require.Len(t, keys, 2)
+				assert.Equal(t, first.HashedSecret, keys[0].HashedSecret)
+				assert.Equal(t, []string{"read"}, keys[0].Scopes)
+				require.NotNil(t, keys[0].LastUsedAt)
+				assert.EqualValues(t, now.Unix(), keys[0].LastUsedAt.Unix())
+				return keys, nil
+			}))
+		})
+
+		t.Run("case=does not store the keys if update fails", func(t *testing.T) {
+			expected := errors.New("expected")
+			err := p.UpdateAPIKeys(ctx, i.ID, func(keys []CredentialsKey) ([]CredentialsKey, error) {
+				return nil, expected
+			})
+			assert.True(t, errors.Is(err, expected), "%+v", err)
+
+			require.NoError(t, p.UpdateAPIKeys(ctx, i.ID, func(keys []CredentialsKey) ([]CredentialsKey, error) {
+				assert.Len(t, keys, 2)
+				return keys, nil
+			}))
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package breakglass
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Break-Glass Grant
+//
+// A break-glass grant allows administrative operations which bypass the protections of an identity for a limited
+// time. Grants are never deleted and serve as the audit record of the access.
+//
+// swagger:model breakGlassGrant
+type Grant struct {
+	// required: true
+	ID uuid.UUID `json:"id" db:"id"`
+
+	// IdentityID is the ID of the identity the grant allows access to.
+	//
+	// required: true
+	IdentityID uuid.UUID `json:"identity_id" db:"identity_id"`
+
+	// OpenedBy is the ID of the identity owning the API key which opened the grant.
+	//
+	// required: true
+	OpenedBy uuid.UUID `json:"opened_by" db:"opened_by"`
+
+	// APIKeyID is the ID of the API key which opened the grant. Only this key can use the grant.
+	//
+	// required: true
+	APIKeyID string `json:"api_key_id" db:"api_key_id"`
+
+	// Justification explains why the access is necessary, for example the reference of a support ticket.
+	//
+	// required: true
+	Justification string `json:"justification" db:"justification"`
+
+	// ExpiresAt is the time the grant expires at.
+	//
+	// required: true
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+
+	// ClosedAt is set if the grant was closed before it expired.
+	ClosedAt *time.Time `json:"closed_at,omitempty" db:"closed_at"`
+
+	// required: true
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+}
+
+func (g Grant) TableName() string {
+	return "break_glass_grants"
+}
+
+// IsOpen returns true if the grant was neither closed nor expired at the given time.
+func (g *Grant) IsOpen(now time.Time) bool {
+	return g.ClosedAt == nil && now.Before(g.ExpiresAt)
+}
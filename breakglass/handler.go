@@ -0,0 +1,249 @@
+package breakglass
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+const RouteCollection = "/break-glass-grants"
+
+type (
+	handlerDependencies interface {
+		x.WriterProvider
+		ManagementProvider
+		PersistenceProvider
+	}
+	HandlerProvider interface {
+		BreakGlassHandler() *Handler
+	}
+	Handler struct {
+		d handlerDependencies
+		c *config.Provider
+	}
+)
+
+func NewHandler(d handlerDependencies, c *config.Provider) *Handler {
+	return &Handler{d: d, c: c}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteCollection, h.list)
+	admin.POST(RouteCollection, h.open)
+	admin.GET(RouteCollection+"/:id", h.get)
+	admin.DELETE(RouteCollection+"/:id", h.close)
+}
+
+// nolint:deadcode,unused
+// swagger:parameters listBreakGlassGrants
+type listBreakGlassGrantsParameters struct {
+	// IdentityID only returns the grants for this identity.
+	//
+	// in: query
+	IdentityID string `json:"identity_id"`
+
+	// Items per Page
+	//
+	// This is the number of items per page.
+	//
+	// required: false
+	// in: query
+	// default: 100
+	// min: 1
+	// max: 500
+	PerPage int `json:"per_page"`
+
+	// Pagination Page
+	//
+	// required: false
+	// in: query
+	// default: 0
+	// min: 0
+	Page int `json:"page"`
+}
+
+// A list of break-glass grants.
+// swagger:model breakGlassGrantList
+// nolint:deadcode,unused
+type breakGlassGrantList []Grant
+
+// swagger:route GET /break-glass-grants admin listBreakGlassGrants
+//
+// List Break-Glass Grants
+//
+// Lists all break-glass grants, newest first, including closed and expired ones. Grants are never deleted and
+// serve as the audit record of break-glass access.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: breakGlassGrantList
+//       400: genericError
+//       500: genericError
+func (h *Handler) list(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var identityID uuid.UUID
+	if raw := r.URL.Query().Get("identity_id"); raw != "" {
+		var err error
+		if identityID, err = uuid.FromString(raw); err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The identity ID is invalid: %s", err)))
+			return
+		}
+	}
+
+	page, itemsPerPage := x.ParsePagination(r)
+	gs, total, err := h.d.BreakGlassPersister().ListBreakGlassGrants(r.Context(), identityID, page, itemsPerPage)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	x.PaginationHeader(w, urlx.CopyWithQuery(urlx.AppendPaths(h.c.SelfAdminURL(), RouteCollection), r.URL.Query()), total, page, itemsPerPage)
+	h.d.Writer().Write(w, r, gs)
+}
+
+// nolint:deadcode,unused
+// swagger:parameters openBreakGlassGrant
+type openBreakGlassGrantParameters struct {
+	// The API key having the break-glass scope, sent as `Bearer <api_key>`.
+	//
+	// required: true
+	// in: header
+	Authorization string `json:"Authorization"`
+
+	// in: body
+	// required: true
+	Body OpenBreakGlassGrant
+}
+
+type OpenBreakGlassGrant struct {
+	// IdentityID is the ID of the identity to open the grant for.
+	//
+	// required: true
+	IdentityID uuid.UUID `json:"identity_id"`
+
+	// Justification explains why the access is necessary, for example the reference of a support ticket.
+	//
+	// required: true
+	Justification string `json:"justification"`
+
+	// Duration defines how long the grant is open, for example `15m`. It defaults to and must not exceed
+	// `break_glass.max_duration`.
+	Duration string `json:"duration"`
+}
+
+// swagger:route POST /break-glass-grants admin openBreakGlassGrant
+//
+// Open a Break-Glass Grant
+//
+// Opens a time-limited grant which allows deleting the credentials of an identity, for example to reset its second
+// factor, and releasing it from quarantine. The request must be authenticated with an API key having the scope
+// configured in `break_glass.scope`. To use the grant, send its ID in the `X-Break-Glass-Grant` HTTP Header
+// together with the same API key. Opening, using, and closing grants is audited and sent to
+// `break_glass.notification_url`.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       201: breakGlassGrant
+//       400: genericError
+//       401: genericError
+//       403: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) open(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var p OpenBreakGlassGrant
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+		return
+	}
+
+	var duration time.Duration
+	if p.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(p.Duration); err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The duration is invalid: %s", err)))
+			return
+		}
+	}
+
+	g, err := h.d.BreakGlassManager().Open(r, p.IdentityID, p.Justification, duration)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().WriteCreated(w, r, urlx.AppendPaths(h.c.SelfAdminURL(), RouteCollection, g.ID.String()).String(), g)
+}
+
+// nolint:deadcode,unused
+// swagger:parameters getBreakGlassGrant closeBreakGlassGrant
+type breakGlassGrantParameters struct {
+	// ID is the ID of the grant.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route GET /break-glass-grants/{id} admin getBreakGlassGrant
+//
+// Get a Break-Glass Grant
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: breakGlassGrant
+//       404: genericError
+//       500: genericError
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	g, err := h.d.BreakGlassPersister().GetBreakGlassGrant(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, g)
+}
+
+// swagger:route DELETE /break-glass-grants/{id} admin closeBreakGlassGrant
+//
+// Close a Break-Glass Grant
+//
+// Closes a grant before it expires. The grant is kept as the audit record. Closing a closed grant is a no-op.
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       404: genericError
+//       500: genericError
+func (h *Handler) close(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if _, err := h.d.BreakGlassManager().Close(r, x.ParseUUID(ps.ByName("id"))); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
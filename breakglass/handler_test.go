@@ -0,0 +1,223 @@
+package breakglass_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/breakglass"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/x"
+)
+
+func TestHandler(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+	conf.MustSet(config.ViperKeyBreakGlassEnabled, true)
+	conf.MustSet(config.ViperKeyBreakGlassMaxDuration, "1h")
+
+	var lock sync.Mutex
+	var events []breakglass.Event
+	notificationTS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e breakglass.Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		lock.Lock()
+		events = append(events, e)
+		lock.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(notificationTS.Close)
+	conf.MustSet(config.ViperKeyBreakGlassNotificationURL, notificationTS.URL)
+
+	lastEvent := func(t *testing.T) breakglass.Event {
+		lock.Lock()
+		defer lock.Unlock()
+		require.NotEmpty(t, events)
+		return events[len(events)-1]
+	}
+
+	_, adminTS := testhelpers.NewKratosServer(t, reg)
+
+	do := func(t *testing.T, method, path, apiKey, grantID string, body interface{}, expectedStatus int) gjson.Result {
+		var b bytes.Buffer
+		require.NoError(t, json.NewEncoder(&b).Encode(body))
+		req, err := http.NewRequest(method, adminTS.URL+path, &b)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		if grantID != "" {
+			req.Header.Set(breakglass.HeaderGrant, grantID)
+		}
+		res, err := adminTS.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		var out json.RawMessage
+		if res.StatusCode != http.StatusNoContent {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&out))
+		}
+		require.Equal(t, expectedStatus, res.StatusCode, "%s", out)
+		return gjson.ParseBytes(out)
+	}
+
+	operator := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	operator.Traits = identity.Traits(`{"name":"security operator"}`)
+	require.NoError(t, reg.IdentityManager().Create(context.Background(), operator))
+	_, apiKey, err := reg.APIKeyManager().Create(context.Background(), operator.ID, "break-glass", []string{"kratos:break_glass"})
+	require.NoError(t, err)
+	_, otherAPIKey, err := reg.APIKeyManager().Create(context.Background(), operator.ID, "break-glass-2", []string{"kratos:break_glass"})
+	require.NoError(t, err)
+	_, unscopedAPIKey, err := reg.APIKeyManager().Create(context.Background(), operator.ID, "deploy", []string{"deploy"})
+	require.NoError(t, err)
+
+	newLockedIdentity := func(t *testing.T) *identity.Identity {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(`{"name":"locked"}`)
+		i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+			Type:        identity.CredentialsTypePassword,
+			Identifiers: []string{x.NewUUID().String()},
+			Config:      []byte(`{}`),
+		})
+		i.Quarantine("registration_velocity")
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+		return i
+	}
+
+	open := func(t *testing.T, apiKey string, body map[string]interface{}, expectedStatus int) gjson.Result {
+		return do(t, "POST", breakglass.RouteCollection, apiKey, "", body, expectedStatus)
+	}
+
+	i := newLockedIdentity(t)
+	deleteCredentials := "/identities/" + i.ID.String() + "/credentials/password"
+	releaseQuarantine := "/identity-quarantine/" + i.ID.String()
+
+	t.Run("case=operations require a grant", func(t *testing.T) {
+		do(t, "DELETE", deleteCredentials, "", "", nil, http.StatusForbidden)
+		do(t, "DELETE", releaseQuarantine, apiKey, x.NewUUID().String(), nil, http.StatusForbidden)
+		do(t, "DELETE", releaseQuarantine, apiKey, "not-a-grant", nil, http.StatusForbidden)
+	})
+
+	t.Run("case=opening a grant requires a scoped api key", func(t *testing.T) {
+		body := map[string]interface{}{"identity_id": i.ID, "justification": "Support ticket #1234"}
+		open(t, "", body, http.StatusUnauthorized)
+		open(t, "kratos_unknown_secret", body, http.StatusUnauthorized)
+		open(t, unscopedAPIKey, body, http.StatusForbidden)
+	})
+
+	t.Run("case=opening a grant requires an active operator", func(t *testing.T) {
+		deactivated := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		deactivated.Traits = identity.Traits(`{"name":"former operator"}`)
+		require.NoError(t, reg.IdentityManager().Create(context.Background(), deactivated))
+		_, deactivatedAPIKey, err := reg.APIKeyManager().Create(context.Background(), deactivated.ID, "break-glass", []string{"kratos:break_glass"})
+		require.NoError(t, err)
+
+		deactivated, err = reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), deactivated.ID)
+		require.NoError(t, err)
+		deactivated.State = identity.StateInactive
+		require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentity(context.Background(), deactivated))
+
+		open(t, deactivatedAPIKey, map[string]interface{}{"identity_id": i.ID, "justification": "Support ticket #1234"}, http.StatusUnauthorized)
+	})
+
+	t.Run("case=opening a grant requires a justification and a valid duration", func(t *testing.T) {
+		open(t, apiKey, map[string]interface{}{"identity_id": i.ID, "justification": "  "}, http.StatusBadRequest)
+		open(t, apiKey, map[string]interface{}{"identity_id": i.ID, "justification": "Support ticket #1234", "duration": "2h"}, http.StatusBadRequest)
+		open(t, apiKey, map[string]interface{}{"identity_id": i.ID, "justification": "Support ticket #1234", "duration": "forever"}, http.StatusBadRequest)
+		open(t, apiKey, map[string]interface{}{"identity_id": x.NewUUID(), "justification": "Support ticket #1234"}, http.StatusNotFound)
+	})
+
+	grant := open(t, apiKey, map[string]interface{}{"identity_id": i.ID, "justification": " Support ticket #1234 ", "duration": "15m"}, http.StatusCreated)
+	grantID := grant.Get("id").String()
+
+	t.Run("case=opened a grant", func(t *testing.T) {
+		assert.Equal(t, i.ID.String(), grant.Get("identity_id").String())
+		assert.Equal(t, operator.ID.String(), grant.Get("opened_by").String())
+		assert.Equal(t, "Support ticket #1234", grant.Get("justification").String())
+		assert.False(t, grant.Get("closed_at").Exists())
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), grant.Get("expires_at").Time(), 5*time.Second)
+
+		e := lastEvent(t)
+		assert.Equal(t, breakglass.EventGrantOpened, e.Type)
+		assert.Equal(t, grantID, e.Grant.ID.String())
+	})
+
+	t.Run("case=grants are bound to the identity and the api key", func(t *testing.T) {
+		other := newLockedIdentity(t)
+		do(t, "DELETE", "/identity-quarantine/"+other.ID.String(), apiKey, grantID, nil, http.StatusForbidden)
+		do(t, "DELETE", releaseQuarantine, otherAPIKey, grantID, nil, http.StatusForbidden)
+		do(t, "DELETE", releaseQuarantine, "", grantID, nil, http.StatusUnauthorized)
+	})
+
+	t.Run("case=uses the grant", func(t *testing.T) {
+		do(t, "DELETE", deleteCredentials, apiKey, grantID, nil, http.StatusNoContent)
+		e := lastEvent(t)
+		assert.Equal(t, breakglass.EventGrantUsed, e.Type)
+		assert.Equal(t, identity.BreakGlassOperationDeleteCredentials, e.Operation)
+
+		do(t, "DELETE", releaseQuarantine, apiKey, grantID, nil, http.StatusNoContent)
+		assert.Equal(t, identity.BreakGlassOperationReleaseQuarantine, lastEvent(t).Operation)
+
+		actual, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), i.ID)
+		require.NoError(t, err)
+		assert.False(t, actual.IsQuarantined())
+		_, ok := actual.GetCredentials(identity.CredentialsTypePassword)
+		assert.False(t, ok)
+	})
+
+	t.Run("case=lists and gets grants", func(t *testing.T) {
+		listed := do(t, "GET", breakglass.RouteCollection+"?identity_id="+i.ID.String(), "", "", nil, http.StatusOK)
+		require.Len(t, listed.Array(), 1, "%s", listed.Raw)
+		assert.Equal(t, grantID, listed.Get("0.id").String())
+
+		do(t, "GET", breakglass.RouteCollection+"?identity_id=foo", "", "", nil, http.StatusBadRequest)
+		assert.Equal(t, grantID, do(t, "GET", breakglass.RouteCollection+"/"+grantID, "", "", nil, http.StatusOK).Get("id").String())
+		do(t, "GET", breakglass.RouteCollection+"/"+x.NewUUID().String(), "", "", nil, http.StatusNotFound)
+	})
+
+	t.Run("case=closed grants can not be used", func(t *testing.T) {
+		do(t, "DELETE", breakglass.RouteCollection+"/"+grantID, "", "", nil, http.StatusNoContent)
+		assert.Equal(t, breakglass.EventGrantClosed, lastEvent(t).Type)
+		assert.True(t, do(t, "GET", breakglass.RouteCollection+"/"+grantID, "", "", nil, http.StatusOK).Get("closed_at").Exists())
+
+		do(t, "DELETE", deleteCredentials, apiKey, grantID, nil, http.StatusForbidden)
+		do(t, "DELETE", breakglass.RouteCollection+"/"+x.NewUUID().String(), "", "", nil, http.StatusNotFound)
+	})
+
+	t.Run("case=expired grants can not be used", func(t *testing.T) {
+		other := newLockedIdentity(t)
+		g := open(t, apiKey, map[string]interface{}{"identity_id": other.ID, "justification": "Support ticket #1234", "duration": "1ns"}, http.StatusCreated)
+		do(t, "DELETE", "/identity-quarantine/"+other.ID.String(), apiKey, g.Get("id").String(), nil, http.StatusForbidden)
+
+		clock := new(x.TestClock)
+		reg.WithClock(clock)
+		t.Cleanup(func() { reg.WithClock(x.SystemClock{}) })
+
+		g = open(t, apiKey, map[string]interface{}{"identity_id": other.ID, "justification": "Support ticket #1234", "duration": "30m"}, http.StatusCreated)
+		clock.Advance(31 * time.Minute)
+		do(t, "DELETE", "/identity-quarantine/"+other.ID.String(), apiKey, g.Get("id").String(), nil, http.StatusForbidden)
+	})
+
+	t.Run("case=operations do not require a grant if break-glass access is disabled", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyBreakGlassEnabled, false)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyBreakGlassEnabled, true)
+		})
+
+		other := newLockedIdentity(t)
+		do(t, "DELETE", "/identity-quarantine/"+other.ID.String(), "", "", nil, http.StatusNoContent)
+	})
+}
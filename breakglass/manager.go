@@ -0,0 +1,191 @@
+package breakglass
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/httpx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/stringslice"
+
+	"github.com/ory/kratos/apikey"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+// HeaderGrant is the HTTP header which references the break-glass grant used by a request.
+const HeaderGrant = "X-Break-Glass-Grant"
+
+var (
+	// ErrGrantRequired is returned if an operation requires a break-glass grant but the request did not use an open
+	// grant for the identity.
+	ErrGrantRequired = herodot.ErrForbidden.
+				WithError("break-glass grant required").
+				WithReasonf("This operation requires an open break-glass grant for the identity. Open one with an API key having the break-glass scope and send its ID in the %s HTTP Header together with the same API key.", HeaderGrant)
+
+	// ErrScopeMissing is returned if the API key used to open or use a grant does not have the break-glass scope.
+	ErrScopeMissing = herodot.ErrForbidden.
+			WithError("break-glass scope missing").
+			WithReason("The API key does not have the break-glass scope.")
+)
+
+var _ identity.BreakGlassGuard = new(Manager)
+
+type (
+	managerDependencies interface {
+		x.LoggingProvider
+		identity.PrivilegedPoolProvider
+		apikey.ManagementProvider
+		PersistenceProvider
+		x.ClockProvider
+		Configuration() *config.Provider
+	}
+	ManagementProvider interface {
+		BreakGlassManager() *Manager
+	}
+	Manager struct {
+		d      managerDependencies
+		client *http.Client
+	}
+)
+
+func NewManager(d managerDependencies) *Manager {
+	return &Manager{d: d, client: httpx.NewResilientClientLatencyToleranceMedium(nil)}
+}
+
+// Open opens a grant for the identity using the API key from the request's `Authorization: Bearer` HTTP Header. If
+// the duration is zero, the grant is opened for the maximum duration.
+func (m *Manager) Open(r *http.Request, identityID uuid.UUID, justification string, duration time.Duration) (*Grant, error) {
+	c := m.d.Configuration().BreakGlass()
+	if duration == 0 {
+		duration = c.MaxDuration
+	} else if duration < 0 || duration > c.MaxDuration {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The duration of a break-glass grant must be positive and must not exceed %s.", c.MaxDuration))
+	}
+
+	justification = strings.TrimSpace(justification)
+	if justification == "" {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The justification of a break-glass grant must be set."))
+	}
+
+	owner, key, err := m.verify(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := m.d.PrivilegedIdentityPool().GetIdentity(r.Context(), identityID); err != nil {
+		return nil, err
+	}
+
+	now := m.d.Clock().Now().Truncate(time.Second)
+	g := &Grant{
+		ID:            x.NewUUID(),
+		IdentityID:    identityID,
+		OpenedBy:      owner.ID,
+		APIKeyID:      key.ID,
+		Justification: justification,
+		ExpiresAt:     now.Add(duration),
+		CreatedAt:     now,
+	}
+	if err := m.d.BreakGlassPersister().CreateBreakGlassGrant(r.Context(), g); err != nil {
+		return nil, err
+	}
+
+	m.d.Audit().
+		WithRequest(r).
+		WithField("break_glass_grant_id", g.ID).
+		WithField("identity_id", g.IdentityID).
+		WithField("opened_by", g.OpenedBy).
+		WithField("api_key_id", g.APIKeyID).
+		WithField("justification", g.Justification).
+		WithField("expires_at", g.ExpiresAt).
+		Info("A break-glass grant was opened.")
+	m.notify(r, EventGrantOpened, g, "")
+	return g, nil
+}
+
+// Close closes a grant before it expires. Closing a closed grant is a no-op.
+func (m *Manager) Close(r *http.Request, id uuid.UUID) (*Grant, error) {
+	if err := m.d.BreakGlassPersister().CloseBreakGlassGrant(r.Context(), id, m.d.Clock().Now().Truncate(time.Second)); err != nil {
+		return nil, err
+	}
+
+	g, err := m.d.BreakGlassPersister().GetBreakGlassGrant(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	m.d.Audit().
+		WithRequest(r).
+		WithField("break_glass_grant_id", g.ID).
+		WithField("identity_id", g.IdentityID).
+		Info("A break-glass grant was closed.")
+	m.notify(r, EventGrantClosed, g, "")
+	return g, nil
+}
+
+// AuthorizeBreakGlass allows the operation if break-glass access is disabled. Otherwise, the request must reference
+// an open grant for the identity in the X-Break-Glass-Grant HTTP Header and authenticate with the API key which
+// opened it. Every use of a grant is audited.
+func (m *Manager) AuthorizeBreakGlass(r *http.Request, identityID uuid.UUID, operation string) error {
+	if !m.d.Configuration().BreakGlassEnabled() {
+		return nil
+	}
+
+	grantID, err := uuid.FromString(r.Header.Get(HeaderGrant))
+	if err != nil {
+		return errors.WithStack(ErrGrantRequired)
+	}
+
+	g, err := m.d.BreakGlassPersister().GetBreakGlassGrant(r.Context(), grantID)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return errors.WithStack(ErrGrantRequired)
+	} else if err != nil {
+		return err
+	}
+
+	if g.IdentityID != identityID || !g.IsOpen(m.d.Clock().Now()) {
+		return errors.WithStack(ErrGrantRequired)
+	}
+
+	if _, key, err := m.verify(r); err != nil {
+		return err
+	} else if key.ID != g.APIKeyID {
+		return errors.WithStack(ErrGrantRequired)
+	}
+
+	m.d.Audit().
+		WithRequest(r).
+		WithField("break_glass_grant_id", g.ID).
+		WithField("identity_id", g.IdentityID).
+		WithField("operation", operation).
+		WithField("justification", g.Justification).
+		Info("A break-glass grant was used.")
+	m.notify(r, EventGrantUsed, g, operation)
+	return nil
+}
+
+// verify returns the API key from the request's `Authorization: Bearer` HTTP Header and the identity owning it if the
+// key has the break-glass scope.
+func (m *Manager) verify(r *http.Request) (*identity.Identity, *apikey.Key, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, nil, errors.WithStack(apikey.ErrInvalidAPIKey)
+	}
+
+	owner, key, err := m.d.APIKeyManager().Verify(r.Context(), strings.TrimPrefix(header, "Bearer "))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !stringslice.Has(key.Scopes, m.d.Configuration().BreakGlass().Scope) {
+		return nil, nil, errors.WithStack(ErrScopeMissing)
+	}
+	return owner, key, nil
+}
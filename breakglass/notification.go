@@ -0,0 +1,74 @@
+package breakglass
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	EventGrantOpened = "break_glass.grant.opened"
+	EventGrantUsed   = "break_glass.grant.used"
+	EventGrantClosed = "break_glass.grant.closed"
+)
+
+// Event is sent to the notification URL whenever a grant is opened, used, or closed.
+type Event struct {
+	// Type is one of `break_glass.grant.opened`, `break_glass.grant.used`, and `break_glass.grant.closed`.
+	Type string `json:"type"`
+
+	// Operation is the operation a grant was used for.
+	Operation string `json:"operation,omitempty"`
+
+	Grant *Grant `json:"grant"`
+
+	Time time.Time `json:"time"`
+}
+
+// notify sends the event to the notification URL. The event was audited already, which is why failures are logged
+// instead of failing the request.
+func (m *Manager) notify(r *http.Request, eventType string, g *Grant, operation string) {
+	if err := m.send(r, &Event{Type: eventType, Operation: operation, Grant: g, Time: m.d.Clock().Now()}); err != nil {
+		m.d.Logger().
+			WithRequest(r).
+			WithError(err).
+			WithField("break_glass_grant_id", g.ID).
+			WithField("event", eventType).
+			Error("Unable to send the break-glass notification.")
+	}
+}
+
+func (m *Manager) send(r *http.Request, e *Event) error {
+	u := m.d.Configuration().BreakGlass().NotificationURL
+	if u == nil {
+		return nil
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(e); err != nil {
+		return errors.WithStack(err)
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), "POST", u.String(), &body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := m.client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	payload, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	_ = res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return errors.Errorf("the notification URL responded with status code %d: %s", res.StatusCode, payload)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package breakglass
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/x"
+)
+
+type (
+	Persister interface {
+		// CreateBreakGlassGrant persists a grant.
+		CreateBreakGlassGrant(ctx context.Context, g *Grant) error
+
+		// GetBreakGlassGrant returns a grant by its ID or sqlcon.ErrNoRows.
+		GetBreakGlassGrant(ctx context.Context, id uuid.UUID) (*Grant, error)
+
+		// ListBreakGlassGrants returns the grants, newest first, and their total number. If the identity ID is not
+		// nil, only the grants for that identity are returned.
+		ListBreakGlassGrants(ctx context.Context, identityID uuid.UUID, page, itemsPerPage int) ([]Grant, int64, error)
+
+		// CloseBreakGlassGrant closes a grant at the given time. Closing a closed grant is a no-op. Returns
+		// sqlcon.ErrNoRows if the grant does not exist.
+		CloseBreakGlassGrant(ctx context.Context, id uuid.UUID, closedAt time.Time) error
+	}
+
+	PersistenceProvider interface {
+		BreakGlassPersister() Persister
+	}
+)
+
+func TestPersister(p Persister) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+
+		newGrant := func(identityID uuid.UUID, createdAt time.Time) *Grant {
+			return &Grant{
+				ID:            x.NewUUID(),
+				IdentityID:    identityID,
+				OpenedBy:      x.NewUUID(),
+				APIKeyID:      "key-" + x.NewUUID().String(),
+				Justification: "Support ticket #1234",
+				ExpiresAt:     createdAt.Add(time.Hour),
+				CreatedAt:     createdAt,
+			}
+		}
+
+		t.Run("case=not found", func(t *testing.T) {
+			_, err := p.GetBreakGlassGrant(ctx, x.NewUUID())
+			require.EqualError(t, err, sqlcon.ErrNoRows.Error())
+			require.EqualError(t, p.CloseBreakGlassGrant(ctx, x.NewUUID(), time.Now()), sqlcon.ErrNoRows.Error())
+		})
+
+		t.Run("case=create, list, and close", func(t *testing.T) {
+			identityID := x.NewUUID()
+			now := time.Now().UTC().Round(time.Second)
+			older := newGrant(identityID, now.Add(-time.Minute))
+			newer := newGrant(identityID, now)
+			other := newGrant(x.NewUUID(), now)
+			for _, g := range []*Grant{older, newer, other} {
+				require.NoError(t, p.CreateBreakGlassGrant(ctx, g))
+			}
+
+			actual, err := p.GetBreakGlassGrant(ctx, older.ID)
+			require.NoError(t, err)
+			assert.Equal(t, older.IdentityID, actual.IdentityID)
+			assert.Equal(t, older.OpenedBy, actual.OpenedBy)
+			assert.Equal(t, older.APIKeyID, actual.APIKeyID)
+			assert.Equal(t, older.Justification, actual.Justification)
+			assert.True(t, older.ExpiresAt.Equal(actual.ExpiresAt))
+			assert.Nil(t, actual.ClosedAt)
+
+			found, total, err := p.ListBreakGlassGrants(ctx, identityID, 0, 10)
+			require.NoError(t, err)
+			assert.EqualValues(t, 2, total)
+			require.Len(t, found, 2)
+			assert.Equal(t, newer.ID, found[0].ID)
+			assert.Equal(t, older.ID, found[1].ID)
+
+			found, total, err = p.ListBreakGlassGrants(ctx, uuid.Nil, 0, 1)
+			require.NoError(t, err)
+			assert.True(t, total >= 3)
+			assert.Len(t, found, 1)
+
+			closedAt := now.Add(time.Minute)
+			require.NoError(t, p.CloseBreakGlassGrant(ctx, older.ID, closedAt))
+			require.NoError(t, p.CloseBreakGlassGrant(ctx, older.ID, closedAt.Add(time.Minute)))
+
+			actual, err = p.GetBreakGlassGrant(ctx, older.ID)
+			require.NoError(t, err)
+			require.NotNil(t, actual.ClosedAt)
+			assert.True(t, closedAt.Equal(*actual.ClosedAt), "closing a closed grant must not change when it was closed")
+		})
+	}
+}
@@ -23,8 +23,10 @@ import (
 	"github.com/urfave/negroni"
 
 	"github.com/ory/graceful"
+	"github.com/ory/nosurf"
 	"github.com/ory/x/metricsx"
 
+	"github.com/ory/kratos/continuity"
 	"github.com/ory/kratos/driver"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/errorx"
@@ -56,9 +58,13 @@ func servePublic(r driver.Registry, wg *sync.WaitGroup, cmd *cobra.Command, args
 		stringsx.Coalesce(c.SelfPublicURL().Path, "/"),
 		c.SelfPublicURL().Hostname(),
 		!flagx.MustGetBool(cmd, "dev"),
+		c.CSRFCookieSameSiteMode(),
 	)
 
 	n.UseFunc(x.CleanPath) // Prevent double slashes from breaking CSRF.
+	n.UseFunc(x.PartitionCookies(func() []string { return partitionedCookies(c) }))
+	n.Use(r.BotScoreMiddleware())
+	n.UseFunc(session.CacheResolvedSessions)
 	r.WithCSRFHandler(csrf)
 	n.UseHandler(r.CSRFHandler())
 
@@ -88,6 +94,20 @@ func servePublic(r driver.Registry, wg *sync.WaitGroup, cmd *cobra.Command, args
 	l.Println("Public httpd was shutdown gracefully")
 }
 
+// partitionedCookies returns the names of the cookies which are partitioned by the top-level site.
+func partitionedCookies(c *config.Provider) (names []string) {
+	if c.SessionCookiePartitioned() {
+		names = append(names, session.DefaultSessionCookieName)
+	}
+	if c.CSRFCookiePartitioned() {
+		names = append(names, nosurf.CookieName)
+	}
+	if c.ContinuityCookiePartitioned() {
+		names = append(names, continuity.CookieName)
+	}
+	return names
+}
+
 func serveAdmin(r driver.Registry, wg *sync.WaitGroup, cmd *cobra.Command, args []string) {
 	defer wg.Done()
 
@@ -191,6 +211,23 @@ func sqa(cmd *cobra.Command, d driver.Registry) *metricsx.Service {
 func bgTasks(d driver.Registry, wg *sync.WaitGroup, cmd *cobra.Command, args []string) {
 	defer wg.Done()
 
+	if d.Configuration().ConfigVersionsEnabled() {
+		d.Logger().Println("Configuration version sync started.")
+		go d.ConfigVersionManager().Watch(cmd.Context())
+	}
+
+	d.Logger().Println("Session janitor started.")
+	go d.SessionJanitor().Watch(cmd.Context())
+
+	d.Logger().Println("Identity purger started.")
+	go d.IdentityPurger().Watch(cmd.Context())
+
+	d.Logger().Println("Identity growth rollups started.")
+	go d.IdentityGrowthAggregator().Watch(cmd.Context())
+
+	d.Logger().Println("OpenID Connect provider health checks started.")
+	go d.OIDCHealthChecker().Watch(cmd.Context())
+
 	d.Logger().Println("Courier worker started.")
 	if err := graceful.Graceful(d.Courier().Work, d.Courier().Shutdown); err != nil {
 		d.Logger().WithError(err).Fatalf("Failed to run courier worker.")
@@ -15,6 +15,8 @@
 package serve
 
 import (
+	"os"
+
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/internal/clihelpers"
 	"github.com/ory/x/configx"
@@ -30,6 +32,16 @@ var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Run the ORY Kratos server",
 	Run: func(cmd *cobra.Command, args []string) {
+		if selfTest, _ := cmd.Flags().GetBool("self-test"); selfTest {
+			// The self-test connects to the database itself so that an unreachable database is reported instead of retried.
+			report := driver.NewWithoutInit(configx.WithFlags(cmd.Flags())).SelfTest(cmd.Context())
+			report.Write(cmd.OutOrStdout())
+			if report.Failed() {
+				os.Exit(1)
+			}
+			return
+		}
+
 		d := driver.New(configx.WithFlags(cmd.Flags()))
 
 		if d.Configuration().IsInsecureDevMode() {
@@ -63,4 +75,5 @@ func init() {
 
 	serveCmd.PersistentFlags().Bool("sqa-opt-out", false, "Disable anonymized telemetry reports - for more information please visit https://www.ory.sh/docs/ecosystem/sqa")
 	serveCmd.PersistentFlags().Bool("dev", false, "Disables critical security features to make development easier")
+	serveCmd.PersistentFlags().Bool("self-test", false, "Checks the database, migrations, schemas, templates and Jsonnet mappers, performs a courier dry-run, prints a report and exits with a non-zero code if any check fails")
 }
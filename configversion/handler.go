@@ -0,0 +1,289 @@
+package configversion
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/x/configx"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteVersions = "/config/versions"
+	RouteActivate = RouteVersions + "/:id/activate"
+	RouteActive   = "/config/active"
+	RouteRollback = "/config/rollback"
+)
+
+type (
+	handlerDependencies interface {
+		x.WriterProvider
+		ManagementProvider
+		PersistenceProvider
+	}
+	HandlerProvider interface {
+		ConfigVersionHandler() *Handler
+	}
+	Handler struct {
+		d handlerDependencies
+		c *config.Provider
+	}
+)
+
+// Active Configuration Version
+//
+// swagger:model activeConfigVersion
+type ActiveVersion struct {
+	// Version is the version all instances converge on.
+	//
+	// required: true
+	Version *Version `json:"version"`
+
+	// RunningHash is the hash of the configuration used by the instance which handled the request. It differs
+	// from the hash of the active version until the instance synced.
+	//
+	// required: true
+	RunningHash string `json:"running_hash"`
+}
+
+func NewHandler(d handlerDependencies, c *config.Provider) *Handler {
+	return &Handler{d: d, c: c}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteVersions, h.enabled(h.list))
+	admin.POST(RouteVersions, h.enabled(h.stage))
+	admin.PUT(RouteActivate, h.enabled(h.activate))
+	admin.GET(RouteActive, h.enabled(h.active))
+	admin.POST(RouteRollback, h.enabled(h.rollback))
+}
+
+func (h *Handler) enabled(next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !h.c.ConfigVersionsEnabled() {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("Configuration versions are disabled. Set `config_versions.enabled` to record them.")))
+			return
+		}
+		next(w, r, ps)
+	}
+}
+
+// writeError reports invalid configurations as bad requests.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch e := errorsx.Cause(err).(type) {
+	case *jsonschema.ValidationError:
+		err = errors.WithStack(herodot.ErrBadRequest.WithReasonf("The configuration is invalid: %s", e))
+	case *configx.ImmutableError:
+		// The values are omitted because they may contain secrets.
+		err = errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The configuration changes the value of "%s" which can not be changed at runtime.`, e.Key))
+	}
+	h.d.Writer().WriteError(w, r, err)
+}
+
+// nolint:deadcode,unused
+// swagger:parameters listConfigVersions
+type listConfigVersionsParameters struct {
+	// Items per Page
+	//
+	// required: false
+	// in: query
+	// default: 100
+	// min: 1
+	// max: 500
+	PerPage int `json:"per_page"`
+
+	// Pagination Page
+	//
+	// required: false
+	// in: query
+	// default: 0
+	// min: 0
+	Page int `json:"page"`
+}
+
+// A list of configuration versions.
+// swagger:model configVersionList
+// nolint:deadcode,unused
+type configVersionList []Version
+
+// swagger:route GET /config/versions admin listConfigVersions
+//
+// List Configuration Versions
+//
+// Lists the staged, active, and retired configuration versions, newest first. This endpoint requires
+// `config_versions.enabled` to be set.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: configVersionList
+//       404: genericError
+//       500: genericError
+func (h *Handler) list(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	page, itemsPerPage := x.ParsePagination(r)
+	vs, err := h.d.ConfigVersionPersister().ListConfigVersions(r.Context(), page, itemsPerPage)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, vs)
+}
+
+// nolint:deadcode,unused
+// swagger:parameters stageConfigVersion
+type stageConfigVersionParameters struct {
+	// The complete configuration as JSON. It replaces all configuration files, flags, and environment variables.
+	//
+	// in: body
+	// required: true
+	Body map[string]interface{}
+}
+
+// swagger:route POST /config/versions admin stageConfigVersion
+//
+// Stage a Configuration Version
+//
+// Validates a configuration and stores it without activating it. Values which can not change at runtime, such as
+// `dsn` and `serve`, must equal the ones in use. Staging a configuration which is known already returns the
+// existing version.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       201: configVersion
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) stage(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var document json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&document); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the configuration: %s", err)))
+		return
+	}
+
+	v, err := h.d.ConfigVersionManager().Stage(r.Context(), document)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.d.Writer().WriteCode(w, r, http.StatusCreated, v)
+}
+
+// nolint:deadcode,unused
+// swagger:parameters activateConfigVersion
+type activateConfigVersionParameters struct {
+	// ID is the ID of the configuration version.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route PUT /config/versions/{id}/activate admin activateConfigVersion
+//
+// Activate a Configuration Version
+//
+// Applies the configuration version to the instance handling the request and makes it the version all other
+// instances sharing the database apply within `config_versions.sync_interval`. The configuration in use is not
+// changed if the version is invalid.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: configVersion
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) activate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	v, err := h.d.ConfigVersionManager().Activate(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, v)
+}
+
+// swagger:route GET /config/active admin getActiveConfigVersion
+//
+// Get the Active Configuration Version
+//
+// Returns the version all instances converge on and the hash of the configuration used by the instance handling
+// the request.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: activeConfigVersion
+//       404: genericError
+//       500: genericError
+func (h *Handler) active(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	v, err := h.d.ConfigVersionPersister().GetActiveConfigVersion(r.Context())
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	hash, err := h.d.ConfigVersionManager().RunningHash()
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, &ActiveVersion{Version: v, RunningHash: hash})
+}
+
+// swagger:route POST /config/rollback admin rollbackConfigVersion
+//
+// Roll Back the Active Configuration Version
+//
+// Activates the version which was active before the active one.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: configVersion
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) rollback(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	v, err := h.d.ConfigVersionManager().Rollback(r.Context())
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("There is no version to roll back to.")))
+		return
+	} else if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, v)
+}
@@ -0,0 +1,119 @@
+package configversion_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/kratos/configversion"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+)
+
+func TestHandler(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	_, adminTS := testhelpers.NewKratosServer(t, reg)
+
+	do := func(t *testing.T, method, path string, body []byte, expectedStatus int, out interface{}) {
+		req, err := http.NewRequest(method, adminTS.URL+path, bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		res, err := adminTS.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, expectedStatus, res.StatusCode)
+		if out != nil {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(out))
+		}
+	}
+
+	t.Run("case=is disabled per default", func(t *testing.T) {
+		do(t, "GET", configversion.RouteVersions, nil, http.StatusNotFound, nil)
+	})
+
+	conf.MustSet(config.ViperKeySecretsDefault, []string{"a-secret-which-is-long-enough-32"})
+	conf.MustSet(config.ViperKeyConfigVersionsEnabled, true)
+	require.NoError(t, reg.ConfigVersionManager().Sync(context.Background()))
+
+	var initial configversion.ActiveVersion
+	do(t, "GET", configversion.RouteActive, nil, http.StatusOK, &initial)
+	assert.Equal(t, initial.Version.Hash, initial.RunningHash)
+	assert.Equal(t, configversion.StateActive, initial.Version.State)
+
+	document := func(t *testing.T, key string, value interface{}) []byte {
+		snapshot, err := conf.Snapshot()
+		require.NoError(t, err)
+		document, err := sjson.SetBytes(snapshot, key, value)
+		require.NoError(t, err)
+		return document
+	}
+
+	t.Run("case=rejects invalid configurations", func(t *testing.T) {
+		do(t, "POST", configversion.RouteVersions, document(t, config.ViperKeyConfigVersionsSyncInterval, "often"), http.StatusBadRequest, nil)
+		do(t, "POST", configversion.RouteVersions, document(t, config.ViperKeyAdminPort, 1), http.StatusBadRequest, nil)
+		do(t, "POST", configversion.RouteVersions, []byte("{"), http.StatusBadRequest, nil)
+	})
+
+	var staged configversion.Version
+	t.Run("case=stages a configuration", func(t *testing.T) {
+		do(t, "POST", configversion.RouteVersions, document(t, config.ViperKeySelfServiceBrowserDefaultReturnTo, "https://www.ory.sh/staged"), http.StatusCreated, &staged)
+		assert.Equal(t, configversion.StateStaged, staged.State)
+		assert.Equal(t, "https://www.ory.sh/redirect-not-set", conf.SelfServiceBrowserDefaultReturnTo().String(), "staging must not change the configuration in use")
+
+		stored, err := reg.ConfigVersionPersister().GetConfigVersion(context.Background(), staged.ID)
+		require.NoError(t, err)
+		assert.False(t, gjson.GetBytes(stored.Config, "secrets").Exists(), "secrets must not be stored")
+		assert.False(t, gjson.GetBytes(stored.Config, config.ViperKeyDSN).Exists(), "the DSN must not be stored")
+
+		var again configversion.Version
+		do(t, "POST", configversion.RouteVersions, document(t, config.ViperKeySelfServiceBrowserDefaultReturnTo, "https://www.ory.sh/staged"), http.StatusCreated, &again)
+		assert.Equal(t, staged.ID, again.ID)
+
+		var vs []configversion.Version
+		do(t, "GET", configversion.RouteVersions, nil, http.StatusOK, &vs)
+		assert.Len(t, vs, 2)
+	})
+
+	t.Run("case=activates a configuration", func(t *testing.T) {
+		var activated configversion.Version
+		do(t, "PUT", "/config/versions/"+staged.ID.String()+"/activate", nil, http.StatusOK, &activated)
+		assert.Equal(t, configversion.StateActive, activated.State)
+		assert.Equal(t, "https://www.ory.sh/staged", conf.SelfServiceBrowserDefaultReturnTo().String())
+		assert.Equal(t, [][]byte{[]byte("a-secret-which-is-long-enough-32")}, conf.SecretsDefault(), "activating a version must keep the secrets in use")
+
+		var active configversion.ActiveVersion
+		do(t, "GET", configversion.RouteActive, nil, http.StatusOK, &active)
+		assert.Equal(t, staged.Hash, active.RunningHash)
+	})
+
+	t.Run("case=rolls back", func(t *testing.T) {
+		var previous configversion.Version
+		do(t, "POST", configversion.RouteRollback, nil, http.StatusOK, &previous)
+		assert.Equal(t, initial.Version.ID, previous.ID)
+		assert.Equal(t, "https://www.ory.sh/redirect-not-set", conf.SelfServiceBrowserDefaultReturnTo().String())
+	})
+
+	t.Run("case=syncs versions activated by other instances", func(t *testing.T) {
+		require.NoError(t, reg.ConfigVersionPersister().ActivateConfigVersion(context.Background(), staged.ID))
+		require.NoError(t, reg.ConfigVersionManager().Sync(context.Background()))
+		assert.Equal(t, "https://www.ory.sh/staged", conf.SelfServiceBrowserDefaultReturnTo().String())
+	})
+
+	t.Run("case=activates configurations which were not seen before", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceBrowserDefaultReturnTo, "https://www.ory.sh/changed-file")
+		require.NoError(t, reg.ConfigVersionManager().Sync(context.Background()))
+		assert.Equal(t, "https://www.ory.sh/changed-file", conf.SelfServiceBrowserDefaultReturnTo().String())
+
+		var active configversion.ActiveVersion
+		do(t, "GET", configversion.RouteActive, nil, http.StatusOK, &active)
+		assert.Equal(t, active.Version.Hash, active.RunningHash)
+	})
+}
@@ -0,0 +1,152 @@
+package configversion
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	managerDependencies interface {
+		x.LoggingProvider
+		PersistenceProvider
+	}
+	ManagementProvider interface {
+		ConfigVersionManager() *Manager
+	}
+	Manager struct {
+		d managerDependencies
+		c *config.Provider
+	}
+)
+
+func NewManager(d managerDependencies, c *config.Provider) *Manager {
+	return &Manager{d: d, c: c}
+}
+
+// Stage validates a configuration document and stores the configuration it results in. Staging a configuration
+// which is known already returns the existing version.
+func (m *Manager) Stage(ctx context.Context, document []byte) (*Version, error) {
+	snapshot, err := m.c.Load(document)
+	if err != nil {
+		return nil, err
+	}
+	return m.findOrCreate(ctx, snapshot)
+}
+
+// Activate makes the version the one all instances converge on and applies it to this instance.
+func (m *Manager) Activate(ctx context.Context, id uuid.UUID) (*Version, error) {
+	v, err := m.d.ConfigVersionPersister().GetConfigVersion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The version is validated again because the schema or the immutable values may have changed since it was staged.
+	if _, err := m.c.Load(v.Config); err != nil {
+		return nil, err
+	}
+
+	if err := m.d.ConfigVersionPersister().ActivateConfigVersion(ctx, v.ID); err != nil {
+		return nil, err
+	}
+
+	if err := m.c.Apply(v.Config); err != nil {
+		return nil, err
+	}
+
+	return m.d.ConfigVersionPersister().GetConfigVersion(ctx, v.ID)
+}
+
+// Rollback activates the version which was active before the active one.
+func (m *Manager) Rollback(ctx context.Context) (*Version, error) {
+	previous, err := m.d.ConfigVersionPersister().GetPreviousConfigVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.Activate(ctx, previous.ID)
+}
+
+// RunningHash returns the hash of the configuration this instance uses.
+func (m *Manager) RunningHash() (string, error) {
+	snapshot, err := m.c.Snapshot()
+	if err != nil {
+		return "", err
+	}
+	return config.Hash(snapshot), nil
+}
+
+// Sync records the configuration this instance uses. If it was not seen before, for example because a configuration
+// file changed, it becomes the active version. Otherwise the active version is applied to this instance.
+func (m *Manager) Sync(ctx context.Context) error {
+	snapshot, err := m.c.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	running, err := m.d.ConfigVersionPersister().FindConfigVersionByHash(ctx, config.Hash(snapshot))
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		running, err = m.findOrCreate(ctx, snapshot)
+		if err != nil {
+			return err
+		}
+		m.d.Logger().WithField("config_version", running.ID).Info("Activating the configuration loaded by this instance.")
+		return m.d.ConfigVersionPersister().ActivateConfigVersion(ctx, running.ID)
+	} else if err != nil {
+		return err
+	}
+
+	active, err := m.d.ConfigVersionPersister().GetActiveConfigVersion(ctx)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return m.d.ConfigVersionPersister().ActivateConfigVersion(ctx, running.ID)
+	} else if err != nil {
+		return err
+	}
+
+	if active.ID == running.ID {
+		return nil
+	}
+
+	m.d.Logger().WithField("config_version", active.ID).Info("Applying the active configuration version.")
+	return m.c.Apply(active.Config)
+}
+
+// Watch syncs the configuration until the context is canceled.
+func (m *Manager) Watch(ctx context.Context) {
+	for {
+		if err := m.Sync(ctx); err != nil {
+			m.d.Logger().WithError(err).Error("Unable to sync the configuration version.")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.c.ConfigVersionsSyncInterval()):
+		}
+	}
+}
+
+func (m *Manager) findOrCreate(ctx context.Context, snapshot []byte) (*Version, error) {
+	hash := config.Hash(snapshot)
+	v, err := m.d.ConfigVersionPersister().FindConfigVersionByHash(ctx, hash)
+	if err == nil {
+		return v, nil
+	} else if !errors.Is(err, sqlcon.ErrNoRows) {
+		return nil, err
+	}
+
+	v = &Version{ID: x.NewUUID(), Hash: hash, State: StateStaged, Config: snapshot}
+	if err := m.d.ConfigVersionPersister().CreateConfigVersion(ctx, v); errors.Is(err, sqlcon.ErrUniqueViolation) {
+		// Another instance stored the same configuration concurrently.
+		return m.d.ConfigVersionPersister().FindConfigVersionByHash(ctx, hash)
+	} else if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,122 @@
+package configversion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/x"
+)
+
+type (
+	Persister interface {
+		// CreateConfigVersion persists a version. Returns sqlcon.ErrUniqueViolation if a version with the same hash
+		// exists already.
+		CreateConfigVersion(ctx context.Context, v *Version) error
+
+		// GetConfigVersion returns a version by its ID or sqlcon.ErrNoRows.
+		GetConfigVersion(ctx context.Context, id uuid.UUID) (*Version, error)
+
+		// FindConfigVersionByHash returns a version by its hash or sqlcon.ErrNoRows.
+		FindConfigVersionByHash(ctx context.Context, hash string) (*Version, error)
+
+		// GetActiveConfigVersion returns the active version or sqlcon.ErrNoRows.
+		GetActiveConfigVersion(ctx context.Context) (*Version, error)
+
+		// GetPreviousConfigVersion returns the retired version which was activated last or sqlcon.ErrNoRows.
+		GetPreviousConfigVersion(ctx context.Context) (*Version, error)
+
+		// ListConfigVersions returns the versions, newest first.
+		ListConfigVersions(ctx context.Context, page, itemsPerPage int) ([]Version, error)
+
+		// ActivateConfigVersion retires the active version and activates the given one in a single transaction.
+		ActivateConfigVersion(ctx context.Context, id uuid.UUID) error
+	}
+
+	PersistenceProvider interface {
+		ConfigVersionPersister() Persister
+	}
+)
+
+func TestPersister(p Persister) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+
+		now := time.Now().UTC().Truncate(time.Second)
+		newVersion := func(hash string, createdAt time.Time) *Version {
+			return &Version{ID: x.NewUUID(), Hash: hash, State: StateStaged, Config: []byte(`{"hash":"` + hash + `"}`), CreatedAt: createdAt}
+		}
+
+		t.Run("case=no version is active", func(t *testing.T) {
+			_, err := p.GetActiveConfigVersion(ctx)
+			assert.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+			_, err = p.GetPreviousConfigVersion(ctx)
+			assert.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+		})
+
+		first, second := newVersion("first", now.Add(-time.Minute)), newVersion("second", now)
+		require.NoError(t, p.CreateConfigVersion(ctx, first))
+		require.NoError(t, p.CreateConfigVersion(ctx, second))
+
+		t.Run("case=hashes are unique", func(t *testing.T) {
+			err := p.CreateConfigVersion(ctx, newVersion("first", now))
+			assert.True(t, errors.Is(err, sqlcon.ErrUniqueViolation), "%+v", err)
+		})
+
+		t.Run("case=finds versions", func(t *testing.T) {
+			actual, err := p.GetConfigVersion(ctx, first.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "first", actual.Hash)
+			assert.JSONEq(t, `{"hash":"first"}`, string(actual.Config))
+
+			actual, err = p.FindConfigVersionByHash(ctx, "second")
+			require.NoError(t, err)
+			assert.Equal(t, second.ID, actual.ID)
+
+			_, err = p.FindConfigVersionByHash(ctx, "unknown")
+			assert.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+		})
+
+		t.Run("case=activates versions", func(t *testing.T) {
+			require.NoError(t, p.ActivateConfigVersion(ctx, first.ID))
+			require.NoError(t, p.ActivateConfigVersion(ctx, second.ID))
+
+			active, err := p.GetActiveConfigVersion(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, second.ID, active.ID)
+			assert.NotNil(t, active.ActivatedAt)
+
+			previous, err := p.GetPreviousConfigVersion(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, first.ID, previous.ID)
+			assert.Equal(t, StateRetired, previous.State)
+
+			err = p.ActivateConfigVersion(ctx, x.NewUUID())
+			assert.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+
+			active, err = p.GetActiveConfigVersion(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, second.ID, active.ID, "a failed activation must not retire the active version")
+		})
+
+		t.Run("case=lists versions", func(t *testing.T) {
+			vs, err := p.ListConfigVersions(ctx, 0, 10)
+			require.NoError(t, err)
+			require.Len(t, vs, 2)
+			assert.Equal(t, second.ID, vs[0].ID)
+			assert.Equal(t, first.ID, vs[1].ID)
+
+			vs, err = p.ListConfigVersions(ctx, 2, 1)
+			require.NoError(t, err)
+			require.Len(t, vs, 1)
+			assert.Equal(t, first.ID, vs[0].ID)
+		})
+	}
+}
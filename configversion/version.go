@@ -0,0 +1,55 @@
+package configversion
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/sqlxx"
+)
+
+type State string
+
+const (
+	// StateStaged versions were validated but never activated.
+	StateStaged State = "staged"
+
+	// StateActive is the state of the version all instances converge on. There is at most one active version.
+	StateActive State = "active"
+
+	// StateRetired versions were active before and can be rolled back to.
+	StateRetired State = "retired"
+)
+
+// Configuration Version
+//
+// swagger:model configVersion
+type Version struct {
+	// required: true
+	ID uuid.UUID `json:"id" db:"id"`
+
+	// Hash is the hex-encoded SHA-256 hash of the configuration including its defaults.
+	//
+	// required: true
+	Hash string `json:"hash" db:"hash"`
+
+	// State is either `staged`, `active`, or `retired`.
+	//
+	// required: true
+	State State `json:"state" db:"state"`
+
+	// Config is the configuration without its secrets and is not exposed.
+	Config sqlxx.JSONRawMessage `json:"-" db:"config"`
+
+	// ActivatedAt is the time this version was activated last.
+	ActivatedAt *time.Time `json:"activated_at,omitempty" db:"activated_at"`
+
+	// required: true
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+}
+
+func (v Version) TableName() string {
+	return "config_versions"
+}
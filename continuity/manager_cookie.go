@@ -5,8 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/gorilla/sessions"
 	"github.com/pkg/errors"
 
 	"github.com/ory/herodot"
@@ -19,7 +21,13 @@ import (
 var _ Manager = new(ManagerCookie)
 var ErrNotResumable = *herodot.ErrBadRequest.WithError("session is not resumable").WithReasonf("No resumable session could be found in the HTTP Header.")
 
-const cookieName = "ory_kratos_continuity"
+const (
+	CookieName = "ory_kratos_continuity"
+
+	// cookieExpiresAtKey holds the unix time at which the last container referenced by the cookie expires. It
+	// is used to expire the cookie together with its containers.
+	cookieExpiresAtKey = "expires_at"
+)
 
 type (
 	managerCookieDependencies interface {
@@ -36,6 +44,8 @@ func NewManagerCookie(d managerCookieDependencies) *ManagerCookie {
 	return &ManagerCookie{d: d}
 }
 
+// Pause stores the container in the datastore and only references it by its ID in the continuity cookie. A
+// container previously paused with the same name is replaced.
 func (m *ManagerCookie) Pause(ctx context.Context, w http.ResponseWriter, r *http.Request, name string, opts ...ManagerOption) error {
 	if len(name) == 0 {
 		return errors.Errorf("continuity container name must be set")
@@ -47,17 +57,23 @@ func (m *ManagerCookie) Pause(ctx context.Context, w http.ResponseWriter, r *htt
 	}
 	c := NewContainer(name, *o)
 
-	if err := x.SessionPersistValues(w, r, m.d.ContinuityCookieManager(), cookieName, map[string]interface{}{
-		name: c.ID.String(),
-	}); err != nil {
-		return err
+	if sid, err := m.sid(ctx, r, name); err == nil {
+		if err := m.d.ContinuityPersister().DeleteContinuitySession(ctx, sid); err != nil && !errors.Is(err, sqlcon.ErrNoRows) {
+			return err
+		}
 	}
 
 	if err := m.d.ContinuityPersister().SaveContinuitySession(ctx, c); err != nil {
 		return errors.WithStack(err)
 	}
 
-	return nil
+	cookie := m.cookie(r)
+	cookie.Values[name] = c.ID.String()
+	if expiresAt, ok := cookie.Values[cookieExpiresAtKey].(int64); !ok || expiresAt < c.ExpiresAt.Unix() {
+		cookie.Values[cookieExpiresAtKey] = c.ExpiresAt.Unix()
+	}
+
+	return m.save(w, r, cookie)
 }
 
 func (m *ManagerCookie) Continue(ctx context.Context, w http.ResponseWriter, r *http.Request, name string, opts ...ManagerOption) (*Container, error) {
@@ -72,6 +88,11 @@ func (m *ManagerCookie) Continue(ctx context.Context, w http.ResponseWriter, r *
 	}
 
 	if err := container.Valid(o.iid); err != nil {
+		if container.ExpiresAt.Before(time.Now()) {
+			if err := m.remove(ctx, w, r, name, container.ID); err != nil {
+				return nil, err
+			}
+		}
 		return nil, err
 	}
 
@@ -81,12 +102,11 @@ func (m *ManagerCookie) Continue(ctx context.Context, w http.ResponseWriter, r *
 		}
 	}
 
-	if err := m.d.ContinuityPersister().DeleteContinuitySession(ctx, container.ID); err != nil {
-		return nil, err
-	}
-
-	if err := x.SessionUnsetKey(w, r, m.d.ContinuityCookieManager(), cookieName, name); err != nil {
-		return nil, err
+	// Containers kept with DontCleanUp must be consumed explicitly by calling Abort.
+	if o.cleanUp {
+		if err := m.remove(ctx, w, r, name, container.ID); err != nil {
+			return nil, err
+		}
 	}
 
 	return container, nil
@@ -94,7 +114,7 @@ func (m *ManagerCookie) Continue(ctx context.Context, w http.ResponseWriter, r *
 
 func (m *ManagerCookie) sid(ctx context.Context, r *http.Request, name string) (uuid.UUID, error) {
 	var sid uuid.UUID
-	if s, err := x.SessionGetString(r, m.d.ContinuityCookieManager(), cookieName, name); err != nil {
+	if s, err := x.SessionGetString(r, m.d.ContinuityCookieManager(), CookieName, name); err != nil {
 		return sid, errors.WithStack(ErrNotResumable.WithDebugf("%+v", err))
 	} else if sid = x.ParseUUID(s); sid == uuid.Nil {
 		return sid, errors.WithStack(ErrNotResumable.WithDebug("session id is not a valid uuid"))
@@ -127,9 +147,45 @@ func (m ManagerCookie) Abort(ctx context.Context, w http.ResponseWriter, r *http
 		return err
 	}
 
-	if err := x.SessionUnsetKey(w, r, m.d.ContinuityCookieManager(), cookieName, name); err != nil {
+	return m.remove(ctx, w, r, name, sid)
+}
+
+// remove deletes the container from the datastore and its reference from the continuity cookie. The cookie is
+// removed once it no longer references any container.
+func (m *ManagerCookie) remove(ctx context.Context, w http.ResponseWriter, r *http.Request, name string, sid uuid.UUID) error {
+	cookie := m.cookie(r)
+	delete(cookie.Values, name)
+
+	references := len(cookie.Values)
+	if _, ok := cookie.Values[cookieExpiresAtKey]; ok {
+		references--
+	}
+	if references == 0 {
+		cookie.Options.MaxAge = -1
+	}
+
+	if err := m.save(w, r, cookie); err != nil {
 		return err
 	}
 
 	return errors.WithStack(m.d.ContinuityPersister().DeleteContinuitySession(ctx, sid))
 }
+
+func (m *ManagerCookie) cookie(r *http.Request) *sessions.Session {
+	// The error does not matter because in the worst case we're re-writing the continuity cookie.
+	cookie, err := m.d.ContinuityCookieManager().Get(r, CookieName)
+	if err != nil {
+		cookie, _ = m.d.ContinuityCookieManager().New(r, CookieName)
+	}
+	return cookie
+}
+
+// save writes the continuity cookie so that it expires together with the last container it references.
+func (m *ManagerCookie) save(w http.ResponseWriter, r *http.Request, cookie *sessions.Session) error {
+	if expiresAt, ok := cookie.Values[cookieExpiresAtKey].(int64); ok && cookie.Options.MaxAge >= 0 {
+		if cookie.Options.MaxAge = int(time.Until(time.Unix(expiresAt, 0)).Seconds()) + 1; cookie.Options.MaxAge <= 0 {
+			cookie.Options.MaxAge = -1
+		}
+	}
+	return errors.WithStack(cookie.Save(r, w))
+}
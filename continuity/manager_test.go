@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/ory/x/ioutilx"
 
@@ -217,3 +219,86 @@ func TestManager(t *testing.T) {
 		})
 	}
 }
+
+func TestManagerCookie(t *testing.T) {
+	_, reg := internal.NewFastRegistryWithMocks(t)
+	m := reg.ContinuityManager()
+
+	var pause = func(t *testing.T, r *http.Request, name string, opts ...continuity.ManagerOption) *http.Request {
+		w := httptest.NewRecorder()
+		require.NoError(t, m.Pause(context.Background(), w, r, name, opts...))
+
+		next := httptest.NewRequest("GET", "/", nil)
+		for _, c := range w.Result().Cookies() {
+			next.AddCookie(c)
+		}
+		return next
+	}
+
+	var cont = func(t *testing.T, r *http.Request, name string, opts ...continuity.ManagerOption) (*continuity.Container, *http.Response) {
+		w := httptest.NewRecorder()
+		c, err := m.Continue(context.Background(), w, r, name, opts...)
+		require.NoError(t, err)
+		return c, w.Result()
+	}
+
+	t.Run("case=cookie expires with the longest living container", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		require.NoError(t, m.Pause(context.Background(), w, httptest.NewRequest("GET", "/", nil), "short", continuity.WithLifespan(time.Minute)))
+		require.Len(t, w.Result().Cookies(), 1)
+		assert.InDelta(t, 60, w.Result().Cookies()[0].MaxAge, 2)
+
+		r := pause(t, httptest.NewRequest("GET", "/", nil), "short", continuity.WithLifespan(time.Minute))
+		w = httptest.NewRecorder()
+		require.NoError(t, m.Pause(context.Background(), w, r, "long", continuity.WithLifespan(time.Hour)))
+		assert.InDelta(t, 3600, w.Result().Cookies()[0].MaxAge, 2)
+	})
+
+	t.Run("case=pausing again replaces the container", func(t *testing.T) {
+		r := pause(t, httptest.NewRequest("GET", "/", nil), "replace", continuity.WithPayload(&persisterTestPayload{"first"}))
+		first, err := reg.ContinuityPersister().GetContinuitySession(context.Background(), x.ParseUUID(x.SessionGetStringOr(r, reg.ContinuityCookieManager(), "ory_kratos_continuity", "replace", "")))
+		require.NoError(t, err)
+
+		r = pause(t, r, "replace", continuity.WithPayload(&persisterTestPayload{"second"}))
+		_, err = reg.ContinuityPersister().GetContinuitySession(context.Background(), first.ID)
+		require.Error(t, err)
+
+		var p persisterTestPayload
+		_, _ = cont(t, r, "replace", continuity.WithPayload(&p))
+		assert.Equal(t, "second", p.Foo)
+	})
+
+	t.Run("case=container is consumed and the cookie removed", func(t *testing.T) {
+		r := pause(t, httptest.NewRequest("GET", "/", nil), "consume")
+		c, res := cont(t, r, "consume")
+
+		require.Len(t, res.Cookies(), 1)
+		assert.Equal(t, -1, res.Cookies()[0].MaxAge)
+		_, err := reg.ContinuityPersister().GetContinuitySession(context.Background(), c.ID)
+		require.Error(t, err)
+	})
+
+	t.Run("case=container is kept until it is consumed explicitly", func(t *testing.T) {
+		r := pause(t, httptest.NewRequest("GET", "/", nil), "keep")
+		c, _ := cont(t, r, "keep", continuity.DontCleanUp())
+		again, _ := cont(t, r, "keep", continuity.DontCleanUp())
+		assert.Equal(t, c.ID, again.ID)
+
+		require.NoError(t, m.Abort(context.Background(), httptest.NewRecorder(), r, "keep"))
+		_, err := reg.ContinuityPersister().GetContinuitySession(context.Background(), c.ID)
+		require.Error(t, err)
+	})
+
+	t.Run("case=expired container is removed", func(t *testing.T) {
+		r := pause(t, httptest.NewRequest("GET", "/", nil), "expired", continuity.WithLifespan(-time.Minute))
+		sid := x.ParseUUID(x.SessionGetStringOr(r, reg.ContinuityCookieManager(), "ory_kratos_continuity", "expired", ""))
+
+		_, err := m.Continue(context.Background(), httptest.NewRecorder(), r, "expired")
+		var he *herodot.DefaultError
+		require.True(t, errors.As(err, &he), "%+v", err)
+		assert.Contains(t, he.Reason(), "expired")
+
+		_, err = reg.ContinuityPersister().GetContinuitySession(context.Background(), sid)
+		require.Error(t, err)
+	})
+}
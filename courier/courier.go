@@ -4,6 +4,8 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"strconv"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ory/herodot"
+	"github.com/ory/x/httpx"
 
 	gomail "github.com/ory/mail/v3"
 
@@ -25,9 +28,10 @@ type (
 		x.LoggingProvider
 	}
 	Courier struct {
-		Dialer *gomail.Dialer
-		d      smtpDependencies
-		c      *config.Provider
+		Dialer     *gomail.Dialer
+		d          smtpDependencies
+		c          *config.Provider
+		httpClient *http.Client
 		// graceful shutdown handling
 		ctx      context.Context
 		shutdown context.CancelFunc
@@ -35,8 +39,16 @@ type (
 	Provider interface {
 		Courier() *Courier
 	}
+
+	// Preferences decide whether a recipient wants to receive messages which are not required for the security
+	// of its account.
+	Preferences interface {
+		Allows(t MessageType) bool
+	}
 )
 
+var ErrRecipientOptedOut = errors.New("the recipient opted out of receiving this message")
+
 func NewSMTP(d smtpDependencies, c *config.Provider) *Courier {
 	uri := c.CourierSMTPURL()
 	password, _ := uri.User.Password()
@@ -53,10 +65,11 @@ func NewSMTP(d smtpDependencies, c *config.Provider) *Courier {
 	}
 
 	return &Courier{
-		d:        d,
-		c:        c,
-		ctx:      ctx,
-		shutdown: cancel,
+		d:          d,
+		c:          c,
+		httpClient: httpx.NewResilientClientLatencyToleranceMedium(nil),
+		ctx:        ctx,
+		shutdown:   cancel,
 		Dialer: &gomail.Dialer{
 			/* #nosec we need to support SMTP servers without TLS */
 			TLSConfig:    tlsConfig,
@@ -71,35 +84,86 @@ func NewSMTP(d smtpDependencies, c *config.Provider) *Courier {
 	}
 }
 
-func (m *Courier) QueueEmail(ctx context.Context, t EmailTemplate) (uuid.UUID, error) {
+func newEmailMessage(t EmailTemplate) (*Message, error) {
 	body, err := t.EmailBody()
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
 
 	subject, err := t.EmailSubject()
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
 
 	recipient, err := t.EmailRecipient()
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
 
-	message := &Message{
+	return &Message{
 		Status:    MessageStatusQueued,
 		Type:      MessageTypeEmail,
 		Body:      body,
 		Subject:   subject,
 		Recipient: recipient,
+	}, nil
+}
+
+func (m *Courier) QueueEmail(ctx context.Context, t EmailTemplate) (uuid.UUID, error) {
+	message, err := newEmailMessage(t)
+	if err != nil {
+		return uuid.Nil, err
 	}
+
 	if err := m.d.CourierPersister().AddMessage(ctx, message); err != nil {
 		return uuid.Nil, err
 	}
 	return message.ID, nil
 }
 
+// QueueNotificationEmail queues an email which is not required for the security of the recipient's account, for
+// example a product update. Returns ErrRecipientOptedOut without queueing the email if the recipient's preferences
+// do not allow it. Security messages such as recovery emails must be queued using QueueEmail instead.
+func (m *Courier) QueueNotificationEmail(ctx context.Context, p Preferences, t EmailTemplate) (uuid.UUID, error) {
+	if p != nil && !p.Allows(MessageTypeEmail) {
+		return uuid.Nil, errors.WithStack(ErrRecipientOptedOut)
+	}
+	return m.QueueEmail(ctx, t)
+}
+
+// DryRun renders the template into a complete email and connects to the SMTP server
+// without queueing or sending the email.
+func (m *Courier) DryRun(ctx context.Context, t EmailTemplate) error {
+	if len(m.Dialer.Host) == 0 {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Courier tried to connect to the SMTP server but courier.smtp_url is not set!"))
+	}
+
+	message, err := newEmailMessage(t)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.emailMessage(message).WriteTo(ioutil.Discard); err != nil {
+		return errors.WithStack(err)
+	}
+
+	sc, err := m.Dialer.Dial(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(sc.Close())
+}
+
+func (m *Courier) emailMessage(msg *Message) *gomail.Message {
+	gm := gomail.NewMessage()
+	gm.SetHeader("From", m.c.CourierSMTPFrom())
+	gm.SetHeader("To", msg.Recipient)
+	gm.SetHeader("Subject", msg.Subject)
+	gm.SetBody("text/plain", msg.Body)
+	gm.AddAlternative("text/html", msg.Body)
+	return gm
+}
+
 func (m *Courier) Work() error {
 	errChan := make(chan error)
 	defer close(errChan)
@@ -142,12 +206,7 @@ func (m *Courier) watchMessages(ctx context.Context, errChan chan error) {
 				switch msg.Type {
 				case MessageTypeEmail:
 					from := m.c.CourierSMTPFrom()
-					gm := gomail.NewMessage()
-					gm.SetHeader("From", from)
-					gm.SetHeader("To", msg.Recipient)
-					gm.SetHeader("Subject", msg.Subject)
-					gm.SetBody("text/plain", msg.Body)
-					gm.AddAlternative("text/html", msg.Body)
+					gm := m.emailMessage(&msg)
 
 					if err := m.Dialer.DialAndSend(ctx, gm); err != nil {
 						m.d.Logger().
@@ -173,6 +232,28 @@ func (m *Courier) watchMessages(ctx context.Context, errChan chan error) {
 						WithField("message_type", msg.Type).
 						WithField("message_subject", msg.Subject).
 						Debug("Courier sent out message.")
+				case MessageTypeSMS:
+					if err := m.sendSMS(ctx, &msg); err != nil {
+						m.d.Logger().
+							WithError(err).
+							WithField("message_id", msg.ID).
+							WithField("sms_provider", msg.Provider).
+							Error("Unable to send text message.")
+						continue
+					}
+
+					if err := m.d.CourierPersister().SetMessageStatus(ctx, msg.ID, MessageStatusSent); err != nil {
+						m.d.Logger().
+							WithError(err).
+							WithField("message_id", msg.ID).
+							Error(`Unable to set the message status to "sent".`)
+						return err
+					}
+
+					m.d.Logger().
+						WithField("message_id", msg.ID).
+						WithField("message_type", msg.Type).
+						Debug("Courier sent out message.")
 				default:
 					return errors.Errorf("received unexpected message type: %d", msg.Type)
 				}
@@ -2,9 +2,11 @@ package courier_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
@@ -20,6 +22,7 @@ import (
 
 	dhelper "github.com/ory/x/sqlcon/dockertest"
 
+	"github.com/ory/kratos/courier"
 	templates "github.com/ory/kratos/courier/template"
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/internal"
@@ -165,3 +168,144 @@ func TestSMTP(t *testing.T) {
 		}
 	})
 }
+
+type preferencesFunc func(courier.MessageType) bool
+
+func (f preferencesFunc) Allows(t courier.MessageType) bool {
+	return f(t)
+}
+
+func TestQueueNotificationEmail(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyCourierSMTPURL, "smtp://foo@bar@dev.null/")
+	c := reg.Courier()
+	ctx := context.Background()
+
+	tpl := templates.NewTestStub(conf, &templates.TestStubModel{
+		To:      "test-recipient@example.org",
+		Subject: "test-subject",
+		Body:    "test-body",
+	})
+
+	t.Run("case=queues the email if the recipient allows it", func(t *testing.T) {
+		id, err := c.QueueNotificationEmail(ctx, preferencesFunc(func(mt courier.MessageType) bool {
+			return mt == courier.MessageTypeEmail
+		}), tpl)
+		require.NoError(t, err)
+		assert.NotEqual(t, uuid.Nil, id)
+	})
+
+	t.Run("case=queues the email if the recipient has no preferences", func(t *testing.T) {
+		id, err := c.QueueNotificationEmail(ctx, nil, tpl)
+		require.NoError(t, err)
+		assert.NotEqual(t, uuid.Nil, id)
+	})
+
+	t.Run("case=does not queue the email if the recipient opted out", func(t *testing.T) {
+		_, err := c.QueueNotificationEmail(ctx, preferencesFunc(func(courier.MessageType) bool {
+			return false
+		}), tpl)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, courier.ErrRecipientOptedOut))
+	})
+}
+
+func TestQueueSMS(t *testing.T) {
+	received := make(chan map[string]string, 10)
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		body["authorization"] = r.Header.Get("Authorization")
+		received <- body
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(provider.Close)
+
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyCourierSMTPURL, "smtp://foo@bar@dev.null/")
+	conf.MustSet(config.ViperKeyCourierSMSRoutes, []map[string]interface{}{
+		{"countries": []string{"DE", "AT"}, "provider": "a", "sender_id": "Example", "template_variant": "de"},
+		{"countries": []string{"US"}, "provider": "b", "template_variant": "en"},
+		{"countries": []string{"GB"}, "provider": "unknown"},
+	})
+	conf.MustSet(config.ViperKeyCourierSMSProviders, []map[string]interface{}{
+		{"id": "a", "url": provider.URL, "headers": map[string]string{"Authorization": "Bearer secret"}},
+		{"id": "b", "url": provider.URL},
+	})
+	c := reg.Courier()
+	ctx := context.Background()
+
+	t.Run("case=uses the route of the recipient's country", func(t *testing.T) {
+		id, err := c.QueueSMS(ctx, &templates.TestSMSStub{To: "+4915112345678", Body: "123456"})
+		require.NoError(t, err)
+
+		m, err := reg.CourierPersister().LatestQueuedMessage(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, id, m.ID)
+		assert.Equal(t, courier.MessageTypeSMS, m.Type)
+		assert.Equal(t, "+4915112345678", m.Recipient)
+		assert.Equal(t, "de: 123456", m.Body)
+		assert.Equal(t, "a", m.Provider)
+		assert.Equal(t, "Example", m.SenderID)
+	})
+
+	t.Run("case=rejects countries without a route", func(t *testing.T) {
+		_, err := c.QueueSMS(ctx, &templates.TestSMSStub{To: "+33612345678"})
+		require.True(t, errors.Is(err, courier.ErrNoSMSRoute), "%+v", err)
+	})
+
+	t.Run("case=rejects routes with an unknown provider", func(t *testing.T) {
+		_, err := c.QueueSMS(ctx, &templates.TestSMSStub{To: "+447700900123"})
+		require.Error(t, err)
+	})
+
+	t.Run("case=rejects invalid phone numbers", func(t *testing.T) {
+		_, err := c.QueueSMS(ctx, &templates.TestSMSStub{To: "015112345678"})
+		require.Error(t, err)
+	})
+
+	t.Run("case=sends text messages to the provider of their route", func(t *testing.T) {
+		go func() {
+			require.NoError(t, c.Work())
+		}()
+		t.Cleanup(func() {
+			require.NoError(t, c.Shutdown(ctx))
+		})
+
+		select {
+		case body := <-received:
+			assert.Equal(t, map[string]string{
+				"from":          "Example",
+				"to":            "+4915112345678",
+				"body":          "de: 123456",
+				"authorization": "Bearer secret",
+			}, body)
+		case <-time.After(10 * time.Second):
+			t.Fatal("the text message was not sent to the provider")
+		}
+
+		var err error
+		for k := 0; k < 20; k++ {
+			if _, err = reg.CourierPersister().NextMessages(ctx, 10); errors.Is(err, courier.ErrQueueEmpty) {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		require.True(t, errors.Is(err, courier.ErrQueueEmpty), "%+v", err)
+	})
+}
+
+func TestSMSRoute(t *testing.T) {
+	routes := []config.CourierSMSRoute{
+		{Countries: []string{"DE"}, Provider: "a"},
+		{Provider: "b"},
+	}
+
+	r, err := courier.SMSRoute(routes, "+4915112345678")
+	require.NoError(t, err)
+	assert.Equal(t, "a", r.Provider)
+
+	r, err = courier.SMSRoute(routes, "+12025550123")
+	require.NoError(t, err)
+	assert.Equal(t, "b", r.Provider)
+}
@@ -17,6 +17,7 @@ type MessageType int
 
 const (
 	MessageTypeEmail MessageType = iota + 1
+	MessageTypeSMS
 )
 
 type Message struct {
@@ -27,6 +28,11 @@ type Message struct {
 	Body      string        `json:"-" db:"body"`
 	Subject   string        `json:"-" db:"subject"`
 
+	// Provider is the ID of the SMS provider sending a text message.
+	Provider string `json:"-" db:"provider"`
+	// SenderID is shown to the recipient as the sender of a text message.
+	SenderID string `json:"-" db:"sender_id"`
+
 	// CreatedAt is a helper struct field for gobuffalo.pop.
 	CreatedAt time.Time `json:"-" faker:"-" db:"created_at"`
 	// UpdatedAt is a helper struct field for gobuffalo.pop.
@@ -0,0 +1,137 @@
+package courier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/nyaruka/phonenumbers"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+// ErrNoSMSRoute is returned if no SMS route matches the country of the recipient.
+var ErrNoSMSRoute = herodot.ErrInternalServerError.
+	WithError("no SMS route").
+	WithReason("Text messages can not be sent to phone numbers of this country.")
+
+// SMSRoute returns the first route matching the country of the recipient, whose phone number must be in E.164
+// format. Routes without countries match all countries.
+func SMSRoute(routes []config.CourierSMSRoute, recipient string) (*config.CourierSMSRoute, error) {
+	number, err := phonenumbers.Parse(recipient, "")
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The recipient %q is not a phone number in E.164 format.", recipient))
+	}
+
+	country := phonenumbers.GetRegionCodeForNumber(number)
+	for k := range routes {
+		if len(routes[k].Countries) == 0 {
+			return &routes[k], nil
+		}
+		for _, c := range routes[k].Countries {
+			if c == country {
+				return &routes[k], nil
+			}
+		}
+	}
+
+	return nil, errors.WithStack(ErrNoSMSRoute.WithDetail("country", country))
+}
+
+type smsRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Body string `json:"body"`
+}
+
+func newSMSMessage(t SMSTemplate, routes []config.CourierSMSRoute) (*Message, error) {
+	recipient, err := t.SMSRecipient()
+	if err != nil {
+		return nil, err
+	}
+
+	route, err := SMSRoute(routes, recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := t.SMSBody(route.TemplateVariant)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Status:    MessageStatusQueued,
+		Type:      MessageTypeSMS,
+		Body:      body,
+		Recipient: recipient,
+		Provider:  route.Provider,
+		SenderID:  route.SenderID,
+	}, nil
+}
+
+func (m *Courier) smsProvider(id string) (*config.CourierSMSProvider, error) {
+	providers := m.c.CourierSMSProviders()
+	for k := range providers {
+		if providers[k].ID == id {
+			return &providers[k], nil
+		}
+	}
+	return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("SMS provider %q is not configured in courier.sms.providers.", id))
+}
+
+// QueueSMS queues a text message which is sent using the provider of the route matching the country of the
+// recipient. Returns ErrNoSMSRoute if no route matches the country of the recipient.
+func (m *Courier) QueueSMS(ctx context.Context, t SMSTemplate) (uuid.UUID, error) {
+	message, err := newSMSMessage(t, m.c.CourierSMSRoutes())
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if _, err := m.smsProvider(message.Provider); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := m.d.CourierPersister().AddMessage(ctx, message); err != nil {
+		return uuid.Nil, err
+	}
+	return message.ID, nil
+}
+
+// sendSMS POSTs the text message to the provider it was routed to.
+func (m *Courier) sendSMS(ctx context.Context, msg *Message) error {
+	provider, err := m.smsProvider(msg.Provider)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(&smsRequest{From: msg.SenderID, To: msg.Recipient, Body: msg.Body}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", provider.URL, &b)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range provider.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := m.httpClient.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Errorf("SMS provider %q responded with status code %d", provider.ID, res.StatusCode)
+	}
+	return nil
+}
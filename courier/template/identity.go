@@ -0,0 +1,48 @@
+package template
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/kratos/identity"
+)
+
+const (
+	TypeRecoveryValid     = "recovery_valid"
+	TypeVerificationValid = "verification_valid"
+	TypeVerificationCode  = "verification_code"
+)
+
+// NewIdentityModel returns the fields of the identity which are allowed, for example `traits.name.first`. Only
+// these fields are available to templates, which prevents personal data from accidentally leaking into messages.
+func NewIdentityModel(allowed []string, i *identity.Identity) (map[string]interface{}, error) {
+	if len(allowed) == 0 || i == nil {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(i)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	out := []byte("{}")
+	for _, path := range allowed {
+		value := gjson.GetBytes(raw, path)
+		if !value.Exists() {
+			continue
+		}
+
+		if out, err = sjson.SetRawBytes(out, path, []byte(value.Raw)); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	var model map[string]interface{}
+	if err := json.Unmarshal(out, &model); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return model, nil
+}
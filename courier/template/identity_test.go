@@ -0,0 +1,29 @@
+package template_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/courier/template"
+	"github.com/ory/kratos/identity"
+)
+
+func TestNewIdentityModel(t *testing.T) {
+	i := identity.NewIdentity("default")
+	i.Traits = identity.Traits(`{"email":"foo@ory.sh","name":{"first":"Foo","last":"Bar"}}`)
+
+	t.Run("case=returns nothing if no fields are allowed", func(t *testing.T) {
+		model, err := template.NewIdentityModel(nil, i)
+		require.NoError(t, err)
+		assert.Nil(t, model)
+	})
+
+	t.Run("case=returns only allowed fields", func(t *testing.T) {
+		model, err := template.NewIdentityModel([]string{"traits.name.first", "traits.unknown"}, i)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"traits": map[string]interface{}{"name": map[string]interface{}{"first": "Foo"}}}, model)
+	})
+
+}
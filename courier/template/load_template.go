@@ -6,7 +6,6 @@ import (
 	"os"
 	"text/template"
 
-	"github.com/Masterminds/sprig/v3"
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/markbates/pkger"
 	"github.com/pkg/errors"
@@ -43,7 +42,7 @@ func loadTextTemplate(path string, model interface{}) (string, error) {
 		}
 	}
 
-	t, err := template.New(path).Funcs(sprig.TxtFuncMap()).Parse(b.String())
+	t, err := template.New(path).Funcs(sandboxedFuncs).Parse(b.String())
 	if err != nil {
 		return "", errors.WithStack(err)
 	}
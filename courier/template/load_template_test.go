@@ -34,3 +34,30 @@ func TestLoadTextTemplate(t *testing.T) {
 		assert.Contains(t, executeTemplate(t, fp), "cached stub body")
 	})
 }
+
+func TestSandboxedFuncs(t *testing.T) {
+	render := func(t *testing.T, tpl string, model interface{}) (string, error) {
+		fp := filepath.Join(os.TempDir(), x.NewUUID().String()) + ".body.gotmpl"
+		require.NoError(t, ioutil.WriteFile(fp, bytes.NewBufferString(tpl)))
+		defer os.RemoveAll(fp)
+		return loadTextTemplate(fp, model)
+	}
+
+	t.Run("case=allows safe functions", func(t *testing.T) {
+		actual, err := render(t, `{{ "foo" | upper }} {{ .Name | default "bar" }}`, map[string]interface{}{})
+		require.NoError(t, err)
+		assert.Equal(t, "FOO bar", actual)
+	})
+
+	t.Run("case=does not allow reading the environment", func(t *testing.T) {
+		_, err := render(t, `{{ env "HOME" }}`, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `function "env" not defined`)
+	})
+
+	t.Run("case=does not allow calling functions of the model", func(t *testing.T) {
+		_, err := render(t, `{{ call .F }}`, map[string]interface{}{"F": func() string { return "called" }})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "calling functions is not allowed")
+	})
+}
@@ -14,6 +14,9 @@ type (
 	RecoveryValidModel struct {
 		To          string
 		RecoveryURL string
+
+		// Identity contains the identity fields allowed by `courier.template_variables`.
+		Identity map[string]interface{}
 	}
 )
 
@@ -0,0 +1,46 @@
+package template
+
+import (
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/pkg/errors"
+)
+
+// sandboxedFuncs are the only functions templates may call. Templates can be supplied by operators, which is why
+// functions reading the environment, resolving hosts, generating keys, or calling functions found in the model
+// are not available.
+var sandboxedFuncs = newSandboxedFuncs(
+	// strings
+	"abbrev", "abbrevboth", "trunc", "trim", "trimAll", "trimPrefix", "trimSuffix", "upper", "lower", "title",
+	"untitle", "substr", "repeat", "nospace", "initials", "wrap", "wrapWith", "contains", "hasPrefix", "hasSuffix",
+	"quote", "squote", "cat", "indent", "nindent", "replace", "plural", "snakecase", "camelcase", "kebabcase",
+	"swapcase", "shuffle", "toString", "join", "split", "splitList", "splitn", "sortAlpha",
+	// defaults and flow control
+	"default", "empty", "coalesce", "ternary", "fail",
+	// lists and dictionaries
+	"list", "first", "last", "rest", "initial", "append", "prepend", "concat", "reverse", "uniq", "has", "compact",
+	"dict", "get", "set", "unset", "hasKey", "keys", "values", "pick", "omit",
+	// math
+	"add", "add1", "sub", "div", "mod", "mul", "max", "min", "floor", "ceil", "round",
+	// dates
+	"now", "date", "dateInZone", "htmlDate", "htmlDateInZone", "ago", "duration", "durationRound",
+	// encoding
+	"b64enc", "b64dec", "b32enc", "b32dec", "toJson", "toPrettyJson",
+)
+
+func newSandboxedFuncs(names ...string) template.FuncMap {
+	all := sprig.TxtFuncMap()
+	funcs := make(template.FuncMap, len(names)+1)
+	for _, name := range names {
+		if f, ok := all[name]; ok {
+			funcs[name] = f
+		}
+	}
+
+	// Overrides the builtin function which calls functions found in the model.
+	funcs["call"] = func(interface{}, ...interface{}) (interface{}, error) {
+		return nil, errors.New("calling functions is not allowed in message templates")
+	}
+	return funcs
+}
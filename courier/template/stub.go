@@ -17,6 +17,20 @@ type TestStubModel struct {
 	Body    string
 }
 
+// TestSMSStub is a text message whose body contains the template variant.
+type TestSMSStub struct {
+	To   string
+	Body string
+}
+
+func (t *TestSMSStub) SMSRecipient() (string, error) {
+	return t.To, nil
+}
+
+func (t *TestSMSStub) SMSBody(variant string) (string, error) {
+	return variant + ": " + t.Body, nil
+}
+
 func NewTestStub(c *config.Provider, m *TestStubModel) *TestStub {
 	return &TestStub{c: c, m: m}
 }
@@ -0,0 +1,50 @@
+package template
+
+import (
+	"path/filepath"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+type (
+	VerificationCode struct {
+		c *config.Provider
+		m *VerificationCodeModel
+	}
+	VerificationCodeModel struct {
+		To               string
+		VerificationCode string
+
+		// Identity contains the identity fields allowed by `courier.template_variables`.
+		Identity map[string]interface{}
+	}
+)
+
+func NewVerificationCode(c *config.Provider, m *VerificationCodeModel) *VerificationCode {
+	return &VerificationCode{c: c, m: m}
+}
+
+func (t *VerificationCode) EmailRecipient() (string, error) {
+	return t.m.To, nil
+}
+
+func (t *VerificationCode) EmailSubject() (string, error) {
+	return loadTextTemplate(filepath.Join(t.c.CourierTemplatesRoot(), "verification/code/email.subject.gotmpl"), t.m)
+}
+
+func (t *VerificationCode) EmailBody() (string, error) {
+	return loadTextTemplate(filepath.Join(t.c.CourierTemplatesRoot(), "verification/code/email.body.gotmpl"), t.m)
+}
+
+func (t *VerificationCode) SMSRecipient() (string, error) {
+	return t.m.To, nil
+}
+
+// SMSBody renders `verification/code/sms.body.gotmpl` or, for a template variant, `sms.body.<variant>.gotmpl`.
+func (t *VerificationCode) SMSBody(variant string) (string, error) {
+	name := "sms.body.gotmpl"
+	if variant != "" {
+		name = "sms.body." + variant + ".gotmpl"
+	}
+	return loadTextTemplate(filepath.Join(t.c.CourierTemplatesRoot(), "verification/code", name), t.m)
+}
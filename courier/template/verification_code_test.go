@@ -0,0 +1,31 @@
+package template_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/courier/template"
+	"github.com/ory/kratos/internal"
+)
+
+func TestVerificationCode(t *testing.T) {
+	conf, _ := internal.NewFastRegistryWithMocks(t)
+	tpl := template.NewVerificationCode(conf, &template.VerificationCodeModel{VerificationCode: "123456"})
+
+	rendered, err := tpl.EmailBody()
+	require.NoError(t, err)
+	assert.Contains(t, rendered, "123456")
+
+	rendered, err = tpl.EmailSubject()
+	require.NoError(t, err)
+	assert.NotEmpty(t, rendered)
+
+	rendered, err = tpl.SMSBody("")
+	require.NoError(t, err)
+	assert.Contains(t, rendered, "123456")
+
+	_, err = tpl.SMSBody("unknown")
+	require.Error(t, err)
+}
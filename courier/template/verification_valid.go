@@ -14,6 +14,9 @@ type (
 	VerificationValidModel struct {
 		To              string
 		VerificationURL string
+
+		// Identity contains the identity fields allowed by `courier.template_variables`.
+		Identity map[string]interface{}
 	}
 )
 
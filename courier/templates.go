@@ -5,3 +5,11 @@ type EmailTemplate interface {
 	EmailBody() (string, error)
 	EmailRecipient() (string, error)
 }
+
+type SMSTemplate interface {
+	// SMSBody renders the text of the message using the template variant of the recipient's SMS route.
+	SMSBody(variant string) (string, error)
+
+	// SMSRecipient returns the phone number of the recipient in E.164 format.
+	SMSRecipient() (string, error)
+}
@@ -0,0 +1,92 @@
+package config
+
+type (
+	// ExperimentalFeature is a feature which may change or be removed without a deprecation period.
+	//
+	// swagger:model experimentalFeature
+	ExperimentalFeature struct {
+		// Key is the configuration key enabling the feature.
+		//
+		// required: true
+		Key string `json:"key"`
+
+		// Description describes the feature.
+		//
+		// required: true
+		Description string `json:"description"`
+
+		// Enabled is true if the feature is enabled.
+		//
+		// required: true
+		Enabled bool `json:"enabled"`
+	}
+
+	// DeprecatedOption is a configuration key which is still supported but will be removed in a future version.
+	//
+	// swagger:model deprecatedConfigOption
+	DeprecatedOption struct {
+		// Key is the deprecated configuration key.
+		//
+		// required: true
+		Key string `json:"key"`
+
+		// Replacement is the configuration key to use instead, if any.
+		Replacement string `json:"replacement,omitempty"`
+
+		// InUse is true if the key is set in the configuration.
+		//
+		// required: true
+		InUse bool `json:"in_use"`
+	}
+)
+
+var (
+	experimentalFeatures = []ExperimentalFeature{
+		{Key: "selfservice.methods.saml.enabled", Description: "Login and registration using SAML 2.0 identity providers."},
+		{Key: "selfservice.methods.ldap.enabled", Description: "Login using an LDAP directory."},
+		{Key: "selfservice.methods.kerberos.enabled", Description: "Login using Kerberos tickets sent with SPNEGO."},
+		{Key: "selfservice.methods.mtls.enabled", Description: "Login using client certificates."},
+		{Key: ViperKeySCIMEnabled, Description: "Provisioning identities using SCIM 2.0."},
+		{Key: ViperKeyConfigVersionsEnabled, Description: "Storing configuration versions in the database and syncing instances to the active one."},
+		{Key: ViperKeyBreakGlassEnabled, Description: "Requiring break-glass grants for administrative operations which bypass the protections of an identity."},
+	}
+
+	deprecatedOptions = []DeprecatedOption{
+		{Key: ViperKeyURLsWhitelistedReturnToDomains, Replacement: ViperKeyURLsAllowedReturnToDomains},
+	}
+)
+
+// ExperimentalFeatures returns all experimental features and whether they are enabled.
+func (p *Provider) ExperimentalFeatures() []ExperimentalFeature {
+	features := make([]ExperimentalFeature, len(experimentalFeatures))
+	for k, f := range experimentalFeatures {
+		f.Enabled = p.source().Bool(f.Key)
+		features[k] = f
+	}
+	return features
+}
+
+// DeprecatedOptions returns all deprecated configuration keys and whether they are set.
+func (p *Provider) DeprecatedOptions() []DeprecatedOption {
+	options := make([]DeprecatedOption, len(deprecatedOptions))
+	for k, o := range deprecatedOptions {
+		o.InUse = p.source().Exists(o.Key)
+		options[k] = o
+	}
+	return options
+}
+
+func (p *Provider) warnDeprecatedOptions() {
+	for _, o := range p.DeprecatedOptions() {
+		if !o.InUse {
+			continue
+		}
+		l := p.l.WithField("key", o.Key)
+		if o.Replacement == "" {
+			l.Warnf("Configuration key \"%s\" is deprecated and will be removed in a future version.", o.Key)
+			continue
+		}
+		l.WithField("replacement", o.Replacement).
+			Warnf("Configuration key \"%s\" is deprecated and will be removed in a future version. Use \"%s\" instead.", o.Key, o.Replacement)
+	}
+}
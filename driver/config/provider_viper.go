@@ -9,12 +9,14 @@ import (
 	"net/url"
 	"os"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/markbates/pkger"
 	"github.com/rs/cors"
 	"github.com/tidwall/gjson"
 
+	"github.com/ory/jsonschema/v3"
 	"github.com/ory/x/configx"
 	"github.com/ory/x/jsonx"
 
@@ -23,8 +25,10 @@ import (
 
 	"github.com/ory/x/logrusx"
 	"github.com/ory/x/tracing"
+	"github.com/ory/x/urlx"
 
 	kjson "github.com/knadh/koanf/parsers/json"
+	"github.com/knadh/koanf/providers/confmap"
 )
 
 const (
@@ -33,9 +37,13 @@ const (
 	DefaultSQLiteMemoryDSN                                          = "sqlite://:memory:?_fk=true"
 	UnknownVersion                                                  = "unknown version"
 	ViperKeyDSN                                                     = "dsn"
+	ViperKeyDatabaseUUIDVersion                                     = "database.uuid_version"
 	ViperKeyCourierSMTPURL                                          = "courier.smtp.connection_uri"
 	ViperKeyCourierTemplatesPath                                    = "courier.template_override_path"
+	ViperKeyCourierTemplateVariables                                = "courier.template_variables"
 	ViperKeyCourierSMTPFrom                                         = "courier.smtp.from_address"
+	ViperKeyCourierSMSRoutes                                        = "courier.sms.routes"
+	ViperKeyCourierSMSProviders                                     = "courier.sms.providers"
 	ViperKeySecretsDefault                                          = "secrets.default"
 	ViperKeySecretsCookie                                           = "secrets.cookie"
 	ViperKeyPublicBaseURL                                           = "serve.public.base_url"
@@ -44,24 +52,59 @@ const (
 	ViperKeyAdminBaseURL                                            = "serve.admin.base_url"
 	ViperKeyAdminPort                                               = "serve.admin.port"
 	ViperKeyAdminHost                                               = "serve.admin.host"
+	ViperKeyErrorsProblemJSONEnabled                                = "serve.errors.problem_json.enabled"
+	ViperKeyErrorsProblemJSONTypeBaseURL                            = "serve.errors.problem_json.type_base_url"
 	ViperKeySessionLifespan                                         = "session.lifespan"
 	ViperKeySessionSameSite                                         = "session.cookie.same_site"
 	ViperKeySessionDomain                                           = "session.cookie.domain"
 	ViperKeySessionPath                                             = "session.cookie.path"
 	ViperKeySessionPersistentCookie                                 = "session.cookie.persistent"
+	ViperKeySessionCookieEncryption                                 = "session.cookie.encrypt"
+	ViperKeySessionCookiePartitioned                                = "session.cookie.partitioned"
+	ViperKeyCookiesCSRFSameSite                                     = "cookies.csrf.same_site"
+	ViperKeyCookiesCSRFPartitioned                                  = "cookies.csrf.partitioned"
+	ViperKeyCookiesContinuitySameSite                               = "cookies.continuity.same_site"
+	ViperKeyCookiesContinuityPartitioned                            = "cookies.continuity.partitioned"
+	ViperKeySessionJWTJWKSURL                                       = "session.jwt.jwks_url"
+	ViperKeySessionJWTLifespan                                      = "session.jwt.lifespan"
+	ViperKeySessionJanitorInterval                                  = "session.janitor.interval"
+	ViperKeySessionDisclosureLifespan                               = "session.disclosure.lifespan"
+	ViperKeySessionLocationHeader                                   = "session.location_header"
+	ViperKeySessionRememberMeEnabled                                = "session.remember_me.enabled"
+	ViperKeySessionRememberMeLifespan                               = "session.remember_me.lifespan"
+	ViperKeySessionCacheEnabled                                     = "session.cache.enabled"
+	ViperKeySessionCacheTTL                                         = "session.cache.ttl"
+	ViperKeySessionSlidingExpirationEnabled                         = "session.sliding_expiration.enabled"
+	ViperKeySessionSlidingExpirationMaxLifespan                     = "session.sliding_expiration.max_lifespan"
+	ViperKeySessionRotationEnabled                                  = "session.rotation.enabled"
 	ViperKeySelfServiceStrategyConfig                               = "selfservice.methods"
+	ViperKeySelfServiceOIDCHealthCheckInterval                      = "selfservice.methods.oidc.health_checks.interval"
+	ViperKeySelfServiceOIDCHealthCheckTimeout                       = "selfservice.methods.oidc.health_checks.timeout"
 	ViperKeySelfServiceBrowserDefaultReturnTo                       = "selfservice." + DefaultBrowserReturnURL
+	ViperKeySelfServiceBrowserReturnToRules                         = "selfservice.default_browser_return_rules"
+	ViperKeyURLsAllowedReturnToDomains                              = "selfservice.allowed_return_urls"
 	ViperKeyURLsWhitelistedReturnToDomains                          = "selfservice.whitelisted_return_urls"
+	ViperKeySelfServiceAPIFlowBinding                               = "selfservice.api_flow_binding"
+	ViperKeySelfServiceFlowStatsEnabled                             = "selfservice.flow_stats.enabled"
+	ViperKeySelfServiceRegistrationEnabled                          = "selfservice.flows.registration.enabled"
 	ViperKeySelfServiceRegistrationUI                               = "selfservice.flows.registration.ui_url"
 	ViperKeySelfServiceRegistrationRequestLifespan                  = "selfservice.flows.registration.lifespan"
 	ViperKeySelfServiceRegistrationAfter                            = "selfservice.flows.registration.after"
 	ViperKeySelfServiceRegistrationBeforeHooks                      = "selfservice.flows.registration.before.hooks"
 	ViperKeySelfServiceLoginUI                                      = "selfservice.flows.login.ui_url"
 	ViperKeySelfServiceLoginRequestLifespan                         = "selfservice.flows.login.lifespan"
+	ViperKeySelfServiceFlowExpiredGracePeriod                       = "selfservice.flows.expired_grace_period"
 	ViperKeySelfServiceLoginAfter                                   = "selfservice.flows.login.after"
 	ViperKeySelfServiceLoginBeforeHooks                             = "selfservice.flows.login.before.hooks"
+	ViperKeySelfServiceLoginRestrictions                            = "selfservice.flows.login.restrictions"
 	ViperKeySelfServiceErrorUI                                      = "selfservice.flows.error.ui_url"
 	ViperKeySelfServiceLogoutBrowserDefaultReturnTo                 = "selfservice.flows.logout.after." + DefaultBrowserReturnURL
+	ViperKeySelfServiceLogoutFrontChannelURLs                       = "selfservice.flows.logout.front_channel.urls"
+	ViperKeySelfServiceLogoutFrontChannelTimeout                    = "selfservice.flows.logout.front_channel.timeout"
+	ViperKeySelfServiceLogoutBackChannelURLs                        = "selfservice.flows.logout.back_channel.urls"
+	ViperKeySelfServiceLogoutBackChannelTimeout                     = "selfservice.flows.logout.back_channel.timeout"
+	ViperKeySelfServiceLogoutBackChannelMaxRetries                  = "selfservice.flows.logout.back_channel.max_retries"
+	ViperKeySelfServiceLogoutBackChannelRetryWait                   = "selfservice.flows.logout.back_channel.retry_wait"
 	ViperKeySelfServiceSettingsURL                                  = "selfservice.flows.settings.ui_url"
 	ViperKeySelfServiceSettingsAfter                                = "selfservice.flows.settings.after"
 	ViperKeySelfServiceSettingsRequestLifespan                      = "selfservice.flows.settings.lifespan"
@@ -76,18 +119,77 @@ const (
 	ViperKeySelfServiceVerificationBrowserDefaultReturnTo           = "selfservice.flows.verification.after." + DefaultBrowserReturnURL
 	ViperKeyDefaultIdentitySchemaURL                                = "identity.default_schema_url"
 	ViperKeyIdentitySchemas                                         = "identity.schemas"
+	ViperKeyDefaultIdentitySchemaVersion                            = "identity.default_schema_version"
+	ViperKeyDefaultIdentitySchemaMigrationURL                       = "identity.default_schema_migration_url"
+	ViperKeyIdentitySoftDeleteRetention                             = "identity.soft_delete.retention"
+	ViperKeyIdentitySoftDeletePurgeInterval                         = "identity.soft_delete.purge_interval"
+	ViperKeyIdentitySchemaValidationMaxPatternComplexity            = "identity.schema_validation.max_pattern_complexity"
+	ViperKeyIdentityGrowthStatsEnabled                              = "identity.growth_stats.enabled"
+	ViperKeyIdentityGrowthStatsCohortDays                           = "identity.growth_stats.cohort_days"
 	ViperKeyHasherArgon2ConfigMemory                                = "hashers.argon2.memory"
 	ViperKeyHasherArgon2ConfigIterations                            = "hashers.argon2.iterations"
 	ViperKeyHasherArgon2ConfigParallelism                           = "hashers.argon2.parallelism"
 	ViperKeyHasherArgon2ConfigSaltLength                            = "hashers.argon2.salt_length"
 	ViperKeyHasherArgon2ConfigKeyLength                             = "hashers.argon2.key_length"
+	ViperKeyHasherAlgorithm                                         = "hashers.algorithm"
+	ViperKeyHasherBcryptConfigCost                                  = "hashers.bcrypt.cost"
+	ViperKeyHasherShadowAlgorithm                                   = "hashers.shadow.algorithm"
 	ViperKeyPasswordMaxBreaches                                     = "password.max_breaches"
 	ViperKeyIgnoreNetworkErrors                                     = "password.ignore_network_errors"
 	ViperKeyVersion                                                 = "version"
+	ViperKeyPasswordExternalStoreURL                                = "selfservice.methods.password.config.external_store.url"
+	ViperKeyPasswordExternalStoreBearerToken                        = "selfservice.methods.password.config.external_store.bearer_token"
+	ViperKeyPasswordExternalStoreMapperURL                          = "selfservice.methods.password.config.external_store.mapper_url"
+	ViperKeyPasswordExternalStoreTimeout                            = "selfservice.methods.password.config.external_store.timeout"
+	ViperKeyLDAPURL                                                 = "selfservice.methods.ldap.config.url"
+	ViperKeyLDAPStartTLS                                            = "selfservice.methods.ldap.config.start_tls"
+	ViperKeyLDAPCACertificateURL                                    = "selfservice.methods.ldap.config.ca_certificate_url"
+	ViperKeyLDAPBindDN                                              = "selfservice.methods.ldap.config.bind_dn"
+	ViperKeyLDAPBindPassword                                        = "selfservice.methods.ldap.config.bind_password"
+	ViperKeyLDAPSearchBase                                          = "selfservice.methods.ldap.config.search.base"
+	ViperKeyLDAPSearchFilter                                        = "selfservice.methods.ldap.config.search.filter"
+	ViperKeyLDAPSearchAttributes                                    = "selfservice.methods.ldap.config.search.attributes"
+	ViperKeyLDAPUniqueIDAttribute                                   = "selfservice.methods.ldap.config.unique_id_attribute"
+	ViperKeyLDAPProvisioningEnabled                                 = "selfservice.methods.ldap.config.provisioning.enabled"
+	ViperKeyLDAPProvisioningMapperURL                               = "selfservice.methods.ldap.config.provisioning.mapper_url"
+	ViperKeyLDAPPoolSize                                            = "selfservice.methods.ldap.config.pool.size"
+	ViperKeyLDAPTimeout                                             = "selfservice.methods.ldap.config.timeout"
+	ViperKeyKerberosKeytabURL                                       = "selfservice.methods.kerberos.config.keytab_url"
+	ViperKeyKerberosServicePrincipal                                = "selfservice.methods.kerberos.config.service_principal"
+	ViperKeyKerberosRealms                                          = "selfservice.methods.kerberos.config.realms"
+	ViperKeyKerberosIdentifierFormat                                = "selfservice.methods.kerberos.config.identifier.format"
+	ViperKeyKerberosIdentifierCredentialsType                       = "selfservice.methods.kerberos.config.identifier.credentials_type"
+	ViperKeyKerberosMaxClockSkew                                    = "selfservice.methods.kerberos.config.max_clock_skew"
+	ViperKeyMTLSHeader                                              = "selfservice.methods.mtls.config.header"
+	ViperKeyMTLSHeaderFormat                                        = "selfservice.methods.mtls.config.header_format"
+	ViperKeyMTLSCACertificateURL                                    = "selfservice.methods.mtls.config.ca_certificate_url"
+	ViperKeyMTLSTrustedProxies                                      = "selfservice.methods.mtls.config.trusted_proxies"
+	ViperKeyMTLSIdentifierSource                                    = "selfservice.methods.mtls.config.identifier.source"
+	ViperKeyMTLSIdentifierCredentialsType                           = "selfservice.methods.mtls.config.identifier.credentials_type"
+	ViperKeyEmailsTrait                                             = "selfservice.methods.emails.config.trait"
+	ViperKeyCodeLifespan                                            = "selfservice.methods.code.config.lifespan"
+	ViperKeyCodeMaxAttempts                                         = "selfservice.methods.code.config.max_attempts"
+	ViperKeyBotScoreHeader                                          = "selfservice.bot_score.header"
+	ViperKeyBotScoreTrustedProxies                                  = "selfservice.bot_score.trusted_proxies"
+	ViperKeyBotScoreRateLimitBelow                                  = "selfservice.bot_score.rate_limit.below"
+	ViperKeyBotScoreRateLimitMaxRequests                            = "selfservice.bot_score.rate_limit.max_requests"
+	ViperKeyBotScoreRateLimitWindow                                 = "selfservice.bot_score.rate_limit.window"
+	ViperKeySCIMEnabled                                             = "scim.enabled"
+	ViperKeySCIMToken                                               = "scim.token"
+	ViperKeySCIMSchemaID                                            = "scim.schema_id"
+	ViperKeySCIMMapperURL                                           = "scim.mapper_url"
+	ViperKeyOAuth2ProviderURL                                       = "oauth2_provider.url"
+	ViperKeyConfigVersionsEnabled                                   = "config_versions.enabled"
+	ViperKeyConfigVersionsSyncInterval                              = "config_versions.sync_interval"
+	ViperKeyBreakGlassEnabled                                       = "break_glass.enabled"
+	ViperKeyBreakGlassScope                                         = "break_glass.scope"
+	ViperKeyBreakGlassMaxDuration                                   = "break_glass.max_duration"
+	ViperKeyBreakGlassNotificationURL                               = "break_glass.notification_url"
 	Argon2DefaultMemory                                      uint32 = 4 * 1024 * 1024
 	Argon2DefaultIterations                                  uint32 = 4
 	Argon2DefaultSaltLength                                  uint32 = 16
 	Argon2DefaultKeyLength                                   uint32 = 32
+	BcryptDefaultCost                                        uint32 = 12
 )
 
 type (
@@ -98,6 +200,9 @@ type (
 		SaltLength  uint32 `json:"salt_length"`
 		KeyLength   uint32 `json:"key_length"`
 	}
+	HasherBcryptConfig struct {
+		Cost uint32 `json:"cost"`
+	}
 	SelfServiceHook struct {
 		Name   string          `json:"hook"`
 		Config json.RawMessage `json:"config"`
@@ -105,19 +210,196 @@ type (
 	SelfServiceStrategy struct {
 		Enabled bool            `json:"enabled"`
 		Config  json.RawMessage `json:"config"`
+
+		// UI contains the hints for rendering the strategy or nil if none are configured.
+		UI *SelfServiceStrategyUI `json:"ui"`
+	}
+	// SelfServiceStrategyUI contains hints for rendering a strategy in the forms of self-service flows.
+	SelfServiceStrategyUI struct {
+		Label string `json:"label"`
+		Icon  string `json:"icon"`
+		Order int    `json:"order"`
 	}
 	SchemaConfig struct {
 		ID  string `json:"id"`
 		URL string `json:"url"`
+
+		// Version is the version of the schema. Schemas without a version have version 1.
+		Version int `json:"version,omitempty"`
+
+		// MigrationURL points to a Jsonnet file migrating traits from the previous version of the schema.
+		MigrationURL string `json:"migration_url,omitempty"`
+
+		// LoginRestrictions replace the global login restrictions for identities using this schema.
+		LoginRestrictions *LoginRestrictions `json:"login_restrictions,omitempty"`
+
+		// RegistrationEnabled replaces the global registration switch for identities using this schema.
+		RegistrationEnabled *bool `json:"registration_enabled,omitempty"`
+	}
+	// LoginRestrictions limit when identities can sign in. Existing sessions are not affected.
+	LoginRestrictions struct {
+		// Timezone is the IANA time zone the allowed hours are given in. Defaults to UTC.
+		Timezone string `json:"timezone"`
+
+		// AllowedHours are the times at which identities may sign in. If empty, signing in is allowed at any time.
+		AllowedHours []LoginAllowedHours `json:"allowed_hours"`
+
+		// MaintenanceWindows are periods during which nobody may sign in.
+		MaintenanceWindows []LoginMaintenanceWindow `json:"maintenance_windows"`
+	}
+	// CourierSMSRoute decides how text messages to the phone numbers of some countries are sent.
+	CourierSMSRoute struct {
+		// Countries are the ISO 3166-1 alpha-2 codes of the countries the route is used for. Routes without
+		// countries are used for all countries.
+		Countries []string `json:"countries"`
+
+		// Provider is the ID of the SMS provider sending the messages.
+		Provider string `json:"provider"`
+
+		// SenderID is shown to the recipient as the sender of the messages.
+		SenderID string `json:"sender_id"`
+
+		// TemplateVariant selects the variant of the message templates, for example a language.
+		TemplateVariant string `json:"template_variant"`
+	}
+	// CourierSMSProvider is an HTTP endpoint text messages are sent to.
+	CourierSMSProvider struct {
+		// ID is the ID routes use to refer to the provider.
+		ID string `json:"id"`
+
+		// URL is the endpoint the messages are POSTed to as JSON.
+		URL string `json:"url"`
+
+		// Headers are added to every request sent to the provider, for example to authenticate.
+		Headers map[string]string `json:"headers"`
+	}
+	// SelfServiceReturnToRule chooses where browsers are sent after completing a flow. Conditions which are not set
+	// match every flow.
+	SelfServiceReturnToRule struct {
+		// Flow is the flow type, for example `registration`.
+		Flow string `json:"flow"`
+
+		// Method is the method used to complete the flow, for example `password`.
+		Method string `json:"method"`
+
+		// SchemaID is the ID of the identity's traits schema.
+		SchemaID string `json:"schema_id"`
+
+		// ReturnTo is a regular expression matched against the requested `return_to` URL, which is empty if none was
+		// requested.
+		ReturnTo string `json:"return_to"`
+
+		// FirstLogin matches flows which issued the first session of the identity.
+		FirstLogin *bool `json:"first_login"`
+
+		// To is the URL the browser is sent to if the rule matches.
+		To string `json:"to"`
+	}
+	// SelfServiceLogoutFrontChannelURL is the front-channel logout URL of an application sharing the session cookie.
+	SelfServiceLogoutFrontChannelURL struct {
+		// URL is loaded by the browser when the user logs out.
+		URL string `json:"url"`
+
+		// Type is `iframe` if the URL is loaded in a hidden iframe, or `image` if it is loaded as an image.
+		Type string `json:"type"`
+	}
+	// SelfServiceLogoutBackChannelURL is the back-channel logout URL of an application which is notified when sessions
+	// are revoked.
+	SelfServiceLogoutBackChannelURL struct {
+		// URL receives the notifications as HTTP POST requests.
+		URL string `json:"url"`
+
+		// Secret is used to sign the notifications with HMAC-SHA256.
+		Secret string `json:"secret"`
+	}
+	LoginAllowedHours struct {
+		// Days are the weekdays, for example `mon`, the hours apply to. If empty, they apply to every day.
+		Days []string `json:"days"`
+
+		// From and To are local times formatted as `15:04`. If To is not after From, the hours span midnight.
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	LoginMaintenanceWindow struct {
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
 	}
 	PasswordPolicyConfig struct {
 		MaxBreaches         uint `json:"max_breaches"`
 		IgnoreNetworkErrors bool `json:"ignore_network_errors"`
 	}
+	PasswordExternalStoreConfig struct {
+		URL         string
+		BearerToken string
+		MapperURL   string
+		Timeout     time.Duration
+	}
+	LDAPConfig struct {
+		URL               string
+		StartTLS          bool
+		CACertificateURL  string
+		BindDN            string
+		BindPassword      string
+		SearchBase        string
+		SearchFilter      string
+		SearchAttributes  []string
+		UniqueIDAttribute string
+		Provisioning      bool
+		MapperURL         string
+		PoolSize          int
+		Timeout           time.Duration
+	}
+	KerberosConfig struct {
+		KeytabURL                 string
+		ServicePrincipal          string
+		Realms                    []string
+		IdentifierFormat          string
+		IdentifierCredentialsType string
+		MaxClockSkew              time.Duration
+	}
+	MTLSConfig struct {
+		Header                    string
+		HeaderFormat              string
+		CACertificateURL          string
+		TrustedProxies            []string
+		IdentifierSource          string
+		IdentifierCredentialsType string
+	}
+	CodeConfig struct {
+		Lifespan    time.Duration
+		MaxAttempts int
+	}
+	BotScoreConfig struct {
+		Header               string
+		TrustedProxies       []string
+		RateLimitBelow       int
+		RateLimitMaxRequests int
+		RateLimitWindow      time.Duration
+	}
+	SCIMConfig struct {
+		Token     string
+		SchemaID  string
+		MapperURL string
+	}
+	BreakGlassConfig struct {
+		Scope           string
+		MaxDuration     time.Duration
+		NotificationURL *url.URL
+	}
 	SchemaConfigs []SchemaConfig
 	Provider      struct {
-		l *logrusx.Logger
-		p *configx.Provider
+		l         *logrusx.Logger
+		schema    []byte
+		validator *jsonschema.Schema
+
+		// lock guards p, which is replaced when a configuration version is applied.
+		lock sync.RWMutex
+		p    *configx.Provider
+
+		// loaded is the configuration loaded from files, flags, and environment variables. It is reloaded when a
+		// file changes. appliedOver is the hash of the loaded configuration at the time a version was applied.
+		loaded      *configx.Provider
+		appliedOver string
 	}
 )
 
@@ -156,11 +438,13 @@ func New(l *logrusx.Logger, opts ...configx.OptionModifier) (*Provider, error) {
 		return nil, err
 	}
 
+	c := &Provider{l: l, schema: schema}
 	opts = append([]configx.OptionModifier{
 		configx.WithStderrValidationReporter(),
-		configx.OmitKeysFromTracing("dsn", "secrets.default", "secrets.cookie", "client_secret"),
-		configx.WithImmutables("serve", "profiling", "log"),
+		configx.OmitKeysFromTracing("dsn", "secrets.default", "secrets.cookie", "client_secret", ViperKeyLDAPBindPassword, ViperKeyPasswordExternalStoreBearerToken, ViperKeyKerberosKeytabURL, ViperKeySCIMToken),
+		configx.WithImmutables(immutables...),
 		configx.WithLogrusWatcher(l),
+		configx.AttachWatcher(c.reloaded),
 	}, opts...)
 
 	p, err := configx.New(schema, opts...)
@@ -168,11 +452,24 @@ func New(l *logrusx.Logger, opts ...configx.OptionModifier) (*Provider, error) {
 		return nil, err
 	}
 
+	validator, err := compileSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
 	l.UseConfig(p)
-	return &Provider{l: l, p: p}, nil
+	c.p, c.loaded, c.validator = p, p, validator
+	c.warnDeprecatedOptions()
+	return c, nil
 }
 
 func (p *Provider) Source() *configx.Provider {
+	return p.source()
+}
+
+func (p *Provider) source() *configx.Provider {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
 	return p.p
 }
 
@@ -188,7 +485,7 @@ func (p *Provider) CORS(iface string) (cors.Options, bool) {
 }
 
 func (p *Provider) cors(prefix string) (cors.Options, bool) {
-	return p.p.CORS(prefix, cors.Options{
+	return p.source().CORS(prefix, cors.Options{
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
 		AllowedHeaders:   []string{"Authorization", "Content-Type", "Cookie"},
 		ExposedHeaders:   []string{"Content-Type", "Set-Cookie"},
@@ -197,47 +494,96 @@ func (p *Provider) cors(prefix string) (cors.Options, bool) {
 }
 
 func (p *Provider) Set(key string, value interface{}) error {
-	return p.p.Set(key, value)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.p != p.loaded {
+		// A configuration version was applied, the value is set on it as well.
+		k := p.p.Koanf.Copy()
+		if err := k.Load(confmap.Provider(map[string]interface{}{key: value}, configx.Delimiter), nil); err != nil {
+			return errors.WithStack(err)
+		}
+		next := *p.p
+		next.Koanf = k
+		p.p = &next
+	}
+
+	if err := p.loaded.Set(key, value); err != nil {
+		return err
+	}
+	if p.p == p.loaded {
+		return nil
+	}
+
+	// The version stays applied until the configuration files change.
+	loaded, err := snapshot(p.loaded)
+	if err != nil {
+		return err
+	}
+	p.appliedOver = Hash(loaded)
+	return nil
 }
 
 func (p *Provider) MustSet(key string, value interface{}) {
-	if err := p.p.Set(key, value); err != nil {
+	if err := p.Set(key, value); err != nil {
 		p.l.WithError(err).Fatalf("Unable to set \"%s\" to \"%s\".", key, value)
 	}
 }
 
 func (p *Provider) SessionDomain() string {
-	return p.p.String(ViperKeySessionDomain)
+	return p.source().String(ViperKeySessionDomain)
 }
 
 func (p *Provider) SessionPath() string {
-	return p.p.String(ViperKeySessionPath)
+	return p.source().String(ViperKeySessionPath)
+}
+
+// SessionCookieEncryption returns true if session and continuity cookies are encrypted in addition to being signed.
+func (p *Provider) SessionCookieEncryption() bool {
+	return p.source().Bool(ViperKeySessionCookieEncryption)
 }
 
 func (p *Provider) HasherArgon2() *HasherArgon2Config {
 	// warn about usage of default values and point to the docs
 	// warning will require https://github.com/ory/viper/issues/19
 	return &HasherArgon2Config{
-		Memory:      uint32(p.p.IntF(ViperKeyHasherArgon2ConfigMemory, int(Argon2DefaultMemory))),
-		Iterations:  uint32(p.p.IntF(ViperKeyHasherArgon2ConfigIterations, int(Argon2DefaultIterations))),
-		Parallelism: uint8(p.p.IntF(ViperKeyHasherArgon2ConfigParallelism, int(Argon2DefaultParallelism))),
-		SaltLength:  uint32(p.p.IntF(ViperKeyHasherArgon2ConfigSaltLength, int(Argon2DefaultSaltLength))),
-		KeyLength:   uint32(p.p.IntF(ViperKeyHasherArgon2ConfigKeyLength, int(Argon2DefaultKeyLength))),
+		Memory:      uint32(p.source().IntF(ViperKeyHasherArgon2ConfigMemory, int(Argon2DefaultMemory))),
+		Iterations:  uint32(p.source().IntF(ViperKeyHasherArgon2ConfigIterations, int(Argon2DefaultIterations))),
+		Parallelism: uint8(p.source().IntF(ViperKeyHasherArgon2ConfigParallelism, int(Argon2DefaultParallelism))),
+		SaltLength:  uint32(p.source().IntF(ViperKeyHasherArgon2ConfigSaltLength, int(Argon2DefaultSaltLength))),
+		KeyLength:   uint32(p.source().IntF(ViperKeyHasherArgon2ConfigKeyLength, int(Argon2DefaultKeyLength))),
 	}
 }
 
+func (p *Provider) HasherBcrypt() *HasherBcryptConfig {
+	return &HasherBcryptConfig{
+		Cost: uint32(p.source().IntF(ViperKeyHasherBcryptConfigCost, int(BcryptDefaultCost))),
+	}
+}
+
+// HasherAlgorithm returns the algorithm used to hash new passwords.
+func (p *Provider) HasherAlgorithm() string {
+	return p.source().StringF(ViperKeyHasherAlgorithm, "argon2")
+}
+
+// HasherShadowAlgorithm returns the algorithm evaluated in shadow mode alongside HasherAlgorithm or an empty
+// string if shadow mode is disabled.
+func (p *Provider) HasherShadowAlgorithm() string {
+	return p.source().String(ViperKeyHasherShadowAlgorithm)
+}
+
 func (p *Provider) listenOn(key string) string {
 	fb := 4433
 	if key == "admin" {
 		fb = 4434
 	}
 
-	port := p.p.IntF("serve."+key+".port", fb)
+	port := p.source().IntF("serve."+key+".port", fb)
 	if port < 1 {
 		p.l.Fatalf("serve.%s.port can not be zero or negative", key)
 	}
 
-	return fmt.Sprintf("%s:%d", p.p.String("serve."+key+".host"), port)
+	return fmt.Sprintf("%s:%d", p.source().String("serve."+key+".host"), port)
 }
 
 func (p *Provider) DefaultIdentityTraitsSchemaURL() *url.URL {
@@ -246,16 +592,18 @@ func (p *Provider) DefaultIdentityTraitsSchemaURL() *url.URL {
 
 func (p *Provider) IdentityTraitsSchemas() SchemaConfigs {
 	ds := SchemaConfig{
-		ID:  DefaultIdentityTraitsSchemaID,
-		URL: p.DefaultIdentityTraitsSchemaURL().String(),
+		ID:           DefaultIdentityTraitsSchemaID,
+		URL:          p.DefaultIdentityTraitsSchemaURL().String(),
+		Version:      p.source().Int(ViperKeyDefaultIdentitySchemaVersion),
+		MigrationURL: p.source().String(ViperKeyDefaultIdentitySchemaMigrationURL),
 	}
 
-	if !p.p.Exists(ViperKeyIdentitySchemas) {
+	if !p.source().Exists(ViperKeyIdentitySchemas) {
 		return SchemaConfigs{ds}
 	}
 
 	var ss SchemaConfigs
-	out, err := p.p.Marshal(kjson.Parser())
+	out, err := p.source().Marshal(kjson.Parser())
 	if err != nil {
 		p.l.WithError(err).Fatalf("Unable to dencode values from %s.", ViperKeyIdentitySchemas)
 		return SchemaConfigs{ds}
@@ -274,6 +622,28 @@ func (p *Provider) IdentityTraitsSchemas() SchemaConfigs {
 	return append(ss, ds)
 }
 
+func (p *Provider) IdentitySoftDeleteRetention() time.Duration {
+	return p.source().DurationF(ViperKeyIdentitySoftDeleteRetention, time.Hour*24*30)
+}
+
+func (p *Provider) IdentitySoftDeletePurgeInterval() time.Duration {
+	return p.source().DurationF(ViperKeyIdentitySoftDeletePurgeInterval, time.Hour)
+}
+
+func (p *Provider) IdentitySchemaValidationMaxPatternComplexity() int {
+	return p.source().IntF(ViperKeyIdentitySchemaValidationMaxPatternComplexity, 2000)
+}
+
+func (p *Provider) IdentityGrowthStatsEnabled() bool {
+	return p.source().Bool(ViperKeyIdentityGrowthStatsEnabled)
+}
+
+// IdentityGrowthStatsCohortDays returns for how many days after their registration the retention of identities is
+// tracked.
+func (p *Provider) IdentityGrowthStatsCohortDays() int {
+	return p.source().IntF(ViperKeyIdentityGrowthStatsCohortDays, 30)
+}
+
 func (p *Provider) AdminListenOn() string {
 	return p.listenOn("admin")
 }
@@ -283,7 +653,7 @@ func (p *Provider) PublicListenOn() string {
 }
 
 func (p *Provider) DSN() string {
-	dsn := p.p.String(ViperKeyDSN)
+	dsn := p.source().String(ViperKeyDSN)
 
 	if dsn == "memory" {
 		return DefaultSQLiteMemoryDSN
@@ -297,6 +667,11 @@ func (p *Provider) DSN() string {
 	return ""
 }
 
+// DatabaseUUIDVersion returns the UUID version (4 or 7) used for new identity, session, and flow IDs.
+func (p *Provider) DatabaseUUIDVersion() int {
+	return p.source().IntF(ViperKeyDatabaseUUIDVersion, 4)
+}
+
 func (p *Provider) DisableAPIFlowEnforcement() bool {
 	if p.IsInsecureDevMode() && os.Getenv("DEV_DISABLE_API_FLOW_ENFORCEMENT") == "true" {
 		p.l.Warn("Because \"DEV_DISABLE_API_FLOW_ENFORCEMENT=true\" and the \"--dev\" flag are set, self-service API flows will no longer check if the interaction is actually a browser flow. This is very dangerous as it allows bypassing of anti-CSRF measures, leaving the deployment highly vulnerable. This option should only be used for automated testing and never come close to real user data anywhere.")
@@ -306,28 +681,37 @@ func (p *Provider) DisableAPIFlowEnforcement() bool {
 }
 
 func (p *Provider) SelfServiceFlowVerificationEnabled() bool {
-	return p.p.Bool(ViperKeySelfServiceVerificationEnabled)
+	return p.source().Bool(ViperKeySelfServiceVerificationEnabled)
 }
 
 func (p *Provider) SelfServiceFlowRecoveryEnabled() bool {
-	return p.p.Bool(ViperKeySelfServiceRecoveryEnabled)
+	return p.source().Bool(ViperKeySelfServiceRecoveryEnabled)
 }
 
 func (p *Provider) SelfServiceFlowLoginBeforeHooks() []SelfServiceHook {
 	return p.selfServiceHooks(ViperKeySelfServiceLoginBeforeHooks)
 }
 
+// SelfServiceFlowRegistrationEnabled returns whether identities of the given schema may sign up using the public
+// registration endpoints.
+func (p *Provider) SelfServiceFlowRegistrationEnabled(schemaID string) bool {
+	if sc, err := p.IdentityTraitsSchemas().FindSchemaByID(schemaID); err == nil && sc.RegistrationEnabled != nil {
+		return *sc.RegistrationEnabled
+	}
+	return p.source().BoolF(ViperKeySelfServiceRegistrationEnabled, true)
+}
+
 func (p *Provider) SelfServiceFlowRegistrationBeforeHooks() []SelfServiceHook {
 	return p.selfServiceHooks(ViperKeySelfServiceRegistrationBeforeHooks)
 }
 
 func (p *Provider) selfServiceHooks(key string) []SelfServiceHook {
 	var hooks []SelfServiceHook
-	if !p.p.Exists(key) {
+	if !p.source().Exists(key) {
 		return []SelfServiceHook{}
 	}
 
-	out, err := p.p.Marshal(kjson.Parser())
+	out, err := p.source().Marshal(kjson.Parser())
 	if err != nil {
 		p.l.WithError(err).Fatalf("Unable to decode values from configuration key: %s", key)
 	}
@@ -350,6 +734,65 @@ func (p *Provider) selfServiceHooks(key string) []SelfServiceHook {
 	return hooks
 }
 
+// SelfServiceFlowLoginRestrictions returns the login restrictions for identities of the given schema or nil if
+// signing in is not restricted.
+func (p *Provider) SelfServiceFlowLoginRestrictions(schemaID string) *LoginRestrictions {
+	if sc, err := p.IdentityTraitsSchemas().FindSchemaByID(schemaID); err == nil && sc.LoginRestrictions != nil {
+		return sc.LoginRestrictions
+	}
+
+	if !p.source().Exists(ViperKeySelfServiceLoginRestrictions) {
+		return nil
+	}
+
+	out, err := p.source().Marshal(kjson.Parser())
+	if err != nil {
+		p.l.WithError(err).Fatalf("Unable to decode values from configuration key: %s", ViperKeySelfServiceLoginRestrictions)
+	}
+
+	config := gjson.GetBytes(out, ViperKeySelfServiceLoginRestrictions).Raw
+	if len(config) == 0 {
+		return nil
+	}
+
+	var restrictions LoginRestrictions
+	if err := jsonx.NewStrictDecoder(bytes.NewBufferString(config)).Decode(&restrictions); err != nil {
+		p.l.WithError(err).Fatalf("Unable to encode value \"%s\" from configuration key: %s", config, ViperKeySelfServiceLoginRestrictions)
+	}
+
+	// The key exists as soon as the default time zone is applied.
+	if len(restrictions.AllowedHours) == 0 && len(restrictions.MaintenanceWindows) == 0 {
+		return nil
+	}
+
+	return &restrictions
+}
+
+// SelfServiceBrowserReturnToRules returns the rules choosing where browsers are sent after completing a flow in the
+// order they are evaluated.
+func (p *Provider) SelfServiceBrowserReturnToRules() []SelfServiceReturnToRule {
+	if !p.source().Exists(ViperKeySelfServiceBrowserReturnToRules) {
+		return []SelfServiceReturnToRule{}
+	}
+
+	out, err := p.source().Marshal(kjson.Parser())
+	if err != nil {
+		p.l.WithError(err).Fatalf("Unable to decode values from configuration key: %s", ViperKeySelfServiceBrowserReturnToRules)
+	}
+
+	config := gjson.GetBytes(out, ViperKeySelfServiceBrowserReturnToRules).Raw
+	if len(config) == 0 {
+		return []SelfServiceReturnToRule{}
+	}
+
+	var rules []SelfServiceReturnToRule
+	if err := jsonx.NewStrictDecoder(bytes.NewBufferString(config)).Decode(&rules); err != nil {
+		p.l.WithError(err).Fatalf("Unable to encode value \"%s\" from configuration key: %s", config, ViperKeySelfServiceBrowserReturnToRules)
+	}
+
+	return rules
+}
+
 func (p *Provider) SelfServiceFlowLoginAfterHooks(strategy string) []SelfServiceHook {
 	return p.selfServiceHooks(HookStrategyKey(ViperKeySelfServiceLoginAfter, strategy))
 }
@@ -364,23 +807,33 @@ func (p *Provider) SelfServiceFlowRegistrationAfterHooks(strategy string) []Self
 
 func (p *Provider) SelfServiceStrategy(strategy string) *SelfServiceStrategy {
 	config := "{}"
-	out, err := p.p.Marshal(kjson.Parser())
+	var ui *SelfServiceStrategyUI
+	out, err := p.source().Marshal(kjson.Parser())
 	if err != nil {
 		p.l.WithError(err).Warn("Unable to marshal self service strategy configuration.")
-	} else if c := gjson.GetBytes(out,
-		fmt.Sprintf("%s.%s.config", ViperKeySelfServiceStrategyConfig, strategy)).Raw; len(c) > 0 {
-		config = c
+	} else {
+		if c := gjson.GetBytes(out,
+			fmt.Sprintf("%s.%s.config", ViperKeySelfServiceStrategyConfig, strategy)).Raw; len(c) > 0 {
+			config = c
+		}
+		if c := gjson.GetBytes(out,
+			fmt.Sprintf("%s.%s.ui", ViperKeySelfServiceStrategyConfig, strategy)).Raw; len(c) > 0 {
+			if err := json.Unmarshal([]byte(c), &ui); err != nil {
+				p.l.WithError(err).Warn("Unable to decode self service strategy user interface hints.")
+			}
+		}
 	}
 
 	enabledKey := fmt.Sprintf("%s.%s.enabled", ViperKeySelfServiceStrategyConfig, strategy)
 	s := &SelfServiceStrategy{
-		Enabled: p.p.Bool(enabledKey),
+		Enabled: p.source().Bool(enabledKey),
 		Config:  json.RawMessage(config),
+		UI:      ui,
 	}
 
 	// The default value can easily be overwritten by setting e.g. `{"selfservice": "null"}` which means that
 	// we need to forcibly set these values here:
-	if !p.p.Exists(enabledKey) {
+	if !p.source().Exists(enabledKey) {
 		switch strategy {
 		case "password":
 			fallthrough
@@ -399,7 +852,7 @@ func (p *Provider) SelfServiceStrategy(strategy string) *SelfServiceStrategy {
 }
 
 func (p *Provider) SecretsDefault() [][]byte {
-	secrets := p.p.Strings(ViperKeySecretsDefault)
+	secrets := p.source().Strings(ViperKeySecretsDefault)
 
 	if len(secrets) == 0 {
 		secrets = []string{uuid.New().String()}
@@ -415,7 +868,7 @@ func (p *Provider) SecretsDefault() [][]byte {
 }
 
 func (p *Provider) SecretsSession() [][]byte {
-	secrets := p.p.Strings(ViperKeySecretsCookie)
+	secrets := p.source().Strings(ViperKeySecretsCookie)
 	if len(secrets) == 0 {
 		return p.SecretsDefault()
 	}
@@ -433,9 +886,9 @@ func (p *Provider) SelfServiceBrowserDefaultReturnTo() *url.URL {
 }
 
 func (p *Provider) guessBaseURL(keyHost, keyPort string, defaultPort int) *url.URL {
-	port := p.p.IntF(keyPort, defaultPort)
+	port := p.source().IntF(keyPort, defaultPort)
 
-	host := p.p.String(keyHost)
+	host := p.source().String(keyHost)
 	if host == "0.0.0.0" || len(host) == 0 {
 		var err error
 		host, err = os.Hostname()
@@ -454,7 +907,7 @@ func (p *Provider) guessBaseURL(keyHost, keyPort string, defaultPort int) *url.U
 }
 
 func (p *Provider) baseURL(keyURL, keyHost, keyPort string, defaultPort int) *url.URL {
-	switch t := p.p.Get(keyURL).(type) {
+	switch t := p.source().Get(keyURL).(type) {
 	case *url.URL:
 		return t
 	case url.URL:
@@ -484,6 +937,17 @@ func (p *Provider) CourierSMTPURL() *url.URL {
 	return p.parseURIOrFail(ViperKeyCourierSMTPURL)
 }
 
+// ErrorsProblemJSONEnabled returns true if errors are written as RFC 7807 problem details.
+func (p *Provider) ErrorsProblemJSONEnabled() bool {
+	return p.source().Bool(ViperKeyErrorsProblemJSONEnabled)
+}
+
+// ErrorsProblemJSONTypeBaseURL returns the URL which the type URIs of problem details are relative to. The error code
+// is appended as the fragment.
+func (p *Provider) ErrorsProblemJSONTypeBaseURL() *url.URL {
+	return p.source().RequestURIF(ViperKeyErrorsProblemJSONTypeBaseURL, urlx.ParseOrPanic("https://www.ory.sh/kratos/docs/reference/errors"))
+}
+
 func (p *Provider) SelfServiceFlowLoginUI() *url.URL {
 	return p.parseURIOrFail(ViperKeySelfServiceLoginUI)
 }
@@ -506,15 +970,100 @@ func (p *Provider) SelfServiceFlowRecoveryUI() *url.URL {
 
 // SessionLifespan returns nil when the value is not set.
 func (p *Provider) SessionLifespan() time.Duration {
-	return p.p.DurationF(ViperKeySessionLifespan, time.Hour*24)
+	return p.source().DurationF(ViperKeySessionLifespan, time.Hour*24)
+}
+
+// SessionJWTJWKSURL returns the location of the JSON Web Key Set used to sign session JWTs. Session JWTs are
+// disabled if it is empty.
+func (p *Provider) SessionJWTJWKSURL() string {
+	return p.source().String(ViperKeySessionJWTJWKSURL)
+}
+
+func (p *Provider) SessionJWTLifespan() time.Duration {
+	return p.source().DurationF(ViperKeySessionJWTLifespan, time.Minute*5)
+}
+
+func (p *Provider) SessionJanitorInterval() time.Duration {
+	return p.source().DurationF(ViperKeySessionJanitorInterval, time.Minute)
+}
+
+func (p *Provider) SessionDisclosureLifespan() time.Duration {
+	return p.source().DurationF(ViperKeySessionDisclosureLifespan, time.Minute*5)
+}
+
+// SessionLocationHeader returns the request header from which the location of the client is recorded when a session
+// is issued, for example `CF-IPCountry`. The location is not recorded if it is empty.
+func (p *Provider) SessionLocationHeader() string {
+	return p.source().String(ViperKeySessionLocationHeader)
+}
+
+// SessionRememberMeEnabled returns true if users choose whether their session is remembered when signing in.
+func (p *Provider) SessionRememberMeEnabled() bool {
+	return p.source().Bool(ViperKeySessionRememberMeEnabled)
+}
+
+// SessionRememberMeLifespan returns the lifespan of sessions which users asked to be remembered.
+func (p *Provider) SessionRememberMeLifespan() time.Duration {
+	return p.source().DurationF(ViperKeySessionRememberMeLifespan, time.Hour*24*30)
+}
+
+// SessionCacheEnabled returns true if sessions resolved by their token are cached in memory.
+func (p *Provider) SessionCacheEnabled() bool {
+	return p.source().Bool(ViperKeySessionCacheEnabled)
+}
+
+// SessionCacheTTL returns how long sessions are cached in memory. Other instances may return stale sessions for this
+// long after a session was revoked or its identity was updated.
+func (p *Provider) SessionCacheTTL() time.Duration {
+	return p.source().DurationF(ViperKeySessionCacheTTL, time.Second*10)
+}
+
+// SessionSlidingExpirationEnabled returns true if checking a session extends its lifespan.
+func (p *Provider) SessionSlidingExpirationEnabled() bool {
+	return p.source().Bool(ViperKeySessionSlidingExpirationEnabled)
+}
+
+// SessionMaxLifespan returns the duration after authenticating past which sliding expiration does not extend
+// sessions.
+func (p *Provider) SessionMaxLifespan() time.Duration {
+	return p.source().DurationF(ViperKeySessionSlidingExpirationMaxLifespan, time.Hour*24*30)
+}
+
+// SessionRotationEnabled returns true if sessions are replaced by new ones with a new ID and token when their
+// privileges change, for example after the password was changed, and the replaced sessions are revoked.
+func (p *Provider) SessionRotationEnabled() bool {
+	return p.source().BoolF(ViperKeySessionRotationEnabled, true)
+}
+
+func (p *Provider) SelfServiceOIDCHealthCheckInterval() time.Duration {
+	return p.source().DurationF(ViperKeySelfServiceOIDCHealthCheckInterval, 5*time.Minute)
+}
+
+func (p *Provider) SelfServiceOIDCHealthCheckTimeout() time.Duration {
+	return p.source().DurationF(ViperKeySelfServiceOIDCHealthCheckTimeout, 10*time.Second)
 }
 
 func (p *Provider) SessionPersistentCookie() bool {
-	return p.p.Bool(ViperKeySessionPersistentCookie)
+	return p.source().Bool(ViperKeySessionPersistentCookie)
+}
+
+func (p *Provider) SelfServiceAPIFlowBinding() bool {
+	return p.source().Bool(ViperKeySelfServiceAPIFlowBinding)
+}
+
+func (p *Provider) SelfServiceFlowStatsEnabled() bool {
+	return p.source().Bool(ViperKeySelfServiceFlowStatsEnabled)
 }
 
+// SelfServiceBrowserWhitelistedReturnToDomains returns the URLs browsers may be returned to. The deprecated key
+// `selfservice.whitelisted_return_urls` is used if `selfservice.allowed_return_urls` is not set.
 func (p *Provider) SelfServiceBrowserWhitelistedReturnToDomains() (us []url.URL) {
-	src := p.p.Strings(ViperKeyURLsWhitelistedReturnToDomains)
+	key := ViperKeyURLsAllowedReturnToDomains
+	if !p.source().Exists(key) {
+		key = ViperKeyURLsWhitelistedReturnToDomains
+	}
+
+	src := p.source().Strings(key)
 	for k, u := range src {
 		if len(u) == 0 {
 			continue
@@ -522,7 +1071,7 @@ func (p *Provider) SelfServiceBrowserWhitelistedReturnToDomains() (us []url.URL)
 
 		parsed, err := url.ParseRequestURI(u)
 		if err != nil {
-			p.l.WithError(err).Warnf("Ignoring URL \"%s\" from configuration key \"%s.%d\".", u, ViperKeyURLsWhitelistedReturnToDomains, k)
+			p.l.WithError(err).Warnf("Ignoring URL \"%s\" from configuration key \"%s.%d\".", u, key, k)
 			continue
 		}
 
@@ -533,40 +1082,176 @@ func (p *Provider) SelfServiceBrowserWhitelistedReturnToDomains() (us []url.URL)
 }
 
 func (p *Provider) SelfServiceFlowLoginRequestLifespan() time.Duration {
-	return p.p.DurationF(ViperKeySelfServiceLoginRequestLifespan, time.Hour)
+	return p.source().DurationF(ViperKeySelfServiceLoginRequestLifespan, time.Hour)
+}
+
+// SelfServiceFlowExpiredGracePeriod returns how long browsers may still submit login and registration flows after they
+// expired.
+func (p *Provider) SelfServiceFlowExpiredGracePeriod() time.Duration {
+	return p.source().DurationF(ViperKeySelfServiceFlowExpiredGracePeriod, 0)
 }
 
 func (p *Provider) SelfServiceFlowSettingsFlowLifespan() time.Duration {
-	return p.p.DurationF(ViperKeySelfServiceSettingsRequestLifespan, time.Hour)
+	return p.source().DurationF(ViperKeySelfServiceSettingsRequestLifespan, time.Hour)
 }
 
 func (p *Provider) SelfServiceFlowRegistrationRequestLifespan() time.Duration {
-	return p.p.DurationF(ViperKeySelfServiceRegistrationRequestLifespan, time.Hour)
+	return p.source().DurationF(ViperKeySelfServiceRegistrationRequestLifespan, time.Hour)
+}
+
+// SelfServiceFlowLogoutFrontChannelURLs returns the front-channel logout URLs which browsers load when users log out.
+func (p *Provider) SelfServiceFlowLogoutFrontChannelURLs() []SelfServiceLogoutFrontChannelURL {
+	if !p.source().Exists(ViperKeySelfServiceLogoutFrontChannelURLs) {
+		return []SelfServiceLogoutFrontChannelURL{}
+	}
+
+	out, err := p.source().Marshal(kjson.Parser())
+	if err != nil {
+		p.l.WithError(err).Fatalf("Unable to decode values from configuration key: %s", ViperKeySelfServiceLogoutFrontChannelURLs)
+	}
+
+	config := gjson.GetBytes(out, ViperKeySelfServiceLogoutFrontChannelURLs).Raw
+	if len(config) == 0 {
+		return []SelfServiceLogoutFrontChannelURL{}
+	}
+
+	var urls []SelfServiceLogoutFrontChannelURL
+	if err := jsonx.NewStrictDecoder(bytes.NewBufferString(config)).Decode(&urls); err != nil {
+		p.l.WithError(err).Fatalf("Unable to encode value \"%s\" from configuration key: %s", config, ViperKeySelfServiceLogoutFrontChannelURLs)
+	}
+
+	for k := range urls {
+		if urls[k].Type == "" {
+			urls[k].Type = "iframe"
+		}
+	}
+	return urls
+}
+
+// SelfServiceFlowLogoutFrontChannelTimeout returns how long browsers wait for the front-channel logout URLs to load
+// before they are redirected.
+func (p *Provider) SelfServiceFlowLogoutFrontChannelTimeout() time.Duration {
+	return p.source().DurationF(ViperKeySelfServiceLogoutFrontChannelTimeout, time.Second*5)
+}
+
+// SelfServiceFlowLogoutBackChannelURLs returns the back-channel logout URLs which are notified when sessions are
+// revoked.
+func (p *Provider) SelfServiceFlowLogoutBackChannelURLs() []SelfServiceLogoutBackChannelURL {
+	if !p.source().Exists(ViperKeySelfServiceLogoutBackChannelURLs) {
+		return []SelfServiceLogoutBackChannelURL{}
+	}
+
+	out, err := p.source().Marshal(kjson.Parser())
+	if err != nil {
+		p.l.WithError(err).Fatalf("Unable to decode values from configuration key: %s", ViperKeySelfServiceLogoutBackChannelURLs)
+	}
+
+	config := gjson.GetBytes(out, ViperKeySelfServiceLogoutBackChannelURLs).Raw
+	if len(config) == 0 {
+		return []SelfServiceLogoutBackChannelURL{}
+	}
+
+	var urls []SelfServiceLogoutBackChannelURL
+	if err := jsonx.NewStrictDecoder(bytes.NewBufferString(config)).Decode(&urls); err != nil {
+		p.l.WithError(err).Fatalf("Unable to encode value \"%s\" from configuration key: %s", config, ViperKeySelfServiceLogoutBackChannelURLs)
+	}
+	return urls
+}
+
+// SelfServiceFlowLogoutBackChannelTimeout returns how long a single delivery of a back-channel logout notification may
+// take.
+func (p *Provider) SelfServiceFlowLogoutBackChannelTimeout() time.Duration {
+	return p.source().DurationF(ViperKeySelfServiceLogoutBackChannelTimeout, time.Second*10)
+}
+
+// SelfServiceFlowLogoutBackChannelMaxRetries returns how often a failed delivery of a back-channel logout notification
+// is retried.
+func (p *Provider) SelfServiceFlowLogoutBackChannelMaxRetries() int {
+	return p.source().IntF(ViperKeySelfServiceLogoutBackChannelMaxRetries, 5)
+}
+
+// SelfServiceFlowLogoutBackChannelRetryWait returns how long to wait before the first retry. The wait doubles with
+// every further retry.
+func (p *Provider) SelfServiceFlowLogoutBackChannelRetryWait() time.Duration {
+	return p.source().DurationF(ViperKeySelfServiceLogoutBackChannelRetryWait, time.Second)
 }
 
 func (p *Provider) SelfServiceFlowLogoutRedirectURL() *url.URL {
-	return p.p.RequestURIF(ViperKeySelfServiceLogoutBrowserDefaultReturnTo, p.SelfServiceBrowserDefaultReturnTo())
+	return p.source().RequestURIF(ViperKeySelfServiceLogoutBrowserDefaultReturnTo, p.SelfServiceBrowserDefaultReturnTo())
 }
 
 func (p *Provider) CourierSMTPFrom() string {
-	return p.p.StringF(ViperKeyCourierSMTPFrom, "noreply@kratos.ory.sh")
+	return p.source().StringF(ViperKeyCourierSMTPFrom, "noreply@kratos.ory.sh")
 }
 
 func (p *Provider) CourierTemplatesRoot() string {
-	return p.p.StringF(ViperKeyCourierTemplatesPath, "/courier/template/templates")
+	return p.source().StringF(ViperKeyCourierTemplatesPath, "/courier/template/templates")
+}
+
+// CourierTemplateVariables returns the paths of the identity fields which the template may use. Templates may not
+// use any identity fields per default.
+func (p *Provider) CourierTemplateVariables(template string) []string {
+	return p.source().Strings(ViperKeyCourierTemplateVariables + "." + template)
+}
+
+// CourierSMSRoutes returns the routes of text messages. The first route matching the country of the recipient is used.
+func (p *Provider) CourierSMSRoutes() []CourierSMSRoute {
+	if !p.source().Exists(ViperKeyCourierSMSRoutes) {
+		return []CourierSMSRoute{}
+	}
+
+	out, err := p.source().Marshal(kjson.Parser())
+	if err != nil {
+		p.l.WithError(err).Fatalf("Unable to decode values from configuration key: %s", ViperKeyCourierSMSRoutes)
+	}
+
+	config := gjson.GetBytes(out, ViperKeyCourierSMSRoutes).Raw
+	if len(config) == 0 {
+		return []CourierSMSRoute{}
+	}
+
+	var routes []CourierSMSRoute
+	if err := jsonx.NewStrictDecoder(bytes.NewBufferString(config)).Decode(&routes); err != nil {
+		p.l.WithError(err).Fatalf("Unable to encode value \"%s\" from configuration key: %s", config, ViperKeyCourierSMSRoutes)
+	}
+
+	return routes
+}
+
+func (p *Provider) CourierSMSProviders() []CourierSMSProvider {
+	if !p.source().Exists(ViperKeyCourierSMSProviders) {
+		return []CourierSMSProvider{}
+	}
+
+	out, err := p.source().Marshal(kjson.Parser())
+	if err != nil {
+		p.l.WithError(err).Fatalf("Unable to decode values from configuration key: %s", ViperKeyCourierSMSProviders)
+	}
+
+	config := gjson.GetBytes(out, ViperKeyCourierSMSProviders).Raw
+	if len(config) == 0 {
+		return []CourierSMSProvider{}
+	}
+
+	var providers []CourierSMSProvider
+	if err := jsonx.NewStrictDecoder(bytes.NewBufferString(config)).Decode(&providers); err != nil {
+		p.l.WithError(err).Fatalf("Unable to encode value \"%s\" from configuration key: %s", config, ViperKeyCourierSMSProviders)
+	}
+
+	return providers
 }
 
 func (p *Provider) parseURIOrFail(key string) *url.URL {
-	u, err := url.ParseRequestURI(p.p.String(key))
+	u, err := url.ParseRequestURI(p.source().String(key))
 	if err != nil {
 		p.l.WithError(errors.WithStack(err)).
-			Fatalf("Configuration value from key %s is not a valid URL: %s", key, p.p.String(key))
+			Fatalf("Configuration value from key %s is not a valid URL: %s", key, p.source().String(key))
 	}
 	return u
 }
 
 func (p *Provider) Tracing() *tracing.Config {
-	return p.p.TracingConfig("ORY Kratos")
+	return p.source().TracingConfig("ORY Kratos")
 }
 
 func (p *Provider) IsInsecureDevMode() bool {
@@ -578,27 +1263,58 @@ func (p *Provider) SelfServiceFlowVerificationUI() *url.URL {
 }
 
 func (p *Provider) SelfServiceFlowVerificationRequestLifespan() time.Duration {
-	return p.p.DurationF(ViperKeySelfServiceVerificationRequestLifespan, time.Hour)
+	return p.source().DurationF(ViperKeySelfServiceVerificationRequestLifespan, time.Hour)
 }
 
 func (p *Provider) SelfServiceFlowVerificationReturnTo(defaultReturnTo *url.URL) *url.URL {
-	return p.p.RequestURIF(ViperKeySelfServiceVerificationBrowserDefaultReturnTo, defaultReturnTo)
+	return p.source().RequestURIF(ViperKeySelfServiceVerificationBrowserDefaultReturnTo, defaultReturnTo)
 }
 
 func (p *Provider) SelfServiceFlowRecoveryReturnTo() *url.URL {
-	return p.p.RequestURIF(ViperKeySelfServiceRecoveryBrowserDefaultReturnTo, p.SelfServiceBrowserDefaultReturnTo())
+	return p.source().RequestURIF(ViperKeySelfServiceRecoveryBrowserDefaultReturnTo, p.SelfServiceBrowserDefaultReturnTo())
 }
 
 func (p *Provider) SelfServiceFlowRecoveryRequestLifespan() time.Duration {
-	return p.p.DurationF(ViperKeySelfServiceRecoveryRequestLifespan, time.Hour)
+	return p.source().DurationF(ViperKeySelfServiceRecoveryRequestLifespan, time.Hour)
 }
 
 func (p *Provider) SelfServiceFlowSettingsPrivilegedSessionMaxAge() time.Duration {
-	return p.p.DurationF(ViperKeySelfServiceSettingsPrivilegedAuthenticationAfter, time.Hour)
+	return p.source().DurationF(ViperKeySelfServiceSettingsPrivilegedAuthenticationAfter, time.Hour)
 }
 
 func (p *Provider) SessionSameSiteMode() http.SameSite {
-	switch p.p.StringF(ViperKeySessionSameSite, "Lax") {
+	return p.sameSiteMode(ViperKeySessionSameSite, "Lax")
+}
+
+// SessionCookiePartitioned returns true if the session cookie is partitioned by the top-level site (CHIPS).
+func (p *Provider) SessionCookiePartitioned() bool {
+	return p.source().Bool(ViperKeySessionCookiePartitioned)
+}
+
+// CSRFCookieSameSiteMode returns the SameSite attribute of the anti-CSRF cookie, or 0 if it is not configured.
+func (p *Provider) CSRFCookieSameSiteMode() http.SameSite {
+	return p.sameSiteMode(ViperKeyCookiesCSRFSameSite, "")
+}
+
+// CSRFCookiePartitioned returns true if the anti-CSRF cookie is partitioned by the top-level site (CHIPS).
+func (p *Provider) CSRFCookiePartitioned() bool {
+	return p.source().Bool(ViperKeyCookiesCSRFPartitioned)
+}
+
+// ContinuityCookieSameSiteMode returns the SameSite attribute of the cookie keeping state between the steps of
+// self-service flows.
+func (p *Provider) ContinuityCookieSameSiteMode() http.SameSite {
+	return p.sameSiteMode(ViperKeyCookiesContinuitySameSite, "Lax")
+}
+
+// ContinuityCookiePartitioned returns true if the cookie keeping state between the steps of self-service flows is
+// partitioned by the top-level site (CHIPS).
+func (p *Provider) ContinuityCookiePartitioned() bool {
+	return p.source().Bool(ViperKeyCookiesContinuityPartitioned)
+}
+
+func (p *Provider) sameSiteMode(key, fallback string) http.SameSite {
+	switch p.source().StringF(key, fallback) {
 	case "Lax":
 		return http.SameSiteLaxMode
 	case "Strict":
@@ -618,30 +1334,157 @@ func (p *Provider) SelfServiceFlowRegistrationReturnTo(strategy string) *url.URL
 }
 
 func (p *Provider) SelfServiceFlowSettingsReturnTo(strategy string, defaultReturnTo *url.URL) *url.URL {
-	return p.p.RequestURIF(
+	return p.source().RequestURIF(
 		ViperKeySelfServiceSettingsAfter+"."+strategy+"."+DefaultBrowserReturnURL,
-		p.p.RequestURIF(ViperKeySelfServiceSettingsAfter+"."+DefaultBrowserReturnURL,
+		p.source().RequestURIF(ViperKeySelfServiceSettingsAfter+"."+DefaultBrowserReturnURL,
 			defaultReturnTo,
 		),
 	)
 }
 
 func (p *Provider) selfServiceReturnTo(key string, strategy string) *url.URL {
-	return p.p.RequestURIF(
+	return p.source().RequestURIF(
 		key+"."+strategy+"."+DefaultBrowserReturnURL,
-		p.p.RequestURIF(key+"."+DefaultBrowserReturnURL,
+		p.source().RequestURIF(key+"."+DefaultBrowserReturnURL,
 			p.SelfServiceBrowserDefaultReturnTo(),
 		),
 	)
 }
 
 func (p *Provider) ConfigVersion() string {
-	return p.p.StringF(ViperKeyVersion, UnknownVersion)
+	return p.source().StringF(ViperKeyVersion, UnknownVersion)
 }
 
 func (p *Provider) PasswordPolicyConfig() *PasswordPolicyConfig {
 	return &PasswordPolicyConfig{
-		MaxBreaches:         uint(p.p.Int(ViperKeyPasswordMaxBreaches)),
-		IgnoreNetworkErrors: p.p.BoolF(ViperKeyIgnoreNetworkErrors, true),
+		MaxBreaches:         uint(p.source().Int(ViperKeyPasswordMaxBreaches)),
+		IgnoreNetworkErrors: p.source().BoolF(ViperKeyIgnoreNetworkErrors, true),
+	}
+}
+
+// SelfServiceStrategyPasswordExternalStore returns the settings of the external identity store which verifies the
+// passwords of identities not yet imported.
+func (p *Provider) SelfServiceStrategyPasswordExternalStore() *PasswordExternalStoreConfig {
+	return &PasswordExternalStoreConfig{
+		URL:         p.source().String(ViperKeyPasswordExternalStoreURL),
+		BearerToken: p.source().String(ViperKeyPasswordExternalStoreBearerToken),
+		MapperURL:   p.source().String(ViperKeyPasswordExternalStoreMapperURL),
+		Timeout:     p.source().DurationF(ViperKeyPasswordExternalStoreTimeout, 10*time.Second),
+	}
+}
+
+// SelfServiceStrategyLDAP returns the connection, search, and provisioning settings of the LDAP strategy.
+func (p *Provider) SelfServiceStrategyLDAP() *LDAPConfig {
+	return &LDAPConfig{
+		URL:               p.source().String(ViperKeyLDAPURL),
+		StartTLS:          p.source().Bool(ViperKeyLDAPStartTLS),
+		CACertificateURL:  p.source().String(ViperKeyLDAPCACertificateURL),
+		BindDN:            p.source().String(ViperKeyLDAPBindDN),
+		BindPassword:      p.source().String(ViperKeyLDAPBindPassword),
+		SearchBase:        p.source().String(ViperKeyLDAPSearchBase),
+		SearchFilter:      p.source().StringF(ViperKeyLDAPSearchFilter, "(uid={identifier})"),
+		SearchAttributes:  p.source().Strings(ViperKeyLDAPSearchAttributes),
+		UniqueIDAttribute: p.source().String(ViperKeyLDAPUniqueIDAttribute),
+		Provisioning:      p.source().Bool(ViperKeyLDAPProvisioningEnabled),
+		MapperURL:         p.source().String(ViperKeyLDAPProvisioningMapperURL),
+		PoolSize:          p.source().IntF(ViperKeyLDAPPoolSize, 4),
+		Timeout:           p.source().DurationF(ViperKeyLDAPTimeout, 10*time.Second),
 	}
 }
+
+// SelfServiceStrategyKerberos returns the keytab and principal mapping settings of the Kerberos strategy.
+func (p *Provider) SelfServiceStrategyKerberos() *KerberosConfig {
+	return &KerberosConfig{
+		KeytabURL:                 p.source().String(ViperKeyKerberosKeytabURL),
+		ServicePrincipal:          p.source().String(ViperKeyKerberosServicePrincipal),
+		Realms:                    p.source().Strings(ViperKeyKerberosRealms),
+		IdentifierFormat:          p.source().StringF(ViperKeyKerberosIdentifierFormat, "username"),
+		IdentifierCredentialsType: p.source().StringF(ViperKeyKerberosIdentifierCredentialsType, "password"),
+		MaxClockSkew:              p.source().DurationF(ViperKeyKerberosMaxClockSkew, 5*time.Minute),
+	}
+}
+
+// SelfServiceStrategyMTLS returns the client certificate header and identifier mapping settings of the mTLS strategy.
+func (p *Provider) SelfServiceStrategyMTLS() *MTLSConfig {
+	return &MTLSConfig{
+		Header:                    p.source().StringF(ViperKeyMTLSHeader, "X-Client-Cert"),
+		HeaderFormat:              p.source().StringF(ViperKeyMTLSHeaderFormat, "pem"),
+		CACertificateURL:          p.source().String(ViperKeyMTLSCACertificateURL),
+		TrustedProxies:            p.source().Strings(ViperKeyMTLSTrustedProxies),
+		IdentifierSource:          p.source().StringF(ViperKeyMTLSIdentifierSource, "subject_common_name"),
+		IdentifierCredentialsType: p.source().StringF(ViperKeyMTLSIdentifierCredentialsType, "password"),
+	}
+}
+
+// SelfServiceStrategyEmailsTrait returns the path of the traits' array of email addresses managed by the emails
+// strategy. The first address is the primary one.
+func (p *Provider) SelfServiceStrategyEmailsTrait() string {
+	return p.source().StringF(ViperKeyEmailsTrait, "emails")
+}
+
+// SelfServiceStrategyCode returns how long verification codes are valid and how often a code may be entered
+// incorrectly before it is invalidated.
+func (p *Provider) SelfServiceStrategyCode() *CodeConfig {
+	return &CodeConfig{
+		Lifespan:    p.source().DurationF(ViperKeyCodeLifespan, 15*time.Minute),
+		MaxAttempts: p.source().IntF(ViperKeyCodeMaxAttempts, 5),
+	}
+}
+
+// BotScore returns the header and trusted proxies used to read the bot score computed by a CDN, and the rate limit
+// of likely automated clients.
+func (p *Provider) BotScore() *BotScoreConfig {
+	return &BotScoreConfig{
+		Header:               p.source().String(ViperKeyBotScoreHeader),
+		TrustedProxies:       p.source().Strings(ViperKeyBotScoreTrustedProxies),
+		RateLimitBelow:       p.source().Int(ViperKeyBotScoreRateLimitBelow),
+		RateLimitMaxRequests: p.source().Int(ViperKeyBotScoreRateLimitMaxRequests),
+		RateLimitWindow:      p.source().DurationF(ViperKeyBotScoreRateLimitWindow, time.Minute),
+	}
+}
+
+func (p *Provider) SCIMEnabled() bool {
+	return p.source().Bool(ViperKeySCIMEnabled)
+}
+
+// SCIM returns the authentication and identity mapping settings of the SCIM provisioning endpoints.
+func (p *Provider) SCIM() *SCIMConfig {
+	return &SCIMConfig{
+		Token:     p.source().String(ViperKeySCIMToken),
+		SchemaID:  p.source().StringF(ViperKeySCIMSchemaID, DefaultIdentityTraitsSchemaID),
+		MapperURL: p.source().String(ViperKeySCIMMapperURL),
+	}
+}
+
+// OAuth2ProviderURL returns the admin URL of the ORY Hydra instance whose login and consent challenges are handled,
+// or nil if none is configured.
+func (p *Provider) OAuth2ProviderURL() *url.URL {
+	if len(p.source().String(ViperKeyOAuth2ProviderURL)) == 0 {
+		return nil
+	}
+	return p.parseURIOrFail(ViperKeyOAuth2ProviderURL)
+}
+
+func (p *Provider) BreakGlassEnabled() bool {
+	return p.source().Bool(ViperKeyBreakGlassEnabled)
+}
+
+// BreakGlass returns the settings of break-glass grants. NotificationURL is nil if no notifications are sent.
+func (p *Provider) BreakGlass() *BreakGlassConfig {
+	c := &BreakGlassConfig{
+		Scope:       p.source().StringF(ViperKeyBreakGlassScope, "kratos:break_glass"),
+		MaxDuration: p.source().DurationF(ViperKeyBreakGlassMaxDuration, time.Hour),
+	}
+	if len(p.source().String(ViperKeyBreakGlassNotificationURL)) > 0 {
+		c.NotificationURL = p.parseURIOrFail(ViperKeyBreakGlassNotificationURL)
+	}
+	return c
+}
+
+func (p *Provider) ConfigVersionsEnabled() bool {
+	return p.source().Bool(ViperKeyConfigVersionsEnabled)
+}
+
+func (p *Provider) ConfigVersionsSyncInterval() time.Duration {
+	return p.source().DurationF(ViperKeyConfigVersionsSyncInterval, time.Second*30)
+}
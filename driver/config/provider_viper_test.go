@@ -1,12 +1,19 @@
 package config_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
 	"github.com/ory/x/configx"
 
 	"github.com/ory/x/logrusx"
@@ -17,6 +24,7 @@ import (
 	"github.com/ory/kratos/driver/config"
 
 	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -226,6 +234,9 @@ func TestViperProvider(t *testing.T) {
 		t.Run("group=hashers", func(t *testing.T) {
 			assert.Equal(t, &config.HasherArgon2Config{Memory: 1048576, Iterations: 2, Parallelism: 4,
 				SaltLength: 16, KeyLength: 32}, p.HasherArgon2())
+			assert.Equal(t, "argon2", p.HasherAlgorithm())
+			assert.Equal(t, &config.HasherBcryptConfig{Cost: config.BcryptDefaultCost}, p.HasherBcrypt())
+			assert.Empty(t, p.HasherShadowAlgorithm())
 		})
 
 		t.Run("group=set_provider_by_json", func(t *testing.T) {
@@ -390,6 +401,34 @@ func TestViperProvider_ReturnTo(t *testing.T) {
 	assert.Equal(t, "https://www.ory.sh/verification", p.SelfServiceFlowVerificationReturnTo(urlx.ParseOrPanic("https://www.ory.sh/")).String())
 }
 
+func TestViperProvider_CourierSMSRoutes(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+	assert.Empty(t, p.CourierSMSRoutes())
+
+	p.MustSet(config.ViperKeyCourierSMSRoutes, []map[string]interface{}{
+		{"countries": []string{"DE", "AT"}, "provider": "a", "sender_id": "Example", "template_variant": "de"},
+		{"provider": "b"},
+	})
+	assert.Equal(t, []config.CourierSMSRoute{
+		{Countries: []string{"DE", "AT"}, Provider: "a", SenderID: "Example", TemplateVariant: "de"},
+		{Provider: "b"},
+	}, p.CourierSMSRoutes())
+}
+
+func TestViperProvider_CourierSMSProviders(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+	assert.Empty(t, p.CourierSMSProviders())
+
+	p.MustSet(config.ViperKeyCourierSMSProviders, []map[string]interface{}{
+		{"id": "a", "url": "https://sms.example.org/messages", "headers": map[string]string{"Authorization": "Bearer secret"}},
+		{"id": "b", "url": "https://sms.example.com/"},
+	})
+	assert.Equal(t, []config.CourierSMSProvider{
+		{ID: "a", URL: "https://sms.example.org/messages", Headers: map[string]string{"Authorization": "Bearer secret"}},
+		{ID: "b", URL: "https://sms.example.com/"},
+	}, p.CourierSMSProviders())
+}
+
 func TestViperProvider_DSN(t *testing.T) {
 	t.Run("case=dsn: memory", func(t *testing.T) {
 		p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
@@ -420,3 +459,491 @@ func TestViperProvider_DSN(t *testing.T) {
 		assert.NotEqual(t, 0, exitCode)
 	})
 }
+
+func TestViperProvider_SelfServiceStrategyUI(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+	assert.Nil(t, p.SelfServiceStrategy("password").UI)
+
+	p.MustSet(config.ViperKeySelfServiceStrategyConfig+".password.ui", map[string]interface{}{"label": "Password", "icon": "key", "order": 2})
+	assert.Equal(t, &config.SelfServiceStrategyUI{Label: "Password", Icon: "key", Order: 2}, p.SelfServiceStrategy("password").UI)
+}
+
+func TestViperProvider_SelfServiceStrategyLDAP(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+
+	t.Run("case=defaults", func(t *testing.T) {
+		c := p.SelfServiceStrategyLDAP()
+		assert.Equal(t, "(uid={identifier})", c.SearchFilter)
+		assert.Equal(t, 4, c.PoolSize)
+		assert.Equal(t, 10*time.Second, c.Timeout)
+		assert.False(t, c.StartTLS)
+		assert.False(t, c.Provisioning)
+	})
+
+	t.Run("case=configured", func(t *testing.T) {
+		p.MustSet(config.ViperKeySelfServiceStrategyConfig+".ldap.config", map[string]interface{}{
+			"url":       "ldap://ldap.example.com",
+			"start_tls": true,
+			"bind_dn":   "cn=kratos,dc=example,dc=com",
+			"search": map[string]interface{}{
+				"base":       "ou=people,dc=example,dc=com",
+				"filter":     "(mail={identifier})",
+				"attributes": []string{"mail"},
+			},
+			"provisioning": map[string]interface{}{"enabled": true, "mapper_url": "file://ldap.jsonnet"},
+			"pool":         map[string]interface{}{"size": 0},
+			"timeout":      "1s",
+		})
+
+		c := p.SelfServiceStrategyLDAP()
+		assert.Equal(t, "ldap://ldap.example.com", c.URL)
+		assert.True(t, c.StartTLS)
+		assert.Equal(t, "cn=kratos,dc=example,dc=com", c.BindDN)
+		assert.Equal(t, "ou=people,dc=example,dc=com", c.SearchBase)
+		assert.Equal(t, "(mail={identifier})", c.SearchFilter)
+		assert.Equal(t, []string{"mail"}, c.SearchAttributes)
+		assert.True(t, c.Provisioning)
+		assert.Equal(t, "file://ldap.jsonnet", c.MapperURL)
+		assert.Equal(t, 0, c.PoolSize)
+		assert.Equal(t, time.Second, c.Timeout)
+	})
+}
+
+func TestViperProvider_SelfServiceStrategyKerberos(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+
+	t.Run("case=defaults", func(t *testing.T) {
+		c := p.SelfServiceStrategyKerberos()
+		assert.Equal(t, "username", c.IdentifierFormat)
+		assert.Equal(t, "password", c.IdentifierCredentialsType)
+		assert.Equal(t, 5*time.Minute, c.MaxClockSkew)
+		assert.Empty(t, c.Realms)
+	})
+
+	t.Run("case=configured", func(t *testing.T) {
+		p.MustSet(config.ViperKeySelfServiceStrategyConfig+".kerberos.config", map[string]interface{}{
+			"keytab_url":        "file:///etc/kratos/http.keytab",
+			"service_principal": "HTTP/intranet.example.com",
+			"realms":            []string{"EXAMPLE.COM"},
+			"identifier":        map[string]interface{}{"format": "principal", "credentials_type": "ldap"},
+			"max_clock_skew":    "1m",
+		})
+
+		c := p.SelfServiceStrategyKerberos()
+		assert.Equal(t, "file:///etc/kratos/http.keytab", c.KeytabURL)
+		assert.Equal(t, "HTTP/intranet.example.com", c.ServicePrincipal)
+		assert.Equal(t, []string{"EXAMPLE.COM"}, c.Realms)
+		assert.Equal(t, "principal", c.IdentifierFormat)
+		assert.Equal(t, "ldap", c.IdentifierCredentialsType)
+		assert.Equal(t, time.Minute, c.MaxClockSkew)
+	})
+}
+
+func TestViperProvider_SelfServiceStrategyMTLS(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+
+	t.Run("case=defaults", func(t *testing.T) {
+		c := p.SelfServiceStrategyMTLS()
+		assert.Equal(t, "X-Client-Cert", c.Header)
+		assert.Equal(t, "pem", c.HeaderFormat)
+		assert.Equal(t, "subject_common_name", c.IdentifierSource)
+		assert.Equal(t, "password", c.IdentifierCredentialsType)
+		assert.Empty(t, c.TrustedProxies)
+	})
+
+	t.Run("case=configured", func(t *testing.T) {
+		p.MustSet(config.ViperKeySelfServiceStrategyConfig+".mtls.config", map[string]interface{}{
+			"header":             "X-Forwarded-Tls-Client-Cert",
+			"header_format":      "sha256",
+			"ca_certificate_url": "file:///etc/kratos/client-ca.pem",
+			"trusted_proxies":    []string{"10.0.0.0/8"},
+			"identifier":         map[string]interface{}{"source": "sha256", "credentials_type": "ldap"},
+		})
+
+		c := p.SelfServiceStrategyMTLS()
+		assert.Equal(t, "X-Forwarded-Tls-Client-Cert", c.Header)
+		assert.Equal(t, "sha256", c.HeaderFormat)
+		assert.Equal(t, "file:///etc/kratos/client-ca.pem", c.CACertificateURL)
+		assert.Equal(t, []string{"10.0.0.0/8"}, c.TrustedProxies)
+		assert.Equal(t, "sha256", c.IdentifierSource)
+		assert.Equal(t, "ldap", c.IdentifierCredentialsType)
+	})
+
+	t.Run("case=validation", func(t *testing.T) {
+		for k, tc := range []struct {
+			config    map[string]interface{}
+			expectErr bool
+		}{
+			{config: map[string]interface{}{"enabled": true}, expectErr: true},
+			{config: map[string]interface{}{"enabled": true, "config": map[string]interface{}{
+				"ca_certificate_url": "file:///etc/kratos/client-ca.pem",
+			}}, expectErr: true},
+			{config: map[string]interface{}{"enabled": true, "config": map[string]interface{}{
+				"ca_certificate_url": "file:///etc/kratos/client-ca.pem",
+				"trusted_proxies":    []string{},
+			}}, expectErr: true},
+			{config: map[string]interface{}{"enabled": true, "config": map[string]interface{}{
+				"trusted_proxies": []string{"10.0.0.0/8"},
+			}}, expectErr: true},
+			{config: map[string]interface{}{"enabled": true, "config": map[string]interface{}{
+				"header_format":   "pem",
+				"trusted_proxies": []string{"10.0.0.0/8"},
+			}}, expectErr: true},
+			{config: map[string]interface{}{"enabled": true, "config": map[string]interface{}{
+				"ca_certificate_url": "file:///etc/kratos/client-ca.pem",
+				"trusted_proxies":    []string{"10.0.0.0/8"},
+			}}},
+			{config: map[string]interface{}{"enabled": true, "config": map[string]interface{}{
+				"header_format":   "sha256",
+				"trusted_proxies": []string{"10.0.0.0/8"},
+				"identifier":      map[string]interface{}{"source": "sha256"},
+			}}},
+			{config: map[string]interface{}{"enabled": false}},
+		} {
+			t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+				_, err := config.New(logrusx.New("", ""),
+					configx.WithConfigFiles("../../internal/.kratos.yaml"),
+					configx.WithValue(config.ViperKeySelfServiceStrategyConfig+".mtls", tc.config))
+				if tc.expectErr {
+					require.Error(t, err)
+				} else {
+					require.NoError(t, err)
+				}
+			})
+		}
+	})
+}
+
+func TestViperProvider_SelfServiceStrategyCode(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+
+	t.Run("case=defaults", func(t *testing.T) {
+		c := p.SelfServiceStrategyCode()
+		assert.Equal(t, 15*time.Minute, c.Lifespan)
+		assert.Equal(t, 5, c.MaxAttempts)
+		assert.False(t, p.SelfServiceStrategy("code").Enabled)
+	})
+
+	t.Run("case=configured", func(t *testing.T) {
+		p.MustSet(config.ViperKeyCodeLifespan, "5m")
+		p.MustSet(config.ViperKeyCodeMaxAttempts, 3)
+
+		c := p.SelfServiceStrategyCode()
+		assert.Equal(t, 5*time.Minute, c.Lifespan)
+		assert.Equal(t, 3, c.MaxAttempts)
+	})
+}
+
+func TestViperProvider_SelfServiceFlowLoginRestrictions(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+	p.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+
+	t.Run("case=defaults", func(t *testing.T) {
+		assert.Nil(t, p.SelfServiceFlowLoginRestrictions(config.DefaultIdentityTraitsSchemaID))
+	})
+
+	p.MustSet(config.ViperKeySelfServiceLoginRestrictions, map[string]interface{}{
+		"timezone":      "Europe/Berlin",
+		"allowed_hours": []map[string]interface{}{{"days": []string{"mon", "fri"}, "from": "08:00", "to": "18:00"}},
+		"maintenance_windows": []map[string]interface{}{
+			{"start": "2030-01-01T00:00:00Z", "end": "2030-01-01T02:00:00Z"},
+		},
+	})
+	p.MustSet(config.ViperKeyIdentitySchemas, []map[string]interface{}{
+		{"id": "operator", "url": "file://stub/operator.schema.json", "login_restrictions": map[string]interface{}{}},
+		{"id": "customer", "url": "file://stub/customer.schema.json"},
+	})
+
+	t.Run("case=global", func(t *testing.T) {
+		for _, id := range []string{config.DefaultIdentityTraitsSchemaID, "customer"} {
+			r := p.SelfServiceFlowLoginRestrictions(id)
+			require.NotNil(t, r)
+			assert.Equal(t, "Europe/Berlin", r.Timezone)
+			assert.Equal(t, []config.LoginAllowedHours{{Days: []string{"mon", "fri"}, From: "08:00", To: "18:00"}}, r.AllowedHours)
+			require.Len(t, r.MaintenanceWindows, 1)
+			assert.Equal(t, time.Date(2030, 1, 1, 2, 0, 0, 0, time.UTC), r.MaintenanceWindows[0].End.UTC())
+		}
+	})
+
+	t.Run("case=schema override", func(t *testing.T) {
+		r := p.SelfServiceFlowLoginRestrictions("operator")
+		require.NotNil(t, r)
+		assert.Empty(t, r.AllowedHours)
+		assert.Empty(t, r.MaintenanceWindows)
+	})
+}
+
+func TestViperProvider_SelfServiceFlowRegistrationEnabled(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+	p.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+
+	t.Run("case=defaults", func(t *testing.T) {
+		assert.True(t, p.SelfServiceFlowRegistrationEnabled(config.DefaultIdentityTraitsSchemaID))
+	})
+
+	p.MustSet(config.ViperKeySelfServiceRegistrationEnabled, false)
+	p.MustSet(config.ViperKeyIdentitySchemas, []map[string]interface{}{
+		{"id": "customer", "url": "file://stub/customer.schema.json", "registration_enabled": true},
+		{"id": "employee", "url": "file://stub/employee.schema.json"},
+	})
+
+	t.Run("case=global", func(t *testing.T) {
+		assert.False(t, p.SelfServiceFlowRegistrationEnabled(config.DefaultIdentityTraitsSchemaID))
+		assert.False(t, p.SelfServiceFlowRegistrationEnabled("employee"))
+	})
+
+	t.Run("case=schema override", func(t *testing.T) {
+		assert.True(t, p.SelfServiceFlowRegistrationEnabled("customer"))
+	})
+}
+
+func TestViperProvider_ErrorsProblemJSON(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+
+	t.Run("case=defaults", func(t *testing.T) {
+		assert.False(t, p.ErrorsProblemJSONEnabled())
+		assert.Equal(t, "https://www.ory.sh/kratos/docs/reference/errors", p.ErrorsProblemJSONTypeBaseURL().String())
+	})
+
+	t.Run("case=configured", func(t *testing.T) {
+		p.MustSet(config.ViperKeyErrorsProblemJSONEnabled, true)
+		p.MustSet(config.ViperKeyErrorsProblemJSONTypeBaseURL, "https://errors.example.org/kratos")
+		assert.True(t, p.ErrorsProblemJSONEnabled())
+		assert.Equal(t, "https://errors.example.org/kratos", p.ErrorsProblemJSONTypeBaseURL().String())
+	})
+}
+
+func TestViperProvider_SelfServiceFlowExpiredGracePeriod(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+	assert.Equal(t, time.Duration(0), p.SelfServiceFlowExpiredGracePeriod())
+
+	p.MustSet(config.ViperKeySelfServiceFlowExpiredGracePeriod, "5m")
+	assert.Equal(t, 5*time.Minute, p.SelfServiceFlowExpiredGracePeriod())
+}
+
+func TestViperProvider_SelfServiceFlowLogoutFrontChannel(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+	assert.Empty(t, p.SelfServiceFlowLogoutFrontChannelURLs())
+	assert.Equal(t, 5*time.Second, p.SelfServiceFlowLogoutFrontChannelTimeout())
+
+	p.MustSet(config.ViperKeySelfServiceLogoutFrontChannelURLs, []map[string]interface{}{
+		{"url": "https://app.example.org/logout"},
+		{"url": "https://pixel.example.org/logout.gif", "type": "image"},
+	})
+	p.MustSet(config.ViperKeySelfServiceLogoutFrontChannelTimeout, "2s")
+	assert.Equal(t, []config.SelfServiceLogoutFrontChannelURL{
+		{URL: "https://app.example.org/logout", Type: "iframe"},
+		{URL: "https://pixel.example.org/logout.gif", Type: "image"},
+	}, p.SelfServiceFlowLogoutFrontChannelURLs())
+	assert.Equal(t, 2*time.Second, p.SelfServiceFlowLogoutFrontChannelTimeout())
+}
+
+func TestViperProvider_SelfServiceFlowLogoutBackChannel(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+	assert.Empty(t, p.SelfServiceFlowLogoutBackChannelURLs())
+	assert.Equal(t, 10*time.Second, p.SelfServiceFlowLogoutBackChannelTimeout())
+	assert.Equal(t, 5, p.SelfServiceFlowLogoutBackChannelMaxRetries())
+	assert.Equal(t, time.Second, p.SelfServiceFlowLogoutBackChannelRetryWait())
+
+	p.MustSet(config.ViperKeySelfServiceLogoutBackChannelURLs, []map[string]interface{}{
+		{"url": "https://app.example.org/backchannel-logout", "secret": "a-very-secret-value"},
+	})
+	p.MustSet(config.ViperKeySelfServiceLogoutBackChannelTimeout, "2s")
+	p.MustSet(config.ViperKeySelfServiceLogoutBackChannelMaxRetries, 2)
+	p.MustSet(config.ViperKeySelfServiceLogoutBackChannelRetryWait, "100ms")
+	assert.Equal(t, []config.SelfServiceLogoutBackChannelURL{
+		{URL: "https://app.example.org/backchannel-logout", Secret: "a-very-secret-value"},
+	}, p.SelfServiceFlowLogoutBackChannelURLs())
+	assert.Equal(t, 2*time.Second, p.SelfServiceFlowLogoutBackChannelTimeout())
+	assert.Equal(t, 2, p.SelfServiceFlowLogoutBackChannelMaxRetries())
+	assert.Equal(t, 100*time.Millisecond, p.SelfServiceFlowLogoutBackChannelRetryWait())
+}
+
+func TestViperProvider_Cookies(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+
+	t.Run("case=defaults", func(t *testing.T) {
+		assert.Equal(t, http.SameSiteLaxMode, p.SessionSameSiteMode())
+		assert.Equal(t, http.SameSiteDefaultMode, p.CSRFCookieSameSiteMode())
+		assert.Equal(t, http.SameSiteLaxMode, p.ContinuityCookieSameSiteMode())
+		assert.False(t, p.SessionCookiePartitioned())
+		assert.False(t, p.CSRFCookiePartitioned())
+		assert.False(t, p.ContinuityCookiePartitioned())
+	})
+
+	t.Run("case=configured", func(t *testing.T) {
+		p.MustSet(config.ViperKeyCookiesCSRFSameSite, "None")
+		p.MustSet(config.ViperKeyCookiesContinuitySameSite, "Strict")
+		p.MustSet(config.ViperKeySessionCookiePartitioned, true)
+		p.MustSet(config.ViperKeyCookiesCSRFPartitioned, true)
+		p.MustSet(config.ViperKeyCookiesContinuityPartitioned, true)
+
+		assert.Equal(t, http.SameSiteNoneMode, p.CSRFCookieSameSiteMode())
+		assert.Equal(t, http.SameSiteStrictMode, p.ContinuityCookieSameSiteMode())
+		assert.True(t, p.SessionCookiePartitioned())
+		assert.True(t, p.CSRFCookiePartitioned())
+		assert.True(t, p.ContinuityCookiePartitioned())
+	})
+}
+
+func TestViperProvider_SessionCache(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+
+	t.Run("case=defaults", func(t *testing.T) {
+		assert.False(t, p.SessionCacheEnabled())
+		assert.Equal(t, 10*time.Second, p.SessionCacheTTL())
+	})
+
+	t.Run("case=configured", func(t *testing.T) {
+		p.MustSet(config.ViperKeySessionCacheEnabled, true)
+		p.MustSet(config.ViperKeySessionCacheTTL, "1m")
+		assert.True(t, p.SessionCacheEnabled())
+		assert.Equal(t, time.Minute, p.SessionCacheTTL())
+	})
+}
+
+func TestViperProvider_SessionRotation(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+	assert.True(t, p.SessionRotationEnabled())
+
+	p.MustSet(config.ViperKeySessionRotationEnabled, false)
+	assert.False(t, p.SessionRotationEnabled())
+}
+
+func TestViperProvider_IdentitySchemaValidation(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+	assert.Equal(t, 2000, p.IdentitySchemaValidationMaxPatternComplexity())
+
+	p.MustSet(config.ViperKeyIdentitySchemaValidationMaxPatternComplexity, 500)
+	assert.Equal(t, 500, p.IdentitySchemaValidationMaxPatternComplexity())
+}
+
+func TestViperProvider_IdentityGrowthStats(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+	assert.False(t, p.IdentityGrowthStatsEnabled())
+	assert.Equal(t, 30, p.IdentityGrowthStatsCohortDays())
+
+	p.MustSet(config.ViperKeyIdentityGrowthStatsEnabled, true)
+	p.MustSet(config.ViperKeyIdentityGrowthStatsCohortDays, 90)
+	assert.True(t, p.IdentityGrowthStatsEnabled())
+	assert.Equal(t, 90, p.IdentityGrowthStatsCohortDays())
+}
+
+func TestViperProvider_BreakGlass(t *testing.T) {
+	p := config.MustNew(logrusx.New("", ""), configx.SkipValidation())
+	assert.False(t, p.BreakGlassEnabled())
+	assert.Equal(t, &config.BreakGlassConfig{Scope: "kratos:break_glass", MaxDuration: time.Hour}, p.BreakGlass())
+
+	p.MustSet(config.ViperKeyBreakGlassEnabled, true)
+	p.MustSet(config.ViperKeyBreakGlassScope, "break-glass")
+	p.MustSet(config.ViperKeyBreakGlassMaxDuration, "15m")
+	p.MustSet(config.ViperKeyBreakGlassNotificationURL, "https://siem.example.org/hooks/kratos")
+	assert.True(t, p.BreakGlassEnabled())
+	c := p.BreakGlass()
+	assert.Equal(t, "break-glass", c.Scope)
+	assert.Equal(t, 15*time.Minute, c.MaxDuration)
+	assert.Equal(t, "https://siem.example.org/hooks/kratos", c.NotificationURL.String())
+}
+
+func TestViperProvider_Features(t *testing.T) {
+	t.Run("case=reports experimental features", func(t *testing.T) {
+		p := config.MustNew(logrusx.New("", ""), configx.SkipValidation(),
+			configx.WithValue(config.ViperKeySCIMEnabled, true))
+
+		enabled := map[string]bool{}
+		for _, f := range p.ExperimentalFeatures() {
+			assert.NotEmpty(t, f.Description, f.Key)
+			enabled[f.Key] = f.Enabled
+		}
+		assert.True(t, enabled[config.ViperKeySCIMEnabled])
+		assert.False(t, enabled[config.ViperKeyConfigVersionsEnabled])
+		assert.False(t, enabled["selfservice.methods.ldap.enabled"])
+	})
+
+	t.Run("case=reports and warns about deprecated keys in use", func(t *testing.T) {
+		hook := new(test.Hook)
+		p := config.MustNew(logrusx.New("", "", logrusx.WithHook(hook)), configx.SkipValidation(),
+			configx.WithValue(config.ViperKeyURLsWhitelistedReturnToDomains, []string{"https://www.ory.sh/"}))
+
+		assert.Contains(t, p.DeprecatedOptions(), config.DeprecatedOption{
+			Key:         config.ViperKeyURLsWhitelistedReturnToDomains,
+			Replacement: config.ViperKeyURLsAllowedReturnToDomains,
+			InUse:       true,
+		})
+
+		var warned bool
+		for _, e := range hook.AllEntries() {
+			if e.Level == logrus.WarnLevel && e.Data["key"] == config.ViperKeyURLsWhitelistedReturnToDomains {
+				warned = true
+			}
+		}
+		assert.True(t, warned)
+
+		hook.Reset()
+		p = config.MustNew(logrusx.New("", "", logrusx.WithHook(hook)), configx.SkipValidation())
+		for _, o := range p.DeprecatedOptions() {
+			assert.False(t, o.InUse, o.Key)
+		}
+		for _, e := range hook.AllEntries() {
+			assert.NotEqual(t, config.ViperKeyURLsWhitelistedReturnToDomains, e.Data["key"])
+		}
+	})
+
+	t.Run("case=prefers the allowed return urls over the deprecated key", func(t *testing.T) {
+		p := config.MustNew(logrusx.New("", ""), configx.SkipValidation(),
+			configx.WithValue(config.ViperKeyURLsWhitelistedReturnToDomains, []string{"https://whitelisted.ory.sh/"}))
+		require.Len(t, p.SelfServiceBrowserWhitelistedReturnToDomains(), 1)
+		assert.Equal(t, "https://whitelisted.ory.sh/", p.SelfServiceBrowserWhitelistedReturnToDomains()[0].String())
+
+		p.MustSet(config.ViperKeyURLsAllowedReturnToDomains, []string{"https://allowed.ory.sh/"})
+		require.Len(t, p.SelfServiceBrowserWhitelistedReturnToDomains(), 1)
+		assert.Equal(t, "https://allowed.ory.sh/", p.SelfServiceBrowserWhitelistedReturnToDomains()[0].String())
+	})
+}
+
+func TestViperProvider_Snapshot(t *testing.T) {
+	original, err := ioutil.ReadFile("../../internal/.kratos.yaml")
+	require.NoError(t, err)
+	dir, err := ioutil.TempDir("", "kratos-config-*")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "kratos.yaml")
+	require.NoError(t, ioutil.WriteFile(path, original, 0600))
+
+	p := config.MustNew(logrusx.New("", ""), configx.WithConfigFiles(path))
+
+	snapshot, err := p.Snapshot()
+	require.NoError(t, err)
+	for _, key := range []string{"secrets", config.ViperKeyDSN, config.ViperKeySelfServiceStrategyConfig + ".oidc.config.providers.0.client_secret"} {
+		assert.False(t, gjson.GetBytes(snapshot, key).Exists(), key)
+	}
+	assert.Equal(t, "github", gjson.GetBytes(snapshot, config.ViperKeySelfServiceStrategyConfig+".oidc.config.providers.0.id").String())
+
+	document, err := sjson.SetBytes(snapshot, config.ViperKeySelfServiceBrowserDefaultReturnTo, "https://www.ory.sh/applied")
+	require.NoError(t, err)
+	document, err = sjson.SetBytes(document, config.ViperKeySecretsCookie, []string{"another-session-key-7f8a9b77"})
+	require.NoError(t, err)
+
+	loaded, err := p.Load(document)
+	require.NoError(t, err)
+	assert.False(t, gjson.GetBytes(loaded, "secrets").Exists(), "secrets of the document must not be part of the snapshot")
+
+	require.NoError(t, p.Apply(document))
+	assert.Equal(t, "https://www.ory.sh/applied", p.SelfServiceBrowserDefaultReturnTo().String())
+	assert.Equal(t, [][]byte{[]byte("session-key-7f8a9b77-1"), []byte("session-key-7f8a9b77-2")}, p.SecretsSession())
+	assert.Equal(t, "sqlite://foo.db?mode=memory&_fk=true", p.DSN())
+	assert.Equal(t, "b", gjson.GetBytes(p.SelfServiceStrategy("oidc").Config, "providers.0.client_secret").String())
+
+	t.Run("case=keeps the applied version if the files did not change", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(path, original, 0600))
+		time.Sleep(time.Second)
+		assert.Equal(t, "https://www.ory.sh/applied", p.SelfServiceBrowserDefaultReturnTo().String())
+	})
+
+	t.Run("case=uses the files again once they changed", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(path, bytes.Replace(original, []byte("http://return-to-3-test.ory.sh/"), []byte("http://return-to-4-test.ory.sh/"), 1), 0600))
+		assert.Eventually(t, func() bool {
+			return p.SelfServiceBrowserDefaultReturnTo().String() == "http://return-to-4-test.ory.sh/"
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+}
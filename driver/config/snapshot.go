@@ -0,0 +1,234 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/knadh/koanf"
+	kjson "github.com/knadh/koanf/parsers/json"
+	"github.com/knadh/koanf/providers/rawbytes"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/x/configx"
+	"github.com/ory/x/watcherx"
+)
+
+// immutables are the keys which can not change while ORY Kratos is running.
+var immutables = []string{"serve", "profiling", "log"}
+
+// secrets are the keys of values which are never part of a snapshot. Every instance uses its own values, which are
+// loaded from configuration files, flags, and environment variables.
+var secrets = []string{
+	"secrets",
+	ViperKeyDSN,
+	ViperKeyCourierSMTPURL,
+	ViperKeyLDAPBindPassword,
+	ViperKeyPasswordExternalStoreBearerToken,
+	ViperKeyKerberosKeytabURL,
+	ViperKeySCIMToken,
+}
+
+// listSecrets are the secret values of list items. Items are matched with the items of the configuration in use by
+// their key.
+var listSecrets = []struct{ list, key, secret string }{
+	{list: ViperKeySelfServiceStrategyConfig + ".oidc.config.providers", key: "id", secret: "client_secret"},
+	{list: ViperKeySelfServiceLogoutBackChannelURLs, key: "url", secret: "secret"},
+	{list: ViperKeyCourierSMSProviders, key: "id", secret: "headers"},
+}
+
+func compileSchema(schema []byte) (*jsonschema.Schema, error) {
+	id := gjson.GetBytes(schema, "$id").String()
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(id, bytes.NewReader(schema)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return compiler.Compile(id)
+}
+
+// Snapshot returns the configuration in use, including defaults and without secrets, as a JSON document.
+func (p *Provider) Snapshot() ([]byte, error) {
+	return snapshot(p.source())
+}
+
+func snapshot(c *configx.Provider) ([]byte, error) {
+	out, err := c.Koanf.Marshal(kjson.Parser())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return redact(out)
+}
+
+// redact removes all secrets from a snapshot.
+func redact(snapshot []byte) (_ []byte, err error) {
+	for _, key := range secrets {
+		if snapshot, err = sjson.DeleteBytes(snapshot, key); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	for _, s := range listSecrets {
+		for k := range gjson.GetBytes(snapshot, s.list).Array() {
+			if snapshot, err = sjson.DeleteBytes(snapshot, fmt.Sprintf("%s.%d.%s", s.list, k, s.secret)); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// withSecrets replaces the secrets of a snapshot with the secrets of the configuration in use.
+func withSecrets(snapshot, current []byte) ([]byte, error) {
+	snapshot, err := redact(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range secrets {
+		if v := gjson.GetBytes(current, key); v.Exists() {
+			if snapshot, err = sjson.SetRawBytes(snapshot, key, []byte(v.Raw)); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+	}
+	for _, s := range listSecrets {
+		for k, item := range gjson.GetBytes(snapshot, s.list).Array() {
+			for _, c := range gjson.GetBytes(current, s.list).Array() {
+				if v := c.Get(s.secret); c.Get(s.key).String() == item.Get(s.key).String() && v.Exists() {
+					if snapshot, err = sjson.SetRawBytes(snapshot, fmt.Sprintf("%s.%d.%s", s.list, k, s.secret), []byte(v.Raw)); err != nil {
+						return nil, errors.WithStack(err)
+					}
+				}
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// Hash returns the hex-encoded SHA-256 hash of a snapshot.
+func Hash(snapshot []byte) string {
+	h := sha256.Sum256(snapshot)
+	return hex.EncodeToString(h[:])
+}
+
+// Load validates a configuration document and returns the snapshot it results in. The document replaces all
+// configuration files, flags, and environment variables, but must not change values which are immutable at runtime.
+// Secrets in the document are ignored, the secrets of the configuration in use are kept.
+func (p *Provider) Load(document []byte) ([]byte, error) {
+	k, err := p.load(document)
+	if err != nil {
+		return nil, err
+	}
+	out, err := k.Marshal(kjson.Parser())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return redact(out)
+}
+
+// Apply validates a configuration document like Load does and replaces the configuration in use with it. The
+// configuration loaded from files, flags, and environment variables is used again once a configuration file changes.
+func (p *Provider) Apply(document []byte) error {
+	k, err := p.load(document)
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	loaded, err := snapshot(p.loaded)
+	if err != nil {
+		return err
+	}
+
+	next := *p.loaded
+	next.Koanf = k
+	p.p, p.appliedOver = &next, Hash(loaded)
+	return nil
+}
+
+// reloaded is called when the configuration files were loaded again. An applied configuration version is kept
+// unless the files changed since it was applied.
+func (p *Provider) reloaded(_ watcherx.Event, err error) {
+	if err != nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.p == p.loaded {
+		return
+	}
+
+	loaded, err := snapshot(p.loaded)
+	if err != nil {
+		p.l.WithError(err).Error("Unable to compare the configuration files with the applied configuration version.")
+		return
+	}
+	if Hash(loaded) != p.appliedOver {
+		p.l.Info("The configuration files changed and replace the applied configuration version.")
+		p.p = p.loaded
+	}
+}
+
+func (p *Provider) load(document []byte) (*koanf.Koanf, error) {
+	k := koanf.New(configx.Delimiter)
+
+	defaults, err := configx.NewKoanfSchemaDefaults(p.schema)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.Load(defaults, nil); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := k.Load(rawbytes.Provider(document), kjson.Parser()); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	out, err := k.Marshal(kjson.Parser())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// The secrets of the configuration in use are kept. This includes the DSN because the database connection is
+	// only established once.
+	c := p.source()
+	current, err := c.Koanf.Marshal(kjson.Parser())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if out, err = withSecrets(out, current); err != nil {
+		return nil, err
+	}
+	if err := p.validator.Validate(bytes.NewReader(out)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	k = koanf.New(configx.Delimiter)
+	if err := k.Load(rawbytes.Provider(out), kjson.Parser()); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for _, key := range immutables {
+		// Values are compared as JSON because numbers are decoded differently from YAML and JSON.
+		current, err := json.Marshal(c.Get(key))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		next, err := json.Marshal(k.Get(key))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if !bytes.Equal(current, next) {
+			return nil, errors.WithStack(configx.NewImmutableError(key, fmt.Sprintf("%v", c.Get(key)), fmt.Sprintf("%v", k.Get(key))))
+		}
+	}
+
+	return k, nil
+}
@@ -9,6 +9,19 @@ import (
 )
 
 func New(opts ...configx.OptionModifier) Registry {
+	r := NewWithoutInit(opts...)
+
+	if err := r.Init(); err != nil {
+		r.Logger().WithError(err).Fatal("Unable to initialize service registry.")
+	}
+
+	r.Configuration().Source().SetTracer(context.Background(), r.Tracer())
+
+	return r
+}
+
+// NewWithoutInit returns a registry which has not yet connected to the database. Call Init before using it.
+func NewWithoutInit(opts ...configx.OptionModifier) Registry {
 	l := logrusx.New("ORY Kratos", config.Version)
 	c, err := config.New(l, opts...)
 	if err != nil {
@@ -20,11 +33,5 @@ func New(opts ...configx.OptionModifier) Registry {
 		l.WithError(err).Fatal("Unable to instantiate service registry.")
 	}
 
-	if err = r.Init(); err != nil {
-		l.WithError(err).Fatal("Unable to initialize service registry.")
-	}
-
-	c.Source().SetTracer(context.Background(), r.Tracer())
-
 	return r
 }
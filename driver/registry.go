@@ -1,6 +1,8 @@
 package driver
 
 import (
+	"context"
+
 	"github.com/ory/kratos/metrics/prometheus"
 	"github.com/ory/x/tracing"
 
@@ -9,14 +11,24 @@ import (
 
 	"github.com/ory/x/logrusx"
 
+	"github.com/ory/kratos/apikey"
+	"github.com/ory/kratos/breakglass"
+	"github.com/ory/kratos/configversion"
 	"github.com/ory/kratos/continuity"
 	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/growth"
 	"github.com/ory/kratos/hash"
 	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/scim"
 	"github.com/ory/kratos/selfservice/flow/recovery"
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/hook"
+	"github.com/ory/kratos/selfservice/stats"
+	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/selfservice/strategy/kerberos"
 	"github.com/ory/kratos/selfservice/strategy/link"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
 
 	"github.com/ory/x/healthx"
 
@@ -30,6 +42,7 @@ import (
 	"github.com/ory/x/dbal"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/hydra"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/errorx"
 	password2 "github.com/ory/kratos/selfservice/strategy/password"
@@ -40,6 +53,7 @@ type Registry interface {
 	dbal.Driver
 
 	Init() error
+	SelfTest(ctx context.Context) *SelfTestReport
 
 	Configuration() *config.Provider
 	WithConfig(c *config.Provider) Registry
@@ -47,6 +61,7 @@ type Registry interface {
 
 	WithCSRFHandler(c x.CSRFHandler)
 	WithCSRFTokenGenerator(cg x.CSRFToken)
+	WithClock(c x.Clock)
 
 	HealthHandler() *healthx.Handler
 	CookieManager() sessions.Store
@@ -61,6 +76,8 @@ type Registry interface {
 	x.CSRFProvider
 	x.WriterProvider
 	x.LoggingProvider
+	x.BotScoreMiddlewareProvider
+	x.ClockProvider
 
 	continuity.ManagementProvider
 	continuity.PersistenceProvider
@@ -77,9 +94,12 @@ type Registry interface {
 
 	identity.HandlerProvider
 	identity.ValidationProvider
+	identity.SchemaMigratorProvider
+	identity.TraitsMigrationsProvider
 	identity.PoolProvider
 	identity.PrivilegedPoolProvider
 	identity.ManagementProvider
+	identity.PurgerProvider
 	identity.ActiveCredentialsCounterStrategyProvider
 
 	schema.HandlerProvider
@@ -87,8 +107,13 @@ type Registry interface {
 	password2.ValidationProvider
 
 	session.HandlerProvider
+	session.DisclosureHandlerProvider
+	session.ImpersonationHandlerProvider
 	session.ManagementProvider
 	session.PersistenceProvider
+	session.JWTSignerProvider
+	session.JanitorProvider
+	session.BackChannelNotifierProvider
 
 	settings.HandlerProvider
 	settings.ErrorHandlerProvider
@@ -120,11 +145,50 @@ type Registry interface {
 	link.VerificationTokenPersistenceProvider
 	link.RecoveryTokenPersistenceProvider
 
+	code.VerificationCodePersistenceProvider
+
+	kerberos.ReplayCachePersistenceProvider
+
 	recovery.FlowPersistenceProvider
 	recovery.ErrorHandlerProvider
 	recovery.HandlerProvider
 	recovery.StrategyProvider
 
+	stats.PersistenceProvider
+	stats.RecorderProvider
+	stats.HandlerProvider
+
+	hook.SimulationHooksProvider
+	hook.SimulationHandlerProvider
+
+	scim.PersistenceProvider
+	scim.HandlerProvider
+	scim.HookProvider
+
+	hydra.Provider
+
+	configversion.ManagementProvider
+	configversion.HandlerProvider
+	configversion.PersistenceProvider
+
+	apikey.ManagementProvider
+	apikey.HandlerProvider
+	apikey.PersistenceProvider
+
+	breakglass.ManagementProvider
+	breakglass.HandlerProvider
+	breakglass.PersistenceProvider
+	identity.BreakGlassGuardProvider
+	identity.SessionRevocationNotifierProvider
+
+	growth.PersistenceProvider
+	growth.AggregatorProvider
+	growth.HandlerProvider
+
+	oidc.HealthCheckerProvider
+	oidc.ProviderHandlerProvider
+	oidc.ProviderPersistenceProvider
+
 	x.CSRFTokenGeneratorProvider
 }
 
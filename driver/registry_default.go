@@ -10,13 +10,20 @@ import (
 
 	"github.com/gobuffalo/pop/v5"
 
+	"github.com/ory/kratos/apikey"
+	"github.com/ory/kratos/breakglass"
+	"github.com/ory/kratos/configversion"
 	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/growth"
 	"github.com/ory/kratos/hash"
 	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/scim"
 	"github.com/ory/kratos/selfservice/flow/recovery"
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/flow/verification"
 	"github.com/ory/kratos/selfservice/hook"
+	"github.com/ory/kratos/selfservice/stats"
+	"github.com/ory/kratos/selfservice/strategy/code"
 	"github.com/ory/kratos/selfservice/strategy/link"
 	"github.com/ory/kratos/selfservice/strategy/profile"
 	"github.com/ory/kratos/x"
@@ -39,11 +46,19 @@ import (
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/flow/logout"
 	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/strategy/emails"
+	"github.com/ory/kratos/selfservice/strategy/kerberos"
+	"github.com/ory/kratos/selfservice/strategy/ldap"
+	logout2 "github.com/ory/kratos/selfservice/strategy/logout"
+	"github.com/ory/kratos/selfservice/strategy/mtls"
+	"github.com/ory/kratos/selfservice/strategy/notifications"
 	"github.com/ory/kratos/selfservice/strategy/oidc"
+	"github.com/ory/kratos/selfservice/strategy/saml"
 
 	"github.com/ory/herodot"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/hydra"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/errorx"
 	password2 "github.com/ory/kratos/selfservice/strategy/password"
@@ -62,6 +77,7 @@ type RegistryDefault struct {
 	c *config.Provider
 
 	injectedSelfserviceHooks map[string]func(config.SelfServiceHook) interface{}
+	injectedTraitsMigrations map[string]identity.TraitsMigration
 
 	nosurf         x.CSRFHandler
 	trc            *tracing.Tracer
@@ -77,18 +93,32 @@ type RegistryDefault struct {
 	hookSessionIssuer    *hook.SessionIssuer
 	hookSessionDestroyer *hook.SessionDestroyer
 
+	hookRegistrationVelocity *hook.RegistrationVelocity
+	hookSimulationHandler    *hook.SimulationHandler
+
 	identityHandler   *identity.Handler
 	identityValidator *identity.Validator
 	identityManager   *identity.Manager
+	identityPurger    *identity.Purger
+
+	identitySchemaMigrator *identity.SchemaMigrator
 
 	continuityManager      continuity.Manager
 	continuitySessionStore *sessions.CookieStore
 
 	schemaHandler *schema.Handler
 
-	sessionHandler *session.Handler
-	sessionsStore  *sessions.CookieStore
-	sessionManager session.Manager
+	sessionHandler              *session.Handler
+	sessionDisclosureHandler    *session.DisclosureHandler
+	sessionImpersonationHandler *session.ImpersonationHandler
+	sessionsStore               *sessions.CookieStore
+	sessionManager              session.Manager
+	sessionJWTSigner            *session.JWTSigner
+	sessionJanitor              *session.Janitor
+	sessionBackChannelNotifier  *session.BackChannelNotifier
+
+	oidcHealthChecker   *oidc.HealthChecker
+	oidcProviderHandler *oidc.ProviderHandler
 
 	passwordHasher    hash.Hasher
 	passwordValidator password2.Validator
@@ -120,6 +150,28 @@ type RegistryDefault struct {
 
 	selfserviceLogoutHandler *logout.Handler
 
+	flowStatsRecorder *stats.Recorder
+	flowStatsHandler  *stats.Handler
+
+	scimHandler   *scim.Handler
+	scimLoginHook *scim.LoginHook
+
+	hydra *hydra.Hydra
+
+	botScoreMiddleware *x.BotScoreMiddleware
+
+	configVersionManager *configversion.Manager
+	configVersionHandler *configversion.Handler
+
+	apiKeyManager *apikey.Manager
+	apiKeyHandler *apikey.Handler
+
+	breakGlassManager *breakglass.Manager
+	breakGlassHandler *breakglass.Handler
+
+	identityGrowthAggregator *growth.Aggregator
+	identityGrowthHandler    *growth.Handler
+
 	selfserviceStrategies              []interface{}
 	loginStrategies                    []login.Strategy
 	activeCredentialsCounterStrategies []identity.ActiveCredentialsCounter
@@ -133,6 +185,11 @@ type RegistryDefault struct {
 	buildDate    string
 
 	csrfTokenGenerator x.CSRFToken
+
+	clock        x.Clock
+	clockHandler *x.ClockHandler
+
+	featuresHandler *x.FeaturesHandler
 }
 
 func (m *RegistryDefault) Audit() *logrusx.Logger {
@@ -151,6 +208,7 @@ func (m *RegistryDefault) RegisterPublicRoutes(router *x.RouterPublic) {
 	m.SettingsStrategies().RegisterPublicRoutes(router)
 	m.RegistrationStrategies().RegisterPublicRoutes(router)
 	m.SessionHandler().RegisterPublicRoutes(router)
+	m.SessionDisclosureHandler().RegisterPublicRoutes(router)
 	m.SelfServiceErrorHandler().RegisterPublicRoutes(router)
 	m.SchemaHandler().RegisterPublicRoutes(router)
 
@@ -174,7 +232,28 @@ func (m *RegistryDefault) RegisterAdminRoutes(router *x.RouterAdmin) {
 	m.SettingsHandler().RegisterAdminRoutes(router)
 	m.IdentityHandler().RegisterAdminRoutes(router)
 	m.SessionHandler().RegisterAdminRoutes(router)
+	m.SessionImpersonationHandler().RegisterAdminRoutes(router)
 	m.SelfServiceErrorHandler().RegisterAdminRoutes(router)
+	m.FlowStatsHandler().RegisterAdminRoutes(router)
+	m.IdentityGrowthHandler().RegisterAdminRoutes(router)
+	m.HookSimulationHandler().RegisterAdminRoutes(router)
+	m.ConfigVersionHandler().RegisterAdminRoutes(router)
+	m.APIKeyHandler().RegisterAdminRoutes(router)
+	m.OIDCHealthChecker().RegisterAdminRoutes(router)
+	m.OIDCProviderHandler().RegisterAdminRoutes(router)
+	m.FeaturesHandler().RegisterAdminRoutes(router)
+
+	if m.c.IsInsecureDevMode() {
+		m.ClockHandler().RegisterAdminRoutes(router)
+	}
+
+	if m.c.SCIMEnabled() {
+		m.SCIMHandler().RegisterAdminRoutes(router)
+	}
+
+	if m.c.BreakGlassEnabled() {
+		m.BreakGlassHandler().RegisterAdminRoutes(router)
+	}
 
 	if m.c.SelfServiceFlowRecoveryEnabled() {
 		m.RecoveryHandler().RegisterAdminRoutes(router)
@@ -211,6 +290,112 @@ func (m *RegistryDefault) LogoutHandler() *logout.Handler {
 	return m.selfserviceLogoutHandler
 }
 
+func (m *RegistryDefault) FlowStatsRecorder() *stats.Recorder {
+	if m.flowStatsRecorder == nil {
+		m.flowStatsRecorder = stats.NewRecorder(m, m.c)
+	}
+	return m.flowStatsRecorder
+}
+
+func (m *RegistryDefault) FlowStatsHandler() *stats.Handler {
+	if m.flowStatsHandler == nil {
+		m.flowStatsHandler = stats.NewHandler(m, m.c)
+	}
+	return m.flowStatsHandler
+}
+
+func (m *RegistryDefault) BotScoreMiddleware() *x.BotScoreMiddleware {
+	if m.botScoreMiddleware == nil {
+		m.botScoreMiddleware = x.NewBotScoreMiddleware(m, m.c)
+	}
+	return m.botScoreMiddleware
+}
+
+func (m *RegistryDefault) ConfigVersionManager() *configversion.Manager {
+	if m.configVersionManager == nil {
+		m.configVersionManager = configversion.NewManager(m, m.c)
+	}
+	return m.configVersionManager
+}
+
+func (m *RegistryDefault) ConfigVersionHandler() *configversion.Handler {
+	if m.configVersionHandler == nil {
+		m.configVersionHandler = configversion.NewHandler(m, m.c)
+	}
+	return m.configVersionHandler
+}
+
+func (m *RegistryDefault) APIKeyManager() *apikey.Manager {
+	if m.apiKeyManager == nil {
+		m.apiKeyManager = apikey.NewManager(m)
+	}
+	return m.apiKeyManager
+}
+
+func (m *RegistryDefault) APIKeyHandler() *apikey.Handler {
+	if m.apiKeyHandler == nil {
+		m.apiKeyHandler = apikey.NewHandler(m)
+	}
+	return m.apiKeyHandler
+}
+
+func (m *RegistryDefault) BreakGlassManager() *breakglass.Manager {
+	if m.breakGlassManager == nil {
+		m.breakGlassManager = breakglass.NewManager(m)
+	}
+	return m.breakGlassManager
+}
+
+func (m *RegistryDefault) BreakGlassHandler() *breakglass.Handler {
+	if m.breakGlassHandler == nil {
+		m.breakGlassHandler = breakglass.NewHandler(m, m.c)
+	}
+	return m.breakGlassHandler
+}
+
+func (m *RegistryDefault) IdentityGrowthAggregator() *growth.Aggregator {
+	if m.identityGrowthAggregator == nil {
+		m.identityGrowthAggregator = growth.NewAggregator(m, m.c)
+	}
+	return m.identityGrowthAggregator
+}
+
+func (m *RegistryDefault) IdentityGrowthHandler() *growth.Handler {
+	if m.identityGrowthHandler == nil {
+		m.identityGrowthHandler = growth.NewHandler(m, m.c)
+	}
+	return m.identityGrowthHandler
+}
+
+func (m *RegistryDefault) BreakGlassGuard() identity.BreakGlassGuard {
+	return m.BreakGlassManager()
+}
+
+func (m *RegistryDefault) SessionRevocationNotifier() identity.SessionRevocationNotifier {
+	return m.SessionBackChannelNotifier()
+}
+
+func (m *RegistryDefault) SCIMHandler() *scim.Handler {
+	if m.scimHandler == nil {
+		m.scimHandler = scim.NewHandler(m, m.c)
+	}
+	return m.scimHandler
+}
+
+func (m *RegistryDefault) SCIMLoginHook() *scim.LoginHook {
+	if m.scimLoginHook == nil {
+		m.scimLoginHook = scim.NewLoginHook(m)
+	}
+	return m.scimLoginHook
+}
+
+func (m *RegistryDefault) Hydra() *hydra.Hydra {
+	if m.hydra == nil {
+		m.hydra = hydra.NewHydra(m.c)
+	}
+	return m.hydra
+}
+
 func (m *RegistryDefault) HealthHandler() *healthx.Handler {
 	if m.healthxHandler == nil {
 		m.healthxHandler = healthx.NewHandler(m.Writer(), config.Version,
@@ -251,8 +436,16 @@ func (m *RegistryDefault) selfServiceStrategies() []interface{} {
 		m.selfserviceStrategies = []interface{}{
 			password2.NewStrategy(m, m.c),
 			oidc.NewStrategy(m, m.c),
+			saml.NewStrategy(m, m.c),
+			ldap.NewStrategy(m, m.c),
+			kerberos.NewStrategy(m, m.c),
+			mtls.NewStrategy(m, m.c),
 			profile.NewStrategy(m, m.c),
 			link.NewStrategy(m, m.c),
+			code.NewStrategy(m, m.c),
+			notifications.NewStrategy(m, m.c),
+			emails.NewStrategy(m, m.c),
+			logout2.NewStrategy(m.c),
 		}
 	}
 
@@ -318,6 +511,23 @@ func (m *RegistryDefault) IdentityValidator() *identity.Validator {
 	return m.identityValidator
 }
 
+func (m *RegistryDefault) IdentitySchemaMigrator() *identity.SchemaMigrator {
+	if m.identitySchemaMigrator == nil {
+		m.identitySchemaMigrator = identity.NewSchemaMigrator(m)
+	}
+	return m.identitySchemaMigrator
+}
+
+// WithTraitsMigrations registers identity traits migrations implemented in Go by schema reference, for example
+// `customer@2`.
+func (m *RegistryDefault) WithTraitsMigrations(migrations map[string]identity.TraitsMigration) {
+	m.injectedTraitsMigrations = migrations
+}
+
+func (m *RegistryDefault) TraitsMigrations() map[string]identity.TraitsMigration {
+	return m.injectedTraitsMigrations
+}
+
 func (m *RegistryDefault) WithConfig(c *config.Provider) Registry {
 	m.c = c
 	return m
@@ -325,8 +535,7 @@ func (m *RegistryDefault) WithConfig(c *config.Provider) Registry {
 
 func (m *RegistryDefault) Writer() herodot.Writer {
 	if m.writer == nil {
-		h := herodot.NewJSONWriter(m.Logger())
-		m.writer = h
+		m.writer = x.NewProblemJSONWriter(m.Logger(), m.c)
 	}
 	return m.writer
 }
@@ -354,18 +563,77 @@ func (m *RegistryDefault) SchemaHandler() *schema.Handler {
 
 func (m *RegistryDefault) SessionHandler() *session.Handler {
 	if m.sessionHandler == nil {
-		m.sessionHandler = session.NewHandler(m)
+		m.sessionHandler = session.NewHandler(m, m.c)
 	}
 	return m.sessionHandler
 }
 
+func (m *RegistryDefault) SessionDisclosureHandler() *session.DisclosureHandler {
+	if m.sessionDisclosureHandler == nil {
+		m.sessionDisclosureHandler = session.NewDisclosureHandler(m, m.c)
+	}
+	return m.sessionDisclosureHandler
+}
+
+func (m *RegistryDefault) SessionImpersonationHandler() *session.ImpersonationHandler {
+	if m.sessionImpersonationHandler == nil {
+		m.sessionImpersonationHandler = session.NewImpersonationHandler(m, m.c)
+	}
+	return m.sessionImpersonationHandler
+}
+
+func (m *RegistryDefault) SessionJWTSigner() *session.JWTSigner {
+	if m.sessionJWTSigner == nil {
+		m.sessionJWTSigner = session.NewJWTSigner(m, m.c)
+	}
+	return m.sessionJWTSigner
+}
+
+func (m *RegistryDefault) OIDCHealthChecker() *oidc.HealthChecker {
+	if m.oidcHealthChecker == nil {
+		m.oidcHealthChecker = oidc.NewHealthChecker(m, m.c)
+	}
+	return m.oidcHealthChecker
+}
+
+func (m *RegistryDefault) OIDCProviderHandler() *oidc.ProviderHandler {
+	if m.oidcProviderHandler == nil {
+		m.oidcProviderHandler = oidc.NewProviderHandler(m, m.c)
+	}
+	return m.oidcProviderHandler
+}
+
+func (m *RegistryDefault) SessionJanitor() *session.Janitor {
+	if m.sessionJanitor == nil {
+		m.sessionJanitor = session.NewJanitor(m, m.c)
+	}
+	return m.sessionJanitor
+}
+
+func (m *RegistryDefault) SessionBackChannelNotifier() *session.BackChannelNotifier {
+	if m.sessionBackChannelNotifier == nil {
+		m.sessionBackChannelNotifier = session.NewBackChannelNotifier(m, m.c)
+	}
+	return m.sessionBackChannelNotifier
+}
+
 func (m *RegistryDefault) Hasher() hash.Hasher {
 	if m.passwordHasher == nil {
-		m.passwordHasher = hash.NewHasherArgon2(m.c)
+		m.passwordHasher = m.newHasher(m.c.HasherAlgorithm())
+		if shadow := m.c.HasherShadowAlgorithm(); shadow != "" {
+			m.passwordHasher = hash.NewHasherShadow(m.passwordHasher, m.c.HasherAlgorithm(), m.newHasher(shadow), shadow, m.Logger())
+		}
 	}
 	return m.passwordHasher
 }
 
+func (m *RegistryDefault) newHasher(algorithm string) hash.Hasher {
+	if algorithm == "bcrypt" {
+		return hash.NewHasherBcrypt(m.c)
+	}
+	return hash.NewHasherArgon2(m.c)
+}
+
 func (m *RegistryDefault) PasswordValidator() password2.Validator {
 	if m.passwordValidator == nil {
 		m.passwordValidator = password2.NewDefaultPasswordValidatorStrategy(m.c)
@@ -382,7 +650,7 @@ func (m *RegistryDefault) SelfServiceErrorHandler() *errorx.Handler {
 
 func (m *RegistryDefault) CookieManager() sessions.Store {
 	if m.sessionsStore == nil {
-		cs := sessions.NewCookieStore(m.c.SecretsSession()...)
+		cs := sessions.NewCookieStore(x.CookieKeyPairs(m.c.SecretsSession(), m.c.SessionCookieEncryption())...)
 		cs.Options.Secure = !m.c.IsInsecureDevMode()
 		cs.Options.HttpOnly = true
 		if m.c.SessionDomain() != "" {
@@ -408,10 +676,10 @@ func (m *RegistryDefault) CookieManager() sessions.Store {
 
 func (m *RegistryDefault) ContinuityCookieManager() sessions.Store {
 	if m.continuitySessionStore == nil {
-		cs := sessions.NewCookieStore(m.c.SecretsSession()...)
+		cs := sessions.NewCookieStore(x.CookieKeyPairs(m.c.SecretsSession(), m.c.SessionCookieEncryption())...)
 		cs.Options.Secure = !m.c.IsInsecureDevMode()
 		cs.Options.HttpOnly = true
-		cs.Options.SameSite = http.SameSiteLaxMode
+		cs.Options.SameSite = m.c.ContinuityCookieSameSiteMode()
 		m.continuitySessionStore = cs
 	}
 	return m.continuitySessionStore
@@ -463,6 +731,12 @@ func (m *RegistryDefault) Init() error {
 	bc := backoff.NewExponentialBackOff()
 	bc.MaxElapsedTime = time.Minute * 5
 	bc.Reset()
+	return m.init(bc)
+}
+
+func (m *RegistryDefault) init(bc backoff.BackOff) error {
+	x.UseUUIDv7(m.c.DatabaseUUIDVersion() == 7)
+
 	return errors.WithStack(
 		backoff.Retry(func() error {
 			pool, idlePool, connMaxLifetime, cleanedDSN := sqlcon.ParseConnectionOptions(m.l, m.c.DSN())
@@ -566,6 +840,42 @@ func (m *RegistryDefault) VerificationTokenPersister() link.VerificationTokenPer
 	return m.Persister()
 }
 
+func (m *RegistryDefault) VerificationCodePersister() code.VerificationCodePersister {
+	return m.Persister()
+}
+
+func (m *RegistryDefault) KerberosReplayCachePersister() kerberos.ReplayCachePersister {
+	return m.Persister()
+}
+
+func (m *RegistryDefault) FlowStatsPersister() stats.Persister {
+	return m.persister
+}
+
+func (m *RegistryDefault) SCIMPersister() scim.Persister {
+	return m.persister
+}
+
+func (m *RegistryDefault) ConfigVersionPersister() configversion.Persister {
+	return m.persister
+}
+
+func (m *RegistryDefault) APIKeyPersister() apikey.Persister {
+	return m.persister
+}
+
+func (m *RegistryDefault) OIDCProviderPersister() oidc.ProviderPersister {
+	return m.persister
+}
+
+func (m *RegistryDefault) IdentityGrowthPersister() growth.Persister {
+	return m.persister
+}
+
+func (m *RegistryDefault) BreakGlassPersister() breakglass.Persister {
+	return m.persister
+}
+
 func (m *RegistryDefault) Persister() persistence.Persister {
 	return m.persister
 }
@@ -578,6 +888,38 @@ func (m *RegistryDefault) WithCSRFTokenGenerator(cg x.CSRFToken) {
 	m.csrfTokenGenerator = cg
 }
 
+// WithClock replaces the clock used to issue and expire flows, sessions, and tokens.
+func (m *RegistryDefault) WithClock(c x.Clock) {
+	m.clock = c
+}
+
+// Clock returns the clock used to issue and expire flows, sessions, and tokens. In development mode it is a test
+// clock which can be advanced using the admin API.
+func (m *RegistryDefault) Clock() x.Clock {
+	if m.clock == nil {
+		if m.c.IsInsecureDevMode() {
+			m.clock = new(x.TestClock)
+		} else {
+			m.clock = x.SystemClock{}
+		}
+	}
+	return m.clock
+}
+
+func (m *RegistryDefault) ClockHandler() *x.ClockHandler {
+	if m.clockHandler == nil {
+		m.clockHandler = x.NewClockHandler(m)
+	}
+	return m.clockHandler
+}
+
+func (m *RegistryDefault) FeaturesHandler() *x.FeaturesHandler {
+	if m.featuresHandler == nil {
+		m.featuresHandler = x.NewFeaturesHandler(m, m.c)
+	}
+	return m.featuresHandler
+}
+
 func (m *RegistryDefault) GenerateCSRFToken(r *http.Request) string {
 	if m.csrfTokenGenerator == nil {
 		m.csrfTokenGenerator = x.DefaultCSRFToken
@@ -585,6 +927,13 @@ func (m *RegistryDefault) GenerateCSRFToken(r *http.Request) string {
 	return m.csrfTokenGenerator(r)
 }
 
+func (m *RegistryDefault) IdentityPurger() *identity.Purger {
+	if m.identityPurger == nil {
+		m.identityPurger = identity.NewPurger(m, m.c)
+	}
+	return m.identityPurger
+}
+
 func (m *RegistryDefault) IdentityManager() *identity.Manager {
 	if m.identityManager == nil {
 		m.identityManager = identity.NewManager(m, m.c)
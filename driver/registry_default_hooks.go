@@ -1,6 +1,10 @@
 package driver
 
 import (
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/selfservice/hook"
 )
@@ -26,6 +30,52 @@ func (m *RegistryDefault) HookSessionDestroyer() *hook.SessionDestroyer {
 	return m.hookSessionDestroyer
 }
 
+func (m *RegistryDefault) HookQuarantine(c config.SelfServiceHook) *hook.Quarantine {
+	if m.hookRegistrationVelocity == nil {
+		m.hookRegistrationVelocity = hook.NewRegistrationVelocity()
+	}
+	// The velocity is shared because a new quarantine hook is created for every configuration.
+	return hook.NewQuarantine(m, m.hookRegistrationVelocity, c.Config)
+}
+
+func (m *RegistryDefault) HookKetoRelationTuples(c config.SelfServiceHook) *hook.KetoRelationTuples {
+	return hook.NewKetoRelationTuples(m, c.Config)
+}
+
+func (m *RegistryDefault) HookEmailDeliverability(c config.SelfServiceHook) *hook.EmailDeliverability {
+	return hook.NewEmailDeliverability(m, c.Config)
+}
+
+func (m *RegistryDefault) HookSimulationHandler() *hook.SimulationHandler {
+	if m.hookSimulationHandler == nil {
+		m.hookSimulationHandler = hook.NewSimulationHandler(m, m.c)
+	}
+	return m.hookSimulationHandler
+}
+
+func (m *RegistryDefault) SimulationHooks(flow, method string) ([]hook.SimulatedHook, error) {
+	var configs []config.SelfServiceHook
+	switch flow {
+	case "login":
+		configs = m.c.SelfServiceFlowLoginAfterHooks(method)
+	case "registration":
+		configs = m.c.SelfServiceFlowRegistrationAfterHooks(method)
+	case "settings":
+		configs = m.c.SelfServiceFlowSettingsAfterHooks(method)
+	default:
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Hooks of flow %q can not be simulated, expected one of login, registration, or settings.", flow))
+	}
+
+	hooks := make([]hook.SimulatedHook, len(configs))
+	for k, c := range configs {
+		hooks[k] = hook.SimulatedHook{Name: c.Name}
+		if i := m.getHooks(method, []config.SelfServiceHook{c}); len(i) > 0 {
+			hooks[k].Hook = i[0]
+		}
+	}
+	return hooks, nil
+}
+
 func (m *RegistryDefault) WithHooks(hooks map[string]func(config.SelfServiceHook) interface{}) {
 	m.injectedSelfserviceHooks = hooks
 }
@@ -37,6 +87,12 @@ func (m *RegistryDefault) getHooks(credentialsType string, configs []config.Self
 			i = append(i, m.HookSessionIssuer())
 		case hook.KeySessionDestroyer:
 			i = append(i, m.HookSessionDestroyer())
+		case hook.KeyQuarantine:
+			i = append(i, m.HookQuarantine(h))
+		case hook.KeyKetoRelationTuples:
+			i = append(i, m.HookKetoRelationTuples(h))
+		case hook.KeyEmailDeliverability:
+			i = append(i, m.HookEmailDeliverability(h))
 		default:
 			var found bool
 			for name, m := range m.injectedSelfserviceHooks {
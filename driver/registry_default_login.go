@@ -22,6 +22,18 @@ func (m *RegistryDefault) PreLoginHooks() (b []login.PreHookExecutor) {
 }
 
 func (m *RegistryDefault) PostLoginHooks(credentialsType identity.CredentialsType) (b []login.PostHookExecutor) {
+	// Strategies may need to act on a successful login, e.g. to complete a pending account link.
+	for _, s := range m.LoginStrategies() {
+		if hook, ok := s.(login.PostHookExecutor); ok {
+			b = append(b, hook)
+		}
+	}
+
+	// Users deactivated by the SCIM provisioning client must not be able to sign in.
+	if m.c.SCIMEnabled() {
+		b = append(b, m.SCIMLoginHook())
+	}
+
 	for _, v := range m.getHooks(string(credentialsType), m.c.SelfServiceFlowLoginAfterHooks(string(credentialsType))) {
 		if hook, ok := v.(login.PostHookExecutor); ok {
 			b = append(b, hook)
@@ -17,9 +17,11 @@ func (m *RegistryDefault) IdentityTraitsSchemas() schema.Schemas {
 		}
 
 		ss = append(ss, schema.Schema{
-			ID:     s.ID,
-			URL:    surl,
-			RawURL: s.URL,
+			ID:           s.ID,
+			URL:          surl,
+			RawURL:       s.URL,
+			Version:      s.Version,
+			MigrationURL: s.MigrationURL,
 		})
 	}
 
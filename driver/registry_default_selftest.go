@@ -0,0 +1,217 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/cenkalti/backoff"
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/x/fetcher"
+
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/courier/template"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+)
+
+type (
+	// SelfTestCheck is the result of a single self-test check.
+	SelfTestCheck struct {
+		Name  string
+		Error string
+	}
+
+	// SelfTestReport collects the results of all self-test checks.
+	SelfTestReport struct {
+		Checks []SelfTestCheck
+	}
+)
+
+func (r *SelfTestReport) add(name string, err error) {
+	c := SelfTestCheck{Name: name}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	r.Checks = append(r.Checks, c)
+}
+
+// Failed returns true if at least one check failed.
+func (r *SelfTestReport) Failed() bool {
+	for _, c := range r.Checks {
+		if c.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes a human readable version of the report to w.
+func (r *SelfTestReport) Write(w io.Writer) {
+	for _, c := range r.Checks {
+		if c.Error == "" {
+			_, _ = fmt.Fprintf(w, "[OK]   %s\n", c.Name)
+		} else {
+			_, _ = fmt.Fprintf(w, "[FAIL] %s: %s\n", c.Name, c.Error)
+		}
+	}
+}
+
+// SelfTest checks database connectivity and migrations, identity schemas, courier templates,
+// Jsonnet mappers, and performs a courier dry-run without serving any traffic.
+//
+// If the registry has not been initialized yet, SelfTest connects to the database exactly once
+// instead of retrying, and reports a failed connection as a failed check.
+func (m *RegistryDefault) SelfTest(ctx context.Context) *SelfTestReport {
+	r := new(SelfTestReport)
+
+	if m.persister == nil {
+		if err := m.init(&backoff.StopBackOff{}); err != nil {
+			r.add("database connection", err)
+			r.add("database migrations", errors.New("skipped because the database connection failed"))
+		}
+	}
+
+	if m.persister != nil {
+		r.add("database connection", m.Persister().Ping(ctx))
+		r.add("database migrations", m.selfTestMigrations(ctx))
+	}
+
+	for _, s := range m.IdentityTraitsSchemas() {
+		r.add(fmt.Sprintf("identity schema %q", s.ID), m.selfTestSchema(s))
+	}
+
+	for _, t := range m.selfTestTemplates() {
+		r.add(fmt.Sprintf("courier template %q", t.name), selfTestTemplate(t.t))
+	}
+
+	for _, s := range m.selfServiceStrategies() {
+		if s, ok := s.(*oidc.Strategy); ok {
+			config := s.Config
+			if m.persister == nil {
+				// Providers created using the admin API can not be loaded without a database connection.
+				config = s.FileConfig
+			}
+
+			conf, err := config()
+			if err != nil {
+				r.add("oidc configuration", err)
+				continue
+			}
+
+			f := fetcher.NewFetcher()
+			for _, p := range conf.Providers {
+				r.add(fmt.Sprintf("oidc provider %q jsonnet mapper", p.ID), selfTestJsonnet(f, p.Mapper))
+			}
+		}
+	}
+
+	r.add("courier dry-run", m.Courier().DryRun(ctx, m.selfTestTemplates()[0].t))
+	return r
+}
+
+func (m *RegistryDefault) selfTestMigrations(ctx context.Context) error {
+	pending, err := m.Persister().PendingMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(pending) > 0 {
+		return errors.Errorf("%d migration(s) have not been applied yet, first pending migration is %s", len(pending), pending[0])
+	}
+	return nil
+}
+
+func (m *RegistryDefault) selfTestSchema(s schema.Schema) error {
+	runner, err := schema.NewExtensionRunner(schema.ExtensionRunnerIdentityMetaSchema)
+	if err != nil {
+		return err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	runner.Register(compiler)
+
+	_, err = compiler.Compile(s.URL.String())
+	return errors.WithStack(err)
+}
+
+type selfTestTemplateEntry struct {
+	name string
+	t    courier.EmailTemplate
+}
+
+func (m *RegistryDefault) selfTestTemplates() []selfTestTemplateEntry {
+	return []selfTestTemplateEntry{
+		{name: "verification/valid", t: template.NewVerificationValid(m.c, &template.VerificationValidModel{To: "self-test@example.org", VerificationURL: "https://example.org/verify"})},
+		{name: "verification/code", t: template.NewVerificationCode(m.c, &template.VerificationCodeModel{To: "self-test@example.org", VerificationCode: "123456"})},
+		{name: "verification/invalid", t: template.NewVerificationInvalid(m.c, &template.VerificationInvalidModel{To: "self-test@example.org"})},
+		{name: "recovery/valid", t: template.NewRecoveryValid(m.c, &template.RecoveryValidModel{To: "self-test@example.org", RecoveryURL: "https://example.org/recover"})},
+		{name: "recovery/invalid", t: template.NewRecoveryInvalid(m.c, &template.RecoveryInvalidModel{To: "self-test@example.org"})},
+	}
+}
+
+func selfTestTemplate(t courier.EmailTemplate) error {
+	if _, err := t.EmailSubject(); err != nil {
+		return err
+	}
+	_, err := t.EmailBody()
+	return err
+}
+
+// selfTestClaims are the claims used to evaluate OpenID Connect Jsonnet mappers. All claims are set
+// so that mappers which expect a claim to be present evaluate successfully.
+var selfTestClaims = &oidc.Claims{
+	Issuer:              "https://self-test.example.org",
+	Subject:             "self-test",
+	Name:                "Self Test",
+	GivenName:           "Self",
+	FamilyName:          "Test",
+	LastName:            "Test",
+	MiddleName:          "Self",
+	Nickname:            "self-test",
+	PreferredUsername:   "self-test",
+	Profile:             "https://self-test.example.org/profile",
+	Picture:             "https://self-test.example.org/picture.png",
+	Website:             "https://self-test.example.org",
+	Email:               "self-test@example.org",
+	EmailVerified:       true,
+	Gender:              "other",
+	Birthdate:           "1970-01-01",
+	Zoneinfo:            "Europe/Berlin",
+	Locale:              "en-US",
+	PhoneNumber:         "+15555550100",
+	PhoneNumberVerified: true,
+	UpdatedAt:           1,
+}
+
+func selfTestJsonnet(f *fetcher.Fetcher, source string) error {
+	jn, err := f.Fetch(source)
+	if err != nil {
+		return err
+	}
+
+	// The mapper is evaluated instead of linted because the linter shipped with go-jsonnet v0.16 reports
+	// every local variable used inside an object as unused. Evaluating catches syntax errors, undefined
+	// variables, and runtime errors alike.
+	claims, err := json.Marshal(selfTestClaims)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtCode("claims", string(claims))
+	evaluated, err := vm.EvaluateSnippet(source, jn.String())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !gjson.Get(evaluated, "identity.traits").IsObject() {
+		return errors.New("the mapper did not return an object at identity.traits")
+	}
+	return nil
+}
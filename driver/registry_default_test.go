@@ -3,6 +3,7 @@ package driver_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/ory/kratos/driver"
 	"github.com/ory/x/configx"
@@ -18,6 +19,8 @@ import (
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/hook"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
 )
 
 func TestDriverDefault_Hooks(t *testing.T) {
@@ -189,3 +192,22 @@ func TestDriverDefault_Strategies(t *testing.T) {
 		}
 	})
 }
+
+func TestDriverDefault_UUIDVersion(t *testing.T) {
+	defer x.UseUUIDv7(false)
+
+	for _, version := range []int{4, 7} {
+		t.Run(fmt.Sprintf("version=%d", version), func(t *testing.T) {
+			conf := internal.NewConfigurationWithDefaults()
+			conf.MustSet(config.ViperKeyDatabaseUUIDVersion, version)
+
+			reg, err := driver.NewRegistryFromDSN(conf, logrusx.New("", ""))
+			require.NoError(t, err)
+			require.NoError(t, reg.Init())
+
+			i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+			assert.EqualValues(t, version, i.ID.Version())
+			assert.EqualValues(t, version, session.NewActiveSession(i, conf, time.Now()).ID.Version())
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package growth
+
+import (
+	"context"
+	"time"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+// aggregateInterval is how often the aggregator checks whether a day has passed.
+const aggregateInterval = time.Hour
+
+type (
+	aggregatorDependencies interface {
+		x.LoggingProvider
+		x.ClockProvider
+		PersistenceProvider
+	}
+	AggregatorProvider interface {
+		IdentityGrowthAggregator() *Aggregator
+	}
+
+	// Aggregator computes the daily rollups of the cohorts once a day has passed.
+	Aggregator struct {
+		d aggregatorDependencies
+		c *config.Provider
+	}
+)
+
+func NewAggregator(d aggregatorDependencies, c *config.Provider) *Aggregator {
+	return &Aggregator{d: d, c: c}
+}
+
+// Aggregate computes and stores the rollups of the day, one for each cohort registered on the day or on one of the
+// tracked days before it. The rollup of the cohort registered on the day itself is stored even if it is empty,
+// while older empty cohorts are skipped.
+func (a *Aggregator) Aggregate(ctx context.Context, day time.Time) ([]Rollup, error) {
+	day = Day(day)
+	now := a.d.Clock().Now().UTC()
+
+	days := a.c.IdentityGrowthStatsCohortDays()
+	rs := make([]Rollup, 0, days+1)
+	for k := 0; k <= days; k++ {
+		r, err := a.d.IdentityGrowthPersister().CountGrowthCohort(ctx, day.AddDate(0, 0, -k), day)
+		if err != nil {
+			return nil, err
+		}
+
+		if k > 0 && r.CohortSize == 0 {
+			continue
+		}
+
+		r.ID = x.NewUUID()
+		r.CreatedAt = now
+		r.computeRatios()
+		rs = append(rs, *r)
+	}
+
+	if err := a.d.IdentityGrowthPersister().ReplaceGrowthRollups(ctx, day, rs); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// AggregatePassedDays computes the rollups of all days which passed since the most recent rollup, but at most of
+// as many days as cohorts are tracked.
+func (a *Aggregator) AggregatePassedDays(ctx context.Context) error {
+	yesterday := Day(a.d.Clock().Now()).AddDate(0, 0, -1)
+	oldest := yesterday.AddDate(0, 0, -a.c.IdentityGrowthStatsCohortDays())
+
+	latest, err := a.d.IdentityGrowthPersister().LatestGrowthRollupDay(ctx)
+	if err != nil {
+		return err
+	}
+
+	next := yesterday
+	if !latest.IsZero() {
+		if next = Day(latest).AddDate(0, 0, 1); next.Before(oldest) {
+			next = oldest
+		}
+	}
+
+	for day := next; !day.After(yesterday); day = day.AddDate(0, 0, 1) {
+		rs, err := a.Aggregate(ctx, day)
+		if err != nil {
+			return err
+		}
+		a.d.Logger().WithField("day", day.Format(dayLayout)).WithField("rollups", len(rs)).Debug("Computed the identity growth rollups.")
+	}
+	return nil
+}
+
+// Watch computes the rollups of passed days until the context is canceled.
+func (a *Aggregator) Watch(ctx context.Context) {
+	for {
+		if a.c.IdentityGrowthStatsEnabled() {
+			if err := a.AggregatePassedDays(ctx); err != nil {
+				a.d.Logger().WithError(err).Error("Unable to compute the identity growth rollups.")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(aggregateInterval):
+		}
+	}
+}
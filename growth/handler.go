@@ -0,0 +1,172 @@
+package growth
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+const RouteCollection = "/stats/identities"
+
+type (
+	handlerDependencies interface {
+		x.WriterProvider
+		x.LoggingProvider
+		x.ClockProvider
+		PersistenceProvider
+	}
+	HandlerProvider interface {
+		IdentityGrowthHandler() *Handler
+	}
+	Handler struct {
+		d handlerDependencies
+		c *config.Provider
+	}
+)
+
+// A list of identity growth rollups.
+// swagger:model identityGrowthRollupList
+// nolint:deadcode,unused
+type rollupList []Rollup
+
+func NewHandler(d handlerDependencies, c *config.Provider) *Handler {
+	return &Handler{d: d, c: c}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteCollection, h.list)
+}
+
+// nolint:deadcode,unused
+// swagger:parameters listIdentityGrowthRollups
+type listIdentityGrowthRollupsParameters struct {
+	// Since is the first day formatted as `2006-01-02` and defaults to 29 days before `until`.
+	//
+	// in: query
+	Since string `json:"since"`
+
+	// Until is the last day formatted as `2006-01-02` and defaults to yesterday.
+	//
+	// in: query
+	Until string `json:"until"`
+
+	// Format is either `json`, which is the default, or `csv`.
+	//
+	// in: query
+	Format string `json:"format"`
+}
+
+// swagger:route GET /stats/identities admin listIdentityGrowthRollups
+//
+// List Identity Growth Rollups
+//
+// Lists the daily rollups of the days in the given range, ordered by day and cohort. A cohort are the identities
+// which registered on the same day. The rollup of a cohort on the day it registered describes the growth of
+// identities, its rollups on later days the retention of the cohort. Rollups are computed once a day has passed
+// and require `identity.growth_stats.enabled` to be set.
+//
+// Set `format=csv` to export the rollups as CSV.
+//
+//     Produces:
+//     - application/json
+//     - text/csv
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityGrowthRollupList
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) list(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !h.c.IdentityGrowthStatsEnabled() {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("Identity growth statistics are disabled. Set `identity.growth_stats.enabled` to compute them.")))
+		return
+	}
+
+	until := Day(h.d.Clock().Now()).AddDate(0, 0, -1)
+	if v := r.URL.Query().Get("until"); v != "" {
+		t, err := time.Parse(dayLayout, v)
+		if err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Query parameter "until" must contain a day formatted as 2006-01-02: %s`, err)))
+			return
+		}
+		until = t
+	}
+
+	since := until.AddDate(0, 0, -29)
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(dayLayout, v)
+		if err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Query parameter "since" must contain a day formatted as 2006-01-02: %s`, err)))
+			return
+		}
+		since = t
+	}
+
+	if since.After(until) {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason(`Query parameter "since" must not be after "until".`)))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason(`Query parameter "format" must be "json" or "csv".`)))
+		return
+	}
+
+	rs, err := h.d.IdentityGrowthPersister().ListGrowthRollups(r.Context(), since, until)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	for k := range rs {
+		rs[k].Cohort, rs[k].Day = rs[k].Cohort.UTC(), rs[k].Day.UTC()
+		rs[k].computeRatios()
+	}
+
+	if format == "csv" {
+		h.writeCSV(w, r, rs)
+		return
+	}
+
+	h.d.Writer().Write(w, r, rs)
+}
+
+func (h *Handler) writeCSV(w http.ResponseWriter, r *http.Request, rs []Rollup) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="identity-growth.csv"`)
+
+	out := csv.NewWriter(w)
+	records := [][]string{{
+		"cohort", "day", "days_since_registration", "cohort_size", "verified_identities", "verified_ratio",
+		"active_identities", "retention", "active_sessions",
+	}}
+	for _, rollup := range rs {
+		records = append(records, []string{
+			rollup.Cohort.Format(dayLayout),
+			rollup.Day.Format(dayLayout),
+			strconv.Itoa(rollup.DaysSinceRegistration),
+			strconv.Itoa(rollup.CohortSize),
+			strconv.Itoa(rollup.VerifiedIdentities),
+			strconv.FormatFloat(rollup.VerifiedRatio, 'f', 4, 64),
+			strconv.Itoa(rollup.ActiveIdentities),
+			strconv.FormatFloat(rollup.Retention, 'f', 4, 64),
+			strconv.Itoa(rollup.ActiveSessions),
+		})
+	}
+
+	if err := out.WriteAll(records); err != nil {
+		h.d.Logger().WithRequest(r).WithError(err).Error("Unable to write the identity growth rollups as CSV.")
+	}
+}
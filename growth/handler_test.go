@@ -0,0 +1,125 @@
+package growth_test
+
+import (
+	"context"
+	"encoding/csv"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/growth"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+func TestHandler(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+	conf.MustSet(config.ViperKeyIdentityGrowthStatsEnabled, true)
+	conf.MustSet(config.ViperKeyIdentityGrowthStatsCohortDays, 2)
+	_, adminTS := testhelpers.NewKratosServer(t, reg)
+
+	ctx := context.Background()
+	yesterday := growth.Day(time.Now()).AddDate(0, 0, -1)
+
+	newIdentity := func(t *testing.T, createdAt time.Time, verified bool, sessionIssuedAt time.Time) {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.CreatedAt = createdAt
+		i.VerifiableAddresses = []identity.VerifiableAddress{{
+			Value:    x.NewUUID().String() + "@ory.sh",
+			Via:      identity.VerifiableAddressTypeEmail,
+			Verified: verified,
+			Status:   identity.VerifiableAddressStatusPending,
+		}}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+
+		s := session.NewActiveSession(i, conf, sessionIssuedAt)
+		s.IssuedAt = sessionIssuedAt
+		require.NoError(t, reg.SessionPersister().CreateSession(ctx, s))
+	}
+
+	newIdentity(t, yesterday.Add(time.Hour), true, yesterday.Add(time.Hour))
+	newIdentity(t, yesterday.Add(2*time.Hour), false, yesterday.Add(2*time.Hour))
+	newIdentity(t, yesterday.AddDate(0, 0, -1), true, yesterday.Add(3*time.Hour))
+	newIdentity(t, yesterday.AddDate(0, 0, -1), false, yesterday.AddDate(0, 0, -1))
+	newIdentity(t, yesterday.AddDate(0, 0, -5), true, yesterday.Add(4*time.Hour))
+
+	get := func(t *testing.T, query string, expectedStatus int) []byte {
+		res, err := adminTS.Client().Get(adminTS.URL + growth.RouteCollection + query)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, expectedStatus, res.StatusCode, "%s", body)
+		return body
+	}
+
+	t.Run("case=computes the rollups of passed days", func(t *testing.T) {
+		require.NoError(t, reg.IdentityGrowthAggregator().AggregatePassedDays(ctx))
+		// Days with rollups are not computed again.
+		require.NoError(t, reg.IdentityGrowthAggregator().AggregatePassedDays(ctx))
+
+		rs := gjson.ParseBytes(get(t, "", http.StatusOK))
+		require.Len(t, rs.Array(), 2, "%s", rs.Raw)
+
+		retained := rs.Get("0")
+		assert.Equal(t, yesterday.AddDate(0, 0, -1), retained.Get("cohort").Time())
+		assert.Equal(t, yesterday, retained.Get("day").Time())
+		assert.EqualValues(t, 1, retained.Get("days_since_registration").Int())
+		assert.EqualValues(t, 2, retained.Get("cohort_size").Int())
+		assert.EqualValues(t, 1, retained.Get("active_identities").Int())
+		assert.EqualValues(t, 1, retained.Get("active_sessions").Int())
+		assert.Equal(t, 0.5, retained.Get("retention").Float())
+		assert.Equal(t, 0.5, retained.Get("verified_ratio").Float())
+
+		registered := rs.Get("1")
+		assert.Equal(t, yesterday, registered.Get("cohort").Time())
+		assert.EqualValues(t, 0, registered.Get("days_since_registration").Int())
+		assert.EqualValues(t, 2, registered.Get("cohort_size").Int())
+		assert.EqualValues(t, 1, registered.Get("verified_identities").Int())
+		assert.Equal(t, 1.0, registered.Get("retention").Float())
+	})
+
+	t.Run("case=exports the rollups as CSV", func(t *testing.T) {
+		res, err := adminTS.Client().Get(adminTS.URL + growth.RouteCollection + "?format=csv&since=" + yesterday.Format("2006-01-02"))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, "text/csv; charset=utf-8", res.Header.Get("Content-Type"))
+
+		records, err := csv.NewReader(res.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, []string{"cohort", "day", "days_since_registration", "cohort_size", "verified_identities", "verified_ratio", "active_identities", "retention", "active_sessions"}, records[0])
+		assert.Equal(t, []string{
+			yesterday.AddDate(0, 0, -1).Format("2006-01-02"), yesterday.Format("2006-01-02"),
+			"1", "2", "1", "0.5000", "1", "0.5000", "1",
+		}, records[1])
+	})
+
+	t.Run("case=filters by day", func(t *testing.T) {
+		assert.Equal(t, "[]", string(get(t, "?until="+yesterday.AddDate(0, 0, -1).Format("2006-01-02"), http.StatusOK)))
+	})
+
+	t.Run("case=rejects invalid parameters", func(t *testing.T) {
+		get(t, "?since=yesterday", http.StatusBadRequest)
+		get(t, "?since=2021-01-02&until=2021-01-01", http.StatusBadRequest)
+		get(t, "?format=xml", http.StatusBadRequest)
+	})
+
+	t.Run("case=is not found if disabled", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyIdentityGrowthStatsEnabled, false)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyIdentityGrowthStatsEnabled, true)
+		})
+		get(t, "", http.StatusNotFound)
+	})
+}
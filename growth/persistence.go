@@ -0,0 +1,134 @@
+package growth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	Persister interface {
+		// CountGrowthCohort counts the identities which registered on the day of the cohort, how many of them
+		// verified an address, and how many of them issued or used a session on the day. Deleted identities are
+		// not counted.
+		CountGrowthCohort(ctx context.Context, cohort, day time.Time) (*Rollup, error)
+
+		// ReplaceGrowthRollups replaces the rollups of the day.
+		ReplaceGrowthRollups(ctx context.Context, day time.Time, rs []Rollup) error
+
+		// ListGrowthRollups lists the rollups of the days between since and until, both inclusive, ordered by day
+		// and cohort.
+		ListGrowthRollups(ctx context.Context, since, until time.Time) ([]Rollup, error)
+
+		// LatestGrowthRollupDay returns the most recent day with rollups or the zero time if there are none.
+		LatestGrowthRollupDay(ctx context.Context) (time.Time, error)
+	}
+
+	PersistenceProvider interface {
+		IdentityGrowthPersister() Persister
+	}
+)
+
+func TestPersister(conf *config.Provider, p interface {
+	Persister
+	identity.PrivilegedPool
+	session.Persister
+}) func(t *testing.T) {
+	return func(t *testing.T) {
+		conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+		ctx := context.Background()
+
+		// The cohort is in the past so that identities created by other tests are not counted.
+		cohort := time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC)
+		day := cohort.AddDate(0, 0, 2)
+
+		newIdentity := func(t *testing.T, createdAt time.Time, verified bool) *identity.Identity {
+			i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+			i.CreatedAt = createdAt
+			i.VerifiableAddresses = []identity.VerifiableAddress{{
+				Value:    x.NewUUID().String() + "@ory.sh",
+				Via:      identity.VerifiableAddressTypeEmail,
+				Verified: verified,
+				Status:   identity.VerifiableAddressStatusPending,
+			}}
+			require.NoError(t, p.CreateIdentity(ctx, i))
+			return i
+		}
+
+		newSession := func(t *testing.T, i *identity.Identity, issuedAt time.Time, lastActiveAt *time.Time) {
+			s := session.NewActiveSession(i, conf, issuedAt)
+			s.IssuedAt = issuedAt
+			s.LastActiveAt = lastActiveAt
+			require.NoError(t, p.CreateSession(ctx, s))
+		}
+
+		verified := newIdentity(t, cohort.Add(time.Hour), true)
+		unverified := newIdentity(t, cohort.Add(23*time.Hour), false)
+		deleted := newIdentity(t, cohort.Add(2*time.Hour), true)
+		require.NoError(t, p.SoftDeleteIdentity(ctx, deleted.ID))
+		other := newIdentity(t, cohort.Add(-time.Minute), true)
+
+		usedOnDay := day.Add(time.Hour)
+		newSession(t, verified, day.Add(2*time.Hour), nil)
+		newSession(t, verified, cohort, &usedOnDay)
+		newSession(t, unverified, cohort.Add(23*time.Hour), nil)
+		newSession(t, deleted, day.Add(time.Hour), nil)
+		newSession(t, other, day.Add(time.Hour), nil)
+
+		t.Run("case=counts a cohort", func(t *testing.T) {
+			r, err := p.CountGrowthCohort(ctx, cohort, day)
+			require.NoError(t, err)
+			assert.Equal(t, 2, r.CohortSize)
+			assert.Equal(t, 1, r.VerifiedIdentities)
+			assert.Equal(t, 1, r.ActiveIdentities)
+			assert.Equal(t, 2, r.ActiveSessions)
+
+			r, err = p.CountGrowthCohort(ctx, cohort, cohort)
+			require.NoError(t, err)
+			assert.Equal(t, 2, r.CohortSize)
+			assert.Equal(t, 2, r.ActiveIdentities)
+			assert.Equal(t, 2, r.ActiveSessions)
+		})
+
+		t.Run("case=replaces and lists rollups", func(t *testing.T) {
+			latest, err := p.LatestGrowthRollupDay(ctx)
+			require.NoError(t, err)
+			assert.True(t, latest.IsZero() || latest.After(day), "%s", latest)
+
+			newRollup := func(cohort, day time.Time, size int) Rollup {
+				return Rollup{ID: x.NewUUID(), Cohort: cohort, Day: day, CohortSize: size, CreatedAt: time.Now().UTC()}
+			}
+
+			require.NoError(t, p.ReplaceGrowthRollups(ctx, day, []Rollup{newRollup(day, day, 1), newRollup(cohort, day, 1)}))
+			require.NoError(t, p.ReplaceGrowthRollups(ctx, day, []Rollup{newRollup(day, day, 3), newRollup(cohort, day, 2)}))
+			require.NoError(t, p.ReplaceGrowthRollups(ctx, cohort, []Rollup{newRollup(cohort, cohort, 2)}))
+
+			rs, err := p.ListGrowthRollups(ctx, cohort, day)
+			require.NoError(t, err)
+			require.Len(t, rs, 3)
+			for k, expected := range []Rollup{newRollup(cohort, cohort, 2), newRollup(cohort, day, 2), newRollup(day, day, 3)} {
+				assert.Equal(t, expected.Cohort, rs[k].Cohort.UTC())
+				assert.Equal(t, expected.Day, rs[k].Day.UTC())
+				assert.Equal(t, expected.CohortSize, rs[k].CohortSize)
+			}
+
+			rs, err = p.ListGrowthRollups(ctx, day, day.AddDate(0, 0, 1))
+			require.NoError(t, err)
+			assert.Len(t, rs, 2)
+
+			if latest.IsZero() {
+				latest, err = p.LatestGrowthRollupDay(ctx)
+				require.NoError(t, err)
+				assert.Equal(t, day, latest.UTC())
+			}
+		})
+	}
+}
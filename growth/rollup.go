@@ -0,0 +1,86 @@
+package growth
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// dayLayout formats days in query parameters and CSV exports.
+const dayLayout = "2006-01-02"
+
+// Rollup counts the activity of a cohort, which are the identities registered on the same day, on a single day.
+//
+// swagger:model identityGrowthRollup
+type Rollup struct {
+	ID uuid.UUID `json:"-" faker:"-" db:"id"`
+
+	// Cohort is the day the identities of the cohort registered at.
+	//
+	// required: true
+	Cohort time.Time `json:"cohort" db:"cohort"`
+
+	// Day is the day the activity was counted at. On the day of the cohort, the rollup describes the growth of
+	// identities, on later days their retention.
+	//
+	// required: true
+	Day time.Time `json:"day" db:"day"`
+
+	// DaysSinceRegistration is the number of days between the cohort and the day.
+	//
+	// required: true
+	DaysSinceRegistration int `json:"days_since_registration" db:"-"`
+
+	// CohortSize is the number of identities which registered on the day of the cohort.
+	//
+	// required: true
+	CohortSize int `json:"cohort_size" db:"cohort_size"`
+
+	// VerifiedIdentities is the number of identities of the cohort with at least one verified address.
+	//
+	// required: true
+	VerifiedIdentities int `json:"verified_identities" db:"verified_identities"`
+
+	// VerifiedRatio is the share of verified identities in the cohort.
+	//
+	// required: true
+	VerifiedRatio float64 `json:"verified_ratio" db:"-"`
+
+	// ActiveIdentities is the number of identities of the cohort with a session issued or used on the day.
+	//
+	// required: true
+	ActiveIdentities int `json:"active_identities" db:"active_identities"`
+
+	// Retention is the share of active identities in the cohort.
+	//
+	// required: true
+	Retention float64 `json:"retention" db:"-"`
+
+	// ActiveSessions is the number of sessions of the cohort issued or used on the day.
+	//
+	// required: true
+	ActiveSessions int `json:"active_sessions" db:"active_sessions"`
+
+	// CreatedAt is the time the rollup was computed at.
+	CreatedAt time.Time `json:"-" faker:"-" db:"created_at"`
+}
+
+func (r Rollup) TableName() string {
+	return "identity_growth_rollups"
+}
+
+// computeRatios sets the fields which are derived from the counts.
+func (r *Rollup) computeRatios() {
+	r.DaysSinceRegistration = int(r.Day.Sub(r.Cohort).Hours() / 24)
+	r.VerifiedRatio, r.Retention = 0, 0
+	if r.CohortSize > 0 {
+		r.VerifiedRatio = float64(r.VerifiedIdentities) / float64(r.CohortSize)
+		r.Retention = float64(r.ActiveIdentities) / float64(r.CohortSize)
+	}
+}
+
+// Day returns the start of the UTC day of the time.
+func Day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
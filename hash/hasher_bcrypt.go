@@ -0,0 +1,49 @@
+package hash
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+// ErrBcryptPasswordTooLong is returned for passwords which BCrypt would silently truncate.
+var ErrBcryptPasswordTooLong = errors.New("passwords are limited to a maximum length of 72 bytes when using BCrypt")
+
+type Bcrypt struct {
+	c BcryptConfiguration
+}
+
+type BcryptConfiguration interface {
+	HasherBcrypt() *config.HasherBcryptConfig
+}
+
+func NewHasherBcrypt(c BcryptConfiguration) *Bcrypt {
+	return &Bcrypt{c: c}
+}
+
+func (h *Bcrypt) Generate(password []byte) ([]byte, error) {
+	if len(password) > 72 {
+		return nil, errors.WithStack(ErrBcryptPasswordTooLong)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword(password, int(h.c.HasherBcrypt().Cost))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return hash, nil
+}
+
+func (h *Bcrypt) Compare(password []byte, hash []byte) error {
+	if len(password) > 72 {
+		return ErrMismatchedHashAndPassword
+	}
+
+	if err := bcrypt.CompareHashAndPassword(hash, password); errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatchedHashAndPassword
+	} else if err != nil {
+		// All other errors are caused by hashes which are not BCrypt hashes, for example Argon2 hashes.
+		return ErrInvalidHash
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package hash
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/x/logrusx"
+)
+
+const (
+	ShadowOutcomeMatch       = "match"
+	ShadowOutcomeDiscrepancy = "discrepancy"
+	ShadowOutcomeSkipped     = "skipped"
+)
+
+var (
+	shadowEvaluations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kratos_hasher_shadow_evaluations_total",
+			Help: "Number of password hashing operations evaluated by the shadow hasher by algorithm, operation, and outcome.",
+		},
+		[]string{"algorithm", "operation", "outcome"},
+	)
+	shadowDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "kratos_hasher_shadow_duration_seconds",
+			Help:    "Duration of the password hashing operations of the active and the shadow hasher.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+		},
+		[]string{"algorithm", "operation", "mode"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(shadowEvaluations, shadowDuration)
+}
+
+// Shadow runs a second hasher alongside the active one. The outcome of every operation is decided by the active
+// hasher only. The shadow hasher evaluates the same operation in the background and discrepancies as well as
+// timings are logged and exported as metrics, which de-risks migrating to another hashing algorithm.
+type Shadow struct {
+	active          Hasher
+	activeAlgorithm string
+	shadow          Hasher
+	shadowAlgorithm string
+	l               *logrusx.Logger
+	wg              sync.WaitGroup
+}
+
+func NewHasherShadow(active Hasher, activeAlgorithm string, shadow Hasher, shadowAlgorithm string, l *logrusx.Logger) *Shadow {
+	return &Shadow{
+		active:          active,
+		activeAlgorithm: activeAlgorithm,
+		shadow:          shadow,
+		shadowAlgorithm: shadowAlgorithm,
+		l:               l,
+	}
+}
+
+// Generate returns the hash of the active hasher. The shadow hasher generates a hash as well, which must be
+// accepted by its own Compare, and is discarded.
+func (h *Shadow) Generate(password []byte) ([]byte, error) {
+	start := time.Now()
+	hash, err := h.active.Generate(password)
+	shadowDuration.WithLabelValues(h.activeAlgorithm, "generate", "active").Observe(time.Since(start).Seconds())
+
+	h.evaluate(password, func(password []byte) {
+		start := time.Now()
+		shadowHash, shadowErr := h.shadow.Generate(password)
+		shadowDuration.WithLabelValues(h.shadowAlgorithm, "generate", "shadow").Observe(time.Since(start).Seconds())
+		if shadowErr == nil {
+			shadowErr = h.shadow.Compare(password, shadowHash)
+		}
+
+		if (err == nil) != (shadowErr == nil) {
+			h.discrepancy("generate", err, shadowErr)
+			return
+		}
+		shadowEvaluations.WithLabelValues(h.shadowAlgorithm, "generate", ShadowOutcomeMatch).Inc()
+	})
+
+	return hash, err
+}
+
+// Compare returns the result of the active hasher. The shadow hasher compares the password as well if it
+// understands the format of the hash.
+func (h *Shadow) Compare(password []byte, hash []byte) error {
+	start := time.Now()
+	err := h.active.Compare(password, hash)
+	shadowDuration.WithLabelValues(h.activeAlgorithm, "compare", "active").Observe(time.Since(start).Seconds())
+
+	stored := make([]byte, len(hash))
+	copy(stored, hash)
+	h.evaluate(password, func(password []byte) {
+		start := time.Now()
+		shadowErr := h.shadow.Compare(password, stored)
+		shadowDuration.WithLabelValues(h.shadowAlgorithm, "compare", "shadow").Observe(time.Since(start).Seconds())
+
+		if errors.Is(shadowErr, ErrInvalidHash) {
+			// The hash was generated by another algorithm, which is expected during a migration.
+			shadowEvaluations.WithLabelValues(h.shadowAlgorithm, "compare", ShadowOutcomeSkipped).Inc()
+			return
+		}
+
+		if (err == nil) != (shadowErr == nil) {
+			h.discrepancy("compare", err, shadowErr)
+			return
+		}
+		shadowEvaluations.WithLabelValues(h.shadowAlgorithm, "compare", ShadowOutcomeMatch).Inc()
+	})
+
+	return err
+}
+
+// Wait blocks until all shadow evaluations have completed.
+func (h *Shadow) Wait() {
+	h.wg.Wait()
+}
+
+func (h *Shadow) evaluate(password []byte, f func(password []byte)) {
+	// The caller may reuse the password once the active hasher returned.
+	p := make([]byte, len(password))
+	copy(p, password)
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				h.l.WithField("algorithm", h.shadowAlgorithm).WithField("panic", r).Error("The shadow hasher panicked.")
+			}
+		}()
+		f(p)
+	}()
+}
+
+func (h *Shadow) discrepancy(operation string, activeErr, shadowErr error) {
+	shadowEvaluations.WithLabelValues(h.shadowAlgorithm, operation, ShadowOutcomeDiscrepancy).Inc()
+	l := h.l.
+		WithField("operation", operation).
+		WithField("active_algorithm", h.activeAlgorithm).
+		WithField("shadow_algorithm", h.shadowAlgorithm)
+	if activeErr != nil {
+		l = l.WithField("active_error", activeErr.Error())
+	}
+	if shadowErr != nil {
+		l = l.WithField("shadow_error", shadowErr.Error())
+	}
+	l.Warn("The shadow hasher disagrees with the active hasher.")
+}
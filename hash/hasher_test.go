@@ -2,17 +2,23 @@ package hash_test
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/x/logrusx"
+
+	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/hash"
 	"github.com/ory/kratos/internal"
 )
 
-func mkpw(t *testing.T, length int) []byte {
+func mkpw(t testing.TB, length int) []byte {
 	pw := make([]byte, length)
 	_, err := rand.Read(pw)
 	require.NoError(t, err)
@@ -29,11 +35,17 @@ func TestHasher(t *testing.T) {
 	} {
 		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
 			conf := internal.NewConfigurationWithDefaults()
+			conf.MustSet(config.ViperKeyHasherBcryptConfigCost, 4)
 			for kk, h := range []hash.Hasher{
 				hash.NewHasherArgon2(conf),
+				hash.NewHasherBcrypt(conf),
 			} {
 				t.Run(fmt.Sprintf("hasher=%T/password=%d", h, kk), func(t *testing.T) {
 					hs, err := h.Generate(pw)
+					if _, ok := h.(*hash.Bcrypt); ok && len(pw) > 72 {
+						require.True(t, errors.Is(err, hash.ErrBcryptPasswordTooLong), "%+v", err)
+						return
+					}
 					require.NoError(t, err)
 					assert.NotEqual(t, pw, hs)
 
@@ -49,3 +61,100 @@ func TestHasher(t *testing.T) {
 		})
 	}
 }
+
+type failingHasher struct{ hash.Hasher }
+
+func (h failingHasher) Compare(_ []byte, _ []byte) error {
+	return hash.ErrMismatchedHashAndPassword
+}
+
+func TestShadowHasher(t *testing.T) {
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(config.ViperKeyHasherBcryptConfigCost, 4)
+	argon2, bcrypt := hash.NewHasherArgon2(conf), hash.NewHasherBcrypt(conf)
+	pw := mkpw(t, 16)
+
+	newShadow := func(active, shadow hash.Hasher) (*hash.Shadow, *test.Hook) {
+		hook := new(test.Hook)
+		return hash.NewHasherShadow(active, "active", shadow, "shadow", logrusx.New("", "", logrusx.WithHook(hook))), hook
+	}
+
+	t.Run("case=outcome is decided by the active hasher", func(t *testing.T) {
+		h, hook := newShadow(bcrypt, argon2)
+
+		hs, err := h.Generate(pw)
+		require.NoError(t, err)
+		require.NoError(t, bcrypt.Compare(pw, hs))
+
+		require.NoError(t, h.Compare(pw, hs))
+		require.Error(t, h.Compare(mkpw(t, 16), hs))
+
+		h.Wait()
+		assert.Empty(t, hook.AllEntries(), "hashes of another algorithm must be skipped")
+	})
+
+	t.Run("case=agreeing hashers do not log", func(t *testing.T) {
+		h, hook := newShadow(argon2, argon2)
+
+		hs, err := h.Generate(pw)
+		require.NoError(t, err)
+		require.NoError(t, h.Compare(pw, hs))
+		require.Error(t, h.Compare(mkpw(t, 16), hs))
+
+		h.Wait()
+		assert.Empty(t, hook.AllEntries())
+	})
+
+	t.Run("case=discrepancies are logged without affecting the outcome", func(t *testing.T) {
+		h, hook := newShadow(argon2, failingHasher{Hasher: argon2})
+
+		hs, err := h.Generate(pw)
+		require.NoError(t, err)
+		require.NoError(t, h.Compare(pw, hs))
+
+		h.Wait()
+		require.Len(t, hook.AllEntries(), 2)
+		for _, e := range hook.AllEntries() {
+			assert.Equal(t, logrus.WarnLevel, e.Level)
+			assert.Equal(t, "shadow", e.Data["shadow_algorithm"])
+			assert.Equal(t, hash.ErrMismatchedHashAndPassword.Error(), e.Data["shadow_error"])
+		}
+	})
+}
+
+// BenchmarkHasher measures the hashers, which dominate the latency of password registration and login. Argon2 uses
+// 128 MiB of memory instead of the default of 4 GiB so that the benchmark runs on CI machines.
+func BenchmarkHasher(b *testing.B) {
+	conf := internal.NewConfigurationWithDefaults()
+	conf.MustSet(config.ViperKeyHasherArgon2ConfigMemory, 128*1024)
+	conf.MustSet(config.ViperKeyHasherArgon2ConfigIterations, 1)
+	conf.MustSet(config.ViperKeyHasherArgon2ConfigParallelism, config.Argon2DefaultParallelism)
+	conf.MustSet(config.ViperKeyHasherArgon2ConfigSaltLength, config.Argon2DefaultSaltLength)
+	conf.MustSet(config.ViperKeyHasherArgon2ConfigKeyLength, config.Argon2DefaultKeyLength)
+	conf.MustSet(config.ViperKeyHasherBcryptConfigCost, config.BcryptDefaultCost)
+	pw := mkpw(b, 16)
+
+	for _, h := range []hash.Hasher{
+		hash.NewHasherArgon2(conf),
+		hash.NewHasherBcrypt(conf),
+	} {
+		hs, err := h.Generate(pw)
+		require.NoError(b, err)
+
+		b.Run(fmt.Sprintf("hasher=%T/method=generate", h), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := h.Generate(pw); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("hasher=%T/method=compare", h), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := h.Compare(pw, hs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package hydra
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/httpx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+type (
+	Provider interface {
+		Hydra() *Hydra
+	}
+
+	// Hydra talks to the admin API of ORY Hydra to handle its login and consent challenges.
+	Hydra struct {
+		c      *config.Provider
+		client *http.Client
+	}
+
+	// LoginRequest is ORY Hydra's login request.
+	LoginRequest struct {
+		Challenge  string `json:"challenge"`
+		Skip       bool   `json:"skip"`
+		Subject    string `json:"subject"`
+		RequestURL string `json:"request_url"`
+		Client     Client `json:"client"`
+	}
+
+	// AcceptLoginRequest accepts a login request on behalf of the subject.
+	AcceptLoginRequest struct {
+		Subject     string          `json:"subject"`
+		Remember    bool            `json:"remember"`
+		RememberFor int             `json:"remember_for"`
+		AMR         []string        `json:"amr,omitempty"`
+		Context     json.RawMessage `json:"context,omitempty"`
+	}
+
+	// ConsentRequest is ORY Hydra's consent request.
+	ConsentRequest struct {
+		Challenge         string   `json:"challenge"`
+		Skip              bool     `json:"skip"`
+		Subject           string   `json:"subject"`
+		RequestedScope    []string `json:"requested_scope"`
+		RequestedAudience []string `json:"requested_access_token_audience"`
+		Client            Client   `json:"client"`
+	}
+
+	// AcceptConsentRequest grants the scopes and audiences and sets the claims of the tokens issued by ORY Hydra.
+	AcceptConsentRequest struct {
+		GrantScope    []string       `json:"grant_scope"`
+		GrantAudience []string       `json:"grant_access_token_audience"`
+		Remember      bool           `json:"remember"`
+		RememberFor   int            `json:"remember_for"`
+		Session       ConsentSession `json:"session"`
+	}
+
+	ConsentSession struct {
+		AccessToken map[string]interface{} `json:"access_token,omitempty"`
+		IDToken     map[string]interface{} `json:"id_token,omitempty"`
+	}
+
+	Client struct {
+		ClientID   string `json:"client_id"`
+		ClientName string `json:"client_name"`
+	}
+
+	redirect struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+)
+
+var ErrNotConfigured = herodot.ErrBadRequest.WithReason("An OAuth2 login or consent challenge was received but no OAuth2 provider is configured.")
+
+func NewHydra(c *config.Provider) *Hydra {
+	return &Hydra{c: c, client: httpx.NewResilientClientLatencyToleranceMedium(nil)}
+}
+
+// GetLoginRequest fetches the login request of the challenge.
+func (h *Hydra) GetLoginRequest(ctx context.Context, challenge string) (*LoginRequest, error) {
+	var lr LoginRequest
+	if err := h.do(ctx, "GET", "/oauth2/auth/requests/login", url.Values{"login_challenge": {challenge}}, nil, &lr); err != nil {
+		return nil, err
+	}
+	return &lr, nil
+}
+
+// AcceptLoginRequest accepts the login request of the challenge and returns the URL the browser must be redirected to.
+func (h *Hydra) AcceptLoginRequest(ctx context.Context, challenge string, body *AcceptLoginRequest) (string, error) {
+	var r redirect
+	if err := h.do(ctx, "PUT", "/oauth2/auth/requests/login/accept", url.Values{"login_challenge": {challenge}}, body, &r); err != nil {
+		return "", err
+	}
+	return r.RedirectTo, nil
+}
+
+// GetConsentRequest fetches the consent request of the challenge.
+func (h *Hydra) GetConsentRequest(ctx context.Context, challenge string) (*ConsentRequest, error) {
+	var cr ConsentRequest
+	if err := h.do(ctx, "GET", "/oauth2/auth/requests/consent", url.Values{"consent_challenge": {challenge}}, nil, &cr); err != nil {
+		return nil, err
+	}
+	return &cr, nil
+}
+
+// AcceptConsentRequest accepts the consent request of the challenge and returns the URL the browser must be
+// redirected to.
+func (h *Hydra) AcceptConsentRequest(ctx context.Context, challenge string, body *AcceptConsentRequest) (string, error) {
+	var r redirect
+	if err := h.do(ctx, "PUT", "/oauth2/auth/requests/consent/accept", url.Values{"consent_challenge": {challenge}}, body, &r); err != nil {
+		return "", err
+	}
+	return r.RedirectTo, nil
+}
+
+func (h *Hydra) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	base := h.c.OAuth2ProviderURL()
+	if base == nil {
+		return errors.WithStack(ErrNotConfigured)
+	}
+
+	var body io.Reader
+	if in != nil {
+		var b bytes.Buffer
+		if err := json.NewEncoder(&b).Encode(in); err != nil {
+			return errors.WithStack(err)
+		}
+		body = &b
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, urlx.CopyWithQuery(urlx.AppendPaths(base, path), query).String(), body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to reach the OAuth2 provider: %s", err))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		payload, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		switch res.StatusCode {
+		case http.StatusNotFound, http.StatusConflict, http.StatusGone:
+			// The challenge is unknown, was handled already, or expired.
+			return errors.WithStack(herodot.ErrBadRequest.
+				WithReasonf("The OAuth2 provider rejected the challenge, it may have expired or been used already.").
+				WithDebug(fmt.Sprintf("%d: %s", res.StatusCode, payload)))
+		default:
+			return errors.WithStack(herodot.ErrInternalServerError.
+				WithReasonf("The OAuth2 provider responded with an unexpected status code %d.", res.StatusCode).
+				WithDebug(string(payload)))
+		}
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode the response of the OAuth2 provider: %s", err))
+	}
+	return nil
+}
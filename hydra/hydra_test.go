@@ -0,0 +1,76 @@
+package hydra_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/hydra"
+	"github.com/ory/kratos/internal"
+)
+
+func TestHydra(t *testing.T) {
+	conf, _ := internal.NewFastRegistryWithMocks(t)
+	h := hydra.NewHydra(conf)
+	ctx := context.Background()
+
+	t.Run("case=fails if not configured", func(t *testing.T) {
+		_, err := h.GetLoginRequest(ctx, "challenge")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, hydra.ErrNotConfigured))
+	})
+
+	var accepted hydra.AcceptLoginRequest
+	router := httprouter.New()
+	router.GET("/admin/oauth2/auth/requests/login", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if r.URL.Query().Get("login_challenge") != "valid" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&hydra.LoginRequest{Challenge: "valid", Client: hydra.Client{ClientID: "app"}})
+	})
+	router.PUT("/admin/oauth2/auth/requests/login/accept", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&accepted))
+		_ = json.NewEncoder(w).Encode(map[string]string{"redirect_to": "https://hydra.example/oauth2/auth?login_verifier=1"})
+	})
+	router.GET("/admin/oauth2/auth/requests/consent", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+	conf.MustSet(config.ViperKeyOAuth2ProviderURL, ts.URL+"/admin/")
+
+	t.Run("case=fetches and accepts the login request", func(t *testing.T) {
+		lr, err := h.GetLoginRequest(ctx, "valid")
+		require.NoError(t, err)
+		assert.Equal(t, "app", lr.Client.ClientID)
+
+		redirectTo, err := h.AcceptLoginRequest(ctx, "valid", &hydra.AcceptLoginRequest{Subject: "subject", AMR: []string{"password"}})
+		require.NoError(t, err)
+		assert.Equal(t, "https://hydra.example/oauth2/auth?login_verifier=1", redirectTo)
+		assert.Equal(t, "subject", accepted.Subject)
+		assert.Equal(t, []string{"password"}, accepted.AMR)
+	})
+
+	t.Run("case=rejects unknown challenges", func(t *testing.T) {
+		_, err := h.GetLoginRequest(ctx, "unknown")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, herodot.ErrBadRequest), "%+v", err)
+	})
+
+	t.Run("case=fails on unexpected responses", func(t *testing.T) {
+		_, err := h.GetConsentRequest(ctx, "valid")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, herodot.ErrInternalServerError), "%+v", err)
+	})
+}
@@ -0,0 +1,29 @@
+package identity
+
+import (
+	"net/http"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// BreakGlassOperationDeleteCredentials is the operation of deleting the credentials of an identity, for example
+	// to reset its second factor.
+	BreakGlassOperationDeleteCredentials = "delete_credentials"
+
+	// BreakGlassOperationReleaseQuarantine is the operation of releasing an identity from quarantine.
+	BreakGlassOperationReleaseQuarantine = "release_quarantine"
+)
+
+type (
+	// BreakGlassGuard decides whether administrative operations which bypass the protections of an identity may be
+	// performed.
+	BreakGlassGuard interface {
+		// AuthorizeBreakGlass returns an error if the request is not allowed to perform the operation on the
+		// identity.
+		AuthorizeBreakGlass(r *http.Request, identityID uuid.UUID, operation string) error
+	}
+	BreakGlassGuardProvider interface {
+		BreakGlassGuard() BreakGlassGuard
+	}
+)
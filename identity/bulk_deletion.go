@@ -0,0 +1,200 @@
+package identity
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/x"
+)
+
+// bulkDeletionChunkSize is the number of identities deleted per transaction.
+const bulkDeletionChunkSize = 100
+
+// bulkDeletionLease is how long a job may go without storing its progress before it is considered to have stopped,
+// for example because the instance running it was restarted. Progress is stored after every chunk.
+const bulkDeletionLease = 5 * time.Minute
+
+const (
+	BulkDeletionStatePending   BulkDeletionState = "pending"
+	BulkDeletionStateRunning   BulkDeletionState = "running"
+	BulkDeletionStateCompleted BulkDeletionState = "completed"
+	BulkDeletionStateFailed    BulkDeletionState = "failed"
+)
+
+type (
+	// BulkDeletionState is the state of a bulk deletion job.
+	BulkDeletionState string
+
+	// BulkDeletion is a job deleting all identities matching a filter.
+	//
+	// swagger:model identityBulkDeletion
+	BulkDeletion struct {
+		// ID is the job's ID.
+		ID uuid.UUID `json:"id" db:"id"`
+
+		// Filter is the filter used to select the identities.
+		Filter Filter `json:"filter" db:"filter"`
+
+		// DryRun is true if no identities are deleted.
+		DryRun bool `json:"dry_run" db:"-"`
+
+		// State is one of pending, running, completed, failed.
+		State BulkDeletionState `json:"state" db:"state"`
+
+		// Matched is the number of identities matching the filter.
+		Matched int `json:"matched" db:"matched"`
+
+		// Deleted is the number of identities deleted so far.
+		Deleted int `json:"deleted" db:"deleted"`
+
+		// Skipped is the number of identities which were not deleted because they are under legal hold.
+		Skipped int `json:"skipped" db:"skipped"`
+
+		// Error is set if the job failed.
+		Error string `json:"error,omitempty" db:"error"`
+
+		// CreatedAt is the time the job was created.
+		CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+		// UpdatedAt is a helper struct field for gobuffalo.pop.
+		UpdatedAt time.Time `json:"-" db:"updated_at"`
+
+		// CompletedAt is the time the job was completed or failed.
+		CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	}
+
+	bulkDeletionDependencies interface {
+		PrivilegedPoolProvider
+		SessionRevocationNotifierProvider
+		x.LoggingProvider
+		x.ClockProvider
+	}
+
+	// bulkDeletions runs bulk deletion jobs. The jobs are stored in the database so that their progress can be
+	// fetched from every instance.
+	bulkDeletions struct {
+		d bulkDeletionDependencies
+	}
+)
+
+func (b BulkDeletion) TableName() string {
+	return "identity_bulk_deletions"
+}
+
+func newBulkDeletions(d bulkDeletionDependencies) *bulkDeletions {
+	return &bulkDeletions{d: d}
+}
+
+// start matches identities against the filter and, unless dryRun is set, deletes them in the background.
+func (b *bulkDeletions) start(ctx context.Context, filter *Filter, dryRun bool) (*BulkDeletion, error) {
+	ids, err := b.d.PrivilegedIdentityPool().ListIdentityIDsByFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &BulkDeletion{
+		ID:        x.NewUUID(),
+		Filter:    *filter,
+		DryRun:    dryRun,
+		State:     BulkDeletionStatePending,
+		Matched:   len(ids),
+		CreatedAt: b.d.Clock().Now(),
+	}
+
+	if dryRun {
+		job.State = BulkDeletionStateCompleted
+		job.CompletedAt = &job.CreatedAt
+		return job, nil
+	}
+
+	if err := b.d.PrivilegedIdentityPool().CreateBulkDeletion(ctx, job); err != nil {
+		return nil, err
+	}
+
+	result := *job
+	go b.run(job, ids)
+	return &result, nil
+}
+
+func (b *bulkDeletions) run(job *BulkDeletion, ids []uuid.UUID) {
+	// The job outlives the request which is why it must not use the request's context.
+	ctx := context.Background()
+
+	job.State = BulkDeletionStateRunning
+	b.update(ctx, job)
+
+	for start := 0; start < len(ids); start += bulkDeletionChunkSize {
+		end := start + bulkDeletionChunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		deleted, held, err := b.d.PrivilegedIdentityPool().DeleteIdentities(ctx, ids[start:end])
+		if err != nil {
+			b.d.Logger().WithError(err).WithField("bulk_deletion_id", job.ID).
+				Error("Unable to delete identities during bulk deletion.")
+			now := b.d.Clock().Now()
+			job.State = BulkDeletionStateFailed
+			job.Error = err.Error()
+			job.CompletedAt = &now
+			b.update(ctx, job)
+			return
+		}
+
+		for _, identityID := range held {
+			b.d.Audit().
+				WithField("bulk_deletion_id", job.ID).
+				WithField("identity_id", identityID).
+				Info("An identity under legal hold was skipped by a bulk deletion.")
+		}
+		for _, identityID := range deleted {
+			b.d.Audit().
+				WithField("bulk_deletion_id", job.ID).
+				WithField("identity_id", identityID).
+				Info("An identity was deleted by a bulk deletion.")
+			b.d.SessionRevocationNotifier().IdentitySessionsRevoked(identityID, SessionRevocationReasonIdentityDeleted)
+		}
+		job.Deleted += len(deleted)
+		job.Skipped += len(held)
+		b.update(ctx, job)
+	}
+
+	now := b.d.Clock().Now()
+	job.State = BulkDeletionStateCompleted
+	job.CompletedAt = &now
+	b.update(ctx, job)
+}
+
+func (b *bulkDeletions) update(ctx context.Context, job *BulkDeletion) {
+	if err := b.d.PrivilegedIdentityPool().UpdateBulkDeletion(ctx, job); err != nil {
+		b.d.Logger().WithError(err).WithField("bulk_deletion_id", job.ID).
+			Error("Unable to store the progress of a bulk deletion.")
+	}
+}
+
+// get returns the job and marks it as failed if the instance running it stopped before completing it.
+func (b *bulkDeletions) get(ctx context.Context, id uuid.UUID) (*BulkDeletion, error) {
+	job, err := b.d.PrivilegedIdentityPool().GetBulkDeletion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.State != BulkDeletionStatePending && job.State != BulkDeletionStateRunning {
+		return job, nil
+	}
+
+	now := b.d.Clock().Now()
+	if now.Sub(job.UpdatedAt) <= bulkDeletionLease {
+		return job, nil
+	}
+
+	job.State = BulkDeletionStateFailed
+	job.Error = "The bulk deletion stopped before it was completed, for example because the instance running it was restarted. Start a new bulk deletion to delete the remaining identities."
+	job.CompletedAt = &now
+	if err := b.d.PrivilegedIdentityPool().UpdateBulkDeletion(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
@@ -21,6 +21,17 @@ const (
 	// make sure to add all of these values to the test that ensures they are created during migration
 	CredentialsTypePassword CredentialsType = "password"
 	CredentialsTypeOIDC     CredentialsType = "oidc"
+	CredentialsTypeSAML     CredentialsType = "saml"
+	CredentialsTypeLDAP     CredentialsType = "ldap"
+	CredentialsTypeAPIKey   CredentialsType = "api_key"
+
+	// CredentialsTypeKerberos identifies the Kerberos strategy. Identities do not hold credentials of this type,
+	// the principal is mapped to the identifier of another credentials type instead.
+	CredentialsTypeKerberos CredentialsType = "kerberos"
+
+	// CredentialsTypeMTLS identifies the client certificate strategy. Like Kerberos, the certificate is mapped to
+	// the identifier of another credentials type.
+	CredentialsTypeMTLS CredentialsType = "mtls"
 )
 
 type (
@@ -0,0 +1,90 @@
+package identity
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+type (
+	// CredentialsDescription describes credentials of an identity without their secrets, for example the hashed
+	// password.
+	//
+	// swagger:model identityCredentialsDescription
+	CredentialsDescription struct {
+		// Type is the type of the credentials, for example `password` or `oidc`.
+		//
+		// required: true
+		Type CredentialsType `json:"type"`
+
+		// Identifiers are the identifiers the credentials are found by, for example the email address.
+		//
+		// required: true
+		Identifiers []string `json:"identifiers"`
+
+		// Providers lists the linked provider accounts of OpenID Connect and SAML credentials.
+		Providers []CredentialsProvider `json:"providers,omitempty"`
+
+		// CreatedAt is the time the credentials were enrolled.
+		//
+		// required: true
+		CreatedAt time.Time `json:"created_at"`
+
+		// UpdatedAt is the time the credentials were last changed.
+		//
+		// required: true
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+
+	// CredentialsProvider is an account at an OpenID Connect or SAML provider linked to the identity.
+	CredentialsProvider struct {
+		// Provider is the ID of the provider.
+		//
+		// required: true
+		Provider string `json:"provider"`
+
+		// Subject is the ID of the account at the provider.
+		//
+		// required: true
+		Subject string `json:"subject"`
+	}
+)
+
+// DescribeCredentials returns the description of the credentials. The configuration of the credentials is not
+// included, except for the linked provider accounts, as it may contain secrets.
+func DescribeCredentials(c Credentials) CredentialsDescription {
+	d := CredentialsDescription{
+		Type:        c.Type,
+		Identifiers: c.Identifiers,
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+	}
+	if d.Identifiers == nil {
+		d.Identifiers = []string{}
+	}
+
+	for _, p := range gjson.GetBytes(c.Config, "providers").Array() {
+		d.Providers = append(d.Providers, CredentialsProvider{
+			Provider: p.Get("provider").String(),
+			Subject:  p.Get("subject").String(),
+		})
+	}
+
+	return d
+}
+
+// DescribeAllCredentials returns the descriptions of all credentials of the identity ordered by their type.
+func (i *Identity) DescribeAllCredentials() []CredentialsDescription {
+	i.lock().RLock()
+	defer i.lock().RUnlock()
+
+	ds := make([]CredentialsDescription, 0, len(i.Credentials))
+	for _, c := range i.Credentials {
+		ds = append(ds, DescribeCredentials(c))
+	}
+	sort.Slice(ds, func(a, b int) bool {
+		return ds[a].Type < ds[b].Type
+	})
+	return ds
+}
@@ -19,3 +19,17 @@ func TestCredentialsEqual(t *testing.T) {
 	derived["foo"].Identifiers[0] = "baz"
 	assert.NotEqual(t, original, derived)
 }
+
+func TestDescribeCredentials(t *testing.T) {
+	i := NewIdentity("")
+	i.SetCredentials(CredentialsTypePassword, Credentials{Identifiers: []string{"foo@ory.sh"}, Config: sqlxx.JSONRawMessage(`{"hashed_password":"secret"}`)})
+	i.SetCredentials(CredentialsTypeOIDC, Credentials{Identifiers: []string{"google:1234"}, Config: sqlxx.JSONRawMessage(`{"providers":[{"provider":"google","subject":"1234"}]}`)})
+
+	ds := i.DescribeAllCredentials()
+	assert.Len(t, ds, 2)
+	assert.Equal(t, CredentialsTypeOIDC, ds[0].Type)
+	assert.Equal(t, []CredentialsProvider{{Provider: "google", Subject: "1234"}}, ds[0].Providers)
+	assert.Equal(t, CredentialsTypePassword, ds[1].Type)
+	assert.Equal(t, []string{"foo@ory.sh"}, ds[1].Identifiers)
+	assert.Empty(t, ds[1].Providers)
+}
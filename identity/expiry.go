@@ -0,0 +1,16 @@
+package identity
+
+import (
+	"time"
+
+	"github.com/ory/herodot"
+)
+
+var ErrExpired = herodot.ErrForbidden.
+	WithError("identity is expired").
+	WithReason("This account has expired. Please contact support to extend it.")
+
+// IsExpired returns true if the identity has an expiry date which has passed at the given time.
+func (i *Identity) IsExpired(now time.Time) bool {
+	return i.ExpiresAt != nil && !i.ExpiresAt.After(now)
+}
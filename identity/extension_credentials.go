@@ -35,7 +35,17 @@ func (r *SchemaExtensionCredentials) Run(_ jsonschema.ValidationContext, s schem
 			}
 		}
 
-		r.v = stringslice.Unique(append(r.v, strings.ToLower(fmt.Sprintf("%s", value))))
+		identifier := strings.ToLower(fmt.Sprintf("%s", value))
+		if region, ok := isPhoneNumber(s); ok {
+			normalized, err := NormalizePhoneNumber(identifier, region)
+			if err != nil {
+				// Invalid numbers are reported by SchemaExtensionPhone.
+				return nil
+			}
+			identifier = normalized
+		}
+
+		r.v = stringslice.Unique(append(r.v, identifier))
 		cred.Identifiers = r.v
 		r.i.SetCredentials(CredentialsTypePassword, *cred)
 	}
@@ -0,0 +1,60 @@
+package identity
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+	"github.com/pkg/errors"
+
+	"github.com/ory/jsonschema/v3"
+
+	"github.com/ory/kratos/schema"
+)
+
+// NormalizePhoneNumber validates a phone number and returns it in E.164 format, for example `+4917012345678`.
+// Numbers without a country calling code are parsed as numbers of the default region, an ISO 3166-1 alpha-2 code.
+func NormalizePhoneNumber(value, defaultRegion string) (string, error) {
+	number, err := phonenumbers.Parse(value, strings.ToUpper(defaultRegion))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if !phonenumbers.IsValidNumber(number) {
+		return "", errors.Errorf("%q is not a valid phone number", value)
+	}
+
+	return phonenumbers.Format(number, phonenumbers.E164), nil
+}
+
+// isPhoneNumber returns true and the default region if the schema marks the value as a phone number.
+func isPhoneNumber(s schema.ExtensionConfig) (string, bool) {
+	if s.Phone != nil {
+		return s.Phone.DefaultRegion, true
+	}
+	return "", s.Recovery.Via == AddressTypePhone || s.Verification.Via == string(VerifiableAddressTypeSMS)
+}
+
+// SchemaExtensionPhone validates traits marked as phone numbers. The traits keep the formatting entered by the
+// user while identifiers and recovery addresses use the normalized E.164 format.
+type SchemaExtensionPhone struct{}
+
+func NewSchemaExtensionPhone() *SchemaExtensionPhone {
+	return new(SchemaExtensionPhone)
+}
+
+func (r *SchemaExtensionPhone) Run(ctx jsonschema.ValidationContext, s schema.ExtensionConfig, value interface{}) error {
+	region, ok := isPhoneNumber(s)
+	if !ok {
+		return nil
+	}
+
+	if _, err := NormalizePhoneNumber(fmt.Sprintf("%s", value), region); err != nil {
+		return ctx.Error("format", "%q is not a valid phone number", value)
+	}
+	return nil
+}
+
+func (r *SchemaExtensionPhone) Finish() error {
+	return nil
+}
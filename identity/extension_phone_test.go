@@ -0,0 +1,117 @@
+package identity_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/jsonschema/v3"
+	_ "github.com/ory/jsonschema/v3/fileloader"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/x"
+)
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	for k, tc := range []struct {
+		value, region, expect string
+	}{
+		{value: "+49 30 1234567", expect: "+49301234567"},
+		{value: "+1 (650) 253-0000", region: "DE", expect: "+16502530000"},
+		{value: "030 1234567", region: "DE", expect: "+49301234567"},
+		{value: "030 1234567", region: "de", expect: "+49301234567"},
+		{value: "(650) 253-0000", region: "US", expect: "+16502530000"},
+		{value: "030 1234567"},
+		{value: "+49 1"},
+		{value: "not a number", region: "DE"},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			actual, err := identity.NormalizePhoneNumber(tc.value, tc.region)
+			if tc.expect == "" {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, actual)
+		})
+	}
+}
+
+func TestSchemaExtensionPhone(t *testing.T) {
+	iid := x.NewUUID()
+	for k, tc := range []struct {
+		doc               string
+		expectErr         string
+		expectIdentifiers []string
+		expectRecovery    []string
+		expectVerify      []string
+	}{
+		{
+			doc:               `{"phone":"030 1234567","fax":"+1 (650) 253-0000"}`,
+			expectIdentifiers: []string{"+49301234567"},
+			expectRecovery:    []string{"+49301234567"},
+			expectVerify:      []string{"+49301234567"},
+		},
+		{
+			doc:               `{"phone":"+49 (0)30 1234567","mobile":"+1 (650) 253-0000"}`,
+			expectIdentifiers: []string{"+49301234567"},
+			expectRecovery:    []string{"+49301234567"},
+			expectVerify:      []string{"+49301234567", "+16502530000"},
+		},
+		{
+			doc:       `{"phone":"030 1234567","mobile":"0151 12345678"}`,
+			expectErr: `I[#/mobile] S[#/properties/mobile/format] "0151 12345678" is not a valid phone number`,
+		},
+		{
+			doc:       `{"phone":"12"}`,
+			expectErr: `I[#/phone] S[#/properties/phone/format] "12" is not a valid phone number`,
+		},
+		{
+			doc:       `{"phone":"030 1234567","fax":"(650) 253-0000"}`,
+			expectErr: `I[#/fax] S[#/properties/fax/format] "(650) 253-0000" is not a valid phone number`,
+		},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			i := &identity.Identity{ID: iid}
+			c := jsonschema.NewCompiler()
+			runner, err := schema.NewExtensionRunner(schema.ExtensionRunnerIdentityMetaSchema,
+				identity.NewSchemaExtensionPhone(),
+				identity.NewSchemaExtensionCredentials(i),
+				identity.NewSchemaExtensionRecovery(i),
+				identity.NewSchemaExtensionVerification(i, time.Minute))
+			require.NoError(t, err)
+			runner.Register(c)
+
+			err = c.MustCompile("file://./stub/extension/phone/schema.json").Validate(bytes.NewBufferString(tc.doc))
+			if tc.expectErr != "" {
+				require.EqualError(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, runner.Finish())
+
+			credentials, ok := i.GetCredentials(identity.CredentialsTypePassword)
+			require.True(t, ok)
+			assert.Equal(t, tc.expectIdentifiers, credentials.Identifiers)
+
+			var recovery []string
+			for _, a := range i.RecoveryAddresses {
+				assert.Equal(t, identity.RecoveryAddressTypePhone, a.Via)
+				recovery = append(recovery, a.Value)
+			}
+			assert.Equal(t, tc.expectRecovery, recovery)
+
+			var verify []string
+			for _, a := range i.VerifiableAddresses {
+				assert.Equal(t, identity.VerifiableAddressTypeSMS, a.Via)
+				verify = append(verify, a.Value)
+			}
+			assert.ElementsMatch(t, tc.expectVerify, verify)
+		})
+	}
+}
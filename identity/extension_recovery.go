@@ -29,19 +29,17 @@ func (r *SchemaExtensionRecovery) Run(ctx jsonschema.ValidationContext, s schema
 			return ctx.Error("format", "%q is not valid %q", value, "email")
 		}
 
-		address := NewRecoveryEmailAddress(fmt.Sprintf("%s", value), r.i.ID)
-
-		if has := r.has(r.i.RecoveryAddresses, address); has != nil {
-			if r.has(r.v, address) == nil {
-				r.v = append(r.v, *has)
-			}
+		r.add(NewRecoveryEmailAddress(fmt.Sprintf("%s", value), r.i.ID))
+		return nil
+	case AddressTypePhone:
+		region, _ := isPhoneNumber(s)
+		normalized, err := NormalizePhoneNumber(fmt.Sprintf("%s", value), region)
+		if err != nil {
+			// Invalid numbers are reported by SchemaExtensionPhone.
 			return nil
 		}
 
-		if has := r.has(r.v, address); has == nil {
-			r.v = append(r.v, *address)
-		}
-
+		r.add(NewRecoveryPhoneAddress(normalized, r.i.ID))
 		return nil
 	case "":
 		return nil
@@ -50,6 +48,19 @@ func (r *SchemaExtensionRecovery) Run(ctx jsonschema.ValidationContext, s schema
 	return ctx.Error("", "recovery.via has unknown value %q", s.Recovery.Via)
 }
 
+func (r *SchemaExtensionRecovery) add(address *RecoveryAddress) {
+	if has := r.has(r.i.RecoveryAddresses, address); has != nil {
+		if r.has(r.v, address) == nil {
+			r.v = append(r.v, *has)
+		}
+		return
+	}
+
+	if has := r.has(r.v, address); has == nil {
+		r.v = append(r.v, *address)
+	}
+}
+
 func (r *SchemaExtensionRecovery) has(haystack []RecoveryAddress, needle *RecoveryAddress) *RecoveryAddress {
 	for _, has := range haystack {
 		if has.Value == needle.Value && has.Via == needle.Via {
@@ -31,19 +31,17 @@ func (r *SchemaExtensionVerification) Run(ctx jsonschema.ValidationContext, s sc
 			return ctx.Error("format", "%q is not valid %q", value, "email")
 		}
 
-		address := NewVerifiableEmailAddress(fmt.Sprintf("%s", value), r.i.ID)
-
-		if has := r.has(r.i.VerifiableAddresses, address); has != nil {
-			if r.has(r.v, address) == nil {
-				r.v = append(r.v, *has)
-			}
+		r.add(NewVerifiableEmailAddress(fmt.Sprintf("%s", value), r.i.ID))
+		return nil
+	case string(VerifiableAddressTypeSMS):
+		region, _ := isPhoneNumber(s)
+		normalized, err := NormalizePhoneNumber(fmt.Sprintf("%s", value), region)
+		if err != nil {
+			// Invalid numbers are reported by SchemaExtensionPhone.
 			return nil
 		}
 
-		if has := r.has(r.v, address); has == nil {
-			r.v = append(r.v, *address)
-		}
-
+		r.add(NewVerifiableSMSAddress(normalized, r.i.ID))
 		return nil
 	case "":
 		return nil
@@ -52,6 +50,19 @@ func (r *SchemaExtensionVerification) Run(ctx jsonschema.ValidationContext, s sc
 	return ctx.Error("", "verification.via has unknown value %q", s.Verification.Via)
 }
 
+func (r *SchemaExtensionVerification) add(address *VerifiableAddress) {
+	if has := r.has(r.i.VerifiableAddresses, address); has != nil {
+		if r.has(r.v, address) == nil {
+			r.v = append(r.v, *has)
+		}
+		return
+	}
+
+	if has := r.has(r.v, address); has == nil {
+		r.v = append(r.v, *address)
+	}
+}
+
 func (r *SchemaExtensionVerification) has(haystack []VerifiableAddress, needle *VerifiableAddress) *VerifiableAddress {
 	for _, has := range haystack {
 		if has.Value == needle.Value && has.Via == needle.Via {
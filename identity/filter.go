@@ -0,0 +1,148 @@
+package identity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+)
+
+// Filter selects identities. All fields which are set must match for an identity to be selected.
+type Filter struct {
+	// IDs matches identities with one of the given IDs.
+	IDs []uuid.UUID `json:"ids,omitempty"`
+
+	// SchemaID matches identities using the given traits schema.
+	SchemaID string `json:"schema_id,omitempty"`
+
+	// CreatedBefore matches identities created before the given time.
+	CreatedBefore time.Time `json:"created_before,omitempty"`
+
+	// CreatedAfter matches identities created after the given time.
+	CreatedAfter time.Time `json:"created_after,omitempty"`
+
+	// CredentialsIdentifier matches identities having a credentials identifier (e.g. an email address used for
+	// password login) equal to this value. The wildcard `*` matches any sequence of characters.
+	CredentialsIdentifier string `json:"credentials_identifier,omitempty"`
+
+	// Verified matches identities having at least one verified address if true and identities without any verified
+	// address if false.
+	Verified *bool `json:"verified,omitempty"`
+}
+
+// ParseFilter parses filter expressions of the form `key:value`. Supported keys are
+// `schema_id`, `created_before`, `created_after` (both RFC 3339), `older_than` (a duration such as `90d` or `12h`),
+// `credentials_identifier`, and `verified` (a boolean).
+func ParseFilter(expressions []string) (*Filter, error) {
+	var f Filter
+	for _, expression := range expressions {
+		parts := strings.SplitN(expression, ":", 2)
+		if len(parts) != 2 || len(parts[1]) == 0 {
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Filter "%s" must be of the form "key:value".`, expression))
+		}
+
+		key, value := parts[0], parts[1]
+		switch key {
+		case "schema_id":
+			f.SchemaID = value
+		case "created_before", "created_after":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Filter "%s" must contain a RFC 3339 timestamp: %s`, key, err))
+			}
+			if key == "created_before" {
+				f.CreatedBefore = t
+			} else {
+				f.CreatedAfter = t
+			}
+		case "older_than":
+			d, err := parseFilterDuration(value)
+			if err != nil {
+				return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Filter "%s" must contain a duration such as "90d" or "12h": %s`, key, err))
+			}
+			f.CreatedBefore = time.Now().UTC().Add(-d)
+		case "credentials_identifier":
+			f.CredentialsIdentifier = value
+		case "verified":
+			verified, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Filter "%s" must be true or false.`, key))
+			}
+			f.Verified = &verified
+		default:
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Filter key "%s" is not supported.`, key))
+		}
+	}
+	return &f, nil
+}
+
+// IsEmpty returns true if the filter matches all identities.
+func (f *Filter) IsEmpty() bool {
+	return len(f.IDs) == 0 && f.SchemaID == "" && f.CreatedBefore.IsZero() && f.CreatedAfter.IsZero() &&
+		f.CredentialsIdentifier == "" && f.Verified == nil
+}
+
+func (f *Filter) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return errors.Errorf("unable to scan identity filter of type %T", value)
+	}
+	return errors.WithStack(json.Unmarshal(raw, f))
+}
+
+func (f Filter) Value() (driver.Value, error) {
+	raw, err := json.Marshal(f)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return string(raw), nil
+}
+
+// ParseFilterIDs parses comma-separated identity IDs.
+func ParseFilterIDs(values []string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, value := range values {
+		for _, raw := range strings.Split(value, ",") {
+			if raw = strings.TrimSpace(raw); raw == "" {
+				continue
+			}
+			id, err := uuid.FromString(raw)
+			if err != nil {
+				return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The identity ID "%s" is not a valid UUID.`, raw))
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// parseFilterDuration parses a duration which, in addition to the units of time.ParseDuration, may be given in
+// days, for example `90d`.
+func parseFilterDuration(value string) (time.Duration, error) {
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, errors.Errorf("invalid number of days %q", days)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	} else if d < 0 {
+		return 0, errors.New("the duration must not be negative")
+	}
+	return d, nil
+}
@@ -0,0 +1,60 @@
+package identity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/x"
+)
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter([]string{
+		"schema_id:customer",
+		"created_before:2020-02-01T00:00:00Z",
+		"created_after:2020-01-01T00:00:00Z",
+		"credentials_identifier:*@spam.example.org",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &Filter{
+		SchemaID:              "customer",
+		CreatedBefore:         time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		CreatedAfter:          time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		CredentialsIdentifier: "*@spam.example.org",
+	}, f)
+	assert.False(t, f.IsEmpty())
+
+	f, err = ParseFilter(nil)
+	require.NoError(t, err)
+	assert.True(t, f.IsEmpty())
+
+	f, err = ParseFilter([]string{"verified:false", "older_than:90d"})
+	require.NoError(t, err)
+	require.NotNil(t, f.Verified)
+	assert.False(t, *f.Verified)
+	assert.WithinDuration(t, time.Now().Add(-90*24*time.Hour), f.CreatedBefore, time.Minute)
+
+	f, err = ParseFilter([]string{"older_than:12h"})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(-12*time.Hour), f.CreatedBefore, time.Minute)
+
+	for _, in := range []string{"schema_id", "schema_id:", "foo:bar", "created_after:yesterday", "verified:maybe", "older_than:-1d", "older_than:soon"} {
+		_, err := ParseFilter([]string{in})
+		assert.Error(t, err, in)
+	}
+}
+
+func TestParseFilterIDs(t *testing.T) {
+	a, b := x.NewUUID(), x.NewUUID()
+	ids, err := ParseFilterIDs([]string{a.String() + ", " + b.String(), "", a.String()})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{a, b, a}, ids)
+
+	_, err = ParseFilterIDs([]string{"not-a-uuid"})
+	assert.Error(t, err)
+
+	assert.False(t, (&Filter{IDs: ids}).IsEmpty())
+}
@@ -3,31 +3,57 @@ package identity
 import (
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gofrs/uuid"
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
 
 	"github.com/ory/x/jsonx"
 	"github.com/ory/x/urlx"
 
+	"github.com/ory/kratos/hash"
 	"github.com/ory/kratos/x"
 )
 
-const RouteBase = "/identities"
+const (
+	RouteBase = "/identities"
+
+	RouteBulkDeletions = "/identity-bulk-deletions"
+
+	RouteQuarantine = "/identity-quarantine"
+
+	RouteCount = "/identity-count"
+)
 
 type (
 	handlerDependencies interface {
 		PoolProvider
 		PrivilegedPoolProvider
 		ManagementProvider
+		ValidationProvider
+		hash.HashProvider
 		x.WriterProvider
+		x.LoggingProvider
+		x.ClockProvider
+		BreakGlassGuardProvider
+		SessionRevocationNotifierProvider
 	}
 	HandlerProvider interface {
 		IdentityHandler() *Handler
 	}
 	Handler struct {
-		c Configuration
-		r handlerDependencies
+		c         Configuration
+		r         handlerDependencies
+		deletions *bulkDeletions
 	}
 )
 
@@ -36,18 +62,37 @@ func NewHandler(
 	r handlerDependencies,
 ) *Handler {
 	return &Handler{
-		c: c,
-		r: r,
+		c:         c,
+		r:         r,
+		deletions: newBulkDeletions(r),
 	}
 }
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 	admin.GET(RouteBase, h.list)
+	admin.GET(RouteCount, h.count)
 	admin.GET(RouteBase+"/:id", h.get)
 	admin.DELETE(RouteBase+"/:id", h.delete)
+	admin.DELETE(RouteBase, h.bulkDelete)
+	admin.GET(RouteBulkDeletions+"/:id", h.getBulkDeletion)
+	admin.GET(RouteQuarantine, h.listQuarantined)
+	admin.DELETE(RouteQuarantine+"/:id", h.releaseFromQuarantine)
+	admin.POST(RouteImport, h.importIdentities)
 
 	admin.POST(RouteBase, h.create)
 	admin.PUT(RouteBase+"/:id", h.update)
+	admin.PATCH(RouteBase+"/:id", h.patch)
+	admin.PUT(RouteBase+"/:id/state", h.updateState)
+	admin.PUT(RouteBase+"/:id/expiry", h.updateExpiry)
+	admin.POST(RouteBase+"/:id/restore", h.restore)
+	admin.POST(RouteBase+"/:id/merge", h.merge)
+	admin.PUT(RouteBase+"/:id/legal-hold", h.placeOnLegalHold)
+	admin.DELETE(RouteBase+"/:id/legal-hold", h.releaseFromLegalHold)
+	admin.GET(RouteBase+"/:id/credentials", h.listCredentials)
+	admin.GET(RouteBase+"/:id/credentials/:type", h.getCredentials)
+	admin.DELETE(RouteBase+"/:id/credentials/:type", h.deleteCredentials)
+	admin.PUT(RouteBase+"/:id/labels/:name", h.setLabel)
+	admin.DELETE(RouteBase+"/:id/labels/:name", h.deleteLabel)
 }
 
 // A single identity.
@@ -91,13 +136,76 @@ type listIdentityParameters struct {
 	// default: 0
 	// min: 0
 	Page int `json:"page"`
+
+	// Credentials Identifier
+	//
+	// Only lists identities with credentials using this identifier, for example an email address.
+	//
+	// required: false
+	// in: query
+	CredentialsIdentifier string `json:"credentials_identifier"`
+
+	// Schema ID
+	//
+	// Only lists identities using this traits schema.
+	//
+	// required: false
+	// in: query
+	SchemaID string `json:"schema_id"`
+
+	// State
+	//
+	// Only lists identities in this state, either `active` or `inactive`.
+	//
+	// required: false
+	// in: query
+	State string `json:"state"`
+
+	// Verified
+	//
+	// Only lists identities with at least one verified address if `true` and identities without any verified
+	// address if `false`.
+	//
+	// required: false
+	// in: query
+	Verified string `json:"verified"`
+
+	// Page Size
+	//
+	// This is the number of items per page when using keyset pagination. Setting it or the page token selects
+	// keyset pagination over page and per_page.
+	//
+	// required: false
+	// in: query
+	// default: 250
+	// min: 1
+	// max: 1000
+	PageSize int `json:"page_size"`
+
+	// Page Token
+	//
+	// This is the token of the next page when using keyset pagination. It is returned in the `next` link of the
+	// `Link` header and must be treated as opaque.
+	//
+	// required: false
+	// in: query
+	PageToken string `json:"page_token"`
 }
 
 // swagger:route GET /identities admin listIdentities
 //
 // List Identities
 //
-// Lists all identities. Does not support search at the moment.
+// Lists all identities. Identities can be filtered by a credentials identifier using
+// `?credentials_identifier=foo@bar.com`, by string traits using their path, for example
+// `?traits.department=sales` or `?traits.name.last=Doe`, and by labels using their name, for example
+// `?labels.cohort=beta`. Identities can also be filtered by their schema using `?schema_id=customer`, by their state
+// using `?state=inactive`, and by whether they have a verified address using `?verified=true`. All filters must match.
+//
+// Identities can be paginated using `page` and `per_page`, which becomes slow on large tables, or using keyset
+// pagination by setting `page_size` and following the `next` link of the `Link` header, which contains the
+// `page_token` of the next page. Keyset pagination orders identities by their creation time and ID and does not
+// count the identities.
 //
 // Learn how identities work in [ORY Kratos' User And Identity Model Documentation](https://www.ory.sh/docs/next/kratos/concepts/identity-user-model).
 //
@@ -110,21 +218,235 @@ type listIdentityParameters struct {
 //       200: identityList
 //       500: genericError
 func (h *Handler) list(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	filter, err := parseListIdentitiesFilter(r.URL.Query())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if pageToken, pageSize, ok := x.ParseKeysetPagination(r); ok {
+		h.listByKeyset(w, r, filter, pageToken, pageSize)
+		return
+	}
+
 	page, itemsPerPage := x.ParsePagination(r)
-	is, err := h.r.IdentityPool().ListIdentities(r.Context(), page, itemsPerPage)
+	ids, err := h.r.IdentityPool().ListIdentityIDs(r.Context(), filter, page, itemsPerPage)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	total, err := h.r.IdentityPool().CountIdentities(r.Context(), filter)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	// The links of the pagination header keep the filters.
+	x.PaginationHeader(w, urlx.CopyWithQuery(urlx.AppendPaths(h.c.SelfAdminURL(), RouteBase), r.URL.Query()), total, page, itemsPerPage)
+	h.stream(w, r, ids)
+}
+
+// streamBatchSize is the number of identities loaded at a time when streaming a list of identities.
+const streamBatchSize = 100
+
+// stream writes the identities as a JSON array while loading only a batch of them at a time, because encoding
+// large pages at once uses a lot of memory.
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request, ids []uuid.UUID) {
+	s := x.NewJSONArrayStream(w)
+	err := h.r.IdentityPool().StreamIdentities(r.Context(), ids, streamBatchSize, func(i *Identity) error {
+		if err := s.Write(WithAdminMetadataInJSON(*i)); err != nil {
+			return err
+		}
+		if s.Written()%streamBatchSize == 0 {
+			s.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = s.Close()
+	}
+
+	if err != nil {
+		if !s.Started() {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+
+		h.r.Logger().WithRequest(r).WithError(err).Error("Unable to stream the list of identities.")
+		s.Abort()
+	}
+}
+
+// swagger:parameters countIdentities
+// nolint:deadcode,unused
+type countIdentitiesParameters struct {
+	// Credentials Identifier
+	//
+	// Only counts identities with credentials using this identifier, for example an email address.
+	//
+	// required: false
+	// in: query
+	CredentialsIdentifier string `json:"credentials_identifier"`
+
+	// Schema ID
+	//
+	// Only counts identities using this traits schema.
+	//
+	// required: false
+	// in: query
+	SchemaID string `json:"schema_id"`
+
+	// State
+	//
+	// Only counts identities in this state, either `active` or `inactive`.
+	//
+	// required: false
+	// in: query
+	State string `json:"state"`
+
+	// Verified
+	//
+	// Only counts identities with at least one verified address if `true` and identities without any verified
+	// address if `false`.
+	//
+	// required: false
+	// in: query
+	Verified string `json:"verified"`
+}
+
+// The number of identities matching the filters.
+//
+// swagger:model identityCount
+type identityCount struct {
+	// Count is the number of identities.
+	//
+	// required: true
+	Count int64 `json:"count"`
+}
+
+// swagger:route GET /identity-count admin countIdentities
+//
+// Count Identities
+//
+// Counts the identities matching the filters without listing them. The filters are the same as the ones of
+// listing identities, for example `?schema_id=customer&verified=false`. All filters must match.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityCount
+//       400: genericError
+//       500: genericError
+func (h *Handler) count(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	filter, err := parseListIdentitiesFilter(r.URL.Query())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	count, err := h.r.IdentityPool().CountIdentities(r.Context(), filter)
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
-	total, err := h.r.IdentityPool().CountIdentities(r.Context())
+	h.r.Writer().Write(w, r, &identityCount{Count: count})
+}
+
+func (h *Handler) listByKeyset(w http.ResponseWriter, r *http.Request, filter ListIdentitiesFilter, pageToken string, pageSize int) {
+	var after *PageToken
+	if pageToken != "" {
+		var err error
+		if after, err = ParsePageToken(pageToken); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+	}
+
+	// Fetching one more page token than requested tells whether there is a next page. The link to it is sent
+	// before the identities are streamed.
+	tokens, err := h.r.IdentityPool().ListIdentityPageTokensAfter(r.Context(), filter, after, pageSize+1)
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
-	x.PaginationHeader(w, urlx.AppendPaths(h.c.SelfAdminURL(), RouteBase), total, page, itemsPerPage)
-	h.r.Writer().Write(w, r, is)
+	var next string
+	if len(tokens) > pageSize {
+		tokens = tokens[:pageSize]
+		next = tokens[len(tokens)-1].Encode()
+	}
+
+	ids := make([]uuid.UUID, len(tokens))
+	for k := range tokens {
+		ids[k] = tokens[k].ID
+	}
+
+	x.KeysetPaginationHeader(w, urlx.CopyWithQuery(urlx.AppendPaths(h.c.SelfAdminURL(), RouteBase), r.URL.Query()), pageSize, next)
+	h.stream(w, r, ids)
+}
+
+var traitFilterPath = regexp.MustCompile(`^[a-zA-Z0-9_-]+(\.[a-zA-Z0-9_-]+)*$`)
+
+func parseListIdentitiesFilter(query url.Values) (ListIdentitiesFilter, error) {
+	filter := ListIdentitiesFilter{
+		CredentialsIdentifier: query.Get("credentials_identifier"),
+		SchemaID:              query.Get("schema_id"),
+		State:                 State(query.Get("state")),
+	}
+
+	if filter.State != "" {
+		if err := filter.State.Validate(); err != nil {
+			return filter, err
+		}
+	}
+
+	if raw := query.Get("verified"); raw != "" {
+		verified, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filter, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The verified filter "%s" is invalid, expected "true" or "false".`, raw))
+		}
+		filter.Verified = &verified
+	}
+
+	for key, values := range query {
+		if strings.HasPrefix(key, "labels.") {
+			name := strings.TrimPrefix(key, "labels.")
+			for _, value := range values {
+				if err := ValidateLabel(name, value); err != nil {
+					return filter, err
+				}
+				filter.Labels = append(filter.Labels, LabelFilter{Name: name, Value: value})
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(key, "traits.") {
+			continue
+		}
+
+		path := strings.TrimPrefix(key, "traits.")
+		if !traitFilterPath.MatchString(path) {
+			return filter, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The trait filter "%s" is invalid. Trait paths may only contain letters, digits, underscores, and dashes separated by dots.`, key))
+		}
+
+		for _, value := range values {
+			filter.Traits = append(filter.Traits, TraitFilter{Path: path, Value: value})
+		}
+	}
+
+	// Sort the filters so that identical requests result in identical queries.
+	sort.SliceStable(filter.Traits, func(i, j int) bool {
+		return filter.Traits[i].Path < filter.Traits[j].Path
+	})
+	sort.SliceStable(filter.Labels, func(i, j int) bool {
+		return filter.Labels[i].Name < filter.Labels[j].Name
+	})
+	return filter, nil
 }
 
 // swagger:parameters getIdentity
@@ -162,7 +484,8 @@ func (h *Handler) get(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 		return
 	}
 
-	h.r.Writer().Write(w, r, i)
+	w.Header().Set("ETag", i.Version())
+	h.r.Writer().Write(w, r, WithAdminMetadataInJSON(*i))
 }
 
 // swagger:parameters createIdentity
@@ -186,6 +509,19 @@ type CreateIdentity struct {
 	// required: true
 	// in: body
 	Traits json.RawMessage `json:"traits"`
+
+	// MetadataPublic is visible to the identity but can not be changed by it. It must be an object if set.
+	MetadataPublic Metadata `json:"metadata_public"`
+
+	// MetadataAdmin is only visible to administrators. It must be an object if set.
+	MetadataAdmin Metadata `json:"metadata_admin"`
+
+	// State is either `active` or `inactive` and defaults to `active`.
+	State State `json:"state"`
+
+	// ExpiresAt is the time after which the identity can no longer sign in. If omitted, the identity never
+	// expires.
+	ExpiresAt *time.Time `json:"expires_at"`
 }
 
 // swagger:route POST /identities admin createIdentity
@@ -193,7 +529,7 @@ type CreateIdentity struct {
 // Create an Identity
 //
 // This endpoint creates an identity. It is NOT possible to set an identity's credentials (password, ...)
-// using this method! A way to achieve that will be introduced in the future.
+// using this method! Use the import endpoint (`POST /identity-import`) to create identities with credentials.
 //
 // Learn how identities work in [ORY Kratos' User And Identity Model Documentation](https://www.ory.sh/docs/next/kratos/concepts/identity-user-model).
 //
@@ -217,19 +553,37 @@ func (h *Handler) create(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 		return
 	}
 
-	i := &Identity{SchemaID: cr.SchemaID, Traits: []byte(cr.Traits)}
+	if err := validateMetadata(cr.MetadataPublic, cr.MetadataAdmin); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if cr.State == "" {
+		cr.State = StateActive
+	} else if err := cr.State.Validate(); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	i := &Identity{SchemaID: cr.SchemaID, Traits: []byte(cr.Traits), MetadataPublic: cr.MetadataPublic, MetadataAdmin: cr.MetadataAdmin, State: cr.State, ExpiresAt: cr.ExpiresAt}
 	if err := h.r.IdentityManager().Create(r.Context(), i); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
+	i, err := h.reload(w, r, i.ID)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
 	h.r.Writer().WriteCreated(w, r,
 		urlx.AppendPaths(
 			h.c.SelfAdminURL(),
 			"identities",
 			i.ID.String(),
 		).String(),
-		i,
+		WithAdminMetadataInJSON(*i),
 	)
 }
 
@@ -241,6 +595,13 @@ type updateIdentityParameters struct {
 	// required: true
 	// in: path
 	ID string `json:"id"`
+
+	// IfMatch is the version of the identity as returned in the ETag header. If set, the update fails with 412
+	// Precondition Failed if the identity was changed since.
+	//
+	// in: header
+	IfMatch string `json:"If-Match"`
+
 	// in: body
 	Body UpdateIdentity
 }
@@ -256,6 +617,14 @@ type UpdateIdentity struct {
 	//
 	// required: true
 	Traits json.RawMessage `json:"traits"`
+
+	// MetadataPublic is visible to the identity but can not be changed by it. If omitted, the metadata is
+	// left unchanged. Set it to `null` to remove it.
+	MetadataPublic json.RawMessage `json:"metadata_public"`
+
+	// MetadataAdmin is only visible to administrators. If omitted, the metadata is left unchanged. Set it to
+	// `null` to remove it.
+	MetadataAdmin json.RawMessage `json:"metadata_admin"`
 }
 
 // swagger:route PUT /identities/{id} admin updateIdentity
@@ -265,7 +634,11 @@ type UpdateIdentity struct {
 // This endpoint updates an identity. It is NOT possible to set an identity's credentials (password, ...)
 // using this method! A way to achieve that will be introduced in the future.
 //
-// The full identity payload (except credentials) is expected. This endpoint does not support patching.
+// The full identity payload (except credentials) is expected. Use `PATCH /identities/{id}` to change single
+// traits or metadata fields.
+//
+// Send the ETag of the identity in the `If-Match` header to make sure that changes made by others since the
+// identity was loaded are not overwritten. The update fails with 412 Precondition Failed if the identity was changed.
 //
 // Learn how identities work in [ORY Kratos' User And Identity Model Documentation](https://www.ory.sh/docs/next/kratos/concepts/identity-user-model).
 //
@@ -281,6 +654,7 @@ type UpdateIdentity struct {
 //       200: identityResponse
 //       400: genericError
 //       404: genericError
+//       412: genericError
 //       500: genericError
 func (h *Handler) update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	var ur UpdateIdentity
@@ -300,53 +674,1210 @@ func (h *Handler) update(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		identity.SchemaID = ur.SchemaID
 	}
 
+	updateMetadata(&identity.MetadataPublic, ur.MetadataPublic)
+	updateMetadata(&identity.MetadataAdmin, ur.MetadataAdmin)
+	if err := validateMetadata(identity.MetadataPublic, identity.MetadataAdmin); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
 	identity.Traits = []byte(ur.Traits)
 	if err := h.r.IdentityManager().Update(
 		r.Context(),
 		identity,
 		ManagerAllowWriteProtectedTraits,
+		ManagerExpectVersion(ifMatch(r)...),
 	); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
-	h.r.Writer().Write(w, r, identity)
+	identity, err = h.reload(w, r, id)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, WithAdminMetadataInJSON(*identity))
 }
 
-// swagger:parameters deleteIdentity
+// swagger:parameters patchIdentity
 // nolint:deadcode,unused
-type deleteIdentityParameters struct {
-	// ID is the identity's ID.
+type patchIdentityParameters struct {
+	// ID must be set to the ID of identity you want to patch
 	//
 	// required: true
 	// in: path
 	ID string `json:"id"`
+
+	// IfMatch is the version of the identity as returned in the ETag header. If set, the patch fails with 412
+	// Precondition Failed if the identity was changed since.
+	//
+	// in: header
+	IfMatch string `json:"If-Match"`
+
+	// in: body
+	// required: true
+	Body []x.JSONPatchOperation
 }
 
-// swagger:route DELETE /identities/{id} admin deleteIdentity
+// patchIdentityAttempts is how often a patch is applied if the identity is changed concurrently.
+const patchIdentityAttempts = 3
+
+// patchableIdentityPaths are the JSON Pointers of the fields which can be patched.
+var patchableIdentityPaths = []string{"/traits", "/metadata_public", "/metadata_admin"}
+
+// patchableIdentity is the document JSON Patches of identities are applied to.
+type patchableIdentity struct {
+	Traits         json.RawMessage `json:"traits"`
+	MetadataPublic json.RawMessage `json:"metadata_public"`
+	MetadataAdmin  json.RawMessage `json:"metadata_admin"`
+}
+
+// swagger:route PATCH /identities/{id} admin patchIdentity
 //
-// Delete an Identity
+// Patch an Identity
 //
-// Calling this endpoint irrecoverably and permanently deletes the identity given its ID. This action can not be undone.
-// This endpoint returns 204 when the identity was deleted or when the identity was not found, in which case it is
-// assumed that is has been deleted already.
+// Applies a JSON Patch (RFC 6902) to the `traits`, `metadata_public`, and `metadata_admin` of an identity, for
+// example `[{"op":"replace","path":"/traits/email","value":"foo@ory.sh"}]`. Unlike a full update, a patch only
+// changes the given fields and is applied to the latest version of the identity, so that concurrent changes of
+// other fields are not lost. Use `test` operations to only apply the patch if a field has an expected value.
+// Missing metadata is `null` and must be added as a whole object first. Send the ETag of the identity in the
+// `If-Match` header to only apply the patch if the identity was not changed since it was loaded.
 //
 // Learn how identities work in [ORY Kratos' User And Identity Model Documentation](https://www.ory.sh/docs/next/kratos/concepts/identity-user-model).
 //
+//     Consumes:
+//     - application/json-patch+json
+//     - application/json
+//
 //     Produces:
 //     - application/json
 //
 //     Schemes: http, https
 //
 //     Responses:
-//       204: emptyResponse
-//		 404: genericError
+//       200: identityResponse
+//       400: genericError
+//       404: genericError
+//       409: genericError
+//       412: genericError
 //       500: genericError
-func (h *Handler) delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	if err := h.r.IdentityPool().(PrivilegedPool).DeleteIdentity(r.Context(), x.ParseUUID(ps.ByName("id"))); err != nil {
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var patch x.JSONPatch
+	if err := errors.WithStack(jsonx.NewStrictDecoder(r.Body).Decode(&patch)); err != nil {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := validatePatchPaths(patch); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	id := x.ParseUUID(ps.ByName("id"))
+	versions := ifMatch(r)
+	for attempt := 1; ; attempt++ {
+		i, err := h.r.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), id)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+
+		if err := applyPatch(i, patch); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+
+		err = h.r.IdentityManager().Update(r.Context(), i, ManagerAllowWriteProtectedTraits, ManagerExpectUpdatedAt(i.UpdatedAt), ManagerExpectVersion(versions...))
+		if errors.Is(err, ErrConcurrentUpdate) && attempt < patchIdentityAttempts {
+			// The patch is applied again to the identity as changed by the other request. If a version was
+			// expected, the next attempt fails with ErrVersionMismatch instead.
+			continue
+		} else if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+
+		i, err = h.reload(w, r, id)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+
+		h.r.Writer().Write(w, r, WithAdminMetadataInJSON(*i))
+		return
+	}
+}
+
+func validatePatchPaths(patch x.JSONPatch) error {
+	if len(patch) == 0 {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The JSON Patch must contain at least one operation."))
+	}
+
+	for _, path := range patch.Paths() {
+		var allowed bool
+		for _, prefix := range patchableIdentityPaths {
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The path "%s" can not be patched. Only paths below %s are allowed.`, path, strings.Join(patchableIdentityPaths, ", ")))
+		}
+	}
+	return nil
+}
+
+// applyPatch applies the JSON Patch to the traits and metadata of the identity.
+func applyPatch(i *Identity, patch x.JSONPatch) error {
+	doc, err := json.Marshal(&patchableIdentity{
+		Traits:         json.RawMessage(i.Traits),
+		MetadataPublic: json.RawMessage(i.MetadataPublic),
+		MetadataAdmin:  json.RawMessage(i.MetadataAdmin),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	patched, err := patch.Apply(doc)
+	if err != nil {
+		return err
+	}
+
+	var p patchableIdentity
+	if err := json.Unmarshal(patched, &p); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !gjson.ParseBytes(p.Traits).IsObject() {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("Identity traits must be a JSON object."))
+	}
+
+	i.Traits = Traits(p.Traits)
+	updateMetadata(&i.MetadataPublic, p.MetadataPublic)
+	updateMetadata(&i.MetadataAdmin, p.MetadataAdmin)
+	if len(p.MetadataPublic) == 0 {
+		i.MetadataPublic = nil
+	}
+	if len(p.MetadataAdmin) == 0 {
+		i.MetadataAdmin = nil
+	}
+	return validateMetadata(i.MetadataPublic, i.MetadataAdmin)
+}
+
+// reload loads the identity after it was written and sets the ETag header to its version. The identity is loaded
+// again because the stored timestamps may be less precise than the ones set when writing.
+func (h *Handler) reload(w http.ResponseWriter, r *http.Request, id uuid.UUID) (*Identity, error) {
+	i, err := h.r.IdentityPool().GetIdentity(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	w.Header().Set("ETag", i.Version())
+	return i, nil
+}
+
+// ifMatch returns the identity versions sent in the If-Match header or nil if any version is acceptable.
+func ifMatch(r *http.Request) []string {
+	var versions []string
+	for _, v := range strings.Split(r.Header.Get("If-Match"), ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" {
+			return nil
+		} else if v != "" {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+func withAdminMetadata(is []Identity) []WithAdminMetadataInJSON {
+	out := make([]WithAdminMetadataInJSON, len(is))
+	for k, i := range is {
+		out[k] = WithAdminMetadataInJSON(i)
+	}
+	return out
+}
+
+// updateMetadata replaces the metadata unless the field was omitted from the request.
+func updateMetadata(m *Metadata, raw json.RawMessage) {
+	switch {
+	case len(raw) == 0:
+		return
+	case string(raw) == "null":
+		*m = nil
+	default:
+		*m = Metadata(raw)
+	}
+}
+
+func validateMetadata(metadata ...Metadata) error {
+	for _, m := range metadata {
+		if len(m) > 0 && !gjson.ParseBytes(m).IsObject() {
+			return errors.WithStack(herodot.ErrBadRequest.WithReason("Identity metadata must be a JSON object."))
+		}
+	}
+	return nil
+}
+
+// swagger:parameters updateIdentityState
+// nolint:deadcode,unused
+type updateIdentityStateParameters struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// in: body
+	Body UpdateIdentityState
+}
+
+type UpdateIdentityState struct {
+	// State is either `active` or `inactive`.
+	//
+	// required: true
+	State State `json:"state"`
+}
+
+// swagger:route PUT /identities/{id}/state admin updateIdentityState
+//
+// Activate or Deactivate an Identity
+//
+// Inactive identities can not sign in and their existing sessions are rejected until the identity is activated
+// again. The sessions are not revoked.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityResponse
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) updateState(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ur UpdateIdentityState
+	if err := errors.WithStack(jsonx.NewStrictDecoder(r.Body).Decode(&ur)); err != nil {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := ur.State.Validate(); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	id := x.ParseUUID(ps.ByName("id"))
+	if err := h.r.PrivilegedIdentityPool().UpdateIdentityState(r.Context(), id, ur.State); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	i, err := h.r.PrivilegedIdentityPool().GetIdentity(r.Context(), id)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", id).
+		WithField("state", ur.State).
+		Info("The state of an identity was changed by an administrator.")
+	h.r.Writer().Write(w, r, WithAdminMetadataInJSON(*i))
+}
+
+// swagger:parameters updateIdentityExpiry
+// nolint:deadcode,unused
+type updateIdentityExpiryParameters struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// in: body
+	Body UpdateIdentityExpiry
+}
+
+type UpdateIdentityExpiry struct {
+	// ExpiresAt is the time after which the identity can no longer sign in. Set it to `null` to let the identity
+	// never expire.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// swagger:route PUT /identities/{id}/expiry admin updateIdentityExpiry
+//
+// Set or Remove the Expiry of an Identity
+//
+// Expired identities can not sign in and their sessions are rejected. The sessions are revoked once the
+// session janitor runs. Moving the expiry into the future lets the identity sign in again.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityResponse
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) updateExpiry(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ur UpdateIdentityExpiry
+	if err := errors.WithStack(jsonx.NewStrictDecoder(r.Body).Decode(&ur)); err != nil {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	if ur.ExpiresAt != nil {
+		expiresAt := ur.ExpiresAt.UTC()
+		ur.ExpiresAt = &expiresAt
+	}
+
+	id := x.ParseUUID(ps.ByName("id"))
+	if err := h.r.PrivilegedIdentityPool().UpdateIdentityExpiry(r.Context(), id, ur.ExpiresAt); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	i, err := h.r.PrivilegedIdentityPool().GetIdentity(r.Context(), id)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", id).
+		WithField("expires_at", ur.ExpiresAt).
+		Info("The expiry of an identity was changed by an administrator.")
+	h.r.Writer().Write(w, r, WithAdminMetadataInJSON(*i))
+}
+
+// swagger:parameters deleteIdentity
+// nolint:deadcode,unused
+type deleteIdentityParameters struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// Soft hides the identity instead of deleting it. It can be restored until it is purged after
+	// `identity.soft_delete.retention`.
+	//
+	// in: query
+	Soft bool `json:"soft"`
+}
+
+// swagger:route DELETE /identities/{id} admin deleteIdentity
+//
+// Delete an Identity
+//
+// Calling this endpoint irrecoverably and permanently deletes the identity given its ID. This action can not be undone.
+// This endpoint returns 204 when the identity was deleted or when the identity was not found, in which case it is
+// assumed that is has been deleted already.
+//
+// If `soft` is set, the identity is hidden and its sessions are revoked, but its credentials and addresses are kept
+// so that it can be restored until it is purged.
+//
+// Identities under legal hold can not be deleted and this endpoint returns 409.
+//
+// Learn how identities work in [ORY Kratos' User And Identity Model Documentation](https://www.ory.sh/docs/next/kratos/concepts/identity-user-model).
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//		 404: genericError
+//       409: genericError
+//       500: genericError
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id := x.ParseUUID(ps.ByName("id"))
+	if soft, _ := strconv.ParseBool(r.URL.Query().Get("soft")); soft {
+		if err := h.r.PrivilegedIdentityPool().SoftDeleteIdentity(r.Context(), id); err != nil {
+			h.auditLegalHold(r, id, err)
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+
+		h.r.Audit().
+			WithRequest(r).
+			WithField("identity_id", id).
+			Info("An identity was soft-deleted by an administrator.")
+		h.r.SessionRevocationNotifier().IdentitySessionsRevoked(id, SessionRevocationReasonIdentityDeleted)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := h.r.IdentityPool().(PrivilegedPool).DeleteIdentity(r.Context(), id); err != nil {
+		h.auditLegalHold(r, id, err)
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.SessionRevocationNotifier().IdentitySessionsRevoked(id, SessionRevocationReasonIdentityDeleted)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:parameters restoreIdentity
+// nolint:deadcode,unused
+type restoreIdentityParameters struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route POST /identities/{id}/restore admin restoreIdentity
+//
+// Restore a Soft-Deleted Identity
+//
+// Restores an identity which was soft-deleted and not purged yet. Its sessions stay revoked.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityResponse
+//       404: genericError
+//       500: genericError
+func (h *Handler) restore(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id := x.ParseUUID(ps.ByName("id"))
+	if err := h.r.PrivilegedIdentityPool().RestoreIdentity(r.Context(), id); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	i, err := h.r.PrivilegedIdentityPool().GetIdentity(r.Context(), id)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", id).
+		Info("A soft-deleted identity was restored by an administrator.")
+	h.r.Writer().Write(w, r, WithAdminMetadataInJSON(*i))
+}
+
+// swagger:parameters mergeIdentities
+// nolint:deadcode,unused
+type mergeIdentitiesParameters struct {
+	// ID is the ID of the primary identity which is kept.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// in: body
+	// required: true
+	Body MergeIdentities
+}
+
+type MergeIdentities struct {
+	// DuplicateID is the ID of the identity which is merged into the primary identity and deleted.
+	//
+	// required: true
+	DuplicateID uuid.UUID `json:"duplicate_id"`
+}
+
+// A merge result.
+//
+// swagger:response identityMergeResult
+// nolint:deadcode,unused
+type identityMergeResultResponse struct {
+	// in: body
+	Body MergeResult
+}
+
+// swagger:route POST /identities/{id}/merge admin mergeIdentities
+//
+// Merge a Duplicate Identity into an Identity
+//
+// Folds a duplicate identity into the primary identity and deletes the duplicate in a single transaction:
+//
+// - Credentials of a type the primary does not have are moved to the primary.
+// - Credentials of a type both identities have are merged: the duplicate's identifiers are added to the primary's
+//   credentials and the primary's configuration, for example its password, is kept. Linked OpenID Connect
+//   accounts of both identities remain usable.
+// - Verifiable and recovery addresses are moved to the primary. Addresses which are not part of the primary's
+//   traits are removed the next time its traits are updated.
+// - Sessions are moved to the primary, so that the duplicate's devices stay signed in.
+// - The traits, metadata, state, and schema of the primary are kept. Those of the duplicate are discarded.
+//
+// Duplicates under legal hold can not be merged because merging deletes them.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityMergeResult
+//       400: genericError
+//       404: genericError
+//       409: genericError
+//       500: genericError
+func (h *Handler) merge(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var mr MergeIdentities
+	if err := errors.WithStack(jsonx.NewStrictDecoder(r.Body).Decode(&mr)); err != nil {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	id := x.ParseUUID(ps.ByName("id"))
+	if mr.DuplicateID == uuid.Nil {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The ID of the duplicate identity must be set.")))
+		return
+	} else if mr.DuplicateID == id {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("An identity can not be merged into itself.")))
+		return
+	}
+
+	result, err := h.r.PrivilegedIdentityPool().MergeIdentities(r.Context(), id, mr.DuplicateID)
+	if err != nil {
+		h.auditLegalHold(r, mr.DuplicateID, err)
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", result.Primary).
+		WithField("duplicate_identity_id", result.Duplicate).
+		WithField("moved_credentials", result.MovedCredentials).
+		WithField("merged_credentials", result.MergedCredentials).
+		WithField("moved_identifiers", result.Identifiers).
+		WithField("moved_verifiable_addresses", result.VerifiableAddresses).
+		WithField("moved_recovery_addresses", result.RecoveryAddresses).
+		WithField("moved_sessions", result.Sessions).
+		Info("A duplicate identity was merged into an identity by an administrator.")
+	h.r.Writer().Write(w, r, result)
+}
+
+// swagger:parameters bulkDeleteIdentities
+// nolint:deadcode,unused
+type bulkDeleteIdentitiesParameters struct {
+	// Filter selects the identities to delete and is of the form `key:value`. Supported keys are
+	// `schema_id`, `created_before`, `created_after` (RFC 3339 timestamps), `older_than` (a duration such as
+	// `90d`), `credentials_identifier` which supports `*` as a wildcard, and `verified` which is `true` for
+	// identities with at least one verified address and `false` for identities without. Multiple filters must all
+	// match.
+	//
+	// in: query
+	Filter []string `json:"filter"`
+
+	// IDs are comma-separated IDs of the identities to delete. If combined with filters, only the identities
+	// matching the filters are deleted.
+	//
+	// in: query
+	IDs []string `json:"ids"`
+
+	// DryRun only counts the matching identities without deleting them.
+	//
+	// in: query
+	DryRun bool `json:"dry_run"`
+}
+
+// A bulk deletion job.
+//
+// swagger:response identityBulkDeletionResponse
+// nolint:deadcode,unused
+type identityBulkDeletionResponse struct {
+	// in: body
+	Body BulkDeletion
+}
+
+// swagger:route DELETE /identities admin bulkDeleteIdentities
+//
+// Delete Identities Matching a Filter
+//
+// This endpoint deletes all identities with the given IDs or matching the filter, for example
+// `?filter=verified:false&filter=older_than:90d` to purge unverified identities after 90 days. Use `dry_run=true`
+// to learn how many identities match before deleting them. Without `dry_run` the identities are deleted in the
+// background in chunks of 100 identities per transaction and the endpoint returns the job which can be polled
+// using `GET /identity-bulk-deletions/{id}`. Identities under legal hold are skipped. Each deletion is written to
+// the audit log. This action can not be undone.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityBulkDeletionResponse
+//       202: identityBulkDeletionResponse
+//       400: genericError
+//       500: genericError
+func (h *Handler) bulkDelete(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	filter, err := ParseFilter(r.URL.Query()["filter"])
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if filter.IDs, err = ParseFilterIDs(r.URL.Query()["ids"]); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if filter.IsEmpty() {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("At least one filter or identity ID is required to delete identities in bulk.")))
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	job, err := h.deletions.start(r.Context(), filter, dryRun)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if dryRun {
+		h.r.Writer().Write(w, r, job)
+		return
+	}
+
+	w.Header().Set("Location", urlx.AppendPaths(h.c.SelfAdminURL(), RouteBulkDeletions, job.ID.String()).String())
+	h.r.Writer().WriteCode(w, r, http.StatusAccepted, job)
+}
+
+// swagger:parameters getIdentityBulkDeletion
+// nolint:deadcode,unused
+type getIdentityBulkDeletionParameters struct {
+	// ID is the bulk deletion job's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route GET /identity-bulk-deletions/{id} admin getIdentityBulkDeletion
+//
+// Get a Bulk Identity Deletion Job
+//
+// Returns the progress of a bulk deletion job. Jobs are stored in the database and can be fetched from every instance.
+// If the instance running a job stops, the job is marked as failed once it did not make progress for five minutes.
+// Starting the deletion again deletes the remaining identities.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityBulkDeletionResponse
+//       404: genericError
+func (h *Handler) getBulkDeletion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	job, err := h.deletions.get(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, job)
+}
+
+// swagger:parameters listQuarantinedIdentities
+// nolint:deadcode,unused
+type listQuarantinedIdentitiesParameters struct {
+	// Items per Page
+	//
+	// This is the number of items per page.
+	//
+	// required: false
+	// in: query
+	// default: 100
+	// min: 1
+	// max: 500
+	PerPage int `json:"per_page"`
+
+	// Pagination Page
+	//
+	// required: false
+	// in: query
+	// default: 0
+	// min: 0
+	Page int `json:"page"`
+}
+
+// swagger:route GET /identity-quarantine admin listQuarantinedIdentities
+//
+// List Quarantined Identities
+//
+// Lists the identities which were quarantined because they were registered under suspicious conditions, oldest
+// first. Quarantined identities can not change their settings until they verify an address or are released.
+// Use `DELETE /identity-quarantine/{id}` to release an identity and `DELETE /identities/{id}` to reject it.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityList
+//       500: genericError
+func (h *Handler) listQuarantined(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	page, itemsPerPage := x.ParsePagination(r)
+	is, err := h.r.PrivilegedIdentityPool().ListQuarantinedIdentities(r.Context(), page, itemsPerPage)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, withAdminMetadata(is))
+}
+
+// swagger:parameters releaseIdentityFromQuarantine
+// nolint:deadcode,unused
+type releaseIdentityFromQuarantineParameters struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /identity-quarantine/{id} admin releaseIdentityFromQuarantine
+//
+// Release an Identity from Quarantine
+//
+// Lifts all restrictions of a quarantined identity. This endpoint returns 404 if the identity does not exist or
+// is not quarantined. If break-glass access is enabled, the request must use an open break-glass grant for the
+// identity and returns 403 otherwise.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       403: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) releaseFromQuarantine(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id := x.ParseUUID(ps.ByName("id"))
+	if err := h.r.BreakGlassGuard().AuthorizeBreakGlass(r, id, BreakGlassOperationReleaseQuarantine); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.r.PrivilegedIdentityPool().ReleaseIdentityFromQuarantine(r.Context(), id); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", id).
+		Info("A quarantined identity was released by an administrator.")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// auditLegalHold writes an audit event if the deletion of an identity was blocked by its legal hold.
+func (h *Handler) auditLegalHold(r *http.Request, id uuid.UUID, err error) {
+	if errors.Is(err, ErrLegalHold) {
+		h.r.Audit().
+			WithRequest(r).
+			WithField("identity_id", id).
+			Info("The deletion of an identity under legal hold was blocked.")
+	}
+}
+
+// swagger:parameters placeIdentityOnLegalHold
+// nolint:deadcode,unused
+type placeIdentityOnLegalHoldParameters struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// in: body
+	Body PlaceIdentityOnLegalHold
+}
+
+type PlaceIdentityOnLegalHold struct {
+	// Reason explains why the identity is under legal hold, for example the reference of the litigation.
+	//
+	// required: true
+	Reason string `json:"reason"`
+}
+
+// swagger:route PUT /identities/{id}/legal-hold admin placeIdentityOnLegalHold
+//
+// Place an Identity on Legal Hold
+//
+// Identities under legal hold can be used as usual but can not be deleted, neither using this API nor by
+// purging soft-deleted identities or by bulk deletions, until the legal hold is released. Soft-deleted identities
+// can be placed on legal hold as well. The legal hold is only visible to administrators.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) placeOnLegalHold(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var p PlaceIdentityOnLegalHold
+	if err := errors.WithStack(jsonx.NewStrictDecoder(r.Body).Decode(&p)); err != nil {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	p.Reason = strings.TrimSpace(p.Reason)
+	if p.Reason == "" {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The reason of the legal hold must be set.")))
+		return
+	}
+
+	id := x.ParseUUID(ps.ByName("id"))
+	if err := h.r.PrivilegedIdentityPool().PlaceIdentityOnLegalHold(r.Context(), id, p.Reason); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", id).
+		WithField("reason", p.Reason).
+		Info("An identity was placed on legal hold by an administrator.")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:parameters releaseIdentityFromLegalHold
+// nolint:deadcode,unused
+type releaseIdentityFromLegalHoldParameters struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /identities/{id}/legal-hold admin releaseIdentityFromLegalHold
+//
+// Release an Identity from Legal Hold
+//
+// Allows the identity to be deleted again. This endpoint returns 404 if the identity does not exist or is not
+// under legal hold.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       404: genericError
+//       500: genericError
+func (h *Handler) releaseFromLegalHold(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id := x.ParseUUID(ps.ByName("id"))
+	if err := h.r.PrivilegedIdentityPool().ReleaseIdentityFromLegalHold(r.Context(), id); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", id).
+		Info("An identity was released from legal hold by an administrator.")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:parameters setIdentityLabel
+// nolint:deadcode,unused
+type setIdentityLabelParameters struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// Name is the name of the label. It must be 1 to 64 characters long and may only contain letters, digits,
+	// underscores, dots, and dashes.
+	//
+	// required: true
+	// in: path
+	Name string `json:"name"`
+
+	// in: body
+	Body SetIdentityLabel
+}
+
+type SetIdentityLabel struct {
+	// Value is the value of the label. It must not be longer than 255 characters.
+	//
+	// required: true
+	Value string `json:"value"`
+}
+
+// swagger:route PUT /identities/{id}/labels/{name} admin setIdentityLabel
+//
+// Set a Label of an Identity
+//
+// Labels are key/value pairs which allow operators to segment identities, for example to mark beta testers or
+// identities migrated from another system, without changing the identity schema. Identities can be listed by
+// their labels using `?labels.<name>=<value>`. Setting a label which already exists replaces its value. Labels
+// are only visible to administrators.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) setLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var p SetIdentityLabel
+	if err := errors.WithStack(jsonx.NewStrictDecoder(r.Body).Decode(&p)); err != nil {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	name := ps.ByName("name")
+	if err := ValidateLabel(name, p.Value); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	id := x.ParseUUID(ps.ByName("id"))
+	if err := h.r.PrivilegedIdentityPool().SetIdentityLabel(r.Context(), id, name, p.Value); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", id).
+		WithField("label", name).
+		WithField("value", p.Value).
+		Info("A label of an identity was set by an administrator.")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:parameters deleteIdentityLabel
+// nolint:deadcode,unused
+type deleteIdentityLabelParameters struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// Name is the name of the label.
+	//
+	// required: true
+	// in: path
+	Name string `json:"name"`
+}
+
+// swagger:route DELETE /identities/{id}/labels/{name} admin deleteIdentityLabel
+//
+// Remove a Label from an Identity
+//
+// This endpoint returns 404 if the identity does not exist or does not have the label.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       404: genericError
+//       500: genericError
+func (h *Handler) deleteLabel(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id := x.ParseUUID(ps.ByName("id"))
+	name := ps.ByName("name")
+	if err := h.r.PrivilegedIdentityPool().DeleteIdentityLabel(r.Context(), id, name); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", id).
+		WithField("label", name).
+		Info("A label was removed from an identity by an administrator.")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:parameters listIdentityCredentials
+// nolint:deadcode,unused
+type listIdentityCredentialsParameters struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// A list of credentials descriptions.
+// swagger:response identityCredentialsDescriptionList
+// nolint:deadcode,unused
+type identityCredentialsDescriptionListResponse struct {
+	// in: body
+	// type: array
+	Body []CredentialsDescription
+}
+
+// A credentials description.
+//
+// swagger:response identityCredentialsDescription
+// nolint:deadcode,unused
+type identityCredentialsDescriptionResponse struct {
+	// in: body
+	Body CredentialsDescription
+}
+
+// swagger:route GET /identities/{id}/credentials admin listIdentityCredentials
+//
+// List the Credentials of an Identity
+//
+// Lists the credentials the identity has enrolled, for example a password and linked OpenID Connect provider
+// accounts. Secrets such as hashed passwords are never returned.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityCredentialsDescriptionList
+//       404: genericError
+//       500: genericError
+func (h *Handler) listCredentials(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	i, err := h.r.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, i.DescribeAllCredentials())
+}
+
+// swagger:parameters getIdentityCredentials deleteIdentityCredentials
+// nolint:deadcode,unused
+type identityCredentialsParameters struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// Type is the type of the credentials, for example `password` or `oidc`.
+	//
+	// required: true
+	// in: path
+	Type string `json:"type"`
+}
+
+// swagger:route GET /identities/{id}/credentials/{type} admin getIdentityCredentials
+//
+// Get the Credentials of an Identity by Type
+//
+// Secrets such as hashed passwords are never returned. This endpoint returns 404 if the identity does not exist
+// or does not have credentials of this type.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityCredentialsDescription
+//       404: genericError
+//       500: genericError
+func (h *Handler) getCredentials(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	i, err := h.r.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	c, ok := i.GetCredentials(CredentialsType(ps.ByName("type")))
+	if !ok {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReasonf(`The identity does not have credentials of type "%s".`, ps.ByName("type"))))
+		return
+	}
+
+	h.r.Writer().Write(w, r, DescribeCredentials(*c))
+}
+
+// swagger:route DELETE /identities/{id}/credentials/{type} admin deleteIdentityCredentials
+//
+// Delete the Credentials of an Identity by Type
+//
+// Removes compromised or stuck credentials, for example a password or all linked OpenID Connect provider
+// accounts, without replacing the whole identity. The identity's sessions are not revoked. If the identity has no
+// other credentials left, it can only sign in again after recovering its account. This endpoint returns 404 if
+// the identity does not exist or does not have credentials of this type. If break-glass access is enabled, the
+// request must use an open break-glass grant for the identity and returns 403 otherwise.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       403: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) deleteCredentials(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id := x.ParseUUID(ps.ByName("id"))
+	ct := CredentialsType(ps.ByName("type"))
+	if err := h.r.BreakGlassGuard().AuthorizeBreakGlass(r, id, BreakGlassOperationDeleteCredentials); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.r.PrivilegedIdentityPool().DeleteIdentityCredentials(r.Context(), id, ct); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", id).
+		WithField("credentials_type", ct).
+		Info("Credentials of an identity were deleted by an administrator.")
 	w.WriteHeader(http.StatusNoContent)
 }
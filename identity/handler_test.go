@@ -2,12 +2,20 @@ package identity_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/ory/x/sqlxx"
+	"github.com/ory/x/stringslice"
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/internal/testhelpers"
@@ -20,11 +28,14 @@ import (
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
 
 func TestHandler(t *testing.T) {
 	conf, reg := internal.NewFastRegistryWithMocks(t)
+	clock := new(x.TestClock)
+	reg.WithClock(clock)
 	router := x.NewRouterAdmin()
 	reg.IdentityHandler().RegisterAdminRoutes(router)
 	ts := httptest.NewServer(router)
@@ -38,6 +49,7 @@ func TestHandler(t *testing.T) {
 	testhelpers.SetIdentitySchemas(t, conf, map[string]string{
 		"customer": "file://./stub/handler/customer.schema.json",
 		"employee": "file://./stub/handler/employee.schema.json",
+		"import":   "file://./stub/handler/import.schema.json",
 	})
 	conf.MustSet(config.ViperKeyPublicBaseURL, mockServerURL.String())
 
@@ -116,6 +128,18 @@ func TestHandler(t *testing.T) {
 		assert.Empty(t, res.Get("credentials").String(), "%s", res.Raw)
 	})
 
+	t.Run("case=should create an identity with a version 7 ID if configured", func(t *testing.T) {
+		x.UseUUIDv7(true)
+		t.Cleanup(func() {
+			x.UseUUIDv7(false)
+		})
+
+		var i identity.CreateIdentity
+		i.Traits = []byte(`{"bar":"baz"}`)
+		res := send(t, "POST", "/identities", http.StatusCreated, &i)
+		assert.EqualValues(t, 7, x.ParseUUID(res.Get("id").String()).Version(), "%s", res.Raw)
+	})
+
 	t.Run("case=unable to set ID itself", func(t *testing.T) {
 		res := send(t, "POST", "/identities", http.StatusBadRequest, json.RawMessage(`{"id":"12345","traits":{}}`))
 		assert.Contains(t, res.Raw, "id")
@@ -204,6 +228,61 @@ func TestHandler(t *testing.T) {
 		assert.EqualValues(t, updatedEmail, res.Get("verifiable_addresses.0.value").String(), "%s", res.Raw)
 	})
 
+	t.Run("suite=metadata", func(t *testing.T) {
+		res := send(t, "POST", "/identities", http.StatusCreated, &identity.CreateIdentity{
+			Traits:         []byte(`{"bar":"baz"}`),
+			MetadataPublic: identity.Metadata(`{"plan":"pro"}`),
+			MetadataAdmin:  identity.Metadata(`{"customer_id":"cus_1"}`),
+		})
+		id := res.Get("id").String()
+		assert.Equal(t, "pro", res.Get("metadata_public.plan").String(), "%s", res.Raw)
+		assert.Equal(t, "cus_1", res.Get("metadata_admin.customer_id").String(), "%s", res.Raw)
+
+		t.Run("case=should return the metadata", func(t *testing.T) {
+			res := get(t, "/identities/"+id, http.StatusOK)
+			assert.Equal(t, "cus_1", res.Get("metadata_admin.customer_id").String(), "%s", res.Raw)
+
+			var found bool
+			for _, i := range get(t, "/identities", http.StatusOK).Array() {
+				if i.Get("id").String() == id {
+					found = true
+					assert.Equal(t, "cus_1", i.Get("metadata_admin.customer_id").String(), "%s", i.Raw)
+				}
+			}
+			assert.True(t, found)
+		})
+
+		t.Run("case=should not expose the admin metadata outside of the admin API", func(t *testing.T) {
+			i, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), x.ParseUUID(id))
+			require.NoError(t, err)
+			out, err := json.Marshal(i)
+			require.NoError(t, err)
+			assert.False(t, gjson.GetBytes(out, "metadata_admin").Exists(), "%s", out)
+			assert.Equal(t, "pro", gjson.GetBytes(out, "metadata_public.plan").String(), "%s", out)
+		})
+
+		t.Run("case=should keep the metadata if omitted", func(t *testing.T) {
+			res := send(t, "PUT", "/identities/"+id, http.StatusOK, json.RawMessage(`{"traits":{"bar":"baz"}}`))
+			assert.Equal(t, "pro", res.Get("metadata_public.plan").String(), "%s", res.Raw)
+			assert.Equal(t, "cus_1", res.Get("metadata_admin.customer_id").String(), "%s", res.Raw)
+		})
+
+		t.Run("case=should update and remove the metadata", func(t *testing.T) {
+			res := send(t, "PUT", "/identities/"+id, http.StatusOK, json.RawMessage(`{"traits":{"bar":"baz"},"metadata_public":null,"metadata_admin":{"customer_id":"cus_2"}}`))
+			assert.False(t, res.Get("metadata_public").Exists(), "%s", res.Raw)
+			assert.Equal(t, "cus_2", res.Get("metadata_admin.customer_id").String(), "%s", res.Raw)
+
+			res = get(t, "/identities/"+id, http.StatusOK)
+			assert.False(t, res.Get("metadata_public").Exists(), "%s", res.Raw)
+			assert.Equal(t, "cus_2", res.Get("metadata_admin.customer_id").String(), "%s", res.Raw)
+		})
+
+		t.Run("case=should reject metadata which is not an object", func(t *testing.T) {
+			send(t, "POST", "/identities", http.StatusBadRequest, json.RawMessage(`{"traits":{"bar":"baz"},"metadata_admin":"cus_1"}`))
+			send(t, "PUT", "/identities/"+id, http.StatusBadRequest, json.RawMessage(`{"traits":{"bar":"baz"},"metadata_public":[1]}`))
+		})
+	})
+
 	t.Run("case=should update the schema id and fail because traits are invalid", func(t *testing.T) {
 		var cr identity.CreateIdentity
 		cr.SchemaID = "employee"
@@ -258,6 +337,130 @@ func TestHandler(t *testing.T) {
 		assert.EqualValues(t, "baz", res.Get(`#(traits.bar=="baz").traits.bar`).String(), "%s", res.Raw)
 	})
 
+	t.Run("case=should stream large pages in chunks", func(t *testing.T) {
+		is := make([]*identity.Identity, 150)
+		for k := range is {
+			is[k] = identity.NewIdentity("")
+			is[k].Traits = identity.Traits(fmt.Sprintf(`{"bar":"stream-%d"}`, k))
+		}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentities(context.Background(), is))
+
+		res, err := ts.Client().Get(ts.URL + "/identities?per_page=1000")
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.EqualValues(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.Equal(t, []string{"chunked"}, res.TransferEncoding)
+		assert.Equal(t, "application/json; charset=utf-8", res.Header.Get("Content-Type"))
+
+		listed := gjson.ParseBytes(body).Array()
+		assert.EqualValues(t, get(t, "/identity-count", http.StatusOK).Get("count").Int(), len(listed))
+		for k := 1; k < len(listed); k++ {
+			assert.True(t, listed[k-1].Get("id").String() > listed[k].Get("id").String(), "identities are ordered by their ID")
+		}
+
+		assert.Len(t, get(t, "/identities?page_size=120", http.StatusOK).Array(), 120)
+	})
+
+	t.Run("suite=filter", func(t *testing.T) {
+		withEmail := identity.NewIdentity("")
+		withEmail.Traits = identity.Traits(`{"bar":"filter-a","email":"filter@ory.sh"}`)
+		withEmail.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+			Type: identity.CredentialsTypePassword, Identifiers: []string{"filter@ory.sh"}, Config: sqlxx.JSONRawMessage(`{}`)})
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), withEmail))
+		send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"filter-b"}}`))
+
+		t.Run("case=should filter by credentials identifier", func(t *testing.T) {
+			res := get(t, "/identities?credentials_identifier=filter@ory.sh", http.StatusOK)
+			require.Len(t, res.Array(), 1, "%s", res.Raw)
+			assert.Equal(t, withEmail.ID.String(), res.Get("0.id").String(), "%s", res.Raw)
+		})
+
+		t.Run("case=should filter by traits", func(t *testing.T) {
+			res := get(t, "/identities?traits.bar=filter-b", http.StatusOK)
+			require.Len(t, res.Array(), 1, "%s", res.Raw)
+			assert.Equal(t, "filter-b", res.Get("0.traits.bar").String(), "%s", res.Raw)
+
+			assert.Len(t, get(t, "/identities?traits.bar=filter-a&traits.email=filter@ory.sh", http.StatusOK).Array(), 1)
+			assert.Len(t, get(t, "/identities?traits.bar=filter-b&credentials_identifier=filter@ory.sh", http.StatusOK).Array(), 0)
+		})
+
+		t.Run("case=should keep the filters in the pagination links", func(t *testing.T) {
+			res, err := ts.Client().Get(ts.URL + "/identities?traits.bar=filter-b&per_page=1")
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+			assert.Contains(t, res.Header.Get("Link"), "traits.bar=filter-b")
+		})
+
+		t.Run("case=should reject invalid trait paths", func(t *testing.T) {
+			get(t, "/identities?traits.bar%27=filter-b", http.StatusBadRequest)
+			get(t, "/identities?traits..bar=filter-b", http.StatusBadRequest)
+		})
+
+		t.Run("case=should filter by schema, state, and verified status", func(t *testing.T) {
+			filter := "/identities?credentials_identifier=filter@ory.sh&schema_id=" + config.DefaultIdentityTraitsSchemaID
+			assert.Len(t, get(t, filter+"&state=active&verified=false", http.StatusOK).Array(), 1)
+			assert.Len(t, get(t, filter+"&state=inactive", http.StatusOK).Array(), 0)
+			assert.Len(t, get(t, filter+"&verified=true", http.StatusOK).Array(), 0)
+			assert.Len(t, get(t, "/identities?credentials_identifier=filter@ory.sh&schema_id=customer", http.StatusOK).Array(), 0)
+		})
+
+		t.Run("case=should count identities", func(t *testing.T) {
+			total := get(t, "/identity-count", http.StatusOK).Get("count").Int()
+			assert.EqualValues(t, len(get(t, "/identities?per_page=500", http.StatusOK).Array()), total)
+
+			assert.EqualValues(t, 1, get(t, "/identity-count?traits.bar=filter-b", http.StatusOK).Get("count").Int())
+			assert.EqualValues(t, 1, get(t, "/identity-count?credentials_identifier=filter@ory.sh&state=active&verified=false", http.StatusOK).Get("count").Int())
+			assert.EqualValues(t, 0, get(t, "/identity-count?credentials_identifier=filter@ory.sh&verified=true", http.StatusOK).Get("count").Int())
+		})
+
+		t.Run("case=should reject invalid state and verified filters", func(t *testing.T) {
+			get(t, "/identities?state=paused", http.StatusBadRequest)
+			get(t, "/identity-count?state=paused", http.StatusBadRequest)
+			get(t, "/identity-count?verified=maybe", http.StatusBadRequest)
+		})
+	})
+
+	t.Run("suite=keyset pagination", func(t *testing.T) {
+		nextLink := regexp.MustCompile(`<([^>]+)>; rel="next"`)
+
+		t.Run("case=should follow the next links until all identities are listed", func(t *testing.T) {
+			expected := len(get(t, "/identities", http.StatusOK).Array())
+			require.True(t, expected > 2, "the test requires several identities")
+
+			var ids []string
+			href := ts.URL + "/identities?page_size=2"
+			for href != "" {
+				res, err := ts.Client().Get(href)
+				require.NoError(t, err)
+				body, err := ioutil.ReadAll(res.Body)
+				require.NoError(t, err)
+				require.NoError(t, res.Body.Close())
+				require.EqualValues(t, http.StatusOK, res.StatusCode, "%s", body)
+
+				page := gjson.ParseBytes(body).Array()
+				require.True(t, len(page) <= 2, "%s", body)
+				for _, i := range page {
+					ids = append(ids, i.Get("id").String())
+				}
+
+				href = ""
+				if m := nextLink.FindStringSubmatch(res.Header.Get("Link")); len(m) == 2 {
+					href = m[1]
+				}
+			}
+
+			assert.Len(t, ids, expected)
+			assert.ElementsMatch(t, ids, stringslice.Unique(ids), "identities must not be listed twice")
+		})
+
+		t.Run("case=should reject invalid page tokens", func(t *testing.T) {
+			get(t, "/identities?page_token=invalid", http.StatusBadRequest)
+			get(t, "/identities?page_token=e30", http.StatusBadRequest)
+		})
+	})
+
 	t.Run("case=should not be able to update an identity that does not exist yet", func(t *testing.T) {
 		res := send(t, "PUT", "/identities/not-found", http.StatusNotFound, json.RawMessage(`{"traits": {"bar":"baz"}}`))
 		assert.Contains(t, res.Get("error.message").String(), "Unable to locate the resource", "%s", res.Raw)
@@ -266,4 +469,554 @@ func TestHandler(t *testing.T) {
 	t.Run("case=should return 404 for non-existing identities", func(t *testing.T) {
 		remove(t, "/identities/"+x.NewUUID().String(), http.StatusNotFound)
 	})
+
+	t.Run("suite=bulk delete", func(t *testing.T) {
+		total := len(get(t, "/identities", http.StatusOK).Array())
+		for i := 0; i < 3; i++ {
+			send(t, "POST", "/identities", http.StatusCreated, &identity.CreateIdentity{
+				SchemaID: "customer",
+				Traits:   []byte(`{"email":"` + x.NewUUID().String() + `@ory.sh"}`),
+			})
+		}
+
+		var matched int64
+		t.Run("case=should count matching identities without deleting them", func(t *testing.T) {
+			res := send(t, "DELETE", "/identities?filter=schema_id:customer&dry_run=true", http.StatusOK, nil)
+			matched = res.Get("matched").Int()
+			assert.True(t, matched >= 3, "%s", res.Raw)
+			assert.EqualValues(t, 0, res.Get("deleted").Int(), "%s", res.Raw)
+			assert.EqualValues(t, identity.BulkDeletionStateCompleted, res.Get("state").String(), "%s", res.Raw)
+			assert.Len(t, get(t, "/identities", http.StatusOK).Array(), total+3)
+		})
+
+		t.Run("case=should delete matching identities in the background", func(t *testing.T) {
+			res := send(t, "DELETE", "/identities?filter=schema_id:customer", http.StatusAccepted, nil)
+			assert.EqualValues(t, matched, res.Get("matched").Int(), "%s", res.Raw)
+
+			id := res.Get("id").String()
+			var job gjson.Result
+			require.Eventually(t, func() bool {
+				job = get(t, "/identity-bulk-deletions/"+id, http.StatusOK)
+				return job.Get("state").String() == string(identity.BulkDeletionStateCompleted)
+			}, 5*time.Second, 10*time.Millisecond)
+			assert.EqualValues(t, matched, job.Get("deleted").Int(), "%s", job.Raw)
+
+			res = send(t, "DELETE", "/identities?filter=schema_id:customer&dry_run=true", http.StatusOK, nil)
+			assert.EqualValues(t, 0, res.Get("matched").Int(), "%s", res.Raw)
+			assert.Len(t, get(t, "/identities", http.StatusOK).Array(), total+3-int(matched))
+		})
+
+		t.Run("case=should delete identities by ID", func(t *testing.T) {
+			keep := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"bulk-keep"}}`)).Get("id").String()
+			first := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"bulk-delete"}}`)).Get("id").String()
+			second := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"bulk-delete"}}`)).Get("id").String()
+
+			res := send(t, "DELETE", "/identities?dry_run=true&ids="+first+","+second+"&ids="+x.NewUUID().String(), http.StatusOK, nil)
+			assert.EqualValues(t, 2, res.Get("matched").Int(), "%s", res.Raw)
+
+			res = send(t, "DELETE", "/identities?filter=verified:false&ids="+first+","+second, http.StatusAccepted, nil)
+			id := res.Get("id").String()
+			var job gjson.Result
+			require.Eventually(t, func() bool {
+				job = get(t, "/identity-bulk-deletions/"+id, http.StatusOK)
+				return job.Get("state").String() == string(identity.BulkDeletionStateCompleted)
+			}, 5*time.Second, 10*time.Millisecond)
+			assert.EqualValues(t, 2, job.Get("deleted").Int(), "%s", job.Raw)
+
+			get(t, "/identities/"+first, http.StatusNotFound)
+			get(t, "/identities/"+second, http.StatusNotFound)
+			get(t, "/identities/"+keep, http.StatusOK)
+			remove(t, "/identities/"+keep, http.StatusNoContent)
+		})
+
+		t.Run("case=should fail jobs which stopped making progress", func(t *testing.T) {
+			job := &identity.BulkDeletion{
+				ID:     x.NewUUID(),
+				Filter: identity.Filter{SchemaID: "customer"},
+				State:  identity.BulkDeletionStateRunning,
+			}
+			require.NoError(t, reg.PrivilegedIdentityPool().CreateBulkDeletion(context.Background(), job))
+
+			res := get(t, "/identity-bulk-deletions/"+job.ID.String(), http.StatusOK)
+			assert.EqualValues(t, identity.BulkDeletionStateRunning, res.Get("state").String(), "%s", res.Raw)
+
+			clock.Advance(6 * time.Minute)
+			t.Cleanup(clock.Reset)
+
+			res = get(t, "/identity-bulk-deletions/"+job.ID.String(), http.StatusOK)
+			assert.EqualValues(t, identity.BulkDeletionStateFailed, res.Get("state").String(), "%s", res.Raw)
+			assert.NotEmpty(t, res.Get("error").String(), "%s", res.Raw)
+			assert.True(t, res.Get("completed_at").Exists(), "%s", res.Raw)
+		})
+
+		t.Run("case=should reject missing and invalid filters", func(t *testing.T) {
+			send(t, "DELETE", "/identities", http.StatusBadRequest, nil)
+			send(t, "DELETE", "/identities?ids=not-a-uuid", http.StatusBadRequest, nil)
+			send(t, "DELETE", "/identities?filter=verified:maybe", http.StatusBadRequest, nil)
+			send(t, "DELETE", "/identities?filter=schema_id", http.StatusBadRequest, nil)
+			send(t, "DELETE", "/identities?filter=unknown:foo", http.StatusBadRequest, nil)
+			send(t, "DELETE", "/identities?filter=created_before:yesterday", http.StatusBadRequest, nil)
+		})
+
+		t.Run("case=should return 404 for unknown jobs", func(t *testing.T) {
+			_ = get(t, "/identity-bulk-deletions/"+x.NewUUID().String(), http.StatusNotFound)
+		})
+	})
+
+	t.Run("suite=quarantine", func(t *testing.T) {
+		i := identity.NewIdentity("customer")
+		i.Traits = identity.Traits(`{"email":"` + x.NewUUID().String() + `@ory.sh"}`)
+		i.Quarantine("registration_velocity")
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+		t.Run("case=should list quarantined identities", func(t *testing.T) {
+			res := get(t, "/identity-quarantine", http.StatusOK)
+			assert.Len(t, res.Array(), 1, "%s", res.Raw)
+			assert.EqualValues(t, i.ID.String(), res.Get("0.id").String(), "%s", res.Raw)
+			assert.EqualValues(t, "registration_velocity", res.Get("0.quarantine_reason").String(), "%s", res.Raw)
+		})
+
+		t.Run("case=should release the identity", func(t *testing.T) {
+			remove(t, "/identity-quarantine/"+i.ID.String(), http.StatusNoContent)
+			assert.Len(t, get(t, "/identity-quarantine", http.StatusOK).Array(), 0)
+			assert.False(t, get(t, "/identities/"+i.ID.String(), http.StatusOK).Get("quarantined_at").Exists())
+		})
+
+		t.Run("case=should return 404 for identities which are not quarantined", func(t *testing.T) {
+			remove(t, "/identity-quarantine/"+i.ID.String(), http.StatusNotFound)
+			remove(t, "/identity-quarantine/"+x.NewUUID().String(), http.StatusNotFound)
+		})
+	})
+	t.Run("suite=state", func(t *testing.T) {
+		var id string
+		t.Run("case=should create an inactive identity", func(t *testing.T) {
+			res := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"baz"},"state":"inactive"}`))
+			assert.EqualValues(t, identity.StateInactive, res.Get("state").String(), "%s", res.Raw)
+			id = res.Get("id").String()
+		})
+
+		t.Run("case=should default to active", func(t *testing.T) {
+			res := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"baz"}}`))
+			assert.EqualValues(t, identity.StateActive, res.Get("state").String(), "%s", res.Raw)
+		})
+
+		t.Run("case=should activate the identity", func(t *testing.T) {
+			res := send(t, "PUT", "/identities/"+id+"/state", http.StatusOK, &identity.UpdateIdentityState{State: identity.StateActive})
+			assert.EqualValues(t, identity.StateActive, res.Get("state").String(), "%s", res.Raw)
+			assert.EqualValues(t, identity.StateActive, get(t, "/identities/"+id, http.StatusOK).Get("state").String())
+		})
+
+		t.Run("case=should reject unknown states", func(t *testing.T) {
+			send(t, "PUT", "/identities/"+id+"/state", http.StatusBadRequest, json.RawMessage(`{"state":"paused"}`))
+			send(t, "POST", "/identities", http.StatusBadRequest, json.RawMessage(`{"traits":{"bar":"baz"},"state":"paused"}`))
+		})
+
+		t.Run("case=should return 404 for unknown identities", func(t *testing.T) {
+			send(t, "PUT", "/identities/"+x.NewUUID().String()+"/state", http.StatusNotFound, &identity.UpdateIdentityState{State: identity.StateInactive})
+		})
+	})
+
+	t.Run("suite=expiry", func(t *testing.T) {
+		var id string
+		t.Run("case=should create an identity with an expiry", func(t *testing.T) {
+			res := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"baz"},"expires_at":"2030-01-01T00:00:00Z"}`))
+			assert.EqualValues(t, "2030-01-01T00:00:00Z", res.Get("expires_at").String(), "%s", res.Raw)
+			id = res.Get("id").String()
+		})
+
+		t.Run("case=should update the expiry", func(t *testing.T) {
+			res := send(t, "PUT", "/identities/"+id+"/expiry", http.StatusOK, json.RawMessage(`{"expires_at":"2020-01-01T01:00:00+01:00"}`))
+			assert.EqualValues(t, "2020-01-01T00:00:00Z", res.Get("expires_at").String(), "%s", res.Raw)
+			assert.EqualValues(t, "2020-01-01T00:00:00Z", get(t, "/identities/"+id, http.StatusOK).Get("expires_at").String())
+		})
+
+		t.Run("case=should remove the expiry", func(t *testing.T) {
+			res := send(t, "PUT", "/identities/"+id+"/expiry", http.StatusOK, json.RawMessage(`{"expires_at":null}`))
+			assert.False(t, res.Get("expires_at").Exists(), "%s", res.Raw)
+		})
+
+		t.Run("case=should return 404 for unknown identities", func(t *testing.T) {
+			send(t, "PUT", "/identities/"+x.NewUUID().String()+"/expiry", http.StatusNotFound, json.RawMessage(`{"expires_at":null}`))
+		})
+	})
+
+	t.Run("suite=soft delete", func(t *testing.T) {
+		id := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"baz"}}`)).Get("id").String()
+
+		t.Run("case=should soft-delete the identity", func(t *testing.T) {
+			remove(t, "/identities/"+id+"?soft=true", http.StatusNoContent)
+			get(t, "/identities/"+id, http.StatusNotFound)
+			remove(t, "/identities/"+id+"?soft=true", http.StatusNotFound)
+		})
+
+		t.Run("case=should restore the identity", func(t *testing.T) {
+			res := send(t, "POST", "/identities/"+id+"/restore", http.StatusOK, json.RawMessage(`{}`))
+			assert.EqualValues(t, id, res.Get("id").String(), "%s", res.Raw)
+			get(t, "/identities/"+id, http.StatusOK)
+			send(t, "POST", "/identities/"+id+"/restore", http.StatusNotFound, json.RawMessage(`{}`))
+		})
+
+		t.Run("case=should permanently delete a soft-deleted identity", func(t *testing.T) {
+			remove(t, "/identities/"+id+"?soft=true", http.StatusNoContent)
+			remove(t, "/identities/"+id, http.StatusNoContent)
+			send(t, "POST", "/identities/"+id+"/restore", http.StatusNotFound, json.RawMessage(`{}`))
+		})
+	})
+
+	t.Run("suite=patch", func(t *testing.T) {
+		id := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"patch"},"metadata_admin":{"tier":"free"}}`)).Get("id").String()
+
+		t.Run("case=should patch traits and metadata", func(t *testing.T) {
+			res := send(t, "PATCH", "/identities/"+id, http.StatusOK, json.RawMessage(`[
+	{"op":"test","path":"/traits/bar","value":"patch"},
+	{"op":"replace","path":"/traits/bar","value":"patched"},
+	{"op":"add","path":"/metadata_public","value":{"groups":["beta"]}},
+	{"op":"replace","path":"/metadata_admin/tier","value":"pro"}
+]`))
+			assert.EqualValues(t, "patched", res.Get("traits.bar").String(), "%s", res.Raw)
+			assert.JSONEq(t, `{"groups":["beta"]}`, res.Get("metadata_public").Raw, "%s", res.Raw)
+			assert.JSONEq(t, `{"tier":"pro"}`, res.Get("metadata_admin").Raw, "%s", res.Raw)
+
+			res = get(t, "/identities/"+id, http.StatusOK)
+			assert.EqualValues(t, "patched", res.Get("traits.bar").String(), "%s", res.Raw)
+			assert.JSONEq(t, `{"groups":["beta"]}`, res.Get("metadata_public").Raw, "%s", res.Raw)
+		})
+
+		t.Run("case=should remove metadata", func(t *testing.T) {
+			res := send(t, "PATCH", "/identities/"+id, http.StatusOK, json.RawMessage(`[{"op":"remove","path":"/metadata_public"}]`))
+			assert.False(t, res.Get("metadata_public").Exists(), "%s", res.Raw)
+			assert.JSONEq(t, `{"tier":"pro"}`, res.Get("metadata_admin").Raw, "%s", res.Raw)
+		})
+
+		t.Run("case=should reject invalid patches", func(t *testing.T) {
+			send(t, "PATCH", "/identities/"+id, http.StatusBadRequest, json.RawMessage(`[]`))
+			send(t, "PATCH", "/identities/"+id, http.StatusBadRequest, json.RawMessage(`[{"op":"replace","path":"/schema_id","value":"customer"}]`))
+			send(t, "PATCH", "/identities/"+id, http.StatusBadRequest, json.RawMessage(`[{"op":"move","from":"/credentials","path":"/traits/credentials"}]`))
+			send(t, "PATCH", "/identities/"+id, http.StatusBadRequest, json.RawMessage(`[{"op":"replace","path":"/traits/unknown","value":"foo"}]`))
+			send(t, "PATCH", "/identities/"+id, http.StatusBadRequest, json.RawMessage(`[{"op":"replace","path":"/traits","value":"foo"}]`))
+			send(t, "PATCH", "/identities/"+id, http.StatusBadRequest, json.RawMessage(`[{"op":"replace","path":"/traits/bar","value":123}]`))
+			send(t, "PATCH", "/identities/"+x.NewUUID().String(), http.StatusNotFound, json.RawMessage(`[{"op":"replace","path":"/traits/bar","value":"foo"}]`))
+		})
+
+		t.Run("case=should reject patches whose tests fail", func(t *testing.T) {
+			send(t, "PATCH", "/identities/"+id, http.StatusConflict, json.RawMessage(`[
+	{"op":"test","path":"/traits/bar","value":"patch"},
+	{"op":"replace","path":"/traits/bar","value":"lost update"}
+]`))
+			assert.EqualValues(t, "patched", get(t, "/identities/"+id, http.StatusOK).Get("traits.bar").String())
+		})
+
+		t.Run("case=should not lose concurrent patches", func(t *testing.T) {
+			// Each writer adds another key. A writer whose update conflicts with another one applies its patch
+			// again to the changed identity, so every successful patch must be visible in the end.
+			const writers = 8
+			var wg sync.WaitGroup
+			statuses := make([]int, writers)
+			for k := 0; k < writers; k++ {
+				wg.Add(1)
+				go func(k int) {
+					defer wg.Done()
+					req, err := http.NewRequest("PATCH", ts.URL+"/identities/"+id,
+						strings.NewReader(fmt.Sprintf(`[{"op":"add","path":"/metadata_admin/writer-%d","value":true}]`, k)))
+					if err != nil {
+						return
+					}
+					req.Header.Set("Content-Type", "application/json")
+					res, err := ts.Client().Do(req)
+					if err != nil {
+						return
+					}
+					_ = res.Body.Close()
+					statuses[k] = res.StatusCode
+				}(k)
+			}
+			wg.Wait()
+
+			metadata := get(t, "/identities/"+id, http.StatusOK).Get("metadata_admin")
+			var succeeded int
+			for k, status := range statuses {
+				switch status {
+				case http.StatusOK:
+					succeeded++
+					assert.True(t, metadata.Get(fmt.Sprintf("writer-%d", k)).Bool(), "%d: %s", k, metadata.Raw)
+				case http.StatusConflict:
+					assert.False(t, metadata.Get(fmt.Sprintf("writer-%d", k)).Exists(), "%d: %s", k, metadata.Raw)
+				default:
+					t.Errorf("writer %d received an unexpected status code %d", k, status)
+				}
+			}
+			assert.NotZero(t, succeeded)
+		})
+	})
+
+	t.Run("suite=versions", func(t *testing.T) {
+		var do = func(t *testing.T, method, href, ifMatch string, expectCode int, body string) (string, gjson.Result) {
+			req, err := http.NewRequest(method, ts.URL+href, strings.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			if ifMatch != "" {
+				req.Header.Set("If-Match", ifMatch)
+			}
+			res, err := ts.Client().Do(req)
+			require.NoError(t, err)
+			out, err := ioutil.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+
+			require.EqualValues(t, expectCode, res.StatusCode, "%s", out)
+			return res.Header.Get("ETag"), gjson.ParseBytes(out)
+		}
+
+		created, res := do(t, "POST", "/identities", "", http.StatusCreated, `{"traits":{"bar":"version-1"}}`)
+		require.NotEmpty(t, created)
+		id := res.Get("id").String()
+
+		t.Run("case=should return the same version until the identity changes", func(t *testing.T) {
+			actual, _ := do(t, "GET", "/identities/"+id, "", http.StatusOK, "")
+			assert.Equal(t, created, actual)
+		})
+
+		t.Run("case=should only update the expected version", func(t *testing.T) {
+			updated, res := do(t, "PUT", "/identities/"+id, created, http.StatusOK, `{"traits":{"bar":"version-2"}}`)
+			assert.NotEqual(t, created, updated)
+			assert.EqualValues(t, "version-2", res.Get("traits.bar").String(), "%s", res.Raw)
+			actual, _ := do(t, "GET", "/identities/"+id, "", http.StatusOK, "")
+			assert.Equal(t, updated, actual)
+
+			do(t, "PUT", "/identities/"+id, created, http.StatusPreconditionFailed, `{"traits":{"bar":"lost update"}}`)
+			do(t, "PATCH", "/identities/"+id, created, http.StatusPreconditionFailed, `[{"op":"replace","path":"/traits/bar","value":"lost update"}]`)
+			assert.EqualValues(t, "version-2", get(t, "/identities/"+id, http.StatusOK).Get("traits.bar").String())
+
+			patched, _ := do(t, "PATCH", "/identities/"+id, created+", "+updated, http.StatusOK, `[{"op":"replace","path":"/traits/bar","value":"version-3"}]`)
+			assert.NotEqual(t, updated, patched)
+			do(t, "PUT", "/identities/"+id, "*", http.StatusOK, `{"traits":{"bar":"version-4"}}`)
+			assert.EqualValues(t, "version-4", get(t, "/identities/"+id, http.StatusOK).Get("traits.bar").String())
+		})
+	})
+
+	t.Run("suite=credentials", func(t *testing.T) {
+		i := identity.NewIdentity("")
+		i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+			Identifiers: []string{"credentials-" + x.NewUUID().String() + "@ory.sh"}, Config: sqlxx.JSONRawMessage(`{"hashed_password":"secret"}`)})
+		i.SetCredentials(identity.CredentialsTypeOIDC, identity.Credentials{
+			Identifiers: []string{"google:credentials"}, Config: sqlxx.JSONRawMessage(`{"providers":[{"provider":"google","subject":"credentials"}]}`)})
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+		id := i.ID.String()
+
+		t.Run("case=should list the credentials without secrets", func(t *testing.T) {
+			res := get(t, "/identities/"+id+"/credentials", http.StatusOK)
+			assert.EqualValues(t, []interface{}{"oidc", "password"}, res.Get("#.type").Value(), "%s", res.Raw)
+			assert.NotContains(t, res.Raw, "secret")
+			assert.NotContains(t, res.Raw, "config")
+
+			res = get(t, "/identities/"+id+"/credentials/oidc", http.StatusOK)
+			assert.EqualValues(t, "google", res.Get("providers.0.provider").String(), "%s", res.Raw)
+			assert.EqualValues(t, "credentials", res.Get("providers.0.subject").String(), "%s", res.Raw)
+
+			get(t, "/identities/"+id+"/credentials/ldap", http.StatusNotFound)
+			get(t, "/identities/"+x.NewUUID().String()+"/credentials", http.StatusNotFound)
+		})
+
+		t.Run("case=should delete the credentials", func(t *testing.T) {
+			remove(t, "/identities/"+id+"/credentials/oidc", http.StatusNoContent)
+			remove(t, "/identities/"+id+"/credentials/oidc", http.StatusNotFound)
+			get(t, "/identities/"+id+"/credentials/oidc", http.StatusNotFound)
+
+			res := get(t, "/identities/"+id+"/credentials", http.StatusOK)
+			assert.EqualValues(t, []interface{}{"password"}, res.Get("#.type").Value(), "%s", res.Raw)
+		})
+	})
+
+	t.Run("suite=legal hold", func(t *testing.T) {
+		id := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"baz"}}`)).Get("id").String()
+
+		t.Run("case=should place the identity on legal hold", func(t *testing.T) {
+			send(t, "PUT", "/identities/"+id+"/legal-hold", http.StatusNoContent, &identity.PlaceIdentityOnLegalHold{Reason: "case 42"})
+			res := get(t, "/identities/"+id, http.StatusOK)
+			assert.EqualValues(t, "case 42", res.Get("legal_hold_reason").String(), "%s", res.Raw)
+			assert.True(t, res.Get("legal_hold_at").Exists(), "%s", res.Raw)
+		})
+
+		t.Run("case=should not expose the legal hold outside of the admin API", func(t *testing.T) {
+			i, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), x.ParseUUID(id))
+			require.NoError(t, err)
+			require.True(t, i.IsUnderLegalHold())
+			raw, err := json.Marshal(i)
+			require.NoError(t, err)
+			assert.NotContains(t, string(raw), "legal_hold")
+		})
+
+		t.Run("case=should block deletions", func(t *testing.T) {
+			remove(t, "/identities/"+id, http.StatusConflict)
+			remove(t, "/identities/"+id+"?soft=true", http.StatusConflict)
+
+			primary := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"primary"}}`)).Get("id").String()
+			send(t, "POST", "/identities/"+primary+"/merge", http.StatusConflict, &identity.MergeIdentities{DuplicateID: x.ParseUUID(id)})
+			get(t, "/identities/"+id, http.StatusOK)
+		})
+
+		t.Run("case=should reject legal holds without a reason", func(t *testing.T) {
+			send(t, "PUT", "/identities/"+id+"/legal-hold", http.StatusBadRequest, json.RawMessage(`{"reason":" "}`))
+			send(t, "PUT", "/identities/"+x.NewUUID().String()+"/legal-hold", http.StatusNotFound, &identity.PlaceIdentityOnLegalHold{Reason: "case 42"})
+		})
+
+		t.Run("case=should release the legal hold", func(t *testing.T) {
+			remove(t, "/identities/"+id+"/legal-hold", http.StatusNoContent)
+			remove(t, "/identities/"+id+"/legal-hold", http.StatusNotFound)
+			assert.False(t, get(t, "/identities/"+id, http.StatusOK).Get("legal_hold_at").Exists())
+			remove(t, "/identities/"+id, http.StatusNoContent)
+		})
+	})
+
+	t.Run("suite=labels", func(t *testing.T) {
+		beta := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"beta"}}`)).Get("id").String()
+		other := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"other"}}`)).Get("id").String()
+
+		t.Run("case=should set labels", func(t *testing.T) {
+			send(t, "PUT", "/identities/"+beta+"/labels/cohort", http.StatusNoContent, &identity.SetIdentityLabel{Value: "beta"})
+			send(t, "PUT", "/identities/"+beta+"/labels/migrated-from", http.StatusNoContent, &identity.SetIdentityLabel{Value: "legacy"})
+			send(t, "PUT", "/identities/"+other+"/labels/cohort", http.StatusNoContent, &identity.SetIdentityLabel{Value: "alpha"})
+
+			res := get(t, "/identities/"+beta, http.StatusOK)
+			assert.JSONEq(t, `{"cohort":"beta","migrated-from":"legacy"}`, res.Get("labels").Raw, "%s", res.Raw)
+		})
+
+		t.Run("case=should reject invalid labels", func(t *testing.T) {
+			send(t, "PUT", "/identities/"+beta+"/labels/"+strings.Repeat("a", 65), http.StatusBadRequest, &identity.SetIdentityLabel{Value: "beta"})
+			send(t, "PUT", "/identities/"+beta+"/labels/cohort", http.StatusBadRequest, &identity.SetIdentityLabel{Value: strings.Repeat("a", 256)})
+			send(t, "PUT", "/identities/"+x.NewUUID().String()+"/labels/cohort", http.StatusNotFound, &identity.SetIdentityLabel{Value: "beta"})
+			get(t, "/identities?labels.co%20hort=beta", http.StatusBadRequest)
+		})
+
+		t.Run("case=should filter by labels", func(t *testing.T) {
+			res := get(t, "/identities?labels.cohort=beta", http.StatusOK)
+			require.Len(t, res.Array(), 1, "%s", res.Raw)
+			assert.EqualValues(t, beta, res.Get("0.id").String(), "%s", res.Raw)
+
+			res = get(t, "/identities?labels.cohort=alpha&labels.migrated-from=legacy", http.StatusOK)
+			assert.Len(t, res.Array(), 0, "%s", res.Raw)
+		})
+
+		t.Run("case=should not expose labels outside of the admin API", func(t *testing.T) {
+			i, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), x.ParseUUID(beta))
+			require.NoError(t, err)
+			require.Len(t, i.Labels, 2)
+			raw, err := json.Marshal(i)
+			require.NoError(t, err)
+			assert.NotContains(t, string(raw), "labels")
+		})
+
+		t.Run("case=should remove labels", func(t *testing.T) {
+			remove(t, "/identities/"+beta+"/labels/cohort", http.StatusNoContent)
+			remove(t, "/identities/"+beta+"/labels/cohort", http.StatusNotFound)
+			assert.Len(t, get(t, "/identities?labels.cohort=beta", http.StatusOK).Array(), 0)
+		})
+	})
+
+	t.Run("suite=merge", func(t *testing.T) {
+		primary := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"primary"}}`)).Get("id").String()
+		duplicate := send(t, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits":{"bar":"duplicate"}}`)).Get("id").String()
+
+		i, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), x.ParseUUID(duplicate))
+		require.NoError(t, err)
+		sess := session.NewActiveSession(i, conf, time.Now())
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), sess))
+
+		t.Run("case=should reject invalid requests", func(t *testing.T) {
+			send(t, "POST", "/identities/"+primary+"/merge", http.StatusBadRequest, json.RawMessage(`{}`))
+			send(t, "POST", "/identities/"+primary+"/merge", http.StatusBadRequest, json.RawMessage(`{"duplicate_id":"`+primary+`"}`))
+			send(t, "POST", "/identities/"+primary+"/merge", http.StatusBadRequest, json.RawMessage(`{"duplicate_id":"`+duplicate+`","unknown":true}`))
+			send(t, "POST", "/identities/"+primary+"/merge", http.StatusNotFound, json.RawMessage(`{"duplicate_id":"`+x.NewUUID().String()+`"}`))
+			send(t, "POST", "/identities/"+x.NewUUID().String()+"/merge", http.StatusNotFound, json.RawMessage(`{"duplicate_id":"`+duplicate+`"}`))
+		})
+
+		t.Run("case=should merge the duplicate into the primary", func(t *testing.T) {
+			res := send(t, "POST", "/identities/"+primary+"/merge", http.StatusOK, json.RawMessage(`{"duplicate_id":"`+duplicate+`"}`))
+			assert.EqualValues(t, primary, res.Get("primary").String(), "%s", res.Raw)
+			assert.EqualValues(t, duplicate, res.Get("duplicate").String(), "%s", res.Raw)
+			assert.EqualValues(t, 1, res.Get("sessions").Int(), "%s", res.Raw)
+
+			get(t, "/identities/"+duplicate, http.StatusNotFound)
+			assert.EqualValues(t, "primary", get(t, "/identities/"+primary, http.StatusOK).Get("traits.bar").String())
+
+			actual, err := reg.SessionPersister().GetSessionByToken(context.Background(), sess.Token)
+			require.NoError(t, err)
+			assert.EqualValues(t, primary, actual.IdentityID.String())
+
+			send(t, "POST", "/identities/"+primary+"/merge", http.StatusNotFound, json.RawMessage(`{"duplicate_id":"`+duplicate+`"}`))
+		})
+	})
+
+	t.Run("suite=import", func(t *testing.T) {
+		importIdentities := func(t *testing.T, query, contentType, body string, expectCode int) gjson.Result {
+			res, err := ts.Client().Post(ts.URL+"/identity-import"+query, contentType, strings.NewReader(body))
+			require.NoError(t, err)
+			actual, err := ioutil.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+
+			require.EqualValues(t, expectCode, res.StatusCode, "%s", actual)
+			return gjson.ParseBytes(actual)
+		}
+
+		t.Run("case=should import a JSON array with per-record results", func(t *testing.T) {
+			res := importIdentities(t, "", "application/json", `[
+	{"schema_id":"import","traits":{"email":"import-1@ory.sh"},"credentials":{"password":{"password":"5YHMU2cnnEuJyhZ7"}},"verified_addresses":["import-1@ory.sh"]},
+	{"schema_id":"import","traits":{"email":"not-an-email"}},
+	{"schema_id":"import","traits":{"email":"import-1@ory.sh"}},
+	{"schema_id":"import","traits":{"email":"import-2@ory.sh"},"state":"inactive","metadata_admin":{"source":"legacy"}}
+]`, http.StatusOK)
+
+			assert.EqualValues(t, 2, res.Get("imported").Int(), "%s", res.Raw)
+			assert.EqualValues(t, 2, res.Get("failed").Int(), "%s", res.Raw)
+			for k, code := range []int64{0, http.StatusBadRequest, http.StatusConflict, 0} {
+				assert.EqualValues(t, k, res.Get(fmt.Sprintf("results.%d.index", k)).Int(), "%s", res.Raw)
+				assert.EqualValues(t, code, res.Get(fmt.Sprintf("results.%d.error.code", k)).Int(), "%s", res.Raw)
+			}
+
+			i, c, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(context.Background(), identity.CredentialsTypePassword, "import-1@ory.sh")
+			require.NoError(t, err)
+			assert.EqualValues(t, res.Get("results.0.id").String(), i.ID.String())
+			require.NoError(t, reg.Hasher().Compare([]byte("5YHMU2cnnEuJyhZ7"), []byte(gjson.GetBytes(c.Config, "hashed_password").String())))
+			require.Len(t, i.VerifiableAddresses, 1)
+			assert.True(t, i.VerifiableAddresses[0].Verified)
+			assert.EqualValues(t, identity.VerifiableAddressStatusCompleted, i.VerifiableAddresses[0].Status)
+
+			imported := get(t, "/identities/"+res.Get("results.3.id").String(), http.StatusOK)
+			assert.EqualValues(t, identity.StateInactive, imported.Get("state").String(), "%s", imported.Raw)
+			assert.EqualValues(t, "legacy", imported.Get("metadata_admin.source").String(), "%s", imported.Raw)
+		})
+
+		t.Run("case=should import newline delimited JSON in several batches", func(t *testing.T) {
+			hashed, err := reg.Hasher().Generate([]byte("Jq9UHyu7kd8NBG4C"))
+			require.NoError(t, err)
+
+			res := importIdentities(t, "?batch_size=2", "application/x-ndjson", fmt.Sprintf(`{"schema_id":"import","traits":{"email":"import-3@ory.sh"},"credentials":{"password":{"hashed_password":%q}}}
+{"schema_id":"import","traits":{"email":"import-4@ory.sh"},"credentials":{"oidc":{"providers":[{"provider":"google","subject":"import-4"}]}}}
+{"schema_id":"import","traits":{"email":"import-5@ory.sh"},"verified_addresses":["import-6@ory.sh"]}
+`, hashed), http.StatusOK)
+
+			assert.EqualValues(t, 2, res.Get("imported").Int(), "%s", res.Raw)
+			assert.EqualValues(t, 1, res.Get("failed").Int(), "%s", res.Raw)
+			assert.Contains(t, res.Get("results.2.error.reason").String(), "import-6@ory.sh", "%s", res.Raw)
+
+			_, c, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(context.Background(), identity.CredentialsTypePassword, "import-3@ory.sh")
+			require.NoError(t, err)
+			assert.EqualValues(t, hashed, gjson.GetBytes(c.Config, "hashed_password").String())
+
+			i, _, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(context.Background(), identity.CredentialsTypeOIDC, "google:import-4")
+			require.NoError(t, err)
+			assert.EqualValues(t, res.Get("results.1.id").String(), i.ID.String())
+		})
+
+		t.Run("case=should reject passwords without identifiers", func(t *testing.T) {
+			res := importIdentities(t, "", "application/json", `[{"traits":{"bar":"baz"},"credentials":{"password":{"password":"5YHMU2cnnEuJyhZ7"}}}]`, http.StatusOK)
+			assert.EqualValues(t, 1, res.Get("failed").Int(), "%s", res.Raw)
+			assert.EqualValues(t, http.StatusBadRequest, res.Get("results.0.error.code").Int(), "%s", res.Raw)
+		})
+
+		t.Run("case=should reject invalid batch sizes", func(t *testing.T) {
+			send(t, "POST", "/identity-import?batch_size=0", http.StatusBadRequest, json.RawMessage(`[]`))
+		})
+	})
 }
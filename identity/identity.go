@@ -49,6 +49,18 @@ type (
 		// required: true
 		SchemaURL string `json:"schema_url" faker:"-" db:"-"`
 
+		// SchemaVersion is the version of the schema the traits conform to. Traits are migrated to the latest
+		// version of the schema when the identity is loaded or saved.
+		SchemaVersion int `json:"schema_version" faker:"-" db:"schema_version"`
+
+		// State is either `active` or `inactive`. Inactive identities can not sign in and their sessions are
+		// rejected.
+		State State `json:"state" faker:"-" db:"state"`
+
+		// ExpiresAt is set for temporary identities, for example those of contractors or trial accounts. Once it
+		// has passed, the identity can no longer sign in and its sessions are revoked.
+		ExpiresAt *time.Time `json:"expires_at,omitempty" faker:"-" db:"expires_at"`
+
 		// Traits represent an identity's traits. The identity is able to create, modify, and delete traits
 		// in a self-service manner. The input will always be validated against the JSON Schema defined
 		// in `schema_url`.
@@ -72,6 +84,42 @@ type (
 		// ---
 		RecoveryAddresses []RecoveryAddress `json:"recovery_addresses,omitempty" faker:"-" has_many:"identity_recovery_addresses" fk_id:"identity_id"`
 
+		// MetadataPublic contains data about the identity which can be read by the identity itself and by
+		// downstream applications, for example the groups the identity is a member of at its OpenID Connect
+		// provider. It can not be changed by the identity.
+		MetadataPublic Metadata `json:"metadata_public,omitempty" faker:"-" db:"metadata_public"`
+
+		// MetadataAdmin contains data about the identity which is only visible to administrators, for example
+		// the customer ID at a billing provider. It is omitted from all responses except those of the admin API.
+		MetadataAdmin Metadata `json:"metadata_admin,omitempty" faker:"-" db:"metadata_admin"`
+
+		// QuarantinedAt is set if the identity was quarantined because it was created under suspicious
+		// conditions. A quarantined identity can not change its settings until one of its addresses has been
+		// verified or an administrator released it from quarantine.
+		QuarantinedAt *time.Time `json:"quarantined_at,omitempty" faker:"-" db:"quarantined_at"`
+
+		// QuarantineReason explains why the identity was quarantined.
+		QuarantineReason string `json:"quarantine_reason,omitempty" faker:"-" db:"quarantine_reason"`
+
+		// NotificationPreferences are the channels on which the identity agreed to receive messages which are not
+		// required for the security of its account. If unset, all channels are allowed.
+		NotificationPreferences *NotificationPreferences `json:"notification_preferences,omitempty" faker:"-" db:"notification_preferences"`
+
+		// LegalHoldAt is set if the identity is under legal hold, for example during litigation. It is only visible
+		// to administrators.
+		LegalHoldAt *time.Time `json:"legal_hold_at,omitempty" faker:"-" db:"legal_hold_at"`
+
+		// LegalHoldReason explains why the identity is under legal hold. It is only visible to administrators.
+		LegalHoldReason string `json:"legal_hold_reason,omitempty" faker:"-" db:"legal_hold_reason"`
+
+		// Labels are key/value pairs attached to the identity by administrators, for example to mark cohorts such as
+		// beta testers. Identities can be filtered by their labels. They are only visible to administrators.
+		Labels Labels `json:"labels,omitempty" faker:"-" has_many:"identity_labels" fk_id:"identity_id" order_by:"name asc"`
+
+		// DeletedAt is set if the identity was soft-deleted. Soft-deleted identities are hidden and can be restored
+		// until they are purged.
+		DeletedAt *time.Time `json:"-" faker:"-" db:"deleted_at"`
+
 		// CredentialsCollection is a helper struct field for gobuffalo.pop.
 		CredentialsCollection CredentialsCollection `json:"-" faker:"-" has_many:"identity_credentials" fk_id:"identity_id"`
 
@@ -82,8 +130,30 @@ type (
 		UpdatedAt time.Time `json:"-" db:"updated_at"`
 	}
 	Traits json.RawMessage
+
+	// Metadata is an optional JSON object stored in a nullable column.
+	Metadata json.RawMessage
+
+	// WithAdminMetadataInJSON encodes an identity including its admin metadata. It is used by the admin API.
+	WithAdminMetadataInJSON Identity
 )
 
+// MarshalJSON omits the admin metadata, the legal hold, and the labels as they must never be exposed to the
+// identity itself.
+func (i Identity) MarshalJSON() ([]byte, error) {
+	type localIdentity Identity
+	i.MetadataAdmin = nil
+	i.Labels = nil
+	i.LegalHoldAt = nil
+	i.LegalHoldReason = ""
+	return json.Marshal(localIdentity(i))
+}
+
+func (i WithAdminMetadataInJSON) MarshalJSON() ([]byte, error) {
+	type localIdentity Identity
+	return json.Marshal(localIdentity(i))
+}
+
 func (t *Traits) Scan(value interface{}) error {
 	return sqlxx.JSONScan(t, value)
 }
@@ -113,6 +183,52 @@ func (t *Traits) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+func (m *Metadata) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+	case []byte:
+		*m = append((*m)[0:0], v...)
+	case string:
+		*m = Metadata(v)
+	default:
+		return errors.Errorf("unable to scan metadata of type %T", value)
+	}
+
+	if string(*m) == "null" {
+		*m = nil
+	}
+	return nil
+}
+
+func (m Metadata) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return string(m), nil
+}
+
+// MarshalJSON returns m as the JSON encoding of m.
+func (m Metadata) MarshalJSON() ([]byte, error) {
+	if len(m) == 0 {
+		return []byte("null"), nil
+	}
+	return m, nil
+}
+
+// UnmarshalJSON sets *m to a copy of data.
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	if m == nil {
+		return errors.New("json.RawMessage: UnmarshalJSON on nil pointer")
+	}
+	if string(data) == "null" {
+		*m = nil
+		return nil
+	}
+	*m = append((*m)[0:0], data...)
+	return nil
+}
+
 func (i Identity) TableName() string {
 	return "identities"
 }
@@ -182,6 +298,7 @@ func NewIdentity(traitsSchemaID string) *Identity {
 		Credentials:         map[CredentialsType]Credentials{},
 		Traits:              Traits("{}"),
 		SchemaID:            traitsSchemaID,
+		State:               StateActive,
 		VerifiableAddresses: []VerifiableAddress{},
 		l:                   new(sync.RWMutex),
 	}
@@ -11,8 +11,14 @@ import (
 const (
 	VerifiableAddressTypeEmail VerifiableAddressType = AddressTypeEmail
 
+	// VerifiableAddressTypeSMS marks phone numbers which are verified with codes sent by text message.
+	VerifiableAddressTypeSMS VerifiableAddressType = "sms"
+
 	VerifiableAddressStatusPending   VerifiableAddressStatus = "pending"
 	VerifiableAddressStatusCompleted VerifiableAddressStatus = "completed"
+
+	// VerifiableAddressStatusUndeliverable marks pending addresses which can not receive mail.
+	VerifiableAddressStatusUndeliverable VerifiableAddressStatus = "undeliverable"
 )
 
 type (
@@ -54,6 +60,8 @@ func (v VerifiableAddressType) HTMLFormInputType() string {
 	switch v {
 	case VerifiableAddressTypeEmail:
 		return "email"
+	case VerifiableAddressTypeSMS:
+		return "tel"
 	}
 	return ""
 }
@@ -74,3 +82,17 @@ func NewVerifiableEmailAddress(
 		IdentityID: identity,
 	}
 }
+
+// NewVerifiableSMSAddress returns a pending address for a phone number in E.164 format.
+func NewVerifiableSMSAddress(
+	value string,
+	identity uuid.UUID,
+) *VerifiableAddress {
+	return &VerifiableAddress{
+		Value:      value,
+		Verified:   false,
+		Status:     VerifiableAddressStatusPending,
+		Via:        VerifiableAddressTypeSMS,
+		IdentityID: identity,
+	}
+}
@@ -0,0 +1,417 @@
+package identity
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/x/errorsx"
+	"github.com/ory/x/jsonx"
+	"github.com/ory/x/sqlxx"
+)
+
+const (
+	RouteImport = "/identity-import"
+
+	importDefaultBatchSize = 100
+	importMaxBatchSize     = 1000
+)
+
+type (
+	// ImportIdentity is a single identity to be imported.
+	ImportIdentity struct {
+		// SchemaID is the ID of the JSON Schema to be used for validating the identity's traits.
+		SchemaID string `json:"schema_id"`
+
+		// Traits represent an identity's traits and are validated against the identity's JSON Schema.
+		//
+		// required: true
+		Traits json.RawMessage `json:"traits"`
+
+		// MetadataPublic is visible to the identity but can not be changed by it. It must be an object if set.
+		MetadataPublic Metadata `json:"metadata_public"`
+
+		// MetadataAdmin is only visible to administrators. It must be an object if set.
+		MetadataAdmin Metadata `json:"metadata_admin"`
+
+		// State is either `active` or `inactive` and defaults to `active`.
+		State State `json:"state"`
+
+		// ExpiresAt is the time after which the identity can no longer sign in.
+		ExpiresAt *time.Time `json:"expires_at"`
+
+		// Credentials are the identity's credentials.
+		Credentials *ImportCredentials `json:"credentials"`
+
+		// VerifiedAddresses lists the identity's verifiable addresses which are verified already.
+		VerifiedAddresses []string `json:"verified_addresses"`
+	}
+
+	// ImportCredentials are the credentials of an imported identity.
+	ImportCredentials struct {
+		// Password sets the identity's password.
+		Password *ImportPasswordCredentials `json:"password"`
+
+		// OIDC links the identity to accounts of OpenID Connect providers.
+		OIDC *ImportOIDCCredentials `json:"oidc"`
+	}
+
+	// ImportPasswordCredentials set the password of an imported identity. Either the password or its hash must
+	// be set.
+	ImportPasswordCredentials struct {
+		// HashedPassword is the password hashed by ORY Kratos, for example when migrating from another instance.
+		HashedPassword string `json:"hashed_password"`
+
+		// Password is the password in clear text. It is hashed before it is stored.
+		Password string `json:"password"`
+	}
+
+	// ImportOIDCCredentials link an imported identity to accounts of OpenID Connect providers.
+	ImportOIDCCredentials struct {
+		// Providers lists the linked accounts.
+		Providers []ImportOIDCProvider `json:"providers"`
+	}
+
+	// ImportOIDCProvider is an account of an OpenID Connect provider.
+	ImportOIDCProvider struct {
+		// Provider is the ID of the provider as configured in ORY Kratos.
+		Provider string `json:"provider"`
+
+		// Subject is the subject of the account at the provider.
+		Subject string `json:"subject"`
+	}
+
+	// ImportResult is the result of an identity import.
+	//
+	// swagger:model identityImportResult
+	ImportResult struct {
+		// Imported is the number of identities which were imported.
+		Imported int `json:"imported"`
+
+		// Failed is the number of identities which could not be imported.
+		Failed int `json:"failed"`
+
+		// Results contains the result of each identity in the order of the request.
+		Results []ImportRecordResult `json:"results"`
+	}
+
+	// ImportRecordResult is the result of importing a single identity.
+	ImportRecordResult struct {
+		// Index is the position of the identity in the request, starting at zero.
+		Index int `json:"index"`
+
+		// ID is the ID of the imported identity. It is not set if the import failed.
+		ID *uuid.UUID `json:"id,omitempty"`
+
+		// Error is set if the import failed.
+		Error *herodot.DefaultError `json:"error,omitempty"`
+	}
+
+	// importReader reads identities from a JSON array or from newline delimited JSON.
+	importReader struct {
+		dec *json.Decoder
+	}
+)
+
+func newImportReader(r io.Reader) (*importReader, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.Peek(1)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if unicode.IsSpace(rune(b[0])) {
+			_, _ = br.ReadByte()
+			continue
+		}
+
+		dec := json.NewDecoder(br)
+		if b[0] == '[' {
+			if _, err := dec.Token(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		return &importReader{dec: dec}, nil
+	}
+
+	return &importReader{dec: json.NewDecoder(br)}, nil
+}
+
+// Next returns the next identity or io.EOF if all identities were read.
+func (r *importReader) Next() (json.RawMessage, error) {
+	if !r.dec.More() {
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := r.dec.Decode(&raw); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return raw, nil
+}
+
+func (r *ImportResult) succeeded(index int, id uuid.UUID) {
+	r.Imported++
+	r.Results = append(r.Results, ImportRecordResult{Index: index, ID: &id})
+}
+
+func (r *ImportResult) failed(index int, err error) {
+	r.Failed++
+	r.Results = append(r.Results, ImportRecordResult{Index: index, Error: herodot.ToDefaultError(err, "")})
+}
+
+// swagger:parameters importIdentities
+// nolint:deadcode,unused
+type importIdentitiesParameters struct {
+	// BatchSize is the number of identities stored in one transaction.
+	//
+	// required: false
+	// in: query
+	// default: 100
+	// min: 1
+	// max: 1000
+	BatchSize int `json:"batch_size"`
+
+	// in: body
+	// type: array
+	Body []ImportIdentity
+}
+
+// A result of an identity import.
+//
+// swagger:response identityImportResult
+// nolint:deadcode,unused
+type importIdentitiesResponse struct {
+	// in: body
+	Body ImportResult
+}
+
+// swagger:route POST /identity-import admin importIdentities
+//
+// Import Identities
+//
+// This endpoint imports many identities at once, including their credentials and verified addresses. The body is
+// either a JSON array of identities or newline delimited JSON (`application/x-ndjson`) with one identity per line.
+//
+// Identities are stored in transactions of `batch_size` identities. An identity which can not be imported does
+// not prevent the others from being imported. The response contains the ID or the error of each identity.
+//
+//     Consumes:
+//     - application/json
+//     - application/x-ndjson
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: identityImportResult
+//       400: genericError
+//       500: genericError
+func (h *Handler) importIdentities(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	batchSize := importDefaultBatchSize
+	if raw := r.URL.Query().Get("batch_size"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < 1 || size > importMaxBatchSize {
+			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.
+				WithReasonf("Query parameter batch_size must be a number between 1 and %d.", importMaxBatchSize)))
+			return
+		}
+		batchSize = size
+	}
+
+	reader, err := newImportReader(r.Body)
+	if err != nil {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	result := &ImportResult{Results: []ImportRecordResult{}}
+	batch := make([]*Identity, 0, batchSize)
+	indices := make([]int, 0, batchSize)
+	flush := func() {
+		h.importBatch(r.Context(), result, batch, indices)
+		batch, indices = batch[:0], indices[:0]
+	}
+
+	for index := 0; ; index++ {
+		raw, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			// The body is malformed and the remaining identities can not be read reliably.
+			result.failed(index, herodot.ErrBadRequest.WithReasonf("Unable to decode the identity: %s", err))
+			break
+		}
+
+		i, err := h.newImportedIdentity(raw)
+		if err != nil {
+			result.failed(index, err)
+			continue
+		}
+
+		batch = append(batch, i)
+		indices = append(indices, index)
+		if len(batch) >= batchSize {
+			flush()
+		}
+	}
+	flush()
+
+	sort.Slice(result.Results, func(i, j int) bool {
+		return result.Results[i].Index < result.Results[j].Index
+	})
+
+	h.r.Audit().
+		WithRequest(r).
+		WithField("imported", result.Imported).
+		WithField("failed", result.Failed).
+		Info("Identities were imported by an administrator.")
+	h.r.Writer().Write(w, r, result)
+}
+
+// importBatch stores a batch of identities in one transaction. If that fails, the identities are stored one by
+// one so that the failing identities can be told apart from the others.
+func (h *Handler) importBatch(ctx context.Context, result *ImportResult, batch []*Identity, indices []int) {
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := h.r.PrivilegedIdentityPool().CreateIdentities(ctx, batch); err == nil {
+		for k, i := range batch {
+			result.succeeded(indices[k], i.ID)
+		}
+		return
+	}
+
+	for k, i := range batch {
+		if err := h.r.PrivilegedIdentityPool().CreateIdentity(ctx, i); err != nil {
+			result.failed(indices[k], err)
+			continue
+		}
+		result.succeeded(indices[k], i.ID)
+	}
+}
+
+func (h *Handler) newImportedIdentity(raw json.RawMessage) (*Identity, error) {
+	var ii ImportIdentity
+	if err := jsonx.NewStrictDecoder(strings.NewReader(string(raw))).Decode(&ii); err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the identity: %s", err))
+	}
+
+	if err := validateMetadata(ii.MetadataPublic, ii.MetadataAdmin); err != nil {
+		return nil, err
+	}
+
+	if ii.State == "" {
+		ii.State = StateActive
+	} else if err := ii.State.Validate(); err != nil {
+		return nil, err
+	}
+
+	i := &Identity{SchemaID: ii.SchemaID, Traits: Traits(ii.Traits), MetadataPublic: ii.MetadataPublic, MetadataAdmin: ii.MetadataAdmin, State: ii.State, ExpiresAt: ii.ExpiresAt}
+	if err := h.importCredentials(i, ii.Credentials); err != nil {
+		return nil, err
+	}
+
+	if err := h.r.IdentityValidator().Validate(i); err != nil {
+		if _, ok := errorsx.Cause(err).(*jsonschema.ValidationError); ok {
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("%s", err))
+		}
+		return nil, err
+	}
+
+	if c, ok := i.GetCredentials(CredentialsTypePassword); ok && len(c.Identifiers) == 0 {
+		return nil, errors.WithStack(herodot.ErrBadRequest.
+			WithReason("Unable to import the password because the identity schema does not mark any trait as a password identifier."))
+	}
+
+	return i, markVerified(i, ii.VerifiedAddresses)
+}
+
+func (h *Handler) importCredentials(i *Identity, ic *ImportCredentials) error {
+	if ic == nil {
+		return nil
+	}
+
+	if p := ic.Password; p != nil {
+		hashed := p.HashedPassword
+		if hashed == "" && p.Password != "" {
+			generated, err := h.r.Hasher().Generate([]byte(p.Password))
+			if err != nil {
+				return err
+			}
+			hashed = string(generated)
+		}
+
+		if hashed == "" {
+			return errors.WithStack(herodot.ErrBadRequest.WithReason("Password credentials require either a password or a hashed password."))
+		}
+
+		config, err := json.Marshal(map[string]string{"hashed_password": hashed})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		// The identifiers are set by the identity schema's credentials extension.
+		i.SetCredentials(CredentialsTypePassword, Credentials{Type: CredentialsTypePassword, Identifiers: []string{}, Config: config})
+	}
+
+	if o := ic.OIDC; o != nil && len(o.Providers) > 0 {
+		identifiers := make([]string, len(o.Providers))
+		for k, p := range o.Providers {
+			if p.Provider == "" || p.Subject == "" {
+				return errors.WithStack(herodot.ErrBadRequest.WithReason("OpenID Connect credentials require a provider and a subject."))
+			}
+			identifiers[k] = fmt.Sprintf("%s:%s", p.Provider, p.Subject)
+		}
+
+		config, err := json.Marshal(o)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		i.SetCredentials(CredentialsTypeOIDC, Credentials{Type: CredentialsTypeOIDC, Identifiers: identifiers, Config: config})
+	}
+
+	return nil
+}
+
+// markVerified marks the identity's verifiable addresses as verified.
+func markVerified(i *Identity, addresses []string) error {
+	now := time.Now().UTC()
+	for _, value := range addresses {
+		found := false
+		for k := range i.VerifiableAddresses {
+			if strings.EqualFold(i.VerifiableAddresses[k].Value, value) {
+				i.VerifiableAddresses[k].Verified = true
+				i.VerifiableAddresses[k].Status = VerifiableAddressStatusCompleted
+				i.VerifiableAddresses[k].VerifiedAt = sqlxx.NullTime(now)
+				found = true
+			}
+		}
+
+		if !found {
+			return errors.WithStack(herodot.ErrBadRequest.WithReasonf("Address %q is not a verifiable address of the identity.", value))
+		}
+	}
+	return nil
+}
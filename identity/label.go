@@ -0,0 +1,103 @@
+package identity
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+)
+
+// LabelValueMaxLength is the maximum length of the value of a label as limited by the SQL schema.
+const LabelValueMaxLength = 255
+
+var labelName = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+
+type (
+	// Label is a key/value pair attached to an identity by an administrator, for example to mark beta testers or
+	// identities migrated from another system. Labels are indexed so that identities can be filtered by them.
+	Label struct {
+		ID uuid.UUID `json:"-" faker:"-" db:"id"`
+
+		// Name is the key of the label.
+		Name string `json:"name" db:"name"`
+
+		// Value is the value of the label.
+		Value string `json:"value" db:"value"`
+
+		// IdentityID is a helper struct field for gobuffalo.pop.
+		IdentityID uuid.UUID `json:"-" faker:"-" db:"identity_id"`
+		// CreatedAt is a helper struct field for gobuffalo.pop.
+		CreatedAt time.Time `json:"-" faker:"-" db:"created_at"`
+		// UpdatedAt is a helper struct field for gobuffalo.pop.
+		UpdatedAt time.Time `json:"-" faker:"-" db:"updated_at"`
+	}
+
+	// Labels are encoded as a JSON object mapping the names of the labels to their values.
+	//
+	// swagger:model identityLabels
+	Labels []Label
+
+	// LabelFilter matches identities which have the label Name with the value Value.
+	LabelFilter struct {
+		// Name is the key of the label.
+		Name string
+
+		// Value is the expected value of the label.
+		Value string
+	}
+)
+
+func (l Label) TableName() string {
+	return "identity_labels"
+}
+
+func (l Labels) TableName() string {
+	return "identity_labels"
+}
+
+// Map returns the labels as a map of their names to their values.
+func (l Labels) Map() map[string]string {
+	m := make(map[string]string, len(l))
+	for _, label := range l {
+		m[label.Name] = label.Value
+	}
+	return m
+}
+
+func (l Labels) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.Map())
+}
+
+func (l *Labels) UnmarshalJSON(data []byte) error {
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return errors.WithStack(err)
+	}
+
+	labels := make(Labels, 0, len(m))
+	for name, value := range m {
+		labels = append(labels, Label{Name: name, Value: value})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+
+	*l = labels
+	return nil
+}
+
+// ValidateLabel returns an error if the name or the value of a label are invalid.
+func ValidateLabel(name, value string) error {
+	if !labelName.MatchString(name) {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The label name "%s" is invalid. Label names must be 1 to 64 characters long and may only contain letters, digits, underscores, dots, and dashes.`, name))
+	}
+	if len(value) > LabelValueMaxLength {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("The value of the label must not be longer than %d characters.", LabelValueMaxLength))
+	}
+	return nil
+}
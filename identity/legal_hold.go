@@ -0,0 +1,24 @@
+package identity
+
+import (
+	"time"
+
+	"github.com/ory/herodot"
+)
+
+var ErrLegalHold = herodot.ErrConflict.
+	WithError("identity is under legal hold").
+	WithReason("This identity is under legal hold and can not be deleted until the legal hold is released.")
+
+// PlaceOnLegalHold marks the identity as under legal hold for the given reason.
+func (i *Identity) PlaceOnLegalHold(reason string) {
+	now := time.Now().UTC()
+	i.LegalHoldAt = &now
+	i.LegalHoldReason = reason
+}
+
+// IsUnderLegalHold returns true if the identity is under legal hold. Identities under legal hold can be used as
+// usual but can not be deleted, neither by administrators nor by purge or bulk deletion jobs.
+func (i *Identity) IsUnderLegalHold() bool {
+	return i.LegalHoldAt != nil
+}
@@ -1,8 +1,11 @@
 package identity
 
 import (
+	"bytes"
 	"context"
+	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/gofrs/uuid"
 
@@ -20,6 +23,18 @@ import (
 var ErrProtectedFieldModified = herodot.ErrForbidden.
 	WithReasonf(`A field was modified that updates one or more credentials-related settings. This action was blocked because an unprivileged method was used to execute the update. This is either a configuration issue or a bug and should be reported to the system administrator.`)
 
+var ErrConcurrentUpdate = herodot.ErrConflict.
+	WithError("identity was updated concurrently").
+	WithReason("The identity was changed by another request while it was being updated. Please try again.")
+
+// ErrVersionMismatch is returned if the identity does not have the version the update expected.
+var ErrVersionMismatch = herodot.DefaultError{
+	CodeField:   http.StatusPreconditionFailed,
+	StatusField: http.StatusText(http.StatusPreconditionFailed),
+	ErrorField:  "identity version mismatch",
+	ReasonField: "The identity was changed since it was loaded. Load the identity again and retry the update.",
+}
+
 type (
 	managerDependencies interface {
 		PoolProvider
@@ -37,6 +52,8 @@ type (
 	managerOptions struct {
 		ExposeValidationErrors    bool
 		AllowWriteProtectedTraits bool
+		ExpectUpdatedAt           *time.Time
+		ExpectVersions            []string
 	}
 
 	ManagerOption func(*managerOptions)
@@ -54,6 +71,22 @@ func ManagerAllowWriteProtectedTraits(options *managerOptions) {
 	options.AllowWriteProtectedTraits = true
 }
 
+// ManagerExpectUpdatedAt makes updates fail with ErrConcurrentUpdate if the identity was changed after it was
+// loaded, that is if it was last updated at a different time.
+func ManagerExpectUpdatedAt(updatedAt time.Time) ManagerOption {
+	return func(options *managerOptions) {
+		options.ExpectUpdatedAt = &updatedAt
+	}
+}
+
+// ManagerExpectVersion makes updates fail with ErrVersionMismatch unless the identity has one of the versions as
+// returned by Identity.Version, for example because the versions were sent in an HTTP `If-Match` header.
+func ManagerExpectVersion(versions ...string) ManagerOption {
+	return func(options *managerOptions) {
+		options.ExpectVersions = append(options.ExpectVersions, versions...)
+	}
+}
+
 func newManagerOptions(opts []ManagerOption) *managerOptions {
 	var o managerOptions
 	for _, f := range opts {
@@ -86,6 +119,14 @@ func (m *Manager) requiresPrivilegedAccess(_ context.Context, original, updated
 			*updated = *original
 			return errors.WithStack(ErrProtectedFieldModified)
 		}
+
+		// Metadata can only be changed using the admin API.
+		if !bytes.Equal(original.MetadataPublic, updated.MetadataPublic) ||
+			!bytes.Equal(original.MetadataAdmin, updated.MetadataAdmin) {
+			// reset the identity
+			*updated = *original
+			return errors.WithStack(ErrProtectedFieldModified)
+		}
 	}
 	return nil
 }
@@ -101,11 +142,15 @@ func (m *Manager) Update(ctx context.Context, updated *Identity, opts ...Manager
 		return err
 	}
 
+	if err := checkVersion(original, o); err != nil {
+		return err
+	}
+
 	if err := m.requiresPrivilegedAccess(ctx, original, updated, o); err != nil {
 		return err
 	}
 
-	return m.r.IdentityPool().(PrivilegedPool).UpdateIdentity(ctx, updated)
+	return m.update(ctx, original, updated, o)
 }
 
 func (m *Manager) UpdateSchemaID(ctx context.Context, id uuid.UUID, schemaID string, opts ...ManagerOption) error {
@@ -141,11 +186,45 @@ func (m *Manager) UpdateTraits(ctx context.Context, id uuid.UUID, traits Traits,
 		return err
 	}
 
+	if err := checkVersion(original, o); err != nil {
+		return err
+	}
+
 	if err := m.requiresPrivilegedAccess(ctx, original, updated, o); err != nil {
 		return err
 	}
 
-	return m.r.IdentityPool().(PrivilegedPool).UpdateIdentity(ctx, updated)
+	return m.update(ctx, original, updated, o)
+}
+
+// update stores the identity. If a version was expected, the identity is only stored if it was not changed since
+// the original was loaded, because another request may have changed it after checkVersion.
+func (m *Manager) update(ctx context.Context, original, updated *Identity, o *managerOptions) error {
+	if o.ExpectUpdatedAt == nil && len(o.ExpectVersions) == 0 {
+		return m.r.IdentityPool().(PrivilegedPool).UpdateIdentity(ctx, updated)
+	}
+
+	err := m.r.IdentityPool().(PrivilegedPool).UpdateIdentityIfUnchanged(ctx, updated, original.UpdatedAt)
+	if errors.Is(err, ErrConcurrentUpdate) && o.ExpectUpdatedAt == nil {
+		return errors.WithStack(ErrVersionMismatch)
+	}
+	return err
+}
+
+func checkVersion(original *Identity, o *managerOptions) error {
+	if o.ExpectUpdatedAt != nil && !original.UpdatedAt.Equal(*o.ExpectUpdatedAt) {
+		return errors.WithStack(ErrConcurrentUpdate)
+	}
+
+	if len(o.ExpectVersions) == 0 {
+		return nil
+	}
+	for _, v := range o.ExpectVersions {
+		if v == original.Version() {
+			return nil
+		}
+	}
+	return errors.WithStack(ErrVersionMismatch)
 }
 
 func (m *Manager) validate(i *Identity, o *managerOptions) error {
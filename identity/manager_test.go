@@ -91,6 +91,28 @@ func TestManager(t *testing.T) {
 			checkExtensionFieldsForIdentities(t, "bar@ory.sh", original)
 		})
 
+		t.Run("case=should not update metadata without option", func(t *testing.T) {
+			original := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+			original.Traits = newTraits("email-update-metadata@ory.sh", "")
+			original.MetadataAdmin = identity.Metadata(`{"customer_id":"cus_1"}`)
+			require.NoError(t, reg.IdentityManager().Create(context.Background(), original))
+
+			original.MetadataAdmin = identity.Metadata(`{"customer_id":"cus_2"}`)
+			err := reg.IdentityManager().Update(context.Background(), original)
+			require.Error(t, err)
+			assert.Equal(t, identity.ErrProtectedFieldModified, errors.Cause(err))
+
+			original.MetadataPublic = identity.Metadata(`{"plan":"pro"}`)
+			err = reg.IdentityManager().Update(context.Background(), original)
+			require.Error(t, err)
+			assert.Equal(t, identity.ErrProtectedFieldModified, errors.Cause(err))
+
+			fromStore, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), original.ID)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"customer_id":"cus_1"}`, string(fromStore.MetadataAdmin))
+			assert.Empty(t, fromStore.MetadataPublic)
+		})
+
 		t.Run("case=should not update protected traits without option", func(t *testing.T) {
 			original := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
 			original.Traits = newTraits("email-update-1@ory.sh", "")
@@ -108,6 +130,46 @@ func TestManager(t *testing.T) {
 			checkExtensionFields(fromStore, "email-update-1@ory.sh")(t)
 		})
 
+		t.Run("case=should not update identities changed concurrently", func(t *testing.T) {
+			original := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+			original.Traits = newTraits("email-concurrent-1@ory.sh", "")
+			require.NoError(t, reg.IdentityManager().Create(context.Background(), original))
+
+			loaded, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), original.ID)
+			require.NoError(t, err)
+			stale := loaded.UpdatedAt
+
+			loaded.Traits = newTraits("email-concurrent-2@ory.sh", "")
+			require.NoError(t, reg.IdentityManager().Update(context.Background(), loaded, identity.ManagerAllowWriteProtectedTraits, identity.ManagerExpectUpdatedAt(stale)))
+
+			loaded.Traits = newTraits("email-concurrent-3@ory.sh", "")
+			err = reg.IdentityManager().Update(context.Background(), loaded, identity.ManagerAllowWriteProtectedTraits, identity.ManagerExpectUpdatedAt(stale))
+			require.Error(t, err)
+			assert.Equal(t, identity.ErrConcurrentUpdate, errors.Cause(err))
+		})
+
+		t.Run("case=should only update identities with the expected version", func(t *testing.T) {
+			original := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+			original.Traits = newTraits("email-version-1@ory.sh", "")
+			require.NoError(t, reg.IdentityManager().Create(context.Background(), original))
+
+			loaded, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), original.ID)
+			require.NoError(t, err)
+			version := loaded.Version()
+
+			loaded.Traits = newTraits("email-version-2@ory.sh", "")
+			err = reg.IdentityManager().UpdateTraits(context.Background(), loaded.ID, loaded.Traits, identity.ManagerAllowWriteProtectedTraits, identity.ManagerExpectVersion(`"1"`))
+			require.Error(t, err)
+			assert.Equal(t, identity.ErrVersionMismatch, errors.Cause(err))
+
+			require.NoError(t, reg.IdentityManager().Update(context.Background(), loaded, identity.ManagerAllowWriteProtectedTraits, identity.ManagerExpectVersion(`"1"`, version)))
+
+			loaded.Traits = newTraits("email-version-3@ory.sh", "")
+			err = reg.IdentityManager().Update(context.Background(), loaded, identity.ManagerAllowWriteProtectedTraits, identity.ManagerExpectVersion(version))
+			require.Error(t, err)
+			assert.Equal(t, identity.ErrVersionMismatch, errors.Cause(err))
+		})
+
 		t.Run("case=changing recovery address removes it from the store", func(t *testing.T) {
 			originalEmail := x.NewUUID().String() + "@ory.sh"
 			original := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
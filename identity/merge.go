@@ -0,0 +1,81 @@
+package identity
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/x/sqlxx"
+)
+
+// MergeResult records what was moved from the duplicate to the primary identity by a merge.
+//
+// swagger:model identityMergeResult
+type MergeResult struct {
+	// Primary is the ID of the identity which was kept.
+	//
+	// required: true
+	Primary uuid.UUID `json:"primary"`
+
+	// Duplicate is the ID of the identity which was merged into the primary and deleted.
+	//
+	// required: true
+	Duplicate uuid.UUID `json:"duplicate"`
+
+	// MovedCredentials are the types of the duplicate's credentials which the primary did not have.
+	//
+	// required: true
+	MovedCredentials []CredentialsType `json:"moved_credentials"`
+
+	// MergedCredentials are the types of the credentials both identities had. The duplicate's identifiers were
+	// added to the primary's credentials.
+	//
+	// required: true
+	MergedCredentials []CredentialsType `json:"merged_credentials"`
+
+	// Identifiers is the number of credentials identifiers moved.
+	//
+	// required: true
+	Identifiers int `json:"identifiers"`
+
+	// VerifiableAddresses is the number of verifiable addresses moved.
+	//
+	// required: true
+	VerifiableAddresses int `json:"verifiable_addresses"`
+
+	// RecoveryAddresses is the number of recovery addresses moved.
+	//
+	// required: true
+	RecoveryAddresses int `json:"recovery_addresses"`
+
+	// Sessions is the number of sessions moved.
+	//
+	// required: true
+	Sessions int `json:"sessions"`
+}
+
+// MergeCredentialsConfig returns the configuration of the primary's credentials after the duplicate's
+// credentials of the same type were merged into them. The primary's configuration wins, so that for example its
+// password keeps working. OpenID Connect credentials list one entry per linked provider account, which are
+// combined so that both accounts can still be used to sign in.
+func MergeCredentialsConfig(ct CredentialsType, primary, duplicate sqlxx.JSONRawMessage) (sqlxx.JSONRawMessage, error) {
+	if ct != CredentialsTypeOIDC {
+		return primary, nil
+	}
+
+	merged := []byte(primary)
+	if len(merged) == 0 {
+		merged = []byte("{}")
+	}
+
+	for _, provider := range gjson.GetBytes(duplicate, "providers").Array() {
+		var err error
+		merged, err = sjson.SetRawBytes(merged, "providers.-1", []byte(provider.Raw))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,74 @@
+package identity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/courier"
+)
+
+var _ courier.Preferences = new(NotificationPreferences)
+
+// NotificationPreferences are the channels on which an identity agreed to receive messages which are not required
+// for the security of its account, for example product updates. Security messages such as recovery and
+// verification emails are always delivered.
+//
+// swagger:model notificationPreferences
+type NotificationPreferences struct {
+	// Email is true if the identity agreed to receive emails.
+	Email bool `json:"email"`
+
+	// SMS is true if the identity agreed to receive text messages.
+	SMS bool `json:"sms"`
+
+	// SecurityOnly is true if the identity only wants to receive security messages on any channel.
+	SecurityOnly bool `json:"security_only"`
+}
+
+// DefaultNotificationPreferences are the preferences of identities which did not set any.
+func DefaultNotificationPreferences() *NotificationPreferences {
+	return &NotificationPreferences{Email: true, SMS: true}
+}
+
+// Allows returns true if a message which is not required for the security of the account may be sent on the
+// channel. A nil receiver behaves like the default preferences.
+func (p *NotificationPreferences) Allows(t courier.MessageType) bool {
+	if p == nil {
+		p = DefaultNotificationPreferences()
+	}
+
+	if p.SecurityOnly {
+		return false
+	}
+
+	switch t {
+	case courier.MessageTypeEmail:
+		return p.Email
+	case courier.MessageTypeSMS:
+		return p.SMS
+	}
+	return false
+}
+
+func (p *NotificationPreferences) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return errors.Errorf("unable to scan notification preferences of type %T", value)
+	}
+	return errors.WithStack(json.Unmarshal(raw, p))
+}
+
+func (p NotificationPreferences) Value() (driver.Value, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return string(raw), nil
+}
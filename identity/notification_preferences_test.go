@@ -0,0 +1,42 @@
+package identity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/courier"
+)
+
+func TestNotificationPreferences(t *testing.T) {
+	t.Run("method=Allows", func(t *testing.T) {
+		for k, tc := range []struct {
+			p        *NotificationPreferences
+			expected bool
+		}{
+			{p: nil, expected: true},
+			{p: DefaultNotificationPreferences(), expected: true},
+			{p: &NotificationPreferences{Email: true}, expected: true},
+			{p: &NotificationPreferences{SMS: true}, expected: false},
+			{p: &NotificationPreferences{Email: true, SecurityOnly: true}, expected: false},
+		} {
+			assert.Equal(t, tc.expected, tc.p.Allows(courier.MessageTypeEmail), "%d", k)
+		}
+	})
+
+	t.Run("method=Scan/Value", func(t *testing.T) {
+		expected := NotificationPreferences{SMS: true, SecurityOnly: true}
+		v, err := expected.Value()
+		require.NoError(t, err)
+		assert.Equal(t, `{"email":false,"sms":true,"security_only":true}`, v)
+
+		for _, raw := range []interface{}{v, []byte(v.(string))} {
+			var actual NotificationPreferences
+			require.NoError(t, actual.Scan(raw))
+			assert.Equal(t, expected, actual)
+		}
+
+		require.Error(t, new(NotificationPreferences).Scan(1))
+	})
+}
@@ -0,0 +1,48 @@
+package identity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+)
+
+// PageToken points to the last identity of a page listed using keyset pagination. Identities are ordered by their
+// creation time and ID, which is stable when identities are created or deleted between requests.
+type PageToken struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        uuid.UUID `json:"id"`
+}
+
+func NewPageToken(i *Identity) *PageToken {
+	return &PageToken{CreatedAt: i.CreatedAt, ID: i.ID}
+}
+
+// ParsePageToken decodes a page token returned by Encode.
+func ParsePageToken(token string) (*PageToken, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The page token is invalid.").WithDebug(err.Error()))
+	}
+
+	var t PageToken
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The page token is invalid.").WithDebug(err.Error()))
+	}
+
+	if t.ID == uuid.Nil || t.CreatedAt.IsZero() {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The page token is invalid."))
+	}
+
+	return &t, nil
+}
+
+// Encode returns the opaque representation of the page token used in URLs.
+func (t *PageToken) Encode() string {
+	raw, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
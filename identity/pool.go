@@ -29,11 +29,27 @@ import (
 
 type (
 	Pool interface {
-		// ListIdentities lists all identities in the store given the page and itemsPerPage.
-		ListIdentities(ctx context.Context, page, itemsPerPage int) ([]Identity, error)
+		// ListIdentities lists all identities in the store matching the filter given the page and itemsPerPage.
+		ListIdentities(ctx context.Context, filter ListIdentitiesFilter, page, itemsPerPage int) ([]Identity, error)
 
-		// CountIdentities counts the number of identities in the store.
-		CountIdentities(ctx context.Context) (int64, error)
+		// ListIdentitiesAfter lists at most limit identities in the store matching the filter ordered by their
+		// creation time and ID. If after is set, only identities following it are listed.
+		ListIdentitiesAfter(ctx context.Context, filter ListIdentitiesFilter, after *PageToken, limit int) ([]Identity, error)
+
+		// ListIdentityIDs returns the IDs of the identities ListIdentities lists given the page and itemsPerPage, in
+		// the same order, without loading the identities.
+		ListIdentityIDs(ctx context.Context, filter ListIdentitiesFilter, page, itemsPerPage int) ([]uuid.UUID, error)
+
+		// ListIdentityPageTokensAfter returns the page tokens of the identities ListIdentitiesAfter lists, in the same
+		// order, without loading the identities.
+		ListIdentityPageTokensAfter(ctx context.Context, filter ListIdentitiesFilter, after *PageToken, limit int) ([]PageToken, error)
+
+		// StreamIdentities loads the identities with the given IDs at most batchSize at a time and calls fn with each
+		// of them in the order of the IDs. Identities which do not exist or were deleted in the meantime are skipped.
+		StreamIdentities(ctx context.Context, ids []uuid.UUID, batchSize int, fn func(*Identity) error) error
+
+		// CountIdentities counts the number of identities in the store matching the filter.
+		CountIdentities(ctx context.Context, filter ListIdentitiesFilter) (int64, error)
 
 		// GetIdentity returns an identity by its id. Will return an error if the identity does not exist or backend
 		// connectivity is broken.
@@ -46,6 +62,39 @@ type (
 		FindRecoveryAddressByValue(ctx context.Context, via RecoveryAddressType, address string) (*RecoveryAddress, error)
 	}
 
+	// ListIdentitiesFilter narrows down the identities listed and counted by the pool. The zero value matches all
+	// identities.
+	ListIdentitiesFilter struct {
+		// CredentialsIdentifier matches identities with credentials using this identifier, for example an email
+		// address.
+		CredentialsIdentifier string
+
+		// Traits match identities whose trait at the path equals the value. All traits must match.
+		Traits []TraitFilter
+
+		// Labels match identities which have the label with the value. All labels must match.
+		Labels []LabelFilter
+
+		// SchemaID matches identities using this traits schema.
+		SchemaID string
+
+		// State matches identities in this state.
+		State State
+
+		// Verified matches identities with at least one verified address if true and identities without any
+		// verified address if false.
+		Verified *bool
+	}
+
+	// TraitFilter matches identities whose string trait at Path equals Value.
+	TraitFilter struct {
+		// Path is the dot-separated path of the trait, for example `department` or `name.last`.
+		Path string
+
+		// Value is the expected value of the trait.
+		Value string
+	}
+
 	PoolProvider interface {
 		IdentityPool() Pool
 	}
@@ -61,9 +110,81 @@ type (
 		FindByCredentialsIdentifier(ctx context.Context, ct CredentialsType, match string) (*Identity, *Credentials, error)
 
 		// Delete removes an identity by its id. Will return an error
-		// if identity exists, backend connectivity is broken, or trait validation fails.
+		// if identity exists, backend connectivity is broken, or trait validation fails. Returns ErrLegalHold if
+		// the identity is under legal hold.
 		DeleteIdentity(context.Context, uuid.UUID) error
 
+		// DeleteIdentities deletes the identities in one transaction and returns the IDs of the deleted identities
+		// and of the identities which were kept because they are under legal hold. Unknown IDs are ignored.
+		DeleteIdentities(ctx context.Context, ids []uuid.UUID) (deleted, held []uuid.UUID, err error)
+
+		// SoftDeleteIdentity hides an identity and revokes its sessions while keeping its credentials and addresses
+		// so that it can be restored. Returns sqlcon.ErrNoRows if the identity does not exist or is deleted already
+		// and ErrLegalHold if it is under legal hold.
+		SoftDeleteIdentity(ctx context.Context, id uuid.UUID) error
+
+		// RestoreIdentity restores a soft-deleted identity. Returns sqlcon.ErrNoRows if the identity does not exist
+		// or is not soft-deleted.
+		RestoreIdentity(ctx context.Context, id uuid.UUID) error
+
+		// MergeIdentities moves the credentials identifiers, addresses, and sessions of the duplicate to the
+		// primary identity and deletes the duplicate. The primary's traits and credentials configuration are
+		// kept. Returns sqlcon.ErrNoRows if either identity does not exist or is soft-deleted and ErrLegalHold if
+		// the duplicate is under legal hold.
+		MergeIdentities(ctx context.Context, primary, duplicate uuid.UUID) (*MergeResult, error)
+
+		// PurgeDeletedIdentities permanently deletes all identities soft-deleted before the given time and returns
+		// their number. Identities under legal hold are kept.
+		PurgeDeletedIdentities(ctx context.Context, deletedBefore time.Time) (int, error)
+
+		// ListIdentityIDsByFilter returns the IDs of all identities matching the filter.
+		ListIdentityIDsByFilter(ctx context.Context, filter *Filter) ([]uuid.UUID, error)
+
+		// CreateBulkDeletion stores a new bulk deletion job.
+		CreateBulkDeletion(ctx context.Context, job *BulkDeletion) error
+
+		// UpdateBulkDeletion stores the progress of a bulk deletion job.
+		UpdateBulkDeletion(ctx context.Context, job *BulkDeletion) error
+
+		// GetBulkDeletion returns a bulk deletion job. Returns sqlcon.ErrNoRows if the job does not exist.
+		GetBulkDeletion(ctx context.Context, id uuid.UUID) (*BulkDeletion, error)
+
+		// ListQuarantinedIdentities lists all quarantined identities, oldest quarantine first.
+		ListQuarantinedIdentities(ctx context.Context, page, itemsPerPage int) ([]Identity, error)
+
+		// ReleaseIdentityFromQuarantine lifts the quarantine of an identity. Returns sqlcon.ErrNoRows if the identity
+		// does not exist or is not quarantined.
+		ReleaseIdentityFromQuarantine(ctx context.Context, id uuid.UUID) error
+
+		// PlaceIdentityOnLegalHold places an identity on legal hold for the given reason. Soft-deleted identities
+		// can be placed on legal hold as well, which prevents them from being purged. Returns sqlcon.ErrNoRows if
+		// the identity does not exist.
+		PlaceIdentityOnLegalHold(ctx context.Context, id uuid.UUID, reason string) error
+
+		// ReleaseIdentityFromLegalHold releases an identity from legal hold. Returns sqlcon.ErrNoRows if the
+		// identity does not exist or is not under legal hold.
+		ReleaseIdentityFromLegalHold(ctx context.Context, id uuid.UUID) error
+
+		// DeleteIdentityCredentials removes the credentials of the given type from an identity. Returns
+		// sqlcon.ErrNoRows if the identity does not exist or does not have credentials of this type.
+		DeleteIdentityCredentials(ctx context.Context, id uuid.UUID, ct CredentialsType) error
+
+		// SetIdentityLabel sets the label of an identity to the value, replacing its previous value. Returns
+		// sqlcon.ErrNoRows if the identity does not exist.
+		SetIdentityLabel(ctx context.Context, id uuid.UUID, name, value string) error
+
+		// DeleteIdentityLabel removes the label from an identity. Returns sqlcon.ErrNoRows if the identity does not
+		// exist or does not have the label.
+		DeleteIdentityLabel(ctx context.Context, id uuid.UUID, name string) error
+
+		// UpdateIdentityState changes the state of an identity. Returns sqlcon.ErrNoRows if the identity does not
+		// exist.
+		UpdateIdentityState(ctx context.Context, id uuid.UUID, state State) error
+
+		// UpdateIdentityExpiry sets the time at which an identity expires. If expiresAt is nil the identity never
+		// expires. Returns sqlcon.ErrNoRows if the identity does not exist.
+		UpdateIdentityExpiry(ctx context.Context, id uuid.UUID, expiresAt *time.Time) error
+
 		// UpdateVerifiableAddress
 		UpdateVerifiableAddress(ctx context.Context, address *VerifiableAddress) error
 
@@ -71,9 +192,17 @@ type (
 		// if identity exists, backend connectivity is broken, or trait validation fails.
 		CreateIdentity(context.Context, *Identity) error
 
+		// CreateIdentities creates several identities in one transaction. If one of them can not be created, none
+		// of them are.
+		CreateIdentities(context.Context, []*Identity) error
+
 		// UpdateIdentity updates an identity including its confidential / privileged / protected data.
 		UpdateIdentity(context.Context, *Identity) error
 
+		// UpdateIdentityIfUnchanged updates an identity like UpdateIdentity, but only if it was last updated at
+		// updatedAt. Returns ErrConcurrentUpdate if the identity was changed since.
+		UpdateIdentityIfUnchanged(ctx context.Context, i *Identity, updatedAt time.Time) error
+
 		// GetIdentityConfidential returns the identity including it's raw credentials. This should only be used internally.
 		GetIdentityConfidential(context.Context, uuid.UUID) (*Identity, error)
 
@@ -145,7 +274,7 @@ func TestPool(conf *config.Provider, p interface {
 			assert.NotEqual(t, uuid.Nil, i.ID)
 			createdIDs = append(createdIDs, i.ID)
 
-			count, err := p.CountIdentities(context.Background())
+			count, err := p.CountIdentities(context.Background(), ListIdentitiesFilter{})
 			require.NoError(t, err)
 			assert.EqualValues(t, 1, count)
 		})
@@ -163,11 +292,351 @@ func TestPool(conf *config.Provider, p interface {
 			assert.Equal(t, defaultSchema.SchemaURL(exampleServerURL).String(), actual.SchemaURL)
 			assertEqual(t, expected, actual)
 
-			count, err := p.CountIdentities(context.Background())
+			count, err := p.CountIdentities(context.Background(), ListIdentitiesFilter{})
 			require.NoError(t, err)
 			assert.EqualValues(t, 2, count)
 		})
 
+		t.Run("case=list identity ids by filter", func(t *testing.T) {
+			prefix := "filter-" + x.NewUUID().String()
+			before := passwordIdentity("", prefix+"-a@ory.sh")
+			require.NoError(t, p.CreateIdentity(context.Background(), before))
+
+			alt := passwordIdentity(altSchema.ID, prefix+"-b@ory.sh")
+			require.NoError(t, p.CreateIdentity(context.Background(), alt))
+
+			other := passwordIdentity("", "other-"+prefix+"_%!")
+			require.NoError(t, p.CreateIdentity(context.Background(), other))
+			createdIDs = append(createdIDs, before.ID, alt.ID, other.ID)
+
+			for k, tc := range []struct {
+				f      Filter
+				expect []uuid.UUID
+			}{
+				{f: Filter{CredentialsIdentifier: prefix + "-*"}, expect: []uuid.UUID{before.ID, alt.ID}},
+				{f: Filter{CredentialsIdentifier: prefix + "-a@ory.sh"}, expect: []uuid.UUID{before.ID}},
+				{f: Filter{CredentialsIdentifier: "*" + prefix + "*", SchemaID: altSchema.ID}, expect: []uuid.UUID{alt.ID}},
+				{f: Filter{CredentialsIdentifier: "other-" + prefix + "_%!"}, expect: []uuid.UUID{other.ID}},
+				{f: Filter{CredentialsIdentifier: "other-" + prefix + "__!"}, expect: []uuid.UUID{}},
+				{f: Filter{CredentialsIdentifier: prefix + "-*", CreatedBefore: time.Now().Add(-time.Hour)}, expect: []uuid.UUID{}},
+				{f: Filter{CredentialsIdentifier: prefix + "-*", CreatedAfter: time.Now().Add(-time.Hour)}, expect: []uuid.UUID{before.ID, alt.ID}},
+			} {
+				t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+					ids, err := p.ListIdentityIDsByFilter(context.Background(), &tc.f)
+					require.NoError(t, err)
+					assert.ElementsMatch(t, tc.expect, ids)
+				})
+			}
+
+			t.Run("case=ids", func(t *testing.T) {
+				ids, err := p.ListIdentityIDsByFilter(context.Background(), &Filter{IDs: []uuid.UUID{before.ID, other.ID, x.NewUUID()}})
+				require.NoError(t, err)
+				assert.ElementsMatch(t, []uuid.UUID{before.ID, other.ID}, ids)
+			})
+
+			t.Run("case=verified", func(t *testing.T) {
+				verified, unverified := true, false
+				ids, err := p.ListIdentityIDsByFilter(context.Background(), &Filter{CredentialsIdentifier: prefix + "-*", Verified: &verified})
+				require.NoError(t, err)
+				assert.Empty(t, ids)
+
+				ids, err = p.ListIdentityIDsByFilter(context.Background(), &Filter{CredentialsIdentifier: prefix + "-*", Verified: &unverified})
+				require.NoError(t, err)
+				assert.ElementsMatch(t, []uuid.UUID{before.ID, alt.ID}, ids)
+			})
+
+			count, err := p.CountIdentities(context.Background(), ListIdentitiesFilter{})
+			require.NoError(t, err)
+			ids, err := p.ListIdentityIDsByFilter(context.Background(), new(Filter))
+			require.NoError(t, err)
+			assert.Len(t, ids, int(count))
+		})
+
+		t.Run("case=bulk deletions", func(t *testing.T) {
+			_, err := p.GetBulkDeletion(context.Background(), x.NewUUID())
+			require.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+
+			job := &BulkDeletion{
+				Filter: Filter{CredentialsIdentifier: "bulk-*", SchemaID: "default"},
+				State:  BulkDeletionStatePending,
+			}
+			require.NoError(t, p.CreateBulkDeletion(context.Background(), job))
+			assert.NotEqual(t, uuid.Nil, job.ID)
+
+			job.State = BulkDeletionStateCompleted
+			job.Matched, job.Deleted, job.Skipped = 3, 2, 1
+			require.NoError(t, p.UpdateBulkDeletion(context.Background(), job))
+
+			actual, err := p.GetBulkDeletion(context.Background(), job.ID)
+			require.NoError(t, err)
+			assert.Equal(t, BulkDeletionStateCompleted, actual.State)
+			assert.Equal(t, job.Filter, actual.Filter)
+			assert.Equal(t, []int{3, 2, 1}, []int{actual.Matched, actual.Deleted, actual.Skipped})
+		})
+
+		t.Run("case=quarantine", func(t *testing.T) {
+			quarantined := passwordIdentity("", "quarantine-"+x.NewUUID().String())
+			quarantined.Quarantine("velocity")
+			require.NoError(t, p.CreateIdentity(context.Background(), quarantined))
+
+			regular := passwordIdentity("", "quarantine-"+x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(context.Background(), regular))
+			createdIDs = append(createdIDs, quarantined.ID, regular.ID)
+
+			actual, err := p.GetIdentity(context.Background(), quarantined.ID)
+			require.NoError(t, err)
+			assert.True(t, actual.IsQuarantined())
+			assert.Equal(t, "velocity", actual.QuarantineReason)
+
+			is, err := p.ListQuarantinedIdentities(context.Background(), 0, 100)
+			require.NoError(t, err)
+			require.Len(t, is, 1)
+			assert.Equal(t, quarantined.ID, is[0].ID)
+
+			require.True(t, errors.Is(p.ReleaseIdentityFromQuarantine(context.Background(), regular.ID), sqlcon.ErrNoRows))
+			require.NoError(t, p.ReleaseIdentityFromQuarantine(context.Background(), quarantined.ID))
+			require.True(t, errors.Is(p.ReleaseIdentityFromQuarantine(context.Background(), quarantined.ID), sqlcon.ErrNoRows))
+
+			actual, err = p.GetIdentity(context.Background(), quarantined.ID)
+			require.NoError(t, err)
+			assert.False(t, actual.IsQuarantined())
+			assert.Empty(t, actual.QuarantineReason)
+
+			is, err = p.ListQuarantinedIdentities(context.Background(), 0, 100)
+			require.NoError(t, err)
+			assert.Len(t, is, 0)
+		})
+
+		t.Run("case=state", func(t *testing.T) {
+			expected := passwordIdentity("", "state-"+x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(context.Background(), expected))
+			createdIDs = append(createdIDs, expected.ID)
+
+			actual, err := p.GetIdentity(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.Equal(t, StateActive, actual.State)
+
+			require.NoError(t, p.UpdateIdentityState(context.Background(), expected.ID, StateInactive))
+			actual, err = p.GetIdentity(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.Equal(t, StateInactive, actual.State)
+			assert.False(t, actual.IsActive())
+
+			require.True(t, errors.Is(p.UpdateIdentityState(context.Background(), x.NewUUID(), StateInactive), sqlcon.ErrNoRows))
+		})
+
+		t.Run("case=expiry", func(t *testing.T) {
+			expected := passwordIdentity("", "expiry-"+x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(context.Background(), expected))
+			createdIDs = append(createdIDs, expected.ID)
+
+			actual, err := p.GetIdentity(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.Nil(t, actual.ExpiresAt)
+			assert.False(t, actual.IsExpired(time.Now()))
+
+			expiresAt := time.Now().UTC().Add(-time.Minute).Round(time.Second)
+			require.NoError(t, p.UpdateIdentityExpiry(context.Background(), expected.ID, &expiresAt))
+			actual, err = p.GetIdentity(context.Background(), expected.ID)
+			require.NoError(t, err)
+			require.NotNil(t, actual.ExpiresAt)
+			assert.Equal(t, expiresAt.Unix(), actual.ExpiresAt.Unix())
+			assert.True(t, actual.IsExpired(time.Now()))
+
+			require.NoError(t, p.UpdateIdentityExpiry(context.Background(), expected.ID, nil))
+			actual, err = p.GetIdentity(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.Nil(t, actual.ExpiresAt)
+
+			require.True(t, errors.Is(p.UpdateIdentityExpiry(context.Background(), x.NewUUID(), nil), sqlcon.ErrNoRows))
+		})
+
+		t.Run("case=soft delete", func(t *testing.T) {
+			identifier := "soft-delete-" + x.NewUUID().String()
+			expected := passwordIdentity("", identifier)
+			require.NoError(t, p.CreateIdentity(context.Background(), expected))
+
+			count, err := p.CountIdentities(context.Background(), ListIdentitiesFilter{})
+			require.NoError(t, err)
+
+			require.NoError(t, p.SoftDeleteIdentity(context.Background(), expected.ID))
+			require.True(t, errors.Is(p.SoftDeleteIdentity(context.Background(), expected.ID), sqlcon.ErrNoRows))
+
+			_, err = p.GetIdentity(context.Background(), expected.ID)
+			require.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+			_, _, err = p.FindByCredentialsIdentifier(context.Background(), CredentialsTypePassword, identifier)
+			require.Error(t, err)
+			actual, err := p.CountIdentities(context.Background(), ListIdentitiesFilter{})
+			require.NoError(t, err)
+			assert.Equal(t, count-1, actual)
+
+			require.NoError(t, p.RestoreIdentity(context.Background(), expected.ID))
+			require.True(t, errors.Is(p.RestoreIdentity(context.Background(), expected.ID), sqlcon.ErrNoRows))
+			_, creds, err := p.FindByCredentialsIdentifier(context.Background(), CredentialsTypePassword, identifier)
+			require.NoError(t, err, "credentials must be kept")
+			assert.Equal(t, []string{identifier}, creds.Identifiers)
+
+			require.NoError(t, p.SoftDeleteIdentity(context.Background(), expected.ID))
+			purged, err := p.PurgeDeletedIdentities(context.Background(), time.Now().Add(-time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, 0, purged, "identities within their retention must be kept")
+
+			purged, err = p.PurgeDeletedIdentities(context.Background(), time.Now().Add(time.Minute))
+			require.NoError(t, err)
+			assert.Equal(t, 1, purged)
+			require.True(t, errors.Is(p.RestoreIdentity(context.Background(), expected.ID), sqlcon.ErrNoRows))
+		})
+
+		t.Run("case=legal hold", func(t *testing.T) {
+			expected := passwordIdentity("", "legal-hold-"+x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(context.Background(), expected))
+
+			require.NoError(t, p.PlaceIdentityOnLegalHold(context.Background(), expected.ID, "case 42"))
+			actual, err := p.GetIdentityConfidential(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.True(t, actual.IsUnderLegalHold())
+			assert.Equal(t, "case 42", actual.LegalHoldReason)
+
+			require.NoError(t, p.UpdateIdentity(context.Background(), expected))
+			actual, err = p.GetIdentityConfidential(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.True(t, actual.IsUnderLegalHold(), "updates must not release the legal hold")
+
+			require.True(t, errors.Is(p.DeleteIdentity(context.Background(), expected.ID), ErrLegalHold))
+			require.True(t, errors.Is(p.SoftDeleteIdentity(context.Background(), expected.ID), ErrLegalHold))
+
+			require.NoError(t, p.ReleaseIdentityFromLegalHold(context.Background(), expected.ID))
+			require.True(t, errors.Is(p.ReleaseIdentityFromLegalHold(context.Background(), expected.ID), sqlcon.ErrNoRows))
+
+			require.NoError(t, p.SoftDeleteIdentity(context.Background(), expected.ID))
+			require.NoError(t, p.PlaceIdentityOnLegalHold(context.Background(), expected.ID, "case 43"))
+			_, err = p.PurgeDeletedIdentities(context.Background(), time.Now().Add(time.Minute))
+			require.NoError(t, err)
+			require.NoError(t, p.RestoreIdentity(context.Background(), expected.ID), "identities under legal hold must not be purged")
+
+			require.NoError(t, p.ReleaseIdentityFromLegalHold(context.Background(), expected.ID))
+			require.NoError(t, p.DeleteIdentity(context.Background(), expected.ID))
+
+			require.True(t, errors.Is(p.PlaceIdentityOnLegalHold(context.Background(), x.NewUUID(), "case 44"), sqlcon.ErrNoRows))
+			require.True(t, errors.Is(p.DeleteIdentity(context.Background(), x.NewUUID()), sqlcon.ErrNoRows))
+		})
+
+		t.Run("case=delete identities", func(t *testing.T) {
+			deleted := passwordIdentity("", "bulk-delete-"+x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(context.Background(), deleted))
+			held := passwordIdentity("", "bulk-delete-"+x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(context.Background(), held))
+			require.NoError(t, p.PlaceIdentityOnLegalHold(context.Background(), held.ID, "case 45"))
+
+			actualDeleted, actualHeld, err := p.DeleteIdentities(context.Background(), []uuid.UUID{deleted.ID, held.ID, x.NewUUID()})
+			require.NoError(t, err)
+			assert.Equal(t, []uuid.UUID{deleted.ID}, actualDeleted)
+			assert.Equal(t, []uuid.UUID{held.ID}, actualHeld)
+
+			_, err = p.GetIdentity(context.Background(), deleted.ID)
+			require.True(t, errors.Is(err, sqlcon.ErrNoRows))
+			_, err = p.GetIdentity(context.Background(), held.ID)
+			require.NoError(t, err)
+
+			actualDeleted, actualHeld, err = p.DeleteIdentities(context.Background(), nil)
+			require.NoError(t, err)
+			assert.Empty(t, actualDeleted)
+			assert.Empty(t, actualHeld)
+
+			require.NoError(t, p.ReleaseIdentityFromLegalHold(context.Background(), held.ID))
+			require.NoError(t, p.DeleteIdentity(context.Background(), held.ID))
+		})
+
+		t.Run("case=delete credentials", func(t *testing.T) {
+			expected := passwordIdentity("", "delete-credentials-"+x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(context.Background(), expected))
+
+			require.True(t, errors.Is(p.DeleteIdentityCredentials(context.Background(), expected.ID, CredentialsTypeOIDC), sqlcon.ErrNoRows))
+			require.NoError(t, p.DeleteIdentityCredentials(context.Background(), expected.ID, CredentialsTypePassword))
+			require.True(t, errors.Is(p.DeleteIdentityCredentials(context.Background(), expected.ID, CredentialsTypePassword), sqlcon.ErrNoRows))
+
+			actual, err := p.GetIdentityConfidential(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.Empty(t, actual.Credentials)
+
+			_, _, err = p.FindByCredentialsIdentifier(context.Background(), CredentialsTypePassword, expected.Credentials[CredentialsTypePassword].Identifiers[0])
+			require.Error(t, err)
+
+			require.True(t, errors.Is(p.DeleteIdentityCredentials(context.Background(), x.NewUUID(), CredentialsTypePassword), sqlcon.ErrNoRows))
+			require.NoError(t, p.DeleteIdentity(context.Background(), expected.ID))
+		})
+
+		t.Run("case=labels", func(t *testing.T) {
+			expected := passwordIdentity("", "labels-"+x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(context.Background(), expected))
+			createdIDs = append(createdIDs, expected.ID)
+
+			require.NoError(t, p.SetIdentityLabel(context.Background(), expected.ID, "cohort", "alpha"))
+			require.NoError(t, p.SetIdentityLabel(context.Background(), expected.ID, "cohort", "beta"))
+			require.NoError(t, p.SetIdentityLabel(context.Background(), expected.ID, "migrated-from", "legacy"))
+
+			actual, err := p.GetIdentity(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{"cohort": "beta", "migrated-from": "legacy"}, actual.Labels.Map())
+
+			require.NoError(t, p.UpdateIdentity(context.Background(), expected))
+			actual, err = p.GetIdentityConfidential(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.Len(t, actual.Labels, 2, "updates must keep the labels")
+
+			is, err := p.ListIdentities(context.Background(), ListIdentitiesFilter{Labels: []LabelFilter{{Name: "cohort", Value: "beta"}, {Name: "migrated-from", Value: "legacy"}}}, 0, 25)
+			require.NoError(t, err)
+			require.Len(t, is, 1)
+			assert.Equal(t, expected.ID, is[0].ID)
+			assert.Len(t, is[0].Labels, 2)
+
+			count, err := p.CountIdentities(context.Background(), ListIdentitiesFilter{Labels: []LabelFilter{{Name: "cohort", Value: "alpha"}}})
+			require.NoError(t, err)
+			assert.EqualValues(t, 0, count)
+
+			require.NoError(t, p.DeleteIdentityLabel(context.Background(), expected.ID, "cohort"))
+			require.True(t, errors.Is(p.DeleteIdentityLabel(context.Background(), expected.ID, "cohort"), sqlcon.ErrNoRows))
+			actual, err = p.GetIdentity(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{"migrated-from": "legacy"}, actual.Labels.Map())
+
+			require.True(t, errors.Is(p.SetIdentityLabel(context.Background(), x.NewUUID(), "cohort", "beta"), sqlcon.ErrNoRows))
+		})
+
+		t.Run("case=public metadata", func(t *testing.T) {
+			expected := passwordIdentity("", "metadata-"+x.NewUUID().String())
+			expected.MetadataPublic = Metadata(`{"groups":["admin"]}`)
+			require.NoError(t, p.CreateIdentity(context.Background(), expected))
+			createdIDs = append(createdIDs, expected.ID)
+
+			actual, err := p.GetIdentity(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"groups":["admin"]}`, string(actual.MetadataPublic))
+
+			expected.MetadataPublic = nil
+			require.NoError(t, p.UpdateIdentity(context.Background(), expected))
+			actual, err = p.GetIdentity(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.Empty(t, actual.MetadataPublic)
+		})
+
+		t.Run("case=admin metadata", func(t *testing.T) {
+			expected := passwordIdentity("", "metadata-admin-"+x.NewUUID().String())
+			expected.MetadataAdmin = Metadata(`{"customer_id":"cus_1"}`)
+			require.NoError(t, p.CreateIdentity(context.Background(), expected))
+			createdIDs = append(createdIDs, expected.ID)
+
+			actual, err := p.GetIdentityConfidential(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"customer_id":"cus_1"}`, string(actual.MetadataAdmin))
+
+			expected.MetadataAdmin = Metadata(`{"customer_id":"cus_2"}`)
+			require.NoError(t, p.UpdateIdentity(context.Background(), expected))
+			actual, err = p.GetIdentity(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"customer_id":"cus_2"}`, string(actual.MetadataAdmin))
+		})
+
 		t.Run("case=should error when the identity ID does not exist", func(t *testing.T) {
 			_, err := p.GetIdentity(context.Background(), uuid.UUID{})
 			require.Error(t, err)
@@ -239,6 +708,26 @@ func TestPool(conf *config.Provider, p interface {
 			createdIDs = append(createdIDs, second.ID)
 		})
 
+		t.Run("case=create several identities at once", func(t *testing.T) {
+			first, second := oidcIdentity("", x.NewUUID().String()), oidcIdentity("", x.NewUUID().String())
+			require.NoError(t, p.CreateIdentities(context.Background(), []*Identity{first, second}))
+			createdIDs = append(createdIDs, first.ID, second.ID)
+
+			for _, expected := range []*Identity{first, second} {
+				actual, err := p.GetIdentity(context.Background(), expected.ID)
+				require.NoError(t, err)
+				assertEqual(t, expected, actual)
+			}
+
+			t.Run("case=create none if one fails", func(t *testing.T) {
+				valid, duplicate := oidcIdentity("", x.NewUUID().String()), oidcIdentity("", "oidc-1")
+				require.Error(t, p.CreateIdentities(context.Background(), []*Identity{valid, duplicate}))
+
+				_, err := p.GetIdentity(context.Background(), valid.ID)
+				require.Error(t, err)
+			})
+		})
+
 		t.Run("case=create with invalid traits data", func(t *testing.T) {
 			expected := oidcIdentity("", x.NewUUID().String())
 			expected.Traits = Traits(`{"bar":123}`) // bar should be a string
@@ -338,6 +827,37 @@ func TestPool(conf *config.Provider, p interface {
 			assert.Equal(t, expected.Credentials[CredentialsTypePassword].Identifiers, actual.Credentials[CredentialsTypePassword].Identifiers)
 		})
 
+		t.Run("case=should only update unchanged identities", func(t *testing.T) {
+			expected := passwordIdentity("", x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(context.Background(), expected))
+			t.Cleanup(func() {
+				require.NoError(t, p.DeleteIdentity(context.Background(), expected.ID))
+			})
+
+			first, err := p.GetIdentityConfidential(context.Background(), expected.ID)
+			require.NoError(t, err)
+			second, err := p.GetIdentityConfidential(context.Background(), expected.ID)
+			require.NoError(t, err)
+
+			first.Traits = Traits(`{"email":"` + x.NewUUID().String() + `"}`)
+			require.NoError(t, p.UpdateIdentityIfUnchanged(context.Background(), first, second.UpdatedAt))
+
+			second.Traits = Traits(`{"email":"` + x.NewUUID().String() + `"}`)
+			err = p.UpdateIdentityIfUnchanged(context.Background(), second, second.UpdatedAt)
+			require.True(t, errors.Is(err, ErrConcurrentUpdate), "%+v", err)
+
+			actual, err := p.GetIdentityConfidential(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(first.Traits), string(actual.Traits))
+
+			actual.Traits = second.Traits
+			require.NoError(t, p.UpdateIdentityIfUnchanged(context.Background(), actual, actual.UpdatedAt))
+
+			unknown := passwordIdentity("", x.NewUUID().String())
+			err = p.UpdateIdentityIfUnchanged(context.Background(), unknown, time.Now())
+			require.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+		})
+
 		t.Run("case=delete an identity", func(t *testing.T) {
 			expected := passwordIdentity("", x.NewUUID().String())
 			require.NoError(t, p.CreateIdentity(context.Background(), expected))
@@ -361,7 +881,7 @@ func TestPool(conf *config.Provider, p interface {
 		})
 
 		t.Run("case=list", func(t *testing.T) {
-			is, err := p.ListIdentities(context.Background(), 0, 25)
+			is, err := p.ListIdentities(context.Background(), ListIdentitiesFilter{}, 0, 25)
 			require.NoError(t, err)
 			assert.Len(t, is, len(createdIDs))
 			for _, id := range createdIDs {
@@ -375,6 +895,133 @@ func TestPool(conf *config.Provider, p interface {
 			}
 		})
 
+		t.Run("case=list and count identities matching a filter", func(t *testing.T) {
+			sales := passwordIdentity("", "filter-sales@ory.sh")
+			sales.Traits = Traits(`{"department":"sales","name":{"last":"Doe"}}`)
+			engineering := passwordIdentity("", "filter-engineering@ory.sh")
+			engineering.Traits = Traits(`{"department":"engineering","name":{"last":"Doe"}}`)
+			for _, i := range []*Identity{sales, engineering} {
+				require.NoError(t, p.CreateIdentity(context.Background(), i))
+				createdIDs = append(createdIDs, i.ID)
+			}
+			verified, unverified := true, false
+
+			for k, tc := range []struct {
+				filter   ListIdentitiesFilter
+				expected []uuid.UUID
+			}{
+				{filter: ListIdentitiesFilter{CredentialsIdentifier: "filter-sales@ory.sh"}, expected: []uuid.UUID{sales.ID}},
+				{filter: ListIdentitiesFilter{CredentialsIdentifier: "Filter-Sales@ory.sh"}, expected: []uuid.UUID{sales.ID}},
+				{filter: ListIdentitiesFilter{CredentialsIdentifier: "filter-unknown@ory.sh"}},
+				{filter: ListIdentitiesFilter{Traits: []TraitFilter{{Path: "department", Value: "sales"}}}, expected: []uuid.UUID{sales.ID}},
+				{filter: ListIdentitiesFilter{Traits: []TraitFilter{{Path: "name.last", Value: "Doe"}}}, expected: []uuid.UUID{sales.ID, engineering.ID}},
+				{filter: ListIdentitiesFilter{Traits: []TraitFilter{{Path: "name.last", Value: "Doe"}, {Path: "department", Value: "engineering"}}}, expected: []uuid.UUID{engineering.ID}},
+				{filter: ListIdentitiesFilter{CredentialsIdentifier: "filter-sales@ory.sh", Traits: []TraitFilter{{Path: "department", Value: "engineering"}}}},
+				{filter: ListIdentitiesFilter{CredentialsIdentifier: "filter-sales@ory.sh", State: StateActive}, expected: []uuid.UUID{sales.ID}},
+				{filter: ListIdentitiesFilter{CredentialsIdentifier: "filter-sales@ory.sh", State: StateInactive}},
+				{filter: ListIdentitiesFilter{Traits: []TraitFilter{{Path: "name.last", Value: "Doe"}}, SchemaID: config.DefaultIdentityTraitsSchemaID}, expected: []uuid.UUID{sales.ID, engineering.ID}},
+				{filter: ListIdentitiesFilter{Traits: []TraitFilter{{Path: "name.last", Value: "Doe"}}, SchemaID: altSchema.ID}},
+				{filter: ListIdentitiesFilter{Traits: []TraitFilter{{Path: "name.last", Value: "Doe"}}, Verified: &unverified}, expected: []uuid.UUID{sales.ID, engineering.ID}},
+				{filter: ListIdentitiesFilter{Traits: []TraitFilter{{Path: "name.last", Value: "Doe"}}, Verified: &verified}},
+			} {
+				t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+					is, err := p.ListIdentities(context.Background(), tc.filter, 0, 25)
+					require.NoError(t, err)
+
+					var actual []uuid.UUID
+					for _, i := range is {
+						actual = append(actual, i.ID)
+					}
+					assert.ElementsMatch(t, tc.expected, actual)
+
+					count, err := p.CountIdentities(context.Background(), tc.filter)
+					require.NoError(t, err)
+					assert.EqualValues(t, len(tc.expected), count)
+				})
+			}
+		})
+
+		t.Run("case=list identities using keyset pagination", func(t *testing.T) {
+			total, err := p.CountIdentities(context.Background(), ListIdentitiesFilter{})
+			require.NoError(t, err)
+			require.True(t, total > 2, "the test requires several identities")
+
+			var listed []Identity
+			var after *PageToken
+			for {
+				is, err := p.ListIdentitiesAfter(context.Background(), ListIdentitiesFilter{}, after, 2)
+				require.NoError(t, err)
+				require.True(t, len(is) <= 2)
+				if len(is) == 0 {
+					break
+				}
+
+				listed = append(listed, is...)
+				after = NewPageToken(&is[len(is)-1])
+			}
+
+			require.Len(t, listed, int(total))
+			for k := 1; k < len(listed); k++ {
+				previous, current := listed[k-1], listed[k]
+				assert.True(t, previous.CreatedAt.Before(current.CreatedAt) ||
+					(previous.CreatedAt.Equal(current.CreatedAt) && previous.ID.String() < current.ID.String()),
+					"identities must be ordered by creation time and ID")
+			}
+
+			is, err := p.ListIdentitiesAfter(context.Background(), ListIdentitiesFilter{Traits: []TraitFilter{{Path: "department", Value: "sales"}}}, nil, 10)
+			require.NoError(t, err)
+			assert.Len(t, is, 1, "filters apply to keyset pagination")
+		})
+
+		t.Run("case=stream identities in batches", func(t *testing.T) {
+			expected, err := p.ListIdentities(context.Background(), ListIdentitiesFilter{}, 0, 25)
+			require.NoError(t, err)
+			require.True(t, len(expected) > 2, "the test requires several identities")
+
+			ids, err := p.ListIdentityIDs(context.Background(), ListIdentitiesFilter{}, 0, 25)
+			require.NoError(t, err)
+			require.Len(t, ids, len(expected))
+			for k := range expected {
+				assert.Equal(t, expected[k].ID, ids[k], "the IDs are listed in the order of the identities")
+			}
+
+			var streamed []Identity
+			require.NoError(t, p.StreamIdentities(context.Background(), append(ids, x.NewUUID()), 2, func(i *Identity) error {
+				streamed = append(streamed, *i)
+				return nil
+			}))
+			require.Len(t, streamed, len(expected), "unknown IDs are skipped")
+			for k := range expected {
+				assert.Equal(t, expected[k].ID, streamed[k].ID)
+				assert.JSONEq(t, string(expected[k].Traits), string(streamed[k].Traits))
+				assert.Equal(t, expected[k].SchemaURL, streamed[k].SchemaURL)
+				assert.Len(t, streamed[k].VerifiableAddresses, len(expected[k].VerifiableAddresses))
+			}
+
+			stop := errors.New("stop")
+			var calls int
+			assert.Equal(t, stop, p.StreamIdentities(context.Background(), ids, 2, func(i *Identity) error {
+				calls++
+				return stop
+			}))
+			assert.Equal(t, 1, calls, "errors of the callback stop the stream")
+
+			keyset, err := p.ListIdentitiesAfter(context.Background(), ListIdentitiesFilter{}, nil, 3)
+			require.NoError(t, err)
+			tokens, err := p.ListIdentityPageTokensAfter(context.Background(), ListIdentitiesFilter{}, nil, 3)
+			require.NoError(t, err)
+			require.Len(t, tokens, len(keyset))
+			for k := range keyset {
+				assert.Equal(t, keyset[k].ID, tokens[k].ID)
+				assert.Equal(t, NewPageToken(&keyset[k]).Encode(), tokens[k].Encode())
+			}
+
+			tokens, err = p.ListIdentityPageTokensAfter(context.Background(), ListIdentitiesFilter{}, &tokens[0], 1)
+			require.NoError(t, err)
+			require.Len(t, tokens, 1)
+			assert.Equal(t, keyset[1].ID, tokens[0].ID)
+		})
+
 		t.Run("case=find identity by its credentials identifier", func(t *testing.T) {
 			expected := passwordIdentity("", "find-credentials-identifier@ory.sh")
 			expected.Traits = Traits(`{}`)
@@ -414,6 +1061,72 @@ func TestPool(conf *config.Provider, p interface {
 			assertEqual(t, expected, actual)
 		})
 
+		t.Run("case=merge identities", func(t *testing.T) {
+			primary := passwordIdentity("", "merge-primary@ory.sh")
+			primary.Credentials[CredentialsTypePassword] = Credentials{
+				Type: CredentialsTypePassword, Identifiers: []string{"merge-primary@ory.sh"},
+				Config: sqlxx.JSONRawMessage(`{"hashed_password":"primary"}`),
+			}
+			primary.SetCredentials(CredentialsTypeOIDC, Credentials{
+				Type: CredentialsTypeOIDC, Identifiers: []string{"github:merge-primary"},
+				Config: sqlxx.JSONRawMessage(`{"providers":[{"provider":"github","subject":"merge-primary"}]}`),
+			})
+			require.NoError(t, p.CreateIdentity(context.Background(), primary))
+
+			duplicate := passwordIdentity("", "merge-duplicate@ory.sh")
+			duplicate.Credentials[CredentialsTypePassword] = Credentials{
+				Type: CredentialsTypePassword, Identifiers: []string{"merge-duplicate@ory.sh"},
+				Config: sqlxx.JSONRawMessage(`{"hashed_password":"duplicate"}`),
+			}
+			duplicate.SetCredentials(CredentialsTypeOIDC, Credentials{
+				Type: CredentialsTypeOIDC, Identifiers: []string{"google:merge-duplicate"},
+				Config: sqlxx.JSONRawMessage(`{"providers":[{"provider":"google","subject":"merge-duplicate"}]}`),
+			})
+			duplicate.SetCredentials(CredentialsTypeSAML, Credentials{
+				Type: CredentialsTypeSAML, Identifiers: []string{"idp:merge-duplicate"},
+				Config: sqlxx.JSONRawMessage(`{}`),
+			})
+			duplicate.VerifiableAddresses = []VerifiableAddress{*NewVerifiableEmailAddress("merge-duplicate@ory.sh", duplicate.ID)}
+			duplicate.RecoveryAddresses = []RecoveryAddress{{Value: "merge-duplicate@ory.sh", Via: RecoveryAddressTypeEmail}}
+			require.NoError(t, p.CreateIdentity(context.Background(), duplicate))
+
+			_, err := p.MergeIdentities(context.Background(), primary.ID, x.NewUUID())
+			assert.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+
+			result, err := p.MergeIdentities(context.Background(), primary.ID, duplicate.ID)
+			require.NoError(t, err)
+			assert.Equal(t, []CredentialsType{CredentialsTypeSAML}, result.MovedCredentials)
+			assert.Equal(t, []CredentialsType{CredentialsTypeOIDC, CredentialsTypePassword}, result.MergedCredentials)
+			assert.Equal(t, 3, result.Identifiers)
+			assert.Equal(t, 1, result.VerifiableAddresses)
+			assert.Equal(t, 1, result.RecoveryAddresses)
+
+			_, err = p.GetIdentity(context.Background(), duplicate.ID)
+			assert.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+
+			actual, creds, err := p.FindByCredentialsIdentifier(context.Background(), CredentialsTypePassword, "merge-duplicate@ory.sh")
+			require.NoError(t, err)
+			assert.Equal(t, primary.ID, actual.ID)
+			assert.JSONEq(t, `{"hashed_password":"primary"}`, string(creds.Config), "the primary's password is kept")
+
+			actual, creds, err = p.FindByCredentialsIdentifier(context.Background(), CredentialsTypeOIDC, "google:merge-duplicate")
+			require.NoError(t, err)
+			assert.Equal(t, primary.ID, actual.ID)
+			assert.JSONEq(t, `{"providers":[{"provider":"github","subject":"merge-primary"},{"provider":"google","subject":"merge-duplicate"}]}`, string(creds.Config))
+
+			actual, _, err = p.FindByCredentialsIdentifier(context.Background(), CredentialsTypeSAML, "idp:merge-duplicate")
+			require.NoError(t, err)
+			assert.Equal(t, primary.ID, actual.ID)
+
+			address, err := p.FindVerifiableAddressByValue(context.Background(), VerifiableAddressTypeEmail, "merge-duplicate@ory.sh")
+			require.NoError(t, err)
+			assert.Equal(t, primary.ID, address.IdentityID)
+
+			recovery, err := p.FindRecoveryAddressByValue(context.Background(), RecoveryAddressTypeEmail, "merge-duplicate@ory.sh")
+			require.NoError(t, err)
+			assert.Equal(t, primary.ID, recovery.IdentityID)
+		})
+
 		t.Run("suite=verifiable-address", func(t *testing.T) {
 			createIdentityWithAddresses := func(t *testing.T, email string) VerifiableAddress {
 				var i Identity
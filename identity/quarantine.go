@@ -0,0 +1,23 @@
+package identity
+
+import (
+	"time"
+
+	"github.com/ory/herodot"
+)
+
+var ErrQuarantined = herodot.ErrForbidden.
+	WithError("identity is quarantined").
+	WithReason("This account is under review. Please verify your email address or contact support to lift this restriction.")
+
+// Quarantine marks the identity as quarantined for the given reason.
+func (i *Identity) Quarantine(reason string) {
+	now := time.Now().UTC()
+	i.QuarantinedAt = &now
+	i.QuarantineReason = reason
+}
+
+// IsQuarantined returns true if the identity is quarantined.
+func (i *Identity) IsQuarantined() bool {
+	return i.QuarantinedAt != nil
+}
@@ -0,0 +1,137 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/fetcher"
+
+	"github.com/ory/kratos/schema"
+)
+
+type (
+	// TraitsMigration migrates traits from the previous version of a schema.
+	TraitsMigration func(ctx context.Context, i *Identity) (Traits, error)
+
+	TraitsMigrationsProvider interface {
+		// TraitsMigrations returns migrations implemented in Go by schema reference, for example `customer@2`.
+		// They take precedence over the schema's Jsonnet migration.
+		TraitsMigrations() map[string]TraitsMigration
+	}
+
+	schemaMigratorDependencies interface {
+		IdentityTraitsSchemas() schema.Schemas
+		TraitsMigrationsProvider
+	}
+	SchemaMigratorProvider interface {
+		IdentitySchemaMigrator() *SchemaMigrator
+	}
+
+	// SchemaMigrator migrates the traits of identities to the latest version of their schema.
+	SchemaMigrator struct {
+		d schemaMigratorDependencies
+		f *fetcher.Fetcher
+	}
+)
+
+func NewSchemaMigrator(d schemaMigratorDependencies) *SchemaMigrator {
+	return &SchemaMigrator{d: d, f: fetcher.NewFetcher()}
+}
+
+// SchemaRef returns the reference of the schema version the identity's traits conform to, for example `customer@2`.
+func (i *Identity) SchemaRef() string {
+	version := i.SchemaVersion
+	if version < 1 {
+		version = 1
+	}
+	return i.SchemaID + schema.VersionSeparator + strconv.Itoa(version)
+}
+
+// LatestVersion returns the latest version of the identity's schema.
+func (m *SchemaMigrator) LatestVersion(i *Identity) (int, error) {
+	s, err := m.d.IdentityTraitsSchemas().GetByID(i.SchemaID)
+	if err != nil {
+		return 0, err
+	}
+	return s.GetVersion(), nil
+}
+
+// Migrate applies the migrations of all versions of the identity's schema which are higher than the identity's
+// schema version in order. Versions without a migration do not change the traits.
+func (m *SchemaMigrator) Migrate(ctx context.Context, i *Identity) error {
+	current := i.SchemaVersion
+	if current < 1 {
+		current = 1
+	}
+
+	for _, s := range m.d.IdentityTraitsSchemas().Versions(i.SchemaID) {
+		if s.GetVersion() <= current {
+			continue
+		}
+
+		traits, err := m.migrate(ctx, &s, i)
+		if err != nil {
+			return errors.WithStack(herodot.ErrInternalServerError.
+				WithReasonf(`Unable to migrate the traits of identity %s from JSON Schema "%s" to version %d.`, i.ID, i.SchemaRef(), s.GetVersion()).
+				WithDebugf("%+v", err))
+		}
+
+		i.Traits = traits
+		i.SchemaVersion = s.GetVersion()
+		current = i.SchemaVersion
+	}
+
+	if i.SchemaVersion < current {
+		i.SchemaVersion = current
+	}
+	return nil
+}
+
+func (m *SchemaMigrator) migrate(ctx context.Context, s *schema.Schema, i *Identity) (Traits, error) {
+	ref := s.ID + schema.VersionSeparator + strconv.Itoa(s.GetVersion())
+	if migration, ok := m.d.TraitsMigrations()[ref]; ok {
+		return migration(ctx, i)
+	}
+
+	if s.MigrationURL == "" {
+		return i.Traits, nil
+	}
+
+	jn, err := m.f.Fetch(s.MigrationURL)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := json.Marshal(map[string]interface{}{
+		"id":             i.ID,
+		"schema_id":      i.SchemaID,
+		"schema_version": i.SchemaVersion,
+		"traits":         json.RawMessage(i.Traits),
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtCode("identity", string(input))
+	evaluated, err := vm.EvaluateSnippet(s.MigrationURL, jn.String())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var out struct {
+		Traits json.RawMessage `json:"traits"`
+	}
+	if err := json.Unmarshal([]byte(evaluated), &out); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(out.Traits) == 0 {
+		return nil, errors.New("the migration did not return the traits in key traits")
+	}
+	return Traits(out.Traits), nil
+}
@@ -0,0 +1,121 @@
+package identity_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+)
+
+func TestSchemaMigrator(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+
+	v1 := config.SchemaConfig{ID: "customer", URL: "file://./stub/migration/customer-1.schema.json"}
+	v2 := config.SchemaConfig{ID: "customer", Version: 2, URL: "file://./stub/migration/customer-2.schema.json",
+		MigrationURL: "file://./stub/migration/customer-2.jsonnet"}
+	v3 := config.SchemaConfig{ID: "customer", Version: 3, URL: "file://./stub/migration/customer-3.schema.json"}
+
+	ctx := context.Background()
+	createV1 := func(t *testing.T) *identity.Identity {
+		conf.MustSet(config.ViperKeyIdentitySchemas, []config.SchemaConfig{v1})
+		i := identity.NewIdentity("customer")
+		i.Traits = identity.Traits(`{"name":"Jane van Doe"}`)
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+		assert.Equal(t, 1, i.SchemaVersion)
+		return i
+	}
+
+	t.Run("case=creates identities with the latest version", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyIdentitySchemas, []config.SchemaConfig{v1, v2})
+		i := identity.NewIdentity("customer")
+		i.Traits = identity.Traits(`{"first_name":"Jane","last_name":"Doe"}`)
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+		assert.Equal(t, 2, i.SchemaVersion)
+		assert.Equal(t, "customer@2", i.SchemaRef())
+	})
+
+	t.Run("case=migrates traits with jsonnet on read", func(t *testing.T) {
+		i := createV1(t)
+
+		conf.MustSet(config.ViperKeyIdentitySchemas, []config.SchemaConfig{v1, v2})
+		actual, err := reg.PrivilegedIdentityPool().GetIdentity(ctx, i.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, actual.SchemaVersion)
+		assert.JSONEq(t, `{"first_name":"Jane","last_name":"van Doe"}`, string(actual.Traits))
+
+		t.Run("case=persists the migrated traits on write", func(t *testing.T) {
+			actual.Traits = identity.Traits(`{"first_name":"Janet","last_name":"van Doe"}`)
+			require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentity(ctx, actual))
+
+			conf.MustSet(config.ViperKeyIdentitySchemas, []config.SchemaConfig{v2})
+			actual, err := reg.PrivilegedIdentityPool().GetIdentity(ctx, i.ID)
+			require.NoError(t, err)
+			assert.Equal(t, 2, actual.SchemaVersion)
+			assert.Equal(t, "Janet", gjson.GetBytes(actual.Traits, "first_name").String())
+		})
+	})
+
+	t.Run("case=migrates traits with go hooks across several versions", func(t *testing.T) {
+		i := createV1(t)
+
+		reg.WithTraitsMigrations(map[string]identity.TraitsMigration{
+			"customer@3": func(_ context.Context, i *identity.Identity) (identity.Traits, error) {
+				assert.Equal(t, 2, i.SchemaVersion, "the jsonnet migration must run first")
+				traits, err := sjson.SetBytes(i.Traits, "tier", "free")
+				return identity.Traits(traits), err
+			},
+		})
+		t.Cleanup(func() { reg.WithTraitsMigrations(nil) })
+
+		conf.MustSet(config.ViperKeyIdentitySchemas, []config.SchemaConfig{v3, v2, v1})
+		actual, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(ctx, i.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 3, actual.SchemaVersion)
+		assert.JSONEq(t, `{"first_name":"Jane","last_name":"van Doe","tier":"free"}`, string(actual.Traits))
+
+		is, err := reg.PrivilegedIdentityPool().ListIdentities(ctx, identity.ListIdentitiesFilter{}, 0, 1000)
+		require.NoError(t, err)
+		for _, listed := range is {
+			if listed.ID == i.ID {
+				assert.Equal(t, 3, listed.SchemaVersion)
+			}
+		}
+	})
+
+	t.Run("case=versions without a migration keep the traits", func(t *testing.T) {
+		i := createV1(t)
+
+		conf.MustSet(config.ViperKeyIdentitySchemas, []config.SchemaConfig{v1, {ID: "customer", Version: 2, URL: v1.URL}})
+		actual, err := reg.PrivilegedIdentityPool().GetIdentity(ctx, i.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, actual.SchemaVersion)
+		assert.JSONEq(t, string(i.Traits), string(actual.Traits))
+	})
+
+	t.Run("case=fails if the migration fails", func(t *testing.T) {
+		i := createV1(t)
+
+		reg.WithTraitsMigrations(map[string]identity.TraitsMigration{
+			"customer@2": func(context.Context, *identity.Identity) (identity.Traits, error) {
+				return nil, errors.New("not today")
+			},
+		})
+		t.Cleanup(func() { reg.WithTraitsMigrations(nil) })
+
+		conf.MustSet(config.ViperKeyIdentitySchemas, []config.SchemaConfig{v1, v2})
+		_, err := reg.PrivilegedIdentityPool().GetIdentity(ctx, i.ID)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, herodot.ErrInternalServerError), "%+v", err)
+	})
+}
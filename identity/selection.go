@@ -0,0 +1,126 @@
+package identity
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/herodot"
+)
+
+// FieldSelectionQueryParameter is the query parameter API clients use to select the identity fields included in the
+// responses of completed flows.
+const FieldSelectionQueryParameter = "fields"
+
+var (
+	// selectableFields are the identity fields which may be selected.
+	selectableFields = []string{
+		"id",
+		"schema_id",
+		"schema_url",
+		"schema_version",
+		"state",
+		"traits",
+		"verifiable_addresses",
+		"recovery_addresses",
+		"metadata_public",
+		"notification_preferences",
+	}
+
+	// selectableNestedFields are the identity fields whose keys may be selected individually, for example
+	// `traits.email`.
+	selectableNestedFields = []string{"traits", "metadata_public"}
+)
+
+// FieldSelection lists the identity fields, such as `id` or `traits.email`, to include in a response. An empty
+// selection includes all fields.
+type FieldSelection []string
+
+// ParseFieldSelection parses the comma separated `fields` query parameter of the request. It returns a bad request
+// error if a field is not selectable.
+func ParseFieldSelection(r *http.Request) (FieldSelection, error) {
+	if r.URL == nil {
+		return nil, nil
+	}
+
+	raw := r.URL.Query().Get(FieldSelectionQueryParameter)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var fields FieldSelection
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !isSelectableField(field) {
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+				`The identity field "%s" can not be selected. Selectable fields are: %s.`, field, strings.Join(selectableFields, ", ")).
+				WithDetail("selectable_fields", selectableFields).
+				WithDetail("nested_selectable_fields", selectableNestedFields))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func isSelectableField(field string) bool {
+	parts := strings.SplitN(field, ".", 2)
+	for _, f := range selectableFields {
+		if f != parts[0] {
+			continue
+		}
+		if len(parts) == 1 {
+			return true
+		}
+		for _, n := range selectableNestedFields {
+			if n == f {
+				return parts[1] != ""
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// Apply returns the JSON encoding of v in which the identities at the given paths, for example `session.identity`,
+// only contain the selected fields. It returns v unchanged if the selection is empty.
+func (s FieldSelection) Apply(v interface{}, paths ...string) (interface{}, error) {
+	if len(s) == 0 {
+		return v, nil
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for _, path := range paths {
+		i := gjson.GetBytes(out, path)
+		if !i.IsObject() {
+			continue
+		}
+
+		selected := []byte("{}")
+		for _, field := range s {
+			value := gjson.Get(i.Raw, field)
+			if !value.Exists() {
+				continue
+			}
+			if selected, err = sjson.SetRawBytes(selected, field, []byte(value.Raw)); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+
+		if out, err = sjson.SetRawBytes(out, path, selected); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return json.RawMessage(out), nil
+}
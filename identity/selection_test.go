@@ -0,0 +1,64 @@
+package identity
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+func TestParseFieldSelection(t *testing.T) {
+	for _, tc := range []struct {
+		query    string
+		expected FieldSelection
+		err      bool
+	}{
+		{query: "", expected: nil},
+		{query: "?fields=id", expected: FieldSelection{"id"}},
+		{query: "?fields=id,%20traits.email,,metadata_public.plan", expected: FieldSelection{"id", "traits.email", "metadata_public.plan"}},
+		{query: "?fields=credentials", err: true},
+		{query: "?fields=metadata_admin", err: true},
+		{query: "?fields=traits.", err: true},
+		{query: "?fields=verifiable_addresses.0", err: true},
+	} {
+		t.Run("query="+tc.query, func(t *testing.T) {
+			fields, err := ParseFieldSelection(httptest.NewRequest("POST", "/"+tc.query, nil))
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, fields)
+		})
+	}
+}
+
+func TestFieldSelectionApply(t *testing.T) {
+	i := NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	i.ID = x.NewUUID()
+	i.Traits = Traits(`{"email":"foo@ory.sh","name":"Foo"}`)
+	v := map[string]interface{}{"session": map[string]interface{}{"identity": i}, "token": "token"}
+
+	t.Run("case=returns the value unchanged without selection", func(t *testing.T) {
+		actual, err := FieldSelection(nil).Apply(v, "session.identity")
+		require.NoError(t, err)
+		assert.Equal(t, v, actual)
+	})
+
+	t.Run("case=only includes the selected fields", func(t *testing.T) {
+		actual, err := FieldSelection{"id", "traits.email", "traits.unknown"}.Apply(v, "session.identity", "identity")
+		require.NoError(t, err)
+
+		raw, err := json.Marshal(actual)
+		require.NoError(t, err)
+		assert.Equal(t, "token", gjson.GetBytes(raw, "token").String())
+		assert.JSONEq(t, `{"id":"`+i.ID.String()+`","traits":{"email":"foo@ory.sh"}}`, gjson.GetBytes(raw, "session.identity").Raw)
+		assert.False(t, gjson.GetBytes(raw, "identity").Exists())
+	})
+}
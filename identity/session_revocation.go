@@ -0,0 +1,20 @@
+package identity
+
+import (
+	"github.com/gofrs/uuid"
+)
+
+// SessionRevocationReasonIdentityDeleted is the reason reported when the sessions of an identity were removed
+// because the identity was deleted.
+const SessionRevocationReasonIdentityDeleted = "identity_deleted"
+
+type (
+	// SessionRevocationNotifier is told when the sessions of an identity were removed together with the identity,
+	// for example to notify the back-channel logout URLs.
+	SessionRevocationNotifier interface {
+		IdentitySessionsRevoked(identityID uuid.UUID, reason string)
+	}
+	SessionRevocationNotifierProvider interface {
+		SessionRevocationNotifier() SessionRevocationNotifier
+	}
+)
@@ -0,0 +1,62 @@
+package identity
+
+import (
+	"context"
+	"time"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	purgerDependencies interface {
+		PrivilegedPoolProvider
+		x.LoggingProvider
+		x.ClockProvider
+	}
+	PurgerProvider interface {
+		IdentityPurger() *Purger
+	}
+
+	// Purger permanently deletes identities which were soft-deleted longer ago than the retention.
+	Purger struct {
+		d purgerDependencies
+		c *config.Provider
+	}
+)
+
+func NewPurger(d purgerDependencies, c *config.Provider) *Purger {
+	return &Purger{d: d, c: c}
+}
+
+// Purge permanently deletes all identities past their retention.
+func (p *Purger) Purge(ctx context.Context) error {
+	deletedBefore := p.d.Clock().Now().Add(-p.c.IdentitySoftDeleteRetention())
+	count, err := p.d.PrivilegedIdentityPool().PurgeDeletedIdentities(ctx, deletedBefore)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		p.d.Audit().
+			WithField("deleted_before", deletedBefore.UTC()).
+			WithField("purged_identities", count).
+			Info("Soft-deleted identities were purged.")
+	}
+	return nil
+}
+
+// Watch purges soft-deleted identities until the context is canceled.
+func (p *Purger) Watch(ctx context.Context) {
+	for {
+		if err := p.Purge(ctx); err != nil {
+			p.d.Logger().WithError(err).Error("Unable to purge soft-deleted identities.")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.c.IdentitySoftDeletePurgeInterval()):
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package identity_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/x"
+)
+
+func TestPurger(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	clock := new(x.TestClock)
+	reg.WithClock(clock)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+	conf.MustSet(config.ViperKeyIdentitySoftDeleteRetention, "1h")
+
+	newDeleted := func(t *testing.T) *identity.Identity {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+		require.NoError(t, reg.PrivilegedIdentityPool().SoftDeleteIdentity(context.Background(), i.ID))
+		return i
+	}
+
+	recent := newDeleted(t)
+	require.NoError(t, reg.IdentityPurger().Purge(context.Background()))
+	require.NoError(t, reg.PrivilegedIdentityPool().RestoreIdentity(context.Background(), recent.ID), "identities within the retention must be kept")
+
+	old := newDeleted(t)
+	clock.Advance(2 * time.Hour)
+	require.NoError(t, reg.IdentityPurger().Purge(context.Background()))
+	assert.True(t, errors.Is(reg.PrivilegedIdentityPool().RestoreIdentity(context.Background(), old.ID), sqlcon.ErrNoRows))
+
+	_, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), recent.ID)
+	require.NoError(t, err, "identities which are not deleted must never be purged")
+}
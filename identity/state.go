@@ -0,0 +1,38 @@
+package identity
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+)
+
+var ErrInactive = herodot.ErrForbidden.
+	WithError("identity is inactive").
+	WithReason("This account was deactivated. Please contact support to reactivate it.")
+
+// State is the state of an identity.
+//
+// swagger:model identityState
+type State string
+
+const (
+	// StateActive identities can sign in.
+	StateActive State = "active"
+
+	// StateInactive identities can not sign in and their sessions are rejected.
+	StateInactive State = "inactive"
+)
+
+// Validate returns an error if the state is unknown.
+func (s State) Validate() error {
+	switch s {
+	case StateActive, StateInactive:
+		return nil
+	}
+	return errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Identity state "%s" is unknown, expected "%s" or "%s".`, s, StateActive, StateInactive))
+}
+
+// IsActive returns true unless the identity was deactivated. Identities without a state are active.
+func (i *Identity) IsActive() bool {
+	return i.State != StateInactive
+}
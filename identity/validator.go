@@ -45,11 +45,14 @@ func (v *Validator) ValidateWithRunner(i *Identity, runners ...schema.Extension)
 		return err
 	}
 
-	return v.v.Validate(s.URL.String(), traits, schema.WithExtensionRunner(runner))
+	return v.v.Validate(s.URL.String(), traits,
+		schema.WithExtensionRunner(runner),
+		schema.WithMaxPatternComplexity(v.c.IdentitySchemaValidationMaxPatternComplexity()))
 }
 
 func (v *Validator) Validate(i *Identity) error {
 	return v.ValidateWithRunner(i,
+		NewSchemaExtensionPhone(),
 		NewSchemaExtensionCredentials(i),
 		NewSchemaExtensionVerification(i, v.c.SelfServiceFlowVerificationRequestLifespan()),
 		NewSchemaExtensionRecovery(i),
@@ -0,0 +1,11 @@
+package identity
+
+import (
+	"strconv"
+)
+
+// Version returns the version of the identity as a strong HTTP entity tag. It changes whenever the identity is
+// updated and is only meaningful for identities loaded from the store.
+func (i *Identity) Version() string {
+	return strconv.Quote(strconv.FormatInt(i.UpdatedAt.UnixNano(), 10))
+}
@@ -2,11 +2,15 @@ package internal
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/gobuffalo/pop/v5"
+
 	"github.com/ory/x/configx"
 	"github.com/ory/x/dbal"
-	"github.com/ory/x/stringsx"
+	"github.com/ory/x/sqlcon"
 
 	"github.com/stretchr/testify/require"
 
@@ -41,7 +45,7 @@ func NewConfigurationWithDefaults() *config.Provider {
 
 // NewFastRegistryWithMocks returns a registry with several mocks and an SQLite in memory database that make testing
 // easier and way faster. This suite does not work for e2e or advanced integration tests.
-func NewFastRegistryWithMocks(t *testing.T) (*config.Provider, *driver.RegistryDefault) {
+func NewFastRegistryWithMocks(t testing.TB) (*config.Provider, *driver.RegistryDefault) {
 	conf, reg := NewRegistryDefaultWithDSN(t, "")
 	reg.WithCSRFTokenGenerator(x.FakeCSRFTokenGenerator)
 	reg.WithCSRFHandler(x.NewFakeCSRFHandler(""))
@@ -50,19 +54,74 @@ func NewFastRegistryWithMocks(t *testing.T) (*config.Provider, *driver.RegistryD
 			return &hook.Error{Config: c.Config}
 		},
 	})
-
-	require.NoError(t, reg.Persister().MigrateUp(context.Background()))
 	return conf, reg
 }
 
 // NewRegistryDefaultWithDSN returns a more standard registry without mocks. Good for e2e and advanced integration testing!
-func NewRegistryDefaultWithDSN(t *testing.T, dsn string) (*config.Provider, *driver.RegistryDefault) {
+//
+// If dsn is empty or the SQLite in memory DSN, the registry uses an in memory database of its own which is migrated
+// and removed once the test finishes. Registries returned by this function can therefore be used by parallel tests.
+func NewRegistryDefaultWithDSN(t testing.TB, dsn string) (*config.Provider, *driver.RegistryDefault) {
+	migrate := dsn == "" || dsn == dbal.InMemoryDSN
+	if migrate {
+		dsn = NewInMemoryDSN()
+	}
+
 	c := NewConfigurationWithDefaults()
-	c.MustSet(config.ViperKeyDSN, stringsx.Coalesce(dsn, dbal.InMemoryDSN))
+	c.MustSet(config.ViperKeyDSN, dsn)
 
 	reg, err := driver.NewRegistryFromDSN(c, logrusx.New("", ""))
 	require.NoError(t, err)
 	reg.Configuration().MustSet("dev", true)
 	require.NoError(t, reg.Init())
+	t.Cleanup(func() {
+		_ = reg.Persister().Close(context.Background())
+	})
+
+	if migrate {
+		require.NoError(t, reg.Persister().MigrateUp(context.Background()))
+	}
 	return c, reg.(*driver.RegistryDefault)
 }
+
+// NewRegistryDefaultWithIsolatedDSN is like NewRegistryDefaultWithDSN but creates a database with a random name on the
+// PostgreSQL, CockroachDB, or MySQL server dsn points to. The database is migrated and dropped once the test finishes,
+// so that tests sharing a server can run in parallel.
+func NewRegistryDefaultWithIsolatedDSN(t testing.TB, dsn string) (*config.Provider, *driver.RegistryDefault) {
+	if dsn == "" || strings.HasPrefix(dsn, "sqlite") {
+		return NewRegistryDefaultWithDSN(t, dsn)
+	}
+
+	conf, reg := NewRegistryDefaultWithDSN(t, NewIsolatedDSN(t, dsn))
+	require.NoError(t, reg.Persister().MigrateUp(context.Background()))
+	return conf, reg
+}
+
+// NewInMemoryDSN returns the DSN of a named SQLite in memory database. Contrary to dbal.InMemoryDSN, all connections
+// of a pool share the same database while no other pool sees it.
+func NewInMemoryDSN() string {
+	return fmt.Sprintf("sqlite://file:%s?mode=memory&cache=shared&_fk=true", x.NewUUID())
+}
+
+// NewIsolatedDSN creates a database with a random name on the server dsn points to and returns its DSN. The database
+// is dropped once the test finishes.
+func NewIsolatedDSN(t testing.TB, dsn string) string {
+	name := "kratos_" + strings.ReplaceAll(x.NewUUID().String(), "-", "")
+
+	c, err := pop.NewConnection(&pop.ConnectionDetails{URL: sqlcon.FinalizeDSN(logrusx.New("", ""), dsn)})
+	require.NoError(t, err)
+	require.NoError(t, c.Open())
+	require.NoError(t, c.RawQuery("CREATE DATABASE "+name).Exec())
+	t.Cleanup(func() {
+		if err := c.RawQuery("DROP DATABASE IF EXISTS " + name).Exec(); err != nil {
+			t.Logf(`Unable to drop database "%s": %s`, name, err)
+		}
+		_ = c.Close()
+	})
+
+	query := ""
+	if i := strings.Index(dsn, "?"); i >= 0 {
+		dsn, query = dsn[:i], dsn[i:]
+	}
+	return dsn[:strings.LastIndex(dsn, "/")+1] + name + query
+}
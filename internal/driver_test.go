@@ -0,0 +1,33 @@
+package internal_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+)
+
+func TestNewFastRegistryWithMocks(t *testing.T) {
+	for k := 0; k < 4; k++ {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			conf, reg := internal.NewFastRegistryWithMocks(t)
+			testhelpers.SetDefaultIdentitySchema(t, conf, "file://../identity/stub/identity.schema.json")
+
+			for i := 0; i < 10; i++ {
+				require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), identity.NewIdentity("")))
+			}
+
+			count, err := reg.IdentityPool().CountIdentities(context.Background(), identity.ListIdentitiesFilter{})
+			require.NoError(t, err)
+			assert.EqualValues(t, 10, count, "every registry has a database of its own")
+		})
+	}
+}
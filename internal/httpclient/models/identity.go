@@ -19,11 +19,21 @@ import (
 // swagger:model Identity
 type Identity struct {
 
+	// ExpiresAt is the time after which the identity can no longer sign in.
+	// Format: date-time
+	ExpiresAt *strfmt.DateTime `json:"expires_at,omitempty"`
+
 	// id
 	// Required: true
 	// Format: uuid4
 	ID UUID `json:"id"`
 
+	// MetadataAdmin contains data about the identity which is only visible to administrators.
+	MetadataAdmin interface{} `json:"metadata_admin,omitempty"`
+
+	// MetadataPublic contains data about the identity which can be read by the identity itself.
+	MetadataPublic interface{} `json:"metadata_public,omitempty"`
+
 	// RecoveryAddresses contains all the addresses that can be used to recover an identity.
 	RecoveryAddresses []*RecoveryAddress `json:"recovery_addresses,omitempty"`
 
@@ -37,6 +47,12 @@ type Identity struct {
 	// Required: true
 	SchemaURL *string `json:"schema_url"`
 
+	// SchemaVersion is the version of the JSON Schema the identity's traits conform to.
+	SchemaVersion int64 `json:"schema_version,omitempty"`
+
+	// state
+	State IdentityState `json:"state,omitempty"`
+
 	// traits
 	// Required: true
 	Traits Traits `json:"traits"`
@@ -49,6 +65,10 @@ type Identity struct {
 func (m *Identity) Validate(formats strfmt.Registry) error {
 	var res []error
 
+	if err := m.validateExpiresAt(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateID(formats); err != nil {
 		res = append(res, err)
 	}
@@ -79,6 +99,19 @@ func (m *Identity) Validate(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Identity) validateExpiresAt(formats strfmt.Registry) error {
+
+	if swag.IsZero(m.ExpiresAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("expires_at", "body", "date-time", m.ExpiresAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (m *Identity) validateID(formats strfmt.Registry) error {
 
 	if err := m.ID.Validate(formats); err != nil {
@@ -94,6 +94,7 @@ func SelfServiceHookConfigReset(t *testing.T, conf *config.Provider) func() {
 		conf.MustSet(config.ViperKeySelfServiceRegistrationAfter, nil)
 		conf.MustSet(config.ViperKeySelfServiceRegistrationBeforeHooks, nil)
 		conf.MustSet(config.ViperKeySelfServiceSettingsAfter, nil)
+		conf.MustSet(config.ViperKeySelfServiceBrowserReturnToRules, nil)
 	}
 }
 
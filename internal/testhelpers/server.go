@@ -10,6 +10,9 @@ import (
 	"github.com/ory/kratos/x"
 )
 
+// NewKratosServer starts the public and admin APIs of reg on random ports and stops them once the test finishes. Tests
+// running in parallel must each use a registry of their own, for example one returned by
+// internal.NewFastRegistryWithMocks or internal.NewRegistryDefaultWithIsolatedDSN.
 func NewKratosServer(t *testing.T, reg driver.Registry) (public, admin *httptest.Server) {
 	return NewKratosServerWithRouters(t, reg, x.NewRouterPublic(), x.NewRouterAdmin())
 }
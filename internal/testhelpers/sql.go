@@ -7,15 +7,23 @@ import (
 
 	"github.com/ory/kratos/selfservice/errorx"
 
+	"github.com/ory/kratos/configversion"
+	"github.com/ory/kratos/breakglass"
 	"github.com/ory/kratos/continuity"
 	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/growth"
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/scim"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/flow/recovery"
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/stats"
+	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/selfservice/strategy/kerberos"
 	"github.com/ory/kratos/selfservice/strategy/link"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
 	"github.com/ory/kratos/session"
 )
 
@@ -35,6 +43,8 @@ func CleanSQL(t *testing.T, c *pop.Connection) {
 
 		new(link.RecoveryToken).TableName(),
 		new(link.VerificationToken).TableName(),
+		new(code.VerificationCode).TableName(),
+		new(kerberos.UsedAuthenticator).TableName(),
 
 		new(recovery.FlowMethods).TableName(),
 		new(recovery.Flow).TableName(),
@@ -44,9 +54,19 @@ func CleanSQL(t *testing.T, c *pop.Connection) {
 
 		new(errorx.ErrorContainer).TableName(),
 
+		new(stats.Event).TableName(),
+		new(scim.Resource).TableName(),
+		new(configversion.Version).TableName(),
+		new(oidc.StoredProvider).TableName(),
+		new(breakglass.Grant).TableName(),
+		new(growth.Rollup).TableName(),
+		new(identity.BulkDeletion).TableName(),
+
+		new(session.Disclosure).TableName(),
 		new(session.Session).TableName(),
 		new(identity.CredentialIdentifierCollection).TableName(),
 		new(identity.CredentialsCollection).TableName(),
+		new(identity.Label).TableName(),
 		new(identity.VerifiableAddress).TableName(),
 		new(identity.RecoveryAddress).TableName(),
 		new(identity.Identity).TableName(),
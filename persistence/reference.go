@@ -6,16 +6,25 @@ import (
 
 	"github.com/gobuffalo/pop/v5"
 
+	"github.com/ory/kratos/apikey"
+	"github.com/ory/kratos/breakglass"
+	"github.com/ory/kratos/configversion"
 	"github.com/ory/kratos/continuity"
 	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/growth"
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/scim"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/flow/recovery"
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/stats"
+	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/selfservice/strategy/kerberos"
 	"github.com/ory/kratos/selfservice/strategy/link"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
 	"github.com/ory/kratos/session"
 )
 
@@ -36,10 +45,20 @@ type Persister interface {
 	recovery.FlowPersister
 	link.RecoveryTokenPersister
 	link.VerificationTokenPersister
+	code.VerificationCodePersister
+	kerberos.ReplayCachePersister
+	stats.Persister
+	scim.Persister
+	configversion.Persister
+	apikey.Persister
+	oidc.ProviderPersister
+	breakglass.Persister
+	growth.Persister
 
 	Close(context.Context) error
 	Ping(context.Context) error
 	MigrationStatus(c context.Context, b io.Writer) error
+	PendingMigrations(c context.Context) ([]string, error)
 	MigrateDown(c context.Context, steps int) error
 	MigrateUp(c context.Context) error
 	GetConnection(ctx context.Context) *pop.Connection
@@ -25,6 +25,7 @@ import (
 
 	"github.com/ory/kratos/driver"
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal/testhelpers"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/flow/recovery"
@@ -104,7 +105,7 @@ func TestMigrations(t *testing.T) {
 
 			t.Run("suite=fixtures", func(t *testing.T) {
 				t.Run("case=identity", func(t *testing.T) {
-					ids, err := d.PrivilegedIdentityPool().ListIdentities(context.Background(), 0, 1000)
+					ids, err := d.PrivilegedIdentityPool().ListIdentities(context.Background(), identity.ListIdentitiesFilter{}, 0, 1000)
 					require.NoError(t, err)
 
 					for _, id := range ids {
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "identities_quarantined_at_idx";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identities" DROP COLUMN "quarantine_reason";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identities" DROP COLUMN "quarantined_at";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
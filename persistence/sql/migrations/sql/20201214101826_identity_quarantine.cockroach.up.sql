@@ -0,0 +1,3 @@
+ALTER TABLE "identities" ADD COLUMN "quarantined_at" timestamp;COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identities" ADD COLUMN "quarantine_reason" VARCHAR (255) NOT NULL DEFAULT '';COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "identities_quarantined_at_idx" ON "identities" (quarantined_at);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE `identities` ADD COLUMN `quarantined_at` DATETIME;
+ALTER TABLE `identities` ADD COLUMN `quarantine_reason` VARCHAR (255) NOT NULL DEFAULT "";
+CREATE INDEX `identities_quarantined_at_idx` ON `identities` (`quarantined_at`);
\ No newline at end of file
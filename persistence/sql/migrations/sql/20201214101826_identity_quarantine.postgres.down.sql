@@ -0,0 +1,3 @@
+DROP INDEX "identities_quarantined_at_idx";
+ALTER TABLE "identities" DROP COLUMN "quarantine_reason";
+ALTER TABLE "identities" DROP COLUMN "quarantined_at";
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "selfservice_registration_flows" DROP COLUMN "flow_token_hash";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "selfservice_login_flows" DROP COLUMN "flow_token_hash";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "selfservice_login_flows" ADD COLUMN "flow_token_hash" VARCHAR (64) NOT NULL DEFAULT '';COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "selfservice_registration_flows" ADD COLUMN "flow_token_hash" VARCHAR (64) NOT NULL DEFAULT '';COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
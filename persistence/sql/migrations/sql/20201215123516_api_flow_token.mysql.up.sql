@@ -0,0 +1,2 @@
+ALTER TABLE `selfservice_login_flows` ADD COLUMN `flow_token_hash` VARCHAR (64) NOT NULL DEFAULT "";
+ALTER TABLE `selfservice_registration_flows` ADD COLUMN `flow_token_hash` VARCHAR (64) NOT NULL DEFAULT "";
\ No newline at end of file
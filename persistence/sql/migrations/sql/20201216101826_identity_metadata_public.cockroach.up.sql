@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "metadata_public" json;COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DELETE FROM identity_credential_types WHERE
+    name = 'saml';
@@ -0,0 +1,9 @@
+INSERT INTO identity_credential_types
+    (id, name)
+SELECT 'c6d8d5a6-2d4b-4c3a-9a43-9a8f6f0c1b5e',
+       'saml' WHERE NOT EXISTS
+    (
+        SELECT *
+        FROM identity_credential_types
+        WHERE name = 'saml'
+    );
@@ -0,0 +1,9 @@
+INSERT INTO identity_credential_types
+    (id, name)
+SELECT '4f0e8a3b-7d21-4c5e-b8a9-2e6d1f3c9a70',
+       'ldap' WHERE NOT EXISTS
+    (
+        SELECT *
+        FROM identity_credential_types
+        WHERE name = 'ldap'
+    );
@@ -0,0 +1 @@
+DROP TABLE "selfservice_flow_events";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE "selfservice_flow_events" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"flow_id" UUID NOT NULL,
+"flow" VARCHAR (32) NOT NULL,
+"type" VARCHAR (16) NOT NULL,
+"method" VARCHAR (32) NOT NULL DEFAULT '',
+"event" VARCHAR (16) NOT NULL,
+"created_at" timestamp NOT NULL
+);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "selfservice_flow_events_created_at_idx" ON "selfservice_flow_events" (created_at);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE "selfservice_flow_events";
\ No newline at end of file
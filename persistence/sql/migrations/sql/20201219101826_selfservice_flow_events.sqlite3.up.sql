@@ -0,0 +1,10 @@
+CREATE TABLE "selfservice_flow_events" (
+"id" TEXT PRIMARY KEY,
+"flow_id" char(36) NOT NULL,
+"flow" TEXT NOT NULL,
+"type" TEXT NOT NULL,
+"method" TEXT NOT NULL DEFAULT '',
+"event" TEXT NOT NULL,
+"created_at" DATETIME NOT NULL
+);
+CREATE INDEX "selfservice_flow_events_created_at_idx" ON "selfservice_flow_events" (created_at);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE "scim_resources";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
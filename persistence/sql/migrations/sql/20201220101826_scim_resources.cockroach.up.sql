@@ -0,0 +1,15 @@
+CREATE TABLE "scim_resources" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"identity_id" UUID,
+"resource_type" VARCHAR (16) NOT NULL,
+"external_id" VARCHAR (255) NOT NULL DEFAULT '',
+"name" VARCHAR (255) NOT NULL,
+"active" bool NOT NULL DEFAULT 'true',
+"attributes" json NOT NULL,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL,
+CONSTRAINT "scim_resources_identities_id_fk" FOREIGN KEY ("identity_id") REFERENCES "identities" ("id") ON DELETE cascade
+);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE UNIQUE INDEX "scim_resources_resource_type_name_idx" ON "scim_resources" (resource_type, name);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "scim_resources_resource_type_external_id_idx" ON "scim_resources" (resource_type, external_id);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
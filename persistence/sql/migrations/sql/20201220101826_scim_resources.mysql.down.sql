@@ -0,0 +1 @@
+DROP TABLE `scim_resources`;
\ No newline at end of file
@@ -0,0 +1,15 @@
+CREATE TABLE `scim_resources` (
+`id` char(36) NOT NULL,
+PRIMARY KEY(`id`),
+`identity_id` char(36),
+`resource_type` VARCHAR (16) NOT NULL,
+`external_id` VARCHAR (255) NOT NULL DEFAULT "",
+`name` VARCHAR (255) NOT NULL,
+`active` bool NOT NULL DEFAULT true,
+`attributes` JSON NOT NULL,
+`created_at` DATETIME NOT NULL,
+`updated_at` DATETIME NOT NULL,
+FOREIGN KEY (`identity_id`) REFERENCES `identities` (`id`) ON DELETE cascade
+) ENGINE=InnoDB;
+CREATE UNIQUE INDEX `scim_resources_resource_type_name_idx` ON `scim_resources` (`resource_type`, `name`);
+CREATE INDEX `scim_resources_resource_type_external_id_idx` ON `scim_resources` (`resource_type`, `external_id`);
\ No newline at end of file
@@ -0,0 +1,15 @@
+CREATE TABLE "scim_resources" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"identity_id" UUID,
+"resource_type" VARCHAR (16) NOT NULL,
+"external_id" VARCHAR (255) NOT NULL DEFAULT '',
+"name" VARCHAR (255) NOT NULL,
+"active" bool NOT NULL DEFAULT 'true',
+"attributes" jsonb NOT NULL,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL,
+FOREIGN KEY ("identity_id") REFERENCES "identities" ("id") ON DELETE cascade
+);
+CREATE UNIQUE INDEX "scim_resources_resource_type_name_idx" ON "scim_resources" (resource_type, name);
+CREATE INDEX "scim_resources_resource_type_external_id_idx" ON "scim_resources" (resource_type, external_id);
\ No newline at end of file
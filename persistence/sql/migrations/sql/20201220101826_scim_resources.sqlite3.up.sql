@@ -0,0 +1,14 @@
+CREATE TABLE "scim_resources" (
+"id" TEXT PRIMARY KEY,
+"identity_id" char(36),
+"resource_type" TEXT NOT NULL,
+"external_id" TEXT NOT NULL DEFAULT '',
+"name" TEXT NOT NULL,
+"active" bool NOT NULL DEFAULT 'true',
+"attributes" TEXT NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+FOREIGN KEY (identity_id) REFERENCES identities (id) ON DELETE cascade
+);
+CREATE UNIQUE INDEX "scim_resources_resource_type_name_idx" ON "scim_resources" (resource_type, name);
+CREATE INDEX "scim_resources_resource_type_external_id_idx" ON "scim_resources" (resource_type, external_id);
\ No newline at end of file
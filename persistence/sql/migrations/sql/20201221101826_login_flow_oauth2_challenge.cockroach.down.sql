@@ -0,0 +1 @@
+ALTER TABLE "selfservice_login_flows" DROP COLUMN "oauth2_login_challenge";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
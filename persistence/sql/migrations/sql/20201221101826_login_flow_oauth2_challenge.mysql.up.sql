@@ -0,0 +1 @@
+ALTER TABLE `selfservice_login_flows` ADD COLUMN `oauth2_login_challenge` VARCHAR (255);
\ No newline at end of file
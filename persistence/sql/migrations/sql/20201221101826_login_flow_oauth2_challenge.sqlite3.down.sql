@@ -0,0 +1,18 @@
+CREATE TABLE "_selfservice_login_flows_tmp" (
+"id" TEXT PRIMARY KEY,
+"request_url" TEXT NOT NULL,
+"issued_at" DATETIME NOT NULL DEFAULT 'CURRENT_TIMESTAMP',
+"expires_at" DATETIME NOT NULL,
+"active_method" TEXT NOT NULL,
+"csrf_token" TEXT NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+"forced" bool NOT NULL DEFAULT 'false',
+"messages" TEXT,
+"type" TEXT NOT NULL DEFAULT 'browser',
+"flow_token_hash" TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO "_selfservice_login_flows_tmp" (id, request_url, issued_at, expires_at, active_method, csrf_token, created_at, updated_at, forced, messages, type, flow_token_hash) SELECT id, request_url, issued_at, expires_at, active_method, csrf_token, created_at, updated_at, forced, messages, type, flow_token_hash FROM "selfservice_login_flows";
+
+DROP TABLE "selfservice_login_flows";
+ALTER TABLE "_selfservice_login_flows_tmp" RENAME TO "selfservice_login_flows";
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "selfservice_login_flows" ADD COLUMN "oauth2_login_challenge" TEXT;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "identities" DROP COLUMN "notification_preferences";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "notification_preferences" json;COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
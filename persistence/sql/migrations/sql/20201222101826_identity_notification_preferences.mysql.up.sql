@@ -0,0 +1 @@
+ALTER TABLE `identities` ADD COLUMN `notification_preferences` JSON;
\ No newline at end of file
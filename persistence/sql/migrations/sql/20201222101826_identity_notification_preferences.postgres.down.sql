@@ -0,0 +1 @@
+ALTER TABLE "identities" DROP COLUMN "notification_preferences";
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "notification_preferences" TEXT;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE "config_versions";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE "config_versions" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"hash" VARCHAR (64) NOT NULL,
+"state" VARCHAR (16) NOT NULL,
+"config" json NOT NULL,
+"activated_at" timestamp,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL
+);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE UNIQUE INDEX "config_versions_hash_idx" ON "config_versions" (hash);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "config_versions_state_idx" ON "config_versions" (state);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
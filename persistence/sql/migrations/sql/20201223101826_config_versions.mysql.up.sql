@@ -0,0 +1,12 @@
+CREATE TABLE `config_versions` (
+`id` char(36) NOT NULL,
+PRIMARY KEY(`id`),
+`hash` VARCHAR (64) NOT NULL,
+`state` VARCHAR (16) NOT NULL,
+`config` JSON NOT NULL,
+`activated_at` DATETIME,
+`created_at` DATETIME NOT NULL,
+`updated_at` DATETIME NOT NULL
+) ENGINE=InnoDB;
+CREATE UNIQUE INDEX `config_versions_hash_idx` ON `config_versions` (`hash`);
+CREATE INDEX `config_versions_state_idx` ON `config_versions` (`state`);
\ No newline at end of file
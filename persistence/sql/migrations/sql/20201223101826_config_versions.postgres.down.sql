@@ -0,0 +1 @@
+DROP TABLE "config_versions";
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE "config_versions" (
+"id" TEXT PRIMARY KEY,
+"hash" TEXT NOT NULL,
+"state" TEXT NOT NULL,
+"config" TEXT NOT NULL,
+"activated_at" DATETIME,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX "config_versions_hash_idx" ON "config_versions" (hash);
+CREATE INDEX "config_versions_state_idx" ON "config_versions" (state);
\ No newline at end of file
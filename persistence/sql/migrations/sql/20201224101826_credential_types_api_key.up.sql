@@ -0,0 +1,9 @@
+INSERT INTO identity_credential_types
+    (id, name)
+SELECT '9c2b6e4d-31f7-4a8e-a5d0-7b1e3f8c6a25',
+       'api_key' WHERE NOT EXISTS
+    (
+        SELECT *
+        FROM identity_credential_types
+        WHERE name = 'api_key'
+    );
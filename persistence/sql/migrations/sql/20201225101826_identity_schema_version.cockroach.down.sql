@@ -0,0 +1 @@
+ALTER TABLE "identities" DROP COLUMN "schema_version";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
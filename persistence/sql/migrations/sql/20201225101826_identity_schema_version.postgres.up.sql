@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "schema_version" int NOT NULL DEFAULT '1';
\ No newline at end of file
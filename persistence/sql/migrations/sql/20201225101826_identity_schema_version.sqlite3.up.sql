@@ -0,0 +1 @@
+ALTER TABLE "identities" ADD COLUMN "schema_version" INTEGER NOT NULL DEFAULT '1';
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "selfservice_registration_flows" DROP COLUMN "identity_schema_id";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
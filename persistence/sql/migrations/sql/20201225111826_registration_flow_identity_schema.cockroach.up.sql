@@ -0,0 +1 @@
+ALTER TABLE "selfservice_registration_flows" ADD COLUMN "identity_schema_id" VARCHAR (2048) NOT NULL DEFAULT '';COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
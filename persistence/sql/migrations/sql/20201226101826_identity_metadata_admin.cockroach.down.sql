@@ -0,0 +1 @@
+ALTER TABLE "identities" DROP COLUMN "metadata_admin";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,18 @@
+DROP INDEX IF EXISTS "identities_quarantined_at_idx";
+CREATE TABLE "_identities_tmp" (
+"id" TEXT PRIMARY KEY,
+"schema_id" TEXT NOT NULL,
+"traits" TEXT NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+"quarantined_at" DATETIME,
+"quarantine_reason" TEXT NOT NULL DEFAULT '',
+"metadata_public" TEXT,
+"notification_preferences" TEXT,
+"schema_version" INTEGER NOT NULL DEFAULT '1'
+);
+CREATE INDEX "identities_quarantined_at_idx" ON "_identities_tmp" (quarantined_at);
+INSERT INTO "_identities_tmp" (id, schema_id, traits, created_at, updated_at, quarantined_at, quarantine_reason, metadata_public, notification_preferences, schema_version) SELECT id, schema_id, traits, created_at, updated_at, quarantined_at, quarantine_reason, metadata_public, notification_preferences, schema_version FROM "identities";
+
+DROP TABLE "identities";
+ALTER TABLE "_identities_tmp" RENAME TO "identities";
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "identities_state_idx";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identities" DROP COLUMN "state";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "identities" ADD COLUMN "state" VARCHAR (16) NOT NULL DEFAULT 'active';COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "identities_state_idx" ON "identities" (state);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX `identities_state_idx` ON `identities`;
+ALTER TABLE `identities` DROP COLUMN `state`;
\ No newline at end of file
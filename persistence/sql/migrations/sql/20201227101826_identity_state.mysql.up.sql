@@ -0,0 +1,2 @@
+ALTER TABLE `identities` ADD COLUMN `state` VARCHAR (16) NOT NULL DEFAULT 'active';
+CREATE INDEX `identities_state_idx` ON `identities` (`state`);
\ No newline at end of file
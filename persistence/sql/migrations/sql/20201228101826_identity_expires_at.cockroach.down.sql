@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "identities_expires_at_idx";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identities" DROP COLUMN "expires_at";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
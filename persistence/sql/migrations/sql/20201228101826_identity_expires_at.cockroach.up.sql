@@ -0,0 +1,2 @@
+ALTER TABLE "identities" ADD COLUMN "expires_at" timestamp;COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "identities_expires_at_idx" ON "identities" (expires_at);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
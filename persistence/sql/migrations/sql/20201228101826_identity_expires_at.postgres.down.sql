@@ -0,0 +1,2 @@
+DROP INDEX "identities_expires_at_idx";
+ALTER TABLE "identities" DROP COLUMN "expires_at";
\ No newline at end of file
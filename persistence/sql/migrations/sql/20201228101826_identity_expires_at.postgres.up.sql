@@ -0,0 +1,2 @@
+ALTER TABLE "identities" ADD COLUMN "expires_at" timestamp;
+CREATE INDEX "identities_expires_at_idx" ON "identities" (expires_at);
\ No newline at end of file
@@ -0,0 +1,23 @@
+DROP INDEX IF EXISTS "identities_expires_at_idx";
+DROP INDEX IF EXISTS "identities_state_idx";
+DROP INDEX IF EXISTS "identities_quarantined_at_idx";
+CREATE TABLE "_identities_tmp" (
+"id" TEXT PRIMARY KEY,
+"schema_id" TEXT NOT NULL,
+"traits" TEXT NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+"quarantined_at" DATETIME,
+"quarantine_reason" TEXT NOT NULL DEFAULT '',
+"metadata_public" TEXT,
+"notification_preferences" TEXT,
+"schema_version" INTEGER NOT NULL DEFAULT '1',
+"metadata_admin" TEXT,
+"state" TEXT NOT NULL DEFAULT 'active'
+);
+CREATE INDEX "identities_state_idx" ON "_identities_tmp" (state);
+CREATE INDEX "identities_quarantined_at_idx" ON "_identities_tmp" (quarantined_at);
+INSERT INTO "_identities_tmp" (id, schema_id, traits, created_at, updated_at, quarantined_at, quarantine_reason, metadata_public, notification_preferences, schema_version, metadata_admin, state) SELECT id, schema_id, traits, created_at, updated_at, quarantined_at, quarantine_reason, metadata_public, notification_preferences, schema_version, metadata_admin, state FROM "identities";
+
+DROP TABLE "identities";
+ALTER TABLE "_identities_tmp" RENAME TO "identities";
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "identities" ADD COLUMN "deleted_at" timestamp;COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "identities_deleted_at_idx" ON "identities" (deleted_at);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
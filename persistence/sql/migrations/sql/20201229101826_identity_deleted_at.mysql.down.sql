@@ -0,0 +1,2 @@
+DROP INDEX `identities_deleted_at_idx` ON `identities`;
+ALTER TABLE `identities` DROP COLUMN `deleted_at`;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "identities" ADD COLUMN "deleted_at" DATETIME;
+CREATE INDEX "identities_deleted_at_idx" ON "identities" (deleted_at);
\ No newline at end of file
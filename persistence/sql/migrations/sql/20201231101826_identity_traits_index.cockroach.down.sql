@@ -0,0 +1 @@
+DROP INDEX identities@identities_traits_idx;
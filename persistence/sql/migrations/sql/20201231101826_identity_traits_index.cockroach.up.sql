@@ -0,0 +1 @@
+CREATE INVERTED INDEX identities_traits_idx ON identities (traits);
@@ -0,0 +1 @@
+DROP INDEX identities_traits_idx;
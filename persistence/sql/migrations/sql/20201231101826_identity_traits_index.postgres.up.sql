@@ -0,0 +1 @@
+CREATE INDEX identities_traits_idx ON identities USING GIN (traits);
@@ -0,0 +1 @@
+CREATE INDEX "identities_created_at_id_idx" ON "identities" (created_at, id);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP INDEX `identities_created_at_id_idx` ON `identities`;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "identities_created_at_id_idx";
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE "session_disclosures";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE "session_disclosures" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"token" VARCHAR (64) NOT NULL,
+"session_id" UUID NOT NULL,
+"claims" json NOT NULL,
+"expires_at" timestamp NOT NULL,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL,
+CONSTRAINT "session_disclosures_sessions_id_fk" FOREIGN KEY ("session_id") REFERENCES "sessions" ("id") ON DELETE cascade
+);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE UNIQUE INDEX "session_disclosures_token_idx" ON "session_disclosures" (token);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "session_disclosures_expires_at_idx" ON "session_disclosures" (expires_at);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE `session_disclosures` (
+`id` char(36) NOT NULL,
+PRIMARY KEY(`id`),
+`token` VARCHAR (64) NOT NULL,
+`session_id` char(36) NOT NULL,
+`claims` JSON NOT NULL,
+`expires_at` DATETIME NOT NULL,
+`created_at` DATETIME NOT NULL,
+`updated_at` DATETIME NOT NULL,
+FOREIGN KEY (`session_id`) REFERENCES `sessions` (`id`) ON DELETE cascade
+) ENGINE=InnoDB;
+CREATE UNIQUE INDEX `session_disclosures_token_idx` ON `session_disclosures` (`token`);
+CREATE INDEX `session_disclosures_expires_at_idx` ON `session_disclosures` (`expires_at`);
\ No newline at end of file
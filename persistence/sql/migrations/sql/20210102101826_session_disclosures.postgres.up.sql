@@ -0,0 +1,13 @@
+CREATE TABLE "session_disclosures" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"token" VARCHAR (64) NOT NULL,
+"session_id" UUID NOT NULL,
+"claims" jsonb NOT NULL,
+"expires_at" timestamp NOT NULL,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL,
+FOREIGN KEY ("session_id") REFERENCES "sessions" ("id") ON DELETE cascade
+);
+CREATE UNIQUE INDEX "session_disclosures_token_idx" ON "session_disclosures" (token);
+CREATE INDEX "session_disclosures_expires_at_idx" ON "session_disclosures" (expires_at);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE "session_disclosures";
\ No newline at end of file
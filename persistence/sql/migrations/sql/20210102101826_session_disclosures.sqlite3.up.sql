@@ -0,0 +1,12 @@
+CREATE TABLE "session_disclosures" (
+"id" TEXT PRIMARY KEY,
+"token" TEXT NOT NULL,
+"session_id" char(36) NOT NULL,
+"claims" TEXT NOT NULL,
+"expires_at" DATETIME NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE cascade
+);
+CREATE UNIQUE INDEX "session_disclosures_token_idx" ON "session_disclosures" (token);
+CREATE INDEX "session_disclosures_expires_at_idx" ON "session_disclosures" (expires_at);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "sessions" ADD COLUMN "impersonated_by" VARCHAR (255);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE `sessions` ADD COLUMN `impersonated_by` VARCHAR (255);
\ No newline at end of file
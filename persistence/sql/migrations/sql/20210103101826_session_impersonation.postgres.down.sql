@@ -0,0 +1 @@
+ALTER TABLE "sessions" DROP COLUMN "impersonated_by";
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "sessions" ADD COLUMN "impersonated_by" TEXT;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "identities" DROP COLUMN "legal_hold_reason";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identities" DROP COLUMN "legal_hold_at";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
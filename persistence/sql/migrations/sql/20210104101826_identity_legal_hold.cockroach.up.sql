@@ -0,0 +1,2 @@
+ALTER TABLE "identities" ADD COLUMN "legal_hold_at" timestamp;COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "identities" ADD COLUMN "legal_hold_reason" VARCHAR (255) NOT NULL DEFAULT '';COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE `identities` ADD COLUMN `legal_hold_at` DATETIME;
+ALTER TABLE `identities` ADD COLUMN `legal_hold_reason` VARCHAR (255) NOT NULL DEFAULT "";
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "identities" DROP COLUMN "legal_hold_reason";
+ALTER TABLE "identities" DROP COLUMN "legal_hold_at";
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE "identity_labels";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE "identity_labels" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"identity_id" UUID NOT NULL,
+"name" VARCHAR (64) NOT NULL,
+"value" VARCHAR (255) NOT NULL,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL,
+CONSTRAINT "identity_labels_identities_id_fk" FOREIGN KEY ("identity_id") REFERENCES "identities" ("id") ON DELETE cascade
+);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE UNIQUE INDEX "identity_labels_identity_id_name_idx" ON "identity_labels" (identity_id, name);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "identity_labels_name_value_idx" ON "identity_labels" (name, value);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE `identity_labels` (
+`id` char(36) NOT NULL,
+PRIMARY KEY(`id`),
+`identity_id` char(36) NOT NULL,
+`name` VARCHAR (64) NOT NULL,
+`value` VARCHAR (255) NOT NULL,
+`created_at` DATETIME NOT NULL,
+`updated_at` DATETIME NOT NULL,
+FOREIGN KEY (`identity_id`) REFERENCES `identities` (`id`) ON DELETE cascade
+) ENGINE=InnoDB;
+CREATE UNIQUE INDEX `identity_labels_identity_id_name_idx` ON `identity_labels` (`identity_id`, `name`);
+CREATE INDEX `identity_labels_name_value_idx` ON `identity_labels` (`name`, `value`);
\ No newline at end of file
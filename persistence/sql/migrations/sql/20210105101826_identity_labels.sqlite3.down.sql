@@ -0,0 +1 @@
+DROP TABLE "identity_labels";
\ No newline at end of file
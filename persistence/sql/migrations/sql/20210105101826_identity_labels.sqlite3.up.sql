@@ -0,0 +1,11 @@
+CREATE TABLE "identity_labels" (
+"id" TEXT PRIMARY KEY,
+"identity_id" char(36) NOT NULL,
+"name" TEXT NOT NULL,
+"value" TEXT NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+FOREIGN KEY (identity_id) REFERENCES identities (id) ON DELETE cascade
+);
+CREATE UNIQUE INDEX "identity_labels_identity_id_name_idx" ON "identity_labels" (identity_id, name);
+CREATE INDEX "identity_labels_name_value_idx" ON "identity_labels" (name, value);
\ No newline at end of file
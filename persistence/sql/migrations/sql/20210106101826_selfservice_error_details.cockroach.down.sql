@@ -0,0 +1,4 @@
+ALTER TABLE "selfservice_errors" DROP COLUMN "actions";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "selfservice_errors" DROP COLUMN "description";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "selfservice_errors" DROP COLUMN "title";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "selfservice_errors" DROP COLUMN "flow";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE "selfservice_errors" ADD COLUMN "flow" VARCHAR (32) NOT NULL DEFAULT '';COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "selfservice_errors" ADD COLUMN "title" json;COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "selfservice_errors" ADD COLUMN "description" json;COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "selfservice_errors" ADD COLUMN "actions" json;COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
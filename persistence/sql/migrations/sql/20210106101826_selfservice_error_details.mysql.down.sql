@@ -0,0 +1,4 @@
+ALTER TABLE `selfservice_errors` DROP COLUMN `actions`;
+ALTER TABLE `selfservice_errors` DROP COLUMN `description`;
+ALTER TABLE `selfservice_errors` DROP COLUMN `title`;
+ALTER TABLE `selfservice_errors` DROP COLUMN `flow`;
\ No newline at end of file
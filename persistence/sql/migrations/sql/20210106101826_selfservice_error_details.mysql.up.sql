@@ -0,0 +1,4 @@
+ALTER TABLE `selfservice_errors` ADD COLUMN `flow` VARCHAR (32) NOT NULL DEFAULT "";
+ALTER TABLE `selfservice_errors` ADD COLUMN `title` JSON;
+ALTER TABLE `selfservice_errors` ADD COLUMN `description` JSON;
+ALTER TABLE `selfservice_errors` ADD COLUMN `actions` JSON;
\ No newline at end of file
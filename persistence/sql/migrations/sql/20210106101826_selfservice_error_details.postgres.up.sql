@@ -0,0 +1,4 @@
+ALTER TABLE "selfservice_errors" ADD COLUMN "flow" VARCHAR (32) NOT NULL DEFAULT '';
+ALTER TABLE "selfservice_errors" ADD COLUMN "title" jsonb;
+ALTER TABLE "selfservice_errors" ADD COLUMN "description" jsonb;
+ALTER TABLE "selfservice_errors" ADD COLUMN "actions" jsonb;
\ No newline at end of file
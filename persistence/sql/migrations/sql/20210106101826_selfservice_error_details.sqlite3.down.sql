@@ -0,0 +1,58 @@
+CREATE TABLE "_selfservice_errors_tmp" (
+"id" TEXT PRIMARY KEY,
+"errors" TEXT NOT NULL,
+"seen_at" DATETIME,
+"was_seen" bool NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+"csrf_token" TEXT NOT NULL DEFAULT '',
+"flow" TEXT NOT NULL DEFAULT '',
+"title" TEXT,
+"description" TEXT
+);
+INSERT INTO "_selfservice_errors_tmp" (id, errors, seen_at, was_seen, created_at, updated_at, csrf_token, flow, title, description) SELECT id, errors, seen_at, was_seen, created_at, updated_at, csrf_token, flow, title, description FROM "selfservice_errors";
+
+DROP TABLE "selfservice_errors";
+ALTER TABLE "_selfservice_errors_tmp" RENAME TO "selfservice_errors";
+CREATE TABLE "_selfservice_errors_tmp" (
+"id" TEXT PRIMARY KEY,
+"errors" TEXT NOT NULL,
+"seen_at" DATETIME,
+"was_seen" bool NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+"csrf_token" TEXT NOT NULL DEFAULT '',
+"flow" TEXT NOT NULL DEFAULT '',
+"title" TEXT
+);
+INSERT INTO "_selfservice_errors_tmp" (id, errors, seen_at, was_seen, created_at, updated_at, csrf_token, flow, title) SELECT id, errors, seen_at, was_seen, created_at, updated_at, csrf_token, flow, title FROM "selfservice_errors";
+
+DROP TABLE "selfservice_errors";
+ALTER TABLE "_selfservice_errors_tmp" RENAME TO "selfservice_errors";
+CREATE TABLE "_selfservice_errors_tmp" (
+"id" TEXT PRIMARY KEY,
+"errors" TEXT NOT NULL,
+"seen_at" DATETIME,
+"was_seen" bool NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+"csrf_token" TEXT NOT NULL DEFAULT '',
+"flow" TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO "_selfservice_errors_tmp" (id, errors, seen_at, was_seen, created_at, updated_at, csrf_token, flow) SELECT id, errors, seen_at, was_seen, created_at, updated_at, csrf_token, flow FROM "selfservice_errors";
+
+DROP TABLE "selfservice_errors";
+ALTER TABLE "_selfservice_errors_tmp" RENAME TO "selfservice_errors";
+CREATE TABLE "_selfservice_errors_tmp" (
+"id" TEXT PRIMARY KEY,
+"errors" TEXT NOT NULL,
+"seen_at" DATETIME,
+"was_seen" bool NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+"csrf_token" TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO "_selfservice_errors_tmp" (id, errors, seen_at, was_seen, created_at, updated_at, csrf_token) SELECT id, errors, seen_at, was_seen, created_at, updated_at, csrf_token FROM "selfservice_errors";
+
+DROP TABLE "selfservice_errors";
+ALTER TABLE "_selfservice_errors_tmp" RENAME TO "selfservice_errors";
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE "selfservice_errors" ADD COLUMN "flow" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "selfservice_errors" ADD COLUMN "title" TEXT;
+ALTER TABLE "selfservice_errors" ADD COLUMN "description" TEXT;
+ALTER TABLE "selfservice_errors" ADD COLUMN "actions" TEXT;
\ No newline at end of file
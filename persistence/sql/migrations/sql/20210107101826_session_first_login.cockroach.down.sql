@@ -0,0 +1,2 @@
+ALTER TABLE "sessions" DROP COLUMN "just_registered";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "sessions" DROP COLUMN "first_time_login";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
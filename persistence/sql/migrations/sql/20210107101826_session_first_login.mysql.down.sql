@@ -0,0 +1,2 @@
+ALTER TABLE `sessions` DROP COLUMN `just_registered`;
+ALTER TABLE `sessions` DROP COLUMN `first_time_login`;
\ No newline at end of file
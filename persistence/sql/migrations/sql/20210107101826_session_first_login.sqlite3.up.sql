@@ -0,0 +1,2 @@
+ALTER TABLE "sessions" ADD COLUMN "first_time_login" bool NOT NULL DEFAULT 'false';
+ALTER TABLE "sessions" ADD COLUMN "just_registered" bool NOT NULL DEFAULT 'false';
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "sessions_issued_at_idx";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "sessions" DROP COLUMN "ip_address";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "sessions" ADD COLUMN "ip_address" VARCHAR (64);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "sessions_issued_at_idx" ON "sessions" (issued_at);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX "sessions_issued_at_idx";
+ALTER TABLE "sessions" DROP COLUMN "ip_address";
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "sessions" ADD COLUMN "ip_address" VARCHAR (64);
+CREATE INDEX "sessions_issued_at_idx" ON "sessions" (issued_at);
\ No newline at end of file
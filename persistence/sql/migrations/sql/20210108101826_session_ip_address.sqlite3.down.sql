@@ -0,0 +1,24 @@
+DROP INDEX IF EXISTS "sessions_issued_at_idx";
+DROP INDEX IF EXISTS "sessions_token_idx";
+DROP INDEX IF EXISTS "sessions_token_uq_idx";
+CREATE TABLE "_sessions_tmp" (
+"id" TEXT PRIMARY KEY,
+"issued_at" DATETIME NOT NULL DEFAULT 'CURRENT_TIMESTAMP',
+"expires_at" DATETIME NOT NULL,
+"authenticated_at" DATETIME NOT NULL,
+"identity_id" char(36) NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+"token" TEXT,
+"active" NUMERIC DEFAULT 'false',
+"impersonated_by" TEXT,
+"first_time_login" bool NOT NULL DEFAULT 'false',
+"just_registered" bool NOT NULL DEFAULT 'false',
+FOREIGN KEY (identity_id) REFERENCES identities (id) ON UPDATE NO ACTION ON DELETE CASCADE
+);
+CREATE INDEX "sessions_token_idx" ON "_sessions_tmp" (token);
+CREATE UNIQUE INDEX "sessions_token_uq_idx" ON "_sessions_tmp" (token);
+INSERT INTO "_sessions_tmp" (id, issued_at, expires_at, authenticated_at, identity_id, created_at, updated_at, token, active, impersonated_by, first_time_login, just_registered) SELECT id, issued_at, expires_at, authenticated_at, identity_id, created_at, updated_at, token, active, impersonated_by, first_time_login, just_registered FROM "sessions";
+
+DROP TABLE "sessions";
+ALTER TABLE "_sessions_tmp" RENAME TO "sessions";
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE "sessions" DROP COLUMN "last_active_at";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "sessions" DROP COLUMN "location";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "sessions" DROP COLUMN "user_agent";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
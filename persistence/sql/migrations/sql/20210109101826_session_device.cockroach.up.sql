@@ -0,0 +1,3 @@
+ALTER TABLE "sessions" ADD COLUMN "user_agent" VARCHAR (512);COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "sessions" ADD COLUMN "location" VARCHAR (255);COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "sessions" ADD COLUMN "last_active_at" timestamp;COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE `sessions` DROP COLUMN `last_active_at`;
+ALTER TABLE `sessions` DROP COLUMN `location`;
+ALTER TABLE `sessions` DROP COLUMN `user_agent`;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE "sessions" ADD COLUMN "user_agent" VARCHAR (512);
+ALTER TABLE "sessions" ADD COLUMN "location" VARCHAR (255);
+ALTER TABLE "sessions" ADD COLUMN "last_active_at" timestamp;
\ No newline at end of file
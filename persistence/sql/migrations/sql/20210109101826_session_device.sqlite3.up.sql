@@ -0,0 +1,3 @@
+ALTER TABLE "sessions" ADD COLUMN "user_agent" TEXT;
+ALTER TABLE "sessions" ADD COLUMN "location" TEXT;
+ALTER TABLE "sessions" ADD COLUMN "last_active_at" DATETIME;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE "sessions" DROP COLUMN "device_type";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "sessions" DROP COLUMN "operating_system";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "sessions" DROP COLUMN "browser";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
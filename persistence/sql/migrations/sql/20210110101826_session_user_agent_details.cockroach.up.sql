@@ -0,0 +1,3 @@
+ALTER TABLE "sessions" ADD COLUMN "browser" VARCHAR (64);COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "sessions" ADD COLUMN "operating_system" VARCHAR (64);COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "sessions" ADD COLUMN "device_type" VARCHAR (32);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE `sessions` DROP COLUMN `device_type`;
+ALTER TABLE `sessions` DROP COLUMN `operating_system`;
+ALTER TABLE `sessions` DROP COLUMN `browser`;
\ No newline at end of file
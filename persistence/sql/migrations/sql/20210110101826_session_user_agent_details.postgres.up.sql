@@ -0,0 +1,3 @@
+ALTER TABLE "sessions" ADD COLUMN "browser" VARCHAR (64);
+ALTER TABLE "sessions" ADD COLUMN "operating_system" VARCHAR (64);
+ALTER TABLE "sessions" ADD COLUMN "device_type" VARCHAR (32);
\ No newline at end of file
@@ -0,0 +1,90 @@
+DROP INDEX IF EXISTS "sessions_issued_at_idx";
+DROP INDEX IF EXISTS "sessions_token_idx";
+DROP INDEX IF EXISTS "sessions_token_uq_idx";
+CREATE TABLE "_sessions_tmp" (
+"id" TEXT PRIMARY KEY,
+"issued_at" DATETIME NOT NULL DEFAULT 'CURRENT_TIMESTAMP',
+"expires_at" DATETIME NOT NULL,
+"authenticated_at" DATETIME NOT NULL,
+"identity_id" char(36) NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+"token" TEXT,
+"active" NUMERIC DEFAULT 'false',
+"impersonated_by" TEXT,
+"first_time_login" bool NOT NULL DEFAULT 'false',
+"just_registered" bool NOT NULL DEFAULT 'false',
+"ip_address" TEXT,
+"user_agent" TEXT,
+"location" TEXT,
+"last_active_at" DATETIME,
+"browser" TEXT,
+"operating_system" TEXT,
+FOREIGN KEY (identity_id) REFERENCES identities (id) ON UPDATE NO ACTION ON DELETE CASCADE
+);
+CREATE INDEX "sessions_issued_at_idx" ON "_sessions_tmp" (issued_at);
+CREATE INDEX "sessions_token_idx" ON "_sessions_tmp" (token);
+CREATE UNIQUE INDEX "sessions_token_uq_idx" ON "_sessions_tmp" (token);
+INSERT INTO "_sessions_tmp" (id, issued_at, expires_at, authenticated_at, identity_id, created_at, updated_at, token, active, impersonated_by, first_time_login, just_registered, ip_address, user_agent, location, last_active_at, browser, operating_system) SELECT id, issued_at, expires_at, authenticated_at, identity_id, created_at, updated_at, token, active, impersonated_by, first_time_login, just_registered, ip_address, user_agent, location, last_active_at, browser, operating_system FROM "sessions";
+
+DROP TABLE "sessions";
+ALTER TABLE "_sessions_tmp" RENAME TO "sessions";
+DROP INDEX IF EXISTS "sessions_issued_at_idx";
+DROP INDEX IF EXISTS "sessions_token_idx";
+DROP INDEX IF EXISTS "sessions_token_uq_idx";
+CREATE TABLE "_sessions_tmp" (
+"id" TEXT PRIMARY KEY,
+"issued_at" DATETIME NOT NULL DEFAULT 'CURRENT_TIMESTAMP',
+"expires_at" DATETIME NOT NULL,
+"authenticated_at" DATETIME NOT NULL,
+"identity_id" char(36) NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+"token" TEXT,
+"active" NUMERIC DEFAULT 'false',
+"impersonated_by" TEXT,
+"first_time_login" bool NOT NULL DEFAULT 'false',
+"just_registered" bool NOT NULL DEFAULT 'false',
+"ip_address" TEXT,
+"user_agent" TEXT,
+"location" TEXT,
+"last_active_at" DATETIME,
+"browser" TEXT,
+FOREIGN KEY (identity_id) REFERENCES identities (id) ON UPDATE NO ACTION ON DELETE CASCADE
+);
+CREATE INDEX "sessions_issued_at_idx" ON "_sessions_tmp" (issued_at);
+CREATE INDEX "sessions_token_idx" ON "_sessions_tmp" (token);
+CREATE UNIQUE INDEX "sessions_token_uq_idx" ON "_sessions_tmp" (token);
+INSERT INTO "_sessions_tmp" (id, issued_at, expires_at, authenticated_at, identity_id, created_at, updated_at, token, active, impersonated_by, first_time_login, just_registered, ip_address, user_agent, location, last_active_at, browser) SELECT id, issued_at, expires_at, authenticated_at, identity_id, created_at, updated_at, token, active, impersonated_by, first_time_login, just_registered, ip_address, user_agent, location, last_active_at, browser FROM "sessions";
+
+DROP TABLE "sessions";
+ALTER TABLE "_sessions_tmp" RENAME TO "sessions";
+DROP INDEX IF EXISTS "sessions_issued_at_idx";
+DROP INDEX IF EXISTS "sessions_token_idx";
+DROP INDEX IF EXISTS "sessions_token_uq_idx";
+CREATE TABLE "_sessions_tmp" (
+"id" TEXT PRIMARY KEY,
+"issued_at" DATETIME NOT NULL DEFAULT 'CURRENT_TIMESTAMP',
+"expires_at" DATETIME NOT NULL,
+"authenticated_at" DATETIME NOT NULL,
+"identity_id" char(36) NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL,
+"token" TEXT,
+"active" NUMERIC DEFAULT 'false',
+"impersonated_by" TEXT,
+"first_time_login" bool NOT NULL DEFAULT 'false',
+"just_registered" bool NOT NULL DEFAULT 'false',
+"ip_address" TEXT,
+"user_agent" TEXT,
+"location" TEXT,
+"last_active_at" DATETIME,
+FOREIGN KEY (identity_id) REFERENCES identities (id) ON UPDATE NO ACTION ON DELETE CASCADE
+);
+CREATE INDEX "sessions_issued_at_idx" ON "_sessions_tmp" (issued_at);
+CREATE INDEX "sessions_token_idx" ON "_sessions_tmp" (token);
+CREATE UNIQUE INDEX "sessions_token_uq_idx" ON "_sessions_tmp" (token);
+INSERT INTO "_sessions_tmp" (id, issued_at, expires_at, authenticated_at, identity_id, created_at, updated_at, token, active, impersonated_by, first_time_login, just_registered, ip_address, user_agent, location, last_active_at) SELECT id, issued_at, expires_at, authenticated_at, identity_id, created_at, updated_at, token, active, impersonated_by, first_time_login, just_registered, ip_address, user_agent, location, last_active_at FROM "sessions";
+
+DROP TABLE "sessions";
+ALTER TABLE "_sessions_tmp" RENAME TO "sessions";
\ No newline at end of file
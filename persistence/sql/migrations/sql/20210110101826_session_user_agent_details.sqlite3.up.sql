@@ -0,0 +1,3 @@
+ALTER TABLE "sessions" ADD COLUMN "browser" TEXT;
+ALTER TABLE "sessions" ADD COLUMN "operating_system" TEXT;
+ALTER TABLE "sessions" ADD COLUMN "device_type" TEXT;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "sessions" DROP COLUMN "remembered";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
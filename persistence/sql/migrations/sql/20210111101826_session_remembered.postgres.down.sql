@@ -0,0 +1 @@
+ALTER TABLE "sessions" DROP COLUMN "remembered";
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE "break_glass_grants";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
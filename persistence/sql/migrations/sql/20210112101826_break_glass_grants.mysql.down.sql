@@ -0,0 +1 @@
+DROP TABLE `break_glass_grants`;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE `break_glass_grants` (
+`id` char(36) NOT NULL,
+PRIMARY KEY(`id`),
+`identity_id` char(36) NOT NULL,
+`opened_by` char(36) NOT NULL,
+`api_key_id` VARCHAR (64) NOT NULL,
+`justification` text NOT NULL,
+`expires_at` DATETIME NOT NULL,
+`closed_at` DATETIME,
+`created_at` DATETIME NOT NULL,
+`updated_at` DATETIME NOT NULL
+) ENGINE=InnoDB;
+CREATE INDEX `break_glass_grants_identity_id_created_at_idx` ON `break_glass_grants` (`identity_id`, `created_at`);
\ No newline at end of file
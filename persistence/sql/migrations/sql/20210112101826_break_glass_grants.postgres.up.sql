@@ -0,0 +1,13 @@
+CREATE TABLE "break_glass_grants" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"identity_id" UUID NOT NULL,
+"opened_by" UUID NOT NULL,
+"api_key_id" VARCHAR (64) NOT NULL,
+"justification" text NOT NULL,
+"expires_at" timestamp NOT NULL,
+"closed_at" timestamp,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL
+);
+CREATE INDEX "break_glass_grants_identity_id_created_at_idx" ON "break_glass_grants" (identity_id, created_at);
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE "break_glass_grants" (
+"id" TEXT PRIMARY KEY,
+"identity_id" char(36) NOT NULL,
+"opened_by" char(36) NOT NULL,
+"api_key_id" TEXT NOT NULL,
+"justification" TEXT NOT NULL,
+"expires_at" DATETIME NOT NULL,
+"closed_at" DATETIME,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL
+);
+CREATE INDEX "break_glass_grants_identity_id_created_at_idx" ON "break_glass_grants" (identity_id, created_at);
\ No newline at end of file
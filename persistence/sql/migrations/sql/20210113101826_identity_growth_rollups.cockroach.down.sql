@@ -0,0 +1 @@
+DROP TABLE "identity_growth_rollups";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE `identity_growth_rollups`;
\ No newline at end of file
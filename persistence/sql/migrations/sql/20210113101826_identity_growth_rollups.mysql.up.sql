@@ -0,0 +1,12 @@
+CREATE TABLE `identity_growth_rollups` (
+`id` char(36) NOT NULL,
+PRIMARY KEY(`id`),
+`cohort` DATETIME NOT NULL,
+`day` DATETIME NOT NULL,
+`cohort_size` INTEGER NOT NULL,
+`verified_identities` INTEGER NOT NULL,
+`active_identities` INTEGER NOT NULL,
+`active_sessions` INTEGER NOT NULL,
+`created_at` DATETIME NOT NULL
+) ENGINE=InnoDB;
+CREATE UNIQUE INDEX `identity_growth_rollups_day_cohort_idx` ON `identity_growth_rollups` (`day`, `cohort`);
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE "identity_growth_rollups" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"cohort" timestamp NOT NULL,
+"day" timestamp NOT NULL,
+"cohort_size" integer NOT NULL,
+"verified_identities" integer NOT NULL,
+"active_identities" integer NOT NULL,
+"active_sessions" integer NOT NULL,
+"created_at" timestamp NOT NULL
+);
+CREATE UNIQUE INDEX "identity_growth_rollups_day_cohort_idx" ON "identity_growth_rollups" (day, cohort);
\ No newline at end of file
@@ -0,0 +1,11 @@
+CREATE TABLE "identity_growth_rollups" (
+"id" TEXT PRIMARY KEY,
+"cohort" DATETIME NOT NULL,
+"day" DATETIME NOT NULL,
+"cohort_size" INTEGER NOT NULL,
+"verified_identities" INTEGER NOT NULL,
+"active_identities" INTEGER NOT NULL,
+"active_sessions" INTEGER NOT NULL,
+"created_at" DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX "identity_growth_rollups_day_cohort_idx" ON "identity_growth_rollups" (day, cohort);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE "identity_verification_codes";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
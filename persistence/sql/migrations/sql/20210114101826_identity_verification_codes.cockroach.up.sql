@@ -0,0 +1,18 @@
+CREATE TABLE "identity_verification_codes" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"code" VARCHAR (64) NOT NULL,
+"attempts" integer NOT NULL DEFAULT '0',
+"used" bool NOT NULL DEFAULT 'false',
+"used_at" timestamp,
+"expires_at" timestamp NOT NULL,
+"issued_at" timestamp NOT NULL,
+"identity_verifiable_address_id" UUID NOT NULL,
+"selfservice_verification_flow_id" UUID NOT NULL,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL,
+CONSTRAINT "identity_verification_codes_identity_verifiable_addresses_id_fk" FOREIGN KEY ("identity_verifiable_address_id") REFERENCES "identity_verifiable_addresses" ("id") ON DELETE cascade,
+CONSTRAINT "identity_verification_codes_selfservice_verification_flows_id_fk" FOREIGN KEY ("selfservice_verification_flow_id") REFERENCES "selfservice_verification_flows" ("id") ON DELETE cascade
+);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "identity_verification_codes_verification_flow_id_idx" ON "identity_verification_codes" (selfservice_verification_flow_id);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "identity_verification_codes_verifiable_address_id_idx" ON "identity_verification_codes" (identity_verifiable_address_id);COMMIT TRANSACTION;BEGIN TRANSACTION;
@@ -0,0 +1 @@
+DROP TABLE "identity_verification_codes";
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE "identity_bulk_deletions";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE "identity_bulk_deletions" (
+"id" UUID NOT NULL,
+PRIMARY KEY("id"),
+"filter" text NOT NULL,
+"state" VARCHAR (32) NOT NULL,
+"matched" integer NOT NULL,
+"deleted" integer NOT NULL,
+"skipped" integer NOT NULL,
+"error" text NOT NULL,
+"completed_at" timestamp,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL
+);COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE `identity_bulk_deletions` (
+`id` char(36) NOT NULL,
+PRIMARY KEY(`id`),
+`filter` text NOT NULL,
+`state` VARCHAR (32) NOT NULL,
+`matched` INTEGER NOT NULL,
+`deleted` INTEGER NOT NULL,
+`skipped` INTEGER NOT NULL,
+`error` text NOT NULL,
+`completed_at` DATETIME,
+`created_at` DATETIME NOT NULL,
+`updated_at` DATETIME NOT NULL
+) ENGINE=InnoDB;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE "identity_bulk_deletions";
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE "identity_bulk_deletions" (
+"id" TEXT PRIMARY KEY,
+"filter" TEXT NOT NULL,
+"state" TEXT NOT NULL,
+"matched" INTEGER NOT NULL,
+"deleted" INTEGER NOT NULL,
+"skipped" INTEGER NOT NULL,
+"error" TEXT NOT NULL,
+"completed_at" DATETIME,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL
+);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE "selfservice_kerberos_authenticators";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
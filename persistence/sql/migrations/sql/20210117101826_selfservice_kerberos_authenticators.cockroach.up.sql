@@ -0,0 +1,8 @@
+CREATE TABLE "selfservice_kerberos_authenticators" (
+"id" VARCHAR (64) NOT NULL,
+PRIMARY KEY("id"),
+"expires_at" timestamp NOT NULL,
+"created_at" timestamp NOT NULL,
+"updated_at" timestamp NOT NULL
+);COMMIT TRANSACTION;BEGIN TRANSACTION;
+CREATE INDEX "selfservice_kerberos_authenticators_expires_at_idx" ON "selfservice_kerberos_authenticators" (expires_at);COMMIT TRANSACTION;BEGIN TRANSACTION;
@@ -0,0 +1,8 @@
+CREATE TABLE `selfservice_kerberos_authenticators` (
+`id` VARCHAR (64) NOT NULL,
+PRIMARY KEY(`id`),
+`expires_at` DATETIME NOT NULL,
+`created_at` DATETIME NOT NULL,
+`updated_at` DATETIME NOT NULL
+) ENGINE=InnoDB;
+CREATE INDEX `selfservice_kerberos_authenticators_expires_at_idx` ON `selfservice_kerberos_authenticators` (`expires_at`);
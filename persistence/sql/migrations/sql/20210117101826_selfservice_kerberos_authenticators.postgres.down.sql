@@ -0,0 +1 @@
+DROP TABLE "selfservice_kerberos_authenticators";
\ No newline at end of file
@@ -0,0 +1,7 @@
+CREATE TABLE "selfservice_kerberos_authenticators" (
+"id" TEXT PRIMARY KEY,
+"expires_at" DATETIME NOT NULL,
+"created_at" DATETIME NOT NULL,
+"updated_at" DATETIME NOT NULL
+);
+CREATE INDEX "selfservice_kerberos_authenticators_expires_at_idx" ON "selfservice_kerberos_authenticators" (expires_at);
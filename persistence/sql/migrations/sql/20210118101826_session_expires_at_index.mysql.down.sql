@@ -0,0 +1 @@
+DROP INDEX `sessions_expires_at_idx` ON `sessions`;
\ No newline at end of file
@@ -0,0 +1 @@
+CREATE INDEX "sessions_expires_at_idx" ON "sessions" (expires_at);
\ No newline at end of file
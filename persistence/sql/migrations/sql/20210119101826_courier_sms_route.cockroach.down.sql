@@ -0,0 +1,2 @@
+ALTER TABLE "courier_messages" DROP COLUMN "sender_id";COMMIT TRANSACTION;BEGIN TRANSACTION;
+ALTER TABLE "courier_messages" DROP COLUMN "provider";COMMIT TRANSACTION;BEGIN TRANSACTION;
\ No newline at end of file
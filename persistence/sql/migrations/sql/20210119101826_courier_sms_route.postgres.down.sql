@@ -0,0 +1,2 @@
+ALTER TABLE "courier_messages" DROP COLUMN "sender_id";
+ALTER TABLE "courier_messages" DROP COLUMN "provider";
\ No newline at end of file
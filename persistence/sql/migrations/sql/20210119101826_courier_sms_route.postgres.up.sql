@@ -0,0 +1,2 @@
+ALTER TABLE "courier_messages" ADD COLUMN "provider" VARCHAR (255) NOT NULL DEFAULT '';
+ALTER TABLE "courier_messages" ADD COLUMN "sender_id" VARCHAR (255) NOT NULL DEFAULT '';
\ No newline at end of file
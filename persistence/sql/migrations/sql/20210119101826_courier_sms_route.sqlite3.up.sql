@@ -0,0 +1,2 @@
+ALTER TABLE "courier_messages" ADD COLUMN "provider" TEXT NOT NULL DEFAULT '';
+ALTER TABLE "courier_messages" ADD COLUMN "sender_id" TEXT NOT NULL DEFAULT '';
\ No newline at end of file
@@ -2,11 +2,14 @@ package sql
 
 import (
 	"context"
+	"fmt"
 	"io"
 
 	"github.com/ory/x/pkgerx"
+	"github.com/ory/x/sqlcon"
 
 	"github.com/gobuffalo/pop/v5"
+	"github.com/gobuffalo/pop/v5/columns"
 	"github.com/markbates/pkger"
 	"github.com/pkg/errors"
 
@@ -25,7 +28,9 @@ type (
 	persisterDependencies interface {
 		IdentityTraitsSchemas() schema.Schemas
 		identity.ValidationProvider
+		identity.SchemaMigratorProvider
 		x.LoggingProvider
+		x.ClockProvider
 	}
 	Persister struct {
 		c        *pop.Connection
@@ -33,6 +38,7 @@ type (
 		r        persisterDependencies
 		cf       *config.Provider
 		isSQLite bool
+		sessions *sessionCache
 	}
 )
 
@@ -42,7 +48,7 @@ func NewPersister(r persisterDependencies, conf *config.Provider, c *pop.Connect
 		return nil, err
 	}
 
-	return &Persister{c: c, mb: m, cf: conf, r: r, isSQLite: c.Dialect.Name() == "sqlite3"}, nil
+	return &Persister{c: c, mb: m, cf: conf, r: r, isSQLite: c.Dialect.Name() == "sqlite3", sessions: newSessionCache()}, nil
 }
 
 func (p *Persister) Connection() *pop.Connection {
@@ -53,6 +59,39 @@ func (p *Persister) MigrationStatus(ctx context.Context, w io.Writer) error {
 	return errors.WithStack(p.mb.Status(w))
 }
 
+// PendingMigrations returns the names of all migrations for the current dialect which have not been applied yet.
+// It does not modify the database. If the migration table does not exist yet, all migrations are considered pending.
+func (p *Persister) PendingMigrations(ctx context.Context) ([]string, error) {
+	c := p.GetConnection(ctx)
+
+	// This is how pop checks for the existence of the migration table as well.
+	_, err := c.Store.Exec(fmt.Sprintf("SELECT * FROM %s", c.MigrationTableName()))
+	hasTable := err == nil
+
+	var pending []string
+	for _, mf := range p.mb.Migrations["up"] {
+		if mf.DBType != "all" && mf.DBType != c.Dialect.Name() {
+			continue
+		}
+
+		if !hasTable {
+			pending = append(pending, mf.Version+"_"+mf.Name)
+			continue
+		}
+
+		exists, err := c.Where("version = ?", mf.Version).Exists(c.MigrationTableName())
+		if err != nil {
+			return nil, sqlcon.HandleError(err)
+		}
+
+		if !exists {
+			pending = append(pending, mf.Version+"_"+mf.Name)
+		}
+	}
+
+	return pending, nil
+}
+
 func (p *Persister) MigrateDown(ctx context.Context, steps int) error {
 	return errors.WithStack(p.mb.Down(steps))
 }
@@ -72,3 +111,10 @@ func (p *Persister) Ping(ctx context.Context) error {
 
 	return errors.WithStack(p.GetConnection(ctx).Store.(pinger).Ping())
 }
+
+// selectColumns returns the columns of the model's table as used in a SELECT clause by pop.
+func selectColumns(model interface {
+	TableName() string
+}) string {
+	return columns.ForStruct(model, model.TableName()).Readable().SelectString()
+}
@@ -0,0 +1,89 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/kratos/apikey"
+	"github.com/ory/kratos/identity"
+)
+
+var _ apikey.Persister = new(Persister)
+
+func (p *Persister) UpdateAPIKeys(ctx context.Context, identityID uuid.UUID, update func(keys []apikey.CredentialsKey) ([]apikey.CredentialsKey, error)) error {
+	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		if count, err := tx.Where("id = ?", identityID).Count(new(identity.Identity)); err != nil {
+			return sqlcon.HandleError(err)
+		} else if count == 0 {
+			return errors.WithStack(sqlcon.ErrNoRows)
+		}
+
+		ct, err := p.findIdentityCredentialsType(ctx, identity.CredentialsTypeAPIKey)
+		if err != nil {
+			return err
+		}
+
+		var cred identity.Credentials
+		var conf apikey.CredentialsConfig
+		if err := tx.Where("identity_id = ? AND identity_credential_type_id = ?", identityID, ct.ID).First(&cred); err == nil {
+			if err := json.Unmarshal(cred.Config, &conf); err != nil {
+				return errors.WithStack(err)
+			}
+		} else if !errors.Is(sqlcon.HandleError(err), sqlcon.ErrNoRows) {
+			return sqlcon.HandleError(err)
+		}
+
+		keys, err := update(conf.Keys)
+		if err != nil {
+			return err
+		}
+
+		config, err := json.Marshal(&apikey.CredentialsConfig{Keys: keys})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		cred.Config = sqlxx.JSONRawMessage(config)
+
+		if cred.ID == uuid.Nil {
+			cred.IdentityID = identityID
+			cred.CredentialTypeID = ct.ID
+			if err := tx.Create(&cred); err != nil {
+				return sqlcon.HandleError(err)
+			}
+		} else if err := tx.Update(&cred); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		var identifiers identity.CredentialIdentifierCollection
+		if err := tx.Where("identity_credential_id = ?", cred.ID).All(&identifiers); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		known := make(map[string]bool, len(identifiers))
+		for _, ci := range identifiers {
+			known[ci.Identifier] = true
+		}
+
+		// Revoked keys keep their identifier so that their IDs are never reused.
+		for _, k := range keys {
+			if known[k.ID] {
+				continue
+			}
+			if err := tx.Create(&identity.CredentialIdentifier{
+				Identifier:            k.ID,
+				IdentityCredentialsID: cred.ID,
+			}); err != nil {
+				return sqlcon.HandleError(err)
+			}
+		}
+
+		return nil
+	})
+}
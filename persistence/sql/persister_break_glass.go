@@ -0,0 +1,61 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/breakglass"
+)
+
+var _ breakglass.Persister = new(Persister)
+
+func (p *Persister) CreateBreakGlassGrant(ctx context.Context, g *breakglass.Grant) error {
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(g))
+}
+
+func (p *Persister) GetBreakGlassGrant(ctx context.Context, id uuid.UUID) (*breakglass.Grant, error) {
+	var g breakglass.Grant
+	if err := p.GetConnection(ctx).Where("id = ?", id).First(&g); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &g, nil
+}
+
+func (p *Persister) ListBreakGlassGrants(ctx context.Context, identityID uuid.UUID, page, itemsPerPage int) ([]breakglass.Grant, int64, error) {
+	q := p.GetConnection(ctx).Q()
+	if identityID != uuid.Nil {
+		q = q.Where("identity_id = ?", identityID)
+	}
+
+	total, err := q.Count(new(breakglass.Grant))
+	if err != nil {
+		return nil, 0, sqlcon.HandleError(err)
+	}
+
+	gs := make([]breakglass.Grant, 0)
+	if err := q.Paginate(page, itemsPerPage).Order("created_at DESC, id DESC").All(&gs); err != nil {
+		return nil, 0, sqlcon.HandleError(err)
+	}
+	return gs, int64(total), nil
+}
+
+func (p *Persister) CloseBreakGlassGrant(ctx context.Context, id uuid.UUID, closedAt time.Time) error {
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET closed_at = ?, updated_at = ? WHERE id = ? AND closed_at IS NULL",
+		new(breakglass.Grant).TableName()), closedAt, time.Now().UTC(), id).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count > 0 {
+		return nil
+	}
+
+	// The grant does not exist or was closed already.
+	_, err = p.GetBreakGlassGrant(ctx, id)
+	return err
+}
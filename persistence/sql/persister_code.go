@@ -0,0 +1,93 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/selfservice/strategy/code"
+)
+
+var _ code.VerificationCodePersister = new(Persister)
+
+func (p *Persister) CreateVerificationCode(ctx context.Context, vc *code.VerificationCode) error {
+	c := vc.Code
+	vc.Code = p.hmacValue(c)
+
+	if err := p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		// Incorrect attempts are counted per flow, otherwise requesting a new code would reset them.
+		var previous code.VerificationCode
+		if err := tx.Where("selfservice_verification_flow_id = ?", vc.FlowID).Order("created_at DESC").First(&previous); err == nil {
+			vc.Attempts = previous.Attempts
+		} else if !errors.Is(sqlcon.HandleError(err), sqlcon.ErrNoRows) {
+			return sqlcon.HandleError(err)
+		}
+
+		// Only the most recent code of a flow can be used.
+		/* #nosec G201 TableName is static */
+		if err := tx.RawQuery(fmt.Sprintf("UPDATE %s SET used=true, used_at=? WHERE selfservice_verification_flow_id=? AND NOT used", vc.TableName()),
+			p.r.Clock().Now().UTC(), vc.FlowID).Exec(); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		// This should not create the code eagerly because otherwise we might accidentally create an address that isn't
+		// supposed to be in the database.
+		return sqlcon.HandleError(tx.Create(vc))
+	}); err != nil {
+		return err
+	}
+
+	vc.Code = c
+	return nil
+}
+
+func (p *Persister) UseVerificationCode(ctx context.Context, flowID uuid.UUID, entered string, maxAttempts int) (*code.VerificationCode, error) {
+	var mismatch bool
+	vc := new(code.VerificationCode)
+	if err := sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		now := p.r.Clock().Now().UTC()
+		if err := tx.Eager().Where("selfservice_verification_flow_id = ? AND NOT used AND expires_at > ?", flowID, now).
+			Order("created_at DESC").First(vc); err != nil {
+			return err
+		} else if vc.Attempts >= maxAttempts {
+			// The flow used up its attempts with codes sent before.
+			return errors.WithStack(sqlcon.ErrNoRows)
+		}
+
+		/* #nosec G201 TableName is static */
+		query := fmt.Sprintf("UPDATE %s SET used=true, used_at=? WHERE id=? AND NOT used", vc.TableName())
+		args := []interface{}{now, vc.ID}
+		if mismatch = !p.hmacConstantCompare(entered, vc.Code); mismatch {
+			vc.Attempts++
+			if vc.Attempts < maxAttempts {
+				/* #nosec G201 TableName is static */
+				query = fmt.Sprintf("UPDATE %s SET attempts=attempts+1 WHERE id=? AND NOT used", vc.TableName())
+				args = []interface{}{vc.ID}
+			} else {
+				/* #nosec G201 TableName is static */
+				query = fmt.Sprintf("UPDATE %s SET attempts=attempts+1, used=true, used_at=? WHERE id=? AND NOT used", vc.TableName())
+			}
+		}
+
+		// Only one of several concurrent requests using the same code may count the attempt or mark it as used.
+		count, err := tx.RawQuery(query, args...).ExecWithCount()
+		if err != nil {
+			return err
+		} else if count != 1 {
+			return errors.WithStack(sqlcon.ErrNoRows)
+		}
+		return nil
+	})); err != nil {
+		return nil, err
+	}
+
+	if mismatch {
+		return nil, errors.WithStack(code.ErrCodeMismatch)
+	}
+	return vc, nil
+}
@@ -0,0 +1,87 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/configversion"
+)
+
+var _ configversion.Persister = new(Persister)
+
+func (p *Persister) CreateConfigVersion(ctx context.Context, v *configversion.Version) error {
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(v))
+}
+
+func (p *Persister) GetConfigVersion(ctx context.Context, id uuid.UUID) (*configversion.Version, error) {
+	var v configversion.Version
+	if err := p.GetConnection(ctx).Find(&v, id); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &v, nil
+}
+
+func (p *Persister) FindConfigVersionByHash(ctx context.Context, hash string) (*configversion.Version, error) {
+	var v configversion.Version
+	if err := p.GetConnection(ctx).Where("hash = ?", hash).First(&v); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &v, nil
+}
+
+func (p *Persister) GetActiveConfigVersion(ctx context.Context) (*configversion.Version, error) {
+	var v configversion.Version
+	if err := p.GetConnection(ctx).Where("state = ?", configversion.StateActive).First(&v); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &v, nil
+}
+
+func (p *Persister) GetPreviousConfigVersion(ctx context.Context) (*configversion.Version, error) {
+	var v configversion.Version
+	if err := p.GetConnection(ctx).Where("state = ?", configversion.StateRetired).
+		Order("activated_at DESC").First(&v); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &v, nil
+}
+
+func (p *Persister) ListConfigVersions(ctx context.Context, page, itemsPerPage int) ([]configversion.Version, error) {
+	vs := make([]configversion.Version, 0)
+	if err := p.GetConnection(ctx).Order("created_at DESC").
+		Paginate(page, itemsPerPage).All(&vs); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return vs, nil
+}
+
+func (p *Persister) ActivateConfigVersion(ctx context.Context, id uuid.UUID) error {
+	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		now := time.Now().UTC()
+		table := new(configversion.Version).TableName()
+
+		/* #nosec G201 TableName is static */
+		if err := tx.RawQuery(fmt.Sprintf("UPDATE %s SET state = ?, updated_at = ? WHERE state = ?", table),
+			configversion.StateRetired, now, configversion.StateActive).Exec(); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		/* #nosec G201 TableName is static */
+		count, err := tx.RawQuery(fmt.Sprintf("UPDATE %s SET state = ?, activated_at = ?, updated_at = ? WHERE id = ?", table),
+			configversion.StateActive, now, now, id).ExecWithCount()
+		if err != nil {
+			return sqlcon.HandleError(err)
+		}
+		if count == 0 {
+			return errors.WithStack(sqlcon.ErrNoRows)
+		}
+		return nil
+	})
+}
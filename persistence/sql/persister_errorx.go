@@ -20,6 +20,10 @@ import (
 var _ errorx.Persister = new(Persister)
 
 func (p *Persister) Add(ctx context.Context, csrfToken string, errs ...error) (uuid.UUID, error) {
+	return p.AddWithDetails(ctx, csrfToken, nil, errs...)
+}
+
+func (p *Persister) AddWithDetails(ctx context.Context, csrfToken string, details *errorx.Details, errs ...error) (uuid.UUID, error) {
 	buf, err := p.encodeSelfServiceErrors(errs)
 	if err != nil {
 		return uuid.Nil, err
@@ -30,6 +34,12 @@ func (p *Persister) Add(ctx context.Context, csrfToken string, errs ...error) (u
 		Errors:    buf.Bytes(),
 		WasSeen:   false,
 	}
+	if details != nil {
+		c.Flow = details.Flow
+		c.Title = details.Title
+		c.Description = details.Description
+		c.Actions = details.Actions
+	}
 
 	if err := p.GetConnection(ctx).Create(c); err != nil {
 		return uuid.Nil, sqlcon.HandleError(err)
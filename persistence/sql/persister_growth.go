@@ -0,0 +1,90 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v5"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/growth"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/session"
+)
+
+var _ growth.Persister = new(Persister)
+
+func (p *Persister) CountGrowthCohort(ctx context.Context, cohort, day time.Time) (*growth.Rollup, error) {
+	r := &growth.Rollup{Cohort: cohort.UTC(), Day: day.UTC()}
+	cohortEnd, dayEnd := r.Cohort.AddDate(0, 0, 1), r.Day.AddDate(0, 0, 1)
+	identities, addresses, sessions := new(identity.Identity).TableName(), new(identity.VerifiableAddress).TableName(), new(session.Session).TableName()
+	inCohort := "i.created_at >= ? AND i.created_at < ? AND i.deleted_at IS NULL"
+
+	var identityCounts struct {
+		CohortSize         int `db:"cohort_size"`
+		VerifiedIdentities int `db:"verified_identities"`
+	}
+	/* #nosec G201 TableName is static */
+	if err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(`SELECT COUNT(*) AS cohort_size,
+COUNT(CASE WHEN EXISTS (SELECT 1 FROM %[2]s a WHERE a.identity_id = i.id AND a.verified = ?) THEN 1 END) AS verified_identities
+FROM %[1]s i WHERE %[3]s`, identities, addresses, inCohort),
+		true, r.Cohort, cohortEnd,
+	).First(&identityCounts); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	var sessionCounts struct {
+		ActiveIdentities int `db:"active_identities"`
+		ActiveSessions   int `db:"active_sessions"`
+	}
+	/* #nosec G201 TableName is static */
+	if err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(`SELECT COUNT(DISTINCT s.identity_id) AS active_identities, COUNT(*) AS active_sessions
+FROM %[1]s s INNER JOIN %[2]s i ON i.id = s.identity_id
+WHERE %[3]s AND ((s.issued_at >= ? AND s.issued_at < ?) OR (s.last_active_at >= ? AND s.last_active_at < ?))`, sessions, identities, inCohort),
+		r.Cohort, cohortEnd, r.Day, dayEnd, r.Day, dayEnd,
+	).First(&sessionCounts); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	r.CohortSize, r.VerifiedIdentities = identityCounts.CohortSize, identityCounts.VerifiedIdentities
+	r.ActiveIdentities, r.ActiveSessions = sessionCounts.ActiveIdentities, sessionCounts.ActiveSessions
+	return r, nil
+}
+
+func (p *Persister) ReplaceGrowthRollups(ctx context.Context, day time.Time, rs []growth.Rollup) error {
+	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		/* #nosec G201 TableName is static */
+		if err := tx.RawQuery(fmt.Sprintf("DELETE FROM %s WHERE day = ?", new(growth.Rollup).TableName()), day.UTC()).Exec(); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		for k := range rs {
+			if err := tx.Create(&rs[k]); err != nil {
+				return sqlcon.HandleError(err)
+			}
+		}
+		return nil
+	})
+}
+
+func (p *Persister) ListGrowthRollups(ctx context.Context, since, until time.Time) ([]growth.Rollup, error) {
+	rs := make([]growth.Rollup, 0)
+	if err := p.GetConnection(ctx).Where("day >= ? AND day <= ?", since.UTC(), until.UTC()).
+		Order("day ASC, cohort ASC").All(&rs); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return rs, nil
+}
+
+func (p *Persister) LatestGrowthRollupDay(ctx context.Context) (time.Time, error) {
+	var rs []growth.Rollup
+	if err := p.GetConnection(ctx).Order("day DESC").Limit(1).All(&rs); err != nil {
+		return time.Time{}, sqlcon.HandleError(err)
+	}
+	if len(rs) == 0 {
+		return time.Time{}, nil
+	}
+	return rs[0].Day.UTC(), nil
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/ory/x/logrusx"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
 )
 
 type logRegistryOnly struct {
@@ -29,6 +30,10 @@ func (l *logRegistryOnly) IdentityValidator() *identity.Validator {
 	panic("implement me")
 }
 
+func (l *logRegistryOnly) IdentitySchemaMigrator() *identity.SchemaMigrator {
+	panic("implement me")
+}
+
 func (l *logRegistryOnly) Logger() *logrusx.Logger {
 	if l.l == nil {
 		l.l = logrusx.New("kratos", "testing")
@@ -40,6 +45,10 @@ func (l *logRegistryOnly) Audit() *logrusx.Logger {
 	panic("implement me")
 }
 
+func (l *logRegistryOnly) Clock() x.Clock {
+	return x.SystemClock{}
+}
+
 var _ persisterDependencies = &logRegistryOnly{}
 
 func TestPersisterHMAC(t *testing.T) {
@@ -3,7 +3,9 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -17,6 +19,7 @@ import (
 	"github.com/gobuffalo/pop/v5"
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
 
 	"github.com/ory/herodot"
 	"github.com/ory/x/errorsx"
@@ -64,8 +67,10 @@ func (p *Persister) FindByCredentialsIdentifier(ctx context.Context, ct identity
 FROM identity_credentials ic
          INNER JOIN identity_credential_types ict on ic.identity_credential_type_id = ict.id
          INNER JOIN identity_credential_identifiers ici on ic.id = ici.identity_credential_id
+         INNER JOIN identities i on ic.identity_id = i.id
 WHERE ici.identifier = ?
-  AND ict.name = ?`, match, ct).First(&find); err != nil {
+  AND ict.name = ?
+  AND i.deleted_at IS NULL`, match, ct).First(&find); err != nil {
 		if errors.Cause(err) == sql.ErrNoRows {
 			return nil, nil, herodot.ErrNotFound.WithTrace(err).WithReasonf(`No identity matching credentials identifier "%s" could be found.`, match)
 		}
@@ -159,15 +164,119 @@ func (p *Persister) createRecoveryAddresses(ctx context.Context, i *identity.Ide
 	return nil
 }
 
-func (p *Persister) CountIdentities(ctx context.Context) (int64, error) {
-	count, err := p.c.WithContext(ctx).Count(new(identity.Identity))
+func (p *Persister) CountIdentities(ctx context.Context, filter identity.ListIdentitiesFilter) (int64, error) {
+	q, err := p.whereIdentitiesMatch(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := q.Count(new(identity.Identity))
 	if err != nil {
 		return 0, sqlcon.HandleError(err)
 	}
 	return int64(count), nil
 }
 
+// whereIdentitiesMatch returns a query for the identities which are not deleted and match the filter.
+func (p *Persister) whereIdentitiesMatch(ctx context.Context, filter identity.ListIdentitiesFilter) (*pop.Query, error) {
+	where := func() *pop.Query {
+		q := p.GetConnection(ctx).Where("deleted_at IS NULL")
+		if filter.CredentialsIdentifier != "" {
+			// Identifiers of the password strategy are stored in lower case.
+			q = q.Where(`id IN (SELECT ic.identity_id
+FROM identity_credentials ic
+         INNER JOIN identity_credential_identifiers ici on ic.id = ici.identity_credential_id
+WHERE ici.identifier IN (?, ?))`, filter.CredentialsIdentifier, strings.ToLower(filter.CredentialsIdentifier))
+		}
+		for _, label := range filter.Labels {
+			// Labels are matched using the index on their name and value.
+			q = q.Where("id IN (SELECT identity_id FROM identity_labels WHERE name = ? AND value = ?)", label.Name, label.Value)
+		}
+		if filter.SchemaID != "" {
+			q = q.Where("schema_id = ?", filter.SchemaID)
+		}
+		if filter.State != "" {
+			q = q.Where("state = ?", filter.State)
+		}
+		if filter.Verified != nil {
+			q = q.Where(whereVerified(*filter.Verified), true)
+		}
+		return q
+	}
+
+	q := where()
+	if len(filter.Traits) == 0 {
+		return q, nil
+	}
+
+	if p.isSQLite {
+		// SQLite is built without the JSON1 extension, which is why the traits are matched in memory.
+		var candidates []identity.Identity
+		if err := where().All(&candidates); err != nil {
+			return nil, sqlcon.HandleError(err)
+		}
+
+		ids := make([]interface{}, 0, len(candidates))
+		for _, i := range candidates {
+			if traitsMatch(i.Traits, filter.Traits) {
+				ids = append(ids, i.ID)
+			}
+		}
+
+		if len(ids) == 0 {
+			return q.Where("1 = 0"), nil
+		}
+		return q.Where("id IN (?)", ids...), nil
+	}
+
+	for _, trait := range filter.Traits {
+		keys := strings.Split(trait.Path, ".")
+
+		switch p.c.Dialect.Name() {
+		case "mysql":
+			path := "$"
+			for _, key := range keys {
+				quoted, err := json.Marshal(key)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+				path += "." + string(quoted)
+			}
+			q = q.Where("JSON_UNQUOTE(JSON_EXTRACT(traits, ?)) = ?", path, trait.Value)
+		default:
+			// Containment queries use the inverted index of the traits.
+			var doc interface{} = trait.Value
+			for k := len(keys) - 1; k >= 0; k-- {
+				doc = map[string]interface{}{keys[k]: doc}
+			}
+
+			contains, err := json.Marshal(doc)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			q = q.Where("traits @> ?::jsonb", string(contains))
+		}
+	}
+
+	return q, nil
+}
+
+func traitsMatch(traits identity.Traits, filters []identity.TraitFilter) bool {
+	for _, f := range filters {
+		value := gjson.GetBytes(traits, f.Path)
+		if value.Type != gjson.String || value.Str != f.Value {
+			return false
+		}
+	}
+	return true
+}
+
 func (p *Persister) CreateIdentity(ctx context.Context, i *identity.Identity) error {
+	// pop would assign a random version 4 UUID, ignoring the configured UUID version.
+	if x.IsZeroUUID(i.ID) {
+		i.ID = x.NewUUID()
+	}
+
 	if i.SchemaID == "" {
 		i.SchemaID = config.DefaultIdentityTraitsSchemaID
 	}
@@ -176,6 +285,21 @@ func (p *Persister) CreateIdentity(ctx context.Context, i *identity.Identity) er
 		i.Traits = identity.Traits("{}")
 	}
 
+	if i.State == "" {
+		i.State = identity.StateActive
+	}
+
+	// New identities conform to the latest version of their schema unless they were created with an older one.
+	if i.SchemaVersion == 0 {
+		version, err := p.r.IdentitySchemaMigrator().LatestVersion(i)
+		if err != nil {
+			return err
+		}
+		i.SchemaVersion = version
+	} else if err := p.migrateTraits(ctx, i); err != nil {
+		return err
+	}
+
 	if err := p.injectTraitsSchemaURL(i); err != nil {
 		return err
 	}
@@ -201,16 +325,63 @@ func (p *Persister) CreateIdentity(ctx context.Context, i *identity.Identity) er
 	})
 }
 
-func (p *Persister) ListIdentities(ctx context.Context, page, perPage int) ([]identity.Identity, error) {
+func (p *Persister) CreateIdentities(ctx context.Context, is []*identity.Identity) error {
+	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		for _, i := range is {
+			if err := p.CreateIdentity(ctx, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *Persister) ListIdentities(ctx context.Context, filter identity.ListIdentitiesFilter, page, perPage int) ([]identity.Identity, error) {
 	is := make([]identity.Identity, 0)
 
-	/* #nosec G201 TableName is static */
-	if err := sqlcon.HandleError(p.GetConnection(ctx).Paginate(page, perPage).Order("id DESC").
-		Eager("VerifiableAddresses", "RecoveryAddresses").All(&is)); err != nil {
+	q, err := p.whereIdentitiesMatch(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sqlcon.HandleError(q.Paginate(page, perPage).Order("id DESC").
+		Eager("VerifiableAddresses", "RecoveryAddresses", "Labels").All(&is)); err != nil {
+		return nil, err
+	}
+
+	for i := range is {
+		if err := p.migrateTraits(ctx, &(is[i])); err != nil {
+			return nil, err
+		}
+		if err := p.injectTraitsSchemaURL(&(is[i])); err != nil {
+			return nil, err
+		}
+	}
+
+	return is, nil
+}
+
+func (p *Persister) ListIdentitiesAfter(ctx context.Context, filter identity.ListIdentitiesFilter, after *identity.PageToken, limit int) ([]identity.Identity, error) {
+	is := make([]identity.Identity, 0)
+
+	q, err := p.whereIdentitiesMatch(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if after != nil {
+		q = q.Where("(created_at > ? OR (created_at = ? AND id > ?))", after.CreatedAt, after.CreatedAt, after.ID)
+	}
+
+	if err := sqlcon.HandleError(q.Order("created_at ASC, id ASC").Limit(limit).
+		Eager("VerifiableAddresses", "RecoveryAddresses", "Labels").All(&is)); err != nil {
 		return nil, err
 	}
 
 	for i := range is {
+		if err := p.migrateTraits(ctx, &(is[i])); err != nil {
+			return nil, err
+		}
 		if err := p.injectTraitsSchemaURL(&(is[i])); err != nil {
 			return nil, err
 		}
@@ -219,14 +390,129 @@ func (p *Persister) ListIdentities(ctx context.Context, page, perPage int) ([]id
 	return is, nil
 }
 
+func (p *Persister) ListIdentityIDs(ctx context.Context, filter identity.ListIdentitiesFilter, page, perPage int) ([]uuid.UUID, error) {
+	var is []identity.Identity
+
+	q, err := p.whereIdentitiesMatch(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sqlcon.HandleError(q.Select("id").Paginate(page, perPage).Order("id DESC").All(&is)); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(is))
+	for k := range is {
+		ids[k] = is[k].ID
+	}
+	return ids, nil
+}
+
+func (p *Persister) ListIdentityPageTokensAfter(ctx context.Context, filter identity.ListIdentitiesFilter, after *identity.PageToken, limit int) ([]identity.PageToken, error) {
+	var is []identity.Identity
+
+	q, err := p.whereIdentitiesMatch(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if after != nil {
+		q = q.Where("(created_at > ? OR (created_at = ? AND id > ?))", after.CreatedAt, after.CreatedAt, after.ID)
+	}
+
+	if err := sqlcon.HandleError(q.Select("id", "created_at").Order("created_at ASC, id ASC").Limit(limit).All(&is)); err != nil {
+		return nil, err
+	}
+
+	tokens := make([]identity.PageToken, len(is))
+	for k := range is {
+		tokens[k] = *identity.NewPageToken(&is[k])
+	}
+	return tokens, nil
+}
+
+func (p *Persister) StreamIdentities(ctx context.Context, ids []uuid.UUID, batchSize int, fn func(*identity.Identity) error) error {
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		ids = ids[len(batch):]
+
+		args := make([]interface{}, len(batch))
+		for k, id := range batch {
+			args[k] = id
+		}
+
+		var is []identity.Identity
+		if err := sqlcon.HandleError(p.GetConnection(ctx).Where("deleted_at IS NULL").Where("id IN (?)", args...).
+			Eager("VerifiableAddresses", "RecoveryAddresses", "Labels").All(&is)); err != nil {
+			return err
+		}
+
+		byID := make(map[uuid.UUID]*identity.Identity, len(is))
+		for k := range is {
+			byID[is[k].ID] = &is[k]
+		}
+
+		for _, id := range batch {
+			i, ok := byID[id]
+			if !ok {
+				continue
+			}
+			if err := p.migrateTraits(ctx, i); err != nil {
+				return err
+			}
+			if err := p.injectTraitsSchemaURL(i); err != nil {
+				return err
+			}
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (p *Persister) UpdateIdentity(ctx context.Context, i *identity.Identity) error {
-	if err := p.validateIdentity(i); err != nil {
+	return p.updateIdentity(ctx, i, nil)
+}
+
+func (p *Persister) UpdateIdentityIfUnchanged(ctx context.Context, i *identity.Identity, updatedAt time.Time) error {
+	return p.updateIdentity(ctx, i, &updatedAt)
+}
+
+func (p *Persister) updateIdentity(ctx context.Context, i *identity.Identity, updatedAt *time.Time) error {
+	defer p.sessions.invalidateIdentities(i.ID)
+
+	if err := p.migrateTraits(ctx, i); err != nil {
 		return err
 	}
 
-	return sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+	if err := p.validateIdentity(i); err != nil {
+		return err
+	}
 
-		if count, err := tx.Where("id = ?", i.ID).Count(i); err != nil {
+	err := sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		if updatedAt != nil {
+			// The conditional update locks the row until the transaction ends, so that a concurrent update of the
+			// same version either waits for this one and then fails, or makes this one fail.
+			/* #nosec G201 TableName is static */
+			count, err := tx.RawQuery(fmt.Sprintf(
+				"UPDATE %s SET updated_at = ? WHERE id = ? AND deleted_at IS NULL AND updated_at = ?",
+				i.TableName()), p.r.Clock().Now().UTC(), i.ID, *updatedAt).ExecWithCount()
+			if err != nil {
+				return err
+			} else if count == 0 {
+				if exists, err := tx.Where("id = ? AND deleted_at IS NULL", i.ID).Exists(i); err != nil {
+					return err
+				} else if !exists {
+					return sql.ErrNoRows
+				}
+				return errors.WithStack(identity.ErrConcurrentUpdate)
+			}
+		} else if count, err := tx.Where("id = ? AND deleted_at IS NULL", i.ID).Count(i); err != nil {
 			return err
 		} else if count == 0 {
 			return sql.ErrNoRows
@@ -244,7 +530,9 @@ func (p *Persister) UpdateIdentity(ctx context.Context, i *identity.Identity) er
 			}
 		}
 
-		if err := tx.Update(i); err != nil {
+		// The legal hold is only changed by PlaceIdentityOnLegalHold and ReleaseIdentityFromLegalHold so that an
+		// update can never release it by accident.
+		if err := tx.Update(i, "legal_hold_at", "legal_hold_reason"); err != nil {
 			return err
 		}
 
@@ -258,26 +546,498 @@ func (p *Persister) UpdateIdentity(ctx context.Context, i *identity.Identity) er
 
 		return p.createIdentityCredentials(ctx, i)
 	}))
+	if updatedAt != nil && errors.Is(err, sqlcon.ErrConcurrentUpdate) {
+		// Serializable transactions fail instead of waiting for each other.
+		return errors.WithStack(identity.ErrConcurrentUpdate)
+	}
+	return err
 }
 
 func (p *Persister) DeleteIdentity(ctx context.Context, id uuid.UUID) error {
+	defer p.sessions.invalidateIdentities(id)
+
 	/* #nosec G201 TableName is static */
-	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE id = ?", new(identity.Identity).TableName()), id).ExecWithCount()
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE id = ? AND legal_hold_at IS NULL", new(identity.Identity).TableName()), id).ExecWithCount()
 	if err != nil {
 		return sqlcon.HandleError(err)
 	}
 	if count == 0 {
-		return sqlcon.ErrNoRows
+		return p.notDeletedError(ctx, p.GetConnection(ctx), id)
 	}
 	return nil
 }
 
+func (p *Persister) DeleteIdentities(ctx context.Context, ids []uuid.UUID) (deleted, held []uuid.UUID, err error) {
+	defer p.sessions.invalidateIdentities(ids...)
+
+	if len(ids) == 0 {
+		return []uuid.UUID{}, []uuid.UUID{}, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for k := range ids {
+		args[k] = ids[k]
+	}
+
+	deleted, held = []uuid.UUID{}, []uuid.UUID{}
+	if err := sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		var is []identity.Identity
+		if err := tx.Where("id IN (?)", args...).Select("id", "legal_hold_at").All(&is); err != nil {
+			return err
+		}
+
+		deletable := make([]interface{}, 0, len(is))
+		for _, i := range is {
+			if i.LegalHoldAt != nil {
+				held = append(held, i.ID)
+				continue
+			}
+			deleted = append(deleted, i.ID)
+			deletable = append(deletable, i.ID)
+		}
+
+		if len(deletable) == 0 {
+			return nil
+		}
+
+		/* #nosec G201 TableName is static */
+		return tx.RawQuery(fmt.Sprintf("DELETE FROM %s WHERE id IN (%s) AND legal_hold_at IS NULL",
+			new(identity.Identity).TableName(),
+			strings.TrimSuffix(strings.Repeat("?, ", len(deletable)), ", "),
+		), deletable...).Exec()
+	})); err != nil {
+		return nil, nil, err
+	}
+
+	return deleted, held, nil
+}
+
+// notDeletedError returns ErrLegalHold if the identity was not deleted because it is under legal hold and
+// sqlcon.ErrNoRows otherwise.
+func (p *Persister) notDeletedError(ctx context.Context, c *pop.Connection, id uuid.UUID) error {
+	held, err := c.Where("id = ? AND legal_hold_at IS NOT NULL", id).Exists(new(identity.Identity))
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if held {
+		return errors.WithStack(identity.ErrLegalHold)
+	}
+	return errors.WithStack(sqlcon.ErrNoRows)
+}
+
+func (p *Persister) SoftDeleteIdentity(ctx context.Context, id uuid.UUID) error {
+	defer p.sessions.invalidateIdentities(id)
+
+	return sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		now := p.r.Clock().Now().UTC()
+		/* #nosec G201 TableName is static */
+		count, err := tx.RawQuery(fmt.Sprintf(
+			"UPDATE %s SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL AND legal_hold_at IS NULL",
+			new(identity.Identity).TableName()), now, now, id).ExecWithCount()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return p.notDeletedError(ctx, tx, id)
+		}
+
+		return tx.RawQuery("UPDATE sessions SET active = false WHERE identity_id = ?", id).Exec()
+	}))
+}
+
+func (p *Persister) RestoreIdentity(ctx context.Context, id uuid.UUID) error {
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET deleted_at = NULL, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL",
+		new(identity.Identity).TableName()), p.r.Clock().Now().UTC(), id).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) MergeIdentities(ctx context.Context, primaryID, duplicateID uuid.UUID) (*identity.MergeResult, error) {
+	defer p.sessions.invalidateIdentities(primaryID, duplicateID)
+
+	result := &identity.MergeResult{
+		Primary:           primaryID,
+		Duplicate:         duplicateID,
+		MovedCredentials:  []identity.CredentialsType{},
+		MergedCredentials: []identity.CredentialsType{},
+	}
+
+	if err := p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		primary, err := p.GetIdentityConfidential(ctx, primaryID)
+		if err != nil {
+			return err
+		}
+
+		duplicate, err := p.GetIdentityConfidential(ctx, duplicateID)
+		if err != nil {
+			return err
+		}
+
+		if duplicate.IsUnderLegalHold() {
+			return errors.WithStack(identity.ErrLegalHold)
+		}
+
+		types := make([]string, 0, len(duplicate.Credentials))
+		for ct := range duplicate.Credentials {
+			types = append(types, string(ct))
+		}
+		sort.Strings(types)
+
+		now := p.r.Clock().Now().UTC()
+		for _, t := range types {
+			ct := identity.CredentialsType(t)
+			dc := duplicate.Credentials[ct]
+			result.Identifiers += len(dc.Identifiers)
+
+			pc, ok := primary.Credentials[ct]
+			if !ok {
+				/* #nosec G201 TableName is static */
+				if err := tx.RawQuery(fmt.Sprintf("UPDATE %s SET identity_id = ?, updated_at = ? WHERE id = ?",
+					new(identity.Credentials).TableName()), primaryID, now, dc.ID).Exec(); err != nil {
+					return err
+				}
+				result.MovedCredentials = append(result.MovedCredentials, ct)
+				continue
+			}
+
+			/* #nosec G201 TableName is static */
+			if err := tx.RawQuery(fmt.Sprintf("UPDATE %s SET identity_credential_id = ?, updated_at = ? WHERE identity_credential_id = ?",
+				new(identity.CredentialIdentifier).TableName()), pc.ID, now, dc.ID).Exec(); err != nil {
+				return err
+			}
+
+			config, err := identity.MergeCredentialsConfig(ct, pc.Config, dc.Config)
+			if err != nil {
+				return err
+			}
+
+			/* #nosec G201 TableName is static */
+			if err := tx.RawQuery(fmt.Sprintf("UPDATE %s SET config = ?, updated_at = ? WHERE id = ?",
+				new(identity.Credentials).TableName()), config, now, pc.ID).Exec(); err != nil {
+				return err
+			}
+			result.MergedCredentials = append(result.MergedCredentials, ct)
+		}
+
+		for _, move := range []struct {
+			table string
+			count *int
+		}{
+			{table: new(identity.VerifiableAddress).TableName(), count: &result.VerifiableAddresses},
+			{table: new(identity.RecoveryAddress).TableName(), count: &result.RecoveryAddresses},
+			{table: "sessions", count: &result.Sessions},
+		} {
+			/* #nosec G201 TableName is static */
+			count, err := tx.RawQuery(fmt.Sprintf("UPDATE %s SET identity_id = ? WHERE identity_id = ?", move.table),
+				primaryID, duplicateID).ExecWithCount()
+			if err != nil {
+				return err
+			}
+			*move.count = count
+		}
+
+		/* #nosec G201 TableName is static */
+		if err := tx.RawQuery(fmt.Sprintf("UPDATE %s SET updated_at = ? WHERE id = ?", new(identity.Identity).TableName()),
+			now, primaryID).Exec(); err != nil {
+			return err
+		}
+
+		// The remaining credentials of the duplicate were merged into the primary's and are deleted with it.
+		/* #nosec G201 TableName is static */
+		return tx.RawQuery(fmt.Sprintf("DELETE FROM %s WHERE id = ?", new(identity.Identity).TableName()), duplicateID).Exec()
+	}); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	return result, nil
+}
+
+func (p *Persister) PurgeDeletedIdentities(ctx context.Context, deletedBefore time.Time) (int, error) {
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE deleted_at < ? AND legal_hold_at IS NULL",
+		new(identity.Identity).TableName()), deletedBefore.UTC()).ExecWithCount()
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return count, nil
+}
+
+// likeEscaper escapes LIKE wildcards using "!" which, unlike a backslash, behaves the same in all supported dialects.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+func (p *Persister) ListIdentityIDsByFilter(ctx context.Context, filter *identity.Filter) ([]uuid.UUID, error) {
+	q := p.GetConnection(ctx).Where("deleted_at IS NULL")
+	if len(filter.IDs) > 0 {
+		ids := make([]interface{}, len(filter.IDs))
+		for k := range filter.IDs {
+			ids[k] = filter.IDs[k]
+		}
+		q = q.Where("id IN (?)", ids...)
+	}
+	if filter.SchemaID != "" {
+		q = q.Where("schema_id = ?", filter.SchemaID)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		q = q.Where("created_at < ?", filter.CreatedBefore.UTC())
+	}
+	if !filter.CreatedAfter.IsZero() {
+		q = q.Where("created_at > ?", filter.CreatedAfter.UTC())
+	}
+	if filter.CredentialsIdentifier != "" {
+		parts := strings.Split(filter.CredentialsIdentifier, "*")
+		for k := range parts {
+			parts[k] = likeEscaper.Replace(parts[k])
+		}
+
+		/* #nosec G201 TableName is static */
+		q = q.Where(fmt.Sprintf(`id IN (SELECT ic.identity_id FROM %s ic INNER JOIN %s ici ON ici.identity_credential_id = ic.id WHERE ici.identifier LIKE ? ESCAPE '!')`,
+			new(identity.Credentials).TableName(),
+			new(identity.CredentialIdentifier).TableName(),
+		), strings.Join(parts, "%"))
+	}
+	if filter.Verified != nil {
+		q = q.Where(whereVerified(*filter.Verified), true)
+	}
+
+	var is []identity.Identity
+	if err := q.Select("id").Order("id ASC").All(&is); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	ids := make([]uuid.UUID, len(is))
+	for k := range is {
+		ids[k] = is[k].ID
+	}
+	return ids, nil
+}
+
+func (p *Persister) CreateBulkDeletion(ctx context.Context, job *identity.BulkDeletion) error {
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(job))
+}
+
+func (p *Persister) UpdateBulkDeletion(ctx context.Context, job *identity.BulkDeletion) error {
+	return sqlcon.HandleError(p.GetConnection(ctx).Update(job))
+}
+
+func (p *Persister) GetBulkDeletion(ctx context.Context, id uuid.UUID) (*identity.BulkDeletion, error) {
+	var job identity.BulkDeletion
+	if err := p.GetConnection(ctx).Find(&job, id); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &job, nil
+}
+
+// whereVerified returns the condition matching identities with at least one verified address or, if verified is
+// false, without any. The condition expects true as its argument.
+func whereVerified(verified bool) string {
+	operator := "IN"
+	if !verified {
+		operator = "NOT IN"
+	}
+	/* #nosec G201 TableName and operator are static */
+	return fmt.Sprintf("id %s (SELECT identity_id FROM %s WHERE verified = ?)",
+		operator, new(identity.VerifiableAddress).TableName())
+}
+
+func (p *Persister) ListQuarantinedIdentities(ctx context.Context, page, perPage int) ([]identity.Identity, error) {
+	is := make([]identity.Identity, 0)
+
+	if err := sqlcon.HandleError(p.GetConnection(ctx).Where("quarantined_at IS NOT NULL AND deleted_at IS NULL").
+		Paginate(page, perPage).Order("quarantined_at ASC").
+		Eager("VerifiableAddresses", "RecoveryAddresses", "Labels").All(&is)); err != nil {
+		return nil, err
+	}
+
+	for i := range is {
+		if err := p.migrateTraits(ctx, &(is[i])); err != nil {
+			return nil, err
+		}
+		if err := p.injectTraitsSchemaURL(&(is[i])); err != nil {
+			return nil, err
+		}
+	}
+
+	return is, nil
+}
+
+func (p *Persister) ReleaseIdentityFromQuarantine(ctx context.Context, id uuid.UUID) error {
+	defer p.sessions.invalidateIdentities(id)
+
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET quarantined_at = NULL, quarantine_reason = '', updated_at = ? WHERE id = ? AND quarantined_at IS NOT NULL AND deleted_at IS NULL",
+		new(identity.Identity).TableName()), p.r.Clock().Now().UTC(), id).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) PlaceIdentityOnLegalHold(ctx context.Context, id uuid.UUID, reason string) error {
+	defer p.sessions.invalidateIdentities(id)
+
+	now := p.r.Clock().Now().UTC()
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET legal_hold_at = ?, legal_hold_reason = ?, updated_at = ? WHERE id = ?",
+		new(identity.Identity).TableName()), now, reason, now, id).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) ReleaseIdentityFromLegalHold(ctx context.Context, id uuid.UUID) error {
+	defer p.sessions.invalidateIdentities(id)
+
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET legal_hold_at = NULL, legal_hold_reason = '', updated_at = ? WHERE id = ? AND legal_hold_at IS NOT NULL",
+		new(identity.Identity).TableName()), p.r.Clock().Now().UTC(), id).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) DeleteIdentityCredentials(ctx context.Context, id uuid.UUID, ct identity.CredentialsType) error {
+	defer p.sessions.invalidateIdentities(id)
+
+	// The identifiers of the credentials are removed by the foreign key.
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		`DELETE FROM %s WHERE identity_id = ?
+  AND identity_credential_type_id IN (SELECT id FROM %s WHERE name = ?)
+  AND identity_id IN (SELECT id FROM %s WHERE deleted_at IS NULL)`,
+		new(identity.Credentials).TableName(),
+		new(identity.CredentialsTypeTable).TableName(),
+		new(identity.Identity).TableName()), id, ct).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) SetIdentityLabel(ctx context.Context, id uuid.UUID, name, value string) error {
+	defer p.sessions.invalidateIdentities(id)
+
+	return sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		if exists, err := tx.Where("id = ? AND deleted_at IS NULL", id).Exists(new(identity.Identity)); err != nil {
+			return err
+		} else if !exists {
+			return errors.WithStack(sqlcon.ErrNoRows)
+		}
+
+		/* #nosec G201 TableName is static */
+		if err := tx.RawQuery(fmt.Sprintf("DELETE FROM %s WHERE identity_id = ? AND name = ?",
+			new(identity.Label).TableName()), id, name).Exec(); err != nil {
+			return err
+		}
+
+		return tx.Create(&identity.Label{IdentityID: id, Name: name, Value: value})
+	}))
+}
+
+func (p *Persister) DeleteIdentityLabel(ctx context.Context, id uuid.UUID, name string) error {
+	defer p.sessions.invalidateIdentities(id)
+
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE identity_id = ? AND name = ? AND identity_id IN (SELECT id FROM %s WHERE deleted_at IS NULL)",
+		new(identity.Label).TableName(), new(identity.Identity).TableName()), id, name).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) UpdateIdentityState(ctx context.Context, id uuid.UUID, state identity.State) error {
+	defer p.sessions.invalidateIdentities(id)
+
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET state = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL",
+		new(identity.Identity).TableName()), state, p.r.Clock().Now().UTC(), id).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) UpdateIdentityExpiry(ctx context.Context, id uuid.UUID, expiresAt *time.Time) error {
+	defer p.sessions.invalidateIdentities(id)
+
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET expires_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL",
+		new(identity.Identity).TableName()), expiresAt, p.r.Clock().Now().UTC(), id).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+// The queries of GetIdentity are built once instead of using pop's eager loading because identities are loaded for
+// every request resolving a session.
+var (
+	identityByIDSQL = fmt.Sprintf("SELECT %s FROM identities WHERE id = ? AND deleted_at IS NULL LIMIT 1",
+		selectColumns(new(identity.Identity)))
+	verifiableAddressesByIdentitySQL = fmt.Sprintf("SELECT %s FROM %s WHERE identity_id = ?",
+		selectColumns(new(identity.VerifiableAddress)), new(identity.VerifiableAddress).TableName())
+	recoveryAddressesByIdentitySQL = fmt.Sprintf("SELECT %s FROM %s WHERE identity_id = ?",
+		selectColumns(new(identity.RecoveryAddress)), new(identity.RecoveryAddress).TableName())
+	labelsByIdentitySQL = fmt.Sprintf("SELECT %s FROM %s WHERE identity_id = ? ORDER BY name ASC",
+		selectColumns(new(identity.Label)), new(identity.Label).TableName())
+)
+
 func (p *Persister) GetIdentity(ctx context.Context, id uuid.UUID) (*identity.Identity, error) {
 	var i identity.Identity
-	if err := p.GetConnection(ctx).Eager("VerifiableAddresses", "RecoveryAddresses").Find(&i, id); err != nil {
+	c := p.GetConnection(ctx)
+	if err := c.RawQuery(identityByIDSQL, id).First(&i); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	if err := c.RawQuery(verifiableAddressesByIdentitySQL, id).All(&i.VerifiableAddresses); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	if err := c.RawQuery(recoveryAddressesByIdentitySQL, id).All(&i.RecoveryAddresses); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	if err := c.RawQuery(labelsByIdentitySQL, id).All(&i.Labels); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 	i.Credentials = nil
+	if err := p.migrateTraits(ctx, &i); err != nil {
+		return nil, err
+	}
 	if err := p.injectTraitsSchemaURL(&i); err != nil {
 		return nil, err
 	}
@@ -287,7 +1047,7 @@ func (p *Persister) GetIdentity(ctx context.Context, id uuid.UUID) (*identity.Id
 
 func (p *Persister) GetIdentityConfidential(ctx context.Context, id uuid.UUID) (*identity.Identity, error) {
 	var i identity.Identity
-	if err := p.GetConnection(ctx).Eager().Find(&i, id); err != nil {
+	if err := p.GetConnection(ctx).Where("deleted_at IS NULL").Eager().Find(&i, id); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 
@@ -316,6 +1076,9 @@ func (p *Persister) GetIdentityConfidential(ctx context.Context, id uuid.UUID) (
 		i.Credentials[creds.Type] = creds
 	}
 	i.CredentialsCollection = nil
+	if err := p.migrateTraits(ctx, &i); err != nil {
+		return nil, err
+	}
 	if err := p.injectTraitsSchemaURL(&i); err != nil {
 		return nil, err
 	}
@@ -325,7 +1088,10 @@ func (p *Persister) GetIdentityConfidential(ctx context.Context, id uuid.UUID) (
 
 func (p *Persister) FindVerifiableAddressByValue(ctx context.Context, via identity.VerifiableAddressType, value string) (*identity.VerifiableAddress, error) {
 	var address identity.VerifiableAddress
-	if err := p.GetConnection(ctx).Where("via = ? AND value = ?", via, value).First(&address); err != nil {
+	/* #nosec G201 TableName is static */
+	if err := p.GetConnection(ctx).Where("via = ? AND value = ?", via, value).
+		Where(fmt.Sprintf("identity_id IN (SELECT id FROM %s WHERE deleted_at IS NULL)", new(identity.Identity).TableName())).
+		First(&address); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 
@@ -334,7 +1100,10 @@ func (p *Persister) FindVerifiableAddressByValue(ctx context.Context, via identi
 
 func (p *Persister) FindRecoveryAddressByValue(ctx context.Context, via identity.RecoveryAddressType, value string) (*identity.RecoveryAddress, error) {
 	var address identity.RecoveryAddress
-	if err := p.GetConnection(ctx).Where("via = ? AND value = ?", via, value).First(&address); err != nil {
+	/* #nosec G201 TableName is static */
+	if err := p.GetConnection(ctx).Where("via = ? AND value = ?", via, value).
+		Where(fmt.Sprintf("identity_id IN (SELECT id FROM %s WHERE deleted_at IS NULL)", new(identity.Identity).TableName())).
+		First(&address); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 
@@ -342,6 +1111,9 @@ func (p *Persister) FindRecoveryAddressByValue(ctx context.Context, via identity
 }
 
 func (p *Persister) VerifyAddress(ctx context.Context, code string) error {
+	// The identity of the address is not known without querying it.
+	defer p.sessions.invalidateAll()
+
 	newCode, err := otp.New()
 	if err != nil {
 		return err
@@ -354,10 +1126,10 @@ func (p *Persister) VerifyAddress(ctx context.Context, code string) error {
 			new(identity.VerifiableAddress).TableName(),
 		),
 		identity.VerifiableAddressStatusCompleted,
-		time.Now().UTC().Round(time.Second),
+		p.r.Clock().Now().UTC().Round(time.Second),
 		newCode,
 		code,
-		time.Now().UTC(),
+		p.r.Clock().Now().UTC(),
 	).ExecWithCount()
 	if err != nil {
 		return sqlcon.HandleError(err)
@@ -371,6 +1143,7 @@ func (p *Persister) VerifyAddress(ctx context.Context, code string) error {
 }
 
 func (p *Persister) UpdateVerifiableAddress(ctx context.Context, address *identity.VerifiableAddress) error {
+	defer p.sessions.invalidateIdentities(address.IdentityID)
 	return sqlcon.HandleError(p.GetConnection(ctx).Update(address))
 }
 
@@ -385,6 +1158,11 @@ func (p *Persister) validateIdentity(i *identity.Identity) error {
 	return nil
 }
 
+// migrateTraits migrates the traits of identities created with an older version of their schema.
+func (p *Persister) migrateTraits(ctx context.Context, i *identity.Identity) error {
+	return p.r.IdentitySchemaMigrator().Migrate(ctx, i)
+}
+
 func (p *Persister) injectTraitsSchemaURL(i *identity.Identity) error {
 	s, err := p.r.IdentityTraitsSchemas().GetByID(i.SchemaID)
 	if err != nil {
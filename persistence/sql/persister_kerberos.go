@@ -0,0 +1,31 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/selfservice/strategy/kerberos"
+)
+
+var _ kerberos.ReplayCachePersister = new(Persister)
+
+func (p *Persister) UseKerberosAuthenticator(ctx context.Context, a *kerberos.UsedAuthenticator, now time.Time) error {
+	c := p.GetConnection(ctx)
+
+	/* #nosec G201 TableName is static */
+	if err := c.RawQuery(fmt.Sprintf("DELETE FROM %s WHERE expires_at < ?", a.TableName()), now.UTC()).Exec(); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	if err := sqlcon.HandleError(c.Create(a)); errors.Is(err, sqlcon.ErrUniqueViolation) {
+		return errors.WithStack(kerberos.ErrReplayed)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+)
+
+var _ oidc.ProviderPersister = new(Persister)
+
+func (p *Persister) CreateOIDCProvider(ctx context.Context, sp *oidc.StoredProvider) error {
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(sp))
+}
+
+func (p *Persister) GetOIDCProvider(ctx context.Context, providerID string) (*oidc.StoredProvider, error) {
+	var sp oidc.StoredProvider
+	if err := p.GetConnection(ctx).Where("provider_id = ?", providerID).First(&sp); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &sp, nil
+}
+
+func (p *Persister) ListOIDCProviders(ctx context.Context) ([]oidc.StoredProvider, error) {
+	sps := make([]oidc.StoredProvider, 0)
+	if err := p.GetConnection(ctx).Order("created_at ASC, provider_id ASC").All(&sps); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return sps, nil
+}
+
+func (p *Persister) UpdateOIDCProvider(ctx context.Context, sp *oidc.StoredProvider) error {
+	sp.UpdatedAt = time.Now().UTC()
+
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("UPDATE %s SET config = ?, updated_at = ? WHERE provider_id = ?", sp.TableName()),
+		sp.Config, sp.UpdatedAt, sp.ProviderID).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) DeleteOIDCProvider(ctx context.Context, providerID string) error {
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE provider_id = ?", new(oidc.StoredProvider).TableName()),
+		providerID).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
@@ -2,12 +2,12 @@ package sql
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"time"
 
 	"github.com/gobuffalo/pop/v5"
 	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
 
 	"github.com/ory/x/sqlcon"
 
@@ -91,8 +91,21 @@ func (p *Persister) UseRecoveryToken(ctx context.Context, token string) (*link.R
 		if err != nil {
 			return err
 		}
+
+		// The lookup above is done by the HMAC of the token, compare it in constant time nonetheless.
+		if !p.hmacConstantCompare(token, rt.Token) {
+			return errors.WithStack(sqlcon.ErrNoRows)
+		}
+
+		// Only one of several concurrent requests using the same token may mark it as used.
 		/* #nosec G201 TableName is static */
-		return tx.RawQuery(fmt.Sprintf("UPDATE %s SET used=true, used_at=? WHERE id=?", rt.TableName()), time.Now().UTC(), rt.ID).Exec()
+		count, err := tx.RawQuery(fmt.Sprintf("UPDATE %s SET used=true, used_at=? WHERE id=? AND NOT used", rt.TableName()), time.Now().UTC(), rt.ID).ExecWithCount()
+		if err != nil {
+			return err
+		} else if count != 1 {
+			return errors.WithStack(sqlcon.ErrNoRows)
+		}
+		return nil
 	})); err != nil {
 		return nil, err
 	}
@@ -104,3 +117,10 @@ func (p *Persister) DeleteRecoveryToken(ctx context.Context, token string) error
 	/* #nosec G201 TableName is static */
 	return p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE token=?", new(link.RecoveryToken).TableName()), token).Exec()
 }
+
+func (p *Persister) InvalidateRecoveryTokens(ctx context.Context, identityID uuid.UUID) error {
+	/* #nosec G201 TableName is static */
+	return sqlcon.HandleError(p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET used=true, used_at=? WHERE NOT used AND identity_recovery_address_id IN (SELECT id FROM identity_recovery_addresses WHERE identity_id=?)",
+		new(link.RecoveryToken).TableName()), time.Now().UTC(), identityID).Exec())
+}
@@ -0,0 +1,84 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/scim"
+)
+
+var _ scim.Persister = new(Persister)
+
+func (p *Persister) CreateSCIMResource(ctx context.Context, r *scim.Resource) error {
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(r))
+}
+
+func (p *Persister) GetSCIMResource(ctx context.Context, resourceType string, id uuid.UUID) (*scim.Resource, error) {
+	var r scim.Resource
+	if err := p.GetConnection(ctx).Where("id = ? AND resource_type = ?", id, resourceType).First(&r); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &r, nil
+}
+
+func (p *Persister) UpdateSCIMResource(ctx context.Context, r *scim.Resource) error {
+	r.UpdatedAt = time.Now().UTC()
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET external_id = ?, name = ?, active = ?, attributes = ?, updated_at = ? WHERE id = ? AND resource_type = ?",
+		new(scim.Resource).TableName()), r.ExternalID, r.Name, r.Active, r.Attributes, r.UpdatedAt, r.ID, r.Type).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) DeleteSCIMResource(ctx context.Context, resourceType string, id uuid.UUID) error {
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE id = ? AND resource_type = ?",
+		new(scim.Resource).TableName()), id, resourceType).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) ListSCIMResources(ctx context.Context, resourceType string, filter scim.Filter, offset, limit int) ([]scim.Resource, int, error) {
+	where, args := "resource_type = ?", []interface{}{resourceType}
+	if filter.Name != "" {
+		where, args = where+" AND name = ?", append(args, filter.Name)
+	}
+	if filter.ExternalID != "" {
+		where, args = where+" AND external_id = ?", append(args, filter.ExternalID)
+	}
+
+	var total struct {
+		Count int `db:"count"`
+	}
+	/* #nosec G201 TableName is static */
+	if err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("SELECT COUNT(*) AS count FROM %s WHERE %s",
+		new(scim.Resource).TableName(), where), args...).First(&total); err != nil {
+		return nil, 0, sqlcon.HandleError(err)
+	}
+
+	rs := make([]scim.Resource, 0)
+	/* #nosec G201 TableName is static */
+	if err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("SELECT * FROM %s WHERE %s ORDER BY created_at ASC, id ASC LIMIT %d OFFSET %d",
+		new(scim.Resource).TableName(), where, limit, offset), args...).All(&rs); err != nil {
+		return nil, 0, sqlcon.HandleError(err)
+	}
+
+	return rs, total.Count, nil
+}
@@ -2,11 +2,16 @@ package sql
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/gobuffalo/pop/v5"
 	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
 
 	"github.com/ory/x/sqlcon"
 
+	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/session"
 )
 
@@ -33,19 +38,37 @@ func (p *Persister) CreateSession(ctx context.Context, s *session.Session) error
 }
 
 func (p *Persister) DeleteSession(ctx context.Context, sid uuid.UUID) error {
+	defer p.sessions.invalidateSession(sid)
 	return p.GetConnection(ctx).Destroy(&session.Session{ID: sid}) // This must not be eager or identities will be created / updated
 }
 
 func (p *Persister) DeleteSessionsByIdentity(ctx context.Context, identityID uuid.UUID) error {
+	defer p.sessions.invalidateIdentities(identityID)
+
 	if err := p.GetConnection(ctx).RawQuery("DELETE FROM sessions WHERE identity_id = ?", identityID).Exec(); err != nil {
 		return sqlcon.HandleError(err)
 	}
 	return nil
 }
 
+// sessionByTokenSQL selects a session by its token. It is built once because building the query using pop on every
+// request, which resolves session tokens for every authenticated request, is comparably expensive.
+var sessionByTokenSQL = fmt.Sprintf("SELECT %s FROM sessions WHERE token = ? LIMIT 1", selectColumns(new(session.Session)))
+
 func (p *Persister) GetSessionByToken(ctx context.Context, token string) (*session.Session, error) {
+	// Sessions are not cached within transactions, which may see changes other requests can not see yet.
+	cache := p.cf.SessionCacheEnabled() && ctx.Value(transactionKey) == nil
+	var generation uint64
+	if cache {
+		cached, g, ok := p.sessions.get(token, time.Now())
+		if ok {
+			return cached, nil
+		}
+		generation = g
+	}
+
 	var s session.Session
-	if err := p.GetConnection(ctx).Where("token = ?", token).First(&s); err != nil {
+	if err := p.GetConnection(ctx).RawQuery(sessionByTokenSQL, token).First(&s); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 
@@ -56,10 +79,17 @@ func (p *Persister) GetSessionByToken(ctx context.Context, token string) (*sessi
 		return nil, err
 	}
 	s.Identity = i
+
+	if cache {
+		// The session is not cached if it was changed while it was read.
+		p.sessions.set(token, &s, generation, time.Now(), p.cf.SessionCacheTTL())
+	}
 	return &s, nil
 }
 
 func (p *Persister) DeleteSessionByToken(ctx context.Context, token string) error {
+	defer p.sessions.invalidateToken(token)
+
 	if err := p.GetConnection(ctx).RawQuery("DELETE FROM sessions WHERE token = ?", token).Exec(); err != nil {
 		return sqlcon.HandleError(err)
 	}
@@ -67,8 +97,184 @@ func (p *Persister) DeleteSessionByToken(ctx context.Context, token string) erro
 }
 
 func (p *Persister) RevokeSessionByToken(ctx context.Context, token string) error {
+	defer p.sessions.invalidateToken(token)
+
 	if err := p.GetConnection(ctx).RawQuery("UPDATE sessions SET active = false WHERE token = ?", token).Exec(); err != nil {
 		return sqlcon.HandleError(err)
 	}
 	return nil
 }
+
+func (p *Persister) RevokeSessionOfIdentity(ctx context.Context, identityID, sid uuid.UUID) error {
+	defer p.sessions.invalidateSession(sid)
+
+	// The number of rows affected by the update can not be used to check whether the session exists, because MySQL
+	// only counts the rows which were changed.
+	count, err := p.GetConnection(ctx).Where("id = ? AND identity_id = ?", sid, identityID).Count(new(session.Session))
+	if err != nil {
+		return sqlcon.HandleError(err)
+	} else if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+
+	if err := p.GetConnection(ctx).RawQuery("UPDATE sessions SET active = false WHERE id = ? AND identity_id = ?", sid, identityID).Exec(); err != nil {
+		return sqlcon.HandleError(err)
+	}
+	return nil
+}
+
+func (p *Persister) RevokeSessionsByIdentity(ctx context.Context, identityID uuid.UUID) (int, error) {
+	defer p.sessions.invalidateIdentities(identityID)
+
+	count, err := p.GetConnection(ctx).RawQuery("UPDATE sessions SET active = false WHERE identity_id = ? AND active = true", identityID).ExecWithCount()
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return count, nil
+}
+
+func (p *Persister) UpdateSessionLastActive(ctx context.Context, id uuid.UUID, at time.Time) error {
+	defer p.sessions.invalidateSession(id)
+
+	if err := p.GetConnection(ctx).RawQuery("UPDATE sessions SET last_active_at = ? WHERE id = ?", at.UTC(), id).Exec(); err != nil {
+		return sqlcon.HandleError(err)
+	}
+	return nil
+}
+
+func (p *Persister) UpdateSessionExpiry(ctx context.Context, id uuid.UUID, expiresAt time.Time) error {
+	defer p.sessions.invalidateSession(id)
+
+	if err := p.GetConnection(ctx).RawQuery("UPDATE sessions SET expires_at = ? WHERE id = ?", expiresAt.UTC(), id).Exec(); err != nil {
+		return sqlcon.HandleError(err)
+	}
+	return nil
+}
+
+func (p *Persister) CountSessionsByIdentity(ctx context.Context, identityID uuid.UUID) (int, error) {
+	count, err := p.GetConnection(ctx).Where("identity_id = ?", identityID).Count(new(session.Session))
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return count, nil
+}
+
+func (p *Persister) CountActiveSessions(ctx context.Context, now time.Time) (int, error) {
+	count, err := p.GetConnection(ctx).Where("active = ? AND expires_at > ?", true, now.UTC()).Count(new(session.Session))
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return count, nil
+}
+
+func (p *Persister) whereSessionsMatch(ctx context.Context, filter session.ListSessionsFilter) *pop.Query {
+	// Sessions of deleted identities are kept until the identities are purged but must not be listed.
+	q := p.GetConnection(ctx).Where("identity_id IN (SELECT id FROM identities WHERE deleted_at IS NULL)")
+	if filter.IdentityID != uuid.Nil {
+		q = q.Where("identity_id = ?", filter.IdentityID)
+	}
+	if filter.Active != nil {
+		if *filter.Active {
+			q = q.Where("active = ? AND expires_at > ?", true, filter.Now.UTC())
+		} else {
+			q = q.Where("(active = ? OR expires_at <= ?)", false, filter.Now.UTC())
+		}
+	}
+	if filter.IPAddress != "" {
+		q = q.Where("ip_address = ?", filter.IPAddress)
+	}
+	if !filter.IssuedSince.IsZero() {
+		q = q.Where("issued_at >= ?", filter.IssuedSince.UTC())
+	}
+	if filter.ExceptID != uuid.Nil {
+		q = q.Where("id != ?", filter.ExceptID)
+	}
+	return q
+}
+
+func (p *Persister) ListSessions(ctx context.Context, filter session.ListSessionsFilter, page, perPage int) ([]session.Session, error) {
+	ss := make([]session.Session, 0)
+	if err := p.whereSessionsMatch(ctx, filter).Paginate(page, perPage).Order("issued_at DESC, id DESC").All(&ss); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	// Identities are loaded the same way GetSession does. Sessions of the same identity share it.
+	identities := make(map[uuid.UUID]*identity.Identity)
+	for k := range ss {
+		i, ok := identities[ss[k].IdentityID]
+		if !ok {
+			var err error
+			i, err = p.GetIdentity(ctx, ss[k].IdentityID)
+			if err != nil {
+				return nil, err
+			}
+			identities[ss[k].IdentityID] = i
+		}
+		ss[k].Identity = i
+	}
+
+	return ss, nil
+}
+
+func (p *Persister) CountSessions(ctx context.Context, filter session.ListSessionsFilter) (int, error) {
+	count, err := p.whereSessionsMatch(ctx, filter).Count(new(session.Session))
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return count, nil
+}
+
+func (p *Persister) RevokeExpiredSessions(ctx context.Context, now time.Time, limit int) ([]session.Session, error) {
+	var expired []session.Session
+	if err := p.GetConnection(ctx).
+		Where("active = ? AND expires_at <= ?", true, now.UTC()).
+		Select("id", "identity_id", "expires_at").Order("expires_at ASC").Limit(limit).All(&expired); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	revoked := make([]session.Session, 0, len(expired))
+	for _, s := range expired {
+		count, err := p.GetConnection(ctx).RawQuery("UPDATE sessions SET active = false WHERE id = ? AND active = true", s.ID).ExecWithCount()
+		if err != nil {
+			return nil, sqlcon.HandleError(err)
+		}
+		p.sessions.invalidateSession(s.ID)
+		if count > 0 {
+			revoked = append(revoked, s)
+		}
+	}
+	return revoked, nil
+}
+
+func (p *Persister) ListExpiredIdentitiesWithActiveSessions(ctx context.Context, now time.Time) ([]identity.Identity, error) {
+	var is []identity.Identity
+	/* #nosec G201 TableName is static */
+	if err := p.GetConnection(ctx).
+		Where("expires_at <= ?", now.UTC()).
+		Where(fmt.Sprintf("id IN (SELECT identity_id FROM %s WHERE active = true)", new(session.Session).TableName())).
+		Select("id", "expires_at").Order("expires_at ASC").All(&is); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return is, nil
+}
+
+func (p *Persister) CreateSessionDisclosure(ctx context.Context, d *session.Disclosure) error {
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(d))
+}
+
+func (p *Persister) GetSessionDisclosure(ctx context.Context, token string) (*session.Disclosure, error) {
+	var d session.Disclosure
+	if err := p.GetConnection(ctx).Where("token = ?", token).First(&d); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &d, nil
+}
+
+func (p *Persister) DeleteExpiredSessionDisclosures(ctx context.Context, now time.Time) (int, error) {
+	/* #nosec G201 TableName is static */
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE expires_at <= ?", new(session.Disclosure).TableName()), now.UTC()).ExecWithCount()
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return count, nil
+}
@@ -0,0 +1,42 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/selfservice/stats"
+)
+
+var _ stats.Persister = new(Persister)
+
+func (p *Persister) CreateFlowEvent(ctx context.Context, e *stats.Event) error {
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(e))
+}
+
+func (p *Persister) CountFlowEvents(ctx context.Context, since, until time.Time) ([]stats.EventCount, error) {
+	table := new(stats.Event).TableName()
+	where := "created_at >= ? AND created_at < ?"
+	submissions := where + " AND event IN (?, ?)"
+
+	var counts []stats.EventCount
+	if err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(`SELECT flow, type, method, event, COUNT(*) AS events, COUNT(DISTINCT flow_id) AS flows FROM %[1]s WHERE %[2]s GROUP BY flow, type, method, event
+UNION ALL
+SELECT flow, type, method, '%[4]s' AS event, COUNT(*) AS events, COUNT(DISTINCT flow_id) AS flows FROM %[1]s WHERE %[3]s GROUP BY flow, type, method
+UNION ALL
+SELECT flow, type, '' AS method, event, COUNT(*) AS events, COUNT(DISTINCT flow_id) AS flows FROM %[1]s WHERE %[3]s GROUP BY flow, type, event
+UNION ALL
+SELECT flow, type, '' AS method, '%[4]s' AS event, COUNT(*) AS events, COUNT(DISTINCT flow_id) AS flows FROM %[1]s WHERE %[3]s GROUP BY flow, type`,
+		table, where, submissions, stats.EventSubmitted),
+		since, until,
+		since, until, stats.EventFailed, stats.EventSucceeded,
+		since, until, stats.EventFailed, stats.EventSucceeded,
+		since, until, stats.EventFailed, stats.EventSucceeded,
+	).All(&counts); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	return counts, nil
+}
@@ -7,20 +7,32 @@ import (
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/ory/kratos/driver"
+	"github.com/ory/kratos/driver/config"
 
 	"github.com/go-errors/errors"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
 
+	"github.com/ory/kratos/apikey"
+	"github.com/ory/kratos/breakglass"
+	"github.com/ory/kratos/configversion"
 	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/growth"
 	"github.com/ory/kratos/internal/testhelpers"
 	"github.com/ory/kratos/persistence/sql"
+	"github.com/ory/kratos/scim"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow/recovery"
+	"github.com/ory/kratos/selfservice/stats"
+	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/selfservice/strategy/kerberos"
 	"github.com/ory/kratos/selfservice/strategy/link"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
 	"github.com/ory/kratos/x"
 
 	"github.com/gobuffalo/pop/v5"
@@ -187,10 +199,46 @@ func TestPersister(t *testing.T) {
 				pop.SetLogger(pl(t))
 				link.TestPersister(conf, p)(t)
 			})
+			t.Run("contract=code.TestPersister", func(t *testing.T) {
+				pop.SetLogger(pl(t))
+				code.TestPersister(conf, p)(t)
+			})
+			t.Run("contract=kerberos.TestPersister", func(t *testing.T) {
+				pop.SetLogger(pl(t))
+				kerberos.TestPersister(p)(t)
+			})
 			t.Run("contract=continuity.TestPersister", func(t *testing.T) {
 				pop.SetLogger(pl(t))
 				continuity.TestPersister(p)(t)
 			})
+			t.Run("contract=stats.TestPersister", func(t *testing.T) {
+				pop.SetLogger(pl(t))
+				stats.TestPersister(p)(t)
+			})
+			t.Run("contract=scim.TestPersister", func(t *testing.T) {
+				pop.SetLogger(pl(t))
+				scim.TestPersister(p)(t)
+			})
+			t.Run("contract=configversion.TestPersister", func(t *testing.T) {
+				pop.SetLogger(pl(t))
+				configversion.TestPersister(p)(t)
+			})
+			t.Run("contract=apikey.TestPersister", func(t *testing.T) {
+				pop.SetLogger(pl(t))
+				apikey.TestPersister(conf, p)(t)
+			})
+			t.Run("contract=breakglass.TestPersister", func(t *testing.T) {
+				pop.SetLogger(pl(t))
+				breakglass.TestPersister(p)(t)
+			})
+			t.Run("contract=growth.TestPersister", func(t *testing.T) {
+				pop.SetLogger(pl(t))
+				growth.TestPersister(conf, p)(t)
+			})
+			t.Run("contract=oidc.TestProviderPersister", func(t *testing.T) {
+				pop.SetLogger(pl(t))
+				oidc.TestProviderPersister(p)(t)
+			})
 		})
 	}
 }
@@ -247,3 +295,63 @@ func TestPersister_Transaction(t *testing.T) {
 		assert.Equal(t, sqlcon.ErrNoRows.Error(), err.Error())
 	})
 }
+
+// BenchmarkPersister measures the queries on the core paths of registration, password login, and session checks
+// against SQLite. Set the benchmark time so that enough rows are created for the indices to matter.
+func BenchmarkPersister(b *testing.B) {
+	conf, reg := internal.NewFastRegistryWithMocks(b)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+	conf.MustSet(config.ViperKeyPublicBaseURL, "http://localhost:4433/")
+	p := reg.Persister()
+	ctx := context.Background()
+
+	newIdentity := func() *identity.Identity {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(`{}`)
+		i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+			Type:        identity.CredentialsTypePassword,
+			Identifiers: []string{x.NewUUID().String() + "@ory.sh"},
+			Config:      sqlxx.JSONRawMessage(`{"hashed_password":"$argon2id$v=19$m=16384,t=1,p=1$c2FsdA$aGFzaA"}`),
+		})
+		return i
+	}
+
+	b.Run("method=CreateIdentity", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := p.CreateIdentity(ctx, newIdentity()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	i := newIdentity()
+	require.NoError(b, p.CreateIdentity(ctx, i))
+	identifier := i.Credentials[identity.CredentialsTypePassword].Identifiers[0]
+
+	b.Run("method=FindByCredentialsIdentifier", func(b *testing.B) {
+		for k := 0; k < b.N; k++ {
+			if _, _, err := p.FindByCredentialsIdentifier(ctx, identity.CredentialsTypePassword, identifier); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("method=CreateSession", func(b *testing.B) {
+		for k := 0; k < b.N; k++ {
+			if err := p.CreateSession(ctx, session.NewActiveSession(i, conf, time.Now())); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	s := session.NewActiveSession(i, conf, time.Now())
+	require.NoError(b, p.CreateSession(ctx, s))
+
+	b.Run("method=GetSessionByToken", func(b *testing.B) {
+		for k := 0; k < b.N; k++ {
+			if _, err := p.GetSessionByToken(ctx, s.Token); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -2,12 +2,12 @@ package sql
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"time"
 
 	"github.com/gobuffalo/pop/v5"
 	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
 
 	"github.com/ory/x/sqlcon"
 
@@ -91,8 +91,21 @@ func (p *Persister) UseVerificationToken(ctx context.Context, token string) (*li
 		if err != nil {
 			return err
 		}
+
+		// The lookup above is done by the HMAC of the token, compare it in constant time nonetheless.
+		if !p.hmacConstantCompare(token, rt.Token) {
+			return errors.WithStack(sqlcon.ErrNoRows)
+		}
+
+		// Only one of several concurrent requests using the same token may mark it as used.
 		/* #nosec G201 TableName is static */
-		return tx.RawQuery(fmt.Sprintf("UPDATE %s SET used=true, used_at=? WHERE id=?", rt.TableName()), time.Now().UTC(), rt.ID).Exec()
+		count, err := tx.RawQuery(fmt.Sprintf("UPDATE %s SET used=true, used_at=? WHERE id=? AND NOT used", rt.TableName()), time.Now().UTC(), rt.ID).ExecWithCount()
+		if err != nil {
+			return err
+		} else if count != 1 {
+			return errors.WithStack(sqlcon.ErrNoRows)
+		}
+		return nil
 	})); err != nil {
 		return nil, err
 	}
@@ -104,3 +117,10 @@ func (p *Persister) DeleteVerificationToken(ctx context.Context, token string) e
 	/* #nosec G201 TableName is static */
 	return p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE token=?", new(link.VerificationToken).TableName()), token).Exec()
 }
+
+func (p *Persister) InvalidateVerificationTokens(ctx context.Context, identityID uuid.UUID) error {
+	/* #nosec G201 TableName is static */
+	return sqlcon.HandleError(p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET used=true, used_at=? WHERE NOT used AND identity_verifiable_address_id IN (SELECT id FROM identity_verifiable_addresses WHERE identity_id=?)",
+		new(link.VerificationToken).TableName()), time.Now().UTC(), identityID).Exec())
+}
@@ -0,0 +1,152 @@
+package sql
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/session"
+)
+
+// sessionCacheMaxEntries limits the memory used by the session cache. Sessions are not cached while the cache is full
+// of entries which did not expire yet.
+const sessionCacheMaxEntries = 10000
+
+type (
+	// sessionCache keeps sessions resolved by their token in memory for a short time, because API gateways resolve
+	// the session of nearly every request. Writes to sessions and identities invalidate the affected entries of this
+	// instance only, so other instances may return stale sessions until the entries expire.
+	sessionCache struct {
+		sync.RWMutex
+		entries    map[string]*sessionCacheEntry
+		bySession  map[uuid.UUID]string
+		byIdentity map[uuid.UUID]map[string]bool
+
+		// generation is incremented by every invalidation. Sessions read from the database before an invalidation
+		// may be stale and are not cached.
+		generation uint64
+	}
+
+	sessionCacheEntry struct {
+		session   session.Session
+		expiresAt time.Time
+	}
+)
+
+func newSessionCache() *sessionCache {
+	c := new(sessionCache)
+	c.reset()
+	return c
+}
+
+func (c *sessionCache) reset() {
+	c.entries = map[string]*sessionCacheEntry{}
+	c.bySession = map[uuid.UUID]string{}
+	c.byIdentity = map[uuid.UUID]map[string]bool{}
+}
+
+// get returns a copy of the session cached for the token and the generation of the cache. The generation must be
+// passed to set if the session is read from the database instead.
+func (c *sessionCache) get(token string, now time.Time) (*session.Session, uint64, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	e, ok := c.entries[token]
+	if !ok || !now.Before(e.expiresAt) {
+		return nil, c.generation, false
+	}
+
+	s := e.session
+	if s.Identity != nil {
+		s.Identity = s.Identity.CopyWithoutCredentials()
+	}
+	return &s, c.generation, true
+}
+
+// set caches a copy of the session until now plus ttl unless the cache was invalidated since generation was
+// returned by get.
+func (c *sessionCache) set(token string, s *session.Session, generation uint64, now time.Time, ttl time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if len(c.entries) >= sessionCacheMaxEntries {
+		for token, e := range c.entries {
+			if !now.Before(e.expiresAt) {
+				c.remove(token)
+			}
+		}
+		if len(c.entries) >= sessionCacheMaxEntries {
+			return
+		}
+	}
+
+	c.remove(token)
+	e := &sessionCacheEntry{session: *s, expiresAt: now.Add(ttl)}
+	if s.Identity != nil {
+		e.session.Identity = s.Identity.CopyWithoutCredentials()
+	}
+	c.entries[token] = e
+	c.bySession[s.ID] = token
+	if c.byIdentity[s.IdentityID] == nil {
+		c.byIdentity[s.IdentityID] = map[string]bool{}
+	}
+	c.byIdentity[s.IdentityID][token] = true
+}
+
+// remove deletes the entry of the token and its index entries. The lock must be held.
+func (c *sessionCache) remove(token string) {
+	e, ok := c.entries[token]
+	if !ok {
+		return
+	}
+
+	delete(c.entries, token)
+	if c.bySession[e.session.ID] == token {
+		delete(c.bySession, e.session.ID)
+	}
+	if tokens := c.byIdentity[e.session.IdentityID]; tokens != nil {
+		delete(tokens, token)
+		if len(tokens) == 0 {
+			delete(c.byIdentity, e.session.IdentityID)
+		}
+	}
+}
+
+func (c *sessionCache) invalidateToken(token string) {
+	c.Lock()
+	defer c.Unlock()
+	c.generation++
+	c.remove(token)
+}
+
+func (c *sessionCache) invalidateSession(id uuid.UUID) {
+	c.Lock()
+	defer c.Unlock()
+	c.generation++
+	if token, ok := c.bySession[id]; ok {
+		c.remove(token)
+	}
+}
+
+func (c *sessionCache) invalidateIdentities(ids ...uuid.UUID) {
+	c.Lock()
+	defer c.Unlock()
+	c.generation++
+	for _, id := range ids {
+		for token := range c.byIdentity[id] {
+			c.remove(token)
+		}
+	}
+}
+
+func (c *sessionCache) invalidateAll() {
+	c.Lock()
+	defer c.Unlock()
+	c.generation++
+	c.reset()
+}
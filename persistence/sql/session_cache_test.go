@@ -0,0 +1,116 @@
+package sql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/session"
+)
+
+func TestSessionCache(t *testing.T) {
+	now := time.Now()
+	newSession := func(identityID uuid.UUID) *session.Session {
+		return &session.Session{
+			ID:         uuid.Must(uuid.NewV4()),
+			Token:      uuid.Must(uuid.NewV4()).String(),
+			IdentityID: identityID,
+			Identity: &identity.Identity{
+				ID:     identityID,
+				Traits: identity.Traits(`{}`),
+				Credentials: map[identity.CredentialsType]identity.Credentials{
+					identity.CredentialsTypePassword: {Type: identity.CredentialsTypePassword},
+				},
+			},
+		}
+	}
+
+	t.Run("case=returns copies without credentials until the entry expires", func(t *testing.T) {
+		c := newSessionCache()
+		s := newSession(uuid.Must(uuid.NewV4()))
+		c.set(s.Token, s, 0, now, time.Minute)
+
+		actual, _, ok := c.get(s.Token, now)
+		require.True(t, ok)
+		assert.Equal(t, s.ID, actual.ID)
+		assert.Empty(t, actual.Identity.Credentials)
+		assert.NotEmpty(t, s.Identity.Credentials, "the cached session must not be modified")
+
+		actual.Identity.Traits = identity.Traits(`{"foo":"bar"}`)
+		again, _, ok := c.get(s.Token, now)
+		require.True(t, ok)
+		assert.Equal(t, `{}`, string(again.Identity.Traits))
+
+		_, _, ok = c.get(s.Token, now.Add(time.Minute))
+		assert.False(t, ok)
+		_, _, ok = c.get("unknown", now)
+		assert.False(t, ok)
+	})
+
+	t.Run("case=invalidates entries", func(t *testing.T) {
+		c := newSessionCache()
+		iid := uuid.Must(uuid.NewV4())
+		a, b, other := newSession(iid), newSession(iid), newSession(uuid.Must(uuid.NewV4()))
+		set := func() {
+			for _, s := range []*session.Session{a, b, other} {
+				_, generation, _ := c.get(s.Token, now)
+				c.set(s.Token, s, generation, now, time.Minute)
+			}
+		}
+		cached := func(s *session.Session) bool {
+			_, _, ok := c.get(s.Token, now)
+			return ok
+		}
+
+		set()
+		c.invalidateToken(a.Token)
+		assert.False(t, cached(a))
+		assert.True(t, cached(b))
+
+		set()
+		c.invalidateSession(b.ID)
+		assert.True(t, cached(a))
+		assert.False(t, cached(b))
+
+		set()
+		c.invalidateIdentities(iid)
+		assert.False(t, cached(a))
+		assert.False(t, cached(b))
+		assert.True(t, cached(other))
+
+		set()
+		c.invalidateAll()
+		assert.False(t, cached(other))
+		assert.Empty(t, c.bySession)
+		assert.Empty(t, c.byIdentity)
+
+		set()
+		c.invalidateIdentities(iid, other.IdentityID)
+		assert.Empty(t, c.entries)
+		assert.Empty(t, c.bySession)
+		assert.Empty(t, c.byIdentity)
+	})
+
+	t.Run("case=does not cache sessions read before an invalidation", func(t *testing.T) {
+		c := newSessionCache()
+		s := newSession(uuid.Must(uuid.NewV4()))
+
+		_, generation, ok := c.get(s.Token, now)
+		require.False(t, ok)
+
+		// The session is revoked while it is read from the database.
+		c.invalidateSession(s.ID)
+		c.set(s.Token, s, generation, now, time.Minute)
+		_, _, ok = c.get(s.Token, now)
+		assert.False(t, ok)
+
+		_, generation, _ = c.get(s.Token, now)
+		c.set(s.Token, s, generation, now, time.Minute)
+		_, _, ok = c.get(s.Token, now)
+		assert.True(t, ok)
+	})
+}
@@ -2,6 +2,7 @@ package schema
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -102,3 +103,144 @@ func NewDuplicateCredentialsError() error {
 		Messages: new(text.Messages).Add(text.NewErrorValidationDuplicateCredentials()),
 	})
 }
+
+type ValidationErrorContextNegotiateUnavailableError struct{}
+
+func (r *ValidationErrorContextNegotiateUnavailableError) AddContext(_, _ string) {}
+
+func (r *ValidationErrorContextNegotiateUnavailableError) FinishInstanceContext() {}
+
+func NewNegotiateUnavailableError() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     `signing in with the desktop account is not available`,
+			InstancePtr: "#/",
+			Context:     &ValidationErrorContextNegotiateUnavailableError{},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationLoginNegotiateUnavailable()),
+	})
+}
+
+type ValidationErrorContextClientCertificateError struct{}
+
+func (r *ValidationErrorContextClientCertificateError) AddContext(_, _ string) {}
+
+func (r *ValidationErrorContextClientCertificateError) FinishInstanceContext() {}
+
+func NewClientCertificateError() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     `no valid client certificate was presented`,
+			InstancePtr: "#/",
+			Context:     &ValidationErrorContextClientCertificateError{},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationLoginClientCertificate()),
+	})
+}
+
+type ValidationErrorContextIdentityInactiveError struct{}
+
+func (r *ValidationErrorContextIdentityInactiveError) AddContext(_, _ string) {}
+
+func (r *ValidationErrorContextIdentityInactiveError) FinishInstanceContext() {}
+
+func NewIdentityInactiveError() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     `the account is inactive`,
+			InstancePtr: "#/",
+			Context:     &ValidationErrorContextIdentityInactiveError{},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationLoginIdentityInactive()),
+	})
+}
+
+type ValidationErrorContextIdentityExpiredError struct{}
+
+func (r *ValidationErrorContextIdentityExpiredError) AddContext(_, _ string) {}
+
+func (r *ValidationErrorContextIdentityExpiredError) FinishInstanceContext() {}
+
+func NewIdentityExpiredError(at time.Time) error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     fmt.Sprintf("the account expired at %s", at.Format(time.RFC3339)),
+			InstancePtr: "#/",
+			Context:     &ValidationErrorContextIdentityExpiredError{},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationLoginIdentityExpired(at)),
+	})
+}
+
+type ValidationErrorContextLoginRestrictedError struct{}
+
+func (r *ValidationErrorContextLoginRestrictedError) AddContext(_, _ string) {}
+
+func (r *ValidationErrorContextLoginRestrictedError) FinishInstanceContext() {}
+
+func NewLoginMaintenanceError(until time.Time) error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     fmt.Sprintf("signing in is not possible during maintenance until %s", until.Format(time.RFC3339)),
+			InstancePtr: "#/",
+			Context:     &ValidationErrorContextLoginRestrictedError{},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationLoginMaintenance(until)),
+	})
+}
+
+func NewLoginOutsideAllowedHoursError(next time.Time) error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     fmt.Sprintf("signing in is not possible until %s", next.Format(time.RFC3339)),
+			InstancePtr: "#/",
+			Context:     &ValidationErrorContextLoginRestrictedError{},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationLoginOutsideAllowedHours(next)),
+	})
+}
+
+type ValidationErrorContextEmailUndeliverableError struct{}
+
+func (r *ValidationErrorContextEmailUndeliverableError) AddContext(_, _ string) {}
+
+func (r *ValidationErrorContextEmailUndeliverableError) FinishInstanceContext() {}
+
+func NewEmailUndeliverableError(address string) error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     fmt.Sprintf("the email address %s can not receive mail", address),
+			InstancePtr: "#/",
+			Context:     &ValidationErrorContextEmailUndeliverableError{},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationEmailUndeliverable(address)),
+	})
+}
+
+type ValidationErrorContextVerificationCodeError struct{}
+
+func (r *ValidationErrorContextVerificationCodeError) AddContext(_, _ string) {}
+
+func (r *ValidationErrorContextVerificationCodeError) FinishInstanceContext() {}
+
+func NewVerificationCodeInvalidError() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     `the verification code is invalid`,
+			InstancePtr: "#/code",
+			Context:     &ValidationErrorContextVerificationCodeError{},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationVerificationCodeInvalid()),
+	})
+}
+
+func NewVerificationCodeInvalidOrExpiredError() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     `the verification code is invalid, expired, or was entered incorrectly too often`,
+			InstancePtr: "#/code",
+			Context:     &ValidationErrorContextVerificationCodeError{},
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationVerificationCodeInvalidOrExpired()),
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"path"
 
+	"github.com/ory/x/jsonschemax"
 	"github.com/ory/x/pkgerx"
 
 	"github.com/markbates/pkger"
@@ -18,6 +19,10 @@ var schemas = pkger.Dir("/schema/.schema")
 const (
 	ExtensionRunnerIdentityMetaSchema ExtensionRunnerMetaSchema = "extension/identity.schema.json"
 	extensionName                     string                    = "ory.sh/kratos"
+
+	// PathPropertyPasswordIdentifier is set in the custom properties of paths listed using jsonschemax
+	// if the path is a password identifier.
+	PathPropertyPasswordIdentifier = "passwordIdentifier"
 )
 
 type (
@@ -34,6 +39,11 @@ type (
 		Recovery struct {
 			Via string `json:"via"`
 		} `json:"recovery"`
+		// Phone marks the value as a phone number if set.
+		Phone *struct {
+			// DefaultRegion is the ISO 3166-1 alpha-2 region of numbers entered without a country calling code.
+			DefaultRegion string `json:"default_region"`
+		} `json:"phone"`
 		Mappings struct {
 			Identity struct {
 				Traits []struct {
@@ -57,6 +67,17 @@ type (
 	}
 )
 
+var _ jsonschemax.PathEnhancer = new(ExtensionConfig)
+
+// EnhancePath exposes the extension's configuration to paths listed using jsonschemax, for example
+// to generate forms.
+func (c *ExtensionConfig) EnhancePath(_ jsonschemax.Path) map[string]interface{} {
+	if c.Credentials.Password.Identifier {
+		return map[string]interface{}{PathPropertyPasswordIdentifier: true}
+	}
+	return nil
+}
+
 func NewExtensionRunner(meta ExtensionRunnerMetaSchema, runners ...Extension) (*ExtensionRunner, error) {
 	var err error
 	schema, err := pkgerx.Read(pkger.Open(path.Join(string(schemas), string(meta))))
@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"regexp"
+	"regexp/syntax"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/jsonschema/v3"
+)
+
+// patternComplexity returns the number of instructions of the program the pattern compiles to. Patterns are matched
+// using RE2 semantics, which takes time linear in the length of the value times this number.
+func patternComplexity(re *regexp.Regexp) (int, error) {
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return len(prog.Inst), nil
+}
+
+// checkPatternComplexity returns an error if the schema or one of its subschemas has a pattern which is more complex
+// than the limit.
+func checkPatternComplexity(s *jsonschema.Schema, limit int) error {
+	return walkPatterns(s, map[*jsonschema.Schema]bool{}, func(re *regexp.Regexp) error {
+		complexity, err := patternComplexity(re)
+		if err != nil {
+			return err
+		}
+
+		if complexity > limit {
+			return errors.WithStack(herodot.ErrInternalServerError.
+				WithReasonf("The JSON schema contains a pattern which is too complex to be validated safely.").
+				WithDebugf("Pattern %q has a complexity of %d which exceeds the limit of %d.", re.String(), complexity, limit))
+		}
+		return nil
+	})
+}
+
+func walkPatterns(s *jsonschema.Schema, visited map[*jsonschema.Schema]bool, fn func(re *regexp.Regexp) error) error {
+	if s == nil || visited[s] {
+		return nil
+	}
+	visited[s] = true
+
+	if s.Pattern != nil {
+		if err := fn(s.Pattern); err != nil {
+			return err
+		}
+	}
+
+	children := []*jsonschema.Schema{s.Ref, s.Not, s.If, s.Then, s.Else, s.PropertyNames, s.Contains}
+	children = append(children, s.AllOf...)
+	children = append(children, s.AnyOf...)
+	children = append(children, s.OneOf...)
+
+	for _, child := range s.Properties {
+		children = append(children, child)
+	}
+
+	for re, child := range s.PatternProperties {
+		if err := fn(re); err != nil {
+			return err
+		}
+		children = append(children, child)
+	}
+
+	for _, d := range s.Dependencies {
+		if child, ok := d.(*jsonschema.Schema); ok {
+			children = append(children, child)
+		}
+	}
+
+	for _, v := range []interface{}{s.AdditionalProperties, s.AdditionalItems, s.Items} {
+		switch child := v.(type) {
+		case *jsonschema.Schema:
+			children = append(children, child)
+		case []*jsonschema.Schema:
+			children = append(children, child...)
+		}
+	}
+
+	for _, child := range children {
+		if err := walkPatterns(child, visited, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,6 +3,8 @@ package schema
 import (
 	"io/ioutil"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -19,20 +21,71 @@ import (
 	"github.com/ory/x/urlx"
 )
 
+// VersionSeparator separates the ID and the version of a schema in references such as `customer@2`.
+const VersionSeparator = "@"
+
 type Schemas []Schema
 
+// GetByID returns the schema with the given ID. The ID may reference a version, as in `customer@2`. Otherwise
+// the latest version is returned.
 func (s Schemas) GetByID(id string) (*Schema, error) {
 	if id == "" {
 		id = config.DefaultIdentityTraitsSchemaID
 	}
 
+	version := 0
+	if at := strings.LastIndex(id, VersionSeparator); at >= 0 {
+		var err error
+		if version, err = strconv.Atoi(id[at+1:]); err != nil {
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to parse the version of JSON Schema ID: %s", id))
+		}
+		id = id[:at]
+	}
+
+	var found *Schema
+	for k := range s {
+		ss := s[k]
+		if ss.ID != id {
+			continue
+		}
+		if version > 0 && ss.GetVersion() == version {
+			return &ss, nil
+		}
+		if version == 0 && (found == nil || ss.GetVersion() > found.GetVersion()) {
+			found = &ss
+		}
+	}
+
+	if found == nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to find JSON Schema ID: %s", s.ref(id, version)))
+	}
+	return found, nil
+}
+
+// Versions returns the versions of the schema with the given ID, lowest first.
+func (s Schemas) Versions(id string) Schemas {
+	if id == "" {
+		id = config.DefaultIdentityTraitsSchemaID
+	}
+
+	var versions Schemas
 	for _, ss := range s {
 		if ss.ID == id {
-			return &ss, nil
+			versions = append(versions, ss)
 		}
 	}
 
-	return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to find JSON Schema ID: %s", id))
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].GetVersion() < versions[j].GetVersion()
+	})
+	return versions
+}
+
+func (s Schemas) ref(id string, version int) string {
+	if version == 0 {
+		return id
+	}
+	return id + VersionSeparator + strconv.Itoa(version)
 }
 
 var orderedKeyCacheMutex sync.RWMutex
@@ -81,6 +134,20 @@ type Schema struct {
 	ID     string   `json:"id"`
 	URL    *url.URL `json:"-"`
 	RawURL string   `json:"url"`
+
+	// Version is the version of the schema or zero if the schema is not versioned.
+	Version int `json:"version,omitempty"`
+
+	// MigrationURL points to a Jsonnet file migrating traits from the previous version.
+	MigrationURL string `json:"-"`
+}
+
+// GetVersion returns the version of the schema. Schemas without a version have version 1.
+func (s *Schema) GetVersion() int {
+	if s.Version < 1 {
+		return 1
+	}
+	return s.Version
 }
 
 func (s *Schema) SchemaURL(host *url.URL) *url.URL {
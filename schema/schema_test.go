@@ -68,6 +68,39 @@ func TestSchemas_GetByID(t *testing.T) {
 		require.Error(t, err)
 		assert.Equal(t, (*Schema)(nil), s)
 	})
+
+	t.Run("case=versions", func(t *testing.T) {
+		ss := Schemas{
+			Schema{ID: "customer", Version: 2, RawURL: "http://v2.com"},
+			Schema{ID: "customer", RawURL: "http://v1.com"},
+			Schema{ID: "customer", Version: 3, RawURL: "http://v3.com"},
+		}
+
+		s, err := ss.GetByID("customer")
+		require.NoError(t, err)
+		assert.Equal(t, 3, s.GetVersion(), "returns the latest version")
+
+		s, err = ss.GetByID("customer@1")
+		require.NoError(t, err)
+		assert.Equal(t, "http://v1.com", s.RawURL)
+
+		s, err = ss.GetByID("customer@2")
+		require.NoError(t, err)
+		assert.Equal(t, "http://v2.com", s.RawURL)
+
+		_, err = ss.GetByID("customer@4")
+		require.Error(t, err)
+
+		_, err = ss.GetByID("customer@latest")
+		require.Error(t, err)
+
+		var versions []int
+		for _, s := range ss.Versions("customer") {
+			versions = append(versions, s.GetVersion())
+		}
+		assert.Equal(t, []int{1, 2, 3}, versions)
+		assert.Empty(t, ss.Versions("foo"))
+	})
 }
 
 func TestGetKeysInOrder(t *testing.T) {
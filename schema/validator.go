@@ -25,7 +25,8 @@ func NewValidator() *Validator {
 }
 
 type validatorOptions struct {
-	e *ExtensionRunner
+	e                    *ExtensionRunner
+	maxPatternComplexity int
 }
 
 func WithExtensionRunner(e *ExtensionRunner) func(*validatorOptions) {
@@ -34,6 +35,14 @@ func WithExtensionRunner(e *ExtensionRunner) func(*validatorOptions) {
 	}
 }
 
+// WithMaxPatternComplexity rejects schemas with patterns which compile to more than the given number of
+// instructions, because matching a pattern takes time linear in the length of the value times its complexity.
+func WithMaxPatternComplexity(limit int) func(*validatorOptions) {
+	return func(o *validatorOptions) {
+		o.maxPatternComplexity = limit
+	}
+}
+
 func (v *Validator) Validate(
 	href string,
 	document json.RawMessage,
@@ -63,6 +72,12 @@ func (v *Validator) Validate(
 		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse validate JSON object against JSON schema.").WithDebugf("%s", err))
 	}
 
+	if o.maxPatternComplexity > 0 {
+		if err := checkPatternComplexity(schema, o.maxPatternComplexity); err != nil {
+			return err
+		}
+	}
+
 	if err := schema.Validate(bytes.NewBuffer(document)); err != nil {
 		return errors.WithStack(err)
 	}
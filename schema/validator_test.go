@@ -8,8 +8,12 @@ import (
 	"testing"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/herodot"
+	"github.com/ory/jsonschema/v3"
 	"github.com/ory/x/stringsx"
 )
 
@@ -68,3 +72,22 @@ func TestSchemaValidator(t *testing.T) {
 		})
 	}
 }
+
+func TestSchemaValidatorLimits(t *testing.T) {
+	href := "file://./stub/validator/pattern.schema.json"
+
+	t.Run("case=rejects schemas with too complex patterns", func(t *testing.T) {
+		err := NewValidator().Validate(href, json.RawMessage(`{"email":"foo@bar"}`), WithMaxPatternComplexity(2000))
+		require.Error(t, err)
+		assert.Contains(t, herodot.ToDefaultError(err, "").Debug(), `"^(a|b|c){1,1000}$"`)
+
+		require.NoError(t, NewValidator().Validate(href, json.RawMessage(`{"email":"foo@bar","username":"abc"}`), WithMaxPatternComplexity(5000)))
+		require.NoError(t, NewValidator().Validate(href, json.RawMessage(`{"username":"abc"}`)), "the complexity is not limited by default")
+	})
+
+	t.Run("case=validates patterns", func(t *testing.T) {
+		err := NewValidator().Validate(href, json.RawMessage(`{"email":"foo"}`), WithMaxPatternComplexity(5000))
+		var validationErr *jsonschema.ValidationError
+		require.True(t, errors.As(err, &validationErr), "%+v", err)
+	})
+}
@@ -0,0 +1,71 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/x"
+)
+
+const contentType = "application/scim+json"
+
+// Error is a herodot error carrying the SCIM error type (`scimType`) of RFC 7644 section 3.12.
+type Error struct {
+	*herodot.DefaultError
+	scimType string
+}
+
+func newError(err *herodot.DefaultError, scimType string) *Error {
+	return &Error{DefaultError: err, scimType: scimType}
+}
+
+type errorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var scimType, detail string
+	code := x.RecoverStatusCode(err, http.StatusInternalServerError)
+	if e := new(Error); errors.As(err, &e) {
+		scimType = e.scimType
+	} else if errors.Is(err, sqlcon.ErrUniqueViolation) {
+		scimType = "uniqueness"
+	}
+	if e := new(herodot.DefaultError); errors.As(err, &e) {
+		detail = e.Reason()
+	}
+	if detail == "" {
+		detail = http.StatusText(code)
+	}
+
+	if code >= http.StatusInternalServerError {
+		h.d.Logger().WithRequest(r).WithError(err).Error("An error occurred while handling a SCIM request.")
+		detail = "An internal error occurred."
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(&errorResponse{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(code),
+		SCIMType: scimType,
+		Detail:   detail,
+	})
+}
+
+func (h *Handler) write(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.d.Logger().WithRequest(r).WithError(err).Error("Unable to write SCIM response.")
+	}
+}
@@ -0,0 +1,558 @@
+package scim
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/google/go-jsonnet"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/fetcher"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteBase                  = "/scim/v2"
+	RouteUsers                 = RouteBase + "/Users"
+	RouteGroups                = RouteBase + "/Groups"
+	RouteServiceProviderConfig = RouteBase + "/ServiceProviderConfig"
+
+	defaultItemsPerPage = 100
+	maxItemsPerPage     = 500
+)
+
+type (
+	handlerDependencies interface {
+		x.LoggingProvider
+		identity.ManagementProvider
+		identity.PrivilegedPoolProvider
+		session.PersistenceProvider
+		session.BackChannelNotifierProvider
+		PersistenceProvider
+	}
+	HandlerProvider interface {
+		SCIMHandler() *Handler
+	}
+	Handler struct {
+		d handlerDependencies
+		c *config.Provider
+		f *fetcher.Fetcher
+	}
+
+	listResponse struct {
+		Schemas      []string                 `json:"schemas"`
+		TotalResults int                      `json:"totalResults"`
+		StartIndex   int                      `json:"startIndex"`
+		ItemsPerPage int                      `json:"itemsPerPage"`
+		Resources    []map[string]interface{} `json:"Resources"`
+	}
+)
+
+// These attributes are managed by Kratos or stored in dedicated columns and are never copied into the attributes.
+var managedAttributes = []string{"schemas", "id", "meta", "externalId", "userName", "displayName", "active", "password"}
+
+var filterExpression = regexp.MustCompile(`^\s*([A-Za-z]+)\s+(?i:eq)\s+"([^"]*)"\s*$`)
+
+func NewHandler(d handlerDependencies, c *config.Provider) *Handler {
+	return &Handler{d: d, c: c, f: fetcher.NewFetcher()}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteServiceProviderConfig, h.authenticate(h.serviceProviderConfig))
+
+	admin.GET(RouteUsers, h.authenticate(h.list(ResourceTypeUser)))
+	admin.POST(RouteUsers, h.authenticate(h.createUser))
+	admin.GET(RouteUsers+"/:id", h.authenticate(h.get(ResourceTypeUser)))
+	admin.PUT(RouteUsers+"/:id", h.authenticate(h.replace(ResourceTypeUser)))
+	admin.PATCH(RouteUsers+"/:id", h.authenticate(h.patch(ResourceTypeUser)))
+	admin.DELETE(RouteUsers+"/:id", h.authenticate(h.deleteUser))
+
+	admin.GET(RouteGroups, h.authenticate(h.list(ResourceTypeGroup)))
+	admin.POST(RouteGroups, h.authenticate(h.createGroup))
+	admin.GET(RouteGroups+"/:id", h.authenticate(h.get(ResourceTypeGroup)))
+	admin.PUT(RouteGroups+"/:id", h.authenticate(h.replace(ResourceTypeGroup)))
+	admin.PATCH(RouteGroups+"/:id", h.authenticate(h.patch(ResourceTypeGroup)))
+	admin.DELETE(RouteGroups+"/:id", h.authenticate(h.deleteGroup))
+}
+
+// authenticate only lets requests pass which carry the configured bearer token.
+func (h *Handler) authenticate(next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		expected := h.c.SCIM().Token
+		header := r.Header.Get("Authorization")
+		if len(expected) == 0 || !strings.HasPrefix(header, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, "Bearer ")), []byte(expected)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="SCIM"`)
+			h.writeError(w, r, errors.WithStack(herodot.ErrUnauthorized.WithReason("A valid bearer token is required.")))
+			return
+		}
+		next(w, r, ps)
+	}
+}
+
+func (h *Handler) serviceProviderConfig(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.write(w, r, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{SchemaServiceProviderConfig},
+		"patch":          map[string]interface{}{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": maxItemsPerPage},
+		"changePassword": map[string]interface{}{"supported": false},
+		"sort":           map[string]interface{}{"supported": false},
+		"etag":           map[string]interface{}{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "Authentication with the bearer token configured in `scim.token`.",
+			"primary":     true,
+		}},
+	})
+}
+
+func (h *Handler) location(res *Resource) string {
+	route := RouteUsers
+	if res.Type == ResourceTypeGroup {
+		route = RouteGroups
+	}
+	return urlx.AppendPaths(h.c.SelfAdminURL(), route, res.ID.String()).String()
+}
+
+// render returns the SCIM representation of a resource.
+func (h *Handler) render(res *Resource) (map[string]interface{}, error) {
+	doc := map[string]interface{}{}
+	if len(res.Attributes) > 0 {
+		if err := json.Unmarshal(res.Attributes, &doc); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	doc["id"] = res.ID.String()
+	if len(res.ExternalID) > 0 {
+		doc["externalId"] = res.ExternalID
+	}
+	if res.Type == ResourceTypeGroup {
+		doc["schemas"] = []string{SchemaGroup}
+		doc["displayName"] = res.Name
+	} else {
+		doc["schemas"] = []string{SchemaUser}
+		doc["userName"] = res.Name
+		doc["active"] = res.Active
+	}
+	doc["meta"] = map[string]interface{}{
+		"resourceType": res.Type,
+		"created":      res.CreatedAt.UTC().Format(time.RFC3339),
+		"lastModified": res.UpdatedAt.UTC().Format(time.RFC3339),
+		"location":     h.location(res),
+	}
+	return doc, nil
+}
+
+// parse copies a SCIM representation into the resource.
+func parse(res *Resource, doc map[string]interface{}) error {
+	nameAttribute := "userName"
+	if res.Type == ResourceTypeGroup {
+		nameAttribute = "displayName"
+	}
+
+	name, _ := doc[key(doc, nameAttribute)].(string)
+	if len(name) == 0 {
+		return errors.WithStack(newError(herodot.ErrBadRequest.WithReasonf(`The attribute "%s" is required.`, nameAttribute), "invalidValue"))
+	}
+	res.Name = name
+	res.ExternalID, _ = doc[key(doc, "externalId")].(string)
+
+	res.Active = true
+	switch active := doc[key(doc, "active")].(type) {
+	case bool:
+		res.Active = active
+	case string:
+		// Some clients, for example Azure AD, send booleans as strings.
+		res.Active = !strings.EqualFold(active, "false")
+	}
+
+	attributes := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		attributes[k] = v
+	}
+	for _, managed := range managedAttributes {
+		delete(attributes, key(attributes, managed))
+	}
+
+	raw, err := json.Marshal(attributes)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	res.Attributes = sqlxx.JSONRawMessage(raw)
+	return nil
+}
+
+func decode(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return errors.WithStack(newError(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err), "invalidSyntax"))
+	}
+	return nil
+}
+
+// traits runs the configured Jsonnet mapper with the SCIM user and returns the identity traits.
+func (h *Handler) traits(r *http.Request, user map[string]interface{}) (identity.Traits, error) {
+	mapper := h.c.SCIM().MapperURL
+	jn, err := h.f.Fetch(mapper)
+	if err != nil {
+		return nil, err
+	}
+
+	var input bytes.Buffer
+	if err := json.NewEncoder(&input).Encode(user); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtCode("user", input.String())
+	evaluated, err := vm.EvaluateSnippet(mapper, jn.String())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	h.d.Logger().
+		WithRequest(r).
+		WithSensitiveField("scim_user", user).
+		WithField("mapper_jsonnet_output", evaluated).
+		WithField("mapper_jsonnet_url", mapper).
+		Debug("SCIM Jsonnet mapper completed.")
+
+	traits := gjson.Get(evaluated, "identity.traits")
+	if !traits.IsObject() {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The SCIM Jsonnet mapper did not return an object for key identity.traits. Please check your Jsonnet code!"))
+	}
+	return identity.Traits(traits.Raw), nil
+}
+
+func (h *Handler) list(resourceType string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		var filter Filter
+		if expression := r.URL.Query().Get("filter"); len(expression) > 0 {
+			m := filterExpression.FindStringSubmatch(expression)
+			if m == nil {
+				h.writeError(w, r, errors.WithStack(newError(herodot.ErrBadRequest.WithReasonf(`The filter "%s" is not supported. Only "eq" filters are supported.`, expression), "invalidFilter")))
+				return
+			}
+
+			switch attribute := strings.ToLower(m[1]); {
+			case attribute == "externalid":
+				filter.ExternalID = m[2]
+			case attribute == "username" && resourceType == ResourceTypeUser,
+				attribute == "displayname" && resourceType == ResourceTypeGroup:
+				filter.Name = m[2]
+			default:
+				h.writeError(w, r, errors.WithStack(newError(herodot.ErrBadRequest.WithReasonf(`Filtering by attribute "%s" is not supported.`, m[1]), "invalidFilter")))
+				return
+			}
+		}
+
+		startIndex, _ := strconv.Atoi(r.URL.Query().Get("startIndex"))
+		if startIndex < 1 {
+			startIndex = 1
+		}
+
+		count := defaultItemsPerPage
+		if raw := r.URL.Query().Get("count"); len(raw) > 0 {
+			count, _ = strconv.Atoi(raw)
+		}
+		if count < 0 {
+			count = 0
+		} else if count > maxItemsPerPage {
+			count = maxItemsPerPage
+		}
+
+		rs, total, err := h.d.SCIMPersister().ListSCIMResources(r.Context(), resourceType, filter, startIndex-1, count)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		response := &listResponse{
+			Schemas:      []string{SchemaListResponse},
+			TotalResults: total,
+			StartIndex:   startIndex,
+			ItemsPerPage: len(rs),
+			Resources:    make([]map[string]interface{}, len(rs)),
+		}
+		for k := range rs {
+			if response.Resources[k], err = h.render(&rs[k]); err != nil {
+				h.writeError(w, r, err)
+				return
+			}
+		}
+
+		h.write(w, r, http.StatusOK, response)
+	}
+}
+
+func (h *Handler) get(resourceType string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		res, err := h.d.SCIMPersister().GetSCIMResource(r.Context(), resourceType, x.ParseUUID(ps.ByName("id")))
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		doc, err := h.render(res)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		h.write(w, r, http.StatusOK, doc)
+	}
+}
+
+func (h *Handler) createUser(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var doc map[string]interface{}
+	if err := decode(r, &doc); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	res := &Resource{ID: x.NewUUID(), Type: ResourceTypeUser}
+	if err := parse(res, doc); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// Check the user name before creating the identity so that no identity is left behind.
+	if _, total, err := h.d.SCIMPersister().ListSCIMResources(r.Context(), ResourceTypeUser, Filter{Name: res.Name}, 0, 1); err != nil {
+		h.writeError(w, r, err)
+		return
+	} else if total > 0 {
+		h.writeError(w, r, errors.WithStack(newError(herodot.ErrConflict.WithReasonf(`A user with userName "%s" exists already.`, res.Name), "uniqueness")))
+		return
+	}
+
+	doc["id"] = res.ID.String()
+	traits, err := h.traits(r, doc)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	i := &identity.Identity{ID: res.ID, SchemaID: h.c.SCIM().SchemaID, Traits: traits, State: identityState(res)}
+	if err := h.d.IdentityManager().Create(r.Context(), i); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	res.IdentityID = uuid.NullUUID{UUID: i.ID, Valid: true}
+	if err := h.d.SCIMPersister().CreateSCIMResource(r.Context(), res); err != nil {
+		if deleteErr := h.d.PrivilegedIdentityPool().DeleteIdentity(r.Context(), i.ID); deleteErr != nil {
+			h.d.Logger().WithRequest(r).WithError(deleteErr).WithField("identity_id", i.ID).
+				Error("Unable to delete the identity of a SCIM user which could not be created.")
+		}
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.d.Audit().
+		WithRequest(r).
+		WithField("identity_id", i.ID).
+		WithField("scim_external_id", res.ExternalID).
+		Info("An identity was provisioned using SCIM.")
+
+	h.writeResource(w, r, http.StatusCreated, res)
+}
+
+func (h *Handler) createGroup(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var doc map[string]interface{}
+	if err := decode(r, &doc); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	res := &Resource{ID: x.NewUUID(), Type: ResourceTypeGroup}
+	if err := parse(res, doc); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if err := h.d.SCIMPersister().CreateSCIMResource(r.Context(), res); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.writeResource(w, r, http.StatusCreated, res)
+}
+
+func (h *Handler) replace(resourceType string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		res, err := h.d.SCIMPersister().GetSCIMResource(r.Context(), resourceType, x.ParseUUID(ps.ByName("id")))
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		var doc map[string]interface{}
+		if err := decode(r, &doc); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		h.update(w, r, res, doc)
+	}
+}
+
+func (h *Handler) patch(resourceType string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		res, err := h.d.SCIMPersister().GetSCIMResource(r.Context(), resourceType, x.ParseUUID(ps.ByName("id")))
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		var p PatchRequest
+		if err := decode(r, &p); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		doc, err := h.render(res)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		if err := ApplyPatch(doc, p.Operations); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		h.update(w, r, res, doc)
+	}
+}
+
+// update replaces the resource with doc. The traits of users are mapped again and deactivating a user revokes all
+// of its sessions.
+func (h *Handler) update(w http.ResponseWriter, r *http.Request, res *Resource, doc map[string]interface{}) {
+	wasActive := res.Active
+	if err := parse(res, doc); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if res.Type == ResourceTypeUser {
+		doc["id"] = res.ID.String()
+		traits, err := h.traits(r, doc)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		if err := h.d.IdentityManager().UpdateTraits(r.Context(), res.ID, traits, identity.ManagerAllowWriteProtectedTraits); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+	}
+
+	if err := h.d.SCIMPersister().UpdateSCIMResource(r.Context(), res); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if res.Type == ResourceTypeUser && wasActive != res.Active {
+		if err := h.d.PrivilegedIdentityPool().UpdateIdentityState(r.Context(), res.ID, identityState(res)); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		if !res.Active {
+			if err := h.d.SessionPersister().DeleteSessionsByIdentity(r.Context(), res.ID); err != nil {
+				h.writeError(w, r, err)
+				return
+			}
+			h.d.SessionBackChannelNotifier().IdentitySessionsRevoked(res.ID, session.RevocationReasonIdentityDeactivated)
+		}
+
+		h.d.Audit().
+			WithRequest(r).
+			WithField("identity_id", res.ID).
+			WithField("active", res.Active).
+			Info("The activation state of an identity was changed using SCIM.")
+	}
+
+	h.writeResource(w, r, http.StatusOK, res)
+}
+
+// identityState returns the state of the identity of a user resource.
+func identityState(res *Resource) identity.State {
+	if res.Active {
+		return identity.StateActive
+	}
+	return identity.StateInactive
+}
+
+func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id := x.ParseUUID(ps.ByName("id"))
+
+	// Only identities provisioned using SCIM may be deleted using SCIM.
+	if _, err := h.d.SCIMPersister().GetSCIMResource(r.Context(), ResourceTypeUser, id); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// Deleting the identity deletes the resource as well.
+	if err := h.d.PrivilegedIdentityPool().DeleteIdentity(r.Context(), id); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.d.Audit().
+		WithRequest(r).
+		WithField("identity_id", id).
+		Info("An identity was deprovisioned using SCIM.")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) deleteGroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.d.SCIMPersister().DeleteSCIMResource(r.Context(), ResourceTypeGroup, x.ParseUUID(ps.ByName("id"))); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) writeResource(w http.ResponseWriter, r *http.Request, code int, res *Resource) {
+	// Reload the resource to return the timestamps as they were stored.
+	stored, err := h.d.SCIMPersister().GetSCIMResource(r.Context(), res.Type, res.ID)
+	if err != nil {
+		if errors.Is(err, sqlcon.ErrNoRows) {
+			err = errors.WithStack(herodot.ErrInternalServerError.WithReason("The resource was removed while it was being written."))
+		}
+		h.writeError(w, r, err)
+		return
+	}
+
+	doc, err := h.render(stored)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", h.location(stored))
+	h.write(w, r, code, doc)
+}
@@ -0,0 +1,181 @@
+package scim_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/scim"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+func TestHandler(t *testing.T) {
+	const token = "a-very-secret-scim-token"
+
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	router := x.NewRouterAdmin()
+	reg.SCIMHandler().RegisterAdminRoutes(router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	conf.MustSet(config.ViperKeyAdminBaseURL, ts.URL)
+	testhelpers.SetDefaultIdentitySchema(t, conf, "file://./stub/identity.schema.json")
+	conf.MustSet(config.ViperKeySCIMEnabled, true)
+	conf.MustSet(config.ViperKeySCIMToken, token)
+	conf.MustSet(config.ViperKeySCIMMapperURL, "file://./stub/user.jsonnet")
+
+	var send = func(t *testing.T, method, href, bearer string, expectCode int, body interface{}) gjson.Result {
+		var b bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&b).Encode(body))
+		}
+		req, err := http.NewRequest(method, ts.URL+href, &b)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/scim+json")
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		res, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		payload, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		require.EqualValues(t, expectCode, res.StatusCode, "%s", payload)
+		return gjson.ParseBytes(payload)
+	}
+
+	t.Run("case=requires the bearer token", func(t *testing.T) {
+		res := send(t, "GET", scim.RouteUsers, "wrong-token", http.StatusUnauthorized, nil)
+		assert.Equal(t, scim.SchemaError, res.Get("schemas.0").String())
+		assert.Equal(t, "401", res.Get("status").String())
+	})
+
+	t.Run("case=serves the service provider config", func(t *testing.T) {
+		res := send(t, "GET", scim.RouteServiceProviderConfig, token, http.StatusOK, nil)
+		assert.True(t, res.Get("patch.supported").Bool())
+		assert.False(t, res.Get("bulk.supported").Bool())
+	})
+
+	t.Run("case=provisions, updates, and deprovisions a user", func(t *testing.T) {
+		user := send(t, "POST", scim.RouteUsers, token, http.StatusCreated, map[string]interface{}{
+			"schemas":    []string{scim.SchemaUser},
+			"userName":   "alice@example.com",
+			"externalId": "00u1",
+			"name":       map[string]interface{}{"formatted": "Alice Doe"},
+			"active":     true,
+		})
+		id := user.Get("id").String()
+		assert.Equal(t, "alice@example.com", user.Get("userName").String())
+		assert.Equal(t, "00u1", user.Get("externalId").String())
+		assert.True(t, user.Get("active").Bool())
+		assert.Equal(t, ts.URL+scim.RouteUsers+"/"+id, user.Get("meta.location").String())
+
+		i, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), x.ParseUUID(id))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"email":"alice@example.com","name":"Alice Doe"}`, string(i.Traits))
+
+		t.Run("case=rejects duplicate user names", func(t *testing.T) {
+			res := send(t, "POST", scim.RouteUsers, token, http.StatusConflict, map[string]interface{}{"userName": "alice@example.com"})
+			assert.Equal(t, "uniqueness", res.Get("scimType").String())
+		})
+
+		t.Run("case=lists and filters users", func(t *testing.T) {
+			res := send(t, "GET", scim.RouteUsers+`?filter=userName+eq+"alice@example.com"`, token, http.StatusOK, nil)
+			assert.EqualValues(t, 1, res.Get("totalResults").Int())
+			assert.Equal(t, id, res.Get("Resources.0.id").String())
+
+			res = send(t, "GET", scim.RouteUsers+`?filter=externalId+eq+"unknown"`, token, http.StatusOK, nil)
+			assert.EqualValues(t, 0, res.Get("totalResults").Int())
+
+			res = send(t, "GET", scim.RouteUsers+`?filter=title+co+"x"`, token, http.StatusBadRequest, nil)
+			assert.Equal(t, "invalidFilter", res.Get("scimType").String())
+		})
+
+		t.Run("case=replaces the user", func(t *testing.T) {
+			res := send(t, "PUT", scim.RouteUsers+"/"+id, token, http.StatusOK, map[string]interface{}{
+				"schemas":  []string{scim.SchemaUser},
+				"userName": "alice@example.com",
+				"name":     map[string]interface{}{"formatted": "Alice Smith"},
+				"title":    "Engineer",
+			})
+			assert.Equal(t, "Engineer", res.Get("title").String())
+
+			i, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), x.ParseUUID(id))
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"email":"alice@example.com","name":"Alice Smith"}`, string(i.Traits))
+		})
+
+		t.Run("case=deactivating the user revokes its sessions", func(t *testing.T) {
+			s := session.NewActiveSession(i, conf, time.Now().UTC())
+			require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+
+			res := send(t, "PATCH", scim.RouteUsers+"/"+id, token, http.StatusOK, map[string]interface{}{
+				"schemas":    []string{scim.SchemaPatchOp},
+				"Operations": []map[string]interface{}{{"op": "replace", "path": "active", "value": "False"}},
+			})
+			assert.False(t, res.Get("active").Bool())
+			assert.Equal(t, "Engineer", res.Get("title").String())
+
+			_, err := reg.SessionPersister().GetSession(context.Background(), s.ID)
+			require.Error(t, err)
+
+			r := httptest.NewRequest("GET", "/", nil)
+			require.Error(t, reg.SCIMLoginHook().ExecuteLoginPostHook(nil, r, nil, &session.Session{IdentityID: i.ID}))
+
+			actual, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), x.ParseUUID(id))
+			require.NoError(t, err)
+			assert.Equal(t, identity.StateInactive, actual.State)
+		})
+
+		t.Run("case=deprovisions the user", func(t *testing.T) {
+			send(t, "DELETE", scim.RouteUsers+"/"+id, token, http.StatusNoContent, nil)
+			send(t, "GET", scim.RouteUsers+"/"+id, token, http.StatusNotFound, nil)
+
+			_, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), x.ParseUUID(id))
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("case=does not delete identities which were not provisioned", func(t *testing.T) {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(`{"email":"bob@example.com"}`)
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+		send(t, "DELETE", scim.RouteUsers+"/"+i.ID.String(), token, http.StatusNotFound, nil)
+
+		r := httptest.NewRequest("GET", "/", nil)
+		require.NoError(t, reg.SCIMLoginHook().ExecuteLoginPostHook(nil, r, nil, &session.Session{IdentityID: i.ID}))
+	})
+
+	t.Run("case=manages groups", func(t *testing.T) {
+		group := send(t, "POST", scim.RouteGroups, token, http.StatusCreated, map[string]interface{}{
+			"schemas":     []string{scim.SchemaGroup},
+			"displayName": "Admins",
+		})
+		id := group.Get("id").String()
+		assert.Equal(t, "Group", group.Get("meta.resourceType").String())
+
+		group = send(t, "PATCH", scim.RouteGroups+"/"+id, token, http.StatusOK, map[string]interface{}{
+			"schemas":    []string{scim.SchemaPatchOp},
+			"Operations": []map[string]interface{}{{"op": "add", "path": "members", "value": []map[string]interface{}{{"value": "00u1"}}}},
+		})
+		assert.Equal(t, "00u1", group.Get("members.0.value").String())
+
+		send(t, "GET", scim.RouteUsers+"/"+id, token, http.StatusNotFound, nil)
+		send(t, "DELETE", scim.RouteGroups+"/"+id, token, http.StatusNoContent, nil)
+		send(t, "DELETE", scim.RouteGroups+"/"+id, token, http.StatusNotFound, nil)
+	})
+}
@@ -0,0 +1,48 @@
+package scim
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/session"
+)
+
+var _ login.PostHookExecutor = new(LoginHook)
+
+type (
+	hookDependencies interface {
+		PersistenceProvider
+	}
+	HookProvider interface {
+		SCIMLoginHook() *LoginHook
+	}
+
+	// LoginHook prevents users which were deactivated by the provisioning client from signing in.
+	LoginHook struct {
+		d hookDependencies
+	}
+)
+
+func NewLoginHook(d hookDependencies) *LoginHook {
+	return &LoginHook{d: d}
+}
+
+func (e *LoginHook) ExecuteLoginPostHook(_ http.ResponseWriter, r *http.Request, _ *login.Flow, s *session.Session) error {
+	res, err := e.d.SCIMPersister().GetSCIMResource(r.Context(), ResourceTypeUser, s.IdentityID)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		// The identity was not provisioned using SCIM.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if !res.Active {
+		return errors.WithStack(herodot.ErrForbidden.WithReason("This account was deactivated by your organization."))
+	}
+	return nil
+}
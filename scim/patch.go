@@ -0,0 +1,190 @@
+package scim
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+)
+
+// PatchRequest is a SCIM PATCH request as defined in RFC 7644 section 3.5.2.
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation adds, replaces, or removes an attribute.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// patchPath matches `attribute`, `attribute.sub`, `attribute[sub eq "value"]`, and `attribute[sub eq "value"].sub`.
+var patchPath = regexp.MustCompile(`^([A-Za-z][\w-]*)(?:\[([A-Za-z][\w-]*) eq "([^"]*)"\])?(?:\.([A-Za-z][\w-]*))?$`)
+
+type path struct {
+	attribute   string
+	filterAttr  string
+	filterValue string
+	sub         string
+}
+
+func parsePath(p string) (*path, error) {
+	// Attributes of schema extensions are prefixed with the schema URN, for example
+	// `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department`.
+	if strings.HasPrefix(strings.ToLower(p), "urn:") {
+		i := strings.LastIndex(p, ":")
+		return &path{attribute: p[:i], sub: p[i+1:]}, nil
+	}
+
+	m := patchPath.FindStringSubmatch(p)
+	if m == nil {
+		return nil, errors.WithStack(newError(herodot.ErrBadRequest.WithReasonf(`The path "%s" is not supported.`, p), "invalidPath"))
+	}
+	return &path{attribute: m[1], filterAttr: m[2], filterValue: m[3], sub: m[4]}, nil
+}
+
+// key returns the key of the attribute in doc, matching attribute names case-insensitively as required by SCIM.
+func key(doc map[string]interface{}, attribute string) string {
+	for k := range doc {
+		if strings.EqualFold(k, attribute) {
+			return k
+		}
+	}
+	return attribute
+}
+
+func matches(element interface{}, p *path) bool {
+	e, ok := element.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	v, ok := e[key(e, p.filterAttr)]
+	if !ok {
+		return false
+	}
+	s, ok := v.(string)
+	return ok && s == p.filterValue
+}
+
+// ApplyPatch applies the operations to the JSON object doc.
+func ApplyPatch(doc map[string]interface{}, ops []PatchOperation) error {
+	for _, op := range ops {
+		switch o := strings.ToLower(op.Op); o {
+		case "add", "replace":
+			if op.Path == "" {
+				values, ok := op.Value.(map[string]interface{})
+				if !ok {
+					return errors.WithStack(newError(herodot.ErrBadRequest.WithReasonf(`The value of a "%s" operation without a path must be an object.`, op.Op), "invalidValue"))
+				}
+				for k, v := range values {
+					if err := apply(doc, o, &path{attribute: k}, v); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			p, err := parsePath(op.Path)
+			if err != nil {
+				return err
+			}
+			if err := apply(doc, o, p, op.Value); err != nil {
+				return err
+			}
+		case "remove":
+			if op.Path == "" {
+				return errors.WithStack(newError(herodot.ErrBadRequest.WithReason(`A "remove" operation requires a path.`), "noTarget"))
+			}
+
+			p, err := parsePath(op.Path)
+			if err != nil {
+				return err
+			}
+			remove(doc, p)
+		default:
+			return errors.WithStack(newError(herodot.ErrBadRequest.WithReasonf(`The operation "%s" is not supported.`, op.Op), "invalidSyntax"))
+		}
+	}
+	return nil
+}
+
+func apply(doc map[string]interface{}, op string, p *path, value interface{}) error {
+	k := key(doc, p.attribute)
+
+	if p.filterAttr != "" {
+		elements, _ := doc[k].([]interface{})
+		for i, element := range elements {
+			if !matches(element, p) {
+				continue
+			}
+			e := element.(map[string]interface{})
+			if p.sub != "" {
+				e[key(e, p.sub)] = value
+			} else if values, ok := value.(map[string]interface{}); ok {
+				for vk, vv := range values {
+					e[key(e, vk)] = vv
+				}
+			} else {
+				return errors.WithStack(newError(herodot.ErrBadRequest.WithReasonf(`The value for path "%s" must be an object.`, p.attribute), "invalidValue"))
+			}
+			elements[i] = e
+		}
+		return nil
+	}
+
+	if p.sub != "" {
+		parent, ok := doc[k].(map[string]interface{})
+		if !ok {
+			parent = map[string]interface{}{}
+		}
+		parent[key(parent, p.sub)] = value
+		doc[k] = parent
+		return nil
+	}
+
+	// Adding values to a multi-valued attribute appends them.
+	if existing, ok := doc[k].([]interface{}); ok && op == "add" {
+		if values, ok := value.([]interface{}); ok {
+			doc[k] = append(existing, values...)
+			return nil
+		}
+	}
+
+	doc[k] = value
+	return nil
+}
+
+func remove(doc map[string]interface{}, p *path) {
+	k := key(doc, p.attribute)
+
+	if p.filterAttr != "" {
+		elements, _ := doc[k].([]interface{})
+		kept := make([]interface{}, 0, len(elements))
+		for _, element := range elements {
+			if !matches(element, p) {
+				kept = append(kept, element)
+				continue
+			}
+			if p.sub != "" {
+				e := element.(map[string]interface{})
+				delete(e, key(e, p.sub))
+				kept = append(kept, e)
+			}
+		}
+		doc[k] = kept
+		return
+	}
+
+	if p.sub != "" {
+		if parent, ok := doc[k].(map[string]interface{}); ok {
+			delete(parent, key(parent, p.sub))
+		}
+		return
+	}
+
+	delete(doc, k)
+}
@@ -0,0 +1,107 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPatch(t *testing.T) {
+	const user = `{
+  "userName": "alice@example.com",
+  "active": true,
+  "name": {"givenName": "Alice", "familyName": "Doe"},
+  "emails": [{"type": "work", "value": "alice@example.com", "primary": true}]
+}`
+
+	for k, tc := range []struct {
+		d         string
+		ops       string
+		expected  string
+		expectErr string
+	}{
+		{
+			d:   "replace a simple attribute with a case-insensitive path",
+			ops: `[{"op":"Replace","path":"Active","value":false}]`,
+			expected: `{"userName":"alice@example.com","active":false,"name":{"givenName":"Alice","familyName":"Doe"},
+"emails":[{"type":"work","value":"alice@example.com","primary":true}]}`,
+		},
+		{
+			d:   "replace without a path as sent by Azure AD",
+			ops: `[{"op":"replace","value":{"active":false,"displayName":"Alice Doe"}}]`,
+			expected: `{"userName":"alice@example.com","active":false,"displayName":"Alice Doe","name":{"givenName":"Alice","familyName":"Doe"},
+"emails":[{"type":"work","value":"alice@example.com","primary":true}]}`,
+		},
+		{
+			d:   "replace a sub-attribute",
+			ops: `[{"op":"replace","path":"name.givenName","value":"Alicia"}]`,
+			expected: `{"userName":"alice@example.com","active":true,"name":{"givenName":"Alicia","familyName":"Doe"},
+"emails":[{"type":"work","value":"alice@example.com","primary":true}]}`,
+		},
+		{
+			d:   "replace a filtered sub-attribute",
+			ops: `[{"op":"replace","path":"emails[type eq \"work\"].value","value":"alicia@example.com"}]`,
+			expected: `{"userName":"alice@example.com","active":true,"name":{"givenName":"Alice","familyName":"Doe"},
+"emails":[{"type":"work","value":"alicia@example.com","primary":true}]}`,
+		},
+		{
+			d:   "add to a multi-valued attribute",
+			ops: `[{"op":"add","path":"emails","value":[{"type":"home","value":"alice@example.org"}]}]`,
+			expected: `{"userName":"alice@example.com","active":true,"name":{"givenName":"Alice","familyName":"Doe"},
+"emails":[{"type":"work","value":"alice@example.com","primary":true},{"type":"home","value":"alice@example.org"}]}`,
+		},
+		{
+			d:   "add an extension attribute",
+			ops: `[{"op":"add","path":"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department","value":"Sales"}]`,
+			expected: `{"userName":"alice@example.com","active":true,"name":{"givenName":"Alice","familyName":"Doe"},
+"emails":[{"type":"work","value":"alice@example.com","primary":true}],
+"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"department":"Sales"}}`,
+		},
+		{
+			d:        "remove attributes",
+			ops:      `[{"op":"remove","path":"name.familyName"},{"op":"remove","path":"emails[type eq \"work\"]"}]`,
+			expected: `{"userName":"alice@example.com","active":true,"name":{"givenName":"Alice"},"emails":[]}`,
+		},
+		{
+			d:         "remove requires a path",
+			ops:       `[{"op":"remove"}]`,
+			expectErr: "noTarget",
+		},
+		{
+			d:         "unsupported operation",
+			ops:       `[{"op":"move","path":"active"}]`,
+			expectErr: "invalidSyntax",
+		},
+		{
+			d:         "unsupported path",
+			ops:       `[{"op":"replace","path":"emails[type ne \"work\"]","value":{}}]`,
+			expectErr: "invalidPath",
+		},
+	} {
+		t.Run(fmt.Sprintf("case=%d/description=%s", k, tc.d), func(t *testing.T) {
+			var doc map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(user), &doc))
+
+			var ops []PatchOperation
+			require.NoError(t, json.Unmarshal([]byte(tc.ops), &ops))
+
+			err := ApplyPatch(doc, ops)
+			if tc.expectErr != "" {
+				require.Error(t, err)
+				e, ok := errors.Cause(err).(*Error)
+				require.True(t, ok, "%+v", err)
+				assert.Equal(t, tc.expectErr, e.scimType)
+				return
+			}
+			require.NoError(t, err)
+
+			actual, err := json.Marshal(doc)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expected, string(actual))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package scim
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/kratos/x"
+)
+
+type (
+	Persister interface {
+		// CreateSCIMResource persists a resource. Returns sqlcon.ErrUniqueViolation if a resource of the same type
+		// and name exists already.
+		CreateSCIMResource(ctx context.Context, r *Resource) error
+
+		// GetSCIMResource returns a resource by its type and ID or sqlcon.ErrNoRows.
+		GetSCIMResource(ctx context.Context, resourceType string, id uuid.UUID) (*Resource, error)
+
+		// UpdateSCIMResource updates the external ID, name, active flag, and attributes of a resource.
+		UpdateSCIMResource(ctx context.Context, r *Resource) error
+
+		// DeleteSCIMResource removes a resource or returns sqlcon.ErrNoRows.
+		DeleteSCIMResource(ctx context.Context, resourceType string, id uuid.UUID) error
+
+		// ListSCIMResources returns the resources of a type matching the filter, ordered by creation, and the total
+		// number of matching resources.
+		ListSCIMResources(ctx context.Context, resourceType string, filter Filter, offset, limit int) ([]Resource, int, error)
+	}
+
+	PersistenceProvider interface {
+		SCIMPersister() Persister
+	}
+)
+
+func TestPersister(p Persister) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+
+		newGroup := func(name string) *Resource {
+			return &Resource{
+				ID:         x.NewUUID(),
+				Type:       ResourceTypeGroup,
+				ExternalID: "ext-" + name,
+				Name:       name,
+				Active:     true,
+				Attributes: sqlxx.JSONRawMessage(`{"displayName":"` + name + `"}`),
+			}
+		}
+
+		t.Run("case=not found", func(t *testing.T) {
+			_, err := p.GetSCIMResource(ctx, ResourceTypeGroup, x.NewUUID())
+			require.EqualError(t, err, sqlcon.ErrNoRows.Error())
+			require.EqualError(t, p.DeleteSCIMResource(ctx, ResourceTypeGroup, x.NewUUID()), sqlcon.ErrNoRows.Error())
+		})
+
+		t.Run("case=create, update, list, and delete", func(t *testing.T) {
+			admins := newGroup("admins-" + x.NewUUID().String())
+			require.NoError(t, p.CreateSCIMResource(ctx, admins))
+			err := p.CreateSCIMResource(ctx, &Resource{ID: x.NewUUID(), Type: ResourceTypeGroup, Name: admins.Name, Attributes: sqlxx.JSONRawMessage("{}")})
+			require.True(t, errors.Is(err, sqlcon.ErrUniqueViolation), "%+v", err)
+
+			users := newGroup("users-" + x.NewUUID().String())
+			require.NoError(t, p.CreateSCIMResource(ctx, users))
+
+			actual, err := p.GetSCIMResource(ctx, ResourceTypeGroup, admins.ID)
+			require.NoError(t, err)
+			assert.Equal(t, admins.Name, actual.Name)
+			assert.JSONEq(t, string(admins.Attributes), string(actual.Attributes))
+
+			_, err = p.GetSCIMResource(ctx, ResourceTypeUser, admins.ID)
+			require.EqualError(t, err, sqlcon.ErrNoRows.Error(), "resources of a different type must not be found")
+
+			actual.Active = false
+			actual.ExternalID = "changed"
+			actual.Attributes = sqlxx.JSONRawMessage(`{"displayName":"changed"}`)
+			time.Sleep(time.Millisecond)
+			require.NoError(t, p.UpdateSCIMResource(ctx, actual))
+
+			actual, err = p.GetSCIMResource(ctx, ResourceTypeGroup, admins.ID)
+			require.NoError(t, err)
+			assert.False(t, actual.Active)
+			assert.Equal(t, "changed", actual.ExternalID)
+			assert.JSONEq(t, `{"displayName":"changed"}`, string(actual.Attributes))
+
+			found, total, err := p.ListSCIMResources(ctx, ResourceTypeGroup, Filter{Name: users.Name}, 0, 10)
+			require.NoError(t, err)
+			assert.Equal(t, 1, total)
+			require.Len(t, found, 1)
+			assert.Equal(t, users.ID, found[0].ID)
+
+			found, total, err = p.ListSCIMResources(ctx, ResourceTypeGroup, Filter{ExternalID: "changed"}, 0, 10)
+			require.NoError(t, err)
+			assert.Equal(t, 1, total)
+			require.Len(t, found, 1)
+			assert.Equal(t, admins.ID, found[0].ID)
+
+			found, total, err = p.ListSCIMResources(ctx, ResourceTypeGroup, Filter{}, 0, 1)
+			require.NoError(t, err)
+			assert.True(t, total >= 2)
+			assert.Len(t, found, 1)
+
+			require.NoError(t, p.DeleteSCIMResource(ctx, ResourceTypeGroup, admins.ID))
+			_, err = p.GetSCIMResource(ctx, ResourceTypeGroup, admins.ID)
+			require.EqualError(t, err, sqlcon.ErrNoRows.Error())
+		})
+	}
+}
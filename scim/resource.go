@@ -0,0 +1,59 @@
+package scim
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/sqlxx"
+)
+
+const (
+	ResourceTypeUser  = "User"
+	ResourceTypeGroup = "Group"
+
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+// Resource is a provisioned SCIM user or group.
+//
+// For users, the resource ID equals the ID of the identity which was created for it.
+type Resource struct {
+	ID uuid.UUID `json:"id" db:"id"`
+
+	// IdentityID is set for users and removes the resource when the identity is deleted.
+	IdentityID uuid.NullUUID `json:"-" db:"identity_id"`
+
+	// Type is either `User` or `Group`.
+	Type string `json:"-" db:"resource_type"`
+
+	// ExternalID is the ID of the resource at the provisioning client.
+	ExternalID string `json:"externalId,omitempty" db:"external_id"`
+
+	// Name is the `userName` of users and the `displayName` of groups. It is unique per type.
+	Name string `json:"-" db:"name"`
+
+	// Active is false if the user was deactivated.
+	Active bool `json:"-" db:"active"`
+
+	// Attributes is the resource as it was last sent by the provisioning client.
+	Attributes sqlxx.JSONRawMessage `json:"-" db:"attributes"`
+
+	CreatedAt time.Time `json:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+}
+
+func (r Resource) TableName() string {
+	return "scim_resources"
+}
+
+// Filter selects resources. Empty fields match all resources.
+type Filter struct {
+	Name       string
+	ExternalID string
+}
@@ -0,0 +1,106 @@
+package errorx
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/url"
+
+	"github.com/ory/x/sqlxx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	// ActionRetry starts the flow in which the error occurred again.
+	ActionRetry = "retry"
+
+	// ActionLogin starts a new login flow.
+	ActionLogin = "login"
+
+	// ActionHome returns to the default return URL.
+	ActionHome = "home"
+)
+
+type (
+	// Action suggests what the user can do to recover from an error.
+	//
+	// swagger:model errorContainerAction
+	Action struct {
+		// ID identifies the action, for example `retry`.
+		//
+		// required: true
+		ID string `json:"id"`
+
+		// URL is where the action leads to.
+		//
+		// required: true
+		URL string `json:"url"`
+
+		// Message is the label of the action. Its ID can be used to translate it.
+		//
+		// required: true
+		Message *text.Message `json:"message"`
+	}
+
+	Actions []Action
+
+	// Details help the error user interface to explain errors to the user.
+	Details struct {
+		// Flow is the name of the self-service flow in which the errors occurred, for example `login`.
+		Flow string
+
+		Title       *text.Message
+		Description *text.Message
+		Actions     Actions
+	}
+)
+
+func (a *Actions) Scan(value interface{}) error {
+	return sqlxx.JSONScan(a, value)
+}
+
+func (a Actions) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
+	return sqlxx.JSONValue(&a)
+}
+
+// NewDetails describes the errors which occurred in the flow. The title and the description are derived from the
+// status code of the first error. The flow, if set, must be one which is initialized at
+// `/self-service/<flow>/browser`, which is where the retry action points to.
+func NewDetails(flow string, publicURL, home *url.URL, errs ...error) *Details {
+	code := http.StatusInternalServerError
+	if len(errs) > 0 {
+		code = x.RecoverStatusCode(errs[0], code)
+	}
+
+	d := &Details{Flow: flow}
+	d.Title, d.Description = text.NewErrorSystemUserFacing(code)
+
+	if flow != "" {
+		d.Actions = append(d.Actions, Action{
+			ID:      ActionRetry,
+			URL:     urlx.AppendPaths(publicURL, "self-service", flow, "browser").String(),
+			Message: text.NewInfoSelfServiceErrorActionRetry(flow),
+		})
+	}
+
+	if code == http.StatusUnauthorized && flow != "login" {
+		d.Actions = append(d.Actions, Action{
+			ID:      ActionLogin,
+			URL:     urlx.AppendPaths(publicURL, "self-service", "login", "browser").String(),
+			Message: text.NewInfoSelfServiceErrorActionLogin(),
+		})
+	}
+
+	d.Actions = append(d.Actions, Action{
+		ID:      ActionHome,
+		URL:     home.String(),
+		Message: text.NewInfoSelfServiceErrorActionHome(),
+	})
+
+	return d
+}
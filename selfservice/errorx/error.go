@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/text"
 )
 
 // swagger:model errorContainer
@@ -22,6 +24,19 @@ type ErrorContainer struct {
 	// required: true
 	Errors json.RawMessage `json:"errors" db:"errors"`
 
+	// Flow is the name of the self-service flow in which the errors occurred, for example `login`. It is empty if
+	// the errors did not occur in a flow.
+	Flow string `json:"flow,omitempty" db:"flow"`
+
+	// Title is a short user-facing summary of the errors. Its ID can be used to translate it.
+	Title *text.Message `json:"title,omitempty" faker:"-" db:"title"`
+
+	// Description is a user-facing explanation of the errors. Its ID can be used to translate it.
+	Description *text.Message `json:"description,omitempty" faker:"-" db:"description"`
+
+	// Actions suggest what the user can do to recover from the errors.
+	Actions Actions `json:"actions,omitempty" faker:"-" db:"actions"`
+
 	// CreatedAt is a helper struct field for gobuffalo.pop.
 	CreatedAt time.Time `json:"-" db:"created_at"`
 
@@ -30,6 +30,8 @@ type (
 
 	baseManagerConfiguration interface {
 		SelfServiceFlowErrorURL() *url.URL
+		SelfServiceBrowserDefaultReturnTo() *url.URL
+		SelfPublicURL() *url.URL
 	}
 )
 
@@ -40,11 +42,18 @@ func NewManager(d managerDependencies, c baseManagerConfiguration) *Manager {
 // Create is a simple helper that saves all errors in the store and returns the
 // error url, appending the error ID.
 func (m *Manager) Create(ctx context.Context, w http.ResponseWriter, r *http.Request, errs ...error) (string, error) {
+	return m.CreateForFlow(ctx, w, r, "", errs...)
+}
+
+// CreateForFlow works like Create for errors which occurred in a self-service flow, for example `login`, so that
+// the error user interface is able to offer to start the flow again.
+func (m *Manager) CreateForFlow(ctx context.Context, w http.ResponseWriter, r *http.Request, flow string, errs ...error) (string, error) {
 	for _, err := range errs {
-		m.d.Logger().WithError(err).WithRequest(r).Errorf("An error occurred and is being forwarded to the error user interface.")
+		m.d.Logger().WithError(err).WithRequest(r).WithField("flow", flow).Errorf("An error occurred and is being forwarded to the error user interface.")
 	}
 
-	id, emerr := m.d.SelfServiceErrorPersister().Add(ctx, m.d.GenerateCSRFToken(r), errs...)
+	details := NewDetails(flow, m.c.SelfPublicURL(), m.c.SelfServiceBrowserDefaultReturnTo(), errs...)
+	id, emerr := m.d.SelfServiceErrorPersister().AddWithDetails(ctx, m.d.GenerateCSRFToken(r), details, errs...)
 	if emerr != nil {
 		return "", emerr
 	}
@@ -57,7 +66,12 @@ func (m *Manager) Create(ctx context.Context, w http.ResponseWriter, r *http.Req
 // Forward is a simple helper that saves all errors in the store and forwards the HTTP Request
 // to the error url, appending the error ID.
 func (m *Manager) Forward(ctx context.Context, w http.ResponseWriter, r *http.Request, errs ...error) {
-	to, err := m.Create(ctx, w, r, errs...)
+	m.ForwardForFlow(ctx, w, r, "", errs...)
+}
+
+// ForwardForFlow works like Forward for errors which occurred in a self-service flow, for example `login`.
+func (m *Manager) ForwardForFlow(ctx context.Context, w http.ResponseWriter, r *http.Request, flow string, errs ...error) {
+	to, err := m.CreateForFlow(ctx, w, r, flow, errs...)
 	if err != nil {
 		// Everything failed. Resort to standard error output.
 		m.d.Writer().WriteError(w, r, err)
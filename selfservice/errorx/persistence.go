@@ -12,7 +12,9 @@ import (
 	"github.com/tidwall/gjson"
 
 	"github.com/ory/herodot"
+	"github.com/ory/x/urlx"
 
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
@@ -21,6 +23,10 @@ type (
 		// Add adds an error to the manager and returns a unique identifier or an error if insertion fails.
 		Add(ctx context.Context, csrfToken string, errs ...error) (uuid.UUID, error)
 
+		// AddWithDetails works like Add and stores the details which help the error user interface to explain the
+		// errors to the user.
+		AddWithDetails(ctx context.Context, csrfToken string, details *Details, errs ...error) (uuid.UUID, error)
+
 		// Read returns an error by its unique identifier and marks the error as read. If an error occurs during retrieval
 		// the second return parameter is an error.
 		Read(ctx context.Context, id uuid.UUID) (*ErrorContainer, error)
@@ -58,6 +64,32 @@ func TestPersister(p Persister) func(t *testing.T) {
 			assert.JSONEq(t, `{"code":404,"status":"Not Found","reason":"foobar","message":"The requested resource could not be found"}`, gjson.Get(toJSON(t, actual), "errors.0").String(), toJSON(t, actual))
 		})
 
+		t.Run("case=en- and decode details", func(t *testing.T) {
+			publicURL := urlx.ParseOrPanic("https://www.ory.sh/")
+			home := urlx.ParseOrPanic("https://www.ory.sh/home")
+			actualID, err := p.AddWithDetails(context.Background(), "nosurf", NewDetails("login", publicURL, home, x.ErrGone.WithReason("expired")), x.ErrGone.WithReason("expired"))
+			require.NoError(t, err)
+
+			actual, err := p.Read(context.Background(), actualID)
+			require.NoError(t, err)
+
+			assert.Equal(t, "login", actual.Flow)
+			require.NotNil(t, actual.Title)
+			assert.Equal(t, text.ErrorSystemUserFacingGoneTitle, actual.Title.ID)
+			require.NotNil(t, actual.Description)
+			assert.Equal(t, text.ErrorSystemUserFacingGoneDescription, actual.Description.ID)
+			require.Len(t, actual.Actions, 2)
+			assert.Equal(t, "https://www.ory.sh/self-service/login/browser", actual.Actions[0].URL)
+			assert.Equal(t, ActionHome, actual.Actions[1].ID)
+
+			actualID, err = p.Add(context.Background(), "nosurf", herodot.ErrNotFound)
+			require.NoError(t, err)
+			actual, err = p.Read(context.Background(), actualID)
+			require.NoError(t, err)
+			assert.Nil(t, actual.Title)
+			assert.Empty(t, actual.Actions)
+		})
+
 		t.Run("case=clear", func(t *testing.T) {
 			actualID, err := p.Add(context.Background(), "nosurf", herodot.ErrNotFound.WithReason("foobar"))
 			require.NoError(t, err)
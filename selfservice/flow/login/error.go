@@ -8,6 +8,7 @@ import (
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/form"
 	"github.com/ory/kratos/text"
 
 	"github.com/pkg/errors"
@@ -17,6 +18,7 @@ import (
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/x"
 )
 
@@ -30,6 +32,7 @@ type (
 		errorx.ManagementProvider
 		x.WriterProvider
 		x.LoggingProvider
+		stats.RecorderProvider
 
 		FlowPersistenceProvider
 		HandlerProvider
@@ -84,6 +87,17 @@ func (s *ErrorHandler) WriteFlowError(w http.ResponseWriter, r *http.Request, ct
 			return
 		}
 
+		// keep the values entered by the user so that they do not have to enter them again
+		if method, ok := f.Methods[ct]; ok {
+			if target, ok := a.Methods[ct]; ok {
+				if values, ok := method.Config.FlowMethodConfigurator.(form.ValueGetter); ok {
+					for name, value := range values.Values() {
+						target.Config.SetValue(name, value)
+					}
+				}
+			}
+		}
+
 		a.Messages.Add(text.NewErrorValidationLoginFlowExpired(e.ago))
 		if err := s.d.LoginFlowPersister().UpdateLoginFlow(r.Context(), a); err != nil {
 			s.forward(w, r, a, err)
@@ -106,6 +120,8 @@ func (s *ErrorHandler) WriteFlowError(w http.ResponseWriter, r *http.Request, ct
 		return
 	}
 
+	s.d.FlowStatsRecorder().Record(r.Context(), stats.FlowLogin, f.ID, f.Type, ct.String(), stats.EventFailed)
+
 	if err := method.Config.ParseError(err); err != nil {
 		s.forward(w, r, f, err)
 		return
@@ -135,13 +151,13 @@ func (s *ErrorHandler) forward(w http.ResponseWriter, r *http.Request, rr *Flow,
 			s.d.Writer().WriteError(w, r, err)
 			return
 		}
-		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		s.d.SelfServiceErrorManager().ForwardForFlow(r.Context(), w, r, stats.FlowLogin, err)
 		return
 	}
 
 	if rr.Type == flow.TypeAPI {
 		s.d.Writer().WriteErrorCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), err)
 	} else {
-		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		s.d.SelfServiceErrorManager().ForwardForFlow(r.Context(), w, r, stats.FlowLogin, err)
 	}
 }
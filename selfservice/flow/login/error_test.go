@@ -59,7 +59,7 @@ func TestHandleError(t *testing.T) {
 
 	newFlow := func(t *testing.T, ttl time.Duration, ft flow.Type) *login.Flow {
 		req := &http.Request{URL: urlx.ParseOrPanic("/")}
-		f := login.NewFlow(ttl, "csrf_token", req, ft)
+		f := login.NewFlow(time.Now(), ttl, "csrf_token", req, ft)
 		for _, s := range reg.LoginStrategies() {
 			require.NoError(t, s.PopulateLoginMethod(req, f))
 		}
@@ -130,6 +130,27 @@ func TestHandleError(t *testing.T) {
 			assert.NotEqual(t, loginFlow.ID.String(), gjson.GetBytes(body, "id").String())
 		})
 
+		t.Run("case=expired error keeps the entered values", func(t *testing.T) {
+			t.Cleanup(reset)
+
+			loginFlow = newFlow(t, time.Minute, flow.TypeAPI)
+			loginFlow.Methods[identity.CredentialsTypePassword].Config.SetValue("identifier", "foo@ory.sh")
+			loginFlow.Methods[identity.CredentialsTypePassword].Config.SetValue("password", "secret")
+			flowError = login.NewFlowExpiredError(anHourAgo)
+			ct = identity.CredentialsTypePassword
+
+			res, err := ts.Client().Do(testhelpers.NewHTTPGetJSONRequest(t, ts.URL+"/error"))
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode)
+
+			body, err := ioutil.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.NotEqual(t, loginFlow.ID.String(), gjson.GetBytes(body, "id").String())
+			assert.Equal(t, "foo@ory.sh", gjson.GetBytes(body, `methods.password.config.fields.#(name=="identifier").value`).String(), "%s", body)
+			assert.Empty(t, gjson.GetBytes(body, `methods.password.config.fields.#(name=="password").value`).String(), "%s", body)
+		})
+
 		t.Run("case=validation error", func(t *testing.T) {
 			t.Cleanup(reset)
 
@@ -9,6 +9,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 
+	"github.com/ory/x/sqlxx"
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/identity"
@@ -83,13 +84,27 @@ type Flow struct {
 	// CSRFToken contains the anti-csrf token associated with this flow. Only set for browser flows.
 	CSRFToken string `json:"-" db:"csrf_token"`
 
+	// FlowToken is only returned when an API flow is initialized. It must be sent in the
+	// `X-Kratos-Flow-Token` HTTP Header when completing the flow if API flow binding is enabled.
+	FlowToken string `json:"flow_token,omitempty" faker:"-" db:"-"`
+
+	// FlowTokenHash is the hash of the flow token.
+	FlowTokenHash string `json:"-" faker:"-" db:"flow_token_hash"`
+
+
 	// Forced stores whether this login flow should enforce re-authentication.
 	Forced bool `json:"forced" db:"forced"`
+
+	// Remember is set by login methods if the user asked to stay signed in. It is not stored.
+	Remember bool `json:"-" faker:"-" db:"-"`
+
+	// OAuth2LoginChallenge is set if the flow was initiated by ORY Hydra. The login request is accepted on behalf
+	// of the identity once it signed in.
+	OAuth2LoginChallenge sqlxx.NullString `json:"oauth2_login_challenge,omitempty" faker:"-" db:"oauth2_login_challenge"`
 }
 
-func NewFlow(exp time.Duration, csrf string, r *http.Request, flowType flow.Type) *Flow {
-	now := time.Now().UTC()
-	return &Flow{
+func NewFlow(now time.Time, exp time.Duration, csrf string, r *http.Request, flowType flow.Type) *Flow {
+	f := &Flow{
 		ID:         x.NewUUID(),
 		ExpiresAt:  now.Add(exp),
 		IssuedAt:   now,
@@ -99,6 +114,10 @@ func NewFlow(exp time.Duration, csrf string, r *http.Request, flowType flow.Type
 		Type:       flowType,
 		Forced:     r.URL.Query().Get("refresh") == "true",
 	}
+	if flowType == flow.TypeAPI {
+		f.FlowToken, f.FlowTokenHash = flow.NewFlowToken()
+	}
+	return f
 }
 
 func (f *Flow) BeforeSave(_ *pop.Connection) error {
@@ -133,8 +152,15 @@ func (f Flow) TableName() string {
 	return "selfservice_login_flows"
 }
 
-func (f *Flow) Valid() error {
-	if f.ExpiresAt.Before(time.Now()) {
+// Valid returns an error if the flow expired. Browser flows are still valid if they are submitted within the grace
+// period after they expired.
+func (f *Flow) Valid(now time.Time, gracePeriod time.Duration) error {
+	expiresAt := f.ExpiresAt
+	if f.Type == flow.TypeBrowser {
+		expiresAt = expiresAt.Add(gracePeriod)
+	}
+
+	if expiresAt.Before(now) {
 		return errors.WithStack(NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
@@ -59,6 +59,7 @@ type FlowMethodConfigurator interface {
 	form.Resetter
 	form.MessageResetter
 	form.CSRFSetter
+	form.UISetter
 	form.MessageAdder
 }
 
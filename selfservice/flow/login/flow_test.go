@@ -35,7 +35,7 @@ func TestFakeFlow(t *testing.T) {
 
 func TestNewFlow(t *testing.T) {
 	t.Run("case=0", func(t *testing.T) {
-		r := login.NewFlow(0, "csrf", &http.Request{
+		r := login.NewFlow(time.Now(), 0, "csrf", &http.Request{
 			URL:  urlx.ParseOrPanic("/"),
 			Host: "ory.sh", TLS: &tls.ConnectionState{},
 		}, flow.TypeBrowser)
@@ -46,7 +46,7 @@ func TestNewFlow(t *testing.T) {
 	})
 
 	t.Run("case=1", func(t *testing.T) {
-		r := login.NewFlow(0, "csrf", &http.Request{
+		r := login.NewFlow(time.Now(), 0, "csrf", &http.Request{
 			URL:  urlx.ParseOrPanic("/?refresh=true"),
 			Host: "ory.sh"}, flow.TypeAPI)
 		assert.Equal(t, r.IssuedAt, r.ExpiresAt)
@@ -56,7 +56,7 @@ func TestNewFlow(t *testing.T) {
 	})
 
 	t.Run("case=2", func(t *testing.T) {
-		r := login.NewFlow(0, "csrf", &http.Request{
+		r := login.NewFlow(time.Now(), 0, "csrf", &http.Request{
 			URL:  urlx.ParseOrPanic("https://ory.sh/"),
 			Host: "ory.sh"}, flow.TypeBrowser)
 		assert.Equal(t, "https://ory.sh/", r.RequestURL)
@@ -70,6 +70,7 @@ func TestFlow(t *testing.T) {
 	t.Run("case=expired", func(t *testing.T) {
 		for _, tc := range []struct {
 			r     *login.Flow
+			grace time.Duration
 			valid bool
 		}{
 			{
@@ -77,11 +78,24 @@ func TestFlow(t *testing.T) {
 				valid: true,
 			},
 			{r: &login.Flow{ExpiresAt: time.Now().Add(-time.Hour), IssuedAt: time.Now().Add(-time.Minute)}},
+			{
+				r:     &login.Flow{Type: flow.TypeBrowser, ExpiresAt: time.Now().Add(-time.Minute), IssuedAt: time.Now().Add(-time.Hour)},
+				grace: 5 * time.Minute,
+				valid: true,
+			},
+			{
+				r:     &login.Flow{Type: flow.TypeBrowser, ExpiresAt: time.Now().Add(-time.Hour), IssuedAt: time.Now().Add(-2 * time.Hour)},
+				grace: 5 * time.Minute,
+			},
+			{
+				r:     &login.Flow{Type: flow.TypeAPI, ExpiresAt: time.Now().Add(-time.Minute), IssuedAt: time.Now().Add(-time.Hour)},
+				grace: 5 * time.Minute,
+			},
 		} {
 			if tc.valid {
-				require.NoError(t, tc.r.Valid())
+				require.NoError(t, tc.r.Valid(time.Now(), tc.grace))
 			} else {
-				require.Error(t, tc.r.Valid())
+				require.Error(t, tc.r.Valid(time.Now(), tc.grace))
 			}
 		}
 	})
@@ -2,7 +2,6 @@ package login
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -10,8 +9,11 @@ import (
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/hydra"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
@@ -21,6 +23,8 @@ const (
 	RouteInitAPIFlow     = "/self-service/login/api"
 
 	RouteGetFlow = "/self-service/login/flows"
+
+	RouteOAuth2Consent = "/self-service/login/oauth2/consent"
 )
 
 type (
@@ -34,6 +38,9 @@ type (
 		x.WriterProvider
 		x.CSRFTokenGeneratorProvider
 		x.CSRFProvider
+		stats.RecorderProvider
+		hydra.Provider
+		x.ClockProvider
 	}
 	HandlerProvider interface {
 		LoginHandler() *Handler
@@ -51,9 +58,10 @@ func NewHandler(d handlerDependencies, c *config.Provider) *Handler {
 func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 	h.d.CSRFHandler().IgnorePath(RouteInitAPIFlow)
 
-	public.GET(RouteInitBrowserFlow, h.initBrowserFlow)
+	public.GET(RouteInitBrowserFlow, x.NoPrefetchHandler(h.initBrowserFlow))
 	public.GET(RouteInitAPIFlow, h.initAPIFlow)
 	public.GET(RouteGetFlow, h.fetchFlow)
+	public.GET(RouteOAuth2Consent, h.oauth2Consent)
 }
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
@@ -61,13 +69,23 @@ func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 }
 
 func (h *Handler) NewLoginFlow(w http.ResponseWriter, r *http.Request, flow flow.Type) (*Flow, error) {
-	a := NewFlow(h.c.SelfServiceFlowLoginRequestLifespan(), h.d.GenerateCSRFToken(r), r, flow)
+	a := NewFlow(h.d.Clock().Now(), h.c.SelfServiceFlowLoginRequestLifespan(), h.d.GenerateCSRFToken(r), r, flow)
+	if err := h.setOAuth2LoginChallenge(r, a); err != nil {
+		return nil, err
+	}
+
 	for _, s := range h.d.LoginStrategies() {
 		if err := s.PopulateLoginMethod(r, a); err != nil {
 			return nil, err
 		}
 	}
 
+	for method, m := range a.Methods {
+		if ui := h.c.SelfServiceStrategy(string(method)).UI; ui != nil {
+			m.Config.SetUI(&form.UI{Label: ui.Label, Icon: ui.Icon, Order: ui.Order})
+		}
+	}
+
 	if err := h.d.LoginHookExecutor().PreLoginHook(w, r, a); err != nil {
 		return nil, err
 	}
@@ -75,6 +93,8 @@ func (h *Handler) NewLoginFlow(w http.ResponseWriter, r *http.Request, flow flow
 	if err := h.d.LoginFlowPersister().CreateLoginFlow(r.Context(), a); err != nil {
 		return nil, err
 	}
+
+	h.d.FlowStatsRecorder().Record(r.Context(), stats.FlowLogin, a.ID, a.Type, "", stats.EventCreated)
 	return a, nil
 }
 
@@ -91,6 +111,35 @@ type initializeSelfServiceBrowserLoginFlow struct {
 	Refresh bool `json:"refresh"`
 }
 
+// nolint:deadcode,unused
+// swagger:parameters initializeSelfServiceLoginViaBrowserFlow
+type initializeSelfServiceLoginViaBrowserFlow struct {
+	// Refresh a login session
+	//
+	// If set to true, this will refresh an existing login session by
+	// asking the user to sign in again. This will reset the
+	// authenticated_at time of the session.
+	//
+	// in: query
+	Refresh bool `json:"refresh"`
+
+	// Login Hint
+	//
+	// If set to an email address of a domain configured for an OpenID Connect
+	// provider, the browser is redirected to that provider right away.
+	//
+	// in: query
+	LoginHint string `json:"login_hint"`
+
+	// OAuth2 Login Challenge
+	//
+	// Set by ORY Hydra if `oauth2_provider.url` is configured. Once the user signed in, the login request
+	// is accepted and the browser is redirected back to ORY Hydra.
+	//
+	// in: query
+	LoginChallenge string `json:"login_challenge"`
+}
+
 // swagger:route GET /self-service/login/api public initializeSelfServiceLoginViaAPIFlow
 //
 // Initialize Login Flow for API clients
@@ -102,6 +151,9 @@ type initializeSelfServiceBrowserLoginFlow struct {
 //
 // To fetch an existing login flow call `/self-service/login/flows?flow=<flow_id>`.
 //
+// The response contains a `flow_token` which is only returned once. If `selfservice.api_flow_binding` is enabled,
+// the flow can only be completed by sending this token in the `X-Kratos-Flow-Token` HTTP Header.
+//
 // :::warning
 //
 // You MUST NOT use this endpoint in client-side (Single Page Apps, ReactJS, AngularJS) nor server-side (Java Server
@@ -152,7 +204,11 @@ func (h *Handler) initAPIFlow(w http.ResponseWriter, r *http.Request, _ httprout
 // This endpoint initializes a browser-based user login flow. Once initialized, the browser will be redirected to
 // `selfservice.flows.login.ui_url` with the flow ID set as the query parameter `?flow=`. If a valid user session
 // exists already, the browser will be redirected to `urls.default_redirect_url` unless the query parameter
-// `?refresh=true` was set.
+// `?refresh=true` was set. If the query parameter `?login_hint=` contains an email address of a domain which is
+// configured for an OpenID Connect provider, the browser is redirected to that provider instead.
+//
+// If the query parameter `?login_challenge=` is set by ORY Hydra, the login request is accepted once the user
+// signed in, or right away if a valid session exists, and the browser is redirected back to ORY Hydra.
 //
 // This endpoint is NOT INTENDED for API clients and only works with browsers (Chrome, Firefox, ...).
 //
@@ -164,38 +220,61 @@ func (h *Handler) initAPIFlow(w http.ResponseWriter, r *http.Request, _ httprout
 //       302: emptyResponse
 //       500: genericError
 func (h *Handler) initBrowserFlow(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	a, err := h.NewLoginFlow(w, r, flow.TypeBrowser)
+	// we assume an error means the user has no session
+	sess, err := h.d.SessionManager().FetchFromRequest(r.Context(), r)
+	if err == nil && r.URL.Query().Get("refresh") != "true" {
+		if challenge := r.URL.Query().Get("login_challenge"); challenge != "" {
+			h.acceptOAuth2LoginWithSession(w, r, challenge, sess)
+			return
+		}
 
-	if err != nil {
-		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		// Do not create a flow which would never be used.
+		returnTo, err := x.SecureRedirectTo(r, h.c.SelfServiceBrowserDefaultReturnTo(),
+			x.SecureRedirectAllowSelfServiceURLs(h.c.SelfPublicURL()),
+			x.SecureRedirectAllowURLs(h.c.SelfServiceBrowserWhitelistedReturnToDomains()),
+		)
+		if err != nil {
+			h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, returnTo.String(), http.StatusFound)
 		return
 	}
+	hasSession := err == nil
 
-	// we assume an error means the user has no session
-	if _, err := h.d.SessionManager().FetchFromRequest(r.Context(), r); err != nil {
-		http.Redirect(w, r, a.AppendTo(h.c.SelfServiceFlowLoginUI()).String(), http.StatusFound)
+	a, err := h.NewLoginFlow(w, r, flow.TypeBrowser)
+	if err != nil {
+		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
 	}
 
-	if a.Forced {
-		if err := h.d.LoginFlowPersister().ForceLoginFlow(r.Context(), a.ID); err != nil {
-			h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
-			return
+	if !hasSession {
+		if hint := r.URL.Query().Get("login_hint"); hint != "" {
+			for _, s := range h.d.LoginStrategies() {
+				discoverer, ok := s.(HomeRealmDiscoverer)
+				if !ok {
+					continue
+				}
+
+				if handled, err := discoverer.DiscoverHomeRealm(w, r, a, hint); err != nil {
+					h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+					return
+				} else if handled {
+					return
+				}
+			}
 		}
+
 		http.Redirect(w, r, a.AppendTo(h.c.SelfServiceFlowLoginUI()).String(), http.StatusFound)
 		return
 	}
 
-	returnTo, err := x.SecureRedirectTo(r, h.c.SelfServiceBrowserDefaultReturnTo(),
-		x.SecureRedirectAllowSelfServiceURLs(h.c.SelfPublicURL()),
-		x.SecureRedirectAllowURLs(h.c.SelfServiceBrowserWhitelistedReturnToDomains()),
-	)
-	if err != nil {
+	if err := h.d.LoginFlowPersister().ForceLoginFlow(r.Context(), a.ID); err != nil {
 		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
 	}
-
-	http.Redirect(w, r, returnTo.String(), http.StatusFound)
+	http.Redirect(w, r, a.AppendTo(h.c.SelfServiceFlowLoginUI()).String(), http.StatusFound)
 }
 
 // nolint:deadcode,unused
@@ -237,7 +316,7 @@ func (h *Handler) fetchFlow(w http.ResponseWriter, r *http.Request, _ httprouter
 		return
 	}
 
-	if ar.ExpiresAt.Before(time.Now()) {
+	if ar.ExpiresAt.Before(h.d.Clock().Now()) {
 		if ar.Type == flow.TypeBrowser {
 			h.d.Writer().WriteError(w, r, errors.WithStack(x.ErrGone.
 				WithReason("The login flow has expired. Redirect the user to the login flow init endpoint to initialize a new login flow.").
@@ -107,6 +107,16 @@ func TestInitFlow(t *testing.T) {
 			assert.Contains(t, res.Request.URL.String(), login.RouteInitAPIFlow)
 			assertion(body, true, true)
 		})
+
+		t.Run("case=emits the user interface hints of the strategies", func(t *testing.T) {
+			conf.MustSet(config.ViperKeySelfServiceStrategyConfig+".password.ui", map[string]interface{}{"label": "Password", "icon": "key", "order": 2})
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeySelfServiceStrategyConfig+".password", map[string]interface{}{"enabled": true})
+			})
+
+			_, body := initFlow(t, url.Values{}, true)
+			assert.JSONEq(t, `{"label":"Password","icon":"key","order":2}`, gjson.GetBytes(body, "methods.password.config.ui").Raw, "%s", body)
+		})
 	})
 
 	t.Run("flow=browser", func(t *testing.T) {
@@ -137,6 +147,37 @@ func TestInitFlow(t *testing.T) {
 			assertion(body, true, false)
 			assert.Contains(t, res.Request.URL.String(), loginTS.URL)
 		})
+
+		countFlows := func(t *testing.T) int {
+			count, err := reg.Persister().GetConnection(context.Background()).Count(new(login.Flow))
+			require.NoError(t, err)
+			return count
+		}
+
+		t.Run("case=does not create a flow on authenticated request without refresh=true", func(t *testing.T) {
+			returnTS := testhelpers.NewRedirTS(t, "", conf)
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeySelfServiceBrowserDefaultReturnTo, "https://www.ory.sh")
+			})
+
+			before := countFlows(t)
+			res, _ := initAuthenticatedFlow(t, url.Values{}, false)
+			assert.Contains(t, res.Request.URL.String(), returnTS.URL)
+			assert.Equal(t, before, countFlows(t))
+		})
+
+		t.Run("case=does not create a flow for prefetch requests", func(t *testing.T) {
+			before := countFlows(t)
+
+			req := x.NewTestHTTPRequest(t, "GET", ts.URL+login.RouteInitBrowserFlow, nil)
+			req.Header.Set("Sec-Purpose", "prefetch")
+			res, err := ts.Client().Do(req)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+
+			assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+			assert.Equal(t, before, countFlows(t))
+		})
 	})
 }
 
@@ -3,13 +3,15 @@ package login
 import (
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/hydra"
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
@@ -36,6 +38,9 @@ type (
 		session.PersistenceProvider
 		x.WriterProvider
 		x.LoggingProvider
+		stats.RecorderProvider
+		hydra.Provider
+		x.ClockProvider
 	}
 	HookExecutor struct {
 		d executorDependencies
@@ -62,7 +67,54 @@ func NewHookExecutor(d executorDependencies, c *config.Provider) *HookExecutor {
 }
 
 func (e *HookExecutor) PostLoginHook(w http.ResponseWriter, r *http.Request, ct identity.CredentialsType, a *Flow, i *identity.Identity) error {
-	s := session.NewActiveSession(i, e.c, time.Now().UTC()).Declassify()
+	var fields identity.FieldSelection
+	if a.Type == flow.TypeAPI {
+		var err error
+		if fields, err = identity.ParseFieldSelection(r); err != nil {
+			return err
+		}
+	}
+
+	if !i.IsActive() {
+		e.d.Audit().
+			WithRequest(r).
+			WithField("identity_id", i.ID).
+			WithField("flow_method", ct).
+			Info("An inactive identity tried to sign in.")
+		return schema.NewIdentityInactiveError()
+	}
+
+	if i.IsExpired(e.d.Clock().Now()) {
+		e.d.Audit().
+			WithRequest(r).
+			WithField("identity_id", i.ID).
+			WithField("flow_method", ct).
+			Info("An expired identity tried to sign in.")
+		return schema.NewIdentityExpiredError(*i.ExpiresAt)
+	}
+
+	if err := checkRestrictions(e.c.SelfServiceFlowLoginRestrictions(i.SchemaID), e.d.Clock().Now()); err != nil {
+		e.d.Audit().
+			WithRequest(r).
+			WithError(err).
+			WithField("identity_id", i.ID).
+			WithField("flow_method", ct).
+			Info("A sign in was refused because of the login restrictions.")
+		return err
+	}
+
+	s := session.NewActiveSession(i, e.c, e.d.Clock().Now()).Declassify()
+	if flow.DependsOnFirstLogin(e.c.SelfServiceBrowserReturnToRules(), stats.FlowLogin) {
+		previous, err := e.d.SessionPersister().CountSessionsByIdentity(r.Context(), i.ID)
+		if err != nil {
+			return err
+		}
+		s.FirstTimeLogin = previous == 0
+	}
+	s.SetDevice(r, e.c)
+	if a.Remember && e.c.SessionRememberMeEnabled() {
+		s.Remember(e.c)
+	}
 
 	e.d.Logger().
 		WithRequest(r).
@@ -95,17 +147,39 @@ func (e *HookExecutor) PostLoginHook(w http.ResponseWriter, r *http.Request, ct
 			Debug("ExecuteLoginPostHook completed successfully.")
 	}
 
+	e.d.FlowStatsRecorder().Record(r.Context(), stats.FlowLogin, a.ID, a.Type, ct.String(), stats.EventSucceeded)
+
 	if a.Type == flow.TypeAPI {
 		if err := e.d.SessionPersister().CreateSession(r.Context(), s); err != nil {
 			return errors.WithStack(err)
 		}
+
+		// Refreshing replaces the session the client authenticated with, which must not be used anymore.
+		if a.Forced && e.c.SessionRotationEnabled() {
+			if replaced, err := e.d.SessionManager().FetchFromRequest(r.Context(), r); err == nil {
+				if err := e.d.SessionPersister().RevokeSessionByToken(r.Context(), replaced.Token); err != nil {
+					return errors.WithStack(err)
+				}
+				e.d.Audit().
+					WithRequest(r).
+					WithField("session_id", replaced.ID).
+					WithField("identity_id", replaced.IdentityID).
+					Info("The session was replaced because the identity authenticated again.")
+			}
+		}
+
 		e.d.Audit().
 			WithRequest(r).
 			WithField("session_id", s.ID).
 			WithField("identity_id", i.ID).
 			Info("Identity authenticated successfully and was issued an ORY Kratos Session Token.")
 
-		e.d.Writer().Write(w, r, &APIFlowResponse{Session: s, Token: s.Token})
+		response, err := fields.Apply(&APIFlowResponse{Session: s, Token: s.Token}, "session.identity")
+		if err != nil {
+			return err
+		}
+
+		e.d.Writer().Write(w, r, response)
 		return nil
 	}
 
@@ -118,8 +192,31 @@ func (e *HookExecutor) PostLoginHook(w http.ResponseWriter, r *http.Request, ct
 		WithField("identity_id", i.ID).
 		WithField("session_id", s.ID).
 		Info("Identity authenticated successfully and was issued an ORY Kratos Session Cookie.")
+
+	if a.OAuth2LoginChallenge != "" {
+		redirectTo, err := e.d.Hydra().AcceptLoginRequest(r.Context(), string(a.OAuth2LoginChallenge),
+			newAcceptOAuth2LoginRequest(s, []identity.CredentialsType{ct}))
+		if err != nil {
+			return err
+		}
+
+		http.Redirect(w, r, redirectTo, http.StatusFound)
+		return nil
+	}
+
+	opts, err := flow.ReturnToRedirectOptions(e.c, &flow.ReturnToContext{
+		Flow:       stats.FlowLogin,
+		Method:     ct.String(),
+		SchemaID:   i.SchemaID,
+		RequestURL: a.RequestURL,
+		FirstLogin: s.FirstTimeLogin,
+	}, e.c.SelfServiceFlowLoginReturnTo(ct.String()))
+	if err != nil {
+		return err
+	}
+
 	return x.SecureContentNegotiationRedirection(w, r, s.Declassify(), a.RequestURL,
-		e.d.Writer(), e.c, x.SecureRedirectOverrideDefaultReturnTo(e.c.SelfServiceFlowLoginReturnTo(ct.String())))
+		e.d.Writer(), e.c, opts...)
 }
 
 func (e *HookExecutor) PreLoginHook(w http.ResponseWriter, r *http.Request, a *Flow) error {
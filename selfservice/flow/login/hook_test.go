@@ -1,6 +1,7 @@
 package login_test
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/gobuffalo/httptest"
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tidwall/gjson"
 
 	"github.com/ory/kratos/driver/config"
@@ -34,13 +36,13 @@ func TestLoginExecutor(t *testing.T) {
 				router := httprouter.New()
 
 				router.GET("/login/pre", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-					if testhelpers.SelfServiceHookLoginErrorHandler(t, w, r, reg.LoginHookExecutor().PreLoginHook(w, r, login.NewFlow(time.Minute, "", r, ft))) {
+					if testhelpers.SelfServiceHookLoginErrorHandler(t, w, r, reg.LoginHookExecutor().PreLoginHook(w, r, login.NewFlow(time.Now(), time.Minute, "", r, ft))) {
 						_, _ = w.Write([]byte("ok"))
 					}
 				})
 
 				router.GET("/login/post", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-					a := login.NewFlow(time.Minute, "", r, ft)
+					a := login.NewFlow(time.Now(), time.Minute, "", r, ft)
 					a.RequestURL = x.RequestURL(r).String()
 					testhelpers.SelfServiceHookLoginErrorHandler(t, w, r,
 						reg.LoginHookExecutor().PostLoginHook(w, r, identity.CredentialsType(strategy), a, testhelpers.SelfServiceHookCreateFakeIdentity(t, reg)))
@@ -126,6 +128,29 @@ func TestLoginExecutor(t *testing.T) {
 					assert.EqualValues(t, "https://www.ory.sh/", res.Request.URL.String())
 				})
 
+				t.Run("case=use browser return rules", func(t *testing.T) {
+					t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
+					conf.MustSet(config.ViperKeyURLsWhitelistedReturnToDomains, []string{"https://www.ory.sh/"})
+					conf.MustSet(config.ViperKeySelfServiceBrowserReturnToRules, []map[string]interface{}{
+						{"flow": "registration", "to": "https://www.ory.sh/registration"},
+						{"flow": "login", "method": strategy, "first_login": false, "to": "https://www.ory.sh/returning"},
+						{"flow": "login", "return_to": "^https://www\\.ory\\.sh/docs/", "to": "https://www.ory.sh/docs/kratos"},
+						{"flow": "login", "method": strategy, "first_login": true, "return_to": "^$", "to": "https://www.ory.sh/onboarding"},
+					})
+
+					res, _ := makeRequestPost(t, newServer(t, flow.TypeBrowser), false, url.Values{})
+					assert.EqualValues(t, http.StatusOK, res.StatusCode)
+					assert.EqualValues(t, "https://www.ory.sh/onboarding", res.Request.URL.String())
+
+					res, _ = makeRequestPost(t, newServer(t, flow.TypeBrowser), false, url.Values{"return_to": {"https://www.ory.sh/docs/ecosystem"}})
+					assert.EqualValues(t, http.StatusOK, res.StatusCode)
+					assert.EqualValues(t, "https://www.ory.sh/docs/kratos", res.Request.URL.String())
+
+					res, _ = makeRequestPost(t, newServer(t, flow.TypeBrowser), false, url.Values{"return_to": {"https://www.ory.sh/kratos/"}})
+					assert.EqualValues(t, http.StatusOK, res.StatusCode)
+					assert.EqualValues(t, "https://www.ory.sh/kratos/", res.Request.URL.String())
+				})
+
 				t.Run("case=send a json response for API clients", func(t *testing.T) {
 					t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
 
@@ -133,6 +158,42 @@ func TestLoginExecutor(t *testing.T) {
 					assert.EqualValues(t, http.StatusOK, res.StatusCode)
 					assert.NotEmpty(t, gjson.Get(body, "session.identity.id"))
 				})
+
+				t.Run("case=mark the first session of an identity", func(t *testing.T) {
+					t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
+					t.Cleanup(func() {
+						conf.MustSet(config.ViperKeySelfServiceBrowserReturnToRules, []map[string]interface{}{})
+					})
+					i := testhelpers.SelfServiceHookCreateFakeIdentity(t, reg)
+
+					router := httprouter.New()
+					router.GET("/login/post", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+						a := login.NewFlow(time.Now(), time.Minute, "", r, flow.TypeAPI)
+						a.RequestURL = x.RequestURL(r).String()
+						testhelpers.SelfServiceHookLoginErrorHandler(t, w, r,
+							reg.LoginHookExecutor().PostLoginHook(w, r, identity.CredentialsType(strategy), a, i))
+					})
+					ts := httptest.NewServer(router)
+					t.Cleanup(ts.Close)
+
+					// Sessions are only counted if a rule depends on the first login.
+					conf.MustSet(config.ViperKeySelfServiceBrowserReturnToRules, []map[string]interface{}{})
+					res, body := makeRequestPost(t, ts, true, url.Values{})
+					assert.EqualValues(t, http.StatusOK, res.StatusCode)
+					assert.False(t, gjson.Get(body, "session.first_time_login").Bool(), "%s", body)
+
+					conf.MustSet(config.ViperKeySelfServiceBrowserReturnToRules, []map[string]interface{}{
+						{"flow": "login", "first_login": true, "to": "https://www.ory.sh/onboarding"},
+					})
+					require.NoError(t, reg.SessionPersister().DeleteSessionsByIdentity(context.Background(), i.ID))
+					res, body = makeRequestPost(t, ts, true, url.Values{})
+					assert.EqualValues(t, http.StatusOK, res.StatusCode)
+					assert.True(t, gjson.Get(body, "session.first_time_login").Bool(), "%s", body)
+					assert.False(t, gjson.Get(body, "session.just_registered").Bool(), "%s", body)
+
+					_, body = makeRequestPost(t, ts, true, url.Values{})
+					assert.False(t, gjson.Get(body, "session.first_time_login").Bool(), "%s", body)
+				})
 			})
 
 			t.Run("type=api", func(t *testing.T) {
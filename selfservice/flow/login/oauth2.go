@@ -0,0 +1,139 @@
+package login
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/kratos/hydra"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/session"
+)
+
+// setOAuth2LoginChallenge stores ORY Hydra's login challenge, if any, in browser flows after making sure that it is
+// valid.
+func (h *Handler) setOAuth2LoginChallenge(r *http.Request, f *Flow) error {
+	challenge := r.URL.Query().Get("login_challenge")
+	if challenge == "" || f.Type != flow.TypeBrowser {
+		return nil
+	}
+
+	if _, err := h.d.Hydra().GetLoginRequest(r.Context(), challenge); err != nil {
+		return err
+	}
+
+	f.OAuth2LoginChallenge = sqlxx.NullString(challenge)
+	return nil
+}
+
+// acceptOAuth2LoginWithSession accepts ORY Hydra's login request for the identity of an existing session.
+func (h *Handler) acceptOAuth2LoginWithSession(w http.ResponseWriter, r *http.Request, challenge string, s *session.Session) {
+	lr, err := h.d.Hydra().GetLoginRequest(r.Context(), challenge)
+	if err != nil {
+		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+
+	if lr.Skip && lr.Subject != s.IdentityID.String() {
+		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, errors.WithStack(herodot.ErrForbidden.
+			WithReason("The OAuth2 provider remembered a different user than the one signed in. Please sign out and try again.")))
+		return
+	}
+
+	redirectTo, err := h.d.Hydra().AcceptLoginRequest(r.Context(), challenge, newAcceptOAuth2LoginRequest(s, nil))
+	if err != nil {
+		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, redirectTo, http.StatusFound)
+}
+
+func newAcceptOAuth2LoginRequest(s *session.Session, amr []identity.CredentialsType) *hydra.AcceptLoginRequest {
+	methods := make([]string, len(amr))
+	for k, m := range amr {
+		methods[k] = m.String()
+	}
+
+	// Error is ignored because the context only contains a UUID.
+	context, _ := json.Marshal(map[string]interface{}{"session_id": s.ID})
+
+	// The Kratos session decides whether the user is signed in, which is why ORY Hydra must not remember the login.
+	return &hydra.AcceptLoginRequest{
+		Subject: s.IdentityID.String(),
+		AMR:     methods,
+		Context: context,
+	}
+}
+
+// nolint:deadcode,unused
+// swagger:parameters acceptOAuth2ConsentRequest
+type acceptOAuth2ConsentRequest struct {
+	// The consent challenge sent by ORY Hydra.
+	//
+	// required: true
+	// in: query
+	ConsentChallenge string `json:"consent_challenge"`
+}
+
+// swagger:route GET /self-service/login/oauth2/consent public acceptOAuth2ConsentRequest
+//
+// Accept an ORY Hydra Consent Request
+//
+// This endpoint is ORY Hydra's consent endpoint if `oauth2_provider.url` is configured. It grants all requested
+// scopes and audiences to the identity signed in and adds its traits to the ID Token, after which the browser
+// is redirected back to ORY Hydra.
+//
+// This endpoint is NOT INTENDED for API clients and only works with browsers (Chrome, Firefox, ...).
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       302: emptyResponse
+//       500: genericError
+func (h *Handler) oauth2Consent(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	challenge := r.URL.Query().Get("consent_challenge")
+	if challenge == "" {
+		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, errors.WithStack(herodot.ErrBadRequest.
+			WithReason("The consent_challenge query parameter is missing.")))
+		return
+	}
+
+	s, err := h.d.SessionManager().FetchFromRequest(r.Context(), r)
+	if err != nil {
+		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+
+	cr, err := h.d.Hydra().GetConsentRequest(r.Context(), challenge)
+	if err != nil {
+		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+
+	if cr.Subject != s.IdentityID.String() {
+		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, errors.WithStack(herodot.ErrForbidden.
+			WithReason("The consent request belongs to a different user than the one signed in.")))
+		return
+	}
+
+	redirectTo, err := h.d.Hydra().AcceptConsentRequest(r.Context(), challenge, &hydra.AcceptConsentRequest{
+		GrantScope:    cr.RequestedScope,
+		GrantAudience: cr.RequestedAudience,
+		Session: hydra.ConsentSession{
+			IDToken: map[string]interface{}{"traits": json.RawMessage(s.Identity.Traits)},
+		},
+	})
+	if err != nil {
+		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, redirectTo, http.StatusFound)
+}
@@ -0,0 +1,126 @@
+package login_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/hydra"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/x"
+)
+
+func TestOAuth2Provider(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	router := x.NewRouterPublic()
+	ts, _ := testhelpers.NewKratosServerWithRouters(t, reg, router, x.NewRouterAdmin())
+	loginTS := testhelpers.NewLoginUIFlowEchoServer(t, reg)
+	errTS := testhelpers.NewErrorTestServer(t, reg)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/login.schema.json")
+
+	id := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	id.Traits = identity.Traits(`{"email":"oauth2@ory.sh"}`)
+
+	var acceptedLogin hydra.AcceptLoginRequest
+	var acceptedConsent hydra.AcceptConsentRequest
+	hydraRouter := httprouter.New()
+	hydraRouter.GET("/oauth2/auth/requests/login", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		if r.URL.Query().Get("login_challenge") != "valid" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&hydra.LoginRequest{Challenge: "valid"})
+	})
+	hydraRouter.PUT("/oauth2/auth/requests/login/accept", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&acceptedLogin))
+		_ = json.NewEncoder(w).Encode(map[string]string{"redirect_to": "https://hydra.example/login-accepted"})
+	})
+	hydraRouter.GET("/oauth2/auth/requests/consent", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		_ = json.NewEncoder(w).Encode(&hydra.ConsentRequest{
+			Challenge:      r.URL.Query().Get("consent_challenge"),
+			Subject:        id.ID.String(),
+			RequestedScope: []string{"openid", "offline"},
+		})
+	})
+	hydraRouter.PUT("/oauth2/auth/requests/consent/accept", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&acceptedConsent))
+		_ = json.NewEncoder(w).Encode(map[string]string{"redirect_to": "https://hydra.example/consent-accepted"})
+	})
+	hydraTS := httptest.NewServer(hydraRouter)
+	defer hydraTS.Close()
+	conf.MustSet(config.ViperKeyOAuth2ProviderURL, hydraTS.URL)
+
+	noRedirects := func(c *http.Client) *http.Client {
+		c.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		return c
+	}
+
+	t.Run("case=stores the login challenge in the flow", func(t *testing.T) {
+		res, err := ts.Client().Get(ts.URL + login.RouteInitBrowserFlow + "?login_challenge=valid")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Contains(t, res.Request.URL.String(), loginTS.URL)
+
+		f, err := reg.LoginFlowPersister().GetLoginFlow(context.Background(), x.ParseUUID(res.Request.URL.Query().Get("flow")))
+		require.NoError(t, err)
+		assert.EqualValues(t, "valid", f.OAuth2LoginChallenge)
+	})
+
+	t.Run("case=rejects invalid login challenges", func(t *testing.T) {
+		res, err := ts.Client().Get(ts.URL + login.RouteInitBrowserFlow + "?login_challenge=invalid")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Contains(t, res.Request.URL.String(), errTS.URL)
+	})
+
+	t.Run("case=accepts the login request for an existing session", func(t *testing.T) {
+		c := noRedirects(testhelpers.NewHTTPClientWithIdentitySessionCookie(t, reg, id))
+		res, err := c.Get(ts.URL + login.RouteInitBrowserFlow + "?login_challenge=valid")
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+		assert.Equal(t, "https://hydra.example/login-accepted", res.Header.Get("Location"))
+		assert.Equal(t, id.ID.String(), acceptedLogin.Subject)
+		assert.True(t, gjson.GetBytes(acceptedLogin.Context, "session_id").Exists())
+	})
+
+	t.Run("case=accepts the consent request", func(t *testing.T) {
+		c := noRedirects(testhelpers.NewHTTPClientWithIdentitySessionCookie(t, reg, id))
+		res, err := c.Get(ts.URL + login.RouteOAuth2Consent + "?consent_challenge=consent")
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+		assert.Equal(t, "https://hydra.example/consent-accepted", res.Header.Get("Location"))
+		assert.Equal(t, []string{"openid", "offline"}, acceptedConsent.GrantScope)
+		traits, err := json.Marshal(acceptedConsent.Session.IDToken["traits"])
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"email":"oauth2@ory.sh"}`, string(traits))
+	})
+
+	t.Run("case=rejects consent requests of other identities", func(t *testing.T) {
+		other := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		other.Traits = identity.Traits(`{"email":"other@ory.sh"}`)
+		c := noRedirects(testhelpers.NewHTTPClientWithIdentitySessionCookie(t, reg, other))
+		res, err := c.Get(ts.URL + login.RouteOAuth2Consent + "?consent_challenge=consent")
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+		assert.Contains(t, res.Header.Get("Location"), errTS.URL)
+	})
+}
@@ -0,0 +1,103 @@
+package login
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/stringsx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/schema"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// checkRestrictions returns an error if the restrictions do not allow signing in at the given time.
+func checkRestrictions(r *config.LoginRestrictions, now time.Time) error {
+	if r == nil {
+		return nil
+	}
+
+	for _, w := range r.MaintenanceWindows {
+		if !now.Before(w.Start) && now.Before(w.End) {
+			return schema.NewLoginMaintenanceError(w.End.UTC())
+		}
+	}
+
+	if len(r.AllowedHours) == 0 {
+		return nil
+	}
+
+	loc, err := time.LoadLocation(stringsx.Coalesce(r.Timezone, "UTC"))
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to load the time zone of the login restrictions: %s", err))
+	}
+
+	local := now.In(loc)
+	for _, h := range r.AllowedHours {
+		if hoursAllow(h, local) {
+			return nil
+		}
+	}
+
+	return schema.NewLoginOutsideAllowedHoursError(nextAllowed(r.AllowedHours, local).UTC())
+}
+
+// hoursAllow returns true if the time is within the hours. Hours spanning midnight belong to the day they start on.
+func hoursAllow(h config.LoginAllowedHours, t time.Time) bool {
+	from, to, now := minuteOfDay(h.From), minuteOfDay(h.To), t.Hour()*60+t.Minute()
+	if from < to {
+		return appliesTo(h, t.Weekday()) && from <= now && now < to
+	}
+	return (appliesTo(h, t.Weekday()) && now >= from) ||
+		(appliesTo(h, t.AddDate(0, 0, -1).Weekday()) && now < to)
+}
+
+// nextAllowed returns the time the next allowed hours start at.
+func nextAllowed(hours []config.LoginAllowedHours, t time.Time) time.Time {
+	var next time.Time
+	for day := 0; day <= 7; day++ {
+		for _, h := range hours {
+			from := minuteOfDay(h.From)
+			start := time.Date(t.Year(), t.Month(), t.Day()+day, from/60, from%60, 0, 0, t.Location())
+			if start.After(t) && appliesTo(h, start.Weekday()) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+		if !next.IsZero() {
+			return next
+		}
+	}
+	return t
+}
+
+func appliesTo(h config.LoginAllowedHours, day time.Weekday) bool {
+	if len(h.Days) == 0 {
+		return true
+	}
+	for _, d := range h.Days {
+		if weekdays[d] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// minuteOfDay parses a time formatted as `15:04`. The format is enforced by the configuration schema.
+func minuteOfDay(value string) int {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0
+	}
+	return t.Hour()*60 + t.Minute()
+}
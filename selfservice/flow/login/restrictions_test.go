@@ -0,0 +1,93 @@
+package login
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/text"
+)
+
+func TestCheckRestrictions(t *testing.T) {
+	// 2021-01-04 is a Monday.
+	at := func(value string) time.Time {
+		v, err := time.Parse(time.RFC3339, value)
+		require.NoError(t, err)
+		return v
+	}
+
+	restrictions := &config.LoginRestrictions{
+		Timezone: "Europe/Berlin",
+		AllowedHours: []config.LoginAllowedHours{
+			{Days: []string{"mon", "tue", "wed", "thu", "fri"}, From: "08:00", To: "18:00"},
+			{Days: []string{"sat"}, From: "22:00", To: "02:00"},
+		},
+		MaintenanceWindows: []config.LoginMaintenanceWindow{
+			{Start: at("2021-01-04T10:00:00Z"), End: at("2021-01-04T11:00:00Z")},
+		},
+	}
+
+	refusal := func(t *testing.T, err error) *text.Message {
+		require.Error(t, err)
+		var ve *schema.ValidationError
+		require.True(t, errors.As(err, &ve), "%+v", err)
+		require.Len(t, ve.Messages, 1)
+		return &ve.Messages[0]
+	}
+
+	t.Run("case=unrestricted", func(t *testing.T) {
+		assert.NoError(t, checkRestrictions(nil, time.Now()))
+		assert.NoError(t, checkRestrictions(&config.LoginRestrictions{}, time.Now()))
+	})
+
+	for _, tc := range []struct {
+		name string
+		now  string
+	}{
+		{name: "within working hours", now: "2021-01-04T07:00:00Z"},
+		{name: "at the start of the working hours", now: "2021-01-05T08:00:00+01:00"},
+		{name: "before midnight on saturday", now: "2021-01-09T23:30:00+01:00"},
+		{name: "after midnight on sunday", now: "2021-01-10T01:30:00+01:00"},
+	} {
+		t.Run("case=allows signing in "+tc.name, func(t *testing.T) {
+			assert.NoError(t, checkRestrictions(restrictions, at(tc.now)))
+		})
+	}
+
+	for _, tc := range []struct {
+		name string
+		now  string
+		next string
+	}{
+		{name: "before the working hours", now: "2021-01-04T06:00:00+01:00", next: "2021-01-04T07:00:00Z"},
+		{name: "at the end of the working hours", now: "2021-01-04T18:00:00+01:00", next: "2021-01-05T07:00:00Z"},
+		{name: "on saturday afternoon", now: "2021-01-09T15:00:00+01:00", next: "2021-01-09T21:00:00Z"},
+		{name: "on sunday", now: "2021-01-10T12:00:00+01:00", next: "2021-01-11T07:00:00Z"},
+	} {
+		t.Run("case=refuses signing in "+tc.name, func(t *testing.T) {
+			m := refusal(t, checkRestrictions(restrictions, at(tc.now)))
+			assert.Equal(t, text.ErrorValidationLoginOutsideAllowedHours, m.ID)
+			assert.JSONEq(t, `{"next_allowed_at":"`+tc.next+`"}`, string(m.Context))
+		})
+	}
+
+	t.Run("case=refuses signing in during maintenance", func(t *testing.T) {
+		m := refusal(t, checkRestrictions(restrictions, at("2021-01-04T10:30:00Z")))
+		assert.Equal(t, text.ErrorValidationLoginMaintenance, m.ID)
+		assert.JSONEq(t, `{"until":"2021-01-04T11:00:00Z"}`, string(m.Context))
+
+		assert.NoError(t, checkRestrictions(restrictions, at("2021-01-04T11:00:00Z")))
+	})
+
+	t.Run("case=fails for unknown time zones", func(t *testing.T) {
+		require.Error(t, checkRestrictions(&config.LoginRestrictions{
+			Timezone:     "Mars/Olympus_Mons",
+			AllowedHours: restrictions.AllowedHours,
+		}, time.Now()))
+	})
+}
@@ -15,6 +15,13 @@ type Strategy interface {
 	PopulateLoginMethod(r *http.Request, sr *Flow) error
 }
 
+// HomeRealmDiscoverer is implemented by strategies which are able to send the user to the identity provider
+// responsible for an identifier, for example the OpenID Connect provider of an email domain.
+type HomeRealmDiscoverer interface {
+	// DiscoverHomeRealm returns true if it took over the response.
+	DiscoverHomeRealm(w http.ResponseWriter, r *http.Request, f *Flow, identifier string) (bool, error)
+}
+
 type Strategies []Strategy
 
 func (s Strategies) Strategy(id identity.CredentialsType) (Strategy, error) {
@@ -0,0 +1,105 @@
+package logout
+
+import (
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+// frontChannelPage loads the front-channel logout URLs of the applications sharing the session cookie and redirects
+// the browser once all of them loaded or the timeout passed. Without JavaScript, the URLs are loaded by the markup
+// and the browser is redirected after the timeout.
+var frontChannelPage = template.Must(template.New("front_channel").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Signing out</title>
+<noscript><meta http-equiv="refresh" content="{{.TimeoutSeconds}};url={{.ReturnTo}}"></noscript>
+</head>
+<body>
+<noscript>
+{{range .Frames}}{{if .Image}}<img src="{{.URL}}" alt="" width="1" height="1">{{else}}<iframe src="{{.URL}}" title="Signing out" width="0" height="0" style="border:0"></iframe>{{end}}
+{{end}}</noscript>
+<script>
+(function () {
+  var frames = {{.Frames}};
+  var returnTo = {{.ReturnTo}};
+  var pending = frames.length;
+  var redirected = false;
+  function redirect() {
+    if (!redirected) {
+      redirected = true;
+      window.location.replace(returnTo);
+    }
+  }
+  function loaded() {
+    pending--;
+    if (pending <= 0) {
+      redirect();
+    }
+  }
+  for (var i = 0; i < frames.length; i++) {
+    var e = document.createElement(frames[i].image ? "img" : "iframe");
+    e.onload = loaded;
+    e.onerror = loaded;
+    e.width = 0;
+    e.height = 0;
+    e.style.display = "none";
+    e.src = frames[i].url;
+    document.body.appendChild(e);
+  }
+  setTimeout(redirect, {{.TimeoutMilliseconds}});
+})();
+</script>
+</body>
+</html>
+`))
+
+type frontChannelFrame struct {
+	URL   string `json:"url"`
+	Image bool   `json:"image"`
+}
+
+// frontChannelFrames returns the front-channel logout URLs for the session, which are given the issuer and the
+// session ID as defined by OpenID Connect Front-Channel Logout.
+func (h *Handler) frontChannelFrames(s *session.Session) []frontChannelFrame {
+	configured := h.c.SelfServiceFlowLogoutFrontChannelURLs()
+	frames := make([]frontChannelFrame, 0, len(configured))
+	for _, c := range configured {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			continue
+		}
+
+		query := u.Query()
+		query.Set("iss", h.c.SelfPublicURL().String())
+		query.Set("sid", s.ID.String())
+		u.RawQuery = query.Encode()
+		frames = append(frames, frontChannelFrame{URL: u.String(), Image: c.Type == "image"})
+	}
+	return frames
+}
+
+func (h *Handler) writeFrontChannelPage(w http.ResponseWriter, r *http.Request, frames []frontChannelFrame, returnTo *url.URL) {
+	timeout := h.c.SelfServiceFlowLogoutFrontChannelTimeout()
+
+	x.NoCache(w)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := frontChannelPage.Execute(w, &struct {
+		Frames              []frontChannelFrame
+		ReturnTo            string
+		TimeoutSeconds      int64
+		TimeoutMilliseconds int64
+	}{
+		Frames:              frames,
+		ReturnTo:            returnTo.String(),
+		TimeoutSeconds:      int64(math.Ceil(timeout.Seconds())),
+		TimeoutMilliseconds: timeout.Milliseconds(),
+	}); err != nil {
+		h.d.Logger().WithRequest(r).WithError(err).Error("Unable to write the front-channel logout page.")
+	}
+}
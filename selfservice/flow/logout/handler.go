@@ -19,7 +19,10 @@ const (
 type (
 	handlerDependencies interface {
 		x.CSRFProvider
+		x.LoggingProvider
 		session.ManagementProvider
+		session.PersistenceProvider
+		session.BackChannelNotifierProvider
 		errorx.ManagementProvider
 	}
 	HandlerProvider interface {
@@ -36,7 +39,19 @@ func NewHandler(d handlerDependencies, c *config.Provider) *Handler {
 }
 
 func (h *Handler) RegisterPublicRoutes(router *x.RouterPublic) {
-	router.GET(RouteBrowser, h.logout)
+	router.GET(RouteBrowser, x.NoPrefetchHandler(h.logout))
+}
+
+// nolint:deadcode,unused
+// swagger:parameters initializeSelfServiceBrowserLogoutFlow
+type initializeSelfServiceBrowserLogoutFlow struct {
+	// Log out everywhere
+	//
+	// If set to true, all sessions of the identity are revoked instead of
+	// only the current one, which signs the user out on all devices.
+	//
+	// in: query
+	Everywhere bool `json:"everywhere"`
 }
 
 // swagger:route GET /self-service/browser/flows/logout public initializeSelfServiceBrowserLogoutFlow
@@ -51,16 +66,44 @@ func (h *Handler) RegisterPublicRoutes(router *x.RouterPublic) {
 // On successful logout, the browser will be redirected (HTTP 302 Found) to the `return_to` parameter of the initial request
 // or fall back to `urls.default_return_to`.
 //
+// If the `everywhere` query parameter is `true`, all sessions of the identity are revoked, which signs the user out
+// on all devices.
+//
+// If front-channel logout URLs are configured in `selfservice.flows.logout.front_channel.urls`, a page is returned
+// instead (HTTP 200 OK) which loads these URLs before it redirects the browser.
+//
 // More information can be found at [ORY Kratos User Logout Documentation](https://www.ory.sh/docs/next/kratos/self-service/flows/user-logout).
 //
 //     Schemes: http, https
 //
 //     Responses:
+//       200: emptyResponse
 //       302: emptyResponse
 //       500: genericError
 func (h *Handler) logout(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	_ = h.d.CSRFHandler().RegenerateToken(w, r)
 
+	// The session is resolved before it is purged so that the front-channel logout URLs learn which session ended.
+	var frames []frontChannelFrame
+	if s, err := h.d.SessionManager().FetchFromRequest(r.Context(), r); err == nil {
+		frames = h.frontChannelFrames(s)
+
+		if r.URL.Query().Get("everywhere") == "true" {
+			count, err := h.d.SessionPersister().RevokeSessionsByIdentity(r.Context(), s.IdentityID)
+			if err != nil {
+				h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+				return
+			}
+
+			h.d.Audit().
+				WithRequest(r).
+				WithField("identity_id", s.IdentityID).
+				WithField("revoked_sessions", count).
+				Info("The identity signed out on all devices.")
+			h.d.SessionBackChannelNotifier().IdentitySessionsRevoked(s.IdentityID, session.RevocationReasonLogoutEverywhere)
+		}
+	}
+
 	if err := h.d.SessionManager().PurgeFromRequest(r.Context(), w, r); err != nil {
 		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
@@ -77,5 +120,10 @@ func (h *Handler) logout(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		return
 	}
 
+	if len(frames) > 0 {
+		h.writeFrontChannelPage(w, r, frames, ret)
+		return
+	}
+
 	http.Redirect(w, r, ret.String(), http.StatusFound)
 }
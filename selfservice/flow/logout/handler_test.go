@@ -5,7 +5,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/gobuffalo/httptest"
 	"github.com/julienschmidt/httprouter"
@@ -32,7 +34,7 @@ func TestLogoutHandler(t *testing.T) {
 
 	router := x.NewRouterPublic()
 	handler.RegisterPublicRoutes(router)
-	reg.WithCSRFHandler(x.NewCSRFHandler(router, reg.Writer(), logrusx.New("", ""), "/", "", false))
+	reg.WithCSRFHandler(x.NewCSRFHandler(router, reg.Writer(), logrusx.New("", ""), "/", "", false, 0))
 	ts := httptest.NewServer(reg.CSRFHandler())
 	defer ts.Close()
 
@@ -109,4 +111,77 @@ func TestLogoutHandler(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, returnToURL, res.Request.URL.String())
 	})
+
+	t.Run("case=logs out everywhere", func(t *testing.T) {
+		i := new(identity.Identity)
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+		current := session.NewActiveSession(i, conf, time.Now().UTC())
+		other := session.NewActiveSession(i, conf, time.Now().UTC())
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), other))
+
+		unrelatedIdentity := new(identity.Identity)
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), unrelatedIdentity))
+		unrelated := session.NewActiveSession(unrelatedIdentity, conf, time.Now().UTC())
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), unrelated))
+
+		router.GET("/set/everywhere", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			require.NoError(t, reg.SessionManager().CreateAndIssueCookie(r.Context(), w, r, current))
+		})
+		client := testhelpers.NewClientWithCookies(t)
+		testhelpers.MockHydrateCookieClient(t, client, ts.URL+"/set/everywhere")
+
+		res, err := client.Get(ts.URL + logout.RouteBrowser + "?everywhere=true")
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, redirTS.URL, res.Request.URL.String())
+
+		for s, expected := range map[*session.Session]bool{current: false, other: false, unrelated: true} {
+			actual, err := reg.SessionPersister().GetSession(context.Background(), s.ID)
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual.Active)
+		}
+	})
+
+	t.Run("case=loads the front-channel logout urls", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceLogoutFrontChannelURLs, []map[string]interface{}{
+			{"url": "https://app.example.org/logout?foo=bar"},
+			{"url": "https://pixel.example.org/logout.gif", "type": "image"},
+		})
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceLogoutFrontChannelURLs, []map[string]interface{}{})
+		})
+
+		t.Run("case=without a session", func(t *testing.T) {
+			res, err := testhelpers.NewClientWithCookies(t).Get(ts.URL + logout.RouteBrowser)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			assert.Equal(t, redirTS.URL, res.Request.URL.String())
+		})
+
+		t.Run("case=with a session", func(t *testing.T) {
+			client := testhelpers.NewClientWithCookies(t)
+			testhelpers.MockHydrateCookieClient(t, client, ts.URL+"/set")
+
+			res, err := client.Get(ts.URL + logout.RouteBrowser)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			assert.Equal(t, ts.URL+logout.RouteBrowser, res.Request.URL.String())
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Contains(t, res.Header.Get("Content-Type"), "text/html")
+
+			for _, c := range res.Cookies() {
+				if c.Name == session.DefaultSessionCookieName {
+					assert.True(t, c.MaxAge < 0, "the session cookie must be removed")
+				}
+			}
+
+			body, err := ioutil.ReadAll(res.Body)
+			require.NoError(t, err)
+			iss := url.QueryEscape(ts.URL)
+			assert.Regexp(t, `<iframe src="https://app.example.org/logout\?foo=bar&amp;iss=`+regexp.QuoteMeta(iss)+`&amp;sid=[0-9a-f-]{36}"`, string(body))
+			assert.Regexp(t, `<img src="https://pixel.example.org/logout.gif\?iss=`+regexp.QuoteMeta(iss)+`&amp;sid=[0-9a-f-]{36}"`, string(body))
+			assert.Contains(t, string(body), `content="5;url=`+redirTS.URL+`"`)
+			assert.Contains(t, string(body), `var returnTo = "`+redirTS.URL+`";`)
+		})
+	})
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
@@ -45,9 +46,11 @@ type (
 		x.WriterProvider
 		x.LoggingProvider
 		x.CSRFTokenGeneratorProvider
+		stats.RecorderProvider
 		StrategyProvider
 
 		FlowPersistenceProvider
+		x.ClockProvider
 	}
 
 	ErrorHandlerProvider interface {
@@ -87,7 +90,7 @@ func (s *ErrorHandler) WriteFlowError(
 
 	if e := new(FlowExpiredError); errors.As(err, &e) {
 		// create new flow because the old one is not valid
-		a, err := NewFlow(s.c.SelfServiceFlowRecoveryRequestLifespan(), s.d.GenerateCSRFToken(r), r, s.d.RecoveryStrategies(), f.Type)
+		a, err := NewFlow(s.d.Clock().Now(), s.c.SelfServiceFlowRecoveryRequestLifespan(), s.d.GenerateCSRFToken(r), r, s.d.RecoveryStrategies(), f.Type)
 		if err != nil {
 			// failed to create a new session and redirect to it, handle that error as a new one
 			s.WriteFlowError(w, r, methodName, f, err)
@@ -99,6 +102,7 @@ func (s *ErrorHandler) WriteFlowError(
 			s.forward(w, r, a, err)
 			return
 		}
+		s.d.FlowStatsRecorder().Record(r.Context(), stats.FlowRecovery, a.ID, a.Type, "", stats.EventCreated)
 
 		if f.Type == flow.TypeAPI {
 			http.Redirect(w, r, urlx.CopyWithQuery(urlx.AppendPaths(s.c.SelfPublicURL(),
@@ -116,6 +120,8 @@ func (s *ErrorHandler) WriteFlowError(
 		return
 	}
 
+	s.d.FlowStatsRecorder().Record(r.Context(), stats.FlowRecovery, f.ID, f.Type, methodName, stats.EventFailed)
+
 	if err := method.Config.ParseError(err); err != nil {
 		s.forward(w, r, f, err)
 		return
@@ -146,13 +152,13 @@ func (s *ErrorHandler) forward(w http.ResponseWriter, r *http.Request, rr *Flow,
 			s.d.Writer().WriteError(w, r, err)
 			return
 		}
-		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		s.d.SelfServiceErrorManager().ForwardForFlow(r.Context(), w, r, stats.FlowRecovery, err)
 		return
 	}
 
 	if rr.Type == flow.TypeAPI {
 		s.d.Writer().WriteErrorCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), err)
 	} else {
-		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		s.d.SelfServiceErrorManager().ForwardForFlow(r.Context(), w, r, stats.FlowRecovery, err)
 	}
 }
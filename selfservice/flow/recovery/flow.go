@@ -96,8 +96,7 @@ type Flow struct {
 	RecoveredIdentityID uuid.NullUUID `json:"-" faker:"-" db:"recovered_identity_id"`
 }
 
-func NewFlow(exp time.Duration, csrf string, r *http.Request, strategies Strategies, ft flow.Type) (*Flow, error) {
-	now := time.Now().UTC()
+func NewFlow(now time.Time, exp time.Duration, csrf string, r *http.Request, strategies Strategies, ft flow.Type) (*Flow, error) {
 	req := &Flow{ID: x.NewUUID(),
 		ExpiresAt: now.Add(exp), IssuedAt: now,
 		RequestURL: x.RequestURL(r).String(),
@@ -124,8 +123,8 @@ func (f *Flow) GetID() uuid.UUID {
 	return f.ID
 }
 
-func (f *Flow) Valid() error {
-	if f.ExpiresAt.Before(time.Now().UTC()) {
+func (f *Flow) Valid(now time.Time) error {
+	if f.ExpiresAt.Before(now) {
 		return errors.WithStack(NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
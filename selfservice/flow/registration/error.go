@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/form"
 	"github.com/ory/kratos/text"
 
 	"github.com/pkg/errors"
@@ -16,12 +17,16 @@ import (
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/x"
 )
 
 var (
 	ErrHookAbortFlow   = errors.New("aborted registration hook execution")
 	ErrAlreadyLoggedIn = herodot.ErrBadRequest.WithReason("A valid session was detected and thus registration is not possible.")
+
+	// ErrRegistrationDisabled is returned if identities may not sign up using the public registration endpoints.
+	ErrRegistrationDisabled = herodot.ErrForbidden.WithReason("Registration is disabled. Please contact the administrator to get an account.")
 )
 
 type (
@@ -29,6 +34,7 @@ type (
 		errorx.ManagementProvider
 		x.WriterProvider
 		x.LoggingProvider
+		stats.RecorderProvider
 
 		FlowPersistenceProvider
 		HandlerProvider
@@ -92,6 +98,17 @@ func (s *ErrorHandler) WriteFlowError(
 			return
 		}
 
+		// keep the values entered by the user so that they do not have to enter them again
+		if method, ok := f.Methods[ct]; ok {
+			if target, ok := a.Methods[ct]; ok {
+				if values, ok := method.Config.FlowMethodConfigurator.(form.ValueGetter); ok {
+					for name, value := range values.Values() {
+						target.Config.SetValue(name, value)
+					}
+				}
+			}
+		}
+
 		a.Messages.Add(text.NewErrorValidationRegistrationFlowExpired(e.ago))
 		if err := s.d.RegistrationFlowPersister().UpdateRegistrationFlow(r.Context(), a); err != nil {
 			s.forward(w, r, a, err)
@@ -114,6 +131,8 @@ func (s *ErrorHandler) WriteFlowError(
 		return
 	}
 
+	s.d.FlowStatsRecorder().Record(r.Context(), stats.FlowRegistration, f.ID, f.Type, ct.String(), stats.EventFailed)
+
 	if err := method.Config.ParseError(err); err != nil {
 		s.forward(w, r, f, err)
 		return
@@ -143,13 +162,13 @@ func (s *ErrorHandler) forward(w http.ResponseWriter, r *http.Request, rr *Flow,
 			s.d.Writer().WriteError(w, r, err)
 			return
 		}
-		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		s.d.SelfServiceErrorManager().ForwardForFlow(r.Context(), w, r, stats.FlowRegistration, err)
 		return
 	}
 
 	if rr.Type == flow.TypeAPI {
 		s.d.Writer().WriteErrorCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), err)
 	} else {
-		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		s.d.SelfServiceErrorManager().ForwardForFlow(r.Context(), w, r, stats.FlowRegistration, err)
 	}
 }
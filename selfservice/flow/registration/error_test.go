@@ -62,7 +62,7 @@ func TestHandleError(t *testing.T) {
 
 	newFlow := func(t *testing.T, ttl time.Duration, ft flow.Type) *registration.Flow {
 		req := &http.Request{URL: urlx.ParseOrPanic("/")}
-		f := registration.NewFlow(ttl, "csrf_token", req, ft)
+		f := registration.NewFlow(time.Now(), ttl, "csrf_token", req, ft)
 		for _, s := range reg.RegistrationStrategies() {
 			require.NoError(t, s.PopulateRegistrationMethod(req, f))
 		}
@@ -133,6 +133,27 @@ func TestHandleError(t *testing.T) {
 			assert.NotEqual(t, registrationFlow.ID.String(), gjson.GetBytes(body, "id").String())
 		})
 
+		t.Run("case=expired error keeps the entered values", func(t *testing.T) {
+			t.Cleanup(reset)
+
+			registrationFlow = newFlow(t, time.Minute, flow.TypeAPI)
+			registrationFlow.Methods[identity.CredentialsTypePassword].Config.SetValue("traits.foobar", "foo@ory.sh")
+			registrationFlow.Methods[identity.CredentialsTypePassword].Config.SetValue("password", "secret")
+			flowError = registration.NewFlowExpiredError(anHourAgo)
+			ct = identity.CredentialsTypePassword
+
+			res, err := ts.Client().Do(testhelpers.NewHTTPGetJSONRequest(t, ts.URL+"/error"))
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode)
+
+			body, err := ioutil.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.NotEqual(t, registrationFlow.ID.String(), gjson.GetBytes(body, "id").String())
+			assert.Equal(t, "foo@ory.sh", gjson.GetBytes(body, `methods.password.config.fields.#(name=="traits.foobar").value`).String(), "%s", body)
+			assert.Empty(t, gjson.GetBytes(body, `methods.password.config.fields.#(name=="password").value`).String(), "%s", body)
+		})
+
 		t.Run("case=validation error", func(t *testing.T) {
 			t.Cleanup(reset)
 
@@ -72,11 +72,21 @@ type Flow struct {
 
 	// CSRFToken contains the anti-csrf token associated with this flow. Only set for browser flows.
 	CSRFToken string `json:"-" db:"csrf_token"`
+
+	// FlowToken is only returned when an API flow is initialized. It must be sent in the
+	// `X-Kratos-Flow-Token` HTTP Header when completing the flow if API flow binding is enabled.
+	FlowToken string `json:"flow_token,omitempty" faker:"-" db:"-"`
+
+	// FlowTokenHash is the hash of the flow token.
+	FlowTokenHash string `json:"-" faker:"-" db:"flow_token_hash"`
+
+	// IdentitySchemaID is the ID of the identity schema the registered identity will use. It is chosen
+	// using the `schema` query parameter when the flow is initialized and defaults to `default`.
+	IdentitySchemaID string `json:"identity_schema_id,omitempty" faker:"-" db:"identity_schema_id"`
 }
 
-func NewFlow(exp time.Duration, csrf string, r *http.Request, ft flow.Type) *Flow {
-	now := time.Now().UTC()
-	return &Flow{
+func NewFlow(now time.Time, exp time.Duration, csrf string, r *http.Request, ft flow.Type) *Flow {
+	f := &Flow{
 		ID:         x.NewUUID(),
 		ExpiresAt:  now.Add(exp),
 		IssuedAt:   now,
@@ -85,6 +95,10 @@ func NewFlow(exp time.Duration, csrf string, r *http.Request, ft flow.Type) *Flo
 		CSRFToken:  csrf,
 		Type:       ft,
 	}
+	if ft == flow.TypeAPI {
+		f.FlowToken, f.FlowTokenHash = flow.NewFlowToken()
+	}
+	return f
 }
 
 func (f *Flow) BeforeSave(_ *pop.Connection) error {
@@ -123,8 +137,15 @@ func (f *Flow) GetID() uuid.UUID {
 	return f.ID
 }
 
-func (f *Flow) Valid() error {
-	if f.ExpiresAt.Before(time.Now()) {
+// Valid returns an error if the flow expired. Browser flows are still valid if they are submitted within the grace
+// period after they expired.
+func (f *Flow) Valid(now time.Time, gracePeriod time.Duration) error {
+	expiresAt := f.ExpiresAt
+	if f.Type == flow.TypeBrowser {
+		expiresAt = expiresAt.Add(gracePeriod)
+	}
+
+	if expiresAt.Before(now) {
 		return errors.WithStack(NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
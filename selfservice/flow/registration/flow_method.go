@@ -61,6 +61,7 @@ type FlowMethodConfigurator interface {
 	form.Resetter
 	form.MessageResetter
 	form.CSRFSetter
+	form.UISetter
 	form.FieldSorter
 	form.MessageAdder
 }
@@ -35,7 +35,7 @@ func TestFakeFlow(t *testing.T) {
 
 func TestNewFlow(t *testing.T) {
 	t.Run("case=0", func(t *testing.T) {
-		r := registration.NewFlow(0, "csrf", &http.Request{
+		r := registration.NewFlow(time.Now(), 0, "csrf", &http.Request{
 			URL:  urlx.ParseOrPanic("/"),
 			Host: "ory.sh", TLS: &tls.ConnectionState{},
 		}, flow.TypeBrowser)
@@ -45,7 +45,7 @@ func TestNewFlow(t *testing.T) {
 	})
 
 	t.Run("case=1", func(t *testing.T) {
-		r := registration.NewFlow(0, "csrf", &http.Request{
+		r := registration.NewFlow(time.Now(), 0, "csrf", &http.Request{
 			URL:  urlx.ParseOrPanic("/?refresh=true"),
 			Host: "ory.sh"}, flow.TypeAPI)
 		assert.Equal(t, r.IssuedAt, r.ExpiresAt)
@@ -54,7 +54,7 @@ func TestNewFlow(t *testing.T) {
 	})
 
 	t.Run("case=2", func(t *testing.T) {
-		r := registration.NewFlow(0, "csrf", &http.Request{
+		r := registration.NewFlow(time.Now(), 0, "csrf", &http.Request{
 			URL:  urlx.ParseOrPanic("https://ory.sh/"),
 			Host: "ory.sh"}, flow.TypeBrowser)
 		assert.Equal(t, "https://ory.sh/", r.RequestURL)
@@ -68,6 +68,7 @@ func TestFlow(t *testing.T) {
 	t.Run("case=expired", func(t *testing.T) {
 		for _, tc := range []struct {
 			r     *registration.Flow
+			grace time.Duration
 			valid bool
 		}{
 			{
@@ -75,11 +76,24 @@ func TestFlow(t *testing.T) {
 				valid: true,
 			},
 			{r: &registration.Flow{ExpiresAt: time.Now().Add(-time.Hour), IssuedAt: time.Now().Add(-time.Minute)}},
+			{
+				r:     &registration.Flow{Type: flow.TypeBrowser, ExpiresAt: time.Now().Add(-time.Minute), IssuedAt: time.Now().Add(-time.Hour)},
+				grace: 5 * time.Minute,
+				valid: true,
+			},
+			{
+				r:     &registration.Flow{Type: flow.TypeBrowser, ExpiresAt: time.Now().Add(-time.Hour), IssuedAt: time.Now().Add(-2 * time.Hour)},
+				grace: 5 * time.Minute,
+			},
+			{
+				r:     &registration.Flow{Type: flow.TypeAPI, ExpiresAt: time.Now().Add(-time.Minute), IssuedAt: time.Now().Add(-time.Hour)},
+				grace: 5 * time.Minute,
+			},
 		} {
 			if tc.valid {
-				require.NoError(t, tc.r.Valid())
+				require.NoError(t, tc.r.Valid(time.Now(), tc.grace))
 			} else {
-				require.Error(t, tc.r.Valid())
+				require.Error(t, tc.r.Valid(time.Now(), tc.grace))
 			}
 		}
 	})
@@ -2,16 +2,18 @@ package registration
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
 
+	"github.com/ory/herodot"
 	"github.com/ory/x/urlx"
 
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
@@ -34,6 +36,8 @@ type (
 		HookExecutorProvider
 		FlowPersistenceProvider
 		x.CSRFProvider
+		stats.RecorderProvider
+		x.ClockProvider
 	}
 	HandlerProvider interface {
 		RegistrationHandler() *Handler
@@ -51,7 +55,7 @@ func NewHandler(d handlerDependencies, c *config.Provider) *Handler {
 func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 	h.d.CSRFHandler().IgnorePath(RouteInitAPIFlow)
 
-	public.GET(RouteInitBrowserFlow, h.d.SessionHandler().IsNotAuthenticated(h.initBrowserFlow, session.RedirectOnAuthenticated(h.c)))
+	public.GET(RouteInitBrowserFlow, x.NoPrefetchHandler(h.d.SessionHandler().IsNotAuthenticated(h.initBrowserFlow, session.RedirectOnAuthenticated(h.c))))
 	public.GET(RouteInitAPIFlow, h.d.SessionHandler().IsNotAuthenticated(h.initApiFlow,
 		session.RespondWithJSONErrorOnAuthenticated(h.d.Writer(), errors.WithStack(ErrAlreadyLoggedIn))))
 
@@ -63,13 +67,32 @@ func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 }
 
 func (h *Handler) NewRegistrationFlow(w http.ResponseWriter, r *http.Request, ft flow.Type) (*Flow, error) {
-	a := NewFlow(h.c.SelfServiceFlowRegistrationRequestLifespan(), h.d.GenerateCSRFToken(r), r, ft)
+	schemaID := config.DefaultIdentityTraitsSchemaID
+	if id := r.URL.Query().Get("schema"); id != "" {
+		if _, err := h.c.IdentityTraitsSchemas().FindSchemaByID(id); err != nil {
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The identity schema %s does not exist.", id))
+		}
+		schemaID = id
+	}
+
+	if !h.c.SelfServiceFlowRegistrationEnabled(schemaID) {
+		return nil, errors.WithStack(ErrRegistrationDisabled)
+	}
+
+	a := NewFlow(h.d.Clock().Now(), h.c.SelfServiceFlowRegistrationRequestLifespan(), h.d.GenerateCSRFToken(r), r, ft)
+	a.IdentitySchemaID = schemaID
 	for _, s := range h.d.RegistrationStrategies() {
 		if err := s.PopulateRegistrationMethod(r, a); err != nil {
 			return nil, err
 		}
 	}
 
+	for method, m := range a.Methods {
+		if ui := h.c.SelfServiceStrategy(string(method)).UI; ui != nil {
+			m.Config.SetUI(&form.UI{Label: ui.Label, Icon: ui.Icon, Order: ui.Order})
+		}
+	}
+
 	if err := h.d.RegistrationExecutor().PreRegistrationHook(w, r, a); err != nil {
 		return nil, err
 	}
@@ -78,9 +101,20 @@ func (h *Handler) NewRegistrationFlow(w http.ResponseWriter, r *http.Request, ft
 		return nil, err
 	}
 
+	h.d.FlowStatsRecorder().Record(r.Context(), stats.FlowRegistration, a.ID, a.Type, "", stats.EventCreated)
 	return a, nil
 }
 
+// nolint:deadcode,unused
+// swagger:parameters initializeSelfServiceRegistrationViaAPIFlow initializeSelfServiceRegistrationViaBrowserFlow
+type initializeSelfServiceRegistrationFlowParameters struct {
+	// Schema is the ID of the identity schema the new identity will use, for example `employee`.
+	// It must be configured in `identity.schemas` and defaults to `default`.
+	//
+	// in: query
+	Schema string `json:"schema"`
+}
+
 // swagger:route GET /self-service/registration/api public initializeSelfServiceRegistrationViaAPIFlow
 //
 // Initialize Registration Flow for API clients
@@ -88,10 +122,14 @@ func (h *Handler) NewRegistrationFlow(w http.ResponseWriter, r *http.Request, ft
 // This endpoint initiates a registration flow for API clients such as mobile devices, smart TVs, and so on.
 //
 // If a valid provided session cookie or session token is provided, a 400 Bad Request error
-// will be returned unless the URL query parameter `?refresh=true` is set.
+// will be returned unless the URL query parameter `?refresh=true` is set. If registration is disabled using
+// `selfservice.flows.registration.enabled`, a 403 Forbidden error will be returned.
 //
 // To fetch an existing registration flow call `/self-service/registration/flows?flow=<flow_id>`.
 //
+// The response contains a `flow_token` which is only returned once. If `selfservice.api_flow_binding` is enabled,
+// the flow can only be completed by sending this token in the `X-Kratos-Flow-Token` HTTP Header.
+//
 // :::warning
 //
 // You MUST NOT use this endpoint in client-side (Single Page Apps, ReactJS, AngularJS) nor server-side (Java Server
@@ -109,6 +147,7 @@ func (h *Handler) NewRegistrationFlow(w http.ResponseWriter, r *http.Request, ft
 //     Responses:
 //       200: registrationFlow
 //       400: genericError
+//       403: genericError
 //       500: genericError
 func (h *Handler) initApiFlow(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	a, err := h.NewRegistrationFlow(w, r, flow.TypeAPI)
@@ -127,7 +166,8 @@ func (h *Handler) initApiFlow(w http.ResponseWriter, r *http.Request, ps httprou
 // This endpoint initializes a browser-based user registration flow. Once initialized, the browser will be redirected to
 // `selfservice.flows.registration.ui_url` with the flow ID set as the query parameter `?flow=`. If a valid user session
 // exists already, the browser will be redirected to `urls.default_redirect_url` unless the query parameter
-// `?refresh=true` was set.
+// `?refresh=true` was set. If registration is disabled using `selfservice.flows.registration.enabled`, the browser
+// will be redirected to the error UI.
 //
 // :::note
 //
@@ -195,7 +235,7 @@ func (h *Handler) fetchFlow(w http.ResponseWriter, r *http.Request, ps httproute
 		return
 	}
 
-	if ar.ExpiresAt.Before(time.Now()) {
+	if ar.ExpiresAt.Before(h.d.Clock().Now()) {
 		if ar.Type == flow.TypeBrowser {
 			h.d.Writer().WriteError(w, r, errors.WithStack(x.ErrGone.
 				WithReason("The registration flow has expired. Redirect the user to the registration flow init endpoint to initialize a new registration flow.").
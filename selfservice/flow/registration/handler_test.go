@@ -112,6 +112,57 @@ func TestInitFlow(t *testing.T) {
 			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
 			assertx.EqualAsJSON(t, registration.ErrAlreadyLoggedIn, json.RawMessage(gjson.GetBytes(body, "error").Raw), "%s", body)
 		})
+
+		t.Run("case=fails if registration is disabled", func(t *testing.T) {
+			conf.MustSet(config.ViperKeySelfServiceRegistrationEnabled, false)
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeySelfServiceRegistrationEnabled, true)
+			})
+
+			res, body := initFlow(t, true)
+			assert.Equal(t, http.StatusForbidden, res.StatusCode)
+			assertx.EqualAsJSON(t, registration.ErrRegistrationDisabled, json.RawMessage(gjson.GetBytes(body, "error").Raw), "%s", body)
+		})
+
+		t.Run("case=uses the identity schema from the query", func(t *testing.T) {
+			employee := config.SchemaConfig{ID: "employee", URL: "file://./stub/employee.schema.json"}
+			conf.MustSet(config.ViperKeyIdentitySchemas, []config.SchemaConfig{employee})
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeyIdentitySchemas, []config.SchemaConfig{})
+			})
+
+			res, err := publicTS.Client().Get(publicTS.URL + registration.RouteInitAPIFlow + "?schema=employee")
+			require.NoError(t, err)
+			defer res.Body.Close()
+			body, err := ioutil.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+			assert.Equal(t, "employee", gjson.GetBytes(body, "identity_schema_id").String(), "%s", body)
+			assert.True(t, gjson.GetBytes(body, "methods.password.config.fields.#(name==traits.employee_id)").Exists(), "%s", body)
+			assert.False(t, gjson.GetBytes(body, "methods.password.config.fields.#(name==traits.bar)").Exists(), "%s", body)
+
+			t.Run("case=fails if registration is disabled for the schema", func(t *testing.T) {
+				employee.RegistrationEnabled = new(bool)
+				conf.MustSet(config.ViperKeyIdentitySchemas, []config.SchemaConfig{employee})
+
+				res, err := publicTS.Client().Get(publicTS.URL + registration.RouteInitAPIFlow + "?schema=employee")
+				require.NoError(t, err)
+				defer res.Body.Close()
+				assert.Equal(t, http.StatusForbidden, res.StatusCode)
+			})
+		})
+
+		t.Run("case=fails if the identity schema does not exist", func(t *testing.T) {
+			res, err := publicTS.Client().Get(publicTS.URL + registration.RouteInitAPIFlow + "?schema=does-not-exist")
+			require.NoError(t, err)
+			defer res.Body.Close()
+			body, err := ioutil.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+			assert.Contains(t, gjson.GetBytes(body, "error.reason").String(), "does-not-exist", "%s", body)
+		})
 	})
 
 	t.Run("flow=browser", func(t *testing.T) {
@@ -124,6 +175,19 @@ func TestInitFlow(t *testing.T) {
 			res, _ := initAuthenticatedFlow(t, false)
 			assert.Contains(t, res.Request.URL.String(), "https://www.ory.sh")
 		})
+
+		t.Run("case=redirects to the error UI if registration is disabled", func(t *testing.T) {
+			errTS := testhelpers.NewErrorTestServer(t, reg)
+			conf.MustSet(config.ViperKeySelfServiceRegistrationEnabled, false)
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeySelfServiceRegistrationEnabled, true)
+			})
+
+			res, body := initFlow(t, false)
+			assert.Contains(t, res.Request.URL.String(), errTS.URL)
+			assert.EqualValues(t, http.StatusForbidden, gjson.GetBytes(body, "0.code").Int(), "%s", body)
+			assert.Contains(t, gjson.GetBytes(body, "0.reason").String(), "Registration is disabled", "%s", body)
+		})
 	})
 }
 
@@ -3,7 +3,6 @@ package registration
 import (
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/pkg/errors"
 
@@ -13,6 +12,7 @@ import (
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
@@ -66,6 +66,8 @@ type (
 		HooksProvider
 		x.LoggingProvider
 		x.WriterProvider
+		stats.RecorderProvider
+		x.ClockProvider
 	}
 	HookExecutor struct {
 		d executorDependencies
@@ -84,6 +86,18 @@ func NewHookExecutor(d executorDependencies, c *config.Provider) *HookExecutor {
 }
 
 func (e *HookExecutor) PostRegistrationHook(w http.ResponseWriter, r *http.Request, ct identity.CredentialsType, a *Flow, i *identity.Identity) error {
+	if !e.c.SelfServiceFlowRegistrationEnabled(i.SchemaID) {
+		return errors.WithStack(ErrRegistrationDisabled)
+	}
+
+	var fields identity.FieldSelection
+	if a.Type == flow.TypeAPI {
+		var err error
+		if fields, err = identity.ParseFieldSelection(r); err != nil {
+			return err
+		}
+	}
+
 	e.d.Logger().
 		WithRequest(r).
 		WithField("identity_id", i.ID).
@@ -129,8 +143,12 @@ func (e *HookExecutor) PostRegistrationHook(w http.ResponseWriter, r *http.Reque
 		WithRequest(r).
 		WithField("identity_id", i.ID).
 		Info("A new identity has registered using self-service registration.")
+	e.d.FlowStatsRecorder().Record(r.Context(), stats.FlowRegistration, a.ID, a.Type, ct.String(), stats.EventSucceeded)
 
-	s := session.NewActiveSession(i, e.c, time.Now().UTC())
+	s := session.NewActiveSession(i, e.c, e.d.Clock().Now())
+	s.FirstTimeLogin = true
+	s.JustRegistered = true
+	s.SetDevice(r, e.c)
 	e.d.Logger().
 		WithRequest(r).
 		WithField("identity_id", i.ID).
@@ -168,12 +186,28 @@ func (e *HookExecutor) PostRegistrationHook(w http.ResponseWriter, r *http.Reque
 		Debug("Post registration execution hooks completed successfully.")
 
 	if a.Type == flow.TypeAPI {
-		e.d.Writer().Write(w, r, &APIFlowResponse{Identity: i})
+		response, err := fields.Apply(&APIFlowResponse{Identity: i}, "identity")
+		if err != nil {
+			return err
+		}
+
+		e.d.Writer().Write(w, r, response)
 		return nil
 	}
 
+	opts, err := flow.ReturnToRedirectOptions(e.c, &flow.ReturnToContext{
+		Flow:       stats.FlowRegistration,
+		Method:     ct.String(),
+		SchemaID:   i.SchemaID,
+		RequestURL: a.RequestURL,
+		FirstLogin: s.FirstTimeLogin,
+	}, e.c.SelfServiceFlowRegistrationReturnTo(ct.String()))
+	if err != nil {
+		return err
+	}
+
 	return x.SecureContentNegotiationRedirection(w, r, s.Declassify(), a.RequestURL,
-		e.d.Writer(), e.c, x.SecureRedirectOverrideDefaultReturnTo(e.c.SelfServiceFlowRegistrationReturnTo(ct.String())))
+		e.d.Writer(), e.c, opts...)
 }
 
 func (e *HookExecutor) PreRegistrationHook(w http.ResponseWriter, r *http.Request, a *Flow) error {
@@ -36,7 +36,7 @@ func TestRegistrationExecutor(t *testing.T) {
 				router := httprouter.New()
 				handleErr := testhelpers.SelfServiceHookRegistrationErrorHandler
 				router.GET("/registration/pre", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-					if handleErr(t, w, r, reg.RegistrationHookExecutor().PreRegistrationHook(w, r, registration.NewFlow(time.Minute, x.FakeCSRFToken, r, ft))) {
+					if handleErr(t, w, r, reg.RegistrationHookExecutor().PreRegistrationHook(w, r, registration.NewFlow(time.Now(), time.Minute, x.FakeCSRFToken, r, ft))) {
 						_, _ = w.Write([]byte("ok"))
 					}
 				})
@@ -45,7 +45,7 @@ func TestRegistrationExecutor(t *testing.T) {
 					if i == nil {
 						i = testhelpers.SelfServiceHookFakeIdentity(t)
 					}
-					a := registration.NewFlow(time.Minute, x.FakeCSRFToken, r, ft)
+					a := registration.NewFlow(time.Now(), time.Minute, x.FakeCSRFToken, r, ft)
 					a.RequestURL = x.RequestURL(r).String()
 					_ = handleErr(t, w, r, reg.RegistrationHookExecutor().PostRegistrationHook(w, r, identity.CredentialsType(strategy), a, i))
 				})
@@ -95,6 +95,21 @@ func TestRegistrationExecutor(t *testing.T) {
 					require.Error(t, err)
 				})
 
+				t.Run("case=fail if registration is disabled", func(t *testing.T) {
+					t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
+					conf.MustSet(config.ViperKeySelfServiceRegistrationEnabled, false)
+					t.Cleanup(func() {
+						conf.MustSet(config.ViperKeySelfServiceRegistrationEnabled, true)
+					})
+					i := testhelpers.SelfServiceHookFakeIdentity(t)
+
+					res, _ := makeRequestPost(t, newServer(t, i, flow.TypeBrowser), false, url.Values{})
+					assert.EqualValues(t, http.StatusInternalServerError, res.StatusCode)
+
+					_, err := reg.IdentityPool().GetIdentity(context.Background(), i.ID)
+					require.Error(t, err)
+				})
+
 				t.Run("case=prevent return_to value because domain not whitelisted", func(t *testing.T) {
 					t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
 					i := testhelpers.SelfServiceHookFakeIdentity(t)
@@ -152,6 +167,16 @@ func TestRegistrationExecutor(t *testing.T) {
 					assert.EqualValues(t, http.StatusOK, res.StatusCode)
 					assert.NotEmpty(t, gjson.Get(body, "identity.id"))
 				})
+
+				t.Run("case=mark the session as first login of a new identity", func(t *testing.T) {
+					t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
+					viperSetPost(t, conf, strategy, []config.SelfServiceHook{{Name: "session"}})
+
+					res, body := makeRequestPost(t, newServer(t, nil, flow.TypeAPI), true, url.Values{})
+					assert.EqualValues(t, http.StatusOK, res.StatusCode)
+					assert.True(t, gjson.Get(body, "session.first_time_login").Bool(), "%s", body)
+					assert.True(t, gjson.Get(body, "session.just_registered").Bool(), "%s", body)
+				})
 			})
 
 			t.Run("type=browser/method=PreRegistrationHook", testhelpers.TestSelfServicePreHook(
@@ -0,0 +1,96 @@
+package flow
+
+import (
+	"net/url"
+	"regexp"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+// ReturnToContext describes a completed flow to the browser return rules.
+type ReturnToContext struct {
+	// Flow is the flow type, for example `registration`.
+	Flow string
+
+	// Method is the method used to complete the flow, for example `password`.
+	Method string
+
+	// SchemaID is the ID of the identity's traits schema.
+	SchemaID string
+
+	// RequestURL is the URL the flow was initialized with. It contains the requested `return_to`.
+	RequestURL string
+
+	// FirstLogin is true if the flow issued the first session of the identity.
+	FirstLogin bool
+}
+
+// MatchReturnToRule returns the first rule matching the completed flow or nil if no rule matches.
+func MatchReturnToRule(rules []config.SelfServiceReturnToRule, rc *ReturnToContext) (*config.SelfServiceReturnToRule, error) {
+	var requested string
+	if source, err := url.Parse(rc.RequestURL); err == nil {
+		requested = source.Query().Get("return_to")
+	}
+
+	for k := range rules {
+		rule := rules[k]
+		if (rule.Flow != "" && rule.Flow != rc.Flow) ||
+			(rule.Method != "" && rule.Method != rc.Method) ||
+			(rule.SchemaID != "" && rule.SchemaID != rc.SchemaID) {
+			continue
+		}
+
+		if rule.ReturnTo != "" {
+			matcher, err := regexp.Compile(rule.ReturnTo)
+			if err != nil {
+				return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The return_to expression of browser return rule %d is invalid: %s", k, err))
+			}
+			if !matcher.MatchString(requested) {
+				continue
+			}
+		}
+
+		if rule.FirstLogin != nil && *rule.FirstLogin != rc.FirstLogin {
+			continue
+		}
+
+		return &rule, nil
+	}
+
+	return nil, nil
+}
+
+// DependsOnFirstLogin returns true if a rule for the flow matches on whether the flow issued the first session of
+// the identity. Finding out requires counting the sessions of the identity, which is skipped otherwise.
+func DependsOnFirstLogin(rules []config.SelfServiceReturnToRule, flow string) bool {
+	for _, rule := range rules {
+		if rule.FirstLogin != nil && (rule.Flow == "" || rule.Flow == flow) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReturnToRedirectOptions returns the redirect options sending the browser to the destination of the first browser
+// return rule matching the completed flow. If no rule matches, the browser is sent to the requested `return_to` or to
+// defaultReturnTo.
+func ReturnToRedirectOptions(c *config.Provider, rc *ReturnToContext, defaultReturnTo *url.URL) ([]x.SecureRedirectOption, error) {
+	rule, err := MatchReturnToRule(c.SelfServiceBrowserReturnToRules(), rc)
+	if err != nil {
+		return nil, err
+	} else if rule == nil {
+		return []x.SecureRedirectOption{x.SecureRedirectOverrideDefaultReturnTo(defaultReturnTo)}, nil
+	}
+
+	to, err := url.ParseRequestURI(rule.To)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the destination of a browser return rule: %s", err))
+	}
+
+	return []x.SecureRedirectOption{x.SecureRedirectForceReturnTo(to)}, nil
+}
@@ -0,0 +1,57 @@
+package flow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+func TestMatchReturnToRule(t *testing.T) {
+	yes, no := true, false
+	rules := []config.SelfServiceReturnToRule{
+		{Flow: "registration", FirstLogin: &yes, To: "https://www.ory.sh/onboarding"},
+		{Flow: "login", Method: "oidc", SchemaID: "employee", To: "https://www.ory.sh/intranet"},
+		{Flow: "login", ReturnTo: "^https://www\\.ory\\.sh/docs/", To: "https://www.ory.sh/docs"},
+		{Flow: "login", FirstLogin: &no, ReturnTo: "^$", To: "https://www.ory.sh/welcome-back"},
+	}
+
+	for k, tc := range []struct {
+		rc       ReturnToContext
+		expected string
+	}{
+		{rc: ReturnToContext{Flow: "registration", Method: "password", FirstLogin: true}, expected: "https://www.ory.sh/onboarding"},
+		{rc: ReturnToContext{Flow: "registration", Method: "password"}},
+		{rc: ReturnToContext{Flow: "login", Method: "oidc", SchemaID: "employee"}, expected: "https://www.ory.sh/intranet"},
+		{rc: ReturnToContext{Flow: "login", Method: "password", SchemaID: "employee", RequestURL: "https://kratos/self-service/login/browser?return_to=https://www.ory.sh/docs/kratos"}, expected: "https://www.ory.sh/docs"},
+		{rc: ReturnToContext{Flow: "login", Method: "password", RequestURL: "https://kratos/self-service/login/browser"}, expected: "https://www.ory.sh/welcome-back"},
+		{rc: ReturnToContext{Flow: "login", Method: "password", RequestURL: "https://kratos/self-service/login/browser?return_to=https://www.ory.sh/"}},
+		{rc: ReturnToContext{Flow: "login", Method: "password", RequestURL: "https://kratos/self-service/login/browser", FirstLogin: true}},
+		{rc: ReturnToContext{Flow: "login", Method: "oidc", SchemaID: "employee", FirstLogin: true}, expected: "https://www.ory.sh/intranet"},
+		{rc: ReturnToContext{Flow: "settings", Method: "profile"}},
+	} {
+		rule, err := MatchReturnToRule(rules, &tc.rc)
+		require.NoError(t, err, "%d", k)
+		if tc.expected == "" {
+			assert.Nil(t, rule, "%d", k)
+			continue
+		}
+		require.NotNil(t, rule, "%d", k)
+		assert.Equal(t, tc.expected, rule.To, "%d", k)
+	}
+
+	t.Run("case=first login is only needed by rules which depend on it", func(t *testing.T) {
+		assert.True(t, DependsOnFirstLogin(rules, "login"))
+		assert.True(t, DependsOnFirstLogin(rules, "registration"))
+		assert.False(t, DependsOnFirstLogin(rules, "settings"))
+		assert.True(t, DependsOnFirstLogin([]config.SelfServiceReturnToRule{{FirstLogin: &no}}, "settings"))
+		assert.False(t, DependsOnFirstLogin(nil, "login"))
+	})
+
+	t.Run("case=invalid expressions fail", func(t *testing.T) {
+		_, err := MatchReturnToRule([]config.SelfServiceReturnToRule{{ReturnTo: "(", To: "https://www.ory.sh/"}}, &ReturnToContext{})
+		require.Error(t, err)
+	})
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
@@ -28,6 +29,7 @@ type (
 		errorx.ManagementProvider
 		x.WriterProvider
 		x.LoggingProvider
+		stats.RecorderProvider
 
 		HandlerProvider
 		FlowPersistenceProvider
@@ -141,6 +143,8 @@ func (s *ErrorHandler) WriteFlowError(
 		return
 	}
 
+	s.d.FlowStatsRecorder().Record(r.Context(), stats.FlowSettings, f.ID, f.Type, method, stats.EventFailed)
+
 	if err := f.Methods[method].Config.ParseError(err); err != nil {
 		s.forward(w, r, f, err)
 		return
@@ -170,13 +174,13 @@ func (s *ErrorHandler) forward(w http.ResponseWriter, r *http.Request, rr *Flow,
 			s.d.Writer().WriteError(w, r, err)
 			return
 		}
-		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		s.d.SelfServiceErrorManager().ForwardForFlow(r.Context(), w, r, stats.FlowSettings, err)
 		return
 	}
 
 	if rr.Type == flow.TypeAPI {
 		s.d.Writer().WriteErrorCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), err)
 	} else {
-		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		s.d.SelfServiceErrorManager().ForwardForFlow(r.Context(), w, r, stats.FlowSettings, err)
 	}
 }
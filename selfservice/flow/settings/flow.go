@@ -116,10 +116,15 @@ type APIFlowResponse struct {
 	//
 	// required: true
 	Identity *identity.Identity `json:"identity"`
+
+	// The Session Token
+	//
+	// Set if the session was replaced because the credentials of the identity changed. The session token sent with
+	// the request was revoked and this one must be used instead.
+	SessionToken string `json:"session_token,omitempty"`
 }
 
-func NewFlow(exp time.Duration, r *http.Request, i *identity.Identity, ft flow.Type) *Flow {
-	now := time.Now().UTC()
+func NewFlow(now time.Time, exp time.Duration, r *http.Request, i *identity.Identity, ft flow.Type) *Flow {
 	return &Flow{
 		ID:         x.NewUUID(),
 		ExpiresAt:  now.Add(exp),
@@ -145,8 +150,8 @@ func (r *Flow) AppendTo(settingsURL *url.URL) *url.URL {
 	return urlx.CopyWithQuery(settingsURL, url.Values{"flow": {r.ID.String()}})
 }
 
-func (r *Flow) Valid(s *session.Session) error {
-	if r.ExpiresAt.Before(time.Now().UTC()) {
+func (r *Flow) Valid(s *session.Session, now time.Time) error {
+	if r.ExpiresAt.Before(now) {
 		return errors.WithStack(NewFlowExpiredError(r.ExpiresAt))
 	}
 
@@ -39,7 +39,7 @@ func TestFakeFlow(t *testing.T) {
 func TestNewFlow(t *testing.T) {
 	id := &identity.Identity{ID: x.NewUUID()}
 	t.Run("case=0", func(t *testing.T) {
-		r := settings.NewFlow(0, &http.Request{URL: urlx.ParseOrPanic("/"),
+		r := settings.NewFlow(time.Now(), 0, &http.Request{URL: urlx.ParseOrPanic("/"),
 			Host: "ory.sh", TLS: &tls.ConnectionState{}}, id, flow.TypeBrowser)
 		assert.Equal(t, r.IssuedAt, r.ExpiresAt)
 		assert.Equal(t, flow.TypeBrowser, r.Type)
@@ -47,7 +47,7 @@ func TestNewFlow(t *testing.T) {
 	})
 
 	t.Run("case=1", func(t *testing.T) {
-		r := settings.NewFlow(0, &http.Request{
+		r := settings.NewFlow(time.Now(), 0, &http.Request{
 			URL:  urlx.ParseOrPanic("/?refresh=true"),
 			Host: "ory.sh"}, id, flow.TypeAPI)
 		assert.Equal(t, r.IssuedAt, r.ExpiresAt)
@@ -56,7 +56,7 @@ func TestNewFlow(t *testing.T) {
 	})
 
 	t.Run("case=2", func(t *testing.T) {
-		r := settings.NewFlow(0, &http.Request{
+		r := settings.NewFlow(time.Now(), 0, &http.Request{
 			URL:  urlx.ParseOrPanic("https://ory.sh/"),
 			Host: "ory.sh"}, id, flow.TypeBrowser)
 		assert.Equal(t, "https://ory.sh/", r.RequestURL)
@@ -72,7 +72,7 @@ func TestFlow(t *testing.T) {
 		expectErr bool
 	}{
 		{
-			r: settings.NewFlow(
+			r: settings.NewFlow(time.Now(), 
 				time.Hour,
 				&http.Request{URL: urlx.ParseOrPanic("http://foo/bar/baz"), Host: "foo"},
 				&identity.Identity{ID: alice},
@@ -81,7 +81,7 @@ func TestFlow(t *testing.T) {
 			s: &session.Session{Identity: &identity.Identity{ID: alice}},
 		},
 		{
-			r: settings.NewFlow(
+			r: settings.NewFlow(time.Now(), 
 				time.Hour,
 				&http.Request{URL: urlx.ParseOrPanic("http://foo/bar/baz"), Host: "foo"},
 				&identity.Identity{ID: alice},
@@ -91,7 +91,7 @@ func TestFlow(t *testing.T) {
 			expectErr: true,
 		},
 		{
-			r: settings.NewFlow(
+			r: settings.NewFlow(time.Now(), 
 				-time.Hour,
 				&http.Request{URL: urlx.ParseOrPanic("http://foo/bar/baz"), Host: "foo"},
 				&identity.Identity{ID: alice},
@@ -102,7 +102,7 @@ func TestFlow(t *testing.T) {
 		},
 	} {
 		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
-			err := tc.r.Valid(tc.s)
+			err := tc.r.Valid(tc.s, time.Now())
 			if tc.expectErr {
 				require.Error(t, err)
 				return
@@ -2,7 +2,7 @@ package settings
 
 import (
 	"net/http"
-	"time"
+	"net/url"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -14,6 +14,7 @@ import (
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 	"github.com/ory/nosurf"
@@ -25,6 +26,8 @@ const (
 	RouteInitAPIFlow     = "/self-service/settings/api"
 	RouteGetFlow         = "/self-service/settings/flows"
 
+	RouteWellKnownChangePassword = "/.well-known/change-password"
+
 	ContinuityPrefix = "ory_kratos_settings"
 )
 
@@ -55,6 +58,8 @@ type (
 
 		IdentityTraitsSchemas() schema.Schemas
 		x.CSRFProvider
+		stats.RecorderProvider
+		x.ClockProvider
 	}
 	HandlerProvider interface {
 		SettingsHandler() *Handler
@@ -74,10 +79,12 @@ func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 	h.d.CSRFHandler().IgnorePath(RouteInitAPIFlow)
 
 	redirect := session.RedirectOnUnauthenticated(h.c.SelfServiceFlowLoginUI().String())
-	public.GET(RouteInitBrowserFlow, h.d.SessionHandler().IsAuthenticated(h.initBrowserFlow, redirect))
+	public.GET(RouteInitBrowserFlow, x.NoPrefetchHandler(h.d.SessionHandler().IsAuthenticated(h.initBrowserFlow, redirect)))
 	public.GET(RouteInitAPIFlow, h.d.SessionHandler().IsAuthenticated(h.initApiFlow, nil))
 
 	public.GET(RouteGetFlow, h.d.SessionHandler().IsAuthenticated(h.fetchPublicFlow, OnUnauthenticated(h.c, h.d)))
+
+	public.GET(RouteWellKnownChangePassword, h.changePassword)
 }
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
@@ -85,8 +92,17 @@ func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 }
 
 func (h *Handler) NewFlow(w http.ResponseWriter, r *http.Request, i *identity.Identity, ft flow.Type) (*Flow, error) {
-	f := NewFlow(h.c.SelfServiceFlowSettingsFlowLifespan(), r, i, ft)
-	for _, strategy := range h.d.SettingsStrategies() {
+	return h.newFlow(w, r, i, ft, h.d.SettingsStrategies())
+}
+
+// newFlow creates a settings flow which only contains the methods of the given strategies.
+func (h *Handler) newFlow(w http.ResponseWriter, r *http.Request, i *identity.Identity, ft flow.Type, strategies Strategies) (*Flow, error) {
+	if i.IsQuarantined() {
+		return nil, errors.WithStack(identity.ErrQuarantined)
+	}
+
+	f := NewFlow(h.d.Clock().Now(), h.c.SelfServiceFlowSettingsFlowLifespan(), r, i, ft)
+	for _, strategy := range strategies {
 		if err := h.d.ContinuityManager().Abort(r.Context(), w, r, ContinuityKey(strategy.SettingsStrategyID())); err != nil {
 			return nil, err
 		}
@@ -100,6 +116,7 @@ func (h *Handler) NewFlow(w http.ResponseWriter, r *http.Request, i *identity.Id
 		return nil, err
 	}
 
+	h.d.FlowStatsRecorder().Record(r.Context(), stats.FlowSettings, f.ID, f.Type, "", stats.EventCreated)
 	return f, nil
 }
 
@@ -149,6 +166,16 @@ func (h *Handler) initApiFlow(w http.ResponseWriter, r *http.Request, _ httprout
 	h.d.Writer().Write(w, r, f)
 }
 
+// swagger:parameters initializeSelfServiceSettingsViaBrowserFlow
+// nolint:deadcode,unused
+type initializeSelfServiceSettingsViaBrowserFlowParameters struct {
+	// Method scopes the settings flow to a single method, for example `password`
+	// to only show the form for changing the password.
+	//
+	// in: query
+	Method string `json:"method"`
+}
+
 // swagger:route GET /self-service/settings/browser public initializeSelfServiceSettingsViaBrowserFlow
 //
 // Initialize Settings Flow for Browsers
@@ -157,6 +184,8 @@ func (h *Handler) initApiFlow(w http.ResponseWriter, r *http.Request, _ httprout
 // `selfservice.flows.settings.ui_url` with the flow ID set as the query parameter `?flow=`. If no valid
 // ORY Kratos Session Cookie is included in the request, a login flow will be initialized.
 //
+// If the `method` query parameter is set, the flow only contains the form of that method.
+//
 // :::note
 //
 // This endpoint is NOT INTENDED for API clients and only works with browsers (Chrome, Firefox, ...).
@@ -172,6 +201,7 @@ func (h *Handler) initApiFlow(w http.ResponseWriter, r *http.Request, _ httprout
 //
 //     Responses:
 //       302: emptyResponse
+//       400: genericError
 //       500: genericError
 func (h *Handler) initBrowserFlow(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	s, err := h.d.SessionManager().FetchFromRequest(r.Context(), r)
@@ -180,7 +210,17 @@ func (h *Handler) initBrowserFlow(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
-	f, err := h.NewFlow(w, r, s.Identity, flow.TypeBrowser)
+	strategies := h.d.SettingsStrategies()
+	if method := r.URL.Query().Get("method"); method != "" {
+		strategy, err := strategies.Strategy(method)
+		if err != nil {
+			h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The settings method %s is unknown or not enabled.", method)))
+			return
+		}
+		strategies = Strategies{strategy}
+	}
+
+	f, err := h.newFlow(w, r, s.Identity, flow.TypeBrowser, strategies)
 	if err != nil {
 		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
@@ -189,6 +229,33 @@ func (h *Handler) initBrowserFlow(w http.ResponseWriter, r *http.Request, ps htt
 	http.Redirect(w, r, f.AppendTo(h.c.SelfServiceFlowSettingsUI()).String(), http.StatusFound)
 }
 
+// swagger:route GET /.well-known/change-password public changePasswordWellKnown
+//
+// Redirect to the Change Password Form
+//
+// This endpoint implements the [Well-Known URL for Changing Passwords](https://w3c.github.io/webappsec-change-password-url/)
+// which lets browsers and password managers send users to the page for changing their password, for example after
+// warning about a breached password. It redirects to a browser-based settings flow which only contains the password
+// method.
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       302: emptyResponse
+//       404: genericError
+func (h *Handler) changePassword(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	method := identity.CredentialsTypePassword.String()
+	if _, err := h.d.SettingsStrategies().Strategy(method); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReasonf("Changing the password is not enabled.")))
+		return
+	}
+
+	http.Redirect(w, r, urlx.CopyWithQuery(
+		urlx.AppendPaths(h.c.SelfPublicURL(), RouteInitBrowserFlow),
+		url.Values{"method": {method}},
+	).String(), http.StatusFound)
+}
+
 // nolint:deadcode,unused
 // swagger:parameters getSelfServiceSettingsFlow
 type getSelfServiceSettingsFlowParameters struct {
@@ -270,7 +337,7 @@ func (h *Handler) fetchFlow(w http.ResponseWriter, r *http.Request, checkSession
 		}
 	}
 
-	if pr.ExpiresAt.Before(time.Now().UTC()) {
+	if pr.ExpiresAt.Before(h.d.Clock().Now()) {
 		if pr.Type == flow.TypeBrowser {
 			h.d.Writer().WriteError(w, r, errors.WithStack(x.ErrGone.
 				WithReason("The settings flow has expired. Redirect the user to the settings flow init endpoint to initialize a new settings flow.").
@@ -50,7 +50,7 @@ func TestHandler(t *testing.T) {
 	primaryUser, otherUser := clients["primary"], clients["secondary"]
 	publicClient, adminClient := testhelpers.NewSDKClient(publicTS), testhelpers.NewSDKClient(adminTS)
 	newExpiredFlow := func() *settings.Flow {
-		return settings.NewFlow(-time.Minute,
+		return settings.NewFlow(time.Now(), -time.Minute,
 			&http.Request{URL: urlx.ParseOrPanic(publicTS.URL + login.RouteInitBrowserFlow)},
 			primaryIdentity, flow.TypeBrowser)
 	}
@@ -142,6 +142,28 @@ func TestHandler(t *testing.T) {
 			})
 		})
 
+		t.Run("description=should redirect to a settings flow scoped to the password method", func(t *testing.T) {
+			res, err := primaryUser.Get(publicTS.URL + settings.RouteWellKnownChangePassword)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+
+			rid := res.Request.URL.Query().Get("flow")
+			require.NotEmpty(t, rid, "%s", res.Request.URL)
+
+			f, err := reg.SettingsFlowPersister().GetSettingsFlow(context.Background(), x.ParseUUID(rid))
+			require.NoError(t, err)
+			assert.Contains(t, f.Methods, identity.CredentialsTypePassword.String())
+			assert.NotContains(t, f.Methods, settings.StrategyProfile)
+		})
+
+		t.Run("description=should fail to scope the flow to an unknown method", func(t *testing.T) {
+			res, err := primaryUser.Get(publicTS.URL + settings.RouteInitBrowserFlow + "?method=not-a-method")
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+			assert.Empty(t, res.Request.URL.Query().Get("flow"))
+			assert.NotEmpty(t, res.Request.URL.Query().Get("error"), "%s", res.Request.URL)
+		})
+
 		t.Run("description=should fail to post data if CSRF is missing", func(t *testing.T) {
 			f := testhelpers.GetSettingsFlowMethodConfigDeprecated(t, primaryUser, publicTS, settings.StrategyProfile)
 			res, err := primaryUser.PostForm(pointerx.StringR(f.Action), url.Values{"foo": {"bar"}})
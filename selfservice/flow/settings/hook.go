@@ -3,7 +3,6 @@ package settings
 import (
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -11,6 +10,8 @@ import (
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/stats"
+	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
 
@@ -34,6 +35,9 @@ type (
 		x.LoggingProvider
 		FlowPersistenceProvider
 		x.WriterProvider
+		stats.RecorderProvider
+		x.ClockProvider
+		session.ManagementProvider
 	}
 	HookExecutor struct {
 		d executorDependencies
@@ -78,7 +82,8 @@ func NewHookExecutor(d executorDependencies, c *config.Provider) *HookExecutor {
 type PostSettingsHookOption func(o *postSettingsHookOptions)
 
 type postSettingsHookOptions struct {
-	cb func(ctxUpdate *UpdateContext) error
+	cb            func(ctxUpdate *UpdateContext) error
+	rotateSession bool
 }
 
 func WithCallback(cb func(ctxUpdate *UpdateContext) error) func(o *postSettingsHookOptions) {
@@ -87,6 +92,14 @@ func WithCallback(cb func(ctxUpdate *UpdateContext) error) func(o *postSettingsH
 	}
 }
 
+// WithSessionRotation replaces the session once the identity was updated. Strategies use it when the update changes
+// how the identity authenticates, for example its password.
+func WithSessionRotation() func(o *postSettingsHookOptions) {
+	return func(o *postSettingsHookOptions) {
+		o.rotateSession = true
+	}
+}
+
 func (e *HookExecutor) PostSettingsHook(w http.ResponseWriter, r *http.Request, settingsType string, ctxUpdate *UpdateContext, i *identity.Identity, opts ...PostSettingsHookOption) error {
 	e.d.Logger().
 		WithRequest(r).
@@ -122,9 +135,14 @@ func (e *HookExecutor) PostSettingsHook(w http.ResponseWriter, r *http.Request,
 
 	options := []identity.ManagerOption{identity.ManagerExposeValidationErrorsForInternalTypeAssertion}
 	ttl := e.c.SelfServiceFlowSettingsPrivilegedSessionMaxAge()
-	if ctxUpdate.Session.AuthenticatedAt.Add(ttl).After(time.Now()) {
+	if ctxUpdate.Session.AuthenticatedAt.Add(ttl).After(e.d.Clock().Now()) {
 		options = append(options, identity.ManagerAllowWriteProtectedTraits)
 	}
+	if !i.UpdatedAt.IsZero() {
+		// Changes made by others, for example using the admin API, while this request was processed must not be
+		// overwritten.
+		options = append(options, identity.ManagerExpectUpdatedAt(i.UpdatedAt))
+	}
 
 	if err := e.d.IdentityManager().Update(r.Context(), i, options...); err != nil {
 		if errors.Is(err, identity.ErrProtectedFieldModified) {
@@ -138,6 +156,25 @@ func (e *HookExecutor) PostSettingsHook(w http.ResponseWriter, r *http.Request,
 		WithField("identity_id", i.ID).
 		Debug("An identity's settings have been updated.")
 
+	var sessionToken string
+	if config.rotateSession {
+		rotated, err := e.d.SessionManager().RotateSession(r.Context(), w, r, ctxUpdate.Session)
+		if err != nil {
+			return err
+		}
+
+		if rotated.ID != ctxUpdate.Session.ID {
+			e.d.Audit().
+				WithRequest(r).
+				WithField("identity_id", i.ID).
+				WithField("session_id", ctxUpdate.Session.ID).
+				WithField("rotated_session_id", rotated.ID).
+				Info("The session was rotated because the credentials of the identity changed.")
+			sessionToken = rotated.Token
+		}
+		ctxUpdate.Session = rotated
+	}
+
 	ctxUpdate.Session.Identity = i
 	ctxUpdate.Flow.State = StateSuccess
 	if config.cb != nil {
@@ -154,6 +191,8 @@ func (e *HookExecutor) PostSettingsHook(w http.ResponseWriter, r *http.Request,
 		return err
 	}
 
+	e.d.FlowStatsRecorder().Record(r.Context(), stats.FlowSettings, ctxUpdate.Flow.ID, ctxUpdate.Flow.Type, settingsType, stats.EventSucceeded)
+
 	for k, executor := range e.d.PostSettingsPostPersistHooks(settingsType) {
 		if err := executor.ExecuteSettingsPostPersistHook(w, r, ctxUpdate.Flow, i); err != nil {
 			if errors.Is(err, ErrHookAbortRequest) {
@@ -191,12 +230,19 @@ func (e *HookExecutor) PostSettingsHook(w http.ResponseWriter, r *http.Request,
 			return err
 		}
 
-		e.d.Writer().Write(w, r, &APIFlowResponse{Flow: updatedFlow, Identity: i})
+		e.d.Writer().Write(w, r, &APIFlowResponse{Flow: updatedFlow, Identity: i, SessionToken: sessionToken})
 		return nil
 	}
 
-	return x.SecureContentNegotiationRedirection(w, r, ctxUpdate.Session.Declassify(), ctxUpdate.Flow.RequestURL, e.d.Writer(), e.c,
-		x.SecureRedirectOverrideDefaultReturnTo(
-			e.c.SelfServiceFlowSettingsReturnTo(settingsType,
-				ctxUpdate.Flow.AppendTo(e.c.SelfServiceFlowSettingsUI()))))
+	redirectOpts, err := flow.ReturnToRedirectOptions(e.c, &flow.ReturnToContext{
+		Flow:       stats.FlowSettings,
+		Method:     settingsType,
+		SchemaID:   i.SchemaID,
+		RequestURL: ctxUpdate.Flow.RequestURL,
+	}, e.c.SelfServiceFlowSettingsReturnTo(settingsType, ctxUpdate.Flow.AppendTo(e.c.SelfServiceFlowSettingsUI())))
+	if err != nil {
+		return err
+	}
+
+	return x.SecureContentNegotiationRedirection(w, r, ctxUpdate.Session.Declassify(), ctxUpdate.Flow.RequestURL, e.d.Writer(), e.c, redirectOpts...)
 }
@@ -48,6 +48,7 @@ func PrepareUpdate(d interface {
 	continuity.ManagementProvider
 	session.ManagementProvider
 	FlowPersistenceProvider
+	x.ClockProvider
 }, w http.ResponseWriter, r *http.Request, name string, payload UpdatePayload) (*UpdateContext, error) {
 	ss, err := d.SessionManager().FetchFromRequest(r.Context(), r)
 	if err != nil {
@@ -67,7 +68,7 @@ func PrepareUpdate(d interface {
 		return new(UpdateContext), err
 	}
 
-	if err := req.Valid(ss); err != nil {
+	if err := req.Valid(ss, d.Clock().Now()); err != nil {
 		return new(UpdateContext), err
 	}
 
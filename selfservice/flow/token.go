@@ -0,0 +1,50 @@
+package flow
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/randx"
+)
+
+// HeaderFlowToken is the HTTP Header API clients use to send the flow token
+// they received when initializing an API flow.
+const HeaderFlowToken = "X-Kratos-Flow-Token"
+
+var ErrFlowTokenMismatch = herodot.ErrForbidden.
+	WithError("flow token mismatch").
+	WithReasonf(`The HTTP Request Header "%s" is missing or does not match the flow token issued when the flow was initialized. API flows can only be completed by the client which initialized them.`, HeaderFlowToken)
+
+// NewFlowToken returns a new flow token and its hash. Only the hash is stored, the token
+// itself is returned to the client once when the flow is initialized.
+func NewFlowToken() (token, hash string) {
+	token = randx.MustString(32, randx.AlphaNum)
+	return token, HashFlowToken(token)
+}
+
+// HashFlowToken returns the hex encoded SHA-256 hash of the flow token.
+func HashFlowToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyFlowToken ensures that an API flow is completed by the client which initialized it by
+// comparing the flow token sent in the request with the hash stored in the flow. Browser flows
+// are bound using anti-CSRF tokens instead and are not checked.
+func VerifyFlowToken(r *http.Request, flowType Type, enforce bool, hash string) error {
+	if flowType != TypeAPI || !enforce {
+		return nil
+	}
+
+	token := r.Header.Get(HeaderFlowToken)
+	if token == "" || subtle.ConstantTimeCompare([]byte(HashFlowToken(token)), []byte(hash)) != 1 {
+		return errors.WithStack(ErrFlowTokenMismatch)
+	}
+
+	return nil
+}
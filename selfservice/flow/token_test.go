@@ -0,0 +1,26 @@
+package flow
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyFlowToken(t *testing.T) {
+	token, hash := NewFlowToken()
+	other, _ := NewFlowToken()
+	assert.NotEqual(t, token, hash)
+
+	withToken := func(token string) *http.Request {
+		return &http.Request{Header: http.Header{HeaderFlowToken: {token}}}
+	}
+
+	require.NoError(t, VerifyFlowToken(withToken(token), TypeAPI, true, hash))
+	require.EqualError(t, VerifyFlowToken(withToken(other), TypeAPI, true, hash), ErrFlowTokenMismatch.Error())
+	require.EqualError(t, VerifyFlowToken(&http.Request{Header: http.Header{}}, TypeAPI, true, hash), ErrFlowTokenMismatch.Error())
+	require.EqualError(t, VerifyFlowToken(withToken(token), TypeAPI, true, ""), ErrFlowTokenMismatch.Error())
+	require.NoError(t, VerifyFlowToken(&http.Request{Header: http.Header{}}, TypeAPI, false, hash))
+	require.NoError(t, VerifyFlowToken(&http.Request{Header: http.Header{}}, TypeBrowser, true, ""))
+}
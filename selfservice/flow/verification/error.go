@@ -15,6 +15,7 @@ import (
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
@@ -25,8 +26,10 @@ type (
 		x.WriterProvider
 		x.LoggingProvider
 		x.CSRFTokenGeneratorProvider
+		stats.RecorderProvider
 		FlowPersistenceProvider
 		StrategyProvider
+		x.ClockProvider
 	}
 
 	ErrorHandlerProvider interface {
@@ -79,7 +82,7 @@ func (s *ErrorHandler) WriteFlowError(
 
 	if e := new(FlowExpiredError); errors.As(err, &e) {
 		// create new flow because the old one is not valid
-		a, err := NewFlow(s.c.SelfServiceFlowVerificationRequestLifespan(), s.d.GenerateCSRFToken(r), r, s.d.VerificationStrategies(), f.Type)
+		a, err := NewFlow(s.d.Clock().Now(), s.c.SelfServiceFlowVerificationRequestLifespan(), s.d.GenerateCSRFToken(r), r, s.d.VerificationStrategies(), f.Type)
 		if err != nil {
 			// failed to create a new session and redirect to it, handle that error as a new one
 			s.WriteFlowError(w, r, methodName, f, err)
@@ -91,6 +94,7 @@ func (s *ErrorHandler) WriteFlowError(
 			s.forward(w, r, a, err)
 			return
 		}
+		s.d.FlowStatsRecorder().Record(r.Context(), stats.FlowVerification, a.ID, a.Type, "", stats.EventCreated)
 
 		if f.Type == flow.TypeAPI {
 			http.Redirect(w, r, urlx.CopyWithQuery(urlx.AppendPaths(s.c.SelfPublicURL(),
@@ -108,6 +112,8 @@ func (s *ErrorHandler) WriteFlowError(
 		return
 	}
 
+	s.d.FlowStatsRecorder().Record(r.Context(), stats.FlowVerification, f.ID, f.Type, methodName, stats.EventFailed)
+
 	if err := method.Config.ParseError(err); err != nil {
 		s.forward(w, r, f, err)
 		return
@@ -138,13 +144,13 @@ func (s *ErrorHandler) forward(w http.ResponseWriter, r *http.Request, rr *Flow,
 			s.d.Writer().WriteError(w, r, err)
 			return
 		}
-		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		s.d.SelfServiceErrorManager().ForwardForFlow(r.Context(), w, r, stats.FlowVerification, err)
 		return
 	}
 
 	if rr.Type == flow.TypeAPI {
 		s.d.Writer().WriteErrorCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), err)
 	} else {
-		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		s.d.SelfServiceErrorManager().ForwardForFlow(r.Context(), w, r, stats.FlowVerification, err)
 	}
 }
@@ -89,8 +89,7 @@ func (f Flow) TableName() string {
 	return "selfservice_verification_flows"
 }
 
-func NewFlow(exp time.Duration, csrf string, r *http.Request, strategies Strategies, ft flow.Type) (*Flow, error) {
-	now := time.Now().UTC()
+func NewFlow(now time.Time, exp time.Duration, csrf string, r *http.Request, strategies Strategies, ft flow.Type) (*Flow, error) {
 	f := &Flow{
 		ID:         x.NewUUID(),
 		ExpiresAt:  now.Add(exp),
@@ -111,8 +110,8 @@ func NewFlow(exp time.Duration, csrf string, r *http.Request, strategies Strateg
 	return f, nil
 }
 
-func (f *Flow) Valid() error {
-	if f.ExpiresAt.Before(time.Now()) {
+func (f *Flow) Valid(now time.Time) error {
+	if f.ExpiresAt.Before(now) {
 		return errors.WithStack(NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
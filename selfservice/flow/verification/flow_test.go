@@ -25,11 +25,11 @@ func TestFlow(t *testing.T) {
 		r         *Flow
 		expectErr bool
 	}{
-		{r: must(NewFlow(time.Hour, "", u, nil, flow.TypeBrowser))},
-		{r: must(NewFlow(-time.Hour, "", u, nil, flow.TypeBrowser)), expectErr: true},
+		{r: must(NewFlow(time.Now(), time.Hour, "", u, nil, flow.TypeBrowser))},
+		{r: must(NewFlow(time.Now(), -time.Hour, "", u, nil, flow.TypeBrowser)), expectErr: true},
 	} {
 		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
-			err := tc.r.Valid()
+			err := tc.r.Valid(time.Now())
 			if tc.expectErr {
 				require.Error(t, err)
 				return
@@ -40,5 +40,5 @@ func TestFlow(t *testing.T) {
 	}
 
 	assert.EqualValues(t, StateChooseMethod,
-		must(NewFlow(time.Hour, "", u, nil, flow.TypeBrowser)).State)
+		must(NewFlow(time.Now(), time.Hour, "", u, nil, flow.TypeBrowser)).State)
 }
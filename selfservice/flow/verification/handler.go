@@ -2,7 +2,6 @@ package verification
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -13,6 +12,7 @@ import (
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/x"
 )
 
@@ -37,6 +37,8 @@ type (
 		ErrorHandlerProvider
 		StrategyProvider
 		x.CSRFProvider
+		stats.RecorderProvider
+		x.ClockProvider
 	}
 	Handler struct {
 		d handlerDependencies
@@ -51,7 +53,7 @@ func NewHandler(d handlerDependencies, c *config.Provider) *Handler {
 func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 	h.d.CSRFHandler().IgnorePath(RouteInitAPIFlow)
 
-	public.GET(RouteInitBrowserFlow, h.initBrowserFlow)
+	public.GET(RouteInitBrowserFlow, x.NoPrefetchHandler(h.initBrowserFlow))
 	public.GET(RouteInitAPIFlow, h.initAPIFlow)
 	public.GET(RouteGetFlow, h.fetch)
 }
@@ -87,7 +89,7 @@ func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 //       500: genericError
 //       400: genericError
 func (h *Handler) initAPIFlow(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	req, err := NewFlow(h.c.SelfServiceFlowVerificationRequestLifespan(), h.d.GenerateCSRFToken(r), r, h.d.VerificationStrategies(), flow.TypeAPI)
+	req, err := NewFlow(h.d.Clock().Now(), h.c.SelfServiceFlowVerificationRequestLifespan(), h.d.GenerateCSRFToken(r), r, h.d.VerificationStrategies(), flow.TypeAPI)
 	if err != nil {
 		h.d.Writer().WriteError(w, r, err)
 		return
@@ -98,6 +100,8 @@ func (h *Handler) initAPIFlow(w http.ResponseWriter, r *http.Request, _ httprout
 		return
 	}
 
+	h.d.FlowStatsRecorder().Record(r.Context(), stats.FlowVerification, req.ID, req.Type, "", stats.EventCreated)
+
 	h.d.Writer().Write(w, r, req)
 }
 
@@ -118,7 +122,7 @@ func (h *Handler) initAPIFlow(w http.ResponseWriter, r *http.Request, _ httprout
 //       302: emptyResponse
 //       500: genericError
 func (h *Handler) initBrowserFlow(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	req, err := NewFlow(h.c.SelfServiceFlowVerificationRequestLifespan(), h.d.GenerateCSRFToken(r), r, h.d.VerificationStrategies(), flow.TypeBrowser)
+	req, err := NewFlow(h.d.Clock().Now(), h.c.SelfServiceFlowVerificationRequestLifespan(), h.d.GenerateCSRFToken(r), r, h.d.VerificationStrategies(), flow.TypeBrowser)
 	if err != nil {
 		h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
@@ -129,6 +133,8 @@ func (h *Handler) initBrowserFlow(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
+	h.d.FlowStatsRecorder().Record(r.Context(), stats.FlowVerification, req.ID, req.Type, "", stats.EventCreated)
+
 	http.Redirect(w, r, req.AppendTo(h.c.SelfServiceFlowVerificationUI()).String(), http.StatusFound)
 }
 
@@ -171,7 +177,7 @@ func (h *Handler) fetch(w http.ResponseWriter, r *http.Request, _ httprouter.Par
 		return
 	}
 
-	if req.ExpiresAt.Before(time.Now().UTC()) {
+	if req.ExpiresAt.Before(h.d.Clock().Now()) {
 		if req.Type == flow.TypeBrowser {
 			h.d.Writer().WriteError(w, r, errors.WithStack(x.ErrGone.
 				WithReason("The verification flow has expired. Redirect the user to the verification flow init endpoint to initialize a new verification flow.").
@@ -10,6 +10,7 @@ import (
 
 const (
 	StrategyVerificationLinkName = "link"
+	StrategyVerificationCodeName = "code"
 )
 
 type (
@@ -36,6 +36,11 @@ type ValueSetter interface {
 	SetValue(name string, value interface{})
 }
 
+type ValueGetter interface {
+	// Values returns the values entered into the form, excluding secrets such as passwords and the anti-CSRF token.
+	Values() map[string]interface{}
+}
+
 type MessageAdder interface {
 	// AddMessage adds a message to the form. A message can also be set for one or more fields if
 	// `setForFields` is set.
@@ -47,6 +52,11 @@ type CSRFSetter interface {
 	SetCSRF(string)
 }
 
+type UISetter interface {
+	// SetUI sets the hints for rendering the form.
+	SetUI(ui *UI)
+}
+
 type Resetter interface {
 	// Resets the form or field.
 	Reset(exclude ...string)
@@ -13,6 +13,17 @@ import (
 
 const DisableFormField = "disableFormField"
 
+// Values of the autocomplete attribute which help password managers and browsers to fill in forms, see
+// https://html.spec.whatwg.org/multipage/form-control-infrastructure.html#autofill
+const (
+	AutocompleteUsername        = "username"
+	AutocompleteEmail           = "email"
+	AutocompleteTel             = "tel"
+	AutocompleteCurrentPassword = "current-password"
+	AutocompleteNewPassword     = "new-password"
+	AutocompleteOneTimeCode     = "one-time-code"
+)
+
 // Fields contains multiple fields
 //
 // swagger:model formFields
@@ -54,8 +65,30 @@ type Field struct {
 	// Value is the equivalent of `<input value="{{.Value}}">`
 	Value interface{} `json:"value,omitempty" faker:"string"`
 
+	// Autocomplete is the equivalent of `<input autocomplete="{{.Autocomplete}}">`
+	Autocomplete string `json:"autocomplete,omitempty"`
+
 	// Messages contains a list of messages (e.g. validation errors) that affect this field.
 	Messages text.Messages `json:"messages,omitempty"`
+
+	// UI contains hints for rendering the field, for example the label of an OpenID Connect provider's
+	// submit button.
+	UI *UI `json:"ui,omitempty"`
+}
+
+// UI contains hints for rendering a login method or provider. They are configured server-side so that all user
+// interfaces render the login and registration screens the same way.
+//
+// swagger:model formUI
+type UI struct {
+	// Label is the label displayed to the user.
+	Label string `json:"label,omitempty"`
+
+	// Icon is a hint which icon to display, for example the name of an icon set entry or a URL.
+	Icon string `json:"icon,omitempty"`
+
+	// Order is the position of the method or provider. User interfaces should sort by order in ascending order.
+	Order int `json:"order"`
 }
 
 // Reset resets a field's value and errors.
@@ -128,6 +161,7 @@ func fieldFromPath(name string, p jsonschemax.Path) Field {
 		f.Type = "datetime-local"
 	case "email":
 		f.Type = "email"
+		f.Autocomplete = AutocompleteEmail
 	case "date":
 		f.Type = "date"
 	case "uri":
@@ -148,6 +182,11 @@ func fieldFromPath(name string, p jsonschemax.Path) Field {
 		}
 	}
 
+	// Password managers store the password identifier as the username
+	if isIdentifier, ok := p.CustomProperties[schema.PathPropertyPasswordIdentifier].(bool); ok && isIdentifier {
+		f.Autocomplete = AutocompleteUsername
+	}
+
 	return f
 }
 
@@ -29,6 +29,7 @@ func TestFieldFromPath(t *testing.T) {
 		for _, path := range paths {
 			htmlField := fieldFromPath(path.Name, path)
 			assert.Equal(t, gjson.GetBytes(schema, fmt.Sprintf("properties.%s.test_expected_type", path.Name)).String(), htmlField.Type)
+			assert.Equal(t, gjson.GetBytes(schema, fmt.Sprintf("properties.%s.test_expected_autocomplete", path.Name)).String(), htmlField.Autocomplete)
 			assert.True(t, !gjson.GetBytes(schema, fmt.Sprintf("properties.%s.test_expected_pattern", path.Name)).Exists() || (gjson.GetBytes(schema, fmt.Sprintf("properties.%s.test_expected_pattern", path.Name)).Bool() && htmlField.Pattern != ""))
 		}
 	})
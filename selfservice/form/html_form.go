@@ -28,6 +28,7 @@ var (
 	_       ValueSetter = new(HTMLForm)
 	_       Resetter    = new(HTMLForm)
 	_       CSRFSetter  = new(HTMLForm)
+	_       UISetter    = new(HTMLForm)
 )
 
 // HTMLForm represents a HTML Form. The container can work with both HTTP Form and JSON requests
@@ -53,6 +54,9 @@ type HTMLForm struct {
 
 	// Messages contains all global form messages and errors.
 	Messages text.Messages `json:"messages,omitempty"`
+
+	// UI contains hints for rendering the method this form belongs to.
+	UI *UI `json:"ui,omitempty"`
 }
 
 // NewHTMLForm returns an empty container.
@@ -213,6 +217,22 @@ func (c *HTMLForm) SetValuesFromJSON(raw json.RawMessage, prefix string) {
 	}
 }
 
+// Values returns the values of all fields except password fields and the anti-CSRF token.
+func (c *HTMLForm) Values() map[string]interface{} {
+	c.defaults()
+	c.Lock()
+	defer c.Unlock()
+
+	values := make(map[string]interface{}, len(c.Fields))
+	for _, f := range c.Fields {
+		if f.Name == CSRFTokenName || f.Type == "password" || f.Value == nil {
+			continue
+		}
+		values[f.Name] = f.Value
+	}
+	return values
+}
+
 // getField returns a pointer to the field with the given name.
 func (c *HTMLForm) getField(name string) *Field {
 	// to prevent blocks we don't use c.defaults() here
@@ -266,6 +286,13 @@ func (c *HTMLForm) SetCSRF(token string) {
 	})
 }
 
+// SetUI sets the hints for rendering the form.
+func (c *HTMLForm) SetUI(ui *UI) {
+	c.Lock()
+	defer c.Unlock()
+	c.UI = ui
+}
+
 // SetField sets a field.
 func (c *HTMLForm) SetField(field Field) {
 	c.defaults()
@@ -0,0 +1,344 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+var (
+	_ registration.PostHookPrePersistExecutor  = new(EmailDeliverability)
+	_ registration.PostHookPostPersistExecutor = new(EmailDeliverability)
+	_ Simulator                                = new(EmailDeliverability)
+)
+
+const (
+	EmailDeliverabilityBlock = "block"
+	EmailDeliverabilityMark  = "mark"
+
+	emailDeliverabilityDefaultTimeout = 10 * time.Second
+	emailDeliverabilityDefaultHELO    = "localhost"
+)
+
+type (
+	emailDeliverabilityDependencies interface {
+		x.LoggingProvider
+		identity.PrivilegedPoolProvider
+	}
+
+	// EmailDeliverabilityConfig configures how the email addresses of new identities are checked.
+	EmailDeliverabilityConfig struct {
+		// OnUndeliverable is either `block` or `mark`, defaults to `mark`.
+		OnUndeliverable string `json:"on_undeliverable"`
+
+		// Timeout limits the duration of all checks of an identity, defaults to ten seconds.
+		Timeout string `json:"timeout"`
+
+		// SMTPProbe enables asking the mail server whether it accepts the address if set.
+		SMTPProbe *struct {
+			HELO     string `json:"helo"`
+			MailFrom string `json:"mail_from"`
+		} `json:"smtp_probe"`
+
+		timeout time.Duration
+	}
+
+	// Resolver looks up the mail servers of a domain. It is implemented by net.Resolver.
+	Resolver interface {
+		LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+		LookupHost(ctx context.Context, host string) ([]string, error)
+	}
+
+	// EmailDeliverability checks whether the email addresses of new identities can receive mail. This cuts down on
+	// registrations with mistyped addresses.
+	EmailDeliverability struct {
+		r        emailDeliverabilityDependencies
+		config   json.RawMessage
+		resolver Resolver
+		smtpPort string
+	}
+
+	EmailDeliverabilityOption func(*EmailDeliverability)
+)
+
+// EmailDeliverabilityWithResolver replaces the system's DNS resolver.
+func EmailDeliverabilityWithResolver(resolver Resolver) EmailDeliverabilityOption {
+	return func(e *EmailDeliverability) {
+		e.resolver = resolver
+	}
+}
+
+// EmailDeliverabilityWithSMTPPort replaces port 25 for the SMTP probe.
+func EmailDeliverabilityWithSMTPPort(port string) EmailDeliverabilityOption {
+	return func(e *EmailDeliverability) {
+		e.smtpPort = port
+	}
+}
+
+func NewEmailDeliverability(r emailDeliverabilityDependencies, config json.RawMessage, opts ...EmailDeliverabilityOption) *EmailDeliverability {
+	e := &EmailDeliverability{r: r, config: config, resolver: net.DefaultResolver, smtpPort: "25"}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// ExecutePostRegistrationPrePersistHook fails the registration if an address is undeliverable and the hook is
+// configured to block such registrations.
+func (e *EmailDeliverability) ExecutePostRegistrationPrePersistHook(_ http.ResponseWriter, r *http.Request, _ *registration.Flow, i *identity.Identity) error {
+	c, err := e.decodeConfig()
+	if err != nil {
+		return err
+	}
+
+	if c.OnUndeliverable != EmailDeliverabilityBlock {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+	defer cancel()
+
+	for _, address := range uniqueEmailAddresses(i) {
+		if e.undeliverable(ctx, c, address) {
+			e.r.Audit().
+				WithRequest(r).
+				WithField("address", address).
+				Info("A registration was blocked because its email address can not receive mail.")
+			return schema.NewEmailUndeliverableError(address)
+		}
+	}
+	return nil
+}
+
+// ExecutePostRegistrationPostPersistHook checks the verifiable addresses of the new identity in the background if
+// the hook is configured to mark undeliverable addresses.
+func (e *EmailDeliverability) ExecutePostRegistrationPostPersistHook(_ http.ResponseWriter, r *http.Request, _ *registration.Flow, s *session.Session) error {
+	c, err := e.decodeConfig()
+	if err != nil {
+		return err
+	}
+
+	if c.OnUndeliverable == EmailDeliverabilityBlock || s.Identity == nil {
+		return nil
+	}
+
+	var addresses []identity.VerifiableAddress
+	for _, a := range s.Identity.VerifiableAddresses {
+		if a.Via == identity.VerifiableAddressTypeEmail {
+			addresses = append(addresses, a)
+		}
+	}
+
+	// The checks may take several seconds and must not delay the response.
+	go e.mark(c, s.IdentityID, addresses)
+	return nil
+}
+
+func (e *EmailDeliverability) mark(c *EmailDeliverabilityConfig, identityID uuid.UUID, addresses []identity.VerifiableAddress) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	for _, a := range addresses {
+		if !e.undeliverable(ctx, c, a.Value) {
+			continue
+		}
+
+		// The address is loaded again because it may have been verified in the meantime.
+		address, err := e.r.PrivilegedIdentityPool().FindVerifiableAddressByValue(ctx, a.Via, a.Value)
+		if err != nil {
+			e.r.Logger().WithError(err).WithField("identity_id", identityID).Error("Unable to load the undeliverable email address.")
+			continue
+		}
+		if address.Verified {
+			continue
+		}
+
+		address.Status = identity.VerifiableAddressStatusUndeliverable
+		if err := e.r.PrivilegedIdentityPool().UpdateVerifiableAddress(ctx, address); err != nil {
+			e.r.Logger().WithError(err).WithField("identity_id", identityID).Error("Unable to mark the email address as undeliverable.")
+			continue
+		}
+
+		e.r.Audit().
+			WithField("identity_id", identityID).
+			WithField("address", address.Value).
+			Info("An email address of a new identity was marked as undeliverable.")
+	}
+}
+
+// SimulateHook returns the undeliverable addresses of the identity.
+func (e *EmailDeliverability) SimulateHook(r *http.Request, i *identity.Identity) (*Simulation, error) {
+	c, err := e.decodeConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+	defer cancel()
+
+	undeliverable := []string{}
+	for _, address := range uniqueEmailAddresses(i) {
+		if e.undeliverable(ctx, c, address) {
+			undeliverable = append(undeliverable, address)
+		}
+	}
+
+	response, err := json.Marshal(map[string]interface{}{"on_undeliverable": c.OnUndeliverable, "undeliverable": undeliverable})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Simulation{Response: response}, nil
+}
+
+func (e *EmailDeliverability) decodeConfig() (*EmailDeliverabilityConfig, error) {
+	var c EmailDeliverabilityConfig
+	if len(e.config) > 0 {
+		if err := json.Unmarshal(e.config, &c); err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode the email deliverability hook configuration: %s", err))
+		}
+	}
+
+	if c.OnUndeliverable == "" {
+		c.OnUndeliverable = EmailDeliverabilityMark
+	}
+
+	c.timeout = emailDeliverabilityDefaultTimeout
+	if c.Timeout != "" {
+		var err error
+		if c.timeout, err = time.ParseDuration(c.Timeout); err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the email deliverability hook timeout: %s", err))
+		}
+	}
+	return &c, nil
+}
+
+// undeliverable returns true only if the address certainly can not receive mail. Lookups and probes which fail
+// for other reasons, for example because the DNS server or the mail server is unreachable, are inconclusive.
+func (e *EmailDeliverability) undeliverable(ctx context.Context, c *EmailDeliverabilityConfig, address string) bool {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(address[at+1:])
+
+	hosts, undeliverable := e.mailServers(ctx, domain)
+	if undeliverable || len(hosts) == 0 || c.SMTPProbe == nil {
+		return undeliverable
+	}
+
+	for _, host := range hosts {
+		rejected, err := e.probe(ctx, c, host, address)
+		if err == nil {
+			return rejected
+		}
+		e.r.Logger().WithError(err).WithField("mail_server", host).Debug("Unable to probe the mail server.")
+	}
+	return false
+}
+
+// mailServers returns the mail servers of the domain ordered by preference, or true if the domain can not
+// receive mail.
+func (e *EmailDeliverability) mailServers(ctx context.Context, domain string) ([]string, bool) {
+	mxs, err := e.resolver.LookupMX(ctx, domain)
+	if err == nil {
+		// A single record with host "." is a null MX record (RFC 7505) stating that the domain accepts no mail.
+		if len(mxs) == 1 && mxs[0].Host == "." {
+			return nil, true
+		}
+
+		hosts := make([]string, len(mxs))
+		for k, mx := range mxs {
+			hosts[k] = strings.TrimSuffix(mx.Host, ".")
+		}
+		return hosts, false
+	} else if !isNotFound(err) {
+		e.r.Logger().WithError(err).WithField("domain", domain).Debug("Unable to look up the mail servers of the domain.")
+		return nil, false
+	}
+
+	// Without MX records, mail is delivered to the domain's address records (RFC 5321 section 5.1).
+	if _, err := e.resolver.LookupHost(ctx, domain); isNotFound(err) {
+		return nil, true
+	} else if err != nil {
+		e.r.Logger().WithError(err).WithField("domain", domain).Debug("Unable to look up the addresses of the domain.")
+		return nil, false
+	}
+	return []string{domain}, false
+}
+
+// probe asks the mail server whether it accepts mail for the address without sending a message. It returns true
+// if the server rejected the address permanently.
+func (e *EmailDeliverability) probe(ctx context.Context, c *EmailDeliverabilityConfig, host, address string) (bool, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, e.smtpPort))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return false, errors.WithStack(err)
+		}
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer client.Close()
+
+	helo := c.SMTPProbe.HELO
+	if helo == "" {
+		helo = emailDeliverabilityDefaultHELO
+	}
+	if err := client.Hello(helo); err != nil {
+		return false, errors.WithStack(err)
+	}
+	if err := client.Mail(c.SMTPProbe.MailFrom); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	if err := client.Rcpt(address); err != nil {
+		var protoErr *textproto.Error
+		if errors.As(err, &protoErr) && protoErr.Code >= 500 && protoErr.Code < 600 {
+			return true, nil
+		}
+		return false, errors.WithStack(err)
+	}
+
+	_ = client.Quit()
+	return false, nil
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+func uniqueEmailAddresses(i *identity.Identity) (addresses []string) {
+	seen := map[string]bool{}
+	for _, a := range emailAddresses(i) {
+		if !seen[a] {
+			seen[a] = true
+			addresses = append(addresses, a)
+		}
+	}
+	return addresses
+}
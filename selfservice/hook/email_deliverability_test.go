@@ -0,0 +1,193 @@
+package hook_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/errorsx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/hook"
+	"github.com/ory/kratos/session"
+)
+
+type fakeResolver map[string][]*net.MX
+
+func (r fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	switch name {
+	case "unreachable-dns.com":
+		return nil, &net.DNSError{Err: "i/o timeout", Name: name, IsTimeout: true}
+	}
+	if mxs, ok := r[name]; ok {
+		return mxs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if host == "no-mx.com" {
+		return []string{"192.0.2.1"}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// newSMTPServer starts a mail server which rejects recipients whose local part is "unknown".
+func newSMTPServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				tp := textproto.NewConn(conn)
+				_ = tp.PrintfLine("220 localhost ESMTP")
+				for {
+					line, err := tp.ReadLine()
+					if err != nil {
+						return
+					}
+					switch cmd := strings.ToUpper(line); {
+					case strings.HasPrefix(cmd, "RCPT TO:<UNKNOWN@"):
+						_ = tp.PrintfLine("550 5.1.1 No such user")
+					case strings.HasPrefix(cmd, "QUIT"):
+						_ = tp.PrintfLine("221 Bye")
+						return
+					default:
+						_ = tp.PrintfLine("250 OK")
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+	return port
+}
+
+func TestEmailDeliverability(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/verify.schema.json")
+
+	resolver := fakeResolver{
+		"ory.sh":          {{Host: "127.0.0.1.", Pref: 10}},
+		"null-mx.com":     {{Host: ".", Pref: 0}},
+		"unreachable.com": {{Host: "192.0.2.1.", Pref: 10}},
+	}
+	port := newSMTPServer(t)
+
+	newHook := func(c string) *hook.EmailDeliverability {
+		return hook.NewEmailDeliverability(reg, json.RawMessage(c),
+			hook.EmailDeliverabilityWithResolver(resolver),
+			hook.EmailDeliverabilityWithSMTPPort(port))
+	}
+
+	newIdentity := func(emails ...string) *identity.Identity {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		for _, email := range emails {
+			i.VerifiableAddresses = append(i.VerifiableAddresses, *identity.NewVerifiableEmailAddress(email, i.ID))
+		}
+		return i
+	}
+
+	prePersist := func(h *hook.EmailDeliverability, i *identity.Identity) error {
+		return h.ExecutePostRegistrationPrePersistHook(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil), nil, i)
+	}
+
+	assertUndeliverable := func(t *testing.T, err error) {
+		require.Error(t, err)
+		_, ok := errorsx.Cause(err).(*schema.ValidationError)
+		assert.True(t, ok, "%+v", err)
+	}
+
+	t.Run("on_undeliverable=block", func(t *testing.T) {
+		h := newHook(`{"on_undeliverable":"block"}`)
+
+		for _, email := range []string{"foo@ory.sh", "foo@no-mx.com", "foo@unreachable-dns.com"} {
+			t.Run("case=allows "+email, func(t *testing.T) {
+				require.NoError(t, prePersist(h, newIdentity(email)))
+			})
+		}
+
+		for _, email := range []string{"foo@null-mx.com", "foo@ory-typo.sh"} {
+			t.Run("case=blocks "+email, func(t *testing.T) {
+				assertUndeliverable(t, prePersist(h, newIdentity("bar@ory.sh", email)))
+			})
+		}
+
+		t.Run("case=does not probe the mail server", func(t *testing.T) {
+			require.NoError(t, prePersist(h, newIdentity("unknown@ory.sh")))
+		})
+	})
+
+	t.Run("smtp_probe", func(t *testing.T) {
+		h := newHook(`{"on_undeliverable":"block","timeout":"2s","smtp_probe":{"mail_from":"postmaster@ory.sh"}}`)
+
+		t.Run("case=allows accepted recipients", func(t *testing.T) {
+			require.NoError(t, prePersist(h, newIdentity("foo@ory.sh")))
+		})
+
+		t.Run("case=blocks rejected recipients", func(t *testing.T) {
+			assertUndeliverable(t, prePersist(h, newIdentity("unknown@ory.sh")))
+		})
+
+		t.Run("case=allows recipients if the mail server is unreachable", func(t *testing.T) {
+			h := newHook(`{"on_undeliverable":"block","timeout":"100ms","smtp_probe":{"mail_from":"postmaster@ory.sh"}}`)
+			require.NoError(t, prePersist(h, newIdentity("unknown@unreachable.com")))
+		})
+	})
+
+	t.Run("on_undeliverable=mark", func(t *testing.T) {
+		h := newHook(`{}`)
+
+		i := newIdentity("foo@ory.sh", "foo@null-mx.com")
+		require.NoError(t, prePersist(h, i), "marking addresses must not block the registration")
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+		require.NoError(t, h.ExecutePostRegistrationPostPersistHook(httptest.NewRecorder(), new(http.Request), nil,
+			&session.Session{IdentityID: i.ID, Identity: i}))
+
+		status := func(email string) identity.VerifiableAddressStatus {
+			a, err := reg.IdentityPool().FindVerifiableAddressByValue(context.Background(), identity.VerifiableAddressTypeEmail, email)
+			require.NoError(t, err)
+			return a.Status
+		}
+
+		assert.Eventually(t, func() bool {
+			return status("foo@null-mx.com") == identity.VerifiableAddressStatusUndeliverable
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, identity.VerifiableAddressStatusPending, status("foo@ory.sh"))
+	})
+
+	t.Run("case=simulates the checks", func(t *testing.T) {
+		s, err := newHook(`{}`).SimulateHook(httptest.NewRequest("POST", "/", nil), newIdentity("foo@ory.sh", "foo@null-mx.com"))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"on_undeliverable":"mark","undeliverable":["foo@null-mx.com"]}`, string(s.Response))
+	})
+
+	t.Run("case=fails on invalid configuration", func(t *testing.T) {
+		err := prePersist(newHook(`{"on_undeliverable":"block","timeout":"soon"}`), newIdentity("foo@ory.sh"))
+		require.Error(t, err)
+		_, ok := errorsx.Cause(err).(*schema.ValidationError)
+		assert.False(t, ok)
+	})
+}
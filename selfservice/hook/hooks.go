@@ -1,6 +1,9 @@
 package hook
 
 const (
-	KeySessionIssuer    = "session"
-	KeySessionDestroyer = "revoke_active_sessions"
+	KeySessionIssuer       = "session"
+	KeySessionDestroyer    = "revoke_active_sessions"
+	KeyQuarantine          = "quarantine"
+	KeyKetoRelationTuples  = "keto_relation_tuples"
+	KeyEmailDeliverability = "email_deliverability"
 )
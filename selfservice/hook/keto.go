@@ -0,0 +1,198 @@
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/fetcher"
+	"github.com/ory/x/httpx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+var (
+	_ registration.PostHookPostPersistExecutor = new(KetoRelationTuples)
+	_ Simulator                                = new(KetoRelationTuples)
+)
+
+type (
+	ketoRelationTuplesDependencies interface {
+		x.LoggingProvider
+		Configuration() *config.Provider
+	}
+
+	// KetoRelationTuplesConfig configures where relation tuples are written and how they are created.
+	KetoRelationTuplesConfig struct {
+		// WriteURL is the base URL of ORY Keto's write API.
+		WriteURL string `json:"write_url"`
+
+		// MapperURL points to a Jsonnet file which receives the identity as `std.extVar('identity')` and the
+		// request which triggered the hook as `std.extVar('ctx')`, and returns the relation tuples to write in key
+		// `relation_tuples`.
+		MapperURL string `json:"mapper_url"`
+
+		// RequestHeaders lists the request headers passed to the Jsonnet file.
+		RequestHeaders []string `json:"request_headers"`
+	}
+
+	// RelationTuple is a relation tuple as expected by ORY Keto's write API.
+	RelationTuple struct {
+		Namespace string `json:"namespace"`
+		Object    string `json:"object"`
+		Relation  string `json:"relation"`
+		Subject   string `json:"subject"`
+	}
+
+	// KetoRelationTuples writes relation tuples of newly registered identities to ORY Keto. This gives new identities
+	// baseline permissions without requiring a custom service.
+	KetoRelationTuples struct {
+		r      ketoRelationTuplesDependencies
+		config json.RawMessage
+		f      *fetcher.Fetcher
+		client *http.Client
+	}
+)
+
+func NewKetoRelationTuples(r ketoRelationTuplesDependencies, config json.RawMessage) *KetoRelationTuples {
+	return &KetoRelationTuples{
+		r:      r,
+		config: config,
+		f:      fetcher.NewFetcher(),
+		client: httpx.NewResilientClientLatencyToleranceMedium(nil),
+	}
+}
+
+// ExecutePostRegistrationPostPersistHook writes the relation tuples. The identity exists at this point, which is why
+// failures are logged instead of failing the registration.
+func (e *KetoRelationTuples) ExecutePostRegistrationPostPersistHook(_ http.ResponseWriter, r *http.Request, _ *registration.Flow, s *session.Session) error {
+	if err := e.execute(r, s); err != nil {
+		e.r.Logger().
+			WithRequest(r).
+			WithError(err).
+			WithField("identity_id", s.IdentityID).
+			Error("Unable to write the relation tuples of a new identity to ORY Keto.")
+	}
+	return nil
+}
+
+// SimulateHook renders the relation tuples without writing them to ORY Keto.
+func (e *KetoRelationTuples) SimulateHook(r *http.Request, i *identity.Identity) (*Simulation, error) {
+	c, err := e.decodeConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	tuples, err := e.relationTuples(r, c, i)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(tuples)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Simulation{Payload: payload}, nil
+}
+
+func (e *KetoRelationTuples) decodeConfig() (*KetoRelationTuplesConfig, error) {
+	var c KetoRelationTuplesConfig
+	if err := json.Unmarshal(e.config, &c); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode the keto relation tuples hook configuration: %s", err))
+	}
+	return &c, nil
+}
+
+func (e *KetoRelationTuples) execute(r *http.Request, s *session.Session) error {
+	c, err := e.decodeConfig()
+	if err != nil {
+		return err
+	}
+
+	tuples, err := e.relationTuples(r, c, s.Identity)
+	if err != nil {
+		return err
+	}
+	return e.write(r, c, tuples)
+}
+
+func (e *KetoRelationTuples) relationTuples(r *http.Request, c *KetoRelationTuplesConfig, i *identity.Identity) ([]RelationTuple, error) {
+	jn, err := e.f.Fetch(c.MapperURL)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := json.Marshal(map[string]interface{}{
+		"id":     i.ID,
+		"traits": json.RawMessage(i.Traits),
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ctx, err := json.Marshal(NewRequestContext(r, c.RequestHeaders, e.r.Configuration().SessionLocationHeader()))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtCode("identity", string(input))
+	vm.ExtCode("ctx", string(ctx))
+	evaluated, err := vm.EvaluateSnippet(c.MapperURL, jn.String())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var out struct {
+		RelationTuples []RelationTuple `json:"relation_tuples"`
+	}
+	if err := json.Unmarshal([]byte(evaluated), &out); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The keto relation tuples Jsonnet mapper did not return a list of relation tuples in key relation_tuples: %s", err))
+	}
+
+	return out.RelationTuples, nil
+}
+
+func (e *KetoRelationTuples) write(r *http.Request, c *KetoRelationTuplesConfig, tuples []RelationTuple) error {
+	base, err := url.ParseRequestURI(c.WriteURL)
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the write URL of ORY Keto: %s", err))
+	}
+
+	for _, tuple := range tuples {
+		var body bytes.Buffer
+		if err := json.NewEncoder(&body).Encode(&tuple); err != nil {
+			return errors.WithStack(err)
+		}
+
+		req, err := http.NewRequestWithContext(r.Context(), "PUT", urlx.AppendPaths(base, "/relation-tuples").String(), &body)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := e.client.Do(req)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		payload, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+			return errors.Errorf("ORY Keto responded with status code %d: %s", res.StatusCode, payload)
+		}
+	}
+	return nil
+}
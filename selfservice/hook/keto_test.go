@@ -0,0 +1,74 @@
+package hook_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/hook"
+	"github.com/ory/kratos/session"
+)
+
+func TestKetoRelationTuples(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	var written []hook.RelationTuple
+	router := httprouter.New()
+	router.PUT("/relation-tuples", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		var tuple hook.RelationTuple
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&tuple))
+		written = append(written, tuple)
+		w.WriteHeader(http.StatusCreated)
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	i.Traits = identity.Traits(`{"email":"keto@ory.sh","department":"engineering"}`)
+	s := &session.Session{Identity: i, IdentityID: i.ID}
+
+	run := func(t *testing.T, config string) {
+		h := hook.NewKetoRelationTuples(reg, json.RawMessage(config))
+		require.NoError(t, h.ExecutePostRegistrationPostPersistHook(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil), nil, s))
+	}
+
+	t.Run("case=writes the relation tuples", func(t *testing.T) {
+		written = nil
+		run(t, fmt.Sprintf(`{"write_url":"%s","mapper_url":"file://./stub/keto.jsonnet"}`, ts.URL))
+		assert.Equal(t, []hook.RelationTuple{{Namespace: "groups", Object: "engineering", Relation: "member", Subject: i.ID.String()}}, written)
+	})
+
+	t.Run("case=passes the request context to the mapper", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySessionLocationHeader, "CF-IPCountry")
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySessionLocationHeader, "")
+		})
+
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("CF-IPCountry", "DE")
+		r.Header.Set("X-Device-Fingerprint", "fp-1234")
+
+		written = nil
+		h := hook.NewKetoRelationTuples(reg, json.RawMessage(fmt.Sprintf(`{"write_url":"%s","mapper_url":"file://./stub/keto_ctx.jsonnet","request_headers":["x-device-fingerprint"]}`, ts.URL)))
+		require.NoError(t, h.ExecutePostRegistrationPostPersistHook(httptest.NewRecorder(), r, nil, s))
+		assert.Equal(t, []hook.RelationTuple{
+			{Namespace: "countries", Object: "DE", Relation: "member", Subject: i.ID.String()},
+			{Namespace: "devices", Object: "fp-1234", Relation: "owner", Subject: i.ID.String()},
+		}, written)
+	})
+
+	t.Run("case=does not fail the registration if keto is unavailable", func(t *testing.T) {
+		written = nil
+		run(t, `{"write_url":"http://127.0.0.1:1/","mapper_url":"file://./stub/keto.jsonnet"}`)
+		assert.Empty(t, written)
+	})
+}
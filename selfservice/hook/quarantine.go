@@ -0,0 +1,269 @@
+package hook
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/x"
+)
+
+var (
+	_ registration.PostHookPrePersistExecutor = new(Quarantine)
+	_ Simulator                               = new(Quarantine)
+)
+
+const (
+	QuarantineReasonVelocity              = "registration_velocity"
+	QuarantineReasonBlockedNetwork        = "blocked_network"
+	QuarantineReasonDisposableEmailDomain = "disposable_email_domain"
+	QuarantineReasonBlockedDomainNearMiss = "blocked_domain_near_miss"
+	QuarantineReasonBotScore              = "bot_score"
+
+	quarantineDefaultVelocityWindow = time.Hour
+
+	// Short domains are not checked for near misses as they are too likely to be a legitimate, different domain.
+	quarantineNearMissMinimumLength = 6
+)
+
+type (
+	quarantineDependencies interface {
+		x.LoggingProvider
+	}
+
+	// QuarantineConfig configures the heuristics used to quarantine new identities.
+	QuarantineConfig struct {
+		Velocity struct {
+			// MaxRegistrations is the number of registrations allowed per IP address within the window. Further
+			// registrations are quarantined. Zero disables the check.
+			MaxRegistrations int `json:"max_registrations"`
+
+			// Window is the time window, defaults to one hour.
+			Window string `json:"window"`
+		} `json:"velocity"`
+
+		// BlockedDomains are email domains (e.g. of disposable email providers) whose addresses, and addresses of
+		// domains looking almost the same, are quarantined.
+		BlockedDomains []string `json:"blocked_domains"`
+
+		// BlockedNetworks are CIDR ranges whose registrations are quarantined.
+		BlockedNetworks []string `json:"blocked_networks"`
+
+		BotScore struct {
+			// Min is the bot score registrations must at least have. Registrations without a bot score are not
+			// quarantined. Zero disables the check.
+			Min int `json:"min"`
+		} `json:"bot_score"`
+	}
+
+	// Quarantine quarantines identities registered under suspicious conditions.
+	Quarantine struct {
+		r        quarantineDependencies
+		config   json.RawMessage
+		velocity *RegistrationVelocity
+	}
+
+	// RegistrationVelocity counts registrations per IP address. It is shared by all quarantine hooks.
+	RegistrationVelocity struct {
+		sync.Mutex
+		registrations map[string][]time.Time
+	}
+)
+
+func NewRegistrationVelocity() *RegistrationVelocity {
+	return &RegistrationVelocity{registrations: map[string][]time.Time{}}
+}
+
+// track records a registration from ip and returns the number of registrations within the window.
+func (v *RegistrationVelocity) track(ip string, now time.Time, window time.Duration) int {
+	v.Lock()
+	defer v.Unlock()
+
+	for key, registrations := range v.registrations {
+		var kept []time.Time
+		for _, at := range registrations {
+			if now.Sub(at) < window {
+				kept = append(kept, at)
+			}
+		}
+		if len(kept) == 0 {
+			delete(v.registrations, key)
+		} else {
+			v.registrations[key] = kept
+		}
+	}
+
+	v.registrations[ip] = append(v.registrations[ip], now)
+	return len(v.registrations[ip])
+}
+
+func NewQuarantine(r quarantineDependencies, velocity *RegistrationVelocity, config json.RawMessage) *Quarantine {
+	return &Quarantine{r: r, velocity: velocity, config: config}
+}
+
+func (e *Quarantine) ExecutePostRegistrationPrePersistHook(_ http.ResponseWriter, r *http.Request, _ *registration.Flow, i *identity.Identity) error {
+	c, err := e.decodeConfig()
+	if err != nil {
+		return err
+	}
+
+	reason, err := e.reason(c, r, i)
+	if err != nil {
+		return err
+	}
+
+	if reason != "" {
+		i.Quarantine(reason)
+		e.r.Audit().
+			WithRequest(r).
+			WithField("identity_id", i.ID).
+			WithField("quarantine_reason", reason).
+			Info("A new identity was quarantined because it was registered under suspicious conditions.")
+	}
+	return nil
+}
+
+// SimulateHook returns the reason the identity would be quarantined for. The registration velocity is not
+// simulated because doing so would count the simulated registration.
+func (e *Quarantine) SimulateHook(r *http.Request, i *identity.Identity) (*Simulation, error) {
+	c, err := e.decodeConfig()
+	if err != nil {
+		return nil, err
+	}
+	c.Velocity.MaxRegistrations = 0
+
+	reason, err := e.reason(c, r, i)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := json.Marshal(map[string]interface{}{"quarantined": reason != "", "quarantine_reason": reason})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Simulation{Response: response}, nil
+}
+
+func (e *Quarantine) decodeConfig() (*QuarantineConfig, error) {
+	var c QuarantineConfig
+	if len(e.config) > 0 {
+		if err := json.Unmarshal(e.config, &c); err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode the quarantine hook configuration: %s", err))
+		}
+	}
+	return &c, nil
+}
+
+func (e *Quarantine) reason(c *QuarantineConfig, r *http.Request, i *identity.Identity) (string, error) {
+	ip := x.ClientIP(r)
+
+	if c.Velocity.MaxRegistrations > 0 {
+		window := quarantineDefaultVelocityWindow
+		if c.Velocity.Window != "" {
+			var err error
+			if window, err = time.ParseDuration(c.Velocity.Window); err != nil {
+				return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the quarantine hook velocity window: %s", err))
+			}
+		}
+
+		if e.velocity.track(ip, time.Now(), window) > c.Velocity.MaxRegistrations {
+			return QuarantineReasonVelocity, nil
+		}
+	}
+
+	if score, ok := x.BotScore(r); ok && score < c.BotScore.Min {
+		return QuarantineReasonBotScore, nil
+	}
+
+	if parsed := net.ParseIP(ip); parsed != nil {
+		for _, network := range c.BlockedNetworks {
+			_, cidr, err := net.ParseCIDR(network)
+			if err != nil {
+				return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse blocked network %s of the quarantine hook: %s", network, err))
+			}
+			if cidr.Contains(parsed) {
+				return QuarantineReasonBlockedNetwork, nil
+			}
+		}
+	}
+
+	for _, domain := range emailDomains(i) {
+		for _, blocked := range c.BlockedDomains {
+			blocked = strings.ToLower(blocked)
+			if domain == blocked || strings.HasSuffix(domain, "."+blocked) {
+				return QuarantineReasonDisposableEmailDomain, nil
+			}
+			if len(domain) >= quarantineNearMissMinimumLength && levenshtein(domain, blocked) == 1 {
+				return QuarantineReasonBlockedDomainNearMiss, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// emailAddresses returns the verifiable and recovery email addresses of the identity.
+func emailAddresses(i *identity.Identity) (addresses []string) {
+	for _, a := range i.VerifiableAddresses {
+		if a.Via == identity.VerifiableAddressTypeEmail {
+			addresses = append(addresses, a.Value)
+		}
+	}
+	for _, a := range i.RecoveryAddresses {
+		if a.Via == identity.RecoveryAddressTypeEmail {
+			addresses = append(addresses, a.Value)
+		}
+	}
+	return addresses
+}
+
+func emailDomains(i *identity.Identity) (domains []string) {
+	for _, a := range emailAddresses(i) {
+		if at := strings.LastIndex(a, "@"); at >= 0 {
+			domains = append(domains, strings.ToLower(a[at+1:]))
+		}
+	}
+	return domains
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	for k := range previous {
+		previous[k] = k
+	}
+
+	for ka := range ra {
+		current := make([]int, len(rb)+1)
+		current[0] = ka + 1
+		for kb := range rb {
+			cost := 1
+			if ra[ka] == rb[kb] {
+				cost = 0
+			}
+			current[kb+1] = minInt(previous[kb+1]+1, current[kb]+1, previous[kb]+cost)
+		}
+		previous = current
+	}
+	return previous[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
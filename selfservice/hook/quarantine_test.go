@@ -0,0 +1,100 @@
+package hook_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/hook"
+)
+
+func TestQuarantine(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	newIdentity := func(email string) *identity.Identity {
+		i := identity.NewIdentity("")
+		i.VerifiableAddresses = []identity.VerifiableAddress{{Value: email, Via: identity.VerifiableAddressTypeEmail}}
+		return i
+	}
+
+	run := func(t *testing.T, h *hook.Quarantine, ip string, i *identity.Identity) {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.RemoteAddr = ip + ":1234"
+		require.NoError(t, h.ExecutePostRegistrationPrePersistHook(httptest.NewRecorder(), r, nil, i))
+	}
+
+	t.Run("case=does not quarantine without suspicious conditions", func(t *testing.T) {
+		h := hook.NewQuarantine(reg, hook.NewRegistrationVelocity(), []byte(`{"velocity":{"max_registrations":1},"blocked_domains":["mailinator.com"],"blocked_networks":["192.0.2.0/24"]}`))
+		i := newIdentity("foo@ory.sh")
+		run(t, h, "198.51.100.1", i)
+		assert.False(t, i.IsQuarantined())
+	})
+
+	for _, tc := range []struct {
+		d      string
+		config string
+		email  string
+		reason string
+	}{
+		{d: "blocked domain", config: `{"blocked_domains":["mailinator.com"]}`, email: "foo@mailinator.com", reason: hook.QuarantineReasonDisposableEmailDomain},
+		{d: "blocked subdomain", config: `{"blocked_domains":["mailinator.com"]}`, email: "foo@eu.Mailinator.com", reason: hook.QuarantineReasonDisposableEmailDomain},
+		{d: "near miss", config: `{"blocked_domains":["mailinator.com"]}`, email: "foo@mailinat0r.com", reason: hook.QuarantineReasonBlockedDomainNearMiss},
+		{d: "blocked network", config: `{"blocked_networks":["198.51.100.0/24"]}`, email: "foo@ory.sh", reason: hook.QuarantineReasonBlockedNetwork},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			h := hook.NewQuarantine(reg, hook.NewRegistrationVelocity(), []byte(tc.config))
+			i := newIdentity(tc.email)
+			run(t, h, "198.51.100.1", i)
+			assert.True(t, i.IsQuarantined())
+			assert.Equal(t, tc.reason, i.QuarantineReason)
+		})
+	}
+
+	t.Run("case=quarantines registrations exceeding the velocity", func(t *testing.T) {
+		velocity := hook.NewRegistrationVelocity()
+		h := hook.NewQuarantine(reg, velocity, []byte(`{"velocity":{"max_registrations":2,"window":"1h"}}`))
+
+		for k := 0; k < 2; k++ {
+			i := newIdentity("foo@ory.sh")
+			run(t, h, "198.51.100.1", i)
+			assert.False(t, i.IsQuarantined())
+		}
+
+		i := newIdentity("foo@ory.sh")
+		run(t, h, "198.51.100.2", i)
+		assert.False(t, i.IsQuarantined(), "other addresses are counted separately")
+
+		i = newIdentity("foo@ory.sh")
+		run(t, hook.NewQuarantine(reg, velocity, []byte(`{"velocity":{"max_registrations":2,"window":"1h"}}`)), "198.51.100.1", i)
+		assert.True(t, i.IsQuarantined())
+		assert.Equal(t, hook.QuarantineReasonVelocity, i.QuarantineReason)
+	})
+
+	t.Run("case=quarantines registrations with a low bot score", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyBotScoreHeader, "CF-Bot-Score")
+		h := hook.NewQuarantine(reg, hook.NewRegistrationVelocity(), []byte(`{"bot_score":{"min":30}}`))
+
+		for score, quarantined := range map[string]bool{"12": true, "30": false, "": false} {
+			r := httptest.NewRequest("POST", "/", nil)
+			r.Header.Set("CF-Bot-Score", score)
+			i := newIdentity("foo@ory.sh")
+			reg.BotScoreMiddleware().ServeHTTP(httptest.NewRecorder(), r, func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, h.ExecutePostRegistrationPrePersistHook(w, r, nil, i))
+			})
+			assert.Equal(t, quarantined, i.IsQuarantined(), "%s", score)
+		}
+	})
+
+	t.Run("case=fails on invalid configuration", func(t *testing.T) {
+		h := hook.NewQuarantine(reg, hook.NewRegistrationVelocity(), []byte(`{"blocked_networks":["not-a-network"]}`))
+		r := httptest.NewRequest("POST", "/", nil)
+		r.RemoteAddr = "198.51.100.1:1234"
+		require.Error(t, h.ExecutePostRegistrationPrePersistHook(httptest.NewRecorder(), r, nil, newIdentity("foo@ory.sh")))
+	})
+}
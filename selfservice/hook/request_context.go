@@ -0,0 +1,70 @@
+package hook
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ory/kratos/x"
+)
+
+// sensitiveHeaders are never passed to Jsonnet templates, even if allowed, because they carry credentials.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "X-Session-Token"}
+
+// RequestContext describes the HTTP request which triggered a hook. Hooks calling other systems pass it to their
+// Jsonnet templates as `std.extVar('ctx')`, so that for example fraud detection systems receive the signals of the
+// client without looking them up again.
+type RequestContext struct {
+	// IPAddress is the IP address of the client.
+	IPAddress string `json:"ip_address"`
+
+	// UserAgent is the user agent of the client.
+	UserAgent string `json:"user_agent"`
+
+	// Location is the location of the client as reported by the header set in `session.location_header`.
+	Location string `json:"location,omitempty"`
+
+	// BotScore is the bot score of the request if the bot score header is configured and trusted.
+	BotScore *int `json:"bot_score,omitempty"`
+
+	// Headers contains the allowed request headers which were sent, using their canonical names as keys.
+	// Multiple values of a header are joined by a comma.
+	Headers map[string]string `json:"headers"`
+}
+
+// NewRequestContext describes the request including the allowed headers.
+func NewRequestContext(r *http.Request, allowedHeaders []string, locationHeader string) *RequestContext {
+	c := &RequestContext{
+		IPAddress: x.ClientIP(r),
+		UserAgent: r.UserAgent(),
+		Headers:   map[string]string{},
+	}
+
+	if locationHeader != "" {
+		c.Location = r.Header.Get(locationHeader)
+	}
+
+	if score, ok := x.BotScore(r); ok {
+		c.BotScore = &score
+	}
+
+	for _, name := range allowedHeaders {
+		name = http.CanonicalHeaderKey(name)
+		if isSensitiveHeader(name) {
+			continue
+		}
+		if values := r.Header.Values(name); len(values) > 0 {
+			c.Headers[name] = strings.Join(values, ", ")
+		}
+	}
+
+	return c
+}
+
+func isSensitiveHeader(name string) bool {
+	for _, h := range sensitiveHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package hook_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/kratos/selfservice/hook"
+)
+
+func TestNewRequestContext(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", nil)
+	r.RemoteAddr = "203.0.113.1:1234"
+	r.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh)")
+	r.Header.Set("CF-IPCountry", "DE")
+	r.Header.Add("Accept-Language", "de")
+	r.Header.Add("Accept-Language", "en")
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("Cookie", "ory_kratos_session=secret")
+	r.Header.Set("X-Unlisted", "unlisted")
+
+	c := hook.NewRequestContext(r, []string{"accept-language", "X-Missing", "Authorization", "cookie"}, "CF-IPCountry")
+	assert.Equal(t, "203.0.113.1", c.IPAddress)
+	assert.Equal(t, "Mozilla/5.0 (Macintosh)", c.UserAgent)
+	assert.Equal(t, "DE", c.Location)
+	assert.Nil(t, c.BotScore)
+	assert.Equal(t, map[string]string{"Accept-Language": "de, en"}, c.Headers, "only allowed headers without credentials are passed")
+
+	c = hook.NewRequestContext(r, nil, "")
+	assert.Empty(t, c.Location)
+	assert.Empty(t, c.Headers)
+}
@@ -2,10 +2,10 @@ package hook
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/session"
@@ -21,6 +21,7 @@ type (
 		session.ManagementProvider
 		session.PersistenceProvider
 		x.WriterProvider
+		x.ClockProvider
 	}
 	SessionIssuerProvider interface {
 		HookSessionIssuer() *SessionIssuer
@@ -35,16 +36,27 @@ func NewSessionIssuer(r sessionIssuerDependencies) *SessionIssuer {
 }
 
 func (e *SessionIssuer) ExecutePostRegistrationPostPersistHook(w http.ResponseWriter, r *http.Request, a *registration.Flow, s *session.Session) error {
-	s.AuthenticatedAt = time.Now().UTC()
+	s.AuthenticatedAt = e.r.Clock().Now()
 	if err := e.r.SessionPersister().CreateSession(r.Context(), s); err != nil {
 		return err
 	}
 
 	if a.Type == flow.TypeAPI {
-		e.r.Writer().Write(w, r, &registration.APIFlowResponse{
+		// The selection was validated before the identity was created.
+		fields, err := identity.ParseFieldSelection(r)
+		if err != nil {
+			return err
+		}
+
+		response, err := fields.Apply(&registration.APIFlowResponse{
 			Session: s, Token: s.Token,
 			Identity: s.Identity,
-		})
+		}, "identity", "session.identity")
+		if err != nil {
+			return err
+		}
+
+		e.r.Writer().Write(w, r, response)
 		return errors.WithStack(registration.ErrHookAbortFlow)
 	}
 
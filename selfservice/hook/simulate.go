@@ -0,0 +1,197 @@
+package hook
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/x/errorsx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+const RouteSimulate = "/hooks/simulate"
+
+type (
+	// Simulator is implemented by hooks which can be executed in dry-run mode, meaning without side effects.
+	Simulator interface {
+		SimulateHook(r *http.Request, i *identity.Identity) (*Simulation, error)
+	}
+
+	// SimulatedHook is a hook configured for a flow and method.
+	SimulatedHook struct {
+		// Name is the name of the hook in the configuration.
+		Name string
+
+		// Hook is the hook or nil if the hook is unknown.
+		Hook interface{}
+	}
+
+	SimulationHooksProvider interface {
+		// SimulationHooks returns the after hooks configured for the flow and method.
+		SimulationHooks(flow, method string) ([]SimulatedHook, error)
+	}
+
+	simulationHandlerDependencies interface {
+		x.WriterProvider
+		identity.ValidationProvider
+		SimulationHooksProvider
+	}
+	SimulationHandlerProvider interface {
+		HookSimulationHandler() *SimulationHandler
+	}
+	SimulationHandler struct {
+		d simulationHandlerDependencies
+		c *config.Provider
+	}
+)
+
+// Hook Simulation
+//
+// swagger:model hookSimulation
+type Simulation struct {
+	// Hook is the name of the hook.
+	//
+	// required: true
+	Hook string `json:"hook"`
+
+	// Payload is what the hook would send to external systems.
+	Payload json.RawMessage `json:"payload,omitempty"`
+
+	// Response is the outcome of the hook.
+	Response json.RawMessage `json:"response,omitempty"`
+
+	// Skipped is true if the hook does not support dry-run mode and was therefore not executed.
+	Skipped bool `json:"skipped,omitempty"`
+
+	// Error is set if the hook failed.
+	Error string `json:"error,omitempty"`
+}
+
+// swagger:model simulateHooksBody
+type SimulateHooksBody struct {
+	// Flow is the flow whose after hooks are simulated, one of `login`, `registration`, or `settings`.
+	//
+	// required: true
+	Flow string `json:"flow"`
+
+	// Method is the method whose after hooks are simulated, for example `password`.
+	//
+	// required: true
+	Method string `json:"method"`
+
+	// SchemaID is the identity schema of the sample identity and defaults to the default identity schema.
+	SchemaID string `json:"schema_id"`
+
+	// Traits are the traits of the sample identity.
+	//
+	// required: true
+	Traits identity.Traits `json:"traits"`
+}
+
+func NewSimulationHandler(d simulationHandlerDependencies, c *config.Provider) *SimulationHandler {
+	return &SimulationHandler{d: d, c: c}
+}
+
+func (h *SimulationHandler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.POST(RouteSimulate, h.simulate)
+}
+
+// nolint:deadcode,unused
+// swagger:parameters simulateHooks
+type simulateHooksParameters struct {
+	// in: body
+	// required: true
+	Body SimulateHooksBody
+}
+
+// A list of hook simulations.
+//
+// swagger:response hookSimulationList
+// nolint:deadcode,unused
+type hookSimulationListResponse struct {
+	// in: body
+	Body []Simulation
+}
+
+// swagger:route POST /hooks/simulate admin simulateHooks
+//
+// Simulate Hooks
+//
+// Executes the after hooks configured for a flow and method against a sample identity in dry-run mode and
+// returns what each hook would send and its outcome. Nothing is persisted, no sessions are issued, and no
+// external systems are changed. Hooks which do not support dry-run mode are skipped. Use this endpoint to
+// debug hook configurations safely.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: hookSimulationList
+//       400: genericError
+//       500: genericError
+func (h *SimulationHandler) simulate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body SimulateHooksBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+		return
+	}
+
+	if body.SchemaID == "" {
+		body.SchemaID = config.DefaultIdentityTraitsSchemaID
+	}
+
+	i := identity.NewIdentity(body.SchemaID)
+	i.Traits = body.Traits
+	if err := h.d.IdentityValidator().Validate(i); err != nil {
+		if _, ok := errorsx.Cause(err).(*jsonschema.ValidationError); ok {
+			err = errors.WithStack(herodot.ErrBadRequest.WithReasonf("%s", err))
+		}
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	hooks, err := h.d.SimulationHooks(body.Flow, body.Method)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	simulations := make([]Simulation, len(hooks))
+	for k, hook := range hooks {
+		simulator, ok := hook.Hook.(Simulator)
+		if !ok {
+			simulations[k] = Simulation{Hook: hook.Name, Skipped: true}
+			continue
+		}
+
+		s, err := simulator.SimulateHook(r, i)
+		if err != nil {
+			simulations[k] = Simulation{Hook: hook.Name, Error: simulationError(err)}
+			continue
+		}
+
+		s.Hook = hook.Name
+		simulations[k] = *s
+	}
+
+	h.d.Writer().Write(w, r, simulations)
+}
+
+func simulationError(err error) string {
+	if e := new(herodot.DefaultError); errors.As(err, &e) && e.ReasonField != "" {
+		return e.ReasonField
+	}
+	return err.Error()
+}
@@ -0,0 +1,75 @@
+package hook_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/ioutilx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/hook"
+)
+
+func TestSimulationHandler(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/simulate.schema.json")
+	conf.MustSet(config.ViperKeySelfServiceRegistrationAfter+".password.hooks", []map[string]interface{}{
+		{"hook": "quarantine", "config": map[string]interface{}{"blocked_domains": []string{"mailinator.com"}}},
+		{"hook": "keto_relation_tuples", "config": map[string]interface{}{
+			"write_url":  "http://127.0.0.1:1/",
+			"mapper_url": "file://./stub/keto.jsonnet",
+		}},
+		{"hook": "session"},
+	})
+	_, adminTS := testhelpers.NewKratosServer(t, reg)
+
+	simulate := func(t *testing.T, body string) (*http.Response, string) {
+		res, err := adminTS.Client().Post(adminTS.URL+hook.RouteSimulate, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, string(ioutilx.MustReadAll(res.Body))
+	}
+
+	t.Run("case=simulates the hooks", func(t *testing.T) {
+		res, body := simulate(t, `{"flow":"registration","method":"password","traits":{"email":"foo@mailinator.com","department":"engineering"}}`)
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+
+		var simulations []hook.Simulation
+		require.NoError(t, json.Unmarshal([]byte(body), &simulations))
+		require.Len(t, simulations, 3)
+
+		assert.Equal(t, "quarantine", simulations[0].Hook)
+		assert.Equal(t, hook.QuarantineReasonDisposableEmailDomain, gjson.GetBytes(simulations[0].Response, "quarantine_reason").String())
+
+		assert.Equal(t, "keto_relation_tuples", simulations[1].Hook)
+		assert.Equal(t, "engineering", gjson.GetBytes(simulations[1].Payload, "0.object").String(), "%s", body)
+		assert.Empty(t, simulations[1].Error)
+
+		assert.Equal(t, "session", simulations[2].Hook)
+		assert.True(t, simulations[2].Skipped)
+	})
+
+	t.Run("case=reports hook errors", func(t *testing.T) {
+		res, body := simulate(t, `{"flow":"registration","method":"password","traits":{"email":"foo@ory.sh"}}`)
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.Contains(t, gjson.Get(body, "1.error").String(), "department", "%s", body)
+	})
+
+	t.Run("case=rejects unknown flows", func(t *testing.T) {
+		res, body := simulate(t, `{"flow":"recovery","method":"link","traits":{}}`)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%s", body)
+	})
+
+	t.Run("case=rejects invalid identities", func(t *testing.T) {
+		res, body := simulate(t, `{"flow":"registration","method":"password","traits":{"email":1}}`)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%s", body)
+	})
+}
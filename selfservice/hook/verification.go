@@ -9,6 +9,7 @@ import (
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/strategy/link"
 	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
 )
 
 var _ registration.PostHookPostPersistExecutor = new(Verifier)
@@ -18,6 +19,7 @@ type (
 	verifierDependencies interface {
 		link.SenderProvider
 		link.VerificationTokenPersistenceProvider
+		x.ClockProvider
 	}
 	Verifier struct {
 		r verifierDependencies
@@ -43,11 +45,12 @@ func (e *Verifier) do(r *http.Request, i *identity.Identity) error {
 
 	for k := range i.VerifiableAddresses {
 		address := &i.VerifiableAddresses[k]
-		if address.Verified {
+		// Verification links are sent by email. Phone numbers are verified with codes of the code method.
+		if address.Verified || address.Via != identity.VerifiableAddressTypeEmail {
 			continue
 		}
 
-		token := link.NewVerificationToken(address, e.c.SelfServiceFlowVerificationRequestLifespan())
+		token := link.NewVerificationToken(address, e.r.Clock().Now(), e.c.SelfServiceFlowVerificationRequestLifespan())
 		if err := e.r.VerificationTokenPersister().CreateVerificationToken(r.Context(), token); err != nil {
 			return err
 		}
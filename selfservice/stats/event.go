@@ -0,0 +1,64 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/selfservice/flow"
+)
+
+const (
+	FlowLogin        = "login"
+	FlowRegistration = "registration"
+	FlowSettings     = "settings"
+	FlowRecovery     = "recovery"
+	FlowVerification = "verification"
+
+	// EventCreated is recorded when a flow is initialized.
+	EventCreated = "created"
+	// EventFailed is recorded when a submission of a method fails, for example because of a validation error.
+	EventFailed = "failed"
+	// EventSucceeded is recorded when a flow is completed with a method.
+	EventSucceeded = "succeeded"
+	// EventSubmitted is not recorded but aggregates failed and succeeded events in statistics.
+	EventSubmitted = "submitted"
+)
+
+// Event is a step of a self-service flow which is persisted to compute funnel statistics.
+type Event struct {
+	ID uuid.UUID `json:"id" db:"id"`
+
+	FlowID uuid.UUID `json:"flow_id" db:"flow_id"`
+
+	// Flow is the name of the flow, for example `registration`.
+	Flow string `json:"flow" db:"flow"`
+
+	// Type is the flow type, either `api` or `browser`.
+	Type flow.Type `json:"type" db:"type"`
+
+	// Method is the method which was submitted. It is empty for created events.
+	Method string `json:"method" db:"method"`
+
+	Event string `json:"event" db:"event"`
+
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+func (e Event) TableName() string {
+	return "selfservice_flow_events"
+}
+
+// EventCount aggregates the events of a flow, flow type, method, and event.
+type EventCount struct {
+	Flow   string    `db:"flow"`
+	Type   flow.Type `db:"type"`
+	Method string    `db:"method"`
+	Event  string    `db:"event"`
+
+	// Events is the number of events.
+	Events int `db:"events"`
+
+	// Flows is the number of distinct flows with at least one event.
+	Flows int `db:"flows"`
+}
@@ -0,0 +1,264 @@
+package stats
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/x"
+)
+
+const RouteFlows = "/stats/flows"
+
+type (
+	handlerDependencies interface {
+		x.WriterProvider
+		PersistenceProvider
+	}
+	HandlerProvider interface {
+		FlowStatsHandler() *Handler
+	}
+	Handler struct {
+		d handlerDependencies
+		c *config.Provider
+	}
+)
+
+// Flow Statistics
+//
+// swagger:model flowStatistics
+type Statistics struct {
+	// Since is the start of the time range (inclusive).
+	//
+	// required: true
+	Since time.Time `json:"since"`
+
+	// Until is the end of the time range (exclusive).
+	//
+	// required: true
+	Until time.Time `json:"until"`
+
+	// Funnels contains one funnel per flow and flow type.
+	//
+	// required: true
+	Funnels []Funnel `json:"funnels"`
+}
+
+// Funnel counts how many flows of a flow and flow type were created, submitted, and succeeded.
+//
+// swagger:model flowFunnel
+type Funnel struct {
+	// Flow is the name of the flow, for example `registration`.
+	//
+	// required: true
+	Flow string `json:"flow"`
+
+	// Type is the flow type, either `api` or `browser`.
+	//
+	// required: true
+	Type flow.Type `json:"type"`
+
+	// Created is the number of flows which were initialized.
+	//
+	// required: true
+	Created int `json:"created"`
+
+	// Submitted is the number of flows with at least one submission.
+	//
+	// required: true
+	Submitted int `json:"submitted"`
+
+	// Failed is the number of flows with at least one failed submission.
+	//
+	// required: true
+	Failed int `json:"failed"`
+
+	// Succeeded is the number of flows which were completed.
+	//
+	// required: true
+	Succeeded int `json:"succeeded"`
+
+	// Abandoned is the number of created flows which were never submitted.
+	//
+	// required: true
+	Abandoned int `json:"abandoned"`
+
+	// Methods breaks the submissions down by method.
+	//
+	// required: true
+	Methods map[string]MethodFunnel `json:"methods"`
+}
+
+// MethodFunnel counts the submissions of a single method.
+//
+// swagger:model flowMethodFunnel
+type MethodFunnel struct {
+	// Submitted is the number of flows with at least one submission of this method.
+	//
+	// required: true
+	Submitted int `json:"submitted"`
+
+	// Failed is the number of flows with at least one failed submission of this method.
+	//
+	// required: true
+	Failed int `json:"failed"`
+
+	// Succeeded is the number of flows which were completed with this method.
+	//
+	// required: true
+	Succeeded int `json:"succeeded"`
+
+	// Submissions is the total number of submissions of this method, including retries.
+	//
+	// required: true
+	Submissions int `json:"submissions"`
+}
+
+// NewStatistics aggregates event counts into funnels.
+func NewStatistics(since, until time.Time, counts []EventCount) *Statistics {
+	type key struct {
+		flow string
+		ft   flow.Type
+	}
+
+	funnels := map[key]*Funnel{}
+	for _, c := range counts {
+		k := key{flow: c.Flow, ft: c.Type}
+		f, ok := funnels[k]
+		if !ok {
+			f = &Funnel{Flow: c.Flow, Type: c.Type, Methods: map[string]MethodFunnel{}}
+			funnels[k] = f
+		}
+
+		if c.Method == "" {
+			switch c.Event {
+			case EventCreated:
+				f.Created = c.Flows
+			case EventSubmitted:
+				f.Submitted = c.Flows
+			case EventFailed:
+				f.Failed = c.Flows
+			case EventSucceeded:
+				f.Succeeded = c.Flows
+			}
+			continue
+		}
+
+		m := f.Methods[c.Method]
+		switch c.Event {
+		case EventSubmitted:
+			m.Submitted = c.Flows
+			m.Submissions = c.Events
+		case EventFailed:
+			m.Failed = c.Flows
+		case EventSucceeded:
+			m.Succeeded = c.Flows
+		}
+		f.Methods[c.Method] = m
+	}
+
+	s := &Statistics{Since: since, Until: until, Funnels: make([]Funnel, 0, len(funnels))}
+	for _, f := range funnels {
+		if f.Abandoned = f.Created - f.Submitted; f.Abandoned < 0 {
+			// Flows created before the time range can be submitted within it.
+			f.Abandoned = 0
+		}
+		s.Funnels = append(s.Funnels, *f)
+	}
+
+	sort.Slice(s.Funnels, func(i, j int) bool {
+		if s.Funnels[i].Flow == s.Funnels[j].Flow {
+			return s.Funnels[i].Type < s.Funnels[j].Type
+		}
+		return s.Funnels[i].Flow < s.Funnels[j].Flow
+	})
+
+	return s
+}
+
+func NewHandler(d handlerDependencies, c *config.Provider) *Handler {
+	return &Handler{d: d, c: c}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteFlows, h.flows)
+}
+
+// nolint:deadcode,unused
+// swagger:parameters getFlowStatistics
+type getFlowStatisticsParameters struct {
+	// Since is a RFC 3339 timestamp and defaults to 24 hours before `until`.
+	//
+	// in: query
+	Since string `json:"since"`
+
+	// Until is a RFC 3339 timestamp and defaults to now.
+	//
+	// in: query
+	Until string `json:"until"`
+}
+
+// swagger:route GET /stats/flows admin getFlowStatistics
+//
+// Get Self-Service Flow Statistics
+//
+// Returns how many login, registration, settings, recovery, and verification flows were created, submitted,
+// and completed in the given time range, per flow type and method. Flows which were created but never submitted
+// are counted as abandoned. This endpoint requires `selfservice.flow_stats.enabled` to be set.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: flowStatistics
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) flows(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !h.c.SelfServiceFlowStatsEnabled() {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("Flow statistics are disabled. Set `selfservice.flow_stats.enabled` to record them.")))
+		return
+	}
+
+	until := time.Now().UTC()
+	if v := r.URL.Query().Get("until"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Query parameter "until" must contain a RFC 3339 timestamp: %s`, err)))
+			return
+		}
+		until = t.UTC()
+	}
+
+	since := until.Add(-24 * time.Hour)
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Query parameter "since" must contain a RFC 3339 timestamp: %s`, err)))
+			return
+		}
+		since = t.UTC()
+	}
+
+	if !since.Before(until) {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason(`Query parameter "since" must be before "until".`)))
+		return
+	}
+
+	counts, err := h.d.FlowStatsPersister().CountFlowEvents(r.Context(), since, until)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, NewStatistics(since, until, counts))
+}
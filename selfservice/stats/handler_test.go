@@ -0,0 +1,46 @@
+package stats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/stats"
+)
+
+func TestNewStatistics(t *testing.T) {
+	until := time.Now().UTC()
+	since := until.Add(-time.Hour)
+
+	s := stats.NewStatistics(since, until, []stats.EventCount{
+		{Flow: stats.FlowRegistration, Type: flow.TypeBrowser, Event: stats.EventCreated, Events: 10, Flows: 10},
+		{Flow: stats.FlowRegistration, Type: flow.TypeBrowser, Event: stats.EventSubmitted, Events: 9, Flows: 6},
+		{Flow: stats.FlowRegistration, Type: flow.TypeBrowser, Event: stats.EventFailed, Events: 5, Flows: 3},
+		{Flow: stats.FlowRegistration, Type: flow.TypeBrowser, Event: stats.EventSucceeded, Events: 4, Flows: 4},
+		{Flow: stats.FlowRegistration, Type: flow.TypeBrowser, Method: "password", Event: stats.EventSubmitted, Events: 7, Flows: 4},
+		{Flow: stats.FlowRegistration, Type: flow.TypeBrowser, Method: "password", Event: stats.EventFailed, Events: 5, Flows: 3},
+		{Flow: stats.FlowRegistration, Type: flow.TypeBrowser, Method: "password", Event: stats.EventSucceeded, Events: 2, Flows: 2},
+		{Flow: stats.FlowLogin, Type: flow.TypeAPI, Event: stats.EventCreated, Events: 1, Flows: 1},
+		{Flow: stats.FlowLogin, Type: flow.TypeAPI, Event: stats.EventSubmitted, Events: 2, Flows: 2},
+	})
+
+	require.Len(t, s.Funnels, 2)
+	assert.Equal(t, since, s.Since)
+	assert.Equal(t, until, s.Until)
+
+	login, registration := s.Funnels[0], s.Funnels[1]
+	assert.Equal(t, stats.FlowLogin, login.Flow)
+	assert.Equal(t, 0, login.Abandoned, "flows created before the time range must not result in negative counts")
+
+	assert.Equal(t, stats.FlowRegistration, registration.Flow)
+	assert.Equal(t, flow.TypeBrowser, registration.Type)
+	assert.Equal(t, 10, registration.Created)
+	assert.Equal(t, 6, registration.Submitted)
+	assert.Equal(t, 3, registration.Failed)
+	assert.Equal(t, 4, registration.Succeeded)
+	assert.Equal(t, 4, registration.Abandoned)
+	assert.Equal(t, stats.MethodFunnel{Submitted: 4, Failed: 3, Succeeded: 2, Submissions: 7}, registration.Methods["password"])
+}
@@ -0,0 +1,79 @@
+package stats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	Persister interface {
+		// CreateFlowEvent persists a flow event.
+		CreateFlowEvent(ctx context.Context, e *Event) error
+
+		// CountFlowEvents aggregates the events created in the given time range by flow, flow type, method, and
+		// event. Additional rows with the event `submitted` combine failed and succeeded events, and additional
+		// rows with an empty method combine the failed, succeeded, and submitted events of all methods.
+		CountFlowEvents(ctx context.Context, since, until time.Time) ([]EventCount, error)
+	}
+
+	PersistenceProvider interface {
+		FlowStatsPersister() Persister
+	}
+)
+
+func TestPersister(p Persister) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		now := time.Now().UTC().Truncate(time.Second)
+
+		record := func(t *testing.T, id uuid.UUID, ft flow.Type, method, event string, at time.Time) {
+			require.NoError(t, p.CreateFlowEvent(ctx, &Event{
+				FlowID: id, Flow: FlowRegistration, Type: ft, Method: method, Event: event, CreatedAt: at,
+			}))
+		}
+
+		abandoned, failed, succeeded, old := x.NewUUID(), x.NewUUID(), x.NewUUID(), x.NewUUID()
+		record(t, abandoned, flow.TypeBrowser, "", EventCreated, now)
+		record(t, failed, flow.TypeBrowser, "", EventCreated, now)
+		record(t, failed, flow.TypeBrowser, "password", EventFailed, now)
+		record(t, failed, flow.TypeBrowser, "password", EventFailed, now)
+		record(t, succeeded, flow.TypeBrowser, "", EventCreated, now)
+		record(t, succeeded, flow.TypeBrowser, "password", EventFailed, now)
+		record(t, succeeded, flow.TypeBrowser, "oidc", EventSucceeded, now)
+		record(t, old, flow.TypeAPI, "", EventCreated, now.Add(-time.Hour))
+
+		counts, err := p.CountFlowEvents(ctx, now.Add(-time.Minute), now.Add(time.Minute))
+		require.NoError(t, err)
+
+		find := func(method, event string) EventCount {
+			for _, c := range counts {
+				if c.Flow == FlowRegistration && c.Type == flow.TypeBrowser && c.Method == method && c.Event == event {
+					return c
+				}
+			}
+			return EventCount{}
+		}
+
+		assert.Equal(t, 3, find("", EventCreated).Flows, "%+v", counts)
+		assert.Equal(t, 3, find("password", EventFailed).Events, "%+v", counts)
+		assert.Equal(t, 2, find("password", EventFailed).Flows, "%+v", counts)
+		assert.Equal(t, 1, find("oidc", EventSucceeded).Flows, "%+v", counts)
+		assert.Equal(t, 2, find("password", EventSubmitted).Flows, "%+v", counts)
+		assert.Equal(t, 1, find("oidc", EventSubmitted).Flows, "%+v", counts)
+		assert.Equal(t, 2, find("", EventSubmitted).Flows, "%+v", counts)
+		assert.Equal(t, 2, find("", EventFailed).Flows, "%+v", counts)
+		assert.Equal(t, 1, find("", EventSucceeded).Flows, "%+v", counts)
+
+		for _, c := range counts {
+			assert.NotEqual(t, flow.TypeAPI, c.Type, "events outside of the time range must not be counted: %+v", counts)
+		}
+	}
+}
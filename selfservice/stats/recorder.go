@@ -0,0 +1,73 @@
+package stats
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/x"
+)
+
+var (
+	flowEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kratos_selfservice_flow_events_total",
+			Help: "Number of created, failed, and succeeded self-service flows by flow, flow type, and method.",
+		},
+		[]string{"flow", "type", "method", "event"},
+	)
+	registerFlowEvents sync.Once
+)
+
+type (
+	recorderDependencies interface {
+		x.LoggingProvider
+		PersistenceProvider
+	}
+	RecorderProvider interface {
+		FlowStatsRecorder() *Recorder
+	}
+	// Recorder counts flow events for the funnel statistics.
+	Recorder struct {
+		d recorderDependencies
+		c *config.Provider
+	}
+)
+
+func NewRecorder(d recorderDependencies, c *config.Provider) *Recorder {
+	registerFlowEvents.Do(func() {
+		// The collector is global, registries are not.
+		_ = prometheus.Register(flowEvents)
+	})
+	return &Recorder{d: d, c: c}
+}
+
+// Record counts a flow event in the Prometheus metrics and, if enabled, persists it. Errors are logged but not
+// returned because statistics must never break a flow.
+func (r *Recorder) Record(ctx context.Context, name string, id uuid.UUID, ft flow.Type, method string, event string) {
+	flowEvents.WithLabelValues(name, string(ft), method, event).Inc()
+
+	if !r.c.SelfServiceFlowStatsEnabled() {
+		return
+	}
+
+	if err := r.d.FlowStatsPersister().CreateFlowEvent(ctx, &Event{
+		FlowID:    id,
+		Flow:      name,
+		Type:      ft,
+		Method:    method,
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		r.d.Logger().WithError(err).
+			WithField("flow", name).
+			WithField("flow_id", id).
+			WithField("event", event).
+			Warn("Unable to record the self-service flow event.")
+	}
+}
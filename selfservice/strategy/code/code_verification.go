@@ -0,0 +1,74 @@
+package code
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/randx"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/x"
+)
+
+// CodeLength is the number of digits of a verification code.
+const CodeLength = 6
+
+type VerificationCode struct {
+	// ID represents the code's unique ID.
+	//
+	// required: true
+	// type: string
+	// format: uuid
+	ID uuid.UUID `json:"id" db:"id" faker:"-"`
+
+	// Code represents the verification code which the user enters into the verification flow.
+	Code string `json:"-" db:"code"`
+
+	// Attempts is the number of times an incorrect code was entered.
+	Attempts int `json:"-" db:"attempts"`
+
+	// VerifiableAddress links this code to a verification address.
+	// required: true
+	VerifiableAddress *identity.VerifiableAddress `json:"verification_address" belongs_to:"identity_verifiable_addresses" fk_id:"VerifiableAddressID"`
+
+	// ExpiresAt is the time (UTC) when the code expires.
+	// required: true
+	ExpiresAt time.Time `json:"expires_at" faker:"time_type" db:"expires_at"`
+
+	// IssuedAt is the time (UTC) when the code was issued.
+	// required: true
+	IssuedAt time.Time `json:"issued_at" faker:"time_type" db:"issued_at"`
+
+	// CreatedAt is a helper struct field for gobuffalo.pop.
+	CreatedAt time.Time `json:"-" faker:"-" db:"created_at"`
+	// UpdatedAt is a helper struct field for gobuffalo.pop.
+	UpdatedAt time.Time `json:"-" faker:"-" db:"updated_at"`
+	// VerifiableAddressID is a helper struct field for gobuffalo.pop.
+	VerifiableAddressID uuid.UUID `json:"-" faker:"-" db:"identity_verifiable_address_id"`
+	// FlowID is a helper struct field for gobuffalo.pop.
+	FlowID uuid.UUID `json:"-" faker:"-" db:"selfservice_verification_flow_id"`
+}
+
+func (VerificationCode) TableName() string {
+	return "identity_verification_codes"
+}
+
+// NewVerificationCode returns a random numeric code for verifying the address in the flow. The code expires after
+// the lifespan, but never after the flow.
+func NewVerificationCode(address *identity.VerifiableAddress, f *verification.Flow, now time.Time, lifespan time.Duration) *VerificationCode {
+	expiresAt := now.Add(lifespan)
+	if f.ExpiresAt.Before(expiresAt) {
+		expiresAt = f.ExpiresAt
+	}
+
+	return &VerificationCode{
+		ID:                x.NewUUID(),
+		Code:              randx.MustString(CodeLength, randx.Numeric),
+		VerifiableAddress: address,
+		ExpiresAt:         expiresAt.UTC(),
+		IssuedAt:          now.UTC(),
+		FlowID:            f.ID,
+	}
+}
@@ -0,0 +1,29 @@
+package code
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrCodeMismatch is returned if the entered code is not the code sent for the flow.
+var ErrCodeMismatch = errors.New("the verification code does not match")
+
+type (
+	VerificationCodePersister interface {
+		// CreateVerificationCode stores the code and invalidates all codes sent for the same flow before. The
+		// incorrect attempts of the previous code are carried over to the new one.
+		CreateVerificationCode(ctx context.Context, code *VerificationCode) error
+
+		// UseVerificationCode marks the flow's code as used if it matches and returns it. If the flow has no
+		// unused and unexpired code, sqlcon.ErrNoRows is returned. If the code does not match, the failed attempt
+		// is counted, ErrCodeMismatch is returned, and the code is invalidated once codes were entered incorrectly
+		// maxAttempts times in the flow.
+		UseVerificationCode(ctx context.Context, flowID uuid.UUID, code string, maxAttempts int) (*VerificationCode, error)
+	}
+
+	VerificationCodePersistenceProvider interface {
+		VerificationCodePersister() VerificationCodePersister
+	}
+)
@@ -0,0 +1,147 @@
+package code
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/faker/v3"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/x"
+)
+
+func TestPersister(conf *config.Provider, p interface {
+	VerificationCodePersister
+	verification.FlowPersister
+	identity.PrivilegedPool
+}) func(t *testing.T) {
+	return func(t *testing.T) {
+		conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+		conf.MustSet(config.ViperKeySecretsDefault, []string{"secret-a", "secret-b"})
+		ctx := context.Background()
+
+		newVerificationCode := func(t *testing.T, expiresIn time.Duration) *VerificationCode {
+			var f verification.Flow
+			require.NoError(t, faker.FakeData(&f))
+			f.ExpiresAt = time.Now().Add(time.Hour)
+			require.NoError(t, p.CreateVerificationFlow(ctx, &f))
+
+			var i identity.Identity
+			require.NoError(t, faker.FakeData(&i))
+			i.VerifiableAddresses = append(i.VerifiableAddresses, identity.VerifiableAddress{
+				Value: x.NewUUID().String() + "@ory.sh", Via: identity.VerifiableAddressTypeEmail})
+			require.NoError(t, p.CreateIdentity(ctx, &i))
+
+			return NewVerificationCode(&i.VerifiableAddresses[0], &f, time.Now(), expiresIn)
+		}
+
+		t.Run("case=should error when the flow has no code", func(t *testing.T) {
+			_, err := p.UseVerificationCode(ctx, x.NewUUID(), "123456", 5)
+			require.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+		})
+
+		t.Run("case=should create a verification code and use it", func(t *testing.T) {
+			expected := newVerificationCode(t, time.Hour)
+			entered := expected.Code
+			require.NoError(t, p.CreateVerificationCode(ctx, expected))
+			assert.Equal(t, entered, expected.Code)
+
+			actual, err := p.UseVerificationCode(ctx, expected.FlowID, entered, 5)
+			require.NoError(t, err)
+			assert.Equal(t, expected.ID, actual.ID)
+			assert.Equal(t, expected.VerifiableAddress.ID, actual.VerifiableAddress.ID)
+			assert.NotEqual(t, entered, actual.Code)
+
+			_, err = p.UseVerificationCode(ctx, expected.FlowID, entered, 5)
+			require.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+		})
+
+		t.Run("case=should not use an expired code", func(t *testing.T) {
+			expected := newVerificationCode(t, -time.Minute)
+			require.NoError(t, p.CreateVerificationCode(ctx, expected))
+
+			_, err := p.UseVerificationCode(ctx, expected.FlowID, expected.Code, 5)
+			require.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+		})
+
+		t.Run("case=should only use the most recent code of a flow", func(t *testing.T) {
+			previous := newVerificationCode(t, time.Hour)
+			require.NoError(t, p.CreateVerificationCode(ctx, previous))
+
+			recent := NewVerificationCode(previous.VerifiableAddress, &verification.Flow{ID: previous.FlowID, ExpiresAt: previous.ExpiresAt}, time.Now(), time.Hour)
+			recent.Code = "0" + previous.Code[1:]
+			if recent.Code == previous.Code {
+				recent.Code = "1" + previous.Code[1:]
+			}
+			require.NoError(t, p.CreateVerificationCode(ctx, recent))
+
+			_, err := p.UseVerificationCode(ctx, previous.FlowID, previous.Code, 5)
+			require.True(t, errors.Is(err, ErrCodeMismatch), "%+v", err)
+
+			actual, err := p.UseVerificationCode(ctx, recent.FlowID, recent.Code, 5)
+			require.NoError(t, err)
+			assert.Equal(t, recent.ID, actual.ID)
+		})
+
+		t.Run("case=should invalidate the code after too many incorrect attempts", func(t *testing.T) {
+			expected := newVerificationCode(t, time.Hour)
+			expected.Code = "123456"
+			require.NoError(t, p.CreateVerificationCode(ctx, expected))
+
+			for k := 0; k < 3; k++ {
+				_, err := p.UseVerificationCode(ctx, expected.FlowID, "654321", 3)
+				require.True(t, errors.Is(err, ErrCodeMismatch), "%+v", err)
+			}
+
+			_, err := p.UseVerificationCode(ctx, expected.FlowID, "123456", 3)
+			require.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+		})
+
+		t.Run("case=should not reset the incorrect attempts when a new code is sent", func(t *testing.T) {
+			previous := newVerificationCode(t, time.Hour)
+			previous.Code = "123456"
+			require.NoError(t, p.CreateVerificationCode(ctx, previous))
+
+			for k := 0; k < 2; k++ {
+				_, err := p.UseVerificationCode(ctx, previous.FlowID, "654321", 3)
+				require.True(t, errors.Is(err, ErrCodeMismatch), "%+v", err)
+			}
+
+			recent := NewVerificationCode(previous.VerifiableAddress, &verification.Flow{ID: previous.FlowID, ExpiresAt: previous.ExpiresAt}, time.Now(), time.Hour)
+			recent.Code = "234567"
+			require.NoError(t, p.CreateVerificationCode(ctx, recent))
+			assert.Equal(t, 2, recent.Attempts)
+
+			_, err := p.UseVerificationCode(ctx, recent.FlowID, "654321", 3)
+			require.True(t, errors.Is(err, ErrCodeMismatch), "%+v", err)
+
+			latest := NewVerificationCode(previous.VerifiableAddress, &verification.Flow{ID: previous.FlowID, ExpiresAt: previous.ExpiresAt}, time.Now(), time.Hour)
+			latest.Code = "345678"
+			require.NoError(t, p.CreateVerificationCode(ctx, latest))
+
+			_, err = p.UseVerificationCode(ctx, latest.FlowID, "345678", 3)
+			require.True(t, errors.Is(err, sqlcon.ErrNoRows), "the flow used up its attempts: %+v", err)
+		})
+
+		t.Run("case=should accept the code after fewer incorrect attempts than allowed", func(t *testing.T) {
+			expected := newVerificationCode(t, time.Hour)
+			expected.Code = "123456"
+			require.NoError(t, p.CreateVerificationCode(ctx, expected))
+
+			_, err := p.UseVerificationCode(ctx, expected.FlowID, "654321", 3)
+			require.True(t, errors.Is(err, ErrCodeMismatch), "%+v", err)
+
+			actual, err := p.UseVerificationCode(ctx, expected.FlowID, "123456", 3)
+			require.NoError(t, err)
+			assert.Equal(t, 1, actual.Attempts)
+		})
+	}
+}
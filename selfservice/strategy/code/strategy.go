@@ -0,0 +1,59 @@
+package code
+
+import (
+	"github.com/ory/x/decoderx"
+
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/selfservice/stats"
+	"github.com/ory/kratos/x"
+)
+
+var _ verification.Strategy = new(Strategy)
+var _ verification.PublicHandler = new(Strategy)
+
+type (
+	// FlowMethod contains the configuration for this selfservice strategy.
+	FlowMethod struct {
+		*form.HTMLForm
+	}
+
+	strategyDependencies interface {
+		x.CSRFProvider
+		x.CSRFTokenGeneratorProvider
+		x.WriterProvider
+		x.LoggingProvider
+		x.ClockProvider
+
+		identity.PoolProvider
+		identity.PrivilegedPoolProvider
+
+		courier.Provider
+
+		errorx.ManagementProvider
+
+		verification.ErrorHandlerProvider
+		verification.FlowPersistenceProvider
+		verification.StrategyProvider
+
+		VerificationCodePersistenceProvider
+
+		stats.RecorderProvider
+	}
+
+	// Strategy verifies addresses with a short numeric code which is sent to the address and entered into the
+	// verification flow, which works even if the email is read on another device.
+	Strategy struct {
+		c  *config.Provider
+		d  strategyDependencies
+		dx *decoderx.HTTP
+	}
+)
+
+func NewStrategy(d strategyDependencies, c *config.Provider) *Strategy {
+	return &Strategy{c: c, d: d, dx: decoderx.NewHTTP()}
+}
@@ -0,0 +1,463 @@
+package code
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/markbates/pkger"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/pkgerx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+	"github.com/ory/x/urlx"
+
+	templates "github.com/ory/kratos/courier/template"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/selfservice/stats"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteVerification = "/self-service/verification/methods/code"
+)
+
+func (s *Strategy) VerificationStrategyID() string {
+	return verification.StrategyVerificationCodeName
+}
+
+func (s *Strategy) RegisterPublicVerificationRoutes(public *x.RouterPublic) {
+	public.POST(RouteVerification, s.handleVerification)
+}
+
+func (s *Strategy) PopulateVerificationMethod(r *http.Request, req *verification.Flow) error {
+	f := form.NewHTMLForm(req.AppendTo(urlx.AppendPaths(s.c.SelfPublicURL(), RouteVerification)).String())
+
+	f.SetCSRF(s.d.GenerateCSRFToken(r))
+	s.setAddressFields(f, "", "")
+
+	req.Methods[s.VerificationStrategyID()] = &verification.FlowMethod{
+		Method: s.VerificationStrategyID(),
+		Config: &verification.FlowMethodConfig{FlowMethodConfigurator: &FlowMethod{HTMLForm: f}},
+	}
+	return nil
+}
+
+// smsEnabled returns true if text messages can be routed to a provider which delivers them.
+func (s *Strategy) smsEnabled() bool {
+	return len(s.c.CourierSMSRoutes()) > 0 && len(s.c.CourierSMSProviders()) > 0
+}
+
+// setAddressFields asks for the email address or, if text messages can be sent, for the email address or the
+// phone number.
+func (s *Strategy) setAddressFields(f form.FieldSetter, email, phone string) {
+	emailField := form.Field{Name: "email", Type: "email", Required: true, Autocomplete: form.AutocompleteEmail}
+	if email != "" {
+		emailField.Value = email
+	}
+
+	if !s.smsEnabled() {
+		f.SetField(emailField)
+		return
+	}
+
+	phoneField := form.Field{Name: "phone", Type: "tel", Autocomplete: form.AutocompleteTel}
+	if phone != "" {
+		phoneField.Value = phone
+	}
+
+	emailField.Required = false
+	f.SetField(emailField)
+	f.SetField(phoneField)
+}
+
+// resetForm renders the form of the flow. Once a code was sent, the form asks for the code and allows to request
+// another code by submitting the email address or phone number only.
+func (s *Strategy) resetForm(r *http.Request, f *verification.Flow, email, phone string) error {
+	config, err := f.MethodToForm(s.VerificationStrategyID())
+	if err != nil {
+		return err
+	}
+
+	config.Reset()
+	config.SetCSRF(s.d.GenerateCSRFToken(r))
+	s.setAddressFields(config, email, phone)
+	if f.State == verification.StateEmailSent {
+		config.SetField(form.Field{Name: "code", Type: "text", Pattern: "[0-9]{6}", Autocomplete: form.AutocompleteOneTimeCode})
+	}
+	return nil
+}
+
+// swagger:parameters completeSelfServiceVerificationFlowWithCodeMethod
+type completeSelfServiceVerificationFlowWithCodeMethodParameters struct {
+	// in: body
+	Body completeSelfServiceVerificationFlowWithCodeMethod
+
+	// The Flow ID
+	//
+	// format: uuid
+	// in: query
+	Flow string `json:"flow"`
+}
+
+func (m *completeSelfServiceVerificationFlowWithCodeMethodParameters) GetFlow() uuid.UUID {
+	return x.ParseUUID(m.Flow)
+}
+
+type completeSelfServiceVerificationFlowWithCodeMethod struct {
+	// Email to Verify
+	//
+	// If the email is a registered verification email, a verification code will be sent. If the email is not known,
+	// a email with details on what happened will be sent instead.
+	//
+	// format: email
+	// in: body
+	Email string `json:"email"`
+
+	// Phone Number to Verify
+	//
+	// If the phone number is a registered verification phone number, a verification code will be sent by text
+	// message. Phone numbers must include the country calling code. Only used if `email` is empty and
+	// `courier.sms.routes` and `courier.sms.providers` are configured.
+	//
+	// in: body
+	Phone string `json:"phone"`
+
+	// Verification Code
+	//
+	// The code which was sent to the email address or phone number. If set, the code is checked instead of sending a new one.
+	//
+	// in: body
+	Code string `json:"code"`
+
+	// Sending the anti-csrf token is only required for browser login flows.
+	CSRFToken string `form:"csrf_token" json:"csrf_token"`
+}
+
+func (s *Strategy) decodeVerification(r *http.Request) (*completeSelfServiceVerificationFlowWithCodeMethodParameters, error) {
+	body := &completeSelfServiceVerificationFlowWithCodeMethodParameters{Flow: r.URL.Query().Get("flow")}
+	if err := s.dx.Decode(r, &body.Body,
+		decoderx.MustHTTPRawJSONSchemaCompiler(
+			pkgerx.MustRead(pkger.Open("/selfservice/strategy/code/.schema/verification.schema.json")),
+		),
+		decoderx.HTTPDecoderSetValidatePayloads(false),
+		decoderx.HTTPDecoderJSONFollowsFormFormat()); err != nil {
+		return body, err
+	}
+	return body, nil
+}
+
+// handleVerificationError is a convenience function for handling all types of errors that may occur (e.g. validation error).
+func (s *Strategy) handleVerificationError(w http.ResponseWriter, r *http.Request, f *verification.Flow, body *completeSelfServiceVerificationFlowWithCodeMethodParameters, err error) {
+	if f != nil {
+		if err := s.resetForm(r, f, body.Body.Email, body.Body.Phone); err != nil {
+			s.d.VerificationFlowErrorHandler().WriteFlowError(w, r, s.VerificationStrategyID(), f, err)
+			return
+		}
+	}
+
+	s.d.VerificationFlowErrorHandler().WriteFlowError(w, r, s.VerificationStrategyID(), f, err)
+}
+
+// swagger:route POST /self-service/verification/methods/code public completeSelfServiceVerificationFlowWithCodeMethod
+//
+// Complete Verification Flow with Code Method
+//
+// Use this endpoint to complete a verification flow using the code method. It works with API- and Browser-initiated
+// flows and has several states:
+//
+// - `choose_method` expects `flow` (in the URL query) and `email` (in the body) to be sent. A verification code is
+//   sent to the email address and the flow moves to `sent_email`. If `courier.sms.routes` and
+//   `courier.sms.providers` are configured, `phone` may be sent instead of `email` and the code is sent by text
+//   message.
+// - `sent_email` expects `code` (in the body) to be sent. If the code is valid, the address is verified and the
+//   flow moves to `passed_challenge`. Sending `email` without `code` sends a new code which replaces the previous one.
+//   A code expires after `selfservice.methods.code.config.lifespan`. Once codes were entered incorrectly
+//   `selfservice.methods.code.config.max_attempts` times in the flow, a new flow must be started.
+//
+// For API clients the endpoint returns a HTTP 200 OK when the form is valid and HTTP 400 OK when the form is invalid,
+// and a HTTP 302 Found redirect with a fresh verification flow if the flow was otherwise invalid (e.g. expired).
+// For Browser clients it returns a HTTP 302 Found redirect to the Verification UI URL with the Verification Flow ID appended.
+//
+//     Consumes:
+//     - application/json
+//     - application/x-www-form-urlencoded
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: verificationFlow
+//       400: verificationFlow
+//       302: emptyResponse
+//       500: genericError
+func (s *Strategy) handleVerification(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	body, err := s.decodeVerification(r)
+	if err != nil {
+		s.handleVerificationError(w, r, nil, body, err)
+		return
+	}
+
+	f, err := s.d.VerificationFlowPersister().GetVerificationFlow(r.Context(), body.GetFlow())
+	if err != nil {
+		s.handleVerificationError(w, r, nil, body, err)
+		return
+	}
+
+	if err := f.Valid(s.d.Clock().Now()); err != nil {
+		s.handleVerificationError(w, r, f, body, err)
+		return
+	}
+
+	switch f.State {
+	case verification.StateChooseMethod:
+		fallthrough
+	case verification.StateEmailSent:
+		if err := flow.VerifyRequest(r, f.Type, s.c.DisableAPIFlowEnforcement(), s.d.GenerateCSRFToken, body.Body.CSRFToken); err != nil {
+			s.handleVerificationError(w, r, f, body, err)
+			return
+		}
+
+		if len(body.Body.Code) > 0 {
+			s.verificationUseCode(w, r, f, body)
+			return
+		}
+
+		s.verificationSendCode(w, r, f, body)
+		return
+	case verification.StatePassedChallenge:
+		s.retryVerificationFlowWithMessage(w, r, f.Type, text.NewErrorValidationVerificationRetrySuccess())
+		return
+	default:
+		s.retryVerificationFlowWithMessage(w, r, f.Type, text.NewErrorValidationVerificationStateFailure())
+		return
+	}
+}
+
+func (s *Strategy) verificationSendCode(w http.ResponseWriter, r *http.Request, f *verification.Flow, body *completeSelfServiceVerificationFlowWithCodeMethodParameters) {
+	sent := text.NewVerificationCodeSent()
+	switch {
+	case len(body.Body.Email) > 0:
+		if err := s.sendVerificationCode(r.Context(), f, body.Body.Email); err != nil {
+			s.handleVerificationError(w, r, f, body, err)
+			return
+		}
+	case len(body.Body.Phone) > 0 && s.smsEnabled():
+		if err := s.sendVerificationSMS(r.Context(), f, body.Body.Phone); err != nil {
+			s.handleVerificationError(w, r, f, body, err)
+			return
+		}
+		sent = text.NewVerificationSMSCodeSent()
+	default:
+		s.handleVerificationError(w, r, f, body, schema.NewRequiredError("#/email", "email"))
+		return
+	}
+
+	f.Active = sqlxx.NullString(s.VerificationStrategyID())
+	f.State = verification.StateEmailSent
+	f.Messages.Set(sent)
+	if err := s.resetForm(r, f, body.Body.Email, body.Body.Phone); err != nil {
+		s.handleVerificationError(w, r, f, body, err)
+		return
+	}
+
+	if err := s.d.VerificationFlowPersister().UpdateVerificationFlow(r.Context(), f); err != nil {
+		s.handleVerificationError(w, r, f, body, err)
+		return
+	}
+
+	s.writeFlow(w, r, f, body, f.AppendTo(s.c.SelfServiceFlowVerificationUI()))
+}
+
+// sendVerificationCode sends a verification code to the address. If the address does not exist in the store, an
+// email is still being sent to prevent account enumeration attacks.
+func (s *Strategy) sendVerificationCode(ctx context.Context, f *verification.Flow, to string) error {
+	address, err := s.d.IdentityPool().FindVerifiableAddressByValue(ctx, identity.VerifiableAddressTypeEmail, to)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		s.d.Audit().
+			WithField("via", identity.VerifiableAddressTypeEmail).
+			WithSensitiveField("email_address", to).
+			Info("Sending out invalid verification email because address is unknown.")
+		_, err := s.d.Courier().QueueEmail(ctx, templates.NewVerificationInvalid(s.c, &templates.VerificationInvalidModel{To: to}))
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	vc, model, err := s.createVerificationCode(ctx, f, address)
+	if err != nil {
+		return err
+	}
+
+	s.d.Audit().
+		WithField("via", address.Via).
+		WithField("identity_id", address.IdentityID).
+		WithField("verification_code_id", vc.ID).
+		WithSensitiveField("email_address", address.Value).
+		Info("Sending out verification email with verification code.")
+
+	_, err = s.d.Courier().QueueEmail(ctx, templates.NewVerificationCode(s.c, model))
+	return err
+}
+
+// sendVerificationSMS sends a verification code by text message to the phone number. Unlike emails, no message is
+// sent to unknown phone numbers, the response is the same anyway.
+func (s *Strategy) sendVerificationSMS(ctx context.Context, f *verification.Flow, to string) error {
+	phone, err := identity.NormalizePhoneNumber(to, "")
+	if err != nil {
+		return schema.NewInvalidFormatError("#/phone", "tel", to)
+	}
+
+	address, err := s.d.IdentityPool().FindVerifiableAddressByValue(ctx, identity.VerifiableAddressTypeSMS, phone)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		s.d.Audit().
+			WithField("via", identity.VerifiableAddressTypeSMS).
+			WithSensitiveField("phone_number", phone).
+			Info("Not sending a verification code because the phone number is unknown.")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	vc, model, err := s.createVerificationCode(ctx, f, address)
+	if err != nil {
+		return err
+	}
+
+	s.d.Audit().
+		WithField("via", address.Via).
+		WithField("identity_id", address.IdentityID).
+		WithField("verification_code_id", vc.ID).
+		WithSensitiveField("phone_number", address.Value).
+		Info("Sending out verification text message with verification code.")
+
+	_, err = s.d.Courier().QueueSMS(ctx, templates.NewVerificationCode(s.c, model))
+	return err
+}
+
+// createVerificationCode stores a new code for the address and returns the model of the message sending it.
+func (s *Strategy) createVerificationCode(ctx context.Context, f *verification.Flow, address *identity.VerifiableAddress) (*VerificationCode, *templates.VerificationCodeModel, error) {
+	vc := NewVerificationCode(address, f, s.d.Clock().Now(), s.c.SelfServiceStrategyCode().Lifespan)
+	if err := s.d.VerificationCodePersister().CreateVerificationCode(ctx, vc); err != nil {
+		return nil, nil, err
+	}
+
+	var model map[string]interface{}
+	if allowed := s.c.CourierTemplateVariables(templates.TypeVerificationCode); len(allowed) > 0 {
+		i, err := s.d.IdentityPool().GetIdentity(ctx, address.IdentityID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if model, err = templates.NewIdentityModel(allowed, i); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return vc, &templates.VerificationCodeModel{To: address.Value, VerificationCode: vc.Code, Identity: model}, nil
+}
+
+func (s *Strategy) verificationUseCode(w http.ResponseWriter, r *http.Request, f *verification.Flow, body *completeSelfServiceVerificationFlowWithCodeMethodParameters) {
+	vc, err := s.d.VerificationCodePersister().UseVerificationCode(r.Context(), f.ID, body.Body.Code, s.c.SelfServiceStrategyCode().MaxAttempts)
+	if errors.Is(err, ErrCodeMismatch) {
+		s.handleVerificationError(w, r, f, body, schema.NewVerificationCodeInvalidError())
+		return
+	} else if errors.Is(err, sqlcon.ErrNoRows) {
+		s.handleVerificationError(w, r, f, body, schema.NewVerificationCodeInvalidOrExpiredError())
+		return
+	} else if err != nil {
+		s.handleVerificationError(w, r, f, body, err)
+		return
+	}
+
+	address := vc.VerifiableAddress
+	address.Verified = true
+	address.VerifiedAt = sqlxx.NullTime(s.d.Clock().Now().UTC())
+	address.Status = identity.VerifiableAddressStatusCompleted
+	if err := s.d.PrivilegedIdentityPool().UpdateVerifiableAddress(r.Context(), address); err != nil {
+		s.handleVerificationError(w, r, f, body, err)
+		return
+	}
+
+	email, phone := address.Value, ""
+	if address.Via == identity.VerifiableAddressTypeSMS {
+		email, phone = "", address.Value
+	}
+
+	f.Messages.Clear()
+	f.State = verification.StatePassedChallenge
+	if err := s.resetForm(r, f, email, phone); err != nil {
+		s.handleVerificationError(w, r, f, body, err)
+		return
+	}
+
+	if err := s.d.VerificationFlowPersister().UpdateVerificationFlow(r.Context(), f); err != nil {
+		s.handleVerificationError(w, r, f, body, err)
+		return
+	}
+	s.d.FlowStatsRecorder().Record(r.Context(), stats.FlowVerification, f.ID, f.Type, s.VerificationStrategyID(), stats.EventSucceeded)
+
+	// Proving control over an address lifts the quarantine of identities registered under suspicious conditions.
+	if err := s.d.PrivilegedIdentityPool().ReleaseIdentityFromQuarantine(r.Context(), address.IdentityID); err == nil {
+		s.d.Audit().
+			WithRequest(r).
+			WithField("identity_id", address.IdentityID).
+			Info("A quarantined identity was released because it verified an address.")
+	} else if !errors.Is(err, sqlcon.ErrNoRows) {
+		s.handleVerificationError(w, r, f, body, err)
+		return
+	}
+
+	s.writeFlow(w, r, f, body, s.c.SelfServiceFlowVerificationReturnTo(f.AppendTo(s.c.SelfServiceFlowVerificationUI())))
+}
+
+// writeFlow redirects browsers to the given URL and responds to API clients with the updated flow.
+func (s *Strategy) writeFlow(w http.ResponseWriter, r *http.Request, f *verification.Flow, body *completeSelfServiceVerificationFlowWithCodeMethodParameters, redirectTo *url.URL) {
+	if f.Type == flow.TypeBrowser {
+		http.Redirect(w, r, redirectTo.String(), http.StatusFound)
+		return
+	}
+
+	updatedFlow, err := s.d.VerificationFlowPersister().GetVerificationFlow(r.Context(), f.ID)
+	if err != nil {
+		s.handleVerificationError(w, r, f, body, err)
+		return
+	}
+
+	s.d.Writer().Write(w, r, updatedFlow)
+}
+
+func (s *Strategy) retryVerificationFlowWithMessage(w http.ResponseWriter, r *http.Request, ft flow.Type, message *text.Message) {
+	s.d.Logger().WithRequest(r).WithField("message", message).Debug("A verification flow is being retried because a validation error occurred.")
+
+	req, err := verification.NewFlow(s.d.Clock().Now(), s.c.SelfServiceFlowVerificationRequestLifespan(), s.d.GenerateCSRFToken(r), r, s.d.VerificationStrategies(), ft)
+	if err != nil {
+		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+
+	req.Messages.Add(message)
+	if err := s.d.VerificationFlowPersister().CreateVerificationFlow(r.Context(), req); err != nil {
+		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+
+	if ft == flow.TypeBrowser {
+		http.Redirect(w, r, req.AppendTo(s.c.SelfServiceFlowVerificationUI()).String(), http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, urlx.CopyWithQuery(urlx.AppendPaths(s.c.SelfPublicURL(),
+		verification.RouteGetFlow), url.Values{"id": {req.ID.String()}}).String(), http.StatusFound)
+}
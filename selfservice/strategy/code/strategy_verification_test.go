@@ -0,0 +1,227 @@
+package code_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/assertx"
+	"github.com/ory/x/pointerx"
+
+	"github.com/ory/kratos-client-go/models"
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+func TestVerification(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	clock := new(x.TestClock)
+	reg.WithClock(clock)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/default.schema.json")
+	conf.MustSet(config.ViperKeySelfServiceBrowserDefaultReturnTo, "https://www.ory.sh")
+	conf.MustSet(config.ViperKeySelfServiceStrategyConfig+"."+verification.StrategyVerificationCodeName+".enabled", true)
+	conf.MustSet(config.ViperKeySelfServiceVerificationEnabled, true)
+	conf.MustSet(config.ViperKeyCodeMaxAttempts, 2)
+
+	_ = testhelpers.NewVerificationUIFlowEchoServer(t, reg)
+	_ = testhelpers.NewErrorTestServer(t, reg)
+
+	public, _ := testhelpers.NewKratosServer(t, reg)
+
+	newIdentity := func(t *testing.T) (*identity.Identity, string) {
+		email := x.NewUUID().String() + "@ory.sh"
+		i := &identity.Identity{Traits: identity.Traits(`{"email":"` + email + `"}`), SchemaID: config.DefaultIdentityTraitsSchemaID}
+		require.NoError(t, reg.IdentityManager().Create(context.Background(), i, identity.ManagerAllowWriteProtectedTraits))
+		return i, email
+	}
+
+	sendCode := func(t *testing.T, isAPI bool, email string) string {
+		return testhelpers.SubmitVerificationForm(t, isAPI, testhelpers.NewDebugClient(t), public, func(v url.Values) {
+			v.Set("email", email)
+		}, verification.StrategyVerificationCodeName, http.StatusOK,
+			testhelpers.ExpectURL(isAPI, public.URL+code.RouteVerification, conf.SelfServiceFlowVerificationUI().String()))
+	}
+
+	expectCode := func(t *testing.T, email string) string {
+		message := testhelpers.CourierExpectMessage(t, reg, email, "Please verify your email address")
+		assert.Contains(t, message.Body, "please verify your account by entering the following code")
+
+		match := regexp.MustCompile(`[0-9]{6}`).FindString(message.Body)
+		require.NotEmpty(t, match, "%s", message.Body)
+		return match
+	}
+
+	submitCode := func(t *testing.T, isAPI bool, flow, email, verificationCode string, expectedStatusCode int) string {
+		values := url.Values{"csrf_token": {x.FakeCSRFToken}, "email": {email}, "code": {verificationCode}}
+		action := gjson.Get(flow, "methods.code.config.action").String()
+		require.NotEmpty(t, action, "%s", flow)
+
+		body, res := testhelpers.VerificationMakeRequest(t, isAPI, &models.VerificationFlowMethodConfig{Action: pointerx.String(action)},
+			testhelpers.NewDebugClient(t), testhelpers.EncodeFormAsJSON(t, isAPI, values))
+		assert.EqualValues(t, expectedStatusCode, res.StatusCode, "%s", body)
+		return body
+	}
+
+	t.Run("description=should ask for an email address", func(t *testing.T) {
+		rs := testhelpers.GetVerificationFlow(t, testhelpers.NewClientWithCookies(t), public)
+		require.Contains(t, rs.Payload.Methods, verification.StrategyVerificationCodeName)
+		method := rs.Payload.Methods[verification.StrategyVerificationCodeName]
+
+		assert.EqualValues(t, public.URL+code.RouteVerification+"?flow="+string(rs.Payload.ID), *method.Config.Action)
+		assert.EqualValues(t, models.FormFields{
+			{Name: pointerx.String("csrf_token"), Required: true, Type: pointerx.String("hidden"), Value: x.FakeCSRFToken},
+			{Name: pointerx.String("email"), Required: true, Type: pointerx.String("email")},
+		}, method.Config.Fields)
+	})
+
+	t.Run("description=should send an email to unknown addresses", func(t *testing.T) {
+		email := x.NewUUID().String() + "@ory.sh"
+		actual := sendCode(t, true, email)
+		assertx.EqualAsJSON(t, text.NewVerificationCodeSent(), json.RawMessage(gjson.Get(actual, "messages.0").Raw))
+
+		message := testhelpers.CourierExpectMessage(t, reg, email, "Someone tried to verify this email address")
+		assert.Contains(t, message.Body, "If this was you, check if you signed up using a different address.")
+	})
+
+	t.Run("description=should not ask for phone numbers without an SMS provider", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyCourierSMSRoutes, []map[string]interface{}{{"provider": "test", "countries": []string{"US"}}})
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyCourierSMSRoutes, nil)
+		})
+
+		rs := testhelpers.GetVerificationFlow(t, testhelpers.NewClientWithCookies(t), public)
+		assert.EqualValues(t, models.FormFields{
+			{Name: pointerx.String("csrf_token"), Required: true, Type: pointerx.String("hidden"), Value: x.FakeCSRFToken},
+			{Name: pointerx.String("email"), Required: true, Type: pointerx.String("email")},
+		}, rs.Payload.Methods[verification.StrategyVerificationCodeName].Config.Fields)
+	})
+
+	t.Run("description=should verify a phone number with a code sent by text message", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyCourierSMSRoutes, []map[string]interface{}{{"provider": "test", "countries": []string{"US"}}})
+		conf.MustSet(config.ViperKeyCourierSMSProviders, []map[string]interface{}{{"id": "test", "url": "https://sms.example.org/"}})
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyCourierSMSRoutes, nil)
+			conf.MustSet(config.ViperKeyCourierSMSProviders, nil)
+		})
+
+		i := &identity.Identity{Traits: identity.Traits(`{"email":"` + x.NewUUID().String() + `@ory.sh","phone":"+1 (650) 253-0000"}`), SchemaID: config.DefaultIdentityTraitsSchemaID}
+		require.NoError(t, reg.IdentityManager().Create(context.Background(), i, identity.ManagerAllowWriteProtectedTraits))
+
+		rs := testhelpers.GetVerificationFlow(t, testhelpers.NewClientWithCookies(t), public)
+		assert.EqualValues(t, models.FormFields{
+			{Name: pointerx.String("csrf_token"), Required: true, Type: pointerx.String("hidden"), Value: x.FakeCSRFToken},
+			{Name: pointerx.String("email"), Type: pointerx.String("email")},
+			{Name: pointerx.String("phone"), Type: pointerx.String("tel")},
+		}, rs.Payload.Methods[verification.StrategyVerificationCodeName].Config.Fields)
+
+		flow := testhelpers.SubmitVerificationForm(t, true, testhelpers.NewDebugClient(t), public, func(v url.Values) {
+			v.Del("email")
+			v.Set("phone", "+1 650 253 0000")
+		}, verification.StrategyVerificationCodeName, http.StatusOK, public.URL+code.RouteVerification)
+		assertx.EqualAsJSON(t, text.NewVerificationSMSCodeSent(), json.RawMessage(gjson.Get(flow, "messages.0").Raw))
+
+		message, err := reg.CourierPersister().LatestQueuedMessage(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, courier.MessageTypeSMS, message.Type)
+		assert.Equal(t, "+16502530000", message.Recipient)
+		assert.Equal(t, "test", message.Provider)
+		verificationCode := regexp.MustCompile(`[0-9]{6}`).FindString(message.Body)
+		require.NotEmpty(t, verificationCode, "%s", message.Body)
+
+		values := url.Values{"csrf_token": {x.FakeCSRFToken}, "phone": {"+16502530000"}, "code": {verificationCode}}
+		body, res := testhelpers.VerificationMakeRequest(t, true, &models.VerificationFlowMethodConfig{Action: pointerx.String(gjson.Get(flow, "methods.code.config.action").String())},
+			testhelpers.NewDebugClient(t), testhelpers.EncodeFormAsJSON(t, true, values))
+		require.EqualValues(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.EqualValues(t, verification.StatePassedChallenge, gjson.Get(body, "state").String(), "%s", body)
+		assert.EqualValues(t, "+16502530000", gjson.Get(body, "methods.code.config.fields.#(name==phone).value").String(), "%s", body)
+
+		address, err := reg.IdentityPool().FindVerifiableAddressByValue(context.Background(), identity.VerifiableAddressTypeSMS, "+16502530000")
+		require.NoError(t, err)
+		assert.True(t, address.Verified)
+	})
+
+	for _, tc := range []struct {
+		d     string
+		isAPI bool
+	}{{d: "type=browser"}, {d: "type=api", isAPI: true}} {
+		t.Run(tc.d, func(t *testing.T) {
+			t.Run("description=should verify an email address with the code", func(t *testing.T) {
+				i, email := newIdentity(t)
+
+				flow := sendCode(t, tc.isAPI, email)
+				assert.EqualValues(t, verification.StateEmailSent, gjson.Get(flow, "state").String(), "%s", flow)
+				assert.EqualValues(t, verification.StrategyVerificationCodeName, gjson.Get(flow, "active").String(), "%s", flow)
+				assert.True(t, gjson.Get(flow, "methods.code.config.fields.#(name==code)").Exists(), "%s", flow)
+				verificationCode := expectCode(t, email)
+
+				actual := submitCode(t, tc.isAPI, flow, email, verificationCode, http.StatusOK)
+				assert.EqualValues(t, verification.StatePassedChallenge, gjson.Get(actual, "state").String(), "%s", actual)
+
+				id, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), i.ID)
+				require.NoError(t, err)
+				require.Len(t, id.VerifiableAddresses, 1)
+				assert.True(t, id.VerifiableAddresses[0].Verified)
+				assert.EqualValues(t, identity.VerifiableAddressStatusCompleted, id.VerifiableAddresses[0].Status)
+			})
+
+			t.Run("description=should invalidate the code after too many incorrect attempts", func(t *testing.T) {
+				i, email := newIdentity(t)
+
+				flow := sendCode(t, tc.isAPI, email)
+				verificationCode := expectCode(t, email)
+				incorrect := "000000"
+				if verificationCode == incorrect {
+					incorrect = "111111"
+				}
+
+				status := testhelpers.ExpectStatusCode(tc.isAPI, http.StatusBadRequest, http.StatusOK)
+				actual := submitCode(t, tc.isAPI, flow, email, incorrect, status)
+				assert.EqualValues(t, text.NewErrorValidationVerificationCodeInvalid().Text,
+					gjson.Get(actual, "methods.code.config.fields.#(name==code).messages.0.text").String(), "%s", actual)
+				assert.EqualValues(t, email, gjson.Get(actual, "methods.code.config.fields.#(name==email).value").String(), "%s", actual)
+
+				submitCode(t, tc.isAPI, flow, email, incorrect, status)
+
+				actual = submitCode(t, tc.isAPI, flow, email, verificationCode, status)
+				assert.EqualValues(t, text.NewErrorValidationVerificationCodeInvalidOrExpired().Text,
+					gjson.Get(actual, "methods.code.config.fields.#(name==code).messages.0.text").String(), "%s", actual)
+
+				id, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), i.ID)
+				require.NoError(t, err)
+				assert.False(t, id.VerifiableAddresses[0].Verified)
+			})
+
+			t.Run("description=should not accept an expired code", func(t *testing.T) {
+				conf.MustSet(config.ViperKeyCodeLifespan, "1m")
+				t.Cleanup(func() {
+					conf.MustSet(config.ViperKeyCodeLifespan, "15m")
+				})
+
+				_, email := newIdentity(t)
+				flow := sendCode(t, tc.isAPI, email)
+				verificationCode := expectCode(t, email)
+				clock.Advance(2 * time.Minute)
+				t.Cleanup(clock.Reset)
+
+				actual := submitCode(t, tc.isAPI, flow, email, verificationCode, testhelpers.ExpectStatusCode(tc.isAPI, http.StatusBadRequest, http.StatusOK))
+				assert.EqualValues(t, text.NewErrorValidationVerificationCodeInvalidOrExpired().Text,
+					gjson.Get(actual, "methods.code.config.fields.#(name==code).messages.0.text").String(), "%s", actual)
+			})
+		})
+	}
+}
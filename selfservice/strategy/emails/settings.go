@@ -0,0 +1,408 @@
+package emails
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/markbates/pkger"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/pkgerx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/selfservice/strategy/link"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteSettings = "/self-service/settings/methods/emails"
+)
+
+var UnknownAddressValidationError = &jsonschema.ValidationError{
+	Message: "the email address does not belong to this account", InstancePtr: "#/"}
+var AddressExistsValidationError = &jsonschema.ValidationError{
+	Message: "the email address belongs to this account already", InstancePtr: "#/add"}
+var AddressVerifiedValidationError = &jsonschema.ValidationError{
+	Message: "the email address is verified already", InstancePtr: "#/"}
+var PrimaryAddressValidationError = &jsonschema.ValidationError{
+	Message: "the primary email address can not be removed", InstancePtr: "#/"}
+var UnverifiedPrimaryValidationError = &jsonschema.ValidationError{
+	Message: "only verified email addresses can become the primary email address", InstancePtr: "#/"}
+
+func (s *Strategy) RegisterSettingsRoutes(public *x.RouterPublic) {
+	s.d.CSRFHandler().IgnorePath(RouteSettings)
+
+	public.POST(RouteSettings, s.d.SessionHandler().IsAuthenticated(s.submitSettingsFlow, settings.OnUnauthenticated(s.c, s.d)))
+	public.GET(RouteSettings, s.d.SessionHandler().IsAuthenticated(s.submitSettingsFlow, settings.OnUnauthenticated(s.c, s.d)))
+}
+
+// nolint:deadcode,unused
+// swagger:parameters completeSelfServiceSettingsFlowWithEmailsMethod
+type completeSelfServiceSettingsFlowWithEmailsMethod struct {
+	// in: body
+	Body CompleteSelfServiceSettingsFlowWithEmailsMethod
+
+	// Flow is flow ID.
+	//
+	// in: query
+	Flow string `json:"flow"`
+}
+
+type CompleteSelfServiceSettingsFlowWithEmailsMethod struct {
+	// Add is an email address to add to the account. A verification message is sent to the address.
+	//
+	// Exactly one of `add`, `verify`, `remove`, and `primary` must be set.
+	Add string `json:"add"`
+
+	// Verify is an unverified email address of the account to send a new verification message to.
+	Verify string `json:"verify"`
+
+	// Remove is an email address to remove from the account. The primary email address can not be removed.
+	Remove string `json:"remove"`
+
+	// Primary is a verified email address which becomes the primary email address of the account.
+	Primary string `json:"primary"`
+
+	// CSRFToken is the anti-CSRF token
+	//
+	// type: string
+	CSRFToken string `json:"csrf_token"`
+
+	// Flow is flow ID.
+	//
+	// swagger:ignore
+	Flow string `json:"flow"`
+}
+
+func (p *CompleteSelfServiceSettingsFlowWithEmailsMethod) GetFlowID() uuid.UUID {
+	return x.ParseUUID(p.Flow)
+}
+
+func (p *CompleteSelfServiceSettingsFlowWithEmailsMethod) SetFlowID(rid uuid.UUID) {
+	p.Flow = rid.String()
+}
+
+// swagger:route POST /self-service/settings/methods/emails public completeSelfServiceSettingsFlowWithEmailsMethod
+//
+// Complete Settings Flow with Emails Method
+//
+// Use this endpoint to manage the email addresses of an identity. Each request either adds an address, sends a new
+// verification message to an unverified address, removes an address, or makes a verified address the primary
+// address. Adding and removing addresses requires a privileged session. This endpoint behaves differently for API
+// and browser flows.
+//
+// API-initiated flows expect `application/json` to be sent in the body and respond with
+//   - HTTP 200 and an application/json body with the session token on success;
+//   - HTTP 302 redirect to a fresh settings flow if the original flow expired with the appropriate error messages set;
+//   - HTTP 400 on form validation errors.
+//   - HTTP 401 when the endpoint is called without a valid session token.
+//   - HTTP 403 when the session is not privileged.
+//
+// Browser flows expect `application/x-www-form-urlencoded` to be sent in the body and responds with
+//   - a HTTP 302 redirect to the post/after settings URL or the `return_to` value if it was set and if the flow succeeded;
+//   - a HTTP 302 redirect to the Settings UI URL with the flow ID containing the validation errors otherwise.
+//   - a HTTP 302 redirect to the login endpoint when the session is not privileged.
+//
+// More information can be found at [ORY Kratos User Settings & Profile Management Documentation](../self-service/flows/user-settings).
+//
+//     Consumes:
+//     - application/json
+//     - application/x-www-form-urlencoded
+//
+//     Produces:
+//     - application/json
+//
+//     Security:
+//       sessionToken:
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: settingsViaApiResponse
+//       302: emptyResponse
+//       400: settingsFlow
+//       401: genericError
+//       403: genericError
+//       500: genericError
+func (s *Strategy) submitSettingsFlow(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var p CompleteSelfServiceSettingsFlowWithEmailsMethod
+	ctxUpdate, err := settings.PrepareUpdate(s.d, w, r, settings.ContinuityKey(s.SettingsStrategyID()), &p)
+	if errors.Is(err, settings.ErrContinuePreviousAction) {
+		s.continueSettingsFlow(w, r, ctxUpdate, &p)
+		return
+	} else if err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, &p, err)
+		return
+	}
+
+	if err := s.decodeSettingsFlow(r, &p); err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, &p, err)
+		return
+	}
+
+	// This does not come from the payload!
+	p.Flow = ctxUpdate.Flow.ID.String()
+	s.continueSettingsFlow(w, r, ctxUpdate, &p)
+}
+
+func (s *Strategy) decodeSettingsFlow(r *http.Request, dest interface{}) error {
+	compiler, err := decoderx.HTTPRawJSONSchemaCompiler(pkgerx.MustRead(pkger.Open("/selfservice/strategy/emails/.schema/settings.schema.json")))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return s.dc.Decode(r, dest, compiler,
+		decoderx.HTTPDecoderSetValidatePayloads(false),
+	)
+}
+
+func (s *Strategy) continueSettingsFlow(
+	w http.ResponseWriter, r *http.Request,
+	ctxUpdate *settings.UpdateContext, p *CompleteSelfServiceSettingsFlowWithEmailsMethod,
+) {
+	if err := flow.VerifyRequest(r, ctxUpdate.Flow.Type, s.c.DisableAPIFlowEnforcement(), s.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, p, err)
+		return
+	}
+
+	var actions int
+	for _, v := range []string{p.Add, p.Verify, p.Remove, p.Primary} {
+		if len(v) > 0 {
+			actions++
+		}
+	}
+
+	if actions == 0 {
+		s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(&jsonschema.ValidationError{
+			Message: "missing properties: add, verify, remove, primary", InstancePtr: "#/",
+			Context: &jsonschema.ValidationErrorContextRequired{Missing: []string{"add", "verify", "remove", "primary"}}}))
+		return
+	} else if actions > 1 {
+		s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(&jsonschema.ValidationError{
+			Message:     "it is not possible to change several email addresses in the same request",
+			InstancePtr: "#/",
+		}))
+		return
+	}
+
+	i, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), ctxUpdate.Session.Identity.ID)
+	if err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, p, err)
+		return
+	}
+
+	addresses := s.addresses(i)
+	var sendTo string
+	switch {
+	case len(p.Add) > 0:
+		value := strings.TrimSpace(p.Add)
+		if err := s.isAddable(r.Context(), i, addresses, value); err != nil {
+			s.handleSettingsError(w, r, ctxUpdate, p, err)
+			return
+		}
+
+		addresses = append(addresses, value)
+		sendTo = value
+	case len(p.Verify) > 0:
+		address := s.verifiableAddress(i, p.Verify)
+		if address == nil || indexOf(addresses, p.Verify) < 0 {
+			s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(UnknownAddressValidationError))
+			return
+		} else if address.Verified {
+			s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(AddressVerifiedValidationError))
+			return
+		}
+
+		sendTo = address.Value
+	case len(p.Remove) > 0:
+		k := indexOf(addresses, p.Remove)
+		if k < 0 {
+			s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(UnknownAddressValidationError))
+			return
+		} else if k == 0 {
+			s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(PrimaryAddressValidationError))
+			return
+		}
+
+		addresses = append(addresses[:k], addresses[k+1:]...)
+	case len(p.Primary) > 0:
+		k := indexOf(addresses, p.Primary)
+		if k < 0 {
+			s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(UnknownAddressValidationError))
+			return
+		} else if address := s.verifiableAddress(i, p.Primary); address == nil || !address.Verified {
+			s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(UnverifiedPrimaryValidationError))
+			return
+		}
+
+		primary := addresses[k]
+		addresses = append([]string{primary}, append(addresses[:k], addresses[k+1:]...)...)
+	}
+
+	traits, err := sjson.SetBytes(i.Traits, s.c.SelfServiceStrategyEmailsTrait(), addresses)
+	if err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, p, errors.WithStack(err))
+		return
+	}
+	i.Traits = identity.Traits(traits)
+
+	if err := s.d.SettingsHookExecutor().PostSettingsHook(w, r, s.SettingsStrategyID(), ctxUpdate, i, settings.WithCallback(func(ctxUpdate *settings.UpdateContext) error {
+		if len(sendTo) > 0 {
+			if err := s.sendVerification(r.Context(), ctxUpdate.Session.Identity, sendTo); err != nil {
+				return err
+			}
+		}
+		return s.PopulateSettingsMethod(r, ctxUpdate.Session.Identity, ctxUpdate.Flow)
+	})); err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, p, err)
+		return
+	}
+}
+
+// isAddable returns an error if the address is invalid, belongs to the identity already, or is used by another
+// identity as a verifiable address or as an identifier.
+func (s *Strategy) isAddable(ctx context.Context, i *identity.Identity, addresses []string, value string) error {
+	if !jsonschema.Formats["email"](value) {
+		return errors.WithStack(&jsonschema.ValidationError{
+			Message: fmt.Sprintf("%q is not valid %q", value, "email"), InstancePtr: "#/add"})
+	}
+
+	if indexOf(addresses, value) >= 0 {
+		return errors.WithStack(AddressExistsValidationError)
+	}
+
+	for _, candidate := range []string{value, strings.ToLower(value)} {
+		if address, err := s.d.PrivilegedIdentityPool().FindVerifiableAddressByValue(ctx, identity.VerifiableAddressTypeEmail, candidate); err == nil {
+			if address.IdentityID != i.ID {
+				return schema.NewDuplicateCredentialsError()
+			}
+		} else if !errors.Is(err, sqlcon.ErrNoRows) {
+			return err
+		}
+
+		if owner, _, err := s.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(ctx, identity.CredentialsTypePassword, candidate); err == nil {
+			if owner.ID != i.ID {
+				return schema.NewDuplicateCredentialsError()
+			}
+		} else if !errors.Is(err, herodot.ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sendVerification sends a verification message to the unverified address of the identity with the given value.
+func (s *Strategy) sendVerification(ctx context.Context, i *identity.Identity, value string) error {
+	address := s.verifiableAddress(i, value)
+	if address == nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf(
+			"The email address %s is not verifiable. Mark the email addresses as verifiable in the identity schema.", value))
+	} else if address.Verified {
+		return nil
+	}
+
+	token := link.NewVerificationToken(address, s.d.Clock().Now(), s.c.SelfServiceFlowVerificationRequestLifespan())
+	if err := s.d.VerificationTokenPersister().CreateVerificationToken(ctx, token); err != nil {
+		return err
+	}
+
+	return s.d.LinkSender().SendVerificationTokenTo(ctx, address, token)
+}
+
+// addresses returns the email addresses stored in the configured trait. The first address is the primary address.
+func (s *Strategy) addresses(i *identity.Identity) []string {
+	var addresses []string
+	for _, address := range gjson.GetBytes(i.Traits, s.c.SelfServiceStrategyEmailsTrait()).Array() {
+		addresses = append(addresses, address.String())
+	}
+	return addresses
+}
+
+func (s *Strategy) verifiableAddress(i *identity.Identity, value string) *identity.VerifiableAddress {
+	for k := range i.VerifiableAddresses {
+		address := &i.VerifiableAddresses[k]
+		if address.Via == identity.VerifiableAddressTypeEmail && strings.EqualFold(address.Value, value) {
+			return address
+		}
+	}
+	return nil
+}
+
+func indexOf(addresses []string, value string) int {
+	for k, address := range addresses {
+		if strings.EqualFold(address, value) {
+			return k
+		}
+	}
+	return -1
+}
+
+func (s *Strategy) PopulateSettingsMethod(r *http.Request, id *identity.Identity, f *settings.Flow) error {
+	i, err := s.d.PrivilegedIdentityPool().GetIdentity(r.Context(), id.ID)
+	if err != nil {
+		return err
+	}
+
+	hf := form.NewHTMLForm(urlx.CopyWithQuery(urlx.AppendPaths(s.c.SelfPublicURL(), RouteSettings),
+		url.Values{"flow": {f.ID.String()}}).String())
+	hf.SetCSRF(s.d.GenerateCSRFToken(r))
+	hf.SetField(form.Field{Name: "add", Type: "email"})
+
+	for k, value := range s.addresses(i) {
+		address := s.verifiableAddress(i, value)
+		verified := address != nil && address.Verified
+
+		if !verified {
+			hf.Fields = append(hf.Fields, form.Field{Name: "verify", Type: "submit", Value: value})
+		}
+		if k > 0 {
+			hf.Fields = append(hf.Fields, form.Field{Name: "remove", Type: "submit", Value: value})
+			if verified {
+				hf.Fields = append(hf.Fields, form.Field{Name: "primary", Type: "submit", Value: value})
+			}
+		}
+	}
+
+	f.Methods[s.SettingsStrategyID()] = &settings.FlowMethod{
+		Method: s.SettingsStrategyID(),
+		Config: &settings.FlowMethodConfig{FlowMethodConfigurator: &FlowMethod{HTMLForm: hf}},
+	}
+	return nil
+}
+
+func (s *Strategy) handleSettingsError(w http.ResponseWriter, r *http.Request, ctxUpdate *settings.UpdateContext, p *CompleteSelfServiceSettingsFlowWithEmailsMethod, err error) {
+	if e := new(settings.FlowNeedsReAuth); errors.As(err, &e) {
+		if err := s.d.ContinuityManager().Pause(r.Context(), w, r,
+			settings.ContinuityKey(s.SettingsStrategyID()), settings.ContinuityOptions(p, ctxUpdate.Session.Identity)...); err != nil {
+			s.d.SettingsFlowErrorHandler().WriteFlowError(w, r, s.SettingsStrategyID(), ctxUpdate.Flow, ctxUpdate.Session.Identity, err)
+			return
+		}
+	}
+
+	var id *identity.Identity
+	if ctxUpdate.Flow != nil {
+		ctxUpdate.Flow.Methods[s.SettingsStrategyID()].Config.ResetMessages()
+		ctxUpdate.Flow.Methods[s.SettingsStrategyID()].Config.SetValue("add", p.Add)
+		ctxUpdate.Flow.Methods[s.SettingsStrategyID()].Config.SetCSRF(s.d.GenerateCSRFToken(r))
+		id = ctxUpdate.Session.Identity
+	}
+
+	s.d.SettingsFlowErrorHandler().WriteFlowError(w, r, s.SettingsStrategyID(), ctxUpdate.Flow, id, err)
+}
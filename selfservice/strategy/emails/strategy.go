@@ -0,0 +1,70 @@
+package emails
+
+import (
+	"github.com/ory/x/decoderx"
+
+	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/selfservice/strategy/link"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	StrategyEmails = "emails"
+)
+
+var _ settings.Strategy = new(Strategy)
+
+type (
+	strategyDependencies interface {
+		x.CSRFProvider
+		x.CSRFTokenGeneratorProvider
+		x.WriterProvider
+		x.LoggingProvider
+		x.ClockProvider
+
+		continuity.ManagementProvider
+
+		session.HandlerProvider
+		session.ManagementProvider
+
+		identity.PoolProvider
+		identity.PrivilegedPoolProvider
+
+		errorx.ManagementProvider
+
+		settings.HookExecutorProvider
+		settings.ErrorHandlerProvider
+		settings.FlowPersistenceProvider
+
+		link.SenderProvider
+		link.VerificationTokenPersistenceProvider
+	}
+
+	// Strategy lets identities manage several email addresses stored in an array trait. Each address is verified
+	// on its own and may be used to sign in if the identity schema marks it as an identifier. The first address
+	// is the primary address.
+	Strategy struct {
+		c  *config.Provider
+		d  strategyDependencies
+		dc *decoderx.HTTP
+	}
+)
+
+// swagger:model settingsEmailsFormConfig
+type FlowMethod struct {
+	*form.HTMLForm
+}
+
+func NewStrategy(d strategyDependencies, c *config.Provider) *Strategy {
+	return &Strategy{c: c, d: d, dc: decoderx.NewHTTP()}
+}
+
+func (s *Strategy) SettingsStrategyID() string {
+	return StrategyEmails
+}
@@ -0,0 +1,212 @@
+package emails_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos-client-go/models"
+	"github.com/ory/x/assertx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/strategy/emails"
+	"github.com/ory/kratos/x"
+)
+
+func TestSettings(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+	conf.MustSet(config.ViperKeySelfServiceBrowserDefaultReturnTo, "https://www.ory.sh/")
+	testhelpers.StrategyEnable(t, conf, emails.StrategyEmails, true)
+
+	_ = testhelpers.NewSettingsUIEchoServer(t, reg)
+	_ = testhelpers.NewErrorTestServer(t, reg)
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+
+	newIdentity := func(addresses ...string) *identity.Identity {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		traits, err := json.Marshal(map[string]interface{}{"emails": addresses})
+		require.NoError(t, err)
+		i.Traits = identity.Traits(traits)
+		require.NoError(t, reg.IdentityManager().Create(context.Background(), i))
+		return i
+	}
+
+	apiIdentity := newIdentity("emails-api@ory.sh")
+	browserIdentity := newIdentity("emails-browser@ory.sh", "emails-browser-secondary@ory.sh")
+	apiUser := testhelpers.NewHTTPClientWithIdentitySessionToken(t, reg, apiIdentity)
+	browserUser := testhelpers.NewHTTPClientWithIdentitySessionCookie(t, reg, browserIdentity)
+
+	_ = newIdentity("emails-other@ory.sh")
+
+	fields := func(f *models.SettingsFlowMethodConfig) map[string][]interface{} {
+		values := map[string][]interface{}{}
+		for _, field := range f.Fields {
+			if *field.Name != "csrf_token" {
+				values[*field.Name] = append(values[*field.Name], field.Value)
+			}
+		}
+		return values
+	}
+
+	submitAPI := func(t *testing.T, payload string) (string, *http.Response) {
+		rs := testhelpers.InitializeSettingsFlowViaAPI(t, apiUser, publicTS)
+		f := testhelpers.GetSettingsFlowMethodConfig(t, rs.Payload, emails.StrategyEmails)
+		return testhelpers.SettingsMakeRequest(t, true, f, apiUser, payload)
+	}
+
+	traits := func(t *testing.T, id *identity.Identity) []string {
+		i, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), id.ID)
+		require.NoError(t, err)
+		var addresses []string
+		for _, a := range gjson.GetBytes(i.Traits, "emails").Array() {
+			addresses = append(addresses, a.String())
+		}
+		return addresses
+	}
+
+	verify := func(t *testing.T, value string) {
+		address, err := reg.PrivilegedIdentityPool().FindVerifiableAddressByValue(context.Background(), identity.VerifiableAddressTypeEmail, value)
+		require.NoError(t, err)
+		address.Verified = true
+		address.Status = identity.VerifiableAddressStatusCompleted
+		require.NoError(t, reg.PrivilegedIdentityPool().UpdateVerifiableAddress(context.Background(), address))
+	}
+
+	t.Run("case=shows the actions of each address", func(t *testing.T) {
+		verify(t, "emails-browser-secondary@ory.sh")
+
+		rs := testhelpers.InitializeSettingsFlowViaBrowser(t, browserUser, publicTS)
+		f := testhelpers.GetSettingsFlowMethodConfig(t, rs.Payload, emails.StrategyEmails)
+		assertx.EqualAsJSON(t, map[string][]interface{}{
+			"add":     {nil},
+			"verify":  {"emails-browser@ory.sh"},
+			"remove":  {"emails-browser-secondary@ory.sh"},
+			"primary": {"emails-browser-secondary@ory.sh"},
+		}, fields(f))
+	})
+
+	t.Run("action=add", func(t *testing.T) {
+		t.Run("case=adds the address and sends a verification message", func(t *testing.T) {
+			actual, res := submitAPI(t, `{"add":"emails-api-secondary@ory.sh"}`)
+			require.EqualValues(t, http.StatusOK, res.StatusCode, "%s", actual)
+			assert.EqualValues(t, "success", gjson.Get(actual, "flow.state").String(), "%s", actual)
+			assert.Equal(t, []string{"emails-api@ory.sh", "emails-api-secondary@ory.sh"}, traits(t, apiIdentity))
+
+			testhelpers.CourierExpectMessage(t, reg, "emails-api-secondary@ory.sh", "Please verify your email address")
+
+			i, _, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(context.Background(), identity.CredentialsTypePassword, "emails-api-secondary@ory.sh")
+			require.NoError(t, err, "the new address must be usable to sign in")
+			assert.Equal(t, apiIdentity.ID, i.ID)
+		})
+
+		for _, tc := range []struct {
+			name     string
+			payload  string
+			messages string
+		}{
+			{name: "belongs to another identity", payload: `{"add":"emails-other@ory.sh"}`, messages: "messages"},
+			{name: "belongs to another identity in a different case", payload: `{"add":"Emails-Other@ory.sh"}`, messages: "messages"},
+			{name: "belongs to the identity already", payload: `{"add":"emails-api@ory.sh"}`, messages: `fields.#(name=="add").messages`},
+			{name: "is not an email address", payload: `{"add":"not-an-email"}`, messages: `fields.#(name=="add").messages`},
+		} {
+			t.Run("case=rejects an address which "+tc.name, func(t *testing.T) {
+				actual, res := submitAPI(t, tc.payload)
+				require.EqualValues(t, http.StatusBadRequest, res.StatusCode, "%s", actual)
+				assert.NotEmpty(t, gjson.Get(actual, "methods.emails.config."+tc.messages+".0.text").String(), "%s", actual)
+				assert.Equal(t, []string{"emails-api@ory.sh", "emails-api-secondary@ory.sh"}, traits(t, apiIdentity))
+			})
+		}
+	})
+
+	t.Run("action=verify", func(t *testing.T) {
+		t.Run("case=sends a new verification message", func(t *testing.T) {
+			actual, res := submitAPI(t, `{"verify":"emails-api@ory.sh"}`)
+			require.EqualValues(t, http.StatusOK, res.StatusCode, "%s", actual)
+			testhelpers.CourierExpectMessage(t, reg, "emails-api@ory.sh", "Please verify your email address")
+		})
+
+		t.Run("case=rejects unknown addresses", func(t *testing.T) {
+			actual, res := submitAPI(t, `{"verify":"emails-other@ory.sh"}`)
+			require.EqualValues(t, http.StatusBadRequest, res.StatusCode, "%s", actual)
+		})
+	})
+
+	t.Run("action=primary", func(t *testing.T) {
+		t.Run("case=rejects unverified addresses", func(t *testing.T) {
+			actual, res := submitAPI(t, `{"primary":"emails-api-secondary@ory.sh"}`)
+			require.EqualValues(t, http.StatusBadRequest, res.StatusCode, "%s", actual)
+			assert.Contains(t, gjson.Get(actual, "methods.emails.config.messages.0.text").String(), "only verified", "%s", actual)
+		})
+
+		t.Run("case=makes a verified address the primary address", func(t *testing.T) {
+			verify(t, "emails-api-secondary@ory.sh")
+
+			actual, res := submitAPI(t, `{"primary":"emails-api-secondary@ory.sh"}`)
+			require.EqualValues(t, http.StatusOK, res.StatusCode, "%s", actual)
+			assert.Equal(t, []string{"emails-api-secondary@ory.sh", "emails-api@ory.sh"}, traits(t, apiIdentity))
+
+			address, err := reg.PrivilegedIdentityPool().FindVerifiableAddressByValue(context.Background(), identity.VerifiableAddressTypeEmail, "emails-api-secondary@ory.sh")
+			require.NoError(t, err)
+			assert.True(t, address.Verified, "reordering the addresses must keep their verification status")
+		})
+	})
+
+	t.Run("action=remove", func(t *testing.T) {
+		t.Run("case=rejects the primary address", func(t *testing.T) {
+			actual, res := submitAPI(t, `{"remove":"emails-api-secondary@ory.sh"}`)
+			require.EqualValues(t, http.StatusBadRequest, res.StatusCode, "%s", actual)
+		})
+
+		t.Run("case=removes the address", func(t *testing.T) {
+			actual, res := submitAPI(t, `{"remove":"emails-api@ory.sh"}`)
+			require.EqualValues(t, http.StatusOK, res.StatusCode, "%s", actual)
+			assert.Equal(t, []string{"emails-api-secondary@ory.sh"}, traits(t, apiIdentity))
+
+			_, _, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(context.Background(), identity.CredentialsTypePassword, "emails-api@ory.sh")
+			require.Error(t, err)
+		})
+
+		t.Run("type=browser", func(t *testing.T) {
+			rs := testhelpers.InitializeSettingsFlowViaBrowser(t, browserUser, publicTS)
+			f := testhelpers.GetSettingsFlowMethodConfig(t, rs.Payload, emails.StrategyEmails)
+
+			values := url.Values{"remove": {"emails-browser-secondary@ory.sh"}}
+			for _, field := range f.Fields {
+				if *field.Name == "csrf_token" {
+					values.Set("csrf_token", field.Value.(string))
+				}
+			}
+
+			actual, res := testhelpers.SettingsMakeRequest(t, false, f, browserUser, values.Encode())
+			require.EqualValues(t, http.StatusOK, res.StatusCode, "%s", actual)
+			assert.EqualValues(t, "success", gjson.Get(actual, "state").String(), "%s", actual)
+			assert.Equal(t, []string{"emails-browser@ory.sh"}, traits(t, browserIdentity))
+			assert.False(t, gjson.Get(actual, `methods.emails.config.fields.#(name=="remove")`).Exists(), "%s", actual)
+		})
+	})
+
+	t.Run("case=rejects several actions at once", func(t *testing.T) {
+		actual, res := submitAPI(t, `{"verify":"emails-api-secondary@ory.sh","remove":"emails-api-secondary@ory.sh"}`)
+		require.EqualValues(t, http.StatusBadRequest, res.StatusCode, "%s", actual)
+	})
+
+	t.Run("case=rejects invalid csrf tokens/type=browser", func(t *testing.T) {
+		rs := testhelpers.InitializeSettingsFlowViaBrowser(t, browserUser, publicTS)
+		f := testhelpers.GetSettingsFlowMethodConfig(t, rs.Payload, emails.StrategyEmails)
+
+		actual, res := testhelpers.SettingsMakeRequest(t, false, f, browserUser,
+			url.Values{"add": {"emails-browser-third@ory.sh"}, "csrf_token": {"invalid"}}.Encode())
+		assert.EqualValues(t, http.StatusOK, res.StatusCode)
+		assertx.EqualAsJSON(t, x.ErrInvalidCSRFToken, json.RawMessage(gjson.Get(actual, "0").Raw))
+	})
+}
@@ -0,0 +1,116 @@
+package kerberos
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1tools"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/x"
+)
+
+// TestRealm issues service tickets like a KDC would. It is exported for the tests of package kerberos_test.
+type TestRealm struct {
+	Realm   string
+	Service string
+	Keytab  []byte
+
+	kt *keytab.Keytab
+}
+
+// TestTicket describes the ticket and authenticator sent by a client.
+type TestTicket struct {
+	Client      string
+	ClientRealm string
+
+	// Service defaults to the realm's service.
+	Service string
+
+	EndTime time.Time
+	CTime   time.Time
+
+	// Raw sends a Kerberos token instead of wrapping it in SPNEGO.
+	Raw bool
+}
+
+const testKVNO = 3
+
+func NewTestRealm(t *testing.T, realm, service string) *TestRealm {
+	kt := keytab.New()
+	require.NoError(t, kt.AddEntry(service, realm, x.NewUUID().String(), time.Now(), testKVNO, etypeID.AES256_CTS_HMAC_SHA1_96))
+
+	b, err := kt.Marshal()
+	require.NoError(t, err)
+
+	return &TestRealm{Realm: realm, Service: service, Keytab: b, kt: kt}
+}
+
+// Negotiate returns the token for a `Negotiate` authorization header.
+func (r *TestRealm) Negotiate(t *testing.T, tt TestTicket) string {
+	return base64.StdEncoding.EncodeToString(r.token(t, tt))
+}
+
+func (r *TestRealm) token(t *testing.T, tt TestTicket) []byte {
+	now := time.Now().UTC().Truncate(time.Second)
+	if tt.ClientRealm == "" {
+		tt.ClientRealm = r.Realm
+	}
+	if tt.Service == "" {
+		tt.Service = r.Service
+	}
+	if tt.EndTime.IsZero() {
+		tt.EndTime = now.Add(time.Hour)
+	}
+
+	// Tickets for services which are not in the keytab are encrypted with the realm's service key.
+	kt := r.kt
+	if tt.Service != r.Service {
+		kt = keytab.New()
+		for _, e := range r.kt.Entries {
+			e.Principal.Components = types.NewPrincipalName(nametype.KRB_NT_SRV_INST, tt.Service).NameString
+			kt.Entries = append(kt.Entries, e)
+		}
+	}
+
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, tt.Client)
+	tkt, sessionKey, err := messages.NewTicket(cname, tt.ClientRealm,
+		types.NewPrincipalName(nametype.KRB_NT_SRV_INST, tt.Service), r.Realm,
+		types.NewKrbFlags(), kt, etypeID.AES256_CTS_HMAC_SHA1_96, testKVNO, now, now, tt.EndTime, tt.EndTime)
+	require.NoError(t, err)
+
+	auth, err := types.NewAuthenticator(tt.ClientRealm, cname)
+	require.NoError(t, err)
+	if !tt.CTime.IsZero() {
+		auth.CTime, auth.Cusec = tt.CTime.UTC().Truncate(time.Second), tt.CTime.Nanosecond()/int(time.Microsecond)
+	}
+
+	req, err := messages.NewAPReq(tkt, sessionKey, auth)
+	require.NoError(t, err)
+	b, err := req.Marshal()
+	require.NoError(t, err)
+
+	// The token ID prefixes the AP-REQ in a Kerberos GSS-API token (RFC 4121, Section 4.1).
+	oid, err := asn1.Marshal(gssapi.OIDKRB5.OID())
+	require.NoError(t, err)
+	token := asn1tools.AddASNAppTag(append(append(oid, 0x01, 0x00), b...), 0)
+	if tt.Raw {
+		return token
+	}
+
+	token, err = (&spnego.SPNEGOToken{Init: true, NegTokenInit: spnego.NegTokenInit{
+		MechTypes:      []asn1.ObjectIdentifier{gssapi.OIDKRB5.OID()},
+		MechTokenBytes: token,
+	}}).Marshal()
+	require.NoError(t, err)
+	return token
+}
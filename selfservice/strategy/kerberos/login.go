@@ -0,0 +1,267 @@
+package kerberos
+
+import (
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/markbates/pkger"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/pkgerx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteLogin = "/self-service/login/methods/kerberos"
+
+	schemeNegotiate = "Negotiate"
+)
+
+// fallback is shown by browsers which are not set up for desktop single sign-on and therefore do not respond to
+// the `Negotiate` challenge. It leads back to the login UI where the other methods are available.
+var fallback = template.Must(template.New("fallback").Parse(`<!DOCTYPE html>
+<html><head><meta http-equiv="refresh" content="0;url={{.}}"></head>
+<body><a href="{{.}}">Continue</a></body></html>
+`))
+
+func (s *Strategy) RegisterLoginRoutes(r *x.RouterPublic) {
+	s.d.CSRFHandler().IgnorePath(RouteLogin)
+
+	r.POST(RouteLogin, s.handleLogin)
+}
+
+func (s *Strategy) handleLoginError(w http.ResponseWriter, r *http.Request, rr *login.Flow, err error) {
+	if rr != nil {
+		if method, ok := rr.Methods[s.ID()]; ok {
+			method.Config.Reset()
+			if rr.Type == flow.TypeBrowser {
+				method.Config.SetCSRF(s.d.GenerateCSRFToken(r))
+			}
+
+			rr.Methods[s.ID()] = method
+		}
+	}
+
+	s.d.LoginFlowErrorHandler().WriteFlowError(w, r, s.ID(), rr, err)
+}
+
+// nolint:deadcode,unused
+// swagger:parameters completeSelfServiceLoginFlowWithKerberosMethod
+type completeSelfServiceLoginFlowWithKerberosMethodParameters struct {
+	// The Flow ID
+	//
+	// required: true
+	// in: query
+	Flow string `json:"flow"`
+
+	// The flow token returned when initializing an API flow. Required for API flows if
+	// `selfservice.api_flow_binding` is enabled.
+	//
+	// in: header
+	FlowToken string `json:"X-Kratos-Flow-Token"`
+
+	// The Kerberos ticket using the `Negotiate` scheme, for example `Negotiate YIIGhgYGKwYBBQUCoII...`.
+	//
+	// in: header
+	Authorization string `json:"Authorization"`
+
+	// in: body
+	Body CompleteSelfServiceLoginFlowWithKerberosMethod
+}
+
+// swagger:route POST /self-service/login/methods/kerberos public completeSelfServiceLoginFlowWithKerberosMethod
+//
+// Complete Login Flow with the Kerberos Method
+//
+// Use this endpoint to complete a login flow with desktop single sign-on. The Kerberos ticket is sent in the
+// `Authorization` header using the `Negotiate` scheme (SPNEGO) and is validated with the configured keytab. The
+// client principal is mapped to an identifier to find the identity. This endpoint behaves differently for API and
+// browser flows.
+//
+// API flows expect `application/json` to be sent in the body and responds with
+//   - HTTP 200 and a application/json body with the session token on success;
+//   - HTTP 302 redirect to a fresh login flow if the original flow expired with the appropriate error messages set;
+//   - HTTP 400 on form validation errors or if the ticket is missing or invalid.
+//
+// Browser flows expect `application/x-www-form-urlencoded` to be sent in the body and responds with
+//   - a HTTP 302 redirect to the post/after login URL or the `return_to` value if it was set and if the login succeeded;
+//   - a HTTP 401 `Negotiate` challenge if the ticket is missing. Browsers which are not set up for desktop
+//     single sign-on follow the response body back to the login UI;
+//   - a HTTP 302 redirect to the login UI URL with the flow ID containing the validation errors otherwise.
+//
+//     Schemes: http, https
+//
+//     Consumes:
+//     - application/json
+//     - application/x-www-form-urlencoded
+//
+//     Produces:
+//     - application/json
+//
+//     Responses:
+//       200: loginViaApiResponse
+//       302: emptyResponse
+//       400: loginFlow
+//       401: emptyResponse
+//       500: genericError
+func (s *Strategy) handleLogin(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	rid := x.ParseUUID(r.URL.Query().Get("flow"))
+	if x.IsZeroUUID(rid) {
+		s.handleLoginError(w, r, nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The flow query parameter is missing or invalid.")))
+		return
+	}
+
+	ar, err := s.d.LoginFlowPersister().GetLoginFlow(r.Context(), rid)
+	if err != nil {
+		s.handleLoginError(w, r, nil, err)
+		return
+	}
+
+	var p CompleteSelfServiceLoginFlowWithKerberosMethod
+	if err := s.hd.Decode(r, &p, decoderx.MustHTTPRawJSONSchemaCompiler(pkgerx.MustRead(
+		pkger.Open("/selfservice/strategy/kerberos/.schema/login.schema.json")))); err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	if err := flow.VerifyRequest(r, ar.Type, s.c.DisableAPIFlowEnforcement(), s.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	if err := flow.VerifyFlowToken(r, ar.Type, s.c.SelfServiceAPIFlowBinding(), ar.FlowTokenHash); err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	if _, err := s.d.SessionManager().FetchFromRequest(r.Context(), r); err == nil && !ar.Forced {
+		if ar.Type == flow.TypeBrowser {
+			http.Redirect(w, r, s.c.SelfServiceBrowserDefaultReturnTo().String(), http.StatusFound)
+			return
+		}
+
+		s.d.Writer().WriteError(w, r, errors.WithStack(login.ErrAlreadyLoggedIn))
+		return
+	}
+
+	if err := ar.Valid(s.d.Clock().Now(), s.c.SelfServiceFlowExpiredGracePeriod()); err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	token, ok := negotiateToken(r)
+	if !ok {
+		s.challenge(w, r, ar)
+		return
+	}
+
+	v, err := s.tickets()
+	if err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	principal, err := v.verify(r.Context(), token, s.d.Clock().Now())
+	if err != nil {
+		s.d.Logger().WithRequest(r).WithError(err).Info("Rejected a Kerberos ticket.")
+		s.handleLoginError(w, r, ar, schema.NewNegotiateUnavailableError())
+		return
+	}
+
+	c := s.c.SelfServiceStrategyKerberos()
+	identifier, err := s.identifier(c, principal)
+	if err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	i, _, err := s.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(r.Context(), identity.CredentialsType(c.IdentifierCredentialsType), identifier)
+	if errors.Is(err, herodot.ErrNotFound) {
+		s.d.Logger().WithRequest(r).WithField("principal", principal.String()).
+			Debug("The Kerberos ticket is valid but no identity matches the principal.")
+		s.handleLoginError(w, r, ar, schema.NewNegotiateUnavailableError())
+		return
+	} else if err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	if err := s.d.LoginHookExecutor().PostLoginHook(w, r, s.ID(), ar, i); err != nil {
+		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+}
+
+// negotiateToken returns the token of a `Negotiate` authorization header.
+func negotiateToken(r *http.Request) ([]byte, bool) {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], schemeNegotiate) {
+		return nil, false
+	}
+
+	token, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+	if err != nil || len(token) == 0 {
+		return nil, false
+	}
+	return token, true
+}
+
+// challenge asks the client for a Kerberos ticket. The flow is updated first so that clients which can not
+// respond to the challenge find an explanation in the login UI.
+func (s *Strategy) challenge(w http.ResponseWriter, r *http.Request, ar *login.Flow) {
+	w.Header().Set("WWW-Authenticate", schemeNegotiate)
+	if ar.Type == flow.TypeAPI {
+		s.handleLoginError(w, r, ar, schema.NewNegotiateUnavailableError())
+		return
+	}
+
+	method, ok := ar.Methods[s.ID()]
+	if !ok {
+		s.handleLoginError(w, r, ar, errors.WithStack(herodot.ErrInternalServerError.
+			WithErrorf(`Expected login method "%s" to exist in flow. This is a bug in the code and should be reported on GitHub.`, s.ID())))
+		return
+	}
+
+	method.Config.Reset()
+	method.Config.SetCSRF(s.d.GenerateCSRFToken(r))
+	if err := method.Config.ParseError(schema.NewNegotiateUnavailableError()); err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	if err := s.d.LoginFlowPersister().UpdateLoginFlowMethod(r.Context(), ar.ID, s.ID(), method); err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusUnauthorized)
+	if err := fallback.Execute(w, ar.AppendTo(s.c.SelfServiceFlowLoginUI()).String()); err != nil {
+		s.d.Logger().WithRequest(r).WithError(err).Error("Unable to write the Kerberos fallback page.")
+	}
+}
+
+func (s *Strategy) PopulateLoginMethod(r *http.Request, sr *login.Flow) error {
+	f := &form.HTMLForm{
+		Action: sr.AppendTo(urlx.AppendPaths(s.c.SelfPublicURL(), RouteLogin)).String(),
+		Method: "POST",
+		Fields: form.Fields{}}
+	f.SetCSRF(s.d.GenerateCSRFToken(r))
+
+	sr.Methods[s.ID()] = &login.FlowMethod{
+		Method: s.ID(),
+		Config: &login.FlowMethodConfig{FlowMethodConfigurator: &FlowMethod{HTMLForm: f}}}
+	return nil
+}
@@ -0,0 +1,38 @@
+package kerberos
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrReplayed is returned if an authenticator was used before.
+var ErrReplayed = errors.New("kerberos: the authenticator was replayed")
+
+type (
+	// UsedAuthenticator records an accepted authenticator until it is outside of the allowed clock skew and
+	// would be rejected anyway (RFC 4120, Section 3.2.3).
+	UsedAuthenticator struct {
+		// ID is derived from the client principal and the time of the authenticator.
+		ID string `db:"id"`
+
+		ExpiresAt time.Time `db:"expires_at"`
+		CreatedAt time.Time `db:"created_at"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+
+	ReplayCachePersister interface {
+		// UseKerberosAuthenticator stores the authenticator and removes all authenticators which expired before
+		// now. It returns ErrReplayed if the authenticator was stored before.
+		UseKerberosAuthenticator(ctx context.Context, a *UsedAuthenticator, now time.Time) error
+	}
+
+	ReplayCachePersistenceProvider interface {
+		KerberosReplayCachePersister() ReplayCachePersister
+	}
+)
+
+func (UsedAuthenticator) TableName() string {
+	return "selfservice_kerberos_authenticators"
+}
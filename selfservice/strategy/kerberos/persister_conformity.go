@@ -0,0 +1,36 @@
+package kerberos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/x"
+)
+
+func TestPersister(p ReplayCachePersister) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+
+		t.Run("case=should reject a used authenticator", func(t *testing.T) {
+			id := x.NewUUID().String()
+			now := time.Now()
+			require.NoError(t, p.UseKerberosAuthenticator(ctx, &UsedAuthenticator{ID: id, ExpiresAt: now.Add(time.Minute)}, now))
+
+			err := p.UseKerberosAuthenticator(ctx, &UsedAuthenticator{ID: id, ExpiresAt: now.Add(time.Minute)}, now)
+			require.True(t, errors.Is(err, ErrReplayed), "%+v", err)
+
+			require.NoError(t, p.UseKerberosAuthenticator(ctx, &UsedAuthenticator{ID: x.NewUUID().String(), ExpiresAt: now.Add(time.Minute)}, now))
+		})
+
+		t.Run("case=should remove expired authenticators", func(t *testing.T) {
+			id := x.NewUUID().String()
+			now := time.Now()
+			require.NoError(t, p.UseKerberosAuthenticator(ctx, &UsedAuthenticator{ID: id, ExpiresAt: now.Add(-time.Minute)}, now))
+			require.NoError(t, p.UseKerberosAuthenticator(ctx, &UsedAuthenticator{ID: id, ExpiresAt: now.Add(time.Minute)}, now))
+		})
+	}
+}
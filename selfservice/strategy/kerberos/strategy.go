@@ -0,0 +1,114 @@
+package kerberos
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/fetcher"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+var _ login.Strategy = new(Strategy)
+
+const (
+	IdentifierFormatUsername  = "username"
+	IdentifierFormatPrincipal = "principal"
+)
+
+type dependencies interface {
+	errorx.ManagementProvider
+
+	x.LoggingProvider
+	x.WriterProvider
+	x.CSRFProvider
+	x.CSRFTokenGeneratorProvider
+	x.ClockProvider
+
+	identity.PrivilegedPoolProvider
+
+	session.ManagementProvider
+
+	ReplayCachePersistenceProvider
+
+	login.HookExecutorProvider
+	login.FlowPersistenceProvider
+	login.ErrorHandlerProvider
+}
+
+// Strategy implements desktop single sign-on by accepting Kerberos tickets sent with the `Negotiate` HTTP
+// authentication scheme (RFC 4559). Identities are found by mapping the client principal to an identifier of
+// another credentials type, for example the username of the password method.
+type Strategy struct {
+	c  *config.Provider
+	d  dependencies
+	f  *fetcher.Fetcher
+	hd *decoderx.HTTP
+
+	sync.Mutex
+	verifierConfig *config.KerberosConfig
+	verifier       *verifier
+}
+
+func NewStrategy(d dependencies, c *config.Provider) *Strategy {
+	return &Strategy{
+		c:  c,
+		d:  d,
+		f:  fetcher.NewFetcher(),
+		hd: decoderx.NewHTTP(),
+	}
+}
+
+func (s *Strategy) ID() identity.CredentialsType {
+	return identity.CredentialsTypeKerberos
+}
+
+// tickets returns the verifier for the current configuration. The keytab is loaded again when the configuration
+// changes.
+func (s *Strategy) tickets() (*verifier, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	c := s.c.SelfServiceStrategyKerberos()
+	if s.verifier != nil && reflect.DeepEqual(s.verifierConfig, c) {
+		return s.verifier, nil
+	}
+
+	if c.KeytabURL == "" {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The Kerberos method is enabled but no keytab is configured."))
+	}
+
+	keytab, err := s.f.Fetch(c.KeytabURL)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to fetch the Kerberos keytab.").WithDebug(err.Error()))
+	}
+
+	kt, err := parseKeytab(keytab.Bytes())
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the Kerberos keytab.").WithDebug(err.Error()))
+	}
+
+	s.verifierConfig, s.verifier = c, newVerifier(kt, c.ServicePrincipal, c.Realms, c.MaxClockSkew, s.d.KerberosReplayCachePersister())
+	return s.verifier, nil
+}
+
+// identifier maps the client principal to the identifier used to find the identity.
+func (s *Strategy) identifier(c *config.KerberosConfig, p *principal) (string, error) {
+	switch c.IdentifierFormat {
+	case IdentifierFormatUsername:
+		return strings.ToLower(p.Name), nil
+	case IdentifierFormatPrincipal:
+		return strings.ToLower(p.String()), nil
+	}
+	return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf(`The Kerberos identifier format "%s" is not supported.`, c.IdentifierFormat))
+}
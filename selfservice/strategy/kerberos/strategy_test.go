@@ -0,0 +1,140 @@
+package kerberos_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/strategy/kerberos"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+type negotiateTransport struct {
+	token func() string
+}
+
+func (t *negotiateTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Path == kerberos.RouteLogin && t.token != nil {
+		r.Header.Set("Authorization", "Negotiate "+t.token())
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestStrategy(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	realm := kerberos.NewTestRealm(t, "EXAMPLE.COM", "HTTP/kratos.example.com")
+	conf.MustSet(config.ViperKeySelfServiceStrategyConfig+"."+string(identity.CredentialsTypeKerberos), map[string]interface{}{
+		"enabled": true,
+		"config": map[string]interface{}{
+			"keytab_url":        "base64://" + base64.StdEncoding.EncodeToString(realm.Keytab),
+			"service_principal": "HTTP/kratos.example.com",
+			"realms":            []string{"EXAMPLE.COM"},
+		},
+	})
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+	_ = testhelpers.NewLoginUIFlowEchoServer(t, reg)
+	returnTS := testhelpers.NewRedirSessionEchoTS(t, reg)
+	_ = testhelpers.NewErrorTestServer(t, reg)
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	i.Traits = identity.Traits(`{"username":"alice"}`)
+	i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+		Type: identity.CredentialsTypePassword, Identifiers: []string{"alice"}, Config: []byte(`{}`),
+	})
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+	login := func(t *testing.T, isAPI bool, token func() string, expectedStatusCode int, expectedURL string) string {
+		var hc *http.Client
+		if !isAPI {
+			hc = testhelpers.NewClientWithCookies(t)
+			hc.Transport = &negotiateTransport{token: token}
+		} else {
+			hc = &http.Client{Transport: &negotiateTransport{token: token}}
+		}
+
+		return testhelpers.SubmitLoginForm(t, isAPI, hc, publicTS, func(v url.Values) {}, identity.CredentialsTypeKerberos, false, expectedStatusCode, expectedURL)
+	}
+
+	ticket := func(tt kerberos.TestTicket) func() string {
+		return func() string { return realm.Negotiate(t, tt) }
+	}
+
+	unavailable := func(t *testing.T, body string) {
+		assert.EqualValues(t, text.ErrorValidationLoginNegotiateUnavailable, gjson.Get(body, "methods.kerberos.config.messages.0.id").Int(), "%s", body)
+	}
+
+	t.Run("case=should sign in with a valid ticket", func(t *testing.T) {
+		body := login(t, true, ticket(kerberos.TestTicket{Client: "alice"}), http.StatusOK, publicTS.URL)
+		assert.Equal(t, i.ID.String(), gjson.Get(body, "session.identity.id").String(), "%s", body)
+		assert.NotEmpty(t, gjson.Get(body, "session_token").String(), "%s", body)
+
+		body = login(t, false, ticket(kerberos.TestTicket{Client: "alice"}), http.StatusOK, returnTS.URL)
+		assert.Equal(t, i.ID.String(), gjson.Get(body, "identity.id").String(), "%s", body)
+	})
+
+	t.Run("case=should map the principal case-insensitively", func(t *testing.T) {
+		body := login(t, true, ticket(kerberos.TestTicket{Client: "Alice"}), http.StatusOK, publicTS.URL)
+		assert.Equal(t, i.ID.String(), gjson.Get(body, "session.identity.id").String(), "%s", body)
+	})
+
+	t.Run("case=should challenge API clients without a ticket", func(t *testing.T) {
+		unavailable(t, login(t, true, nil, http.StatusBadRequest, publicTS.URL))
+	})
+
+	t.Run("case=should challenge browsers and fall back to the login UI", func(t *testing.T) {
+		hc := testhelpers.NewClientWithCookies(t)
+		f := testhelpers.InitializeLoginFlowViaBrowser(t, hc, publicTS, false).Payload
+		method := testhelpers.GetLoginFlowMethodConfig(t, f, identity.CredentialsTypeKerberos.String())
+
+		res, err := hc.PostForm(*method.Action, testhelpers.SDKFormFieldsToURLValues(method.Fields))
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		assert.Equal(t, "Negotiate", res.Header.Get("WWW-Authenticate"))
+		body := x.MustReadAll(res.Body)
+		assert.Contains(t, string(body), conf.SelfServiceFlowLoginUI().String()+"?flow="+string(f.ID), "%s", body)
+
+		updated, err := reg.LoginFlowPersister().GetLoginFlow(context.Background(), x.ParseUUID(string(f.ID)))
+		require.NoError(t, err)
+		unavailable(t, x.MustEncodeJSON(t, updated))
+	})
+
+	t.Run("case=should reject invalid tickets", func(t *testing.T) {
+		other := kerberos.NewTestRealm(t, "EXAMPLE.COM", "HTTP/kratos.example.com")
+		for _, token := range []func() string{
+			func() string { return other.Negotiate(t, kerberos.TestTicket{Client: "alice"}) },
+			ticket(kerberos.TestTicket{Client: "alice", ClientRealm: "EVIL.COM"}),
+			func() string { return base64.StdEncoding.EncodeToString([]byte("NTLMSSP")) },
+		} {
+			unavailable(t, login(t, true, token, http.StatusBadRequest, publicTS.URL))
+		}
+	})
+
+	t.Run("case=should reject principals without identity", func(t *testing.T) {
+		unavailable(t, login(t, true, ticket(kerberos.TestTicket{Client: "bob"}), http.StatusBadRequest, publicTS.URL))
+	})
+
+	t.Run("case=should map the full principal", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyKerberosIdentifierFormat, kerberos.IdentifierFormatPrincipal)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyKerberosIdentifierFormat, kerberos.IdentifierFormatUsername)
+		})
+
+		unavailable(t, login(t, true, ticket(kerberos.TestTicket{Client: "alice"}), http.StatusBadRequest, publicTS.URL))
+	})
+}
@@ -0,0 +1,19 @@
+package kerberos
+
+import (
+	"github.com/ory/kratos/selfservice/form"
+)
+
+type (
+	// CompleteSelfServiceLoginFlowWithKerberosMethod is used to decode the login form payload. The Kerberos
+	// ticket is sent in the `Authorization` header.
+	CompleteSelfServiceLoginFlowWithKerberosMethod struct {
+		// Sending the anti-csrf token is only required for browser login flows.
+		CSRFToken string `form:"csrf_token" json:"csrf_token"`
+	}
+)
+
+// FlowMethod contains the configuration for this selfservice strategy.
+type FlowMethod struct {
+	*form.HTMLForm
+}
@@ -0,0 +1,132 @@
+package kerberos
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/pkg/errors"
+)
+
+// Tickets are decrypted and verified by gokrb5 (https://github.com/jcmturner/gokrb5). This file only adds the
+// checks which are specific to this strategy and records authenticators in the replay cache.
+
+// principal is an authenticated Kerberos client principal.
+type principal struct {
+	Name  string
+	Realm string
+}
+
+func (p *principal) String() string {
+	return p.Name + "@" + p.Realm
+}
+
+// verifier validates AP-REQ messages with the service keys from a keytab (RFC 4120, Section 3.2.3).
+type verifier struct {
+	keytab           *keytab.Keytab
+	servicePrincipal string
+	realms           []string
+	skew             time.Duration
+	replays          ReplayCachePersister
+}
+
+func newVerifier(kt *keytab.Keytab, servicePrincipal string, realms []string, skew time.Duration, replays ReplayCachePersister) *verifier {
+	return &verifier{
+		keytab:           kt,
+		servicePrincipal: servicePrincipal,
+		realms:           realms,
+		skew:             skew,
+		replays:          replays,
+	}
+}
+
+// parseKeytab parses a keytab in the format written by MIT Kerberos and Active Directory's ktpass.
+func parseKeytab(b []byte) (*keytab.Keytab, error) {
+	kt := keytab.New()
+	if err := kt.Unmarshal(b); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return kt, nil
+}
+
+// parseNegotiateToken extracts the AP-REQ from the token of a `Negotiate` authorization header (RFC 4559). It
+// accepts SPNEGO tokens carrying an optimistic Kerberos token as well as raw Kerberos tokens.
+func parseNegotiateToken(token []byte) (*messages.APReq, error) {
+	var negotiate spnego.SPNEGOToken
+	if err := negotiate.Unmarshal(token); err == nil {
+		init := negotiate.NegTokenInit
+		if !negotiate.Init || len(init.MechTypes) == 0 || !isKerberos(init.MechTypes[0]) || len(init.MechTokenBytes) == 0 {
+			return nil, errors.New("kerberos: the SPNEGO token does not contain a Kerberos token")
+		}
+		token = init.MechTokenBytes
+	}
+
+	var krb5 spnego.KRB5Token
+	if err := krb5.Unmarshal(token); err != nil {
+		return nil, errors.Wrap(err, "kerberos: unable to decode the token")
+	}
+	if !krb5.IsAPReq() {
+		return nil, errors.New("kerberos: the token does not contain an AP-REQ")
+	}
+	return &krb5.APReq, nil
+}
+
+func isKerberos(mech asn1.ObjectIdentifier) bool {
+	return mech.Equal(gssapi.OIDKRB5.OID()) || mech.Equal(gssapi.OIDMSLegacyKRB5.OID())
+}
+
+func (v *verifier) verify(ctx context.Context, token []byte, now time.Time) (*principal, error) {
+	req, err := parseNegotiateToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	sname := req.Ticket.SName.PrincipalNameString()
+	if v.servicePrincipal != "" && v.servicePrincipal != sname && v.servicePrincipal != sname+"@"+req.Ticket.Realm {
+		return nil, errors.Errorf("kerberos: the ticket was issued for service %s@%s", sname, req.Ticket.Realm)
+	}
+
+	// This is service.VerifyAPREQ without its replay cache, which is kept in memory and not shared between
+	// instances, and without decoding the PAC, which is not used.
+	ok, err := req.Verify(v.keytab, v.skew, types.HostAddress{}, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "kerberos: the ticket is invalid")
+	} else if !ok {
+		return nil, errors.New("kerberos: the ticket is invalid")
+	}
+
+	p := &principal{Name: req.Authenticator.CName.PrincipalNameString(), Realm: req.Authenticator.CRealm}
+	if !v.allowed(p.Realm) {
+		return nil, errors.Errorf("kerberos: the realm %s is not allowed", p.Realm)
+	}
+
+	ctime := req.Authenticator.CTime.Add(time.Duration(req.Authenticator.Cusec) * time.Microsecond)
+	id := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%d", p, sname, ctime.UnixNano())))
+	if err := v.replays.UseKerberosAuthenticator(ctx, &UsedAuthenticator{
+		ID:        hex.EncodeToString(id[:]),
+		ExpiresAt: ctime.Add(v.skew),
+	}, now); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (v *verifier) allowed(realm string) bool {
+	if len(v.realms) == 0 {
+		return true
+	}
+	for _, r := range v.realms {
+		if r == realm {
+			return true
+		}
+	}
+	return false
+}
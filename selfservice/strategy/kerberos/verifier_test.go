@@ -0,0 +1,123 @@
+package kerberos
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type replayCache struct {
+	sync.Mutex
+	seen map[string]bool
+}
+
+func (c *replayCache) UseKerberosAuthenticator(_ context.Context, a *UsedAuthenticator, _ time.Time) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.seen[a.ID] {
+		return errors.WithStack(ErrReplayed)
+	}
+	c.seen[a.ID] = true
+	return nil
+}
+
+func TestParseKeytab(t *testing.T) {
+	realm := NewTestRealm(t, "EXAMPLE.COM", "HTTP/kratos.example.com")
+	kt, err := parseKeytab(realm.Keytab)
+	require.NoError(t, err)
+	require.Len(t, kt.Entries, 1)
+	assert.Equal(t, []string{"HTTP", "kratos.example.com"}, kt.Entries[0].Principal.Components)
+	assert.EqualValues(t, testKVNO, kt.Entries[0].KVNO)
+
+	_, err = parseKeytab([]byte{0x05, 0x01})
+	require.Error(t, err)
+}
+
+func TestVerifier(t *testing.T) {
+	realm := NewTestRealm(t, "EXAMPLE.COM", "HTTP/kratos.example.com")
+	kt, err := parseKeytab(realm.Keytab)
+	require.NoError(t, err)
+
+	now := time.Now()
+	replays := &replayCache{seen: map[string]bool{}}
+	v := newVerifier(kt, "HTTP/kratos.example.com", []string{"EXAMPLE.COM", "CORP.EXAMPLE.COM"}, 5*time.Minute, replays)
+
+	for k, tc := range []struct {
+		ticket   TestTicket
+		expected string
+	}{
+		{ticket: TestTicket{Client: "alice"}, expected: "alice@EXAMPLE.COM"},
+		{ticket: TestTicket{Client: "alice", Raw: true}, expected: "alice@EXAMPLE.COM"},
+		{ticket: TestTicket{Client: "bob/admin", ClientRealm: "CORP.EXAMPLE.COM"}, expected: "bob/admin@CORP.EXAMPLE.COM"},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			p, err := v.verify(context.Background(), realm.token(t, tc.ticket), now)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, p.String())
+		})
+	}
+
+	for k, tc := range []struct {
+		d      string
+		ticket TestTicket
+		v      *verifier
+	}{
+		{d: "expired ticket", ticket: TestTicket{Client: "alice", EndTime: now.Add(-time.Hour)}},
+		{d: "old authenticator", ticket: TestTicket{Client: "alice", CTime: now.Add(-time.Hour)}},
+		{d: "other service", ticket: TestTicket{Client: "alice", Service: "HTTP/other.example.com"}},
+		{d: "unknown key", ticket: TestTicket{Client: "alice", Service: "HTTP/other.example.com"}, v: newVerifier(kt, "", nil, 5*time.Minute, replays)},
+		{d: "disallowed realm", ticket: TestTicket{Client: "mallory", ClientRealm: "EVIL.COM"}},
+	} {
+		t.Run(fmt.Sprintf("case=%d/description=%s", k, tc.d), func(t *testing.T) {
+			if tc.v == nil {
+				tc.v = v
+			}
+			_, err := tc.v.verify(context.Background(), realm.token(t, tc.ticket), now)
+			require.Error(t, err)
+		})
+	}
+
+	t.Run("case=wrong service key", func(t *testing.T) {
+		other := NewTestRealm(t, "EXAMPLE.COM", "HTTP/kratos.example.com")
+		_, err := v.verify(context.Background(), other.token(t, TestTicket{Client: "alice"}), now)
+		require.Error(t, err)
+	})
+
+	t.Run("case=replayed authenticator", func(t *testing.T) {
+		token := realm.token(t, TestTicket{Client: "carol"})
+		_, err := v.verify(context.Background(), token, now)
+		require.NoError(t, err)
+
+		_, err = v.verify(context.Background(), token, now)
+		require.Error(t, err)
+	})
+
+	t.Run("case=authenticator used on another instance", func(t *testing.T) {
+		ctime := time.Now()
+		token := realm.token(t, TestTicket{Client: "dave", CTime: ctime})
+		shared := &replayCache{seen: map[string]bool{}}
+		_, err := newVerifier(kt, "HTTP/kratos.example.com", nil, 5*time.Minute, shared).verify(context.Background(), token, now)
+		require.NoError(t, err)
+		require.Len(t, shared.seen, 1)
+
+		// Another ticket of the same client issued at the same time is indistinguishable from a replay.
+		replayed := &replayCache{seen: map[string]bool{}}
+		for id := range shared.seen {
+			replayed.seen[id] = true
+		}
+		_, err = newVerifier(kt, "HTTP/kratos.example.com", nil, 5*time.Minute, replayed).
+			verify(context.Background(), realm.token(t, TestTicket{Client: "dave", CTime: ctime}), now)
+		require.True(t, errors.Is(err, ErrReplayed), "%+v", err)
+	})
+
+	t.Run("case=garbage", func(t *testing.T) {
+		_, err := v.verify(context.Background(), []byte("NTLMSSP"), now)
+		require.Error(t, err)
+	})
+}
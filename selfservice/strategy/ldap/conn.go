@@ -0,0 +1,112 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+const (
+	// identifierPlaceholder is replaced with the escaped identifier in the configured search filter.
+	identifierPlaceholder = "{identifier}"
+
+	allUserAttributes = "*"
+)
+
+// entry is a directory entry returned by a search.
+type entry struct {
+	DN         string              `json:"dn"`
+	Attributes map[string][]string `json:"attributes"`
+}
+
+// conn is a connection to an LDAP server.
+type conn struct {
+	*ldap.Conn
+	timeout time.Duration
+
+	// broken is set once the connection is in an unknown state and must not be reused.
+	broken bool
+}
+
+func dial(c *config.LDAPConfig, tlsConfig *tls.Config) (*conn, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		return nil, errors.Errorf(`ldap: unsupported URL scheme "%s"`, u.Scheme)
+	}
+
+	l, err := ldap.DialURL(c.URL, ldap.DialWithDialer(&net.Dialer{Timeout: c.Timeout}), ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	l.SetTimeout(c.Timeout)
+
+	if c.StartTLS && u.Scheme == "ldap" {
+		if err := l.StartTLS(tlsConfig); err != nil {
+			l.Close()
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return &conn{Conn: l, timeout: c.Timeout}, nil
+}
+
+// usable returns false if err leaves the connection in an unknown state. LDAP result codes other than success
+// are answers of the server to a single operation and do not affect the connection.
+func usable(err error) bool {
+	var le *ldap.Error
+	return err == nil || (errors.As(err, &le) && le.ResultCode < ldap.ErrorNetwork)
+}
+
+// bind performs a simple bind. The empty DN and password perform an anonymous bind.
+func (c *conn) bind(dn, password string) error {
+	_, err := c.SimpleBind(&ldap.SimpleBindRequest{Username: dn, Password: password, AllowEmptyPassword: dn == ""})
+	if !usable(err) {
+		c.broken = true
+	}
+	return err
+}
+
+// search returns at most sizeLimit entries below base matching filter. A search exceeding the size limit is not
+// an error, callers see it from the number of entries returned. Referrals to other servers are not followed.
+func (c *conn) search(base, filter string, attributes []string, sizeLimit int) ([]entry, error) {
+	if len(attributes) == 0 {
+		attributes = []string{allUserAttributes}
+	}
+
+	result, err := c.Search(ldap.NewSearchRequest(base, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		sizeLimit, int(c.timeout/time.Second), false, filter, attributes, nil))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		if !usable(err) {
+			c.broken = true
+		}
+		return nil, err
+	}
+
+	entries := make([]entry, len(result.Entries))
+	for k, e := range result.Entries {
+		entries[k] = entry{DN: e.DN, Attributes: map[string][]string{}}
+		for _, a := range e.Attributes {
+			entries[k].Attributes[a.Name] = append(entries[k].Attributes[a.Name], a.Values...)
+		}
+	}
+	return entries, nil
+}
+
+// searchFilter replaces the identifier placeholder of the configured filter with the escaped identifier and
+// validates the result.
+func searchFilter(filter, identifier string) (string, error) {
+	filter = strings.Replace(filter, identifierPlaceholder, ldap.EscapeFilter(identifier), -1)
+	if _, err := ldap.CompileFilter(filter); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return filter, nil
+}
@@ -0,0 +1,115 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+func TestSearchFilter(t *testing.T) {
+	filter, err := searchFilter("(&(objectClass=person)(uid={identifier}))", "*)(uid=*\\\x00")
+	require.NoError(t, err)
+	assert.Equal(t, `(&(objectClass=person)(uid=\2a\29\28uid=\2a\5c\00))`, filter)
+
+	_, err = searchFilter("(uid={identifier}", "alice")
+	require.Error(t, err)
+}
+
+func TestConn(t *testing.T) {
+	s := NewTestServer(t, TestEntry{
+		DN:         "uid=alice,ou=people,dc=example,dc=com",
+		Password:   "secret",
+		Attributes: map[string][]string{"uid": {"alice"}, "mail": {"alice@example.com"}},
+	})
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(s.CACertificate))
+	tlsConfig := &tls.Config{ServerName: "127.0.0.1", RootCAs: roots}
+
+	conf := func() *config.LDAPConfig {
+		return &config.LDAPConfig{
+			URL:          s.URL,
+			BindDN:       s.ServiceDN,
+			BindPassword: s.ServicePassword,
+			SearchBase:   "ou=people,dc=example,dc=com",
+			PoolSize:     2,
+			Timeout:      time.Second,
+		}
+	}
+
+	for _, startTLS := range []bool{false, true} {
+		c := conf()
+		c.StartTLS = startTLS
+
+		l, err := dial(c, tlsConfig)
+		require.NoError(t, err)
+		_, isTLS := l.TLSConnectionState()
+		assert.Equal(t, startTLS, isTLS)
+
+		t.Run("case=denies searches without binding", func(t *testing.T) {
+			_, err := l.search(c.SearchBase, "(uid=alice)", nil, 2)
+			require.Error(t, err)
+			assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultInsufficientAccessRights))
+			assert.False(t, l.broken)
+		})
+
+		t.Run("case=rejects invalid credentials", func(t *testing.T) {
+			err := l.bind("uid=alice,ou=people,dc=example,dc=com", "wrong")
+			assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials))
+			assert.False(t, l.broken)
+		})
+
+		t.Run("case=searches entries", func(t *testing.T) {
+			require.NoError(t, l.bind(c.BindDN, c.BindPassword))
+
+			entries, err := l.search(c.SearchBase, "(&(uid=alice)(mail=*))", nil, 2)
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			assert.Equal(t, "uid=alice,ou=people,dc=example,dc=com", entries[0].DN)
+			assert.Equal(t, []string{"alice@example.com"}, entries[0].Attributes["mail"])
+
+			entries, err = l.search(c.SearchBase, "(uid=bob)", nil, 2)
+			require.NoError(t, err)
+			assert.Len(t, entries, 0)
+		})
+
+		l.Close()
+	}
+
+	t.Run("case=rejects untrusted certificates", func(t *testing.T) {
+		c := conf()
+		c.StartTLS = true
+		_, err := dial(c, &tls.Config{ServerName: "127.0.0.1"})
+		require.Error(t, err)
+	})
+
+	t.Run("case=pools connections", func(t *testing.T) {
+		p := newPool(conf(), tlsConfig)
+		dials := s.Dials()
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, p.do(func(c *conn) error {
+				_, err := c.search(p.c.SearchBase, "(uid=alice)", nil, 2)
+				return err
+			}))
+		}
+		assert.Equal(t, dials+1, s.Dials())
+
+		// The server drops the idle connection and the operation is retried on a new one.
+		s.CloseConnections()
+		require.NoError(t, p.do(func(c *conn) error {
+			return c.bind(p.c.BindDN, p.c.BindPassword)
+		}))
+		assert.Equal(t, dials+2, s.Dials())
+
+		p.close()
+		assert.Len(t, p.idle, 0)
+	})
+}
@@ -0,0 +1,261 @@
+package ldap
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEntry is a directory entry served by the TestServer.
+type TestEntry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// TestServer is a minimal LDAP server supporting simple binds, searches, and StartTLS. It is exported for the
+// tests of package ldap_test.
+type TestServer struct {
+	URL             string
+	ServiceDN       string
+	ServicePassword string
+	CACertificate   []byte
+
+	Entries []TestEntry
+
+	dials int32
+	tls   *tls.Config
+	l     net.Listener
+
+	sync.Mutex
+	conns []net.Conn
+}
+
+func NewTestServer(t *testing.T, entries ...TestEntry) *TestServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &TestServer{
+		URL:             "ldap://" + l.Addr().String(),
+		ServiceDN:       "cn=kratos,dc=example,dc=com",
+		ServicePassword: "service-secret",
+		CACertificate:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Entries:         entries,
+		tls: &tls.Config{Certificates: []tls.Certificate{{
+			Certificate: [][]byte{der},
+			PrivateKey:  key,
+		}}},
+		l: l,
+	}
+	t.Cleanup(func() {
+		_ = l.Close()
+		s.CloseConnections()
+	})
+
+	go func() {
+		for {
+			nc, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			atomic.AddInt32(&s.dials, 1)
+			s.Lock()
+			s.conns = append(s.conns, nc)
+			s.Unlock()
+			go s.serve(nc)
+		}
+	}()
+
+	return s
+}
+
+// Dials returns the number of connections accepted so far.
+func (s *TestServer) Dials() int {
+	return int(atomic.LoadInt32(&s.dials))
+}
+
+// CloseConnections closes all open connections like a server dropping idle connections would.
+func (s *TestServer) CloseConnections() {
+	s.Lock()
+	defer s.Unlock()
+	for _, nc := range s.conns {
+		_ = nc.Close()
+	}
+	s.conns = nil
+}
+
+func (s *TestServer) serve(nc net.Conn) {
+	defer nc.Close()
+
+	r := bufio.NewReader(nc)
+	var bound string
+	for {
+		message, err := ber.ReadPacket(r)
+		if err != nil || len(message.Children) < 2 {
+			return
+		}
+
+		id := message.Children[0].Value.(int64)
+		op := message.Children[1]
+		respond := func(tag ber.Tag, code int64) {
+			response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+			response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, ""))
+			response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+			response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+			_, _ = nc.Write(testMessage(id, response))
+		}
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn, password := op.Children[1].Data.String(), op.Children[2].Data.String()
+			code := int64(ldap.LDAPResultInvalidCredentials)
+			if (dn == "" && password == "") || (dn == s.ServiceDN && password == s.ServicePassword) {
+				code = ldap.LDAPResultSuccess
+			}
+			for _, e := range s.Entries {
+				if strings.EqualFold(e.DN, dn) && e.Password == password && password != "" {
+					code = ldap.LDAPResultSuccess
+				}
+			}
+			if code == ldap.LDAPResultSuccess {
+				bound = dn
+			}
+			respond(ldap.ApplicationBindResponse, code)
+		case ldap.ApplicationSearchRequest:
+			if bound != s.ServiceDN {
+				respond(ldap.ApplicationSearchResultDone, ldap.LDAPResultInsufficientAccessRights)
+				continue
+			}
+
+			base := strings.ToLower(op.Children[0].Data.String())
+			limit := op.Children[3].Value.(int64)
+			var matched int64
+			code := int64(ldap.LDAPResultSuccess)
+			for _, e := range s.Entries {
+				if !strings.HasSuffix(strings.ToLower(e.DN), base) || !testMatch(op.Children[6], e) {
+					continue
+				}
+				if matched++; limit > 0 && matched > limit {
+					code = ldap.LDAPResultSizeLimitExceeded
+					break
+				}
+				_, _ = nc.Write(testMessage(id, testEntry(e)))
+			}
+			respond(ldap.ApplicationSearchResultDone, code)
+		case ldap.ApplicationExtendedRequest:
+			if op.Children[0].Data.String() != "1.3.6.1.4.1.1466.20037" {
+				respond(ldap.ApplicationExtendedResponse, ldap.LDAPResultProtocolError)
+				continue
+			}
+			respond(ldap.ApplicationExtendedResponse, ldap.LDAPResultSuccess)
+
+			tc := tls.Server(nc, s.tls)
+			if err := tc.Handshake(); err != nil {
+				return
+			}
+			nc, r = tc, bufio.NewReader(tc)
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func testMessage(id int64, op *ber.Packet) []byte {
+	message := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	message.AppendChild(op)
+	return message.Bytes()
+}
+
+func testEntry(e TestEntry) *ber.Packet {
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	for name, values := range e.Attributes {
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, ""))
+		}
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+
+	entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "")
+	entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, ""))
+	entry.AppendChild(attributes)
+	return entry
+}
+
+// testMatch evaluates and, or, not, equality, and presence filters.
+func testMatch(filter *ber.Packet, e TestEntry) bool {
+	values := func(name string) []string {
+		for n, v := range e.Attributes {
+			if strings.EqualFold(n, name) {
+				return v
+			}
+		}
+		return nil
+	}
+
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, c := range filter.Children {
+			if !testMatch(c, e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, c := range filter.Children {
+			if testMatch(c, e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !testMatch(filter.Children[0], e)
+	case ldap.FilterEqualityMatch:
+		for _, v := range values(filter.Children[0].Data.String()) {
+			if strings.EqualFold(v, filter.Children[1].Data.String()) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(values(filter.Data.String())) > 0
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"sync"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+// pool keeps idle connections bound to the service account.
+type pool struct {
+	c    *config.LDAPConfig
+	tls  *tls.Config
+	idle chan *conn
+
+	sync.Mutex
+	closed bool
+}
+
+func newPool(c *config.LDAPConfig, tlsConfig *tls.Config) *pool {
+	return &pool{c: c, tls: tlsConfig, idle: make(chan *conn, c.PoolSize)}
+}
+
+func (p *pool) get() (c *conn, pooled bool, err error) {
+	select {
+	case c := <-p.idle:
+		if !c.IsClosing() {
+			return c, true, nil
+		}
+		c.Close()
+	default:
+	}
+
+	c, err = dial(p.c, p.tls)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := c.bind(p.c.BindDN, p.c.BindPassword); err != nil {
+		c.Close()
+		return nil, false, err
+	}
+
+	return c, false, nil
+}
+
+func (p *pool) put(c *conn) {
+	p.Lock()
+	defer p.Unlock()
+
+	if c.broken || p.closed {
+		c.Close()
+		return
+	}
+
+	select {
+	case p.idle <- c:
+	default:
+		c.Close()
+	}
+}
+
+// do passes a connection bound to the service account to f and returns it to the pool afterwards. f must leave
+// the connection bound to the service account. If a pooled connection turns out to be closed by the server, f
+// is retried with the next connection.
+func (p *pool) do(f func(c *conn) error) error {
+	c, pooled, err := p.get()
+	if err != nil {
+		return err
+	}
+
+	err = f(c)
+	if err != nil && c.broken && pooled {
+		c.Close()
+		return p.do(f)
+	}
+
+	p.put(c)
+	return err
+}
+
+func (p *pool) close() {
+	p.Lock()
+	defer p.Unlock()
+
+	p.closed = true
+	for {
+		select {
+		case c := <-p.idle:
+			c.Close()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package ldap
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/fetcher"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+var _ login.Strategy = new(Strategy)
+var _ identity.ActiveCredentialsCounter = new(Strategy)
+
+type dependencies interface {
+	errorx.ManagementProvider
+
+	x.LoggingProvider
+	x.WriterProvider
+	x.CSRFProvider
+	x.CSRFTokenGeneratorProvider
+	x.ClockProvider
+
+	identity.PrivilegedPoolProvider
+	identity.ManagementProvider
+
+	session.ManagementProvider
+
+	login.HookExecutorProvider
+	login.FlowPersistenceProvider
+	login.ErrorHandlerProvider
+}
+
+// Strategy verifies credentials by binding against an LDAP server, for example Active Directory.
+type Strategy struct {
+	c  *config.Provider
+	d  dependencies
+	f  *fetcher.Fetcher
+	hd *decoderx.HTTP
+
+	sync.Mutex
+	pool *pool
+}
+
+func NewStrategy(d dependencies, c *config.Provider) *Strategy {
+	return &Strategy{
+		c:  c,
+		d:  d,
+		f:  fetcher.NewFetcher(),
+		hd: decoderx.NewHTTP(),
+	}
+}
+
+func (s *Strategy) ID() identity.CredentialsType {
+	return identity.CredentialsTypeLDAP
+}
+
+func (s *Strategy) CountActiveCredentials(cc map[identity.CredentialsType]identity.Credentials) (count int, err error) {
+	for _, c := range cc {
+		if c.Type == s.ID() && len(c.Identifiers) > 0 && len(c.Identifiers[0]) > 0 {
+			count++
+		}
+	}
+	return
+}
+
+// connections returns the connection pool for the current configuration. The pool is replaced when the
+// configuration changes.
+func (s *Strategy) connections() (*pool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	c := s.c.SelfServiceStrategyLDAP()
+	if s.pool != nil && reflect.DeepEqual(s.pool.c, c) {
+		return s.pool, nil
+	}
+
+	if c.URL == "" || c.SearchBase == "" {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The LDAP method is enabled but the server URL or the search base is not configured."))
+	}
+
+	tlsConfig, err := s.tlsConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.pool != nil {
+		s.pool.close()
+	}
+	s.pool = newPool(c, tlsConfig)
+	return s.pool, nil
+}
+
+func (s *Strategy) tlsConfig(c *config.LDAPConfig) (*tls.Config, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The LDAP server URL is invalid.").WithDebug(err.Error()))
+	}
+
+	tlsConfig := &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	if c.CACertificateURL == "" {
+		return tlsConfig, nil
+	}
+
+	certificates, err := s.f.Fetch(c.CACertificateURL)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to fetch the LDAP CA certificates.").WithDebug(err.Error()))
+	}
+
+	tlsConfig.RootCAs = x509.NewCertPool()
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(certificates.Bytes()) {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The LDAP CA certificates do not contain a PEM encoded certificate."))
+	}
+	return tlsConfig, nil
+}
+
+// authenticate searches the user's entry with the service account and binds as the user to verify the password.
+func (s *Strategy) authenticate(identifier, password string) (*entry, error) {
+	if len(password) == 0 {
+		// A simple bind without a password is an unauthenticated bind which many servers accept (RFC 4513,
+		// Section 5.1.2).
+		return nil, errors.WithStack(schema.NewInvalidCredentialsError())
+	}
+
+	p, err := s.connections()
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := searchFilter(p.c.SearchFilter, identifier)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The LDAP search filter is invalid.").WithDebug(err.Error()))
+	}
+
+	var found *entry
+	var invalid bool
+	if err := p.do(func(c *conn) error {
+		found, invalid = nil, false
+
+		entries, err := c.search(p.c.SearchBase, filter, p.c.SearchAttributes, 2)
+		if err != nil {
+			return err
+		}
+
+		if len(entries) != 1 {
+			if len(entries) > 1 {
+				s.d.Logger().WithField("identifier", identifier).
+					Warn("The LDAP search filter matched more than one entry. Rejecting the login because the entry is ambiguous.")
+			}
+			invalid = true
+			return nil
+		}
+
+		if err := c.bind(entries[0].DN, password); ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			invalid = true
+		} else if err != nil {
+			return err
+		} else {
+			found = &entries[0]
+		}
+
+		// Return the connection to the pool bound to the service account.
+		if err := c.bind(p.c.BindDN, p.c.BindPassword); err != nil {
+			c.broken = true
+			return err
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to verify the credentials with the LDAP server.").WithDebug(err.Error()))
+	}
+
+	if invalid {
+		return nil, errors.WithStack(schema.NewInvalidCredentialsError())
+	}
+	return found, nil
+}
+
+// uid returns the credentials identifier linking the entry to the identity.
+func (s *Strategy) uid(c *config.LDAPConfig, e *entry) (string, error) {
+	if c.UniqueIDAttribute == "" {
+		return strings.ToLower(e.DN), nil
+	}
+
+	// Attribute descriptions are case-insensitive.
+	for name, values := range e.Attributes {
+		if strings.EqualFold(name, c.UniqueIDAttribute) && len(values) > 0 && len(values[0]) > 0 {
+			return values[0], nil
+		}
+	}
+
+	return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf(
+		`The LDAP entry does not have a value for the unique ID attribute "%s".`, c.UniqueIDAttribute))
+}
+
+// provision creates the identity of an entry signing in for the first time using the Jsonnet mapper.
+func (s *Strategy) provision(ctx context.Context, c *config.LDAPConfig, e *entry, uid string) (*identity.Identity, error) {
+	if c.MapperURL == "" {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("LDAP provisioning is enabled but no Jsonnet mapper is configured."))
+	}
+
+	jn, err := s.f.Fetch(c.MapperURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var jsonEntry bytes.Buffer
+	if err := json.NewEncoder(&jsonEntry).Encode(e); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtCode("entry", jsonEntry.String())
+	evaluated, err := vm.EvaluateSnippet(c.MapperURL, jn.String())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	s.d.Logger().
+		WithSensitiveField("ldap_entry", e).
+		WithField("mapper_jsonnet_output", evaluated).
+		WithField("mapper_jsonnet_url", c.MapperURL).
+		Debug("LDAP Jsonnet mapper completed.")
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	traits := gjson.Get(evaluated, "identity.traits")
+	if !traits.IsObject() {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The LDAP Jsonnet mapper did not return an object for key identity.traits. Please check your Jsonnet code!"))
+	}
+	i.Traits = []byte(traits.Raw)
+
+	if metadata := gjson.Get(evaluated, "identity.metadata_public"); metadata.IsObject() {
+		i.MetadataPublic = identity.Metadata(metadata.Raw)
+	}
+
+	creds, err := NewCredentials(uid, e.DN)
+	if err != nil {
+		return nil, err
+	}
+	i.SetCredentials(s.ID(), *creds)
+
+	if err := s.d.IdentityManager().Create(ctx, i); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
@@ -0,0 +1,120 @@
+package ldap_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/strategy/ldap"
+	"github.com/ory/kratos/text"
+)
+
+func TestStrategy(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	s := ldap.NewTestServer(t, ldap.TestEntry{
+		DN:         "uid=alice,ou=people,dc=example,dc=com",
+		Password:   "secret",
+		Attributes: map[string][]string{"uid": {"alice"}, "mail": {"alice@example.com"}, "entryUUID": {"3f6d8a1e-alice"}},
+	}, ldap.TestEntry{
+		DN:         "uid=bob,ou=people,dc=example,dc=com",
+		Password:   "secret",
+		Attributes: map[string][]string{"uid": {"bob"}, "entryUUID": {"7c2b9e4f-bob"}},
+	})
+
+	conf.MustSet(config.ViperKeySelfServiceStrategyConfig+"."+string(identity.CredentialsTypeLDAP), map[string]interface{}{
+		"enabled": true,
+		"config": map[string]interface{}{
+			"url":                 s.URL,
+			"start_tls":           true,
+			"ca_certificate_url":  "base64://" + base64.StdEncoding.EncodeToString(s.CACertificate),
+			"bind_dn":             s.ServiceDN,
+			"bind_password":       s.ServicePassword,
+			"search":              map[string]interface{}{"base": "ou=people,dc=example,dc=com"},
+			"unique_id_attribute": "entryUUID",
+			"provisioning":        map[string]interface{}{"enabled": true, "mapper_url": "file://./stub/ldap.jsonnet"},
+		},
+	})
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+	_ = testhelpers.NewLoginUIFlowEchoServer(t, reg)
+	returnTS := testhelpers.NewRedirSessionEchoTS(t, reg)
+	_ = testhelpers.NewErrorTestServer(t, reg)
+
+	login := func(t *testing.T, isAPI bool, identifier, password string, expectedStatusCode int, expectedURL string) string {
+		return testhelpers.SubmitLoginForm(t, isAPI, nil, publicTS, func(v url.Values) {
+			v.Set("identifier", identifier)
+			v.Set("password", password)
+		}, identity.CredentialsTypeLDAP, false, expectedStatusCode, expectedURL)
+	}
+
+	invalid := func(t *testing.T, body string) {
+		assert.Equal(t, text.NewErrorValidationInvalidCredentials().Text, gjson.Get(body, "methods.ldap.config.messages.0.text").String(), "%s", body)
+	}
+
+	t.Run("case=should reject an invalid password", func(t *testing.T) {
+		body := login(t, true, "alice", "wrong", http.StatusBadRequest, publicTS.URL)
+		invalid(t, body)
+		assert.Equal(t, "alice", gjson.Get(body, `methods.ldap.config.fields.#(name=="identifier").value`).String(), "%s", body)
+	})
+
+	t.Run("case=should reject an unknown user", func(t *testing.T) {
+		invalid(t, login(t, true, "mallory", "secret", http.StatusBadRequest, publicTS.URL))
+	})
+
+	t.Run("case=should escape the identifier in the search filter", func(t *testing.T) {
+		invalid(t, login(t, true, "*", "secret", http.StatusBadRequest, publicTS.URL))
+	})
+
+	var id string
+	t.Run("case=should provision the identity on the first login", func(t *testing.T) {
+		body := login(t, true, "alice", "secret", http.StatusOK, publicTS.URL)
+		id = gjson.Get(body, "session.identity.id").String()
+		require.NotEmpty(t, id, "%s", body)
+		assert.Equal(t, "alice", gjson.Get(body, "session.identity.traits.username").String(), "%s", body)
+		assert.Equal(t, "alice@example.com", gjson.Get(body, "session.identity.traits.email").String(), "%s", body)
+
+		i, c, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(context.Background(), identity.CredentialsTypeLDAP, "3f6d8a1e-alice")
+		require.NoError(t, err)
+		assert.Equal(t, id, i.ID.String())
+		assert.Equal(t, "uid=alice,ou=people,dc=example,dc=com", gjson.GetBytes(c.Config, "dn").String())
+	})
+
+	t.Run("case=should sign in the provisioned identity", func(t *testing.T) {
+		body := login(t, false, "alice", "secret", http.StatusOK, returnTS.URL)
+		assert.Equal(t, id, gjson.Get(body, "identity.id").String(), "%s", body)
+
+		body = login(t, true, "alice", "secret", http.StatusOK, publicTS.URL)
+		assert.Equal(t, id, gjson.Get(body, "session.identity.id").String(), "%s", body)
+	})
+
+	t.Run("case=should not provision identities if provisioning is disabled", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyLDAPProvisioningEnabled, false)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyLDAPProvisioningEnabled, true)
+		})
+
+		invalid(t, login(t, true, "bob", "secret", http.StatusBadRequest, publicTS.URL))
+		login(t, true, "alice", "secret", http.StatusOK, publicTS.URL)
+	})
+
+	t.Run("case=should reuse pooled connections", func(t *testing.T) {
+		login(t, true, "alice", "secret", http.StatusOK, publicTS.URL)
+
+		dials := s.Dials()
+		login(t, true, "alice", "secret", http.StatusOK, publicTS.URL)
+		login(t, true, "alice", "secret", http.StatusOK, publicTS.URL)
+		assert.Equal(t, dials, s.Dials())
+	})
+}
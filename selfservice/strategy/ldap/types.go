@@ -0,0 +1,51 @@
+package ldap
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	// CredentialsConfig is the struct that is being used as part of the identity credentials.
+	CredentialsConfig struct {
+		// DN is the distinguished name of the directory entry at the time of the last login.
+		DN string `json:"dn"`
+	}
+
+	// CompleteSelfServiceLoginFlowWithLDAPMethod is used to decode the login form payload.
+	CompleteSelfServiceLoginFlowWithLDAPMethod struct {
+		// The user's directory password.
+		Password string `form:"password" json:"password,omitempty"`
+
+		// Identifier is the username of the user in the directory.
+		Identifier string `form:"identifier" json:"identifier,omitempty"`
+
+		// Sending the anti-csrf token is only required for browser login flows.
+		CSRFToken string `form:"csrf_token" json:"csrf_token"`
+	}
+)
+
+// FlowMethod contains the configuration for this selfservice strategy.
+type FlowMethod struct {
+	*form.HTMLForm
+}
+
+func NewCredentials(uid, dn string) (*identity.Credentials, error) {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(CredentialsConfig{DN: dn}); err != nil {
+		return nil, errors.WithStack(x.PseudoPanic.
+			WithDebugf("Unable to encode LDAP credentials to JSON: %s", err))
+	}
+
+	return &identity.Credentials{
+		Type:        identity.CredentialsTypeLDAP,
+		Identifiers: []string{uid},
+		Config:      b.Bytes(),
+	}, nil
+}
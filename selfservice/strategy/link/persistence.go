@@ -2,6 +2,8 @@ package link
 
 import (
 	"context"
+
+	"github.com/gofrs/uuid"
 )
 
 type (
@@ -9,6 +11,9 @@ type (
 		CreateRecoveryToken(ctx context.Context, token *RecoveryToken) error
 		UseRecoveryToken(ctx context.Context, token string) (*RecoveryToken, error)
 		DeleteRecoveryToken(ctx context.Context, token string) error
+
+		// InvalidateRecoveryTokens marks all outstanding recovery tokens of an identity as used.
+		InvalidateRecoveryTokens(ctx context.Context, identityID uuid.UUID) error
 	}
 
 	RecoveryTokenPersistenceProvider interface {
@@ -19,6 +24,9 @@ type (
 		CreateVerificationToken(ctx context.Context, token *VerificationToken) error
 		UseVerificationToken(ctx context.Context, token string) (*VerificationToken, error)
 		DeleteVerificationToken(ctx context.Context, token string) error
+
+		// InvalidateVerificationTokens marks all outstanding verification tokens of an identity as used.
+		InvalidateVerificationTokens(ctx context.Context, identityID uuid.UUID) error
 	}
 
 	VerificationTokenPersistenceProvider interface {
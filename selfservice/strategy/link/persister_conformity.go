@@ -82,6 +82,20 @@ func TestPersister(conf *config.Provider, p interface {
 				require.Error(t, err)
 			})
 
+			t.Run("case=should invalidate all recovery tokens of an identity", func(t *testing.T) {
+				invalidated := newRecoveryToken(t, "invalidate-user@ory.sh")
+				require.NoError(t, p.CreateRecoveryToken(context.Background(), invalidated))
+				other := newRecoveryToken(t, "not-invalidated-user@ory.sh")
+				require.NoError(t, p.CreateRecoveryToken(context.Background(), other))
+
+				require.NoError(t, p.InvalidateRecoveryTokens(context.Background(), invalidated.RecoveryAddress.IdentityID))
+
+				_, err := p.UseRecoveryToken(context.Background(), invalidated.Token)
+				require.Error(t, err)
+				_, err = p.UseRecoveryToken(context.Background(), other.Token)
+				require.NoError(t, err)
+			})
+
 		})
 		t.Run("token=verification", func(t *testing.T) {
 
@@ -136,6 +150,20 @@ func TestPersister(conf *config.Provider, p interface {
 				_, err = p.UseVerificationToken(context.Background(), expected.Token)
 				require.Error(t, err)
 			})
+
+			t.Run("case=should invalidate all verification tokens of an identity", func(t *testing.T) {
+				invalidated := newVerificationToken(t, "invalidate-user@ory.sh")
+				require.NoError(t, p.CreateVerificationToken(context.Background(), invalidated))
+				other := newVerificationToken(t, "not-invalidated-user@ory.sh")
+				require.NoError(t, p.CreateVerificationToken(context.Background(), other))
+
+				require.NoError(t, p.InvalidateVerificationTokens(context.Background(), invalidated.VerifiableAddress.IdentityID))
+
+				_, err := p.UseVerificationToken(context.Background(), invalidated.Token)
+				require.Error(t, err)
+				_, err = p.UseVerificationToken(context.Background(), other.Token)
+				require.NoError(t, err)
+			})
 		})
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/url"
 
+	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 
 	"github.com/ory/x/errorsx"
@@ -118,10 +119,16 @@ func (s *Sender) SendRecoveryTokenTo(ctx context.Context, address *identity.Reco
 		WithSensitiveField("email_address", address.Value).
 		WithSensitiveField("recovery_link_token", token.Token).
 		Info("Sending out recovery email with recovery link.")
+
+	model, err := s.identityModel(ctx, templates.TypeRecoveryValid, address.IdentityID)
+	if err != nil {
+		return err
+	}
+
 	return s.send(ctx, string(address.Via), templates.NewRecoveryValid(s.c,
 		&templates.RecoveryValidModel{To: address.Value, RecoveryURL: urlx.CopyWithQuery(
 			urlx.AppendPaths(s.c.SelfPublicURL(), RouteRecovery),
-			url.Values{"token": {token.Token}}).String()}))
+			url.Values{"token": {token.Token}}).String(), Identity: model}))
 }
 
 func (s *Sender) SendVerificationTokenTo(ctx context.Context, address *identity.VerifiableAddress, token *VerificationToken) error {
@@ -133,10 +140,30 @@ func (s *Sender) SendVerificationTokenTo(ctx context.Context, address *identity.
 		WithSensitiveField("verification_link_token", token.Token).
 		Info("Sending out verification email with verification link.")
 
+	model, err := s.identityModel(ctx, templates.TypeVerificationValid, address.IdentityID)
+	if err != nil {
+		return err
+	}
+
 	return s.send(ctx, string(address.Via), templates.NewVerificationValid(s.c,
 		&templates.VerificationValidModel{To: address.Value, VerificationURL: urlx.CopyWithQuery(
 			urlx.AppendPaths(s.c.SelfPublicURL(), RouteVerification),
-			url.Values{"token": {token.Token}}).String()}))
+			url.Values{"token": {token.Token}}).String(), Identity: model}))
+}
+
+// identityModel returns the identity fields the template may use. The identity is only loaded if the template
+// may use any of its fields.
+func (s *Sender) identityModel(ctx context.Context, template string, id uuid.UUID) (map[string]interface{}, error) {
+	allowed := s.c.CourierTemplateVariables(template)
+	if len(allowed) == 0 {
+		return nil, nil
+	}
+
+	i, err := s.r.IdentityPool().GetIdentity(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return templates.NewIdentityModel(allowed, i)
 }
 
 func (s *Sender) send(ctx context.Context, via string, t courier.EmailTemplate) error {
@@ -2,13 +2,17 @@ package link_test
 
 import (
 	"context"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/kratos/courier/template"
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
@@ -32,7 +36,7 @@ func TestManager(t *testing.T) {
 	require.NoError(t, reg.IdentityManager().Create(context.Background(), i))
 
 	t.Run("method=SendRecoveryLink", func(t *testing.T) {
-		f, err := recovery.NewFlow(time.Hour, "", u, reg.RecoveryStrategies(), flow.TypeBrowser)
+		f, err := recovery.NewFlow(time.Now(), time.Hour, "", u, reg.RecoveryStrategies(), flow.TypeBrowser)
 		require.NoError(t, err)
 
 		require.NoError(t, reg.RecoveryFlowPersister().CreateRecoveryFlow(context.Background(), f))
@@ -54,7 +58,7 @@ func TestManager(t *testing.T) {
 	})
 
 	t.Run("method=SendVerificationLink", func(t *testing.T) {
-		f, err := verification.NewFlow(time.Hour, "", u, reg.VerificationStrategies(), flow.TypeBrowser)
+		f, err := verification.NewFlow(time.Now(), time.Hour, "", u, reg.VerificationStrategies(), flow.TypeBrowser)
 		require.NoError(t, err)
 
 		require.NoError(t, reg.VerificationFlowPersister().CreateVerificationFlow(context.Background(), f))
@@ -75,3 +79,35 @@ func TestManager(t *testing.T) {
 		assert.NotContains(t, messages[3].Body, urlx.AppendPaths(conf.SelfPublicURL(), link.RouteVerification).String()+"?token=")
 	})
 }
+
+func TestSenderTemplateVariables(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/default.schema.json")
+	conf.MustSet(config.ViperKeyPublicBaseURL, "https://www.ory.sh/")
+	conf.MustSet(config.ViperKeyCourierSMTPURL, "smtp://foo@bar@dev.null/")
+
+	root, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "recovery", "valid"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "recovery", "valid", "email.subject.gotmpl"), []byte("Recover"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "recovery", "valid", "email.body.gotmpl"),
+		[]byte(`id={{ .Identity.id }} email={{ .Identity.traits.email }}`), 0600))
+	conf.MustSet(config.ViperKeyCourierTemplatesPath, root)
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	i.Traits = identity.Traits(`{"email": "variables@ory.sh"}`)
+	require.NoError(t, reg.IdentityManager().Create(context.Background(), i))
+
+	f, err := recovery.NewFlow(time.Now(), time.Hour, "", &http.Request{URL: urlx.ParseOrPanic("https://www.ory.sh/")}, reg.RecoveryStrategies(), flow.TypeBrowser)
+	require.NoError(t, err)
+	require.NoError(t, reg.RecoveryFlowPersister().CreateRecoveryFlow(context.Background(), f))
+
+	conf.MustSet(config.ViperKeyCourierTemplateVariables+"."+template.TypeRecoveryValid, []string{"traits.email"})
+	require.NoError(t, reg.LinkSender().SendRecoveryLink(context.Background(), f, "email", "variables@ory.sh"))
+
+	messages, err := reg.CourierPersister().NextMessages(context.Background(), 12)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "id=<no value> email=variables@ory.sh", messages[0].Body)
+}
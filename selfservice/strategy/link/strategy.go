@@ -12,6 +12,7 @@ import (
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/flow/verification"
 	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
@@ -35,6 +36,7 @@ type (
 		x.CSRFTokenGeneratorProvider
 		x.WriterProvider
 		x.LoggingProvider
+		x.ClockProvider
 
 		session.HandlerProvider
 		session.ManagementProvider
@@ -62,6 +64,8 @@ type (
 		VerificationTokenPersistenceProvider
 		SenderProvider
 
+		stats.RecorderProvider
+
 		IdentityTraitsSchemas() schema.Schemas
 	}
 
@@ -25,6 +25,7 @@ import (
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/recovery"
 	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
@@ -47,13 +48,14 @@ func (s *Strategy) RegisterPublicRecoveryRoutes(public *x.RouterPublic) {
 
 func (s *Strategy) RegisterAdminRecoveryRoutes(admin *x.RouterAdmin) {
 	admin.POST(RouteAdminCreateRecoveryLink, s.createRecoveryLink)
+	admin.DELETE(RouteAdminInvalidateTokens, s.invalidateTokens)
 }
 
 func (s *Strategy) PopulateRecoveryMethod(r *http.Request, req *recovery.Flow) error {
 	f := form.NewHTMLForm(req.AppendTo(urlx.AppendPaths(s.c.SelfPublicURL(), RouteRecovery)).String())
 
 	f.SetCSRF(s.d.GenerateCSRFToken(r))
-	f.SetField(form.Field{Name: "email", Type: "email", Required: true})
+	f.SetField(form.Field{Name: "email", Type: "email", Required: true, Autocomplete: form.AutocompleteEmail})
 
 	req.Methods[s.RecoveryStrategyID()] = &recovery.FlowMethod{
 		Method: s.RecoveryStrategyID(),
@@ -147,12 +149,13 @@ func (s *Strategy) createRecoveryLink(w http.ResponseWriter, r *http.Request, _
 		}
 	}
 
-	if time.Now().Add(expiresIn).Before(time.Now()) {
+	if expiresIn <= 0 {
 		s.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Value from "expires_in" must be result to a future time: %s`, p.ExpiresIn)))
 		return
 	}
 
-	req, err := recovery.NewFlow(expiresIn, s.d.GenerateCSRFToken(r), r, s.d.RecoveryStrategies(), flow.TypeBrowser)
+	now := s.d.Clock().Now()
+	req, err := recovery.NewFlow(now, expiresIn, s.d.GenerateCSRFToken(r), r, s.d.RecoveryStrategies(), flow.TypeBrowser)
 	if err != nil {
 		s.d.Writer().WriteError(w, r, err)
 		return
@@ -170,7 +173,7 @@ func (s *Strategy) createRecoveryLink(w http.ResponseWriter, r *http.Request, _
 	}
 
 	address := id.RecoveryAddresses[0]
-	token := NewRecoveryToken(&address, expiresIn)
+	token := NewRecoveryToken(&address, now, expiresIn)
 	if err := s.d.RecoveryTokenPersister().CreateRecoveryToken(r.Context(), token); err != nil {
 		s.d.Writer().WriteError(w, r, err)
 		return
@@ -284,7 +287,7 @@ func (s *Strategy) handleRecovery(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
-	if err := req.Valid(); err != nil {
+	if err := req.Valid(s.d.Clock().Now()); err != nil {
 		s.handleRecoveryError(w, r, req, body, err)
 		return
 	}
@@ -312,6 +315,16 @@ func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	if !recovered.IsActive() {
+		s.handleRecoveryError(w, r, f, nil, errors.WithStack(identity.ErrInactive))
+		return
+	}
+
+	if recovered.IsExpired(s.d.Clock().Now()) {
+		s.handleRecoveryError(w, r, f, nil, errors.WithStack(identity.ErrExpired))
+		return
+	}
+
 	f.Messages.Clear()
 	f.State = recovery.StatePassedChallenge
 	f.RecoveredIdentityID = uuid.NullUUID{
@@ -322,8 +335,10 @@ func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request,
 		s.handleRecoveryError(w, r, f, nil, err)
 		return
 	}
+	s.d.FlowStatsRecorder().Record(r.Context(), stats.FlowRecovery, f.ID, f.Type, s.RecoveryStrategyID(), stats.EventSucceeded)
 
-	sess := session.NewActiveSession(recovered, s.c, time.Now().UTC())
+	sess := session.NewActiveSession(recovered, s.c, s.d.Clock().Now())
+	sess.SetDevice(r, s.c)
 	if err := s.d.SessionManager().CreateAndIssueCookie(r.Context(), w, r, sess); err != nil {
 		s.handleRecoveryError(w, r, f, nil, err)
 		return
@@ -335,7 +350,7 @@ func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	sf.Messages.Set(text.NewRecoverySuccessful(time.Now().Add(s.c.SelfServiceFlowSettingsPrivilegedSessionMaxAge())))
+	sf.Messages.Set(text.NewRecoverySuccessful(sess.AuthenticatedAt.Add(s.c.SelfServiceFlowSettingsPrivilegedSessionMaxAge())))
 	if err := s.d.SettingsFlowPersister().UpdateSettingsFlow(r.Context(), sf); err != nil {
 		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
@@ -358,7 +373,8 @@ func (s *Strategy) recoveryUseToken(w http.ResponseWriter, r *http.Request, body
 
 	var f *recovery.Flow
 	if !token.FlowID.Valid {
-		f, err = recovery.NewFlow(time.Until(token.ExpiresAt), s.d.GenerateCSRFToken(r), r, s.d.RecoveryStrategies(), flow.TypeBrowser)
+		now := s.d.Clock().Now()
+		f, err = recovery.NewFlow(now, token.ExpiresAt.Sub(now), s.d.GenerateCSRFToken(r), r, s.d.RecoveryStrategies(), flow.TypeBrowser)
 		if err != nil {
 			s.handleRecoveryError(w, r, nil, body, err)
 			return
@@ -376,7 +392,7 @@ func (s *Strategy) recoveryUseToken(w http.ResponseWriter, r *http.Request, body
 		}
 	}
 
-	if err := token.Valid(); err != nil {
+	if err := token.Valid(s.d.Clock().Now()); err != nil {
 		s.handleRecoveryError(w, r, f, body, err)
 		return
 	}
@@ -387,7 +403,7 @@ func (s *Strategy) recoveryUseToken(w http.ResponseWriter, r *http.Request, body
 func (s *Strategy) retryRecoveryFlowWithMessage(w http.ResponseWriter, r *http.Request, ft flow.Type, message *text.Message) {
 	s.d.Logger().WithRequest(r).WithField("message", message).Debug("A recovery flow is being retried because a validation error occurred.")
 
-	req, err := recovery.NewFlow(s.c.SelfServiceFlowRecoveryRequestLifespan(), s.d.GenerateCSRFToken(r), r, s.d.RecoveryStrategies(), ft)
+	req, err := recovery.NewFlow(s.d.Clock().Now(), s.c.SelfServiceFlowRecoveryRequestLifespan(), s.d.GenerateCSRFToken(r), r, s.d.RecoveryStrategies(), ft)
 	if err != nil {
 		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
@@ -442,7 +458,7 @@ func (s *Strategy) recoveryHandleFormSubmission(w http.ResponseWriter, r *http.R
 
 	config.Reset()
 	config.SetCSRF(s.d.GenerateCSRFToken(r))
-	config.SetField(form.Field{Name: "email", Type: "email", Required: true, Value: body.Body.Email})
+	config.SetField(form.Field{Name: "email", Type: "email", Required: true, Value: body.Body.Email, Autocomplete: form.AutocompleteEmail})
 
 	req.Active = sqlxx.NullString(s.RecoveryStrategyID())
 	req.State = recovery.StateEmailSent
@@ -476,7 +492,7 @@ func (s *Strategy) handleRecoveryError(w http.ResponseWriter, r *http.Request, r
 
 		config.Reset()
 		config.SetCSRF(s.d.GenerateCSRFToken(r))
-		config.SetField(form.Field{Name: "email", Type: "email", Required: true, Value: body.Body.Email})
+		config.SetField(form.Field{Name: "email", Type: "email", Required: true, Value: body.Body.Email, Autocomplete: form.AutocompleteEmail})
 	}
 
 	s.d.RecoveryFlowErrorHandler().WriteFlowError(w, r, s.RecoveryStrategyID(), req, err)
@@ -75,6 +75,18 @@ func TestAdminStrategy(t *testing.T) {
 		require.IsType(t, err, new(admin.CreateRecoveryLinkBadRequest), "%T", err)
 	})
 
+	t.Run("description=should not create a recovery link which is expired already", func(t *testing.T) {
+		id := identity.Identity{Traits: identity.Traits(`{"email":"recover.never@ory.sh"}`)}
+		require.NoError(t, reg.IdentityManager().Create(context.Background(),
+			&id, identity.ManagerAllowWriteProtectedTraits))
+
+		for _, expiresIn := range []string{"0s", "-1h"} {
+			_, err := adminSDK.Admin.CreateRecoveryLink(admin.NewCreateRecoveryLinkParams().
+				WithBody(&models.CreateRecoveryLink{IdentityID: models.UUID(id.ID.String()), ExpiresIn: expiresIn}))
+			require.IsType(t, err, new(admin.CreateRecoveryLinkBadRequest), "%s: %T", expiresIn, err)
+		}
+	})
+
 	t.Run("description=should create a valid recovery link and set the expiry time and not be able to recover the account", func(t *testing.T) {
 		id := identity.Identity{Traits: identity.Traits(`{"email":"recover.expired@ory.sh"}`)}
 
@@ -125,6 +137,40 @@ func TestAdminStrategy(t *testing.T) {
 		require.Len(t, sr.Payload.Messages, 1)
 		assert.Equal(t, "You successfully recovered your account. Please change your password or set up an alternative login method (e.g. social sign in) within the next 60.00 minutes.", sr.Payload.Messages[0].Text)
 	})
+
+	t.Run("description=should not be able to invalidate the tokens of an account that does not exist", func(t *testing.T) {
+		req, err := http.NewRequest("DELETE", adminTS.URL+"/identities/"+x.NewUUID().String()+"/link-tokens", nil)
+		require.NoError(t, err)
+		res, err := adminTS.Client().Do(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("description=should invalidate the recovery link and not be able to recover the account", func(t *testing.T) {
+		id := identity.Identity{Traits: identity.Traits(`{"email":"recover.invalidated@ory.sh"}`)}
+		require.NoError(t, reg.IdentityManager().Create(context.Background(),
+			&id, identity.ManagerAllowWriteProtectedTraits))
+
+		rl, err := adminSDK.Admin.CreateRecoveryLink(admin.NewCreateRecoveryLinkParams().
+			WithBody(&models.CreateRecoveryLink{IdentityID: models.UUID(id.ID.String())}))
+		require.NoError(t, err)
+
+		req, err := http.NewRequest("DELETE", adminTS.URL+"/identities/"+id.ID.String()+"/link-tokens", nil)
+		require.NoError(t, err)
+		res, err := adminTS.Client().Do(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		res, err = publicTS.Client().Get(*rl.Payload.RecoveryLink)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+
+		// We end up here because the link has been invalidated.
+		assert.Contains(t, res.Request.URL.Path, "/recover")
+		assert.NotContains(t, res.Request.URL.String(), conf.SelfServiceFlowSettingsUI().String())
+	})
 }
 
 func TestRecovery(t *testing.T) {
@@ -0,0 +1,65 @@
+package link
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteAdminInvalidateTokens = "/identities/:id/link-tokens"
+)
+
+// swagger:parameters invalidateIdentityLinkTokens
+// nolint:deadcode,unused
+type invalidateIdentityLinkTokensParameters struct {
+	// ID must be set to the ID of identity whose tokens should be invalidated.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /identities/{id}/link-tokens admin invalidateIdentityLinkTokens
+//
+// Invalidate all Recovery and Verification Tokens of an Identity
+//
+// Invalidates all outstanding recovery and verification tokens (e.g. sent using email) of an identity. Links
+// containing these tokens can no longer be used. Use this endpoint when you suspect that the identity's
+// email inbox has been compromised.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       404: genericError
+//       500: genericError
+func (s *Strategy) invalidateTokens(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	i, err := s.d.IdentityPool().GetIdentity(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := s.d.RecoveryTokenPersister().InvalidateRecoveryTokens(r.Context(), i.ID); err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := s.d.VerificationTokenPersister().InvalidateVerificationTokens(r.Context(), i.ID); err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	s.d.Audit().
+		WithRequest(r).
+		WithField("identity_id", i.ID).
+		Info("All recovery and verification tokens of the identity have been invalidated.")
+
+	w.WriteHeader(http.StatusNoContent)
+}
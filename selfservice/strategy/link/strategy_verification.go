@@ -3,6 +3,7 @@ package link
 import (
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/ory/x/pkgerx"
@@ -12,6 +13,7 @@ import (
 	"github.com/markbates/pkger"
 	"github.com/pkg/errors"
 
+	"github.com/ory/herodot"
 	"github.com/ory/x/decoderx"
 	"github.com/ory/x/sqlcon"
 	"github.com/ory/x/sqlxx"
@@ -22,12 +24,14 @@ import (
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/verification"
 	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/selfservice/stats"
 	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
 const (
-	RouteVerification = "/self-service/verification/methods/link"
+	RouteVerification                 = "/self-service/verification/methods/link"
+	RouteAdminCreateVerificationEmail = "/identities/:id/verification-emails"
 )
 
 func (s *Strategy) VerificationStrategyID() string {
@@ -40,13 +44,14 @@ func (s *Strategy) RegisterPublicVerificationRoutes(public *x.RouterPublic) {
 }
 
 func (s *Strategy) RegisterAdminVerificationRoutes(admin *x.RouterAdmin) {
+	admin.POST(RouteAdminCreateVerificationEmail, s.createVerificationEmail)
 }
 
 func (s *Strategy) PopulateVerificationMethod(r *http.Request, req *verification.Flow) error {
 	f := form.NewHTMLForm(req.AppendTo(urlx.AppendPaths(s.c.SelfPublicURL(), RouteVerification)).String())
 
 	f.SetCSRF(s.d.GenerateCSRFToken(r))
-	f.SetField(form.Field{Name: "email", Type: "email", Required: true})
+	f.SetField(form.Field{Name: "email", Type: "email", Required: true, Autocomplete: form.AutocompleteEmail})
 
 	req.Methods[s.VerificationStrategyID()] = &verification.FlowMethod{
 		Method: s.VerificationStrategyID(),
@@ -55,6 +60,116 @@ func (s *Strategy) PopulateVerificationMethod(r *http.Request, req *verification
 	return nil
 }
 
+// swagger:parameters createVerificationEmail
+//
+// nolint
+type createVerificationEmailParameters struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// in: body
+	Body CreateVerificationEmail
+}
+
+type CreateVerificationEmail struct {
+	// Address to Verify
+	//
+	// The verifiable address of the identity to send the verification email to.
+	//
+	// required: true
+	Address string `json:"address"`
+}
+
+// swagger:route POST /identities/{id}/verification-emails admin createVerificationEmail
+//
+// Send a Verification Email
+//
+// This endpoint creates a verification flow for one of the identity's verifiable addresses and sends the
+// verification email, for example when the identity lost the first one. The identity completes the flow by
+// clicking the link in the email, exactly as if it had requested the email itself.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       201: verificationFlow
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (s *Strategy) createVerificationEmail(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var p CreateVerificationEmail
+	if err := s.dx.Decode(r, &p, decoderx.HTTPJSONDecoder()); err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	id, err := s.d.IdentityPool().GetIdentity(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	var address *identity.VerifiableAddress
+	for k := range id.VerifiableAddresses {
+		if strings.EqualFold(id.VerifiableAddresses[k].Value, strings.TrimSpace(p.Address)) {
+			address = &id.VerifiableAddresses[k]
+			break
+		}
+	}
+
+	if address == nil {
+		s.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReasonf("The identity does not have the verifiable address %s.", p.Address)))
+		return
+	}
+
+	if address.Verified {
+		s.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The address %s is verified already.", address.Value)))
+		return
+	}
+
+	f, err := verification.NewFlow(s.d.Clock().Now(), s.c.SelfServiceFlowVerificationRequestLifespan(), s.d.GenerateCSRFToken(r), r, s.d.VerificationStrategies(), flow.TypeBrowser)
+	if err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	f.Active = sqlxx.NullString(s.VerificationStrategyID())
+	f.State = verification.StateEmailSent
+	f.Messages.Set(text.NewVerificationEmailSent())
+	if err := s.d.VerificationFlowPersister().CreateVerificationFlow(r.Context(), f); err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	token := NewSelfServiceVerificationToken(address, f)
+	if err := s.d.VerificationTokenPersister().CreateVerificationToken(r.Context(), token); err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := s.d.LinkSender().SendVerificationTokenTo(r.Context(), address, token); err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	s.d.Audit().
+		WithRequest(r).
+		WithField("identity_id", id.ID).
+		WithField("verification_flow_id", f.ID).
+		WithSensitiveField("email_address", address.Value).
+		Info("A verification email was sent on behalf of an administrator.")
+
+	s.d.Writer().WriteCode(w, r, http.StatusCreated, f)
+}
+
 func (s *Strategy) decodeVerification(r *http.Request, decodeBody bool) (*completeSelfServiceVerificationFlowWithLinkMethodParameters, error) {
 	var body completeSelfServiceVerificationFlowWithLinkMethod
 
@@ -88,7 +203,7 @@ func (s *Strategy) handleVerificationError(w http.ResponseWriter, r *http.Reques
 
 		config.Reset()
 		config.SetCSRF(s.d.GenerateCSRFToken(r))
-		config.SetField(form.Field{Name: "email", Type: "email", Required: true, Value: body.Body.Email})
+		config.SetField(form.Field{Name: "email", Type: "email", Required: true, Value: body.Body.Email, Autocomplete: form.AutocompleteEmail})
 	}
 
 	s.d.VerificationFlowErrorHandler().WriteFlowError(w, r, s.VerificationStrategyID(), f, err)
@@ -185,7 +300,7 @@ func (s *Strategy) handleVerification(w http.ResponseWriter, r *http.Request, ps
 		return
 	}
 
-	if err := f.Valid(); err != nil {
+	if err := f.Valid(s.d.Clock().Now()); err != nil {
 		s.handleVerificationError(w, r, f, body, err)
 		return
 	}
@@ -240,7 +355,7 @@ func (s *Strategy) verificationHandleFormSubmission(w http.ResponseWriter, r *ht
 
 	config.Reset()
 	config.SetCSRF(s.d.GenerateCSRFToken(r))
-	config.SetField(form.Field{Name: "email", Type: "email", Required: true, Value: body.Body.Email})
+	config.SetField(form.Field{Name: "email", Type: "email", Required: true, Value: body.Body.Email, Autocomplete: form.AutocompleteEmail})
 
 	f.Active = sqlxx.NullString(s.VerificationStrategyID())
 	f.State = verification.StateEmailSent
@@ -286,7 +401,8 @@ func (s *Strategy) verificationUseToken(w http.ResponseWriter, r *http.Request,
 
 	var f *verification.Flow
 	if !token.FlowID.Valid {
-		f, err = verification.NewFlow(time.Until(token.ExpiresAt), s.d.GenerateCSRFToken(r), r, s.d.VerificationStrategies(), flow.TypeBrowser)
+		now := s.d.Clock().Now()
+		f, err = verification.NewFlow(now, token.ExpiresAt.Sub(now), s.d.GenerateCSRFToken(r), r, s.d.VerificationStrategies(), flow.TypeBrowser)
 		if err != nil {
 			s.handleVerificationError(w, r, nil, body, err)
 			return
@@ -304,7 +420,7 @@ func (s *Strategy) verificationUseToken(w http.ResponseWriter, r *http.Request,
 		}
 	}
 
-	if err := token.Valid(); err != nil {
+	if err := token.Valid(s.d.Clock().Now()); err != nil {
 		s.handleVerificationError(w, r, f, body, err)
 		return
 	}
@@ -324,6 +440,18 @@ func (s *Strategy) verificationUseToken(w http.ResponseWriter, r *http.Request,
 		s.handleVerificationError(w, r, f, body, err)
 		return
 	}
+	s.d.FlowStatsRecorder().Record(r.Context(), stats.FlowVerification, f.ID, f.Type, s.VerificationStrategyID(), stats.EventSucceeded)
+
+	// Proving control over an address lifts the quarantine of identities registered under suspicious conditions.
+	if err := s.d.PrivilegedIdentityPool().ReleaseIdentityFromQuarantine(r.Context(), address.IdentityID); err == nil {
+		s.d.Audit().
+			WithRequest(r).
+			WithField("identity_id", address.IdentityID).
+			Info("A quarantined identity was released because it verified an address.")
+	} else if !errors.Is(err, sqlcon.ErrNoRows) {
+		s.handleVerificationError(w, r, f, body, err)
+		return
+	}
 
 	http.Redirect(w, r, s.c.SelfServiceFlowVerificationReturnTo(f.
 		AppendTo(s.c.SelfServiceFlowVerificationUI())).String(), http.StatusFound)
@@ -332,7 +460,7 @@ func (s *Strategy) verificationUseToken(w http.ResponseWriter, r *http.Request,
 func (s *Strategy) retryVerificationFlowWithMessage(w http.ResponseWriter, r *http.Request, ft flow.Type, message *text.Message) {
 	s.d.Logger().WithRequest(r).WithField("message", message).Debug("A verification flow is being retried because a validation error occurred.")
 
-	req, err := verification.NewFlow(s.c.SelfServiceFlowVerificationRequestLifespan(), s.d.GenerateCSRFToken(r), r, s.d.VerificationStrategies(), ft)
+	req, err := verification.NewFlow(s.d.Clock().Now(), s.c.SelfServiceFlowVerificationRequestLifespan(), s.d.GenerateCSRFToken(r), r, s.d.VerificationStrategies(), ft)
 	if err != nil {
 		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
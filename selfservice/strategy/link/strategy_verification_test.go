@@ -3,8 +3,10 @@ package link_test
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -254,3 +256,56 @@ func TestVerification(t *testing.T) {
 		})
 	})
 }
+
+func TestAdminVerification(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	initViper(t, conf)
+
+	_ = testhelpers.NewVerificationUIFlowEchoServer(t, reg)
+	_ = testhelpers.NewErrorTestServer(t, reg)
+
+	public, admin := testhelpers.NewKratosServer(t, reg)
+
+	i := &identity.Identity{Traits: identity.Traits(`{"email":"admin-verifyme@ory.sh"}`)}
+	require.NoError(t, reg.IdentityManager().Create(context.Background(), i, identity.ManagerAllowWriteProtectedTraits))
+
+	send := func(t *testing.T, id, address string, expectedStatus int) string {
+		res, err := admin.Client().Post(admin.URL+strings.Replace(link.RouteAdminCreateVerificationEmail, ":id", id, 1),
+			"application/json", strings.NewReader(fmt.Sprintf(`{"address":"%s"}`, address)))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body := string(ioutilx.MustReadAll(res.Body))
+		require.Equal(t, expectedStatus, res.StatusCode, "%s", body)
+		return body
+	}
+
+	t.Run("case=should reject unknown identities and addresses", func(t *testing.T) {
+		send(t, x.NewUUID().String(), "admin-verifyme@ory.sh", http.StatusNotFound)
+		send(t, i.ID.String(), "someone-else@ory.sh", http.StatusNotFound)
+	})
+
+	t.Run("case=should send a verification email which verifies the address", func(t *testing.T) {
+		body := send(t, i.ID.String(), "Admin-Verifyme@ory.sh", http.StatusCreated)
+		assert.EqualValues(t, verification.StateEmailSent, gjson.Get(body, "state").String(), "%s", body)
+
+		message := testhelpers.CourierExpectMessage(t, reg, "admin-verifyme@ory.sh", "Please verify your email address")
+		verificationLink := testhelpers.CourierExpectLinkInMessage(t, message, 1)
+		assert.Contains(t, verificationLink, public.URL+link.RouteVerification)
+
+		res, err := testhelpers.NewClientWithCookies(t).Get(verificationLink)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		actual := string(ioutilx.MustReadAll(res.Body))
+		assert.EqualValues(t, gjson.Get(body, "id").String(), gjson.Get(actual, "id").String(), "%s", actual)
+		assert.EqualValues(t, "passed_challenge", gjson.Get(actual, "state").String(), "%s", actual)
+
+		id, err := reg.PrivilegedIdentityPool().GetIdentity(context.Background(), i.ID)
+		require.NoError(t, err)
+		require.Len(t, id.VerifiableAddresses, 1)
+		assert.True(t, id.VerifiableAddresses[0].Verified)
+	})
+
+	t.Run("case=should reject verified addresses", func(t *testing.T) {
+		send(t, i.ID.String(), "admin-verifyme@ory.sh", http.StatusBadRequest)
+	})
+}
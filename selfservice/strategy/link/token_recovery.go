@@ -60,8 +60,7 @@ func NewSelfServiceRecoveryToken(address *identity.RecoveryAddress, f *recovery.
 		FlowID:          uuid.NullUUID{UUID: f.ID, Valid: true}}
 }
 
-func NewRecoveryToken(address *identity.RecoveryAddress, expiresIn time.Duration) *RecoveryToken {
-	now := time.Now().UTC()
+func NewRecoveryToken(address *identity.RecoveryAddress, now time.Time, expiresIn time.Duration) *RecoveryToken {
 	return &RecoveryToken{
 		ID:              x.NewUUID(),
 		Token:           randx.MustString(32, randx.AlphaNum),
@@ -71,8 +70,8 @@ func NewRecoveryToken(address *identity.RecoveryAddress, expiresIn time.Duration
 	}
 }
 
-func (f *RecoveryToken) Valid() error {
-	if f.ExpiresAt.Before(time.Now()) {
+func (f *RecoveryToken) Valid(now time.Time) error {
+	if f.ExpiresAt.Before(now) {
 		return errors.WithStack(recovery.NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
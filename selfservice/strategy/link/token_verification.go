@@ -60,8 +60,7 @@ func NewSelfServiceVerificationToken(address *identity.VerifiableAddress, f *ver
 		FlowID:            uuid.NullUUID{UUID: f.ID, Valid: true}}
 }
 
-func NewVerificationToken(address *identity.VerifiableAddress, expiresIn time.Duration) *VerificationToken {
-	now := time.Now().UTC()
+func NewVerificationToken(address *identity.VerifiableAddress, now time.Time, expiresIn time.Duration) *VerificationToken {
 	return &VerificationToken{
 		ID:                x.NewUUID(),
 		Token:             randx.MustString(32, randx.AlphaNum),
@@ -71,8 +70,8 @@ func NewVerificationToken(address *identity.VerifiableAddress, expiresIn time.Du
 	}
 }
 
-func (f *VerificationToken) Valid() error {
-	if f.ExpiresAt.Before(time.Now()) {
+func (f *VerificationToken) Valid(now time.Time) error {
+	if f.ExpiresAt.Before(now) {
 		return errors.WithStack(verification.NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
@@ -19,7 +19,7 @@ func TestVerificationToken(t *testing.T) {
 	req := &http.Request{URL: urlx.ParseOrPanic("https://www.ory.sh/")}
 	t.Run("func=NewSelfServiceVerificationToken", func(t *testing.T) {
 		t.Run("case=creates unique tokens", func(t *testing.T) {
-			f, err := verification.NewFlow(time.Hour, "", req, nil, flow.TypeBrowser)
+			f, err := verification.NewFlow(time.Now(), time.Hour, "", req, nil, flow.TypeBrowser)
 			require.NoError(t, err)
 
 			tokens := make([]string, 10)
@@ -32,12 +32,12 @@ func TestVerificationToken(t *testing.T) {
 	})
 	t.Run("method=Valid", func(t *testing.T) {
 		t.Run("case=is invalid when the flow is expired", func(t *testing.T) {
-			f, err := verification.NewFlow(-time.Hour, "", req, nil, flow.TypeBrowser)
+			f, err := verification.NewFlow(time.Now(), -time.Hour, "", req, nil, flow.TypeBrowser)
 			require.NoError(t, err)
 
 			token := NewSelfServiceVerificationToken(nil, f)
-			require.Error(t, token.Valid())
-			assert.EqualError(t, token.Valid(), f.Valid().Error())
+			require.Error(t, token.Valid(time.Now()))
+			assert.EqualError(t, token.Valid(time.Now()), f.Valid(time.Now()).Error())
 		})
 	})
 }
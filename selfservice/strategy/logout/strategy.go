@@ -0,0 +1,64 @@
+package logout
+
+import (
+	"net/http"
+
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/logout"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	StrategyLogout = "logout"
+)
+
+var _ settings.Strategy = new(Strategy)
+
+type (
+	// Strategy adds a form to browser settings flows which signs the user out on all devices, so that user interfaces
+	// are able to show a single button for it. The form is submitted to the logout endpoint, which revokes the
+	// sessions.
+	Strategy struct {
+		c *config.Provider
+	}
+)
+
+// swagger:model settingsLogoutFormConfig
+type FlowMethod struct {
+	*form.HTMLForm
+}
+
+func NewStrategy(c *config.Provider) *Strategy {
+	return &Strategy{c: c}
+}
+
+func (s *Strategy) SettingsStrategyID() string {
+	return StrategyLogout
+}
+
+// RegisterSettingsRoutes does not register any routes because the form is submitted to the logout endpoint.
+func (s *Strategy) RegisterSettingsRoutes(_ *x.RouterPublic) {
+}
+
+func (s *Strategy) PopulateSettingsMethod(_ *http.Request, _ *identity.Identity, f *settings.Flow) error {
+	// API clients revoke their sessions using the sessions endpoints instead.
+	if f.Type != flow.TypeBrowser {
+		return nil
+	}
+
+	f.Methods[s.SettingsStrategyID()] = &settings.FlowMethod{
+		Method: s.SettingsStrategyID(),
+		Config: &settings.FlowMethodConfig{FlowMethodConfigurator: &FlowMethod{HTMLForm: &form.HTMLForm{
+			Action: urlx.AppendPaths(s.c.SelfPublicURL(), logout.RouteBrowser).String(),
+			Method: "GET",
+			Fields: form.Fields{{Name: "everywhere", Type: "submit", Value: "true"}},
+		}}},
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package logout_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow/logout"
+	logout2 "github.com/ory/kratos/selfservice/strategy/logout"
+	"github.com/ory/kratos/session"
+)
+
+func TestSettings(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+	testhelpers.StrategyEnable(t, conf, logout2.StrategyLogout, true)
+
+	_ = testhelpers.NewSettingsUIEchoServer(t, reg)
+	_ = testhelpers.NewErrorTestServer(t, reg)
+	redirTS := testhelpers.NewRedirTS(t, "", conf)
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+
+	newIdentity := func(email string) *identity.Identity {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(`{"email":"` + email + `"}`)
+		return i
+	}
+
+	t.Run("type=api", func(t *testing.T) {
+		apiUser := testhelpers.NewHTTPClientWithIdentitySessionToken(t, reg, newIdentity("logout-api@ory.sh"))
+		rs := testhelpers.InitializeSettingsFlowViaAPI(t, apiUser, publicTS)
+		assert.Empty(t, rs.Payload.Methods[logout2.StrategyLogout], "API clients revoke their sessions using the sessions endpoints")
+	})
+
+	t.Run("type=browser", func(t *testing.T) {
+		i := newIdentity("logout-browser@ory.sh")
+		browserUser := testhelpers.NewHTTPClientWithIdentitySessionCookie(t, reg, i)
+		otherDevice := testhelpers.NewHTTPClientWithIdentitySessionToken(t, reg, i)
+
+		rs := testhelpers.InitializeSettingsFlowViaBrowser(t, browserUser, publicTS)
+		f := testhelpers.GetSettingsFlowMethodConfig(t, rs.Payload, logout2.StrategyLogout)
+		assert.Equal(t, publicTS.URL+logout.RouteBrowser, *f.Action)
+		assert.Equal(t, "GET", *f.Method)
+		require.Len(t, f.Fields, 1)
+		assert.Equal(t, "everywhere", *f.Fields[0].Name)
+		assert.Equal(t, "submit", *f.Fields[0].Type)
+		assert.Equal(t, "true", f.Fields[0].Value)
+
+		res, err := browserUser.Get(*f.Action + "?" + *f.Fields[0].Name + "=true")
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, redirTS.URL, res.Request.URL.String())
+
+		for _, c := range []*http.Client{browserUser, otherDevice} {
+			res, err := c.Get(publicTS.URL + session.RouteWhoami)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package mtls
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	pemBegin = "-----BEGIN CERTIFICATE-----"
+	pemEnd   = "-----END CERTIFICATE-----"
+)
+
+// parseCertificates decodes the certificate chain forwarded by the reverse proxy, leaf first. Proxies encode
+// certificates differently, so the value may be URL encoded and contain PEM blocks whose line breaks were
+// replaced by spaces, or base64 encoded DER certificates separated by commas.
+func parseCertificates(value string) ([]*x509.Certificate, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "%") {
+		unescaped, err := url.PathUnescape(value)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		value = unescaped
+	}
+
+	var encoded []string
+	if strings.Contains(value, pemBegin) {
+		for _, block := range strings.Split(value, pemEnd) {
+			i := strings.Index(block, pemBegin)
+			if i < 0 && len(strings.TrimSpace(block)) == 0 {
+				continue
+			} else if i < 0 || len(strings.TrimSpace(block[:i])) > 0 {
+				return nil, errors.New("unexpected data outside of a PEM block")
+			}
+			encoded = append(encoded, block[i+len(pemBegin):])
+		}
+	} else {
+		encoded = strings.Split(value, ",")
+	}
+
+	certificates := make([]*x509.Certificate, 0, len(encoded))
+	for _, e := range encoded {
+		der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(e), ""))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		certificate, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		certificates = append(certificates, certificate)
+	}
+
+	if len(certificates) == 0 {
+		return nil, errors.New("no certificate found")
+	}
+	return certificates, nil
+}
+
+// parseFingerprint decodes a hex encoded SHA-256 fingerprint with or without colons.
+func parseFingerprint(value string) (string, error) {
+	fingerprint, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(value), ":", ""))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if len(fingerprint) != sha256.Size {
+		return "", errors.Errorf("expected a SHA-256 fingerprint of %d bytes but got %d bytes", sha256.Size, len(fingerprint))
+	}
+	return hex.EncodeToString(fingerprint), nil
+}
+
+func fingerprint(c *x509.Certificate) string {
+	sum := sha256.Sum256(c.Raw)
+	return hex.EncodeToString(sum[:])
+}
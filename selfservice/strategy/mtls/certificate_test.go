@@ -0,0 +1,84 @@
+package mtls
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCertificates(t *testing.T) {
+	ca := NewTestCA(t, "Example CA")
+	leaf := ca.Issue(t, x509.Certificate{Subject: pkix.Name{CommonName: "alice"}})
+
+	for k, tc := range []struct {
+		d     string
+		value string
+	}{
+		{d: "pem", value: EncodePEM(leaf)},
+		{d: "url encoded pem", value: url.PathEscape(EncodePEM(leaf))},
+		{d: "pem with spaces instead of line breaks", value: strings.ReplaceAll(EncodePEM(leaf), "\n", " ")},
+		{d: "pem with tabs instead of line breaks", value: strings.ReplaceAll(EncodePEM(leaf), "\n", "\t")},
+		{d: "der", value: base64.StdEncoding.EncodeToString(leaf.Raw)},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			chain, err := parseCertificates(tc.value)
+			require.NoError(t, err, "%d", k)
+			require.Len(t, chain, 1)
+			assert.Equal(t, leaf.Raw, chain[0].Raw)
+		})
+	}
+
+	t.Run("case=chain", func(t *testing.T) {
+		for _, value := range []string{
+			EncodePEM(leaf, ca.Certificate),
+			base64.StdEncoding.EncodeToString(leaf.Raw) + "," + base64.StdEncoding.EncodeToString(ca.Certificate.Raw),
+		} {
+			chain, err := parseCertificates(value)
+			require.NoError(t, err)
+			require.Len(t, chain, 2)
+			assert.Equal(t, leaf.Raw, chain[0].Raw)
+			assert.Equal(t, ca.Certificate.Raw, chain[1].Raw)
+		}
+	})
+
+	t.Run("case=invalid", func(t *testing.T) {
+		for _, value := range []string{
+			"",
+			"not a certificate",
+			base64.StdEncoding.EncodeToString([]byte("not a certificate")),
+			"garbage" + EncodePEM(leaf),
+		} {
+			_, err := parseCertificates(value)
+			assert.Error(t, err, "%s", value)
+		}
+	})
+}
+
+func TestParseFingerprint(t *testing.T) {
+	sum := sha256.Sum256([]byte("certificate"))
+	expected := hex.EncodeToString(sum[:])
+
+	colons := make([]string, len(sum))
+	for i, b := range sum {
+		colons[i] = strings.ToUpper(hex.EncodeToString([]byte{b}))
+	}
+
+	for _, value := range []string{expected, strings.ToUpper(expected), strings.Join(colons, ":")} {
+		actual, err := parseFingerprint(value)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	for _, value := range []string{"", "zz", expected[:32]} {
+		_, err := parseFingerprint(value)
+		assert.Error(t, err, "%s", value)
+	}
+}
@@ -0,0 +1,182 @@
+package mtls
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/markbates/pkger"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/pkgerx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteLogin = "/self-service/login/methods/mtls"
+)
+
+func (s *Strategy) RegisterLoginRoutes(r *x.RouterPublic) {
+	s.d.CSRFHandler().IgnorePath(RouteLogin)
+
+	r.POST(RouteLogin, s.handleLogin)
+}
+
+func (s *Strategy) handleLoginError(w http.ResponseWriter, r *http.Request, rr *login.Flow, err error) {
+	if rr != nil {
+		if method, ok := rr.Methods[s.ID()]; ok {
+			method.Config.Reset()
+			if rr.Type == flow.TypeBrowser {
+				method.Config.SetCSRF(s.d.GenerateCSRFToken(r))
+			}
+
+			rr.Methods[s.ID()] = method
+		}
+	}
+
+	s.d.LoginFlowErrorHandler().WriteFlowError(w, r, s.ID(), rr, err)
+}
+
+// nolint:deadcode,unused
+// swagger:parameters completeSelfServiceLoginFlowWithMTLSMethod
+type completeSelfServiceLoginFlowWithMTLSMethodParameters struct {
+	// The Flow ID
+	//
+	// required: true
+	// in: query
+	Flow string `json:"flow"`
+
+	// The flow token returned when initializing an API flow. Required for API flows if
+	// `selfservice.api_flow_binding` is enabled.
+	//
+	// in: header
+	FlowToken string `json:"X-Kratos-Flow-Token"`
+
+	// in: body
+	Body CompleteSelfServiceLoginFlowWithMTLSMethod
+}
+
+// swagger:route POST /self-service/login/methods/mtls public completeSelfServiceLoginFlowWithMTLSMethod
+//
+// Complete Login Flow with the mTLS Method
+//
+// Use this endpoint to complete a login flow with a TLS client certificate. The reverse proxy terminating the
+// TLS connection forwards the certificate, or its SHA-256 fingerprint, in the configured header. The certificate
+// is mapped to an identifier to find the identity. This endpoint behaves differently for API and browser flows.
+//
+// API flows expect `application/json` to be sent in the body and responds with
+//   - HTTP 200 and a application/json body with the session token on success;
+//   - HTTP 302 redirect to a fresh login flow if the original flow expired with the appropriate error messages set;
+//   - HTTP 400 on form validation errors or if the certificate is missing or invalid.
+//
+// Browser flows expect `application/x-www-form-urlencoded` to be sent in the body and responds with
+//   - a HTTP 302 redirect to the post/after login URL or the `return_to` value if it was set and if the login succeeded;
+//   - a HTTP 302 redirect to the login UI URL with the flow ID containing the validation errors otherwise.
+//
+//     Schemes: http, https
+//
+//     Consumes:
+//     - application/json
+//     - application/x-www-form-urlencoded
+//
+//     Produces:
+//     - application/json
+//
+//     Responses:
+//       200: loginViaApiResponse
+//       302: emptyResponse
+//       400: loginFlow
+//       500: genericError
+func (s *Strategy) handleLogin(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	rid := x.ParseUUID(r.URL.Query().Get("flow"))
+	if x.IsZeroUUID(rid) {
+		s.handleLoginError(w, r, nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The flow query parameter is missing or invalid.")))
+		return
+	}
+
+	ar, err := s.d.LoginFlowPersister().GetLoginFlow(r.Context(), rid)
+	if err != nil {
+		s.handleLoginError(w, r, nil, err)
+		return
+	}
+
+	var p CompleteSelfServiceLoginFlowWithMTLSMethod
+	if err := s.hd.Decode(r, &p, decoderx.MustHTTPRawJSONSchemaCompiler(pkgerx.MustRead(
+		pkger.Open("/selfservice/strategy/mtls/.schema/login.schema.json")))); err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	if err := flow.VerifyRequest(r, ar.Type, s.c.DisableAPIFlowEnforcement(), s.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	if err := flow.VerifyFlowToken(r, ar.Type, s.c.SelfServiceAPIFlowBinding(), ar.FlowTokenHash); err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	if _, err := s.d.SessionManager().FetchFromRequest(r.Context(), r); err == nil && !ar.Forced {
+		if ar.Type == flow.TypeBrowser {
+			http.Redirect(w, r, s.c.SelfServiceBrowserDefaultReturnTo().String(), http.StatusFound)
+			return
+		}
+
+		s.d.Writer().WriteError(w, r, errors.WithStack(login.ErrAlreadyLoggedIn))
+		return
+	}
+
+	if err := ar.Valid(s.d.Clock().Now(), s.c.SelfServiceFlowExpiredGracePeriod()); err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	c := s.c.SelfServiceStrategyMTLS()
+	identifier, err := s.identifier(c, r, s.d.Clock().Now())
+	if errors.Is(err, errRejected) {
+		s.d.Logger().WithRequest(r).WithError(err).Info("Rejected a client certificate.")
+		s.handleLoginError(w, r, ar, schema.NewClientCertificateError())
+		return
+	} else if err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	i, _, err := s.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(r.Context(), identity.CredentialsType(c.IdentifierCredentialsType), identifier)
+	if errors.Is(err, herodot.ErrNotFound) {
+		s.d.Logger().WithRequest(r).WithField("identifier", identifier).
+			Debug("The client certificate is valid but no identity matches its identifier.")
+		s.handleLoginError(w, r, ar, schema.NewClientCertificateError())
+		return
+	} else if err != nil {
+		s.handleLoginError(w, r, ar, err)
+		return
+	}
+
+	if err := s.d.LoginHookExecutor().PostLoginHook(w, r, s.ID(), ar, i); err != nil {
+		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+}
+
+func (s *Strategy) PopulateLoginMethod(r *http.Request, sr *login.Flow) error {
+	f := &form.HTMLForm{
+		Action: sr.AppendTo(urlx.AppendPaths(s.c.SelfPublicURL(), RouteLogin)).String(),
+		Method: "POST",
+		Fields: form.Fields{}}
+	f.SetCSRF(s.d.GenerateCSRFToken(r))
+
+	sr.Methods[s.ID()] = &login.FlowMethod{
+		Method: s.ID(),
+		Config: &login.FlowMethodConfig{FlowMethodConfigurator: &FlowMethod{HTMLForm: f}}}
+	return nil
+}
@@ -0,0 +1,208 @@
+package mtls
+
+import (
+	"crypto/x509"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/fetcher"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+var _ login.Strategy = new(Strategy)
+
+const (
+	HeaderFormatPEM    = "pem"
+	HeaderFormatSHA256 = "sha256"
+
+	IdentifierSourceSubjectCommonName = "subject_common_name"
+	IdentifierSourceSubjectDN         = "subject_dn"
+	IdentifierSourceSANEmail          = "san_email"
+	IdentifierSourceSANDNS            = "san_dns"
+	IdentifierSourceSANURI            = "san_uri"
+	IdentifierSourceSHA256            = "sha256"
+)
+
+// errRejected is returned if the request does not carry a usable client certificate. The reason is logged but
+// not shown to the client.
+var errRejected = errors.New("client certificate rejected")
+
+type dependencies interface {
+	errorx.ManagementProvider
+
+	x.LoggingProvider
+	x.WriterProvider
+	x.CSRFProvider
+	x.CSRFTokenGeneratorProvider
+	x.ClockProvider
+
+	identity.PrivilegedPoolProvider
+
+	session.ManagementProvider
+
+	login.HookExecutorProvider
+	login.FlowPersistenceProvider
+	login.ErrorHandlerProvider
+}
+
+// Strategy signs in with a TLS client certificate. The TLS connection is terminated by a reverse proxy which
+// verifies the certificate and forwards it in a header. The header is only accepted from trusted proxies. Identities are found by mapping the certificate to an
+// identifier of another credentials type, for example the username of the password method.
+type Strategy struct {
+	c  *config.Provider
+	d  dependencies
+	f  *fetcher.Fetcher
+	hd *decoderx.HTTP
+
+	sync.Mutex
+	rootsURL string
+	roots    *x509.CertPool
+}
+
+func NewStrategy(d dependencies, c *config.Provider) *Strategy {
+	return &Strategy{
+		c:  c,
+		d:  d,
+		f:  fetcher.NewFetcher(),
+		hd: decoderx.NewHTTP(),
+	}
+}
+
+func (s *Strategy) ID() identity.CredentialsType {
+	return identity.CredentialsTypeMTLS
+}
+
+// certificateAuthorities returns the configured client certificate authorities. They are fetched again when the
+// URL changes.
+func (s *Strategy) certificateAuthorities(location string) (*x509.CertPool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.roots != nil && s.rootsURL == location {
+		return s.roots, nil
+	}
+
+	certificates, err := s.f.Fetch(location)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to fetch the client CA certificates.").WithDebug(err.Error()))
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(certificates.Bytes()) {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The client CA certificates do not contain a PEM encoded certificate."))
+	}
+
+	s.rootsURL, s.roots = location, roots
+	return s.roots, nil
+}
+
+// identifier maps the forwarded client certificate to the identifier used to find the identity.
+func (s *Strategy) identifier(c *config.MTLSConfig, r *http.Request, now time.Time) (string, error) {
+	// Without trusted proxies every client could set the header, so the method must not be used at all.
+	if len(c.TrustedProxies) == 0 {
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The mTLS method requires trusted proxies because clients could otherwise forward their own certificates."))
+	}
+
+	if ok, err := x.FromTrustedProxy(r, c.TrustedProxies); err != nil {
+		return "", err
+	} else if !ok {
+		return "", errors.Wrapf(errRejected, "the request was not sent by a trusted proxy")
+	}
+
+	value := r.Header.Get(c.Header)
+	if len(value) == 0 {
+		return "", errors.Wrapf(errRejected, `the header "%s" is not set`, c.Header)
+	}
+
+	switch c.HeaderFormat {
+	case HeaderFormatSHA256:
+		if c.IdentifierSource != IdentifierSourceSHA256 {
+			return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf(`The mTLS header format "%s" requires the identifier source "%s".`, c.HeaderFormat, IdentifierSourceSHA256))
+		}
+		if c.CACertificateURL != "" {
+			return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf(`The mTLS header format "%s" can not be combined with CA certificates because the certificate is not forwarded.`, c.HeaderFormat))
+		}
+
+		fingerprint, err := parseFingerprint(value)
+		if err != nil {
+			return "", errors.Wrapf(errRejected, "unable to decode the fingerprint: %s", err)
+		}
+		return fingerprint, nil
+	case HeaderFormatPEM:
+		if c.CACertificateURL == "" {
+			return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf(`The mTLS header format "%s" requires CA certificates to verify the forwarded certificates.`, c.HeaderFormat))
+		}
+	default:
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf(`The mTLS header format "%s" is not supported.`, c.HeaderFormat))
+	}
+
+	chain, err := parseCertificates(value)
+	if err != nil {
+		return "", errors.Wrapf(errRejected, "unable to decode the certificate: %s", err)
+	}
+
+	certificate := chain[0]
+	if now.Before(certificate.NotBefore) || now.After(certificate.NotAfter) {
+		return "", errors.Wrapf(errRejected, "the certificate is valid from %s until %s", certificate.NotBefore, certificate.NotAfter)
+	}
+
+	roots, err := s.certificateAuthorities(c.CACertificateURL)
+	if err != nil {
+		return "", err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, ic := range chain[1:] {
+		intermediates.AddCert(ic)
+	}
+
+	if _, err := certificate.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return "", errors.Wrapf(errRejected, "unable to verify the certificate: %s", err)
+	}
+
+	var identifier string
+	switch c.IdentifierSource {
+	case IdentifierSourceSubjectCommonName:
+		identifier = certificate.Subject.CommonName
+	case IdentifierSourceSubjectDN:
+		identifier = certificate.Subject.String()
+	case IdentifierSourceSANEmail:
+		if len(certificate.EmailAddresses) > 0 {
+			identifier = certificate.EmailAddresses[0]
+		}
+	case IdentifierSourceSANDNS:
+		if len(certificate.DNSNames) > 0 {
+			identifier = certificate.DNSNames[0]
+		}
+	case IdentifierSourceSANURI:
+		if len(certificate.URIs) > 0 {
+			identifier = certificate.URIs[0].String()
+		}
+	case IdentifierSourceSHA256:
+		identifier = fingerprint(certificate)
+	default:
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf(`The mTLS identifier source "%s" is not supported.`, c.IdentifierSource))
+	}
+
+	if len(identifier) == 0 {
+		return "", errors.Wrapf(errRejected, `the certificate has no value for the identifier source "%s"`, c.IdentifierSource)
+	}
+	return strings.ToLower(identifier), nil
+}
@@ -0,0 +1,185 @@
+package mtls_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/strategy/mtls"
+	"github.com/ory/kratos/text"
+)
+
+type certificateTransport struct {
+	value string
+}
+
+func (t *certificateTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Path == mtls.RouteLogin && t.value != "" {
+		r.Header.Set("X-Client-Cert", t.value)
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestStrategy(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	ca := mtls.NewTestCA(t, "Example CA")
+	conf.MustSet(config.ViperKeySelfServiceStrategyConfig+"."+string(identity.CredentialsTypeMTLS), map[string]interface{}{
+		"enabled": true,
+		"config": map[string]interface{}{
+			"ca_certificate_url": "base64://" + base64.StdEncoding.EncodeToString(ca.PEM),
+			"trusted_proxies":    []string{"127.0.0.1"},
+		},
+	})
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+	_ = testhelpers.NewLoginUIFlowEchoServer(t, reg)
+	returnTS := testhelpers.NewRedirSessionEchoTS(t, reg)
+	_ = testhelpers.NewErrorTestServer(t, reg)
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	i.Traits = identity.Traits(`{"username":"alice","email":"alice@example.com"}`)
+	i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+		Type: identity.CredentialsTypePassword, Identifiers: []string{"alice", "alice@example.com"}, Config: []byte(`{}`),
+	})
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+	login := func(t *testing.T, isAPI bool, value string, expectedStatusCode int, expectedURL string) string {
+		var hc *http.Client
+		if !isAPI {
+			hc = testhelpers.NewClientWithCookies(t)
+			hc.Transport = &certificateTransport{value: value}
+		} else {
+			hc = &http.Client{Transport: &certificateTransport{value: value}}
+		}
+
+		return testhelpers.SubmitLoginForm(t, isAPI, hc, publicTS, func(v url.Values) {}, identity.CredentialsTypeMTLS, false, expectedStatusCode, expectedURL)
+	}
+
+	escaped := func(certificates ...*x509.Certificate) string {
+		return url.PathEscape(mtls.EncodePEM(certificates...))
+	}
+
+	rejected := func(t *testing.T, body string) {
+		assert.EqualValues(t, text.ErrorValidationLoginClientCertificate, gjson.Get(body, "methods.mtls.config.messages.0.id").Int(), "%s", body)
+	}
+
+	set := func(t *testing.T, key string, value interface{}) {
+		previous := conf.Source().Get(key)
+		conf.MustSet(key, value)
+		t.Cleanup(func() {
+			conf.MustSet(key, previous)
+		})
+	}
+
+	alice := ca.Issue(t, x509.Certificate{
+		Subject:        pkix.Name{CommonName: "Alice", Organization: []string{"Example"}},
+		EmailAddresses: []string{"Alice@Example.com"},
+	})
+
+	t.Run("case=should sign in with a valid certificate", func(t *testing.T) {
+		body := login(t, true, escaped(alice), http.StatusOK, publicTS.URL)
+		assert.Equal(t, i.ID.String(), gjson.Get(body, "session.identity.id").String(), "%s", body)
+		assert.NotEmpty(t, gjson.Get(body, "session_token").String(), "%s", body)
+
+		body = login(t, false, escaped(alice), http.StatusOK, returnTS.URL)
+		assert.Equal(t, i.ID.String(), gjson.Get(body, "identity.id").String(), "%s", body)
+	})
+
+	t.Run("case=should reject requests without a certificate", func(t *testing.T) {
+		rejected(t, login(t, true, "", http.StatusBadRequest, publicTS.URL))
+	})
+
+	t.Run("case=should reject invalid certificates", func(t *testing.T) {
+		other := mtls.NewTestCA(t, "Other CA")
+		for _, value := range []string{
+			"not a certificate",
+			escaped(other.Issue(t, x509.Certificate{Subject: pkix.Name{CommonName: "alice"}})),
+			escaped(ca.Issue(t, x509.Certificate{Subject: pkix.Name{CommonName: "alice"}, NotAfter: time.Now().Add(-time.Minute)})),
+			escaped(ca.Issue(t, x509.Certificate{Subject: pkix.Name{CommonName: "alice"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})),
+		} {
+			rejected(t, login(t, true, value, http.StatusBadRequest, publicTS.URL))
+		}
+	})
+
+	t.Run("case=should reject certificates without identity", func(t *testing.T) {
+		bob := ca.Issue(t, x509.Certificate{Subject: pkix.Name{CommonName: "bob"}})
+		rejected(t, login(t, true, escaped(bob), http.StatusBadRequest, publicTS.URL))
+	})
+
+	t.Run("case=should only accept certificates forwarded by trusted proxies", func(t *testing.T) {
+		set(t, config.ViperKeyMTLSTrustedProxies, []string{"10.0.0.0/8"})
+		rejected(t, login(t, true, escaped(alice), http.StatusBadRequest, publicTS.URL))
+
+		conf.MustSet(config.ViperKeyMTLSTrustedProxies, []string{"10.0.0.0/8", "127.0.0.1"})
+		login(t, true, escaped(alice), http.StatusOK, publicTS.URL)
+	})
+
+	t.Run("case=should refuse to sign in without trusted proxies", func(t *testing.T) {
+		set(t, config.ViperKeyMTLSTrustedProxies, []string{})
+		body := login(t, true, escaped(alice), http.StatusInternalServerError, publicTS.URL)
+		assert.False(t, gjson.Get(body, "session").Exists(), "%s", body)
+
+		set(t, config.ViperKeyMTLSHeaderFormat, mtls.HeaderFormatSHA256)
+		set(t, config.ViperKeyMTLSIdentifierSource, mtls.IdentifierSourceSHA256)
+		set(t, config.ViperKeyMTLSCACertificateURL, "")
+		sum := sha256.Sum256(alice.Raw)
+		body = login(t, true, hex.EncodeToString(sum[:]), http.StatusInternalServerError, publicTS.URL)
+		assert.False(t, gjson.Get(body, "session").Exists(), "%s", body)
+	})
+
+	t.Run("case=should refuse to sign in with PEM certificates without CA certificates", func(t *testing.T) {
+		set(t, config.ViperKeyMTLSCACertificateURL, "")
+		self := mtls.NewTestCA(t, "alice")
+		for _, value := range []string{escaped(alice), escaped(self.Certificate)} {
+			body := login(t, true, value, http.StatusInternalServerError, publicTS.URL)
+			assert.False(t, gjson.Get(body, "session").Exists(), "%s", body)
+		}
+	})
+
+	t.Run("case=should map the subject alternative name", func(t *testing.T) {
+		set(t, config.ViperKeyMTLSIdentifierSource, mtls.IdentifierSourceSANEmail)
+		body := login(t, true, escaped(alice), http.StatusOK, publicTS.URL)
+		assert.Equal(t, i.ID.String(), gjson.Get(body, "session.identity.id").String(), "%s", body)
+
+		conf.MustSet(config.ViperKeyMTLSIdentifierSource, mtls.IdentifierSourceSANDNS)
+		rejected(t, login(t, true, escaped(alice), http.StatusBadRequest, publicTS.URL))
+	})
+
+	t.Run("case=should sign in with a forwarded fingerprint", func(t *testing.T) {
+		sum := sha256.Sum256(alice.Raw)
+		require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentity(context.Background(), withIdentifier(i, hex.EncodeToString(sum[:]))))
+
+		set(t, config.ViperKeyMTLSHeaderFormat, mtls.HeaderFormatSHA256)
+		set(t, config.ViperKeyMTLSIdentifierSource, mtls.IdentifierSourceSHA256)
+		set(t, config.ViperKeyMTLSCACertificateURL, "")
+
+		body := login(t, true, hex.EncodeToString(sum[:]), http.StatusOK, publicTS.URL)
+		assert.Equal(t, i.ID.String(), gjson.Get(body, "session.identity.id").String(), "%s", body)
+
+		rejected(t, login(t, true, "00", http.StatusBadRequest, publicTS.URL))
+	})
+}
+
+func withIdentifier(i *identity.Identity, identifier string) *identity.Identity {
+	c := i.Credentials[identity.CredentialsTypePassword]
+	c.Identifiers = append(c.Identifiers, identifier)
+	i.SetCredentials(identity.CredentialsTypePassword, c)
+	return i
+}
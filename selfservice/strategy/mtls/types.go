@@ -0,0 +1,19 @@
+package mtls
+
+import (
+	"github.com/ory/kratos/selfservice/form"
+)
+
+type (
+	// CompleteSelfServiceLoginFlowWithMTLSMethod is used to decode the login form payload. The client
+	// certificate is forwarded by the reverse proxy in a header.
+	CompleteSelfServiceLoginFlowWithMTLSMethod struct {
+		// Sending the anti-csrf token is only required for browser login flows.
+		CSRFToken string `form:"csrf_token" json:"csrf_token"`
+	}
+)
+
+// FlowMethod contains the configuration for this selfservice strategy.
+type FlowMethod struct {
+	*form.HTMLForm
+}
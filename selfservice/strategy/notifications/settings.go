@@ -0,0 +1,199 @@
+package notifications
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/markbates/pkger"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/pkgerx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteSettings = "/self-service/settings/methods/notifications"
+)
+
+func (s *Strategy) RegisterSettingsRoutes(public *x.RouterPublic) {
+	s.d.CSRFHandler().IgnorePath(RouteSettings)
+
+	public.POST(RouteSettings, s.d.SessionHandler().IsAuthenticated(s.submitSettingsFlow, settings.OnUnauthenticated(s.c, s.d)))
+	public.GET(RouteSettings, s.d.SessionHandler().IsAuthenticated(s.submitSettingsFlow, settings.OnUnauthenticated(s.c, s.d)))
+}
+
+// nolint:deadcode,unused
+// swagger:parameters completeSelfServiceSettingsFlowWithNotificationsMethod
+type completeSelfServiceSettingsFlowWithNotificationsMethod struct {
+	// in: body
+	Body CompleteSelfServiceSettingsFlowWithNotificationsMethod
+
+	// Flow is flow ID.
+	//
+	// in: query
+	Flow string `json:"flow"`
+}
+
+type CompleteSelfServiceSettingsFlowWithNotificationsMethod struct {
+	// Notifications are the updated notification preferences.
+	//
+	// required: true
+	Notifications identity.NotificationPreferences `json:"notifications"`
+
+	// CSRFToken is the anti-CSRF token
+	//
+	// type: string
+	CSRFToken string `json:"csrf_token"`
+
+	// Flow is flow ID.
+	//
+	// swagger:ignore
+	Flow string `json:"flow"`
+}
+
+func (p *CompleteSelfServiceSettingsFlowWithNotificationsMethod) GetFlowID() uuid.UUID {
+	return x.ParseUUID(p.Flow)
+}
+
+func (p *CompleteSelfServiceSettingsFlowWithNotificationsMethod) SetFlowID(rid uuid.UUID) {
+	p.Flow = rid.String()
+}
+
+// swagger:route POST /self-service/settings/methods/notifications public completeSelfServiceSettingsFlowWithNotificationsMethod
+//
+// Complete Settings Flow with Notifications Method
+//
+// Use this endpoint to complete a settings flow by sending an identity's updated notification preferences. Messages
+// which are not required for the security of the account are only sent on the channels the identity agreed to.
+// Unchecked channels may be omitted from the payload. This endpoint behaves differently for API and browser flows.
+//
+// API-initiated flows expect `application/json` to be sent in the body and respond with
+//   - HTTP 200 and an application/json body with the session token on success;
+//   - HTTP 302 redirect to a fresh settings flow if the original flow expired with the appropriate error messages set;
+//   - HTTP 400 on form validation errors.
+//   - HTTP 401 when the endpoint is called without a valid session token.
+//
+// Browser flows expect `application/x-www-form-urlencoded` to be sent in the body and responds with
+//   - a HTTP 302 redirect to the post/after settings URL or the `return_to` value if it was set and if the flow succeeded;
+//   - a HTTP 302 redirect to the Settings UI URL with the flow ID containing the validation errors otherwise.
+//
+// More information can be found at [ORY Kratos User Settings & Profile Management Documentation](../self-service/flows/user-settings).
+//
+//     Consumes:
+//     - application/json
+//     - application/x-www-form-urlencoded
+//
+//     Produces:
+//     - application/json
+//
+//     Security:
+//       sessionToken:
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: settingsViaApiResponse
+//       302: emptyResponse
+//       400: settingsFlow
+//       401: genericError
+//       500: genericError
+func (s *Strategy) submitSettingsFlow(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var p CompleteSelfServiceSettingsFlowWithNotificationsMethod
+	ctxUpdate, err := settings.PrepareUpdate(s.d, w, r, settings.ContinuityKey(s.SettingsStrategyID()), &p)
+	if errors.Is(err, settings.ErrContinuePreviousAction) {
+		s.continueSettingsFlow(w, r, ctxUpdate, &p)
+		return
+	} else if err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, &p, err)
+		return
+	}
+
+	if err := s.decodeSettingsFlow(r, &p); err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, &p, err)
+		return
+	}
+
+	// This does not come from the payload!
+	p.Flow = ctxUpdate.Flow.ID.String()
+	s.continueSettingsFlow(w, r, ctxUpdate, &p)
+}
+
+func (s *Strategy) decodeSettingsFlow(r *http.Request, dest interface{}) error {
+	compiler, err := decoderx.HTTPRawJSONSchemaCompiler(pkgerx.MustRead(pkger.Open("/selfservice/strategy/notifications/.schema/settings.schema.json")))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return s.dc.Decode(r, dest, compiler,
+		decoderx.HTTPDecoderSetValidatePayloads(false),
+	)
+}
+
+func (s *Strategy) continueSettingsFlow(
+	w http.ResponseWriter, r *http.Request,
+	ctxUpdate *settings.UpdateContext, p *CompleteSelfServiceSettingsFlowWithNotificationsMethod,
+) {
+	if err := flow.VerifyRequest(r, ctxUpdate.Flow.Type, s.c.DisableAPIFlowEnforcement(), s.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, p, err)
+		return
+	}
+
+	i, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), ctxUpdate.Session.Identity.ID)
+	if err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, p, err)
+		return
+	}
+
+	preferences := p.Notifications
+	i.NotificationPreferences = &preferences
+	if err := s.d.SettingsHookExecutor().PostSettingsHook(w, r,
+		s.SettingsStrategyID(), ctxUpdate, i); err != nil {
+		s.handleSettingsError(w, r, ctxUpdate, p, err)
+		return
+	}
+}
+
+func (s *Strategy) PopulateSettingsMethod(r *http.Request, id *identity.Identity, f *settings.Flow) error {
+	hf := &form.HTMLForm{Action: urlx.CopyWithQuery(urlx.AppendPaths(s.c.SelfPublicURL(), RouteSettings),
+		url.Values{"flow": {f.ID.String()}}).String(), Fields: form.Fields{}, Method: "POST"}
+
+	preferences := id.NotificationPreferences
+	if preferences == nil {
+		preferences = identity.DefaultNotificationPreferences()
+	}
+	setValues(hf, preferences)
+	hf.SetCSRF(s.d.GenerateCSRFToken(r))
+
+	f.Methods[s.SettingsStrategyID()] = &settings.FlowMethod{
+		Method: s.SettingsStrategyID(),
+		Config: &settings.FlowMethodConfig{FlowMethodConfigurator: &FlowMethod{HTMLForm: hf}},
+	}
+	return nil
+}
+
+func setValues(c form.ValueSetter, p *identity.NotificationPreferences) {
+	c.SetValue("notifications.email", p.Email)
+	c.SetValue("notifications.sms", p.SMS)
+	c.SetValue("notifications.security_only", p.SecurityOnly)
+}
+
+func (s *Strategy) handleSettingsError(w http.ResponseWriter, r *http.Request, ctxUpdate *settings.UpdateContext, p *CompleteSelfServiceSettingsFlowWithNotificationsMethod, err error) {
+	var id *identity.Identity
+	if ctxUpdate.Flow != nil {
+		ctxUpdate.Flow.Methods[s.SettingsStrategyID()].Config.Reset()
+		setValues(ctxUpdate.Flow.Methods[s.SettingsStrategyID()].Config, &p.Notifications)
+		ctxUpdate.Flow.Methods[s.SettingsStrategyID()].Config.SetCSRF(s.d.GenerateCSRFToken(r))
+		id = ctxUpdate.Session.Identity
+	}
+
+	s.d.SettingsFlowErrorHandler().WriteFlowError(w, r, s.SettingsStrategyID(), ctxUpdate.Flow, id, err)
+}
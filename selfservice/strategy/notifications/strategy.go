@@ -0,0 +1,64 @@
+package notifications
+
+import (
+	"github.com/ory/x/decoderx"
+
+	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	StrategyNotifications = "notifications"
+)
+
+var _ settings.Strategy = new(Strategy)
+
+type (
+	strategyDependencies interface {
+		x.CSRFProvider
+		x.CSRFTokenGeneratorProvider
+		x.WriterProvider
+		x.LoggingProvider
+		x.ClockProvider
+
+		continuity.ManagementProvider
+
+		session.HandlerProvider
+		session.ManagementProvider
+
+		identity.PrivilegedPoolProvider
+
+		errorx.ManagementProvider
+
+		settings.HookExecutorProvider
+		settings.ErrorHandlerProvider
+		settings.FlowPersistenceProvider
+	}
+
+	// Strategy lets identities choose on which channels they want to receive messages which are not required for
+	// the security of their account. The courier enforces these preferences.
+	Strategy struct {
+		c  *config.Provider
+		d  strategyDependencies
+		dc *decoderx.HTTP
+	}
+)
+
+// swagger:model settingsNotificationsFormConfig
+type FlowMethod struct {
+	*form.HTMLForm
+}
+
+func NewStrategy(d strategyDependencies, c *config.Provider) *Strategy {
+	return &Strategy{c: c, d: d, dc: decoderx.NewHTTP()}
+}
+
+func (s *Strategy) SettingsStrategyID() string {
+	return StrategyNotifications
+}
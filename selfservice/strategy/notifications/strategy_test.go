@@ -0,0 +1,107 @@
+package notifications_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/assertx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/strategy/notifications"
+	"github.com/ory/kratos/x"
+)
+
+func TestSettings(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+	conf.MustSet(config.ViperKeySelfServiceBrowserDefaultReturnTo, "https://www.ory.sh/")
+	testhelpers.StrategyEnable(t, conf, notifications.StrategyNotifications, true)
+
+	_ = testhelpers.NewSettingsUIEchoServer(t, reg)
+	_ = testhelpers.NewErrorTestServer(t, reg)
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+
+	newIdentity := func(email string) *identity.Identity {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(`{"email":"` + email + `"}`)
+		return i
+	}
+
+	apiIdentity := newIdentity("notifications-api@ory.sh")
+	browserIdentity := newIdentity("notifications-browser@ory.sh")
+	apiUser := testhelpers.NewHTTPClientWithIdentitySessionToken(t, reg, apiIdentity)
+	browserUser := testhelpers.NewHTTPClientWithIdentitySessionCookie(t, reg, browserIdentity)
+
+	t.Run("case=shows the default preferences", func(t *testing.T) {
+		rs := testhelpers.InitializeSettingsFlowViaAPI(t, apiUser, publicTS)
+		f := testhelpers.GetSettingsFlowMethodConfig(t, rs.Payload, notifications.StrategyNotifications)
+
+		values := map[string]interface{}{}
+		for _, field := range f.Fields {
+			assert.NotNil(t, field.Type)
+			values[*field.Name] = field.Value
+		}
+		assertx.EqualAsJSON(t, map[string]interface{}{
+			"notifications.email":         true,
+			"notifications.sms":           true,
+			"notifications.security_only": false,
+			"csrf_token":                  values["csrf_token"],
+		}, values)
+	})
+
+	t.Run("type=api", func(t *testing.T) {
+		rs := testhelpers.InitializeSettingsFlowViaAPI(t, apiUser, publicTS)
+		f := testhelpers.GetSettingsFlowMethodConfig(t, rs.Payload, notifications.StrategyNotifications)
+
+		actual, res := testhelpers.SettingsMakeRequest(t, true, f, apiUser, `{"notifications":{"sms":true,"security_only":true}}`)
+		require.EqualValues(t, http.StatusOK, res.StatusCode, "%s", actual)
+		assert.EqualValues(t, "success", gjson.Get(actual, "flow.state").String(), "%s", actual)
+
+		i, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), apiIdentity.ID)
+		require.NoError(t, err)
+		require.NotNil(t, i.NotificationPreferences)
+		assert.Equal(t, identity.NotificationPreferences{SMS: true, SecurityOnly: true}, *i.NotificationPreferences)
+	})
+
+	t.Run("type=browser", func(t *testing.T) {
+		rs := testhelpers.InitializeSettingsFlowViaBrowser(t, browserUser, publicTS)
+		f := testhelpers.GetSettingsFlowMethodConfig(t, rs.Payload, notifications.StrategyNotifications)
+
+		values := url.Values{"notifications.email": {"true"}}
+		for _, field := range f.Fields {
+			if *field.Name == "csrf_token" {
+				values.Set("csrf_token", field.Value.(string))
+			}
+		}
+
+		actual, res := testhelpers.SettingsMakeRequest(t, false, f, browserUser, values.Encode())
+		require.EqualValues(t, http.StatusOK, res.StatusCode, "%s", actual)
+		assert.EqualValues(t, "success", gjson.Get(actual, "state").String(), "%s", actual)
+
+		assert.True(t, gjson.Get(actual, `methods.notifications.config.fields.#(name=="notifications.email").value`).Bool(), "%s", actual)
+
+		i, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), browserIdentity.ID)
+		require.NoError(t, err)
+		assert.Equal(t, &identity.NotificationPreferences{Email: true}, i.NotificationPreferences)
+	})
+
+	t.Run("case=rejects invalid csrf tokens/type=browser", func(t *testing.T) {
+		rs := testhelpers.InitializeSettingsFlowViaBrowser(t, browserUser, publicTS)
+		f := testhelpers.GetSettingsFlowMethodConfig(t, rs.Payload, notifications.StrategyNotifications)
+
+		actual, res := testhelpers.SettingsMakeRequest(t, false, f, browserUser,
+			url.Values{"notifications.sms": {"true"}, "csrf_token": {"invalid"}}.Encode())
+		assert.EqualValues(t, http.StatusOK, res.StatusCode)
+		assertx.EqualAsJSON(t, x.ErrInvalidCSRFToken, json.RawMessage(gjson.Get(actual, "0").Raw))
+	})
+}
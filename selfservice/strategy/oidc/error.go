@@ -13,4 +13,16 @@ var (
 
 	ErrAPIFlowNotSupported = herodot.ErrBadRequest.WithError("API-based flows are not supported for this method").
 				WithReasonf("Social Sign In and OpenID Connect are only supported for flows initiated using the Browser endpoint.")
+
+	ErrIDTokenNotSupported = herodot.ErrBadRequest.WithError("the provider does not support ID Token submission").
+				WithReasonf("The selected provider is unable to verify ID Tokens obtained by native apps.")
+
+	ErrIDTokenNonceMismatch = herodot.ErrBadRequest.WithError("id_token nonce mismatch").
+				WithReasonf(`The "nonce" claim of the ID Token does not match the nonce submitted with it.`)
 )
+
+// NewErrLoginOnlyProvider is returned if signing in with a login-only provider would create a new identity.
+func NewErrLoginOnlyProvider(provider string) error {
+	return herodot.ErrBadRequest.WithError("the provider can not be used to sign up").
+		WithReasonf(`No account is linked to this "%s" account. Please sign up using a different method and link your "%s" account in your account settings afterwards.`, provider, provider)
+}
@@ -0,0 +1,396 @@
+package oidc
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/jsonx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteProviders = "/oidc-providers"
+	RouteProvider  = RouteProviders + "/:id"
+
+	ProviderSourceConfig = "config"
+	ProviderSourceAPI    = "api"
+)
+
+type (
+	providerHandlerDependencies interface {
+		x.WriterProvider
+		x.LoggingProvider
+		ProviderPersistenceProvider
+	}
+	ProviderHandlerProvider interface {
+		OIDCProviderHandler() *ProviderHandler
+	}
+
+	// ProviderHandler manages OpenID Connect providers at runtime. Providers created using the admin API are
+	// stored in the database and used in addition to the providers of the configuration file, which can not be
+	// changed using the API.
+	ProviderHandler struct {
+		d providerHandlerDependencies
+		c *config.Provider
+	}
+
+	// ProviderResponse is an OpenID Connect provider without its client secret.
+	//
+	// swagger:model oidcProvider
+	ProviderResponse struct {
+		Configuration
+
+		// ClientSecret is never exposed. It shadows the client secret of the embedded configuration.
+		ClientSecret string `json:"client_secret,omitempty"`
+
+		// Source is `config` for providers of the configuration file and `api` for providers created using the
+		// admin API.
+		//
+		// required: true
+		Source string `json:"source"`
+	}
+)
+
+func NewProviderHandler(d providerHandlerDependencies, c *config.Provider) *ProviderHandler {
+	return &ProviderHandler{d: d, c: c}
+}
+
+func (h *ProviderHandler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteProviders, h.list)
+	admin.GET(RouteProvider, h.get)
+	admin.POST(RouteProviders, h.create)
+	admin.PUT(RouteProvider, h.update)
+	admin.DELETE(RouteProvider, h.delete)
+}
+
+func (h *ProviderHandler) providers(r *http.Request) ([]ProviderResponse, error) {
+	file, err := fileConfiguration(h.c)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := configuration(r.Context(), h.c, h.d.OIDCProviderPersister())
+	if err != nil {
+		return nil, err
+	}
+
+	providers := make([]ProviderResponse, len(merged.Providers))
+	for k, p := range merged.Providers {
+		providers[k] = ProviderResponse{Configuration: p, Source: ProviderSourceAPI}
+		if file.has(p.ID) {
+			providers[k].Source = ProviderSourceConfig
+		}
+	}
+	return providers, nil
+}
+
+// writable returns an error if the provider is defined in the configuration file.
+func (h *ProviderHandler) writable(id string) error {
+	file, err := fileConfiguration(h.c)
+	if err != nil {
+		return err
+	}
+
+	if file.has(id) {
+		return errors.WithStack(herodot.ErrForbidden.
+			WithReasonf(`OpenID Connect provider "%s" is defined in the configuration file and can not be changed using the API.`, id))
+	}
+	return nil
+}
+
+func (h *ProviderHandler) validate(p *Configuration) error {
+	if p.ID == "" || p.ClientID == "" {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("OpenID Connect providers require an id and a client_id."))
+	}
+
+	if _, err := (&ConfigurationCollection{Providers: []Configuration{*p}}).Provider(p.ID, h.c.SelfPublicURL()); err != nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("%s", err))
+	}
+	return nil
+}
+
+// swagger:route GET /oidc-providers admin listOIDCProviders
+//
+// List OpenID Connect Providers
+//
+// Lists the providers of the configuration file followed by the providers created using the admin API. Client
+// secrets are never returned.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: oidcProviderList
+//       500: genericError
+func (h *ProviderHandler) list(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	providers, err := h.providers(r)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, providers)
+}
+
+// A list of OpenID Connect providers.
+//
+// swagger:response oidcProviderList
+// nolint:deadcode,unused
+type oidcProviderListResponse struct {
+	// in: body
+	// type: array
+	Body []ProviderResponse
+}
+
+// An OpenID Connect provider.
+//
+// swagger:response oidcProvider
+// nolint:deadcode,unused
+type oidcProviderResponse struct {
+	// in: body
+	Body ProviderResponse
+}
+
+// swagger:parameters getOIDCProvider deleteOIDCProvider
+// nolint:deadcode,unused
+type oidcProviderParameters struct {
+	// ID is the provider's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route GET /oidc-providers/{id} admin getOIDCProvider
+//
+// Get an OpenID Connect Provider
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: oidcProvider
+//       404: genericError
+//       500: genericError
+func (h *ProviderHandler) get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	providers, err := h.providers(r)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	for _, p := range providers {
+		if p.ID == ps.ByName("id") {
+			h.d.Writer().Write(w, r, p)
+			return
+		}
+	}
+
+	h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReasonf(`OpenID Connect provider "%s" does not exist.`, ps.ByName("id"))))
+}
+
+// swagger:parameters createOIDCProvider
+// nolint:deadcode,unused
+type createOIDCProviderParameters struct {
+	// in: body
+	Body Configuration
+}
+
+// swagger:route POST /oidc-providers admin createOIDCProvider
+//
+// Create an OpenID Connect Provider
+//
+// Creates a provider which is used in addition to the providers of the configuration file. The provider is
+// available to all instances without a configuration change.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       201: oidcProvider
+//       400: genericError
+//       403: genericError
+//       409: genericError
+//       500: genericError
+func (h *ProviderHandler) create(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var p Configuration
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&p); err != nil {
+		h.d.Writer().WriteErrorCode(w, r, http.StatusBadRequest, errors.WithStack(err))
+		return
+	}
+
+	if err := h.validate(&p); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.writable(p.ID); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	sp, err := newStoredProvider(&p)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.d.OIDCProviderPersister().CreateOIDCProvider(r.Context(), sp); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Audit().
+		WithRequest(r).
+		WithField("provider", p.ID).
+		Info("An OpenID Connect provider was created by an administrator.")
+	h.d.Writer().WriteCreated(w, r,
+		urlx.AppendPaths(h.c.SelfAdminURL(), RouteProviders, p.ID).String(),
+		ProviderResponse{Configuration: p, Source: ProviderSourceAPI},
+	)
+}
+
+// swagger:parameters updateOIDCProvider
+// nolint:deadcode,unused
+type updateOIDCProviderParameters struct {
+	// ID is the provider's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// in: body
+	Body Configuration
+}
+
+// swagger:route PUT /oidc-providers/{id} admin updateOIDCProvider
+//
+// Update an OpenID Connect Provider
+//
+// Replaces the configuration of a provider created using the admin API. If the client secret is omitted, the
+// stored client secret is kept. Providers of the configuration file can not be updated.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: oidcProvider
+//       400: genericError
+//       403: genericError
+//       404: genericError
+//       500: genericError
+func (h *ProviderHandler) update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id := ps.ByName("id")
+	if err := h.writable(id); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	var p Configuration
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&p); err != nil {
+		h.d.Writer().WriteErrorCode(w, r, http.StatusBadRequest, errors.WithStack(err))
+		return
+	}
+
+	if p.ID == "" {
+		p.ID = id
+	} else if p.ID != id {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The ID of an OpenID Connect provider can not be changed.")))
+		return
+	}
+
+	stored, err := h.d.OIDCProviderPersister().GetOIDCProvider(r.Context(), id)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if p.ClientSecret == "" {
+		var previous Configuration
+		if err := jsonx.NewStrictDecoder(bytes.NewReader(stored.Config)).Decode(&previous); err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(err))
+			return
+		}
+		p.ClientSecret = previous.ClientSecret
+	}
+
+	if err := h.validate(&p); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	updated, err := newStoredProvider(&p)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	stored.Config = updated.Config
+	if err := h.d.OIDCProviderPersister().UpdateOIDCProvider(r.Context(), stored); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Audit().
+		WithRequest(r).
+		WithField("provider", id).
+		Info("An OpenID Connect provider was updated by an administrator.")
+	h.d.Writer().Write(w, r, ProviderResponse{Configuration: p, Source: ProviderSourceAPI})
+}
+
+// swagger:route DELETE /oidc-providers/{id} admin deleteOIDCProvider
+//
+// Delete an OpenID Connect Provider
+//
+// Deletes a provider created using the admin API. Identities keep their credentials of the provider but can no
+// longer sign in with it. Providers of the configuration file can not be deleted.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       403: genericError
+//       404: genericError
+//       500: genericError
+func (h *ProviderHandler) delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id := ps.ByName("id")
+	if err := h.writable(id); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.d.OIDCProviderPersister().DeleteOIDCProvider(r.Context(), id); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Audit().
+		WithRequest(r).
+		WithField("provider", id).
+		Info("An OpenID Connect provider was deleted by an administrator.")
+	w.WriteHeader(http.StatusNoContent)
+}
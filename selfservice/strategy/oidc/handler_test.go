@@ -0,0 +1,100 @@
+package oidc_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+	"github.com/ory/kratos/x"
+)
+
+func TestProviderHandler(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	viperSetProviderConfig(t, conf, oidc.Configuration{ID: "file", Provider: "github", ClientID: "file-client", ClientSecret: "file-secret"})
+
+	router := x.NewRouterAdmin()
+	reg.OIDCProviderHandler().RegisterAdminRoutes(router)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+	conf.MustSet(config.ViperKeyAdminBaseURL, ts.URL)
+
+	send := func(t *testing.T, method, href, body string, expectCode int) gjson.Result {
+		req, err := http.NewRequest(method, ts.URL+href, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		res, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		actual, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.EqualValues(t, expectCode, res.StatusCode, "%s", actual)
+		return gjson.ParseBytes(actual)
+	}
+
+	t.Run("case=should list the providers of the configuration file", func(t *testing.T) {
+		actual := send(t, "GET", oidc.RouteProviders, "", http.StatusOK)
+		assert.Equal(t, "file", actual.Get("0.id").String(), "%s", actual.Raw)
+		assert.Equal(t, oidc.ProviderSourceConfig, actual.Get("0.source").String(), "%s", actual.Raw)
+		assert.False(t, actual.Get("0.client_secret").Exists(), "%s", actual.Raw)
+	})
+
+	t.Run("case=should create a provider", func(t *testing.T) {
+		actual := send(t, "POST", oidc.RouteProviders, `{"id":"api","provider":"gitlab","client_id":"api-client","client_secret":"api-secret","mapper_url":"file://./stub/oidc.hydra.jsonnet"}`, http.StatusCreated)
+		assert.Equal(t, oidc.ProviderSourceAPI, actual.Get("source").String(), "%s", actual.Raw)
+		assert.False(t, actual.Get("client_secret").Exists(), "%s", actual.Raw)
+
+		actual = send(t, "GET", oidc.RouteProviders+"/api", "", http.StatusOK)
+		assert.Equal(t, "api-client", actual.Get("client_id").String(), "%s", actual.Raw)
+
+		strategy := oidc.NewStrategy(reg, conf)
+		collection, err := strategy.Config()
+		require.NoError(t, err)
+		require.Len(t, collection.Providers, 2)
+		assert.Equal(t, "api-secret", collection.Providers[1].ClientSecret, "the strategy uses providers created using the API")
+	})
+
+	t.Run("case=should reject invalid providers", func(t *testing.T) {
+		for _, body := range []string{
+			`{"id":"invalid","provider":"unknown","client_id":"client"}`,
+			`{"id":"invalid","provider":"github"}`,
+			`{"id":"invalid","provider":"github","client_id":"client","unknown":true}`,
+		} {
+			send(t, "POST", oidc.RouteProviders, body, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("case=should reject duplicate IDs", func(t *testing.T) {
+		send(t, "POST", oidc.RouteProviders, `{"id":"api","provider":"github","client_id":"client"}`, http.StatusConflict)
+		send(t, "POST", oidc.RouteProviders, `{"id":"file","provider":"github","client_id":"client"}`, http.StatusForbidden)
+	})
+
+	t.Run("case=should update a provider and keep its secret", func(t *testing.T) {
+		actual := send(t, "PUT", oidc.RouteProviders+"/api", `{"provider":"gitlab","client_id":"updated-client"}`, http.StatusOK)
+		assert.Equal(t, "updated-client", actual.Get("client_id").String(), "%s", actual.Raw)
+
+		stored, err := reg.OIDCProviderPersister().GetOIDCProvider(context.Background(), "api")
+		require.NoError(t, err)
+		assert.Equal(t, "api-secret", gjson.GetBytes(stored.Config, "client_secret").String())
+
+		send(t, "PUT", oidc.RouteProviders+"/api", `{"id":"renamed","provider":"gitlab","client_id":"client"}`, http.StatusBadRequest)
+		send(t, "PUT", oidc.RouteProviders+"/missing", `{"provider":"gitlab","client_id":"client"}`, http.StatusNotFound)
+		send(t, "PUT", oidc.RouteProviders+"/file", `{"provider":"github","client_id":"client"}`, http.StatusForbidden)
+	})
+
+	t.Run("case=should delete a provider", func(t *testing.T) {
+		send(t, "DELETE", oidc.RouteProviders+"/file", "", http.StatusForbidden)
+		send(t, "DELETE", oidc.RouteProviders+"/api", "", http.StatusNoContent)
+		send(t, "DELETE", oidc.RouteProviders+"/api", "", http.StatusNotFound)
+		send(t, "GET", oidc.RouteProviders+"/api", "", http.StatusNotFound)
+		assert.Len(t, send(t, "GET", oidc.RouteProviders, "", http.StatusOK).Array(), 1)
+	})
+}
@@ -0,0 +1,316 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/errorsx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteProviderStatus = "/oidc-provider-status"
+)
+
+var providerUp = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "kratos_oidc_provider_up",
+		Help: "Whether the OpenID Connect provider was reachable during the last health check (1) or not (0).",
+	},
+	[]string{"provider"},
+)
+
+func init() {
+	prometheus.MustRegister(providerUp)
+}
+
+type (
+	healthCheckerDependencies interface {
+		x.LoggingProvider
+		x.WriterProvider
+		ProviderPersistenceProvider
+	}
+	HealthCheckerProvider interface {
+		OIDCHealthChecker() *HealthChecker
+	}
+
+	// HealthChecker periodically fetches the discovery document and the JSON Web Key Set of each OpenID Connect
+	// provider. Providers which do not support OpenID Connect Discovery are reachable if their token endpoint
+	// responds.
+	HealthChecker struct {
+		d healthCheckerDependencies
+		c *config.Provider
+
+		l        sync.RWMutex
+		statuses []ProviderStatus
+		checked  bool
+	}
+
+	// ProviderStatus is the result of the last health check of an OpenID Connect provider.
+	//
+	// swagger:model oidcProviderStatus
+	ProviderStatus struct {
+		// ID is the provider's ID.
+		//
+		// required: true
+		ID string `json:"id"`
+
+		// Provider is the provider's type, for example `generic` or `google`.
+		//
+		// required: true
+		Provider string `json:"provider"`
+
+		// Reachable is true if the provider responded as expected.
+		//
+		// required: true
+		Reachable bool `json:"reachable"`
+
+		// Error explains why the provider is not reachable.
+		Error string `json:"error,omitempty"`
+
+		// CheckedAt is the time of the health check.
+		//
+		// required: true
+		CheckedAt time.Time `json:"checked_at"`
+	}
+)
+
+func NewHealthChecker(d healthCheckerDependencies, c *config.Provider) *HealthChecker {
+	return &HealthChecker{d: d, c: c}
+}
+
+func (h *HealthChecker) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteProviderStatus, h.status)
+}
+
+// Check probes all configured providers and records their status.
+func (h *HealthChecker) Check(ctx context.Context) ([]ProviderStatus, error) {
+	statuses := []ProviderStatus{}
+	if h.c.SelfServiceStrategy(string(identity.CredentialsTypeOIDC)).Enabled {
+		conf, err := configuration(ctx, h.c, h.d.OIDCProviderPersister())
+		if err != nil {
+			return nil, err
+		}
+
+		client := &http.Client{Timeout: h.c.SelfServiceOIDCHealthCheckTimeout()}
+		for _, p := range conf.Providers {
+			status := ProviderStatus{ID: p.ID, Provider: p.Provider, Reachable: true}
+			if err := h.probe(ctx, client, p); err != nil {
+				status.Reachable = false
+				status.Error = err.Error()
+				if e, ok := errorsx.Cause(err).(*herodot.DefaultError); ok && e.ReasonField != "" {
+					status.Error = e.ReasonField
+				}
+				h.d.Logger().
+					WithField("provider", p.ID).
+					WithError(err).
+					Warn("OpenID Connect provider is unreachable.")
+			}
+			status.CheckedAt = time.Now().UTC()
+			statuses = append(statuses, status)
+		}
+	}
+
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	// Providers removed from the configuration must not be reported any longer.
+	providerUp.Reset()
+	for _, status := range statuses {
+		var up float64
+		if status.Reachable {
+			up = 1
+		}
+		providerUp.WithLabelValues(status.ID).Set(up)
+	}
+
+	h.statuses = statuses
+	h.checked = true
+	return statuses, nil
+}
+
+// Statuses returns the result of the last health check. If no health check was run yet, it runs one.
+func (h *HealthChecker) Statuses(ctx context.Context) ([]ProviderStatus, error) {
+	h.l.RLock()
+	statuses, checked := h.statuses, h.checked
+	h.l.RUnlock()
+
+	if !checked {
+		return h.Check(ctx)
+	}
+	return statuses, nil
+}
+
+// Watch checks the providers until the context is canceled.
+func (h *HealthChecker) Watch(ctx context.Context) {
+	for {
+		if _, err := h.Check(ctx); err != nil {
+			h.d.Logger().WithError(err).Error("Unable to check the OpenID Connect providers.")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(h.c.SelfServiceOIDCHealthCheckInterval()):
+		}
+	}
+}
+
+func (h *HealthChecker) probe(ctx context.Context, client *http.Client, p Configuration) error {
+	if issuer := discoveryIssuer(p); issuer != "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := fetchJSON(ctx, client, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return err
+		} else if discovery.JWKSURI == "" {
+			return errors.New("the discovery document does not contain a jwks_uri")
+		}
+
+		var keys struct {
+			Keys []json.RawMessage `json:"keys"`
+		}
+		if err := fetchJSON(ctx, client, discovery.JWKSURI, &keys); err != nil {
+			return err
+		} else if len(keys.Keys) == 0 {
+			return errors.New("the JSON Web Key Set does not contain any keys")
+		}
+		return nil
+	}
+
+	provider, err := (&ConfigurationCollection{Providers: []Configuration{p}}).Provider(p.ID, h.c.SelfPublicURL())
+	if err != nil {
+		return err
+	}
+
+	oauth2Config, err := provider.OAuth2(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := get(ctx, client, oauth2Config.Endpoint.TokenURL)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// Token endpoints reject GET requests, but they must not fail.
+	if res.StatusCode >= http.StatusInternalServerError {
+		return errors.Errorf("%s responded with status code %d", oauth2Config.Endpoint.TokenURL, res.StatusCode)
+	}
+	return nil
+}
+
+// discoveryIssuer returns the issuer URL used for OpenID Connect Discovery or an empty string if the provider
+// is a plain OAuth2 provider.
+func discoveryIssuer(p Configuration) string {
+	switch p.Provider {
+	case "generic":
+		return p.IssuerURL
+	case "google":
+		return "https://accounts.google.com"
+	case "gitlab":
+		if p.IssuerURL != "" {
+			return p.IssuerURL
+		}
+		return defaultEndpoint
+	case "microsoft":
+		if p.Tenant == "" {
+			return ""
+		}
+		return "https://login.microsoftonline.com/" + p.Tenant + "/v2.0"
+	}
+	return ""
+}
+
+func get(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return res, nil
+}
+
+func fetchJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	res, err := get(ctx, client, url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("%s responded with status code %d", url, res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return errors.Errorf("unable to decode the response of %s: %s", url, err)
+	}
+	return nil
+}
+
+// swagger:parameters getOIDCProviderStatus
+// nolint:deadcode,unused
+type getOIDCProviderStatusParameters struct {
+	// Refresh checks the providers now instead of returning the result of the last health check.
+	//
+	// in: query
+	Refresh bool `json:"refresh"`
+}
+
+// A list of OpenID Connect provider statuses.
+//
+// swagger:response oidcProviderStatusList
+// nolint:deadcode,unused
+type oidcProviderStatusListResponse struct {
+	// in: body
+	// type: array
+	Body []ProviderStatus
+}
+
+// swagger:route GET /oidc-provider-status admin getOIDCProviderStatus
+//
+// Get the Status of OpenID Connect Providers
+//
+// This endpoint returns the result of the last health check of each configured OpenID Connect provider. The
+// providers are checked periodically by fetching their discovery document and JSON Web Key Set, which detects
+// unreachable providers and broken TLS certificates before users run into errors.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: oidcProviderStatusList
+//       500: genericError
+func (h *HealthChecker) status(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	check := h.Statuses
+	if r.URL.Query().Get("refresh") == "true" {
+		check = h.Check
+	}
+
+	statuses, err := check(r.Context())
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, statuses)
+}
@@ -0,0 +1,121 @@
+package oidc_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+	"github.com/ory/kratos/x"
+)
+
+func TestHealthChecker(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	newIssuer := func(t *testing.T, keys []map[string]string) *httptest.Server {
+		mux := http.NewServeMux()
+		ts := httptest.NewServer(mux)
+		t.Cleanup(ts.Close)
+
+		mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"issuer": ts.URL, "jwks_uri": ts.URL + "/jwks"})
+		})
+		mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+		})
+		return ts
+	}
+
+	healthy := newIssuer(t, []map[string]string{{"kty": "RSA", "kid": "key"}})
+	withoutKeys := newIssuer(t, []map[string]string{})
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	viperSetProviderConfig(t, conf,
+		oidc.Configuration{ID: "healthy", Provider: "generic", IssuerURL: healthy.URL},
+		oidc.Configuration{ID: "without-keys", Provider: "generic", IssuerURL: withoutKeys.URL},
+		oidc.Configuration{ID: "down", Provider: "generic", IssuerURL: down.URL},
+		oidc.Configuration{ID: "microsoft", Provider: "microsoft"},
+	)
+
+	router := x.NewRouterAdmin()
+	reg.OIDCHealthChecker().RegisterAdminRoutes(router)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	status := func(t *testing.T, query string) gjson.Result {
+		res, err := ts.Client().Get(ts.URL + oidc.RouteProviderStatus + query)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.EqualValues(t, http.StatusOK, res.StatusCode, "%s", body)
+		return gjson.ParseBytes(body)
+	}
+
+	metrics := func(t *testing.T) map[string]float64 {
+		families, err := prometheus.DefaultGatherer.Gather()
+		require.NoError(t, err)
+
+		values := map[string]float64{}
+		for _, f := range families {
+			if f.GetName() != "kratos_oidc_provider_up" {
+				continue
+			}
+			for _, m := range f.GetMetric() {
+				values[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+			}
+		}
+		return values
+	}
+
+	t.Run("case=should check the providers on the first request", func(t *testing.T) {
+		actual := status(t, "")
+		require.Len(t, actual.Array(), 4, "%s", actual.Raw)
+
+		for k, expected := range []struct {
+			id        string
+			reachable bool
+			error     string
+		}{
+			{id: "healthy", reachable: true},
+			{id: "without-keys", error: "does not contain any keys"},
+			{id: "down", error: "connection refused"},
+			{id: "microsoft", error: "No Tenant specified"},
+		} {
+			p := actual.Get(strconv.Itoa(k))
+			assert.Equal(t, expected.id, p.Get("id").String(), "%s", actual.Raw)
+			assert.Equal(t, expected.reachable, p.Get("reachable").Bool(), "%s", actual.Raw)
+			assert.Contains(t, p.Get("error").String(), expected.error, "%s", actual.Raw)
+			assert.NotEmpty(t, p.Get("checked_at").String(), "%s", actual.Raw)
+		}
+
+		assert.Equal(t, map[string]float64{"healthy": 1, "without-keys": 0, "down": 0, "microsoft": 0}, metrics(t))
+	})
+
+	t.Run("case=should return the last result unless refreshed", func(t *testing.T) {
+		viperSetProviderConfig(t, conf, oidc.Configuration{ID: "healthy", Provider: "generic", IssuerURL: healthy.URL})
+
+		assert.Len(t, status(t, "").Array(), 4)
+		assert.Len(t, status(t, "?refresh=true").Array(), 1)
+		assert.Equal(t, map[string]float64{"healthy": 1}, metrics(t), "providers removed from the configuration are not reported")
+	})
+
+	t.Run("case=should not check providers if the strategy is disabled", func(t *testing.T) {
+		conf.MustSet("selfservice.methods.oidc.enabled", false)
+
+		statuses, err := reg.OIDCHealthChecker().Check(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, statuses)
+	})
+}
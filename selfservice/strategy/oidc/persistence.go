@@ -0,0 +1,129 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/kratos/x"
+)
+
+type (
+	// StoredProvider is an OpenID Connect provider configured using the admin API.
+	StoredProvider struct {
+		ID uuid.UUID `db:"id"`
+
+		// ProviderID is the ID of the provider used in callback URLs and credentials.
+		ProviderID string `db:"provider_id"`
+
+		// Config is the JSON encoded Configuration of the provider.
+		Config sqlxx.JSONRawMessage `db:"config"`
+
+		CreatedAt time.Time `db:"created_at"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+
+	ProviderPersister interface {
+		// CreateOIDCProvider persists a provider. Returns sqlcon.ErrUniqueViolation if a provider with the same
+		// provider ID exists already.
+		CreateOIDCProvider(ctx context.Context, p *StoredProvider) error
+
+		// GetOIDCProvider returns a provider by its provider ID or sqlcon.ErrNoRows.
+		GetOIDCProvider(ctx context.Context, providerID string) (*StoredProvider, error)
+
+		// ListOIDCProviders returns all providers, oldest first.
+		ListOIDCProviders(ctx context.Context) ([]StoredProvider, error)
+
+		// UpdateOIDCProvider updates the configuration of a provider. Returns sqlcon.ErrNoRows if the provider
+		// does not exist.
+		UpdateOIDCProvider(ctx context.Context, p *StoredProvider) error
+
+		// DeleteOIDCProvider deletes a provider by its provider ID. Returns sqlcon.ErrNoRows if the provider does
+		// not exist.
+		DeleteOIDCProvider(ctx context.Context, providerID string) error
+	}
+
+	ProviderPersistenceProvider interface {
+		OIDCProviderPersister() ProviderPersister
+	}
+)
+
+func (p StoredProvider) TableName() string {
+	return "selfservice_oidc_providers"
+}
+
+func newStoredProvider(p *Configuration) (*StoredProvider, error) {
+	config, err := json.Marshal(p)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &StoredProvider{ID: x.NewUUID(), ProviderID: p.ID, Config: config}, nil
+}
+
+func TestProviderPersister(p ProviderPersister) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+
+		newProvider := func(id string) *StoredProvider {
+			return &StoredProvider{ID: x.NewUUID(), ProviderID: id, Config: sqlxx.JSONRawMessage(`{"id":"` + id + `"}`)}
+		}
+
+		t.Run("case=not found", func(t *testing.T) {
+			_, err := p.GetOIDCProvider(ctx, "does-not-exist")
+			assert.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+			assert.True(t, errors.Is(p.UpdateOIDCProvider(ctx, newProvider("does-not-exist")), sqlcon.ErrNoRows))
+			assert.True(t, errors.Is(p.DeleteOIDCProvider(ctx, "does-not-exist"), sqlcon.ErrNoRows))
+		})
+
+		first, second := newProvider("stored-first"), newProvider("stored-second")
+
+		t.Run("case=create and get", func(t *testing.T) {
+			require.NoError(t, p.CreateOIDCProvider(ctx, first))
+			require.NoError(t, p.CreateOIDCProvider(ctx, second))
+
+			err := p.CreateOIDCProvider(ctx, newProvider("stored-first"))
+			assert.True(t, errors.Is(err, sqlcon.ErrUniqueViolation), "%+v", err)
+
+			actual, err := p.GetOIDCProvider(ctx, "stored-first")
+			require.NoError(t, err)
+			assert.Equal(t, first.ID, actual.ID)
+			assert.JSONEq(t, string(first.Config), string(actual.Config))
+		})
+
+		t.Run("case=list", func(t *testing.T) {
+			actual, err := p.ListOIDCProviders(ctx)
+			require.NoError(t, err)
+			var ids []string
+			for _, sp := range actual {
+				ids = append(ids, sp.ProviderID)
+			}
+			assert.Equal(t, []string{"stored-first", "stored-second"}, ids)
+		})
+
+		t.Run("case=update", func(t *testing.T) {
+			first.Config = sqlxx.JSONRawMessage(`{"id":"stored-first","client_id":"updated"}`)
+			require.NoError(t, p.UpdateOIDCProvider(ctx, first))
+
+			actual, err := p.GetOIDCProvider(ctx, "stored-first")
+			require.NoError(t, err)
+			assert.JSONEq(t, string(first.Config), string(actual.Config))
+		})
+
+		t.Run("case=delete", func(t *testing.T) {
+			require.NoError(t, p.DeleteOIDCProvider(ctx, "stored-first"))
+			_, err := p.GetOIDCProvider(ctx, "stored-first")
+			assert.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+
+			require.NoError(t, p.DeleteOIDCProvider(ctx, "stored-second"))
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/randx"
+	"github.com/ory/x/stringslice"
+)
+
+const (
+	// PKCEAuto uses PKCE if the provider advertises support for the S256 code challenge method.
+	PKCEAuto = "auto"
+	// PKCEForce always uses PKCE.
+	PKCEForce = "force"
+	// PKCENever never uses PKCE.
+	PKCENever = "never"
+
+	pkceMethodS256 = "S256"
+)
+
+// PKCEProvider is implemented by providers which are able to tell whether they support PKCE.
+type PKCEProvider interface {
+	PKCEMethodsSupported(ctx context.Context) ([]string, error)
+}
+
+// pkceVerifier returns a new code verifier if PKCE should be used for the provider, or an empty string otherwise.
+func pkceVerifier(ctx context.Context, provider Provider) (string, error) {
+	switch provider.Config().PKCE {
+	case PKCENever:
+		return "", nil
+	case PKCEForce:
+	case PKCEAuto, "":
+		p, ok := provider.(PKCEProvider)
+		if !ok {
+			return "", nil
+		}
+
+		methods, err := p.PKCEMethodsSupported(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		if !stringslice.Has(methods, pkceMethodS256) {
+			return "", nil
+		}
+	default:
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf(`OpenID Connect provider "%s" has an invalid pkce value "%s".`, provider.Config().ID, provider.Config().PKCE))
+	}
+
+	// RFC 7636 requires between 43 and 128 characters from the unreserved set.
+	verifier, err := randx.RuneSequence(64, []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-._~"))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(verifier), nil
+}
+
+func pkceChallenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func pkceAuthCodeURLOptions(verifier string) []oauth2.AuthCodeOption {
+	if verifier == "" {
+		return nil
+	}
+
+	return []oauth2.AuthCodeOption{
+		oauth2.SetAuthURLParam("code_challenge", pkceChallenge(verifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", pkceMethodS256),
+	}
+}
+
+func pkceExchangeOptions(verifier string) []oauth2.AuthCodeOption {
+	if verifier == "" {
+		return nil
+	}
+
+	return []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("code_verifier", verifier)}
+}
@@ -0,0 +1,87 @@
+package oidc
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+type pkceTestProvider struct {
+	*ProviderGenericOIDC
+	methods []string
+}
+
+func (p *pkceTestProvider) PKCEMethodsSupported(_ context.Context) ([]string, error) {
+	return p.methods, nil
+}
+
+type noPKCETestProvider struct {
+	Provider
+	c *Configuration
+}
+
+func (p *noPKCETestProvider) Config() *Configuration {
+	return p.c
+}
+
+func TestPKCE(t *testing.T) {
+	t.Run("case=computes the RFC 7636 challenge", func(t *testing.T) {
+		assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", pkceChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+	})
+
+	t.Run("case=decides whether to use PKCE", func(t *testing.T) {
+		for _, tc := range []struct {
+			mode     string
+			provider func(c *Configuration) Provider
+			expect   bool
+		}{
+			{mode: "", provider: func(c *Configuration) Provider {
+				return &pkceTestProvider{ProviderGenericOIDC: NewProviderGenericOIDC(c, nil), methods: []string{"plain", "S256"}}
+			}, expect: true},
+			{mode: PKCEAuto, provider: func(c *Configuration) Provider {
+				return &pkceTestProvider{ProviderGenericOIDC: NewProviderGenericOIDC(c, nil), methods: []string{"plain"}}
+			}, expect: false},
+			{mode: PKCEAuto, provider: func(c *Configuration) Provider {
+				return &noPKCETestProvider{c: c}
+			}, expect: false},
+			{mode: PKCEForce, provider: func(c *Configuration) Provider {
+				return &noPKCETestProvider{c: c}
+			}, expect: true},
+			{mode: PKCENever, provider: func(c *Configuration) Provider {
+				return &pkceTestProvider{ProviderGenericOIDC: NewProviderGenericOIDC(c, nil), methods: []string{"S256"}}
+			}, expect: false},
+		} {
+			t.Run("mode="+tc.mode, func(t *testing.T) {
+				verifier, err := pkceVerifier(context.Background(), tc.provider(&Configuration{ID: "test", PKCE: tc.mode}))
+				require.NoError(t, err)
+				if tc.expect {
+					assert.Len(t, verifier, 64)
+				} else {
+					assert.Empty(t, verifier)
+				}
+			})
+		}
+
+		_, err := pkceVerifier(context.Background(), &noPKCETestProvider{c: &Configuration{PKCE: "sometimes"}})
+		require.Error(t, err)
+	})
+
+	t.Run("case=adds the challenge and verifier", func(t *testing.T) {
+		c := &oauth2.Config{Endpoint: oauth2.Endpoint{AuthURL: "https://example.org/auth"}}
+
+		u, err := url.Parse(c.AuthCodeURL("state", pkceAuthCodeURLOptions("verifier")...))
+		require.NoError(t, err)
+		assert.Equal(t, pkceChallenge("verifier"), u.Query().Get("code_challenge"))
+		assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+
+		u, err = url.Parse(c.AuthCodeURL("state", pkceAuthCodeURLOptions("")...))
+		require.NoError(t, err)
+		assert.Empty(t, u.Query().Get("code_challenge"))
+		assert.Empty(t, pkceExchangeOptions(""))
+		assert.Len(t, pkceExchangeOptions("verifier"), 1)
+	})
+}
@@ -13,6 +13,12 @@ type Provider interface {
 	AuthCodeURLOptions(r ider) []oauth2.AuthCodeOption
 }
 
+// IDTokenVerifier is implemented by providers which can verify ID Tokens obtained by native apps, for example
+// using Sign in with Apple or Google One Tap.
+type IDTokenVerifier interface {
+	VerifyIDToken(ctx context.Context, rawIDToken string) (*Claims, error)
+}
+
 type Claims struct {
 	Issuer              string `json:"iss,omitempty"`
 	Subject             string `json:"sub,omitempty"`
@@ -35,4 +41,8 @@ type Claims struct {
 	PhoneNumber         string `json:"phone_number,omitempty"`
 	PhoneNumberVerified bool   `json:"phone_number_verified,omitempty"`
 	UpdatedAt           int64  `json:"updated_at,omitempty"`
+
+	// RawClaims contains all claims returned by an OpenID Connect provider, including non-standard
+	// claims such as `groups` or `roles`.
+	RawClaims map[string]interface{} `json:"raw_claims,omitempty"`
 }
@@ -10,6 +10,13 @@ import (
 	"github.com/ory/herodot"
 
 	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/selfservice/form"
+)
+
+const (
+	ClaimsSourceIDToken  = "id_token"
+	ClaimsSourceUserInfo = "userinfo"
 )
 
 type Configuration struct {
@@ -64,6 +71,45 @@ type Configuration struct {
 	//
 	// More information: https://openid.net/specs/openid-connect-core-1_0.html#ClaimsParameter
 	RequestedClaims json.RawMessage `json:"requested_claims"`
+
+	// PKCE controls whether a PKCE code challenge is sent to the provider. It is one of:
+	// - auto: use PKCE if the provider advertises the S256 method in its OpenID Connect Discovery document (default)
+	// - force: always use PKCE, for providers which require it but do not advertise it
+	// - never: never use PKCE
+	PKCE string `json:"pkce"`
+
+	// ClaimsSource controls where the claims passed to the mapper come from. It is one of:
+	// - id_token: use the claims of the ID Token (default)
+	// - userinfo: additionally fetch the UserInfo Endpoint and merge its claims into the ID Token claims
+	//
+	// Only applies to OpenID Connect providers.
+	ClaimsSource string `json:"claims_source"`
+
+	// AccountLinking controls what happens if a registration with this provider returns a verified email address
+	// which is already a verified address of an existing identity. It is one of:
+	// - never: fail the registration because the identifier exists already (default)
+	// - confirm: ask the user to sign in to the existing identity, which links the accounts
+	// - automatic: link the accounts and sign in to the existing identity
+	AccountLinking string `json:"account_linking"`
+
+	// LoginOnly restricts the provider to signing in to identities which are linked to it already. It can not be
+	// used to create new identities.
+	LoginOnly bool `json:"login_only"`
+
+	// SyncMetadataPublicOnLogin updates the identity's public metadata with the `identity.metadata_public`
+	// output of the mapper on every login instead of only on registration.
+	SyncMetadataPublicOnLogin bool `json:"sync_metadata_public_on_login"`
+
+	// EmailDomains are the email domains (e.g. `acme.com`) of the users signing in with this provider. Login
+	// flows with a `login_hint` of one of these domains are sent to this provider right away.
+	EmailDomains []string `json:"email_domains"`
+
+	// AdditionalIDTokenAudiences are accepted as the audience of ID Tokens submitted by native apps in addition
+	// to the client ID, for example the bundle ID of an iOS app using Sign in with Apple.
+	AdditionalIDTokenAudiences []string `json:"additional_id_token_audiences"`
+
+	// UI contains the label, icon, and order of the provider's button in login and registration flows.
+	UI *form.UI `json:"ui,omitempty"`
 }
 
 func (p Configuration) Redir(public *url.URL) string {
@@ -76,6 +122,26 @@ type ConfigurationCollection struct {
 	Providers []Configuration `json:"providers"`
 }
 
+// registrationProviders returns the providers which can be used to create new identities.
+func (c ConfigurationCollection) registrationProviders() []Configuration {
+	providers := make([]Configuration, 0, len(c.Providers))
+	for _, p := range c.Providers {
+		if !p.LoginOnly {
+			providers = append(providers, p)
+		}
+	}
+	return providers
+}
+
+func (c ConfigurationCollection) has(id string) bool {
+	for _, p := range c.Providers {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
 func (c ConfigurationCollection) Provider(id string, public *url.URL) (Provider, error) {
 	for k := range c.Providers {
 		p := c.Providers[k]
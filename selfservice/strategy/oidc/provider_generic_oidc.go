@@ -14,6 +14,8 @@ import (
 )
 
 var _ Provider = new(ProviderGenericOIDC)
+var _ PKCEProvider = new(ProviderGenericOIDC)
+var _ IDTokenVerifier = new(ProviderGenericOIDC)
 
 type ProviderGenericOIDC struct {
 	p      *gooidc.Provider
@@ -72,6 +74,22 @@ func (g *ProviderGenericOIDC) OAuth2(ctx context.Context) (*oauth2.Config, error
 	return g.oauth2ConfigFromEndpoint(endpoint), nil
 }
 
+func (g *ProviderGenericOIDC) PKCEMethodsSupported(ctx context.Context) ([]string, error) {
+	p, err := g.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var discovery struct {
+		CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+	}
+	if err := p.Claims(&discovery); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode OpenID Connect Discovery document: %s", err))
+	}
+
+	return discovery.CodeChallengeMethodsSupported, nil
+}
+
 func (g *ProviderGenericOIDC) AuthCodeURLOptions(r ider) []oauth2.AuthCodeOption {
 	var options []oauth2.AuthCodeOption
 
@@ -95,14 +113,51 @@ func (g *ProviderGenericOIDC) verifyAndDecodeClaimsWithProvider(ctx context.Cont
 		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("%s", err))
 	}
 
+	return decodeIDTokenClaims(token)
+}
+
+func decodeIDTokenClaims(token *gooidc.IDToken) (*Claims, error) {
 	var claims Claims
 	if err := token.Claims(&claims); err != nil {
 		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("%s", err))
 	}
 
+	claims.RawClaims = map[string]interface{}{}
+	if err := token.Claims(&claims.RawClaims); err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("%s", err))
+	}
+
 	return &claims, nil
 }
 
+// VerifyIDToken verifies an ID Token obtained by a native app against the provider's JSON Web Key Set. The
+// audience of the ID Token must be the client ID or one of the additional ID Token audiences.
+func (g *ProviderGenericOIDC) VerifyIDToken(ctx context.Context, raw string) (*Claims, error) {
+	p, err := g.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.Verifier(&gooidc.Config{SkipClientIDCheck: true}).Verify(ctx, raw)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("%s", err))
+	}
+
+	audiences := append([]string{g.config.ClientID}, g.config.AdditionalIDTokenAudiences...)
+	var valid bool
+	for _, aud := range token.Audience {
+		if stringslice.Has(audiences, aud) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The ID Token was issued for audience %v which is not accepted by this provider.", token.Audience))
+	}
+
+	return decodeIDTokenClaims(token)
+}
+
 func (g *ProviderGenericOIDC) Claims(ctx context.Context, exchange *oauth2.Token) (*Claims, error) {
 	raw, ok := exchange.Extra("id_token").(string)
 	if !ok || len(raw) == 0 {
@@ -114,5 +169,52 @@ func (g *ProviderGenericOIDC) Claims(ctx context.Context, exchange *oauth2.Token
 		return nil, err
 	}
 
-	return g.verifyAndDecodeClaimsWithProvider(ctx, p, raw)
+	claims, err := g.verifyAndDecodeClaimsWithProvider(ctx, p, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.mergeUserInfoClaims(ctx, p, exchange, claims)
+}
+
+// mergeUserInfoClaims fetches the UserInfo Endpoint and merges its claims into the ID Token claims if
+// the provider is configured with `claims_source: userinfo`. Claims returned by the UserInfo Endpoint take
+// precedence, except for the issuer.
+func (g *ProviderGenericOIDC) mergeUserInfoClaims(ctx context.Context, provider *gooidc.Provider, exchange *oauth2.Token, claims *Claims) (*Claims, error) {
+	if g.config.ClaimsSource != ClaimsSourceUserInfo {
+		return claims, nil
+	}
+
+	userInfo, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(exchange))
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to fetch claims from the OpenID Connect UserInfo Endpoint: %s", err))
+	}
+
+	merged := *claims
+	merged.RawClaims = nil
+	if err := userInfo.Claims(&merged); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode claims from the OpenID Connect UserInfo Endpoint: %s", err))
+	}
+
+	var raw map[string]interface{}
+	if err := userInfo.Claims(&raw); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode claims from the OpenID Connect UserInfo Endpoint: %s", err))
+	}
+
+	merged.RawClaims = make(map[string]interface{}, len(claims.RawClaims)+len(raw))
+	for _, source := range []map[string]interface{}{claims.RawClaims, raw} {
+		for k, v := range source {
+			merged.RawClaims[k] = v
+		}
+	}
+
+	// The sub claim of the UserInfo Response must be verified to exactly match the sub claim in the ID Token, see:
+	// https://openid.net/specs/openid-connect-core-1_0.html#UserInfoResponse
+	if merged.Subject != claims.Subject {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The subject of the OpenID Connect UserInfo Response does not match the subject of the ID Token."))
+	}
+
+	merged.Issuer = claims.Issuer
+	merged.RawClaims["iss"] = claims.Issuer
+	return &merged, nil
 }
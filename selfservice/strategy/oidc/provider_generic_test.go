@@ -2,12 +2,21 @@ package oidc
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
 	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
 
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/x"
@@ -67,3 +76,93 @@ func TestProviderGenericOIDC_AddAuthCodeURLOptions(t *testing.T) {
 		assert.Contains(t, makeAuthCodeURL(t, r), "claims="+url.QueryEscape(string(makeOIDCClaims())))
 	})
 }
+
+func TestProviderGenericOIDC_Claims(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var userInfo map[string]interface{}
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                ts.URL,
+			"authorization_endpoint":                ts.URL + "/oauth2/auth",
+			"token_endpoint":                        ts.URL + "/oauth2/token",
+			"jwks_uri":                              ts.URL + "/jwks",
+			"userinfo_endpoint":                     ts.URL + "/userinfo",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "alg": "RS256", "use": "sig", "kid": "key",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer access-token", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(userInfo)
+	})
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":    ts.URL,
+		"aud":    "client",
+		"sub":    "subject",
+		"email":  "id-token@ory.sh",
+		"groups": []string{"admin", "dev"},
+		"iat":    time.Now().Unix(),
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "key"
+	raw, err := token.SignedString(key)
+	require.NoError(t, err)
+	exchange := (&oauth2.Token{AccessToken: "access-token", TokenType: "Bearer"}).
+		WithExtra(map[string]interface{}{"id_token": raw})
+
+	claims := func(t *testing.T, source string) (*Claims, error) {
+		public, err := url.Parse("https://ory.sh")
+		require.NoError(t, err)
+		return NewProviderGenericOIDC(&Configuration{
+			Provider:     "generic",
+			ID:           "valid",
+			ClientID:     "client",
+			ClientSecret: "secret",
+			IssuerURL:    ts.URL,
+			ClaimsSource: source,
+		}, public).Claims(context.Background(), exchange)
+	}
+
+	t.Run("case=uses the id token claims by default", func(t *testing.T) {
+		userInfo = map[string]interface{}{"sub": "subject", "name": "Ory"}
+		c, err := claims(t, "")
+		require.NoError(t, err)
+		assert.Equal(t, "id-token@ory.sh", c.Email)
+		assert.Empty(t, c.Name)
+		assert.Equal(t, []interface{}{"admin", "dev"}, c.RawClaims["groups"])
+	})
+
+	t.Run("case=merges the userinfo claims", func(t *testing.T) {
+		userInfo = map[string]interface{}{"sub": "subject", "iss": "https://evil.ory.sh", "name": "Ory", "email": "userinfo@ory.sh", "roles": []string{"owner"}}
+		c, err := claims(t, ClaimsSourceUserInfo)
+		require.NoError(t, err)
+		assert.Equal(t, "userinfo@ory.sh", c.Email)
+		assert.Equal(t, "Ory", c.Name)
+		assert.Equal(t, "subject", c.Subject)
+		assert.Equal(t, ts.URL, c.Issuer)
+		assert.Equal(t, ts.URL, c.RawClaims["iss"])
+		assert.Equal(t, "userinfo@ory.sh", c.RawClaims["email"])
+		assert.Equal(t, []interface{}{"admin", "dev"}, c.RawClaims["groups"])
+		assert.Equal(t, []interface{}{"owner"}, c.RawClaims["roles"])
+	})
+
+	t.Run("case=rejects userinfo claims of a different subject", func(t *testing.T) {
+		userInfo = map[string]interface{}{"sub": "other-subject", "name": "Ory"}
+		_, err := claims(t, ClaimsSourceUserInfo)
+		require.Error(t, err)
+	})
+}
@@ -67,7 +67,12 @@ func (m *ProviderMicrosoft) Claims(ctx context.Context, exchange *oauth2.Token)
 		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to initialize OpenID Connect Provider: %s", err))
 	}
 
-	return m.verifyAndDecodeClaimsWithProvider(ctx, p, raw)
+	claims, err := m.verifyAndDecodeClaimsWithProvider(ctx, p, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.mergeUserInfoClaims(ctx, p, exchange, claims)
 }
 
 type microsoftUnverifiedClaims struct {
@@ -14,6 +14,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
 	"github.com/tidwall/gjson"
+	"golang.org/x/oauth2"
 
 	"github.com/ory/x/jsonx"
 
@@ -51,6 +52,7 @@ type dependencies interface {
 	x.LoggingProvider
 	x.CookieProvider
 	x.CSRFTokenGeneratorProvider
+	x.ClockProvider
 
 	identity.ValidationProvider
 	identity.PrivilegedPoolProvider
@@ -79,6 +81,10 @@ type dependencies interface {
 	continuity.ManagementProvider
 
 	identity.ActiveCredentialsCounterStrategyProvider
+
+	ProviderPersistenceProvider
+
+	IdentityTraitsSchemas() schema.Schemas
 }
 
 func isForced(req interface{}) bool {
@@ -98,9 +104,10 @@ type Strategy struct {
 }
 
 type authCodeContainer struct {
-	FlowID string     `json:"flow_id"`
-	State  string     `json:"state"`
-	Form   url.Values `json:"form"`
+	FlowID       string     `json:"flow_id"`
+	State        string     `json:"state"`
+	Form         url.Values `json:"form"`
+	PKCEVerifier string     `json:"pkce_verifier,omitempty"`
 }
 
 func (s *Strategy) CountActiveCredentials(cc map[identity.CredentialsType]identity.Credentials) (count int, err error) {
@@ -140,6 +147,10 @@ func (s *Strategy) setRoutes(r *x.RouterPublic) {
 	if handle, _, _ := r.Lookup("GET", RouteAuth); handle == nil {
 		r.GET(RouteAuth, s.handleAuth)
 	}
+
+	if handle, _, _ := r.Lookup("POST", RouteIDToken); handle == nil {
+		r.POST(RouteIDToken, s.handleIDToken)
+	}
 }
 
 func NewStrategy(
@@ -166,6 +177,19 @@ func (s *Strategy) handleAuth(w http.ResponseWriter, r *http.Request, ps httprou
 	}
 
 	var pid = r.Form.Get("provider") // this can come from both url query and post body
+	if pid == "" && r.Form.Get("login_hint") != "" {
+		// Identifier-first login: the user entered their email address and no provider was chosen.
+		provider, err := s.discoverProvider(r.Form.Get("login_hint"))
+		if err != nil {
+			s.handleError(w, r, rid, pid, nil, err)
+			return
+		} else if provider == nil {
+			s.handleError(w, r, rid, pid, nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`No OpenID Connect provider is configured for the email domain of the "login_hint" form field.`)))
+			return
+		}
+		pid = provider.Config().ID
+	}
+
 	if pid == "" {
 		s.handleError(w, r, rid, pid, nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The HTTP request did not contain the required "provider" form field`)))
 		return
@@ -177,35 +201,54 @@ func (s *Strategy) handleAuth(w http.ResponseWriter, r *http.Request, ps httprou
 		return
 	}
 
-	config, err := provider.OAuth2(r.Context())
+	req, err := s.validateFlow(r.Context(), r, rid)
 	if err != nil {
 		s.handleError(w, r, rid, pid, nil, err)
 		return
 	}
 
-	req, err := s.validateFlow(r.Context(), r, rid)
-	if err != nil {
-		s.handleError(w, r, rid, pid, nil, err)
+	if s.alreadyAuthenticated(w, r, req) {
 		return
 	}
 
-	if s.alreadyAuthenticated(w, r, req) {
+	if err := s.redirectToProvider(w, r, req, provider, r.PostForm, r.Form.Get("login_hint")); err != nil {
+		s.handleError(w, r, rid, pid, nil, err)
 		return
 	}
+}
+
+// redirectToProvider stores the state of the flow in a continuity container and redirects the browser to the
+// provider's authorization URL.
+func (s *Strategy) redirectToProvider(w http.ResponseWriter, r *http.Request, req ider, provider Provider, form url.Values, loginHint string) error {
+	config, err := provider.OAuth2(r.Context())
+	if err != nil {
+		return err
+	}
+
+	verifier, err := pkceVerifier(r.Context(), provider)
+	if err != nil {
+		return err
+	}
 
 	state := x.NewUUID().String()
 	if err := s.d.ContinuityManager().Pause(r.Context(), w, r, sessionName,
 		continuity.WithPayload(&authCodeContainer{
-			State:  state,
-			FlowID: rid.String(),
-			Form:   r.PostForm,
+			State:        state,
+			FlowID:       req.GetID().String(),
+			Form:         form,
+			PKCEVerifier: verifier,
 		}),
 		continuity.WithLifespan(time.Minute*30)); err != nil {
-		s.handleError(w, r, rid, pid, nil, err)
-		return
+		return err
 	}
 
-	http.Redirect(w, r, config.AuthCodeURL(state, provider.AuthCodeURLOptions(req)...), http.StatusFound)
+	options := append(provider.AuthCodeURLOptions(req), pkceAuthCodeURLOptions(verifier)...)
+	if loginHint != "" {
+		options = append(options, oauth2.SetAuthURLParam("login_hint", loginHint))
+	}
+
+	http.Redirect(w, r, config.AuthCodeURL(state, options...), http.StatusFound)
+	return nil
 }
 
 func (s *Strategy) validateFlow(ctx context.Context, r *http.Request, rid uuid.UUID) (ider, error) {
@@ -218,7 +261,7 @@ func (s *Strategy) validateFlow(ctx context.Context, r *http.Request, rid uuid.U
 			return ar, ErrAPIFlowNotSupported
 		}
 
-		if err := ar.Valid(); err != nil {
+		if err := ar.Valid(s.d.Clock().Now(), s.c.SelfServiceFlowExpiredGracePeriod()); err != nil {
 			return ar, err
 		}
 		return ar, nil
@@ -229,7 +272,7 @@ func (s *Strategy) validateFlow(ctx context.Context, r *http.Request, rid uuid.U
 			return ar, ErrAPIFlowNotSupported
 		}
 
-		if err := ar.Valid(); err != nil {
+		if err := ar.Valid(s.d.Clock().Now(), s.c.SelfServiceFlowExpiredGracePeriod()); err != nil {
 			return ar, err
 		}
 		return ar, nil
@@ -246,7 +289,7 @@ func (s *Strategy) validateFlow(ctx context.Context, r *http.Request, rid uuid.U
 			return ar, err
 		}
 
-		if err := ar.Valid(sess); err != nil {
+		if err := ar.Valid(sess, s.d.Clock().Now()); err != nil {
 			return ar, err
 		}
 		return ar, nil
@@ -336,7 +379,7 @@ func (s *Strategy) handleCallback(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
-	token, err := config.Exchange(r.Context(), code)
+	token, err := config.Exchange(r.Context(), code, pkceExchangeOptions(container.PKCEVerifier)...)
 	if err != nil {
 		s.handleError(w, r, req.GetID(), pid, nil, err)
 		return
@@ -383,31 +426,90 @@ func (s *Strategy) authURL(flowID uuid.UUID) string {
 	).String()
 }
 
-func (s *Strategy) populateMethod(r *http.Request, flowID uuid.UUID) (*FlowMethod, error) {
+func (s *Strategy) idTokenURL(flowID uuid.UUID) string {
+	return urlx.AppendPaths(
+		urlx.Copy(s.c.SelfPublicURL()),
+		strings.Replace(
+			RouteIDToken, ":flow", flowID.String(), 1,
+		),
+	).String()
+}
+
+func (s *Strategy) populateMethod(r *http.Request, flowID uuid.UUID, ft flow.Type, registration bool) (*FlowMethod, error) {
 	conf, err := s.Config()
 	if err != nil {
 		return nil, err
 	}
 
+	providers := conf.Providers
+	if registration {
+		providers = conf.registrationProviders()
+	}
+
+	if ft == flow.TypeAPI {
+		// API flows can not follow redirects and submit the ID Token obtained by the native app instead.
+		f := form.NewHTMLForm(s.idTokenURL(flowID))
+		f.SetField(form.Field{Name: "id_token", Type: "hidden", Required: true})
+		f.SetField(form.Field{Name: "id_token_nonce", Type: "hidden", Required: true})
+		return NewFlowMethod(f).AddProviders(providers), nil
+	}
+
 	f := form.NewHTMLForm(s.authURL(flowID))
 	f.SetCSRF(s.d.GenerateCSRFToken(r))
 	// does not need sorting because there is only one field
 
-	return NewFlowMethod(f).AddProviders(conf.Providers), nil
+	return NewFlowMethod(f).AddProviders(providers), nil
 }
 
 func (s *Strategy) Config() (*ConfigurationCollection, error) {
-	var c ConfigurationCollection
+	return configuration(context.Background(), s.c, s.d.OIDCProviderPersister())
+}
+
+// FileConfig returns only the providers of the configuration file, which does not require a database connection.
+func (s *Strategy) FileConfig() (*ConfigurationCollection, error) {
+	return fileConfiguration(s.c)
+}
 
-	config := s.c.SelfServiceStrategy(string(s.ID())).Config
+// fileConfiguration returns the providers of the configuration file.
+func fileConfiguration(c *config.Provider) (*ConfigurationCollection, error) {
+	var cc ConfigurationCollection
+
+	config := c.SelfServiceStrategy(string(identity.CredentialsTypeOIDC)).Config
 	if err := jsonx.
 		NewStrictDecoder(bytes.NewBuffer(config)).
-		Decode(&c); err != nil {
-		s.d.Logger().WithError(err).WithField("config", config)
+		Decode(&cc); err != nil {
 		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode OpenID Connect Provider configuration: %s", err))
 	}
 
-	return &c, nil
+	return &cc, nil
+}
+
+// configuration returns the providers of the configuration file followed by the providers configured using
+// the admin API. Providers of the configuration file take precedence if both use the same ID.
+func configuration(ctx context.Context, c *config.Provider, p ProviderPersister) (*ConfigurationCollection, error) {
+	cc, err := fileConfiguration(c)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := p.ListOIDCProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sp := range stored {
+		if cc.has(sp.ProviderID) {
+			continue
+		}
+
+		var conf Configuration
+		if err := jsonx.NewStrictDecoder(bytes.NewBuffer(sp.Config)).Decode(&conf); err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode OpenID Connect Provider configuration of %s: %s", sp.ProviderID, err))
+		}
+		cc.Providers = append(cc.Providers, conf)
+	}
+
+	return cc, nil
 }
 
 func (s *Strategy) provider(id string) (Provider, error) {
@@ -456,7 +558,13 @@ func (s *Strategy) handleError(w http.ResponseWriter, r *http.Request, rid uuid.
 			method.Config.ResetMessages()
 
 			method.Config.SetCSRF(s.d.GenerateCSRFToken(r))
-			if errSec := method.Config.SortFields(s.c.DefaultIdentityTraitsSchemaURL().String()); errSec != nil {
+			traitsSchema, errSec := s.d.IdentityTraitsSchemas().GetByID(rr.IdentitySchemaID)
+			if errSec != nil {
+				s.d.RegistrationFlowErrorHandler().WriteFlowError(w, r, s.ID(), rr, errors.Wrap(err, errSec.Error()))
+				return
+			}
+
+			if errSec := method.Config.SortFields(traitsSchema.URL.String()); errSec != nil {
 				s.d.RegistrationFlowErrorHandler().WriteFlowError(w, r, s.ID(), rr, errors.Wrap(err, errSec.Error()))
 				return
 			}
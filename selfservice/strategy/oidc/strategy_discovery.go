@@ -0,0 +1,56 @@
+package oidc
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ory/kratos/selfservice/flow/login"
+)
+
+var _ login.HomeRealmDiscoverer = new(Strategy)
+
+// discoverProvider returns the provider responsible for the email domain of the identifier or nil if
+// no provider is configured for the domain.
+func (s *Strategy) discoverProvider(identifier string) (Provider, error) {
+	at := strings.LastIndex(identifier, "@")
+	if at < 0 {
+		return nil, nil
+	}
+	domain := strings.ToLower(strings.TrimSpace(identifier[at+1:]))
+
+	conf, err := s.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range conf.Providers {
+		for _, d := range p.EmailDomains {
+			if strings.ToLower(d) == domain {
+				return s.provider(p.ID)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// DiscoverHomeRealm redirects the browser to the OpenID Connect provider configured for the email domain of
+// the identifier.
+func (s *Strategy) DiscoverHomeRealm(w http.ResponseWriter, r *http.Request, f *login.Flow, identifier string) (bool, error) {
+	provider, err := s.discoverProvider(identifier)
+	if err != nil {
+		return false, err
+	} else if provider == nil {
+		return false, nil
+	}
+
+	s.d.Logger().
+		WithRequest(r).
+		WithField("provider", provider.Config().ID).
+		Debug("Login hint matches an email domain of an OpenID Connect provider, redirecting to the provider.")
+
+	if err := s.redirectToProvider(w, r, f, provider, nil, identifier); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,87 @@
+package oidc_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+)
+
+func TestStrategy_HomeRealmDiscovery(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	var idp *httptest.Server
+	idp = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/oauth2/auth",
+			"token_endpoint":         idp.URL + "/oauth2/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	}))
+	t.Cleanup(idp.Close)
+
+	viperSetProviderConfig(t, conf, oidc.Configuration{
+		Provider:     "generic",
+		ID:           "acme",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		IssuerURL:    idp.URL,
+		Mapper:       "file://./stub/oidc.hydra.jsonnet",
+		EmailDomains: []string{"acme.com"},
+	})
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/registration.schema.json")
+	conf.MustSet(config.ViperKeySelfServiceLoginUI, "https://www.ory.sh/login")
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+
+	c := newClient(t, nil)
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	get := func(t *testing.T, res *http.Response, err error) *url.URL {
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusFound, res.StatusCode)
+		location, err := res.Location()
+		require.NoError(t, err)
+		return location
+	}
+
+	assertProviderRedirect := func(t *testing.T, location *url.URL, hint string) {
+		assert.True(t, strings.HasPrefix(location.String(), idp.URL+"/oauth2/auth"), "%s", location)
+		assert.Equal(t, "client", location.Query().Get("client_id"))
+		assert.Equal(t, hint, location.Query().Get("login_hint"))
+	}
+
+	t.Run("case=should redirect to the provider of the email domain", func(t *testing.T) {
+		res, err := c.Get(publicTS.URL + login.RouteInitBrowserFlow + "?login_hint=" + url.QueryEscape("alice@ACME.com"))
+		assertProviderRedirect(t, get(t, res, err), "alice@ACME.com")
+	})
+
+	t.Run("case=should show the login ui for other email domains", func(t *testing.T) {
+		res, err := c.Get(publicTS.URL + login.RouteInitBrowserFlow + "?login_hint=" + url.QueryEscape("alice@ory.sh"))
+		location := get(t, res, err)
+		assert.True(t, strings.HasPrefix(location.String(), "https://www.ory.sh/login"), "%s", location)
+	})
+
+	t.Run("case=should redirect to the provider when submitting only the identifier", func(t *testing.T) {
+		res, err := c.Get(publicTS.URL + login.RouteInitBrowserFlow)
+		flow := get(t, res, err).Query().Get("flow")
+		require.NotEmpty(t, flow)
+
+		res, err = c.PostForm(publicTS.URL+strings.Replace(oidc.RouteAuth, ":flow", flow, 1), url.Values{"login_hint": {"bob@acme.com"}})
+		assertProviderRedirect(t, get(t, res, err), "bob@acme.com")
+	})
+}
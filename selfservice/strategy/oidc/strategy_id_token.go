@@ -0,0 +1,232 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/decoderx"
+
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteIDToken = RouteBase + "/id-token/:flow"
+
+	idTokenPayloadSchema = `{
+  "$id": "https://schemas.ory.sh/kratos/selfservice/oidc/id-token/config.schema.json",
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": [
+    "provider",
+    "id_token",
+    "id_token_nonce"
+  ],
+  "properties": {
+    "provider": {
+      "type": "string",
+      "minLength": 1
+    },
+    "id_token": {
+      "type": "string",
+      "minLength": 1
+    },
+    "id_token_nonce": {
+      "type": "string",
+      "minLength": 1
+    },
+    "csrf_token": {
+      "type": "string"
+    }
+  }
+}`
+)
+
+// swagger:parameters completeSelfServiceFlowWithOIDCIDToken
+// nolint:deadcode,unused
+type completeSelfServiceFlowWithOIDCIDTokenParameters struct {
+	// The Login or Registration Flow ID
+	//
+	// required: true
+	// in: path
+	Flow string `json:"flow"`
+
+	// The flow token returned when initializing an API flow. Required for API flows if
+	// `selfservice.api_flow_binding` is enabled.
+	//
+	// in: header
+	FlowToken string `json:"X-Kratos-Flow-Token"`
+
+	// in: body
+	Body CompleteSelfServiceFlowWithOIDCIDToken
+}
+
+// swagger:model completeSelfServiceFlowWithOIDCIDToken
+type CompleteSelfServiceFlowWithOIDCIDToken struct {
+	// The ID of the provider which issued the ID Token.
+	//
+	// required: true
+	Provider string `json:"provider"`
+
+	// The ID Token obtained by the native app, for example using Sign in with Apple or Google One Tap.
+	//
+	// required: true
+	IDToken string `json:"id_token"`
+
+	// The nonce which was used when requesting the ID Token. The "nonce" claim of the ID Token must
+	// be this value or its hex encoded SHA-256 hash.
+	//
+	// required: true
+	IDTokenNonce string `json:"id_token_nonce"`
+
+	// Sending the anti-csrf token is only required for browser flows.
+	CSRFToken string `json:"csrf_token"`
+}
+
+// swagger:route POST /self-service/methods/oidc/id-token/{flow} public completeSelfServiceFlowWithOIDCIDToken
+//
+// Complete Login or Registration Flow with an OpenID Connect ID Token
+//
+// Native apps can not follow the redirects of the OpenID Connect authorization code flow. Instead, they obtain an
+// ID Token using the provider's SDK (e.g. Sign in with Apple or Google One Tap) and submit it to this endpoint.
+// The ID Token is verified using the provider's JSON Web Key Set. Just like the authorization code flow, a
+// login flow registers the identity if it does not exist yet and a registration flow signs in the identity if it
+// exists already.
+//
+// API flows respond with
+//   - HTTP 200 and a application/json body with the session token on success (the body is a
+//     `registrationViaApiResponse` if the identity was registered);
+//   - HTTP 400 if the ID Token or nonce are invalid.
+//
+// Browser flows respond with a HTTP 302 redirect to the post/after login or registration URL.
+//
+//     Schemes: http, https
+//
+//     Consumes:
+//     - application/json
+//     - application/x-www-form-urlencoded
+//
+//     Produces:
+//     - application/json
+//
+//     Responses:
+//       200: loginViaApiResponse
+//       302: emptyResponse
+//       400: genericError
+//       500: genericError
+func (s *Strategy) handleIDToken(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	rid := x.ParseUUID(ps.ByName("flow"))
+
+	var p CompleteSelfServiceFlowWithOIDCIDToken
+	if err := decoderx.NewHTTP().Decode(r, &p,
+		decoderx.MustHTTPRawJSONSchemaCompiler([]byte(idTokenPayloadSchema))); err != nil {
+		s.handleError(w, r, rid, p.Provider, nil, err)
+		return
+	}
+
+	req, ft, err := s.validateIDTokenFlow(r, rid, p.CSRFToken)
+	if err != nil {
+		s.handleError(w, r, rid, p.Provider, nil, err)
+		return
+	}
+
+	if ft == flow.TypeBrowser && s.alreadyAuthenticated(w, r, req) {
+		return
+	}
+
+	provider, err := s.provider(p.Provider)
+	if err != nil {
+		s.handleError(w, r, rid, p.Provider, nil, err)
+		return
+	}
+
+	verifier, ok := provider.(IDTokenVerifier)
+	if !ok {
+		s.handleError(w, r, rid, p.Provider, nil, errors.WithStack(ErrIDTokenNotSupported))
+		return
+	}
+
+	claims, err := verifier.VerifyIDToken(r.Context(), p.IDToken)
+	if err != nil {
+		s.handleError(w, r, rid, p.Provider, nil, err)
+		return
+	}
+
+	if err := verifyIDTokenNonce(claims, p.IDTokenNonce); err != nil {
+		s.handleError(w, r, rid, p.Provider, nil, err)
+		return
+	}
+
+	container := &authCodeContainer{FlowID: rid.String(), Form: url.Values{}}
+	switch a := req.(type) {
+	case *login.Flow:
+		s.processLogin(w, r, a, claims, provider, container)
+	case *registration.Flow:
+		s.processRegistration(w, r, a, claims, provider, container)
+	}
+}
+
+// validateIDTokenFlow returns the login or registration flow an ID Token is submitted to. Contrary to the
+// authorization code flow, API flows are supported as well.
+func (s *Strategy) validateIDTokenFlow(r *http.Request, rid uuid.UUID, csrfToken string) (ider, flow.Type, error) {
+	if x.IsZeroUUID(rid) {
+		return nil, "", errors.WithStack(herodot.ErrBadRequest.WithReason("The flow path parameter is missing or invalid."))
+	}
+
+	var (
+		f             ider
+		ft            flow.Type
+		valid         func(now time.Time, gracePeriod time.Duration) error
+		flowTokenHash string
+	)
+	if lf, err := s.d.LoginFlowPersister().GetLoginFlow(r.Context(), rid); err == nil {
+		f, ft, valid, flowTokenHash = lf, lf.Type, lf.Valid, lf.FlowTokenHash
+	} else if rf, err := s.d.RegistrationFlowPersister().GetRegistrationFlow(r.Context(), rid); err == nil {
+		f, ft, valid, flowTokenHash = rf, rf.Type, rf.Valid, rf.FlowTokenHash
+	} else {
+		return nil, "", err
+	}
+
+	if err := flow.VerifyRequest(r, ft, s.c.DisableAPIFlowEnforcement(), s.d.GenerateCSRFToken, csrfToken); err != nil {
+		return f, ft, err
+	}
+
+	if err := flow.VerifyFlowToken(r, ft, s.c.SelfServiceAPIFlowBinding(), flowTokenHash); err != nil {
+		return f, ft, err
+	}
+
+	if err := valid(s.d.Clock().Now(), s.c.SelfServiceFlowExpiredGracePeriod()); err != nil {
+		return f, ft, err
+	}
+
+	return f, ft, nil
+}
+
+// verifyIDTokenNonce ensures that the ID Token was requested by the client submitting it. Some providers, for example
+// Apple, expect the SHA-256 hash of the nonce to be sent with the authentication request, which is why both the
+// nonce and its hash are accepted.
+func verifyIDTokenNonce(claims *Claims, nonce string) error {
+	expected, _ := claims.RawClaims["nonce"].(string)
+	if len(expected) == 0 || len(nonce) == 0 {
+		return errors.WithStack(ErrIDTokenNonceMismatch)
+	}
+
+	hashed := sha256.Sum256([]byte(nonce))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(nonce)) == 1 ||
+		subtle.ConstantTimeCompare([]byte(expected), []byte(hex.EncodeToString(hashed[:]))) == 1 {
+		return nil
+	}
+
+	return errors.WithStack(ErrIDTokenNonceMismatch)
+}
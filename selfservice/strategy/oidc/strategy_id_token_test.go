@@ -0,0 +1,212 @@
+package oidc_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/ioutilx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+	"github.com/ory/kratos/x"
+)
+
+func TestStrategy_IDToken(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	idp := httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                idp.URL,
+			"authorization_endpoint":                idp.URL + "/oauth2/auth",
+			"token_endpoint":                        idp.URL + "/oauth2/token",
+			"jwks_uri":                              idp.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "alg": "RS256", "use": "sig", "kid": "key",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	provider := oidc.Configuration{
+		Provider:                   "generic",
+		ID:                         "native",
+		ClientID:                   "client",
+		ClientSecret:               "secret",
+		IssuerURL:                  idp.URL,
+		Mapper:                     "file://./stub/oidc.hydra.jsonnet",
+		AdditionalIDTokenAudiences: []string{"com.example.app"},
+	}
+	viperSetProviderConfig(t, conf, provider)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/registration.schema.json")
+	conf.MustSet(config.ViperKeySelfServiceAPIFlowBinding, true)
+	conf.MustSet(config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter,
+		identity.CredentialsTypeOIDC.String()), []config.SelfServiceHook{{Name: "session"}})
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+
+	newIDToken := func(t *testing.T, subject, aud, nonce string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":   idp.URL,
+			"aud":   aud,
+			"sub":   subject,
+			"nonce": nonce,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = "key"
+		raw, err := token.SignedString(key)
+		require.NoError(t, err)
+		return raw
+	}
+
+	initFlow := func(t *testing.T, route string) (id, token string) {
+		res, err := http.Get(publicTS.URL + route)
+		require.NoError(t, err)
+		body := ioutilx.MustReadAll(res.Body)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		return gjson.GetBytes(body, "id").String(), gjson.GetBytes(body, "flow_token").String()
+	}
+
+	submit := func(t *testing.T, flowID, flowToken, idToken, nonce string) (int, []byte) {
+		payload, err := json.Marshal(&oidc.CompleteSelfServiceFlowWithOIDCIDToken{Provider: "native", IDToken: idToken, IDTokenNonce: nonce})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest("POST", publicTS.URL+strings.Replace(oidc.RouteIDToken, ":flow", flowID, 1), bytes.NewReader(payload))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set(flow.HeaderFlowToken, flowToken)
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		body := ioutilx.MustReadAll(res.Body)
+		require.NoError(t, res.Body.Close())
+		return res.StatusCode, body
+	}
+
+	subject := x.NewUUID().String() + "@ory.sh"
+
+	t.Run("case=should register the identity using a registration flow", func(t *testing.T) {
+		id, token := initFlow(t, registration.RouteInitAPIFlow)
+		status, body := submit(t, id, token, newIDToken(t, subject, "client", "some-nonce"), "some-nonce")
+		require.Equal(t, http.StatusOK, status, "%s", body)
+		assert.NotEmpty(t, gjson.GetBytes(body, "session_token").String(), "%s", body)
+		assert.Equal(t, subject, gjson.GetBytes(body, "identity.traits.subject").String(), "%s", body)
+	})
+
+	t.Run("case=should sign in the registered identity using a login flow", func(t *testing.T) {
+		id, token := initFlow(t, login.RouteInitAPIFlow)
+		status, body := submit(t, id, token, newIDToken(t, subject, "client", "some-nonce"), "some-nonce")
+		require.Equal(t, http.StatusOK, status, "%s", body)
+		assert.NotEmpty(t, gjson.GetBytes(body, "session_token").String(), "%s", body)
+		assert.Equal(t, subject, gjson.GetBytes(body, "session.identity.traits.subject").String(), "%s", body)
+	})
+
+	t.Run("case=should accept additional audiences and hashed nonces", func(t *testing.T) {
+		hashed := sha256.Sum256([]byte("raw-nonce"))
+		id, token := initFlow(t, login.RouteInitAPIFlow)
+		status, body := submit(t, id, token, newIDToken(t, subject, "com.example.app", hex.EncodeToString(hashed[:])), "raw-nonce")
+		require.Equal(t, http.StatusOK, status, "%s", body)
+		assert.NotEmpty(t, gjson.GetBytes(body, "session_token").String(), "%s", body)
+	})
+
+	for _, tc := range []struct {
+		d       string
+		idToken func(t *testing.T) string
+		nonce   string
+	}{
+		{d: "nonce mismatch", idToken: func(t *testing.T) string { return newIDToken(t, subject, "client", "some-nonce") }, nonce: "other-nonce"},
+		{d: "unknown audience", idToken: func(t *testing.T) string { return newIDToken(t, subject, "other-client", "some-nonce") }, nonce: "some-nonce"},
+		{d: "invalid signature", idToken: func(t *testing.T) string { return newIDToken(t, subject, "client", "some-nonce") + "x" }, nonce: "some-nonce"},
+	} {
+		t.Run("case=should reject an id token because of a "+tc.d, func(t *testing.T) {
+			id, token := initFlow(t, login.RouteInitAPIFlow)
+			status, body := submit(t, id, token, tc.idToken(t), tc.nonce)
+			assert.Equal(t, http.StatusBadRequest, status, "%s", body)
+			assert.Empty(t, gjson.GetBytes(body, "session_token").String(), "%s", body)
+		})
+	}
+
+	t.Run("case=login-only provider", func(t *testing.T) {
+		loginOnly := provider
+		loginOnly.LoginOnly = true
+		viperSetProviderConfig(t, conf, loginOnly)
+		t.Cleanup(func() {
+			viperSetProviderConfig(t, conf, provider)
+		})
+
+		t.Run("case=should sign in the registered identity", func(t *testing.T) {
+			id, token := initFlow(t, login.RouteInitAPIFlow)
+			status, body := submit(t, id, token, newIDToken(t, subject, "client", "some-nonce"), "some-nonce")
+			require.Equal(t, http.StatusOK, status, "%s", body)
+			assert.NotEmpty(t, gjson.GetBytes(body, "session_token").String(), "%s", body)
+		})
+
+		t.Run("case=should not create an identity", func(t *testing.T) {
+			unknown := x.NewUUID().String() + "@ory.sh"
+			for _, route := range []string{login.RouteInitAPIFlow, registration.RouteInitAPIFlow} {
+				id, token := initFlow(t, route)
+				status, body := submit(t, id, token, newIDToken(t, unknown, "client", "some-nonce"), "some-nonce")
+				assert.Equal(t, http.StatusBadRequest, status, "%s", body)
+				assert.Empty(t, gjson.GetBytes(body, "session_token").String(), "%s", body)
+				assert.Contains(t, string(body), "Please sign up using a different method", "%s", body)
+			}
+
+			_, _, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(context.Background(), identity.CredentialsTypeOIDC, "native:"+unknown)
+			assert.Error(t, err)
+		})
+
+		t.Run("case=should not offer the provider in registration flows", func(t *testing.T) {
+			res, err := http.Get(publicTS.URL + registration.RouteInitAPIFlow)
+			require.NoError(t, err)
+			body := ioutilx.MustReadAll(res.Body)
+			require.NoError(t, res.Body.Close())
+			assert.NotContains(t, gjson.GetBytes(body, "methods.oidc.config.fields.#.value").String(), "native", "%s", body)
+
+			res, err = http.Get(publicTS.URL + login.RouteInitAPIFlow)
+			require.NoError(t, err)
+			body = ioutilx.MustReadAll(res.Body)
+			require.NoError(t, res.Body.Close())
+			assert.Contains(t, gjson.GetBytes(body, "methods.oidc.config.fields.#.value").String(), "native", "%s", body)
+		})
+	})
+
+	t.Run("case=should reject a submission without the flow token", func(t *testing.T) {
+		id, _ := initFlow(t, login.RouteInitAPIFlow)
+		status, body := submit(t, id, "", newIDToken(t, subject, "client", "some-nonce"), "some-nonce")
+		assert.Equal(t, http.StatusForbidden, status, "%s", body)
+	})
+}
@@ -0,0 +1,172 @@
+package oidc
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
+)
+
+const (
+	AccountLinkingNever     = "never"
+	AccountLinkingConfirm   = "confirm"
+	AccountLinkingAutomatic = "automatic"
+
+	linkSessionName = "ory_kratos_oidc_link_session"
+)
+
+var _ login.PostHookExecutor = new(Strategy)
+
+// linkContainer is stored in the continuity container while the user confirms linking an OpenID Connect
+// account to an existing identity by signing in to that identity.
+type linkContainer struct {
+	FlowID     string    `json:"flow_id"`
+	IdentityID uuid.UUID `json:"identity_id"`
+	Provider   string    `json:"provider"`
+	Subject    string    `json:"subject"`
+}
+
+// linkByVerifiedEmail links the OpenID Connect account to an existing identity if the provider is configured
+// for account linking, the provider asserts that the email address is verified, and an identity with the same
+// verified email address exists. It returns false if the registration should proceed as usual.
+func (s *Strategy) linkByVerifiedEmail(w http.ResponseWriter, r *http.Request, a *registration.Flow, claims *Claims, provider Provider) bool {
+	mode := provider.Config().AccountLinking
+	if mode == "" || mode == AccountLinkingNever || claims.Email == "" || !claims.EmailVerified {
+		return false
+	}
+
+	address, err := s.d.PrivilegedIdentityPool().FindVerifiableAddressByValue(r.Context(), identity.VerifiableAddressTypeEmail, claims.Email)
+	if err != nil || !address.Verified {
+		return false
+	}
+
+	i, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), address.IdentityID)
+	if err != nil {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+		return true
+	}
+
+	if mode == AccountLinkingConfirm && a.Type == flow.TypeAPI {
+		// Confirming the link requires a browser because the pending link is stored in a cookie.
+		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, errors.WithStack(ErrAPIFlowNotSupported))
+		return true
+	}
+
+	// Linking creates a new login flow because the user signs in to the existing identity.
+	lf, err := s.d.LoginHandler().NewLoginFlow(w, r, a.Type)
+	if err != nil {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+		return true
+	}
+
+	switch mode {
+	case AccountLinkingAutomatic:
+		if err := s.linkCredentials(r, i, provider.Config().ID, claims.Subject); err != nil {
+			s.handleError(w, r, lf.GetID(), provider.Config().ID, nil, err)
+			return true
+		}
+
+		if err := s.d.LoginHookExecutor().PostLoginHook(w, r, identity.CredentialsTypeOIDC, lf, i); err != nil {
+			s.handleError(w, r, lf.GetID(), provider.Config().ID, nil, err)
+		}
+		return true
+	case AccountLinkingConfirm:
+		// The user must prove control over the existing identity before the accounts are linked. The pending
+		// link is kept in a continuity container and completed by ExecuteLoginPostHook once the user signed in.
+		if err := s.d.ContinuityManager().Pause(r.Context(), w, r, linkSessionName,
+			continuity.WithPayload(&linkContainer{
+				FlowID:     lf.ID.String(),
+				IdentityID: i.ID,
+				Provider:   provider.Config().ID,
+				Subject:    claims.Subject,
+			}),
+			continuity.WithLifespan(s.c.SelfServiceFlowLoginRequestLifespan())); err != nil {
+			s.handleError(w, r, lf.GetID(), provider.Config().ID, nil, err)
+			return true
+		}
+
+		lf.Messages.Add(text.NewInfoLoginLinkCredentials(provider.Config().ID))
+		if err := s.d.LoginFlowPersister().UpdateLoginFlow(r.Context(), lf); err != nil {
+			s.handleError(w, r, lf.GetID(), provider.Config().ID, nil, err)
+			return true
+		}
+
+		http.Redirect(w, r, lf.AppendTo(s.c.SelfServiceFlowLoginUI()).String(), http.StatusFound)
+		return true
+	}
+
+	s.handleError(w, r, a.GetID(), provider.Config().ID, nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf(
+		`OpenID Connect provider "%s" is configured with unknown account linking mode "%s".`, provider.Config().ID, mode)))
+	return true
+}
+
+// ExecuteLoginPostHook completes a pending account link once the user signed in to the identity the
+// OpenID Connect account should be linked to.
+func (s *Strategy) ExecuteLoginPostHook(w http.ResponseWriter, r *http.Request, a *login.Flow, sess *session.Session) error {
+	var lc linkContainer
+	if _, err := s.d.ContinuityManager().Continue(r.Context(), w, r, linkSessionName, continuity.WithPayload(&lc)); errors.Is(err, &continuity.ErrNotResumable) {
+		return nil
+	} else if err != nil {
+		// The pending link is dropped (e.g. because it expired) but the login itself must still succeed.
+		s.d.Logger().WithRequest(r).WithError(err).Warn("Unable to complete pending OpenID Connect account link.")
+		return s.d.ContinuityManager().Abort(r.Context(), w, r, linkSessionName)
+	}
+
+	if lc.FlowID != a.ID.String() || lc.IdentityID != sess.Identity.ID {
+		s.d.Logger().
+			WithRequest(r).
+			WithField("provider", lc.Provider).
+			WithField("identity_id", sess.Identity.ID).
+			Debug("Discarding pending OpenID Connect account link because it was initiated for another flow or identity.")
+		return nil
+	}
+
+	i, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), sess.Identity.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.linkCredentials(r, i, lc.Provider, lc.Subject)
+}
+
+// linkCredentials adds the OpenID Connect provider and subject to the identity's credentials.
+func (s *Strategy) linkCredentials(r *http.Request, i *identity.Identity, provider, subject string) error {
+	var conf CredentialsConfig
+	creds, err := i.ParseCredentials(s.ID(), &conf)
+	if errors.Is(err, herodot.ErrNotFound) {
+		if creds, err = NewCredentials(provider, subject); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	} else {
+		creds.Identifiers = append(creds.Identifiers, uid(provider, subject))
+		conf.Providers = append(conf.Providers, ProviderCredentialsConfig{Subject: subject, Provider: provider})
+		if creds.Config, err = json.Marshal(conf); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	i.SetCredentials(s.ID(), *creds)
+	if err := s.d.PrivilegedIdentityPool().UpdateIdentity(r.Context(), i); err != nil {
+		return err
+	}
+
+	s.d.Audit().
+		WithRequest(r).
+		WithField("identity_id", i.ID).
+		WithField("provider", provider).
+		Info("An OpenID Connect account was linked to an existing identity with the same verified email address.")
+	return nil
+}
@@ -0,0 +1,103 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+	"github.com/ory/kratos/session"
+)
+
+func TestStrategy_ExecuteLoginPostHook(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/registration.schema.json")
+	conf.MustSet(config.ViperKeyPublicBaseURL, "https://www.ory.sh")
+	s := oidc.NewStrategy(reg, conf)
+
+	newIdentity := func(t *testing.T) *identity.Identity {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(`{"subject":"link-` + uuid.Must(uuid.NewV4()).String() + `@ory.sh"}`)
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+		return i
+	}
+
+	newFlow := func(t *testing.T) *login.Flow {
+		f, err := reg.LoginHandler().NewLoginFlow(httptest.NewRecorder(), &http.Request{URL: conf.SelfPublicURL()}, flow.TypeBrowser)
+		require.NoError(t, err)
+		return f
+	}
+
+	// run pauses a pending link for identity and flow, then lets a login of loggedIn in flow login complete it.
+	run := func(t *testing.T, link *identity.Identity, linkFlow *login.Flow, loggedIn *identity.Identity, loginFlow *login.Flow) {
+		router := httprouter.New()
+		router.GET("/pause", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			require.NoError(t, reg.ContinuityManager().Pause(r.Context(), w, r, "ory_kratos_oidc_link_session",
+				continuity.WithPayload(map[string]interface{}{
+					"flow_id":     linkFlow.ID.String(),
+					"identity_id": link.ID,
+					"provider":    "valid",
+					"subject":     "linked-subject",
+				})))
+		})
+		router.GET("/login", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			require.NoError(t, s.ExecuteLoginPostHook(w, r, loginFlow, session.NewActiveSession(loggedIn, conf, time.Now())))
+		})
+		ts := httptest.NewServer(router)
+		t.Cleanup(ts.Close)
+
+		c := newClient(t, nil)
+		for _, path := range []string{"/pause", "/login"} {
+			res, err := c.Get(ts.URL + path)
+			require.NoError(t, err)
+			require.NoError(t, res.Body.Close())
+		}
+	}
+
+	linked := func(t *testing.T, i *identity.Identity) bool {
+		_, _, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(context.Background(), identity.CredentialsTypeOIDC, "valid:linked-subject")
+		if err != nil {
+			return false
+		}
+		actual, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), i.ID)
+		require.NoError(t, err)
+		return assert.Contains(t, actual.Credentials[identity.CredentialsTypeOIDC].Identifiers, "valid:linked-subject")
+	}
+
+	t.Run("case=does nothing without a pending link", func(t *testing.T) {
+		i, f := newIdentity(t), newFlow(t)
+		req := httptest.NewRequest("GET", "/", nil)
+		require.NoError(t, s.ExecuteLoginPostHook(httptest.NewRecorder(), req, f, session.NewActiveSession(i, conf, time.Now())))
+	})
+
+	t.Run("case=discards a link initiated for another identity", func(t *testing.T) {
+		i, f := newIdentity(t), newFlow(t)
+		run(t, newIdentity(t), f, i, f)
+		assert.False(t, linked(t, i))
+	})
+
+	t.Run("case=discards a link initiated for another flow", func(t *testing.T) {
+		i := newIdentity(t)
+		run(t, i, newFlow(t), i, newFlow(t))
+		assert.False(t, linked(t, i))
+	})
+
+	t.Run("case=links the credentials", func(t *testing.T) {
+		i, f := newIdentity(t), newFlow(t)
+		run(t, i, f, i, f)
+		assert.True(t, linked(t, i))
+	})
+}
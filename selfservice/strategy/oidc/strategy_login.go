@@ -10,7 +10,6 @@ import (
 	"github.com/ory/herodot"
 
 	"github.com/ory/kratos/identity"
-	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/x"
 )
@@ -22,11 +21,7 @@ func (s *Strategy) RegisterLoginRoutes(r *x.RouterPublic) {
 }
 
 func (s *Strategy) PopulateLoginMethod(r *http.Request, sr *login.Flow) error {
-	if sr.Type != flow.TypeBrowser {
-		return nil
-	}
-
-	config, err := s.populateMethod(r, sr.ID)
+	config, err := s.populateMethod(r, sr.ID, sr.Type, false)
 	if err != nil {
 		return err
 	}
@@ -52,8 +47,8 @@ func (s *Strategy) processLogin(w http.ResponseWriter, r *http.Request, a *login
 
 			s.d.Logger().WithField("provider", provider.Config().ID).WithField("subject", claims.Subject).Debug("Received successful OpenID Connect callback but user is not registered. Re-initializing registration flow now.")
 
-			// This flow only works for browsers anyways.
-			aa, err := s.d.RegistrationHandler().NewRegistrationFlow(w, r, flow.TypeBrowser)
+			// API flows are only continued this way when the ID Token was submitted by a native app.
+			aa, err := s.d.RegistrationHandler().NewRegistrationFlow(w, r, a.Type)
 			if err != nil {
 				s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
 				return
@@ -75,6 +70,13 @@ func (s *Strategy) processLogin(w http.ResponseWriter, r *http.Request, a *login
 
 	for _, c := range o.Providers {
 		if c.Subject == claims.Subject && c.Provider == provider.Config().ID {
+			if provider.Config().SyncMetadataPublicOnLogin {
+				if i, err = s.syncMetadataPublic(r, i, claims, provider); err != nil {
+					s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+					return
+				}
+			}
+
 			if err = s.d.LoginHookExecutor().PostLoginHook(w, r, identity.CredentialsTypeOIDC, a, i); err != nil {
 				s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
 				return
@@ -85,3 +87,30 @@ func (s *Strategy) processLogin(w http.ResponseWriter, r *http.Request, a *login
 
 	s.handleError(w, r, a.GetID(), provider.Config().ID, nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to find matching OpenID Connect Credentials.").WithDebugf(`Unable to find credentials that match the given provider "%s" and subject "%s".`, provider.Config().ID, claims.Subject)))
 }
+
+// syncMetadataPublic updates the identity's public metadata with the output of the provider's Jsonnet mapper so
+// that it reflects, for example, the current group memberships at the provider.
+func (s *Strategy) syncMetadataPublic(r *http.Request, i *identity.Identity, claims *Claims, provider Provider) (*identity.Identity, error) {
+	evaluated, err := s.evaluateMapper(r, claims, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := mappedMetadataPublic(evaluated)
+	if bytes.Equal(metadata, i.MetadataPublic) {
+		return i, nil
+	}
+
+	// The identity must be fetched including its credentials because updating an identity replaces its credentials.
+	i, err = s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), i.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	i.MetadataPublic = metadata
+	if err := s.d.PrivilegedIdentityPool().UpdateIdentity(r.Context(), i); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
@@ -6,11 +6,10 @@ import (
 	"net/http"
 
 	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
 	"github.com/tidwall/gjson"
 
-	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
-	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/x"
 )
@@ -33,11 +32,7 @@ func (s *Strategy) RegisterRegistrationRoutes(r *x.RouterPublic) {
 }
 
 func (s *Strategy) PopulateRegistrationMethod(r *http.Request, sr *registration.Flow) error {
-	if sr.Type != flow.TypeBrowser {
-		return nil
-	}
-
-	config, err := s.populateMethod(r, sr.ID)
+	config, err := s.populateMethod(r, sr.ID, sr.Type, true)
 	if err != nil {
 		return err
 	}
@@ -63,8 +58,8 @@ func (s *Strategy) processRegistration(w http.ResponseWriter, r *http.Request, a
 			WithField("subject", claims.Subject).
 			Debug("Received successful OpenID Connect callback but user is already registered. Re-initializing login flow now.")
 
-		// This endpoint only handles browser flow at the moment.
-		ar, err := s.d.LoginHandler().NewLoginFlow(w, r, flow.TypeBrowser)
+		// API flows are only continued this way when the ID Token was submitted by a native app.
+		ar, err := s.d.LoginHandler().NewLoginFlow(w, r, a.Type)
 		if err != nil {
 			s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
 			return
@@ -74,27 +69,29 @@ func (s *Strategy) processRegistration(w http.ResponseWriter, r *http.Request, a
 		return
 	}
 
-	jn, err := s.f.Fetch(provider.Config().Mapper)
-	if err != nil {
-		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+	if s.linkByVerifiedEmail(w, r, a, claims, provider) {
 		return
 	}
 
-	var jsonClaims bytes.Buffer
-	if err := json.NewEncoder(&jsonClaims).Encode(claims); err != nil {
-		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+	if provider.Config().LoginOnly {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, errors.WithStack(NewErrLoginOnlyProvider(provider.Config().ID)))
 		return
 	}
 
-	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	evaluated, err := s.evaluateMapper(r, claims, provider)
+	if err != nil {
+		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
+		return
+	}
 
-	vm := jsonnet.MakeVM()
-	vm.ExtCode("claims", jsonClaims.String())
-	evaluated, err := vm.EvaluateSnippet(provider.Config().Mapper, jn.String())
+	traitsSchema, err := s.d.IdentityTraitsSchemas().GetByID(a.IdentitySchemaID)
 	if err != nil {
 		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
 		return
-	} else if traits := gjson.Get(evaluated, "identity.traits"); !traits.IsObject() {
+	}
+
+	i := identity.NewIdentity(a.IdentitySchemaID)
+	if traits := gjson.Get(evaluated, "identity.traits"); !traits.IsObject() {
 		i.Traits = []byte{'{', '}'}
 		s.d.Logger().
 			WithRequest(r).
@@ -106,16 +103,9 @@ func (s *Strategy) processRegistration(w http.ResponseWriter, r *http.Request, a
 	} else {
 		i.Traits = []byte(traits.Raw)
 	}
+	i.MetadataPublic = mappedMetadataPublic(evaluated)
 
-	s.d.Logger().
-		WithRequest(r).
-		WithField("oidc_provider", provider.Config().ID).
-		WithSensitiveField("oidc_claims", claims).
-		WithField("mapper_jsonnet_output", evaluated).
-		WithField("mapper_jsonnet_url", provider.Config().Mapper).
-		Debug("OpenID Connect Jsonnet mapper completed.")
-
-	option, err := decoderRegistration(s.c.DefaultIdentityTraitsSchemaURL().String())
+	option, err := decoderRegistration(traitsSchema.URL.String())
 	if err != nil {
 		s.handleError(w, r, a.GetID(), provider.Config().ID, nil, err)
 		return
@@ -145,3 +135,41 @@ func (s *Strategy) processRegistration(w http.ResponseWriter, r *http.Request, a
 		return
 	}
 }
+
+// evaluateMapper runs the provider's Jsonnet mapper with the claims and returns its output.
+func (s *Strategy) evaluateMapper(r *http.Request, claims *Claims, provider Provider) (string, error) {
+	jn, err := s.f.Fetch(provider.Config().Mapper)
+	if err != nil {
+		return "", err
+	}
+
+	var jsonClaims bytes.Buffer
+	if err := json.NewEncoder(&jsonClaims).Encode(claims); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtCode("claims", jsonClaims.String())
+	evaluated, err := vm.EvaluateSnippet(provider.Config().Mapper, jn.String())
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	s.d.Logger().
+		WithRequest(r).
+		WithField("oidc_provider", provider.Config().ID).
+		WithSensitiveField("oidc_claims", claims).
+		WithField("mapper_jsonnet_output", evaluated).
+		WithField("mapper_jsonnet_url", provider.Config().Mapper).
+		Debug("OpenID Connect Jsonnet mapper completed.")
+	return evaluated, nil
+}
+
+// mappedMetadataPublic returns the `identity.metadata_public` object of the mapper's output or nil if the mapper
+// did not return one.
+func mappedMetadataPublic(evaluated string) identity.Metadata {
+	if metadata := gjson.Get(evaluated, "identity.metadata_public"); metadata.IsObject() {
+		return identity.Metadata(metadata.Raw)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package oidc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-jsonnet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappedMetadataPublic(t *testing.T) {
+	jn, err := ioutil.ReadFile("./stub/oidc.groups.jsonnet")
+	require.NoError(t, err)
+
+	evaluate := func(t *testing.T, claims *Claims) string {
+		encoded, err := json.Marshal(claims)
+		require.NoError(t, err)
+
+		vm := jsonnet.MakeVM()
+		vm.ExtCode("claims", string(encoded))
+		evaluated, err := vm.EvaluateSnippet("oidc.groups.jsonnet", string(jn))
+		require.NoError(t, err)
+		return evaluated
+	}
+
+	t.Run("case=maps raw claims into the public metadata", func(t *testing.T) {
+		evaluated := evaluate(t, &Claims{Subject: "foo", RawClaims: map[string]interface{}{
+			"sub":    "foo",
+			"groups": []interface{}{"admin", "dev"},
+		}})
+		assert.JSONEq(t, `{"groups":["admin","dev"]}`, string(mappedMetadataPublic(evaluated)))
+	})
+
+	t.Run("case=returns nil if the mapper does not return public metadata", func(t *testing.T) {
+		assert.Nil(t, mappedMetadataPublic(`{"identity":{"traits":{}}}`))
+		assert.Nil(t, mappedMetadataPublic(`{"identity":{"metadata_public":"not-an-object"}}`))
+	})
+}
@@ -3,6 +3,7 @@ package oidc
 import (
 	"bytes"
 	"encoding/json"
+	"sort"
 
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
@@ -41,9 +42,24 @@ type FlowMethod struct {
 	*form.HTMLForm
 }
 
+// AddProviders adds a submit button for each provider. The buttons are sorted by the order of the providers'
+// user interface hints and otherwise keep the order of the configuration.
 func (r *FlowMethod) AddProviders(providers []Configuration) *FlowMethod {
-	for _, p := range providers {
-		r.Fields = append(r.Fields, form.Field{Name: "provider", Type: "submit", Value: p.ID})
+	order := func(p Configuration) int {
+		if p.UI == nil {
+			return 0
+		}
+		return p.UI.Order
+	}
+
+	sorted := make([]Configuration, len(providers))
+	copy(sorted, providers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return order(sorted[i]) < order(sorted[j])
+	})
+
+	for _, p := range sorted {
+		r.Fields = append(r.Fields, form.Field{Name: "provider", Type: "submit", Value: p.ID, UI: p.UI})
 	}
 	return r
 }
@@ -0,0 +1,28 @@
+package oidc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+)
+
+func TestFlowMethod_AddProviders(t *testing.T) {
+	m := oidc.NewFlowMethod(form.NewHTMLForm("")).AddProviders([]oidc.Configuration{
+		{ID: "github"},
+		{ID: "google", UI: &form.UI{Label: "Sign in with Google", Icon: "google", Order: -1}},
+		{ID: "gitlab"},
+		{ID: "acme", UI: &form.UI{Order: 1}},
+	})
+
+	var ids []string
+	for _, f := range m.Fields {
+		if f.Name == "provider" {
+			ids = append(ids, f.Value.(string))
+		}
+	}
+	assert.Equal(t, []string{"google", "github", "gitlab", "acme"}, ids)
+	assert.Equal(t, &form.UI{Label: "Sign in with Google", Icon: "google", Order: -1}, m.Fields[len(m.Fields)-4].UI)
+}
@@ -0,0 +1,142 @@
+package password
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+)
+
+// verifyWithExternalStore asks the external identity store whether the password of an identifier not known to
+// ORY Kratos is valid. It returns the user of the external identity store as a JSON object.
+func (s *Strategy) verifyWithExternalStore(ctx context.Context, c *config.PasswordExternalStoreConfig, identifier, password string) (json.RawMessage, error) {
+	if len(password) == 0 {
+		return nil, errors.WithStack(schema.NewInvalidCredentialsError())
+	}
+
+	body, err := json.Marshal(map[string]string{"identifier": identifier, "password": password})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	req, err := http.NewRequest("POST", c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The URL of the external identity store is invalid.").WithDebug(err.Error()))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	}
+
+	client := &http.Client{Timeout: c.Timeout}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to verify the credentials with the external identity store.").WithDebug(err.Error()))
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return nil, errors.WithStack(schema.NewInvalidCredentialsError())
+	default:
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to verify the credentials with the external identity store.").
+			WithDebugf("The external identity store responded with status code %d.", res.StatusCode))
+	}
+
+	user, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to verify the credentials with the external identity store.").WithDebug(err.Error()))
+	}
+
+	if !gjson.ValidBytes(user) || !gjson.ParseBytes(user).IsObject() {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The external identity store did not respond with a JSON object."))
+	}
+
+	return user, nil
+}
+
+// importFromExternalStore verifies the password with the external identity store and, if it is valid, creates
+// the identity using the Jsonnet mapper. The password is stored hashed, so that the identity signs in with ORY
+// Kratos from then on.
+func (s *Strategy) importFromExternalStore(ctx context.Context, identifier, password string) (*identity.Identity, error) {
+	c := s.c.SelfServiceStrategyPasswordExternalStore()
+	if c.MapperURL == "" {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The external identity store is enabled but no Jsonnet mapper is configured."))
+	}
+
+	user, err := s.verifyWithExternalStore(ctx, c, identifier, password)
+	if err != nil {
+		return nil, err
+	}
+
+	jn, err := s.f.Fetch(c.MapperURL)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtCode("user", string(user))
+	evaluated, err := vm.EvaluateSnippet(c.MapperURL, jn.String())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	s.d.Logger().
+		WithSensitiveField("external_user", string(user)).
+		WithField("mapper_jsonnet_output", evaluated).
+		WithField("mapper_jsonnet_url", c.MapperURL).
+		Debug("External identity store Jsonnet mapper completed.")
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	traits := gjson.Get(evaluated, "identity.traits")
+	if !traits.IsObject() {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The external identity store Jsonnet mapper did not return an object for key identity.traits. Please check your Jsonnet code!"))
+	}
+	i.Traits = []byte(traits.Raw)
+
+	if metadata := gjson.Get(evaluated, "identity.metadata_public"); metadata.IsObject() {
+		i.MetadataPublic = identity.Metadata(metadata.Raw)
+	}
+
+	hpw, err := s.d.Hasher().Generate([]byte(password))
+	if err != nil {
+		return nil, err
+	}
+
+	co, err := json.Marshal(&CredentialsConfig{HashedPassword: string(hpw)})
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to encode password options to JSON: %s", err))
+	}
+
+	// The identifiers are replaced by the traits marked as identifiers when the identity is validated. The
+	// identifier used to sign in is kept for schemas which do not mark any traits.
+	i.SetCredentials(s.ID(), identity.Credentials{
+		Type:        s.ID(),
+		Identifiers: []string{strings.ToLower(identifier)},
+		Config:      co,
+	})
+
+	if err := s.d.IdentityManager().Create(ctx, i); err != nil {
+		return nil, err
+	}
+
+	s.d.Audit().
+		WithField("identity_id", i.ID).
+		WithSensitiveField("identifier", identifier).
+		Info("An identity was imported from the external identity store on its first login.")
+	return i, nil
+}
@@ -39,6 +39,9 @@ func (s *Strategy) handleLoginError(w http.ResponseWriter, r *http.Request, rr *
 		if method, ok := rr.Methods[identity.CredentialsTypePassword]; ok {
 			method.Config.Reset()
 			method.Config.SetValue("identifier", payload.Identifier)
+			if s.c.SessionRememberMeEnabled() {
+				method.Config.SetValue("remember", payload.Remember)
+			}
 			if rr.Type == flow.TypeBrowser {
 				method.Config.SetCSRF(s.d.GenerateCSRFToken(r))
 			}
@@ -59,6 +62,19 @@ type completeSelfServiceLoginFlowWithPasswordMethodParameters struct {
 	// in: query
 	Flow string `json:"flow"`
 
+	// The flow token returned when initializing an API flow. Required for API flows if
+	// `selfservice.api_flow_binding` is enabled.
+	//
+	// in: header
+	FlowToken string `json:"X-Kratos-Flow-Token"`
+
+	// Fields is a comma separated list of the identity fields, for example `id,traits.email`, to include in the
+	// response of API flows. Nested fields can only be selected for `traits` and `metadata_public`. All fields are
+	// included if not set.
+	//
+	// in: query
+	Fields string `json:"fields"`
+
 	// in: body
 	Body CompleteSelfServiceLoginFlowWithPasswordMethod
 }
@@ -120,6 +136,11 @@ func (s *Strategy) handleLogin(w http.ResponseWriter, r *http.Request, _ httprou
 		return
 	}
 
+	if err := flow.VerifyFlowToken(r, ar.Type, s.c.SelfServiceAPIFlowBinding(), ar.FlowTokenHash); err != nil {
+		s.handleLoginError(w, r, ar, &p, err)
+		return
+	}
+
 	if _, err := s.d.SessionManager().FetchFromRequest(r.Context(), r); err == nil && !ar.Forced {
 		if ar.Type == flow.TypeBrowser {
 			http.Redirect(w, r, s.c.SelfServiceBrowserDefaultReturnTo().String(), http.StatusFound)
@@ -130,29 +151,42 @@ func (s *Strategy) handleLogin(w http.ResponseWriter, r *http.Request, _ httprou
 		return
 	}
 
-	if err := ar.Valid(); err != nil {
+	if err := ar.Valid(s.d.Clock().Now(), s.c.SelfServiceFlowExpiredGracePeriod()); err != nil {
 		s.handleLoginError(w, r, ar, &p, err)
 		return
 	}
 
 	i, c, err := s.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(r.Context(), s.ID(), p.Identifier)
 	if err != nil {
-		s.handleLoginError(w, r, ar, &p, errors.WithStack(schema.NewInvalidCredentialsError()))
-		return
-	}
-
-	var o CredentialsConfig
-	d := json.NewDecoder(bytes.NewBuffer(c.Config))
-	if err := d.Decode(&o); err != nil {
-		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, herodot.ErrInternalServerError.WithReason("The password credentials could not be decoded properly").WithDebug(err.Error()))
-		return
+		// Phone numbers are stored in E.164 format but may be entered with any formatting.
+		if normalized, nerr := identity.NormalizePhoneNumber(p.Identifier, ""); nerr == nil && normalized != p.Identifier {
+			i, c, err = s.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(r.Context(), s.ID(), normalized)
+		}
 	}
-
-	if err := s.d.Hasher().Compare([]byte(p.Password), []byte(o.HashedPassword)); err != nil {
+	if errors.Is(err, herodot.ErrNotFound) && s.c.SelfServiceStrategyPasswordExternalStore().URL != "" {
+		// Identities of the external identity store are imported when they sign in for the first time.
+		if i, err = s.importFromExternalStore(r.Context(), p.Identifier, p.Password); err != nil {
+			s.handleLoginError(w, r, ar, &p, err)
+			return
+		}
+	} else if err != nil {
 		s.handleLoginError(w, r, ar, &p, errors.WithStack(schema.NewInvalidCredentialsError()))
 		return
+	} else {
+		var o CredentialsConfig
+		d := json.NewDecoder(bytes.NewBuffer(c.Config))
+		if err := d.Decode(&o); err != nil {
+			s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, herodot.ErrInternalServerError.WithReason("The password credentials could not be decoded properly").WithDebug(err.Error()))
+			return
+		}
+
+		if err := s.d.Hasher().Compare([]byte(p.Password), []byte(o.HashedPassword)); err != nil {
+			s.handleLoginError(w, r, ar, &p, errors.WithStack(schema.NewInvalidCredentialsError()))
+			return
+		}
 	}
 
+	ar.Remember = p.Remember
 	if err := s.d.LoginHookExecutor().PostLoginHook(w, r, identity.CredentialsTypePassword, ar, i); err != nil {
 		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
 		return
@@ -180,15 +214,20 @@ func (s *Strategy) PopulateLoginMethod(r *http.Request, sr *login.Flow) error {
 		Action: sr.AppendTo(urlx.AppendPaths(s.c.SelfPublicURL(), RouteLogin)).String(),
 		Method: "POST",
 		Fields: form.Fields{{
-			Name:     "identifier",
-			Type:     "text",
-			Value:    identifier,
-			Required: true,
+			Name:         "identifier",
+			Type:         "text",
+			Value:        identifier,
+			Required:     true,
+			Autocomplete: form.AutocompleteUsername,
 		}, {
-			Name:     "password",
-			Type:     "password",
-			Required: true,
+			Name:         "password",
+			Type:         "password",
+			Required:     true,
+			Autocomplete: form.AutocompleteCurrentPassword,
 		}}}
+	if s.c.SessionRememberMeEnabled() {
+		f.SetField(form.Field{Name: "remember", Type: "checkbox", Value: false})
+	}
 	f.SetCSRF(s.d.GenerateCSRFToken(r))
 
 	sr.Methods[identity.CredentialsTypePassword] = &login.FlowMethod{
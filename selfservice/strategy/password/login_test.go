@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
@@ -33,11 +34,14 @@ import (
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/strategy/password"
 	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
 
 func TestCompleteLogin(t *testing.T) {
 	conf, reg := internal.NewFastRegistryWithMocks(t)
+	clock := new(x.TestClock)
+	reg.WithClock(clock)
 	conf.MustSet(config.ViperKeySelfServiceStrategyConfig+"."+string(identity.CredentialsTypePassword),
 		map[string]interface{}{"enabled": true})
 	publicTS, _ := testhelpers.NewKratosServer(t, reg)
@@ -142,6 +146,7 @@ func TestCompleteLogin(t *testing.T) {
 			assert.Contains(t, res.Request.URL.String(), publicTS.URL+login.RouteGetFlow)
 			assert.NotEqual(t, f.Payload.ID, gjson.Get(actual, "id").String(), "%s", actual)
 			assert.Contains(t, gjson.Get(actual, "messages.0.text").String(), "expired", "%s", actual)
+			assert.Equal(t, "identifier", gjson.Get(actual, `methods.password.config.fields.#(name=="identifier").value`).String(), "%s", actual)
 		})
 
 		t.Run("type=browser", func(t *testing.T) {
@@ -154,6 +159,25 @@ func TestCompleteLogin(t *testing.T) {
 			assert.Contains(t, res.Request.URL.String(), uiTS.URL+"/login-ts")
 			assert.NotEqual(t, f.Payload.ID, gjson.Get(actual, "id").String(), "%s", actual)
 			assert.Contains(t, gjson.Get(actual, "messages.0.text").String(), "expired", "%s", actual)
+			assert.Equal(t, "identifier", gjson.Get(actual, `methods.password.config.fields.#(name=="identifier").value`).String(), "%s", actual)
+		})
+
+		t.Run("type=browser/case=accepts the flow within the grace period", func(t *testing.T) {
+			conf.MustSet(config.ViperKeySelfServiceFlowExpiredGracePeriod, "1m")
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeySelfServiceFlowExpiredGracePeriod, "0s")
+			})
+
+			browserClient := testhelpers.NewClientWithCookies(t)
+			f := testhelpers.InitializeLoginFlowViaBrowser(t, browserClient, publicTS, false)
+			c := testhelpers.GetLoginFlowMethodConfig(t, f.Payload, identity.CredentialsTypePassword.String())
+
+			clock.Advance(30 * time.Second)
+			t.Cleanup(clock.Reset)
+			actual, res := testhelpers.LoginMakeRequest(t, false, c, browserClient, values.Encode())
+			assert.Contains(t, res.Request.URL.String(), uiTS.URL+"/login-ts")
+			assert.Equal(t, string(f.Payload.ID), gjson.Get(actual, "id").String(), "%s", actual)
+			assert.Contains(t, gjson.Get(actual, "methods.password.config.messages.0.text").String(), "credentials are invalid", "%s", actual)
 		})
 	})
 
@@ -220,6 +244,48 @@ func TestCompleteLogin(t *testing.T) {
 		})
 	})
 
+	t.Run("case=should bind API flows to the flow token", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceAPIFlowBinding, true)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceAPIFlowBinding, false)
+		})
+
+		identifier, pwd := x.NewUUID().String(), "password"
+		createIdentity(identifier, pwd)
+
+		var submit = func(t *testing.T, token string) (string, *http.Response) {
+			res, err := apiClient.Get(publicTS.URL + login.RouteInitAPIFlow)
+			require.NoError(t, err)
+			f := gjson.ParseBytes(ioutilx.MustReadAll(res.Body))
+			require.NoError(t, res.Body.Close())
+			require.NotEmpty(t, f.Get("flow_token").String(), "%s", f.Raw)
+
+			if token == "" {
+				token = f.Get("flow_token").String()
+			}
+
+			req := testhelpers.NewRequest(t, true, "POST", f.Get("methods.password.config.action").String(),
+				bytes.NewBufferString(`{"identifier":"`+identifier+`","password":"`+pwd+`"}`))
+			req.Header.Set("X-Kratos-Flow-Token", token)
+			res, err = apiClient.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			return string(ioutilx.MustReadAll(res.Body)), res
+		}
+
+		t.Run("case=should fail with the token of another flow", func(t *testing.T) {
+			actual, res := submit(t, "not-the-flow-token")
+			assert.EqualValues(t, http.StatusForbidden, res.StatusCode, "%s", actual)
+			assert.Contains(t, actual, "flow token mismatch")
+		})
+
+		t.Run("case=should pass with the flow token", func(t *testing.T) {
+			actual, res := submit(t, "")
+			assert.EqualValues(t, http.StatusOK, res.StatusCode, "%s", actual)
+			assert.Equal(t, identifier, gjson.Get(actual, "session.identity.traits.subject").String(), "%s", actual)
+		})
+	})
+
 	var expectValidationError = func(t *testing.T, isAPI, forced bool, values func(url.Values)) string {
 		return testhelpers.SubmitLoginForm(t, isAPI, nil, publicTS, values,
 			identity.CredentialsTypePassword, forced,
@@ -440,6 +506,23 @@ func TestCompleteLogin(t *testing.T) {
 					assert.Equal(t, identifier, gjson.GetBytes(body, "methods.password.config.fields.#(name==identifier).value").String(), "%s", body)
 					assert.Empty(t, gjson.GetBytes(body, "methods.password.config.fields.#(name==password).value").String(), "%s", body)
 				})
+
+				t.Run("revoke the session token which was refreshed", func(t *testing.T) {
+					body := testhelpers.SubmitLoginForm(t, true, c, publicTS, values,
+						identity.CredentialsTypePassword, true, http.StatusOK, publicTS.URL+password.RouteLogin)
+					refreshed := gjson.Get(body, "session_token").String()
+					require.NotEmpty(t, refreshed, "%s", body)
+					assert.NotEqual(t, st, refreshed)
+
+					for token, expected := range map[string]int{st: http.StatusUnauthorized, refreshed: http.StatusOK} {
+						req := testhelpers.NewHTTPGetJSONRequest(t, publicTS.URL+session.RouteWhoami)
+						req.Header.Set("Authorization", "Bearer "+token)
+						res, err := http.DefaultClient.Do(req)
+						require.NoError(t, err)
+						require.NoError(t, res.Body.Close())
+						assert.Equal(t, expected, res.StatusCode)
+					}
+				})
 			})
 		})
 	})
@@ -522,6 +605,75 @@ func TestCompleteLogin(t *testing.T) {
 		assert.NotEqual(t, gjson.Get(body1, "id").String(), gjson.Get(body2, "id").String(), "%s\n\n%s\n", body1, body2)
 	})
 
+	t.Run("case=should remember the session if asked to", func(t *testing.T) {
+		identifier, pwd := x.NewUUID().String(), "password"
+		createIdentity(identifier, pwd)
+
+		conf.MustSet(config.ViperKeySessionRememberMeEnabled, true)
+		conf.MustSet(config.ViperKeySessionRememberMeLifespan, "720h")
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySessionRememberMeEnabled, false)
+		})
+
+		for _, remember := range []bool{false, true} {
+			t.Run(fmt.Sprintf("remember=%t", remember), func(t *testing.T) {
+				browserClient := testhelpers.NewClientWithCookies(t)
+				f := testhelpers.InitializeLoginFlowViaBrowser(t, browserClient, publicTS, false)
+				c := testhelpers.GetLoginFlowMethodConfig(t, f.Payload, identity.CredentialsTypePassword.String())
+				fields := x.MustEncodeJSON(t, c.Fields)
+				assert.Equal(t, "checkbox", gjson.Get(fields, "#(name==remember).type").String(), "%s", fields)
+
+				values := url.Values{"identifier": {identifier}, "password": {pwd}, "csrf_token": {x.FakeCSRFToken}}
+				if remember {
+					values.Set("remember", "true")
+				}
+
+				body, res := testhelpers.LoginMakeRequest(t, false, c, browserClient, values.Encode())
+				assert.EqualValues(t, http.StatusOK, res.StatusCode, "%s", body)
+				assert.Equal(t, remember, gjson.Get(body, "remembered").Bool(), "%s", body)
+
+				lifespan := conf.SessionLifespan()
+				if remember {
+					lifespan = conf.SessionRememberMeLifespan()
+				}
+				assert.WithinDuration(t, time.Now().Add(lifespan), gjson.Get(body, "expires_at").Time(), time.Minute, "%s", body)
+			})
+		}
+	})
+
+	t.Run("case=should only return the selected identity fields", func(t *testing.T) {
+		identifier, pwd := x.NewUUID().String(), "password"
+		createIdentity(identifier, pwd)
+
+		values := testhelpers.EncodeFormAsJSON(t, true, url.Values{"identifier": {identifier}, "password": {pwd}})
+
+		t.Run("type=api", func(t *testing.T) {
+			f := testhelpers.InitializeLoginFlowViaAPI(t, apiClient, publicTS, false)
+			c := testhelpers.GetLoginFlowMethodConfig(t, f.Payload, identity.CredentialsTypePassword.String())
+			c.Action = pointerx.String(pointerx.StringR(c.Action) + "&fields=id,traits.subject")
+
+			body, res := testhelpers.LoginMakeRequest(t, true, c, apiClient, values)
+			assert.EqualValues(t, http.StatusOK, res.StatusCode, "%s", body)
+			assert.NotEmpty(t, gjson.Get(body, "session_token").String(), "%s", body)
+			assert.NotEmpty(t, gjson.Get(body, "session.id").String(), "%s", body)
+			assert.NotEmpty(t, gjson.Get(body, "session.identity.id").String(), "%s", body)
+			assert.Equal(t, identifier, gjson.Get(body, "session.identity.traits.subject").String(), "%s", body)
+			assert.False(t, gjson.Get(body, "session.identity.schema_id").Exists(), "%s", body)
+			assert.False(t, gjson.Get(body, "session.identity.recovery_addresses").Exists(), "%s", body)
+		})
+
+		t.Run("case=should fail if a field can not be selected", func(t *testing.T) {
+			f := testhelpers.InitializeLoginFlowViaAPI(t, apiClient, publicTS, false)
+			c := testhelpers.GetLoginFlowMethodConfig(t, f.Payload, identity.CredentialsTypePassword.String())
+			c.Action = pointerx.String(pointerx.StringR(c.Action) + "&fields=credentials")
+
+			body, res := testhelpers.LoginMakeRequest(t, true, c, apiClient, values)
+			assert.Contains(t, res.Request.URL.String(), errTS.URL, "%s", body)
+			assert.EqualValues(t, http.StatusBadRequest, gjson.Get(body, "0.code").Int(), "%s", body)
+			assert.Contains(t, gjson.Get(body, "0.reason").String(), `The identity field "credentials" can not be selected.`, "%s", body)
+		})
+	})
+
 	t.Run("should login same identity regardless of identifier capitalization", func(t *testing.T) {
 		identifier, pwd := x.NewUUID().String(), "password"
 		createIdentity(identifier, pwd)
@@ -542,3 +694,99 @@ func TestCompleteLogin(t *testing.T) {
 		assert.Equal(t, identifier, gjson.Get(body2, "identity.traits.subject").String(), "%s", body2)
 	})
 }
+
+func TestCompleteLoginWithExternalStore(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeySelfServiceStrategyConfig+"."+string(identity.CredentialsTypePassword),
+		map[string]interface{}{"enabled": true})
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+
+	errTS := testhelpers.NewErrorTestServer(t, reg)
+	uiTS := testhelpers.NewLoginUIFlowEchoServer(t, reg)
+	conf.MustSet(config.ViperKeySelfServiceErrorUI, errTS.URL+"/error-ts")
+	conf.MustSet(config.ViperKeySelfServiceLoginUI, uiTS.URL+"/login-ts")
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/login.schema.json")
+	conf.MustSet(config.ViperKeySecretsDefault, []string{"not-a-secure-session-key"})
+
+	var calls int
+	available := true
+	storeTS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if !available {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var body struct{ Identifier, Password string }
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body.Password != "legacy-password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = fmt.Fprintf(w, `{"id":42,"email":"%s","name":"Legacy User"}`, body.Identifier)
+	}))
+	t.Cleanup(storeTS.Close)
+
+	conf.MustSet(config.ViperKeyPasswordExternalStoreURL, storeTS.URL)
+	conf.MustSet(config.ViperKeyPasswordExternalStoreBearerToken, "secret")
+	conf.MustSet(config.ViperKeyPasswordExternalStoreMapperURL, "file://./stub/external_store.jsonnet")
+
+	login := func(t *testing.T, identifier, pwd string) string {
+		f := testhelpers.InitializeLoginFlowViaAPI(t, testhelpers.NewDebugClient(t), publicTS, false)
+		c := testhelpers.GetLoginFlowMethodConfig(t, f.Payload, identity.CredentialsTypePassword.String())
+		values := fmt.Sprintf(`{"identifier":"%s","password":"%s"}`, identifier, pwd)
+		body, _ := testhelpers.LoginMakeRequest(t, true, c, testhelpers.NewDebugClient(t), values)
+		return body
+	}
+
+	t.Run("case=should reject an invalid password of the external identity store", func(t *testing.T) {
+		identifier := x.NewUUID().String() + "@ory.sh"
+		body := login(t, identifier, "not-the-password")
+		assert.Equal(t, text.NewErrorValidationInvalidCredentials().Text, gjson.Get(body, "methods.password.config.messages.0.text").String(), "%s", body)
+
+		_, _, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(context.Background(), identity.CredentialsTypePassword, identifier)
+		assert.Error(t, err)
+	})
+
+	t.Run("case=should import the identity on first login and use the local password afterwards", func(t *testing.T) {
+		identifier := x.NewUUID().String() + "@ory.sh"
+
+		body := login(t, identifier, "legacy-password")
+		assert.NotEmpty(t, gjson.Get(body, "session_token").String(), "%s", body)
+		assert.Equal(t, identifier, gjson.Get(body, "session.identity.traits.subject").String(), "%s", body)
+		assert.Equal(t, "Legacy User", gjson.Get(body, "session.identity.traits.name").String(), "%s", body)
+
+		i, _, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(context.Background(), identity.CredentialsTypePassword, identifier)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"legacy_id":42}`, string(i.MetadataPublic))
+
+		available = false
+		t.Cleanup(func() {
+			available = true
+		})
+		before := calls
+
+		body = login(t, strings.ToUpper(identifier), "legacy-password")
+		assert.Equal(t, i.ID.String(), gjson.Get(body, "session.identity.id").String(), "%s", body)
+
+		body = login(t, identifier, "wrong-password")
+		assert.Equal(t, text.NewErrorValidationInvalidCredentials().Text, gjson.Get(body, "methods.password.config.messages.0.text").String(), "%s", body)
+		assert.Equal(t, before, calls, "the external identity store must not be called for known identities")
+	})
+
+	t.Run("case=should fail if the external identity store is unavailable", func(t *testing.T) {
+		available = false
+		t.Cleanup(func() {
+			available = true
+		})
+
+		body := login(t, x.NewUUID().String()+"@ory.sh", "legacy-password")
+		assert.Contains(t, body, "Unable to verify the credentials with the external identity store.", "%s", body)
+	})
+}
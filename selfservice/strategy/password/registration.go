@@ -13,11 +13,11 @@ import (
 
 	"github.com/ory/x/errorsx"
 
+	"github.com/ory/jsonschema/v3"
 	_ "github.com/ory/jsonschema/v3/fileloader"
 	_ "github.com/ory/jsonschema/v3/httploader"
 	"github.com/ory/x/decoderx"
 
-	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/session"
 
@@ -68,7 +68,13 @@ func (s *Strategy) handleRegistrationError(w http.ResponseWriter, r *http.Reques
 
 			method.Config.SetCSRF(s.d.GenerateCSRFToken(r))
 			rr.Methods[identity.CredentialsTypePassword] = method
-			if errSec := method.Config.SortFields(s.c.DefaultIdentityTraitsSchemaURL().String()); errSec != nil {
+			traitsSchema, errSec := s.d.IdentityTraitsSchemas().GetByID(rr.IdentitySchemaID)
+			if errSec != nil {
+				s.d.RegistrationFlowErrorHandler().WriteFlowError(w, r, identity.CredentialsTypePassword, rr, errors.Wrap(err, errSec.Error()))
+				return
+			}
+
+			if errSec := method.Config.SortFields(traitsSchema.URL.String()); errSec != nil {
 				s.d.RegistrationFlowErrorHandler().WriteFlowError(w, r, identity.CredentialsTypePassword, rr, errors.Wrap(err, errSec.Error()))
 				return
 			}
@@ -78,9 +84,14 @@ func (s *Strategy) handleRegistrationError(w http.ResponseWriter, r *http.Reques
 	s.d.RegistrationFlowErrorHandler().WriteFlowError(w, r, identity.CredentialsTypePassword, rr, err)
 }
 
-func (s *Strategy) decode(p *RegistrationFormPayload, r *http.Request) error {
+func (s *Strategy) decode(p *RegistrationFormPayload, r *http.Request, schemaID string) error {
+	traitsSchema, err := s.d.IdentityTraitsSchemas().GetByID(schemaID)
+	if err != nil {
+		return err
+	}
+
 	raw, err := sjson.SetBytes(pkgerx.MustRead(pkger.Open("/selfservice/strategy/password/.schema/registration.schema.json")),
-		"properties.traits.$ref", s.c.DefaultIdentityTraitsSchemaURL().String()+"#/properties/traits")
+		"properties.traits.$ref", traitsSchema.URL.String()+"#/properties/traits")
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -101,6 +112,19 @@ type completeSelfServiceRegistrationFlowWithPasswordMethodParameters struct {
 	// in: query
 	Flow string `json:"flow"`
 
+	// The flow token returned when initializing an API flow. Required for API flows if
+	// `selfservice.api_flow_binding` is enabled.
+	//
+	// in: header
+	FlowToken string `json:"X-Kratos-Flow-Token"`
+
+	// Fields is a comma separated list of the identity fields, for example `id,traits.email`, to include in the
+	// response of API flows. Nested fields can only be selected for `traits` and `metadata_public`. All fields are
+	// included if not set.
+	//
+	// in: query
+	Fields string `json:"fields"`
+
 	// in: body
 	Payload interface{}
 }
@@ -151,13 +175,13 @@ func (s *Strategy) handleRegistration(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
-	if err := ar.Valid(); err != nil {
+	if err := ar.Valid(s.d.Clock().Now(), s.c.SelfServiceFlowExpiredGracePeriod()); err != nil {
 		s.handleRegistrationError(w, r, ar, nil, err)
 		return
 	}
 
 	var p RegistrationFormPayload
-	if err := s.decode(&p, r); err != nil {
+	if err := s.decode(&p, r, ar.IdentitySchemaID); err != nil {
 		s.handleRegistrationError(w, r, ar, &p, err)
 		return
 	}
@@ -167,6 +191,11 @@ func (s *Strategy) handleRegistration(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
+	if err := flow.VerifyFlowToken(r, ar.Type, s.c.SelfServiceAPIFlowBinding(), ar.FlowTokenHash); err != nil {
+		s.handleRegistrationError(w, r, ar, &p, err)
+		return
+	}
+
 	if len(p.Password) == 0 {
 		s.handleRegistrationError(w, r, ar, &p, schema.NewRequiredError("#/password", "password"))
 		return
@@ -188,7 +217,7 @@ func (s *Strategy) handleRegistration(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
-	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	i := identity.NewIdentity(ar.IdentitySchemaID)
 	i.Traits = identity.Traits(p.Traits)
 	i.SetCredentials(s.ID(), identity.Credentials{Type: s.ID(), Identifiers: []string{}, Config: co})
 
@@ -230,17 +259,29 @@ func (s *Strategy) validateCredentials(i *identity.Identity, pw string) error {
 
 func (s *Strategy) PopulateRegistrationMethod(r *http.Request, sr *registration.Flow) error {
 	action := sr.AppendTo(urlx.AppendPaths(s.c.SelfPublicURL(), RouteRegistration))
+	traitsSchema, err := s.d.IdentityTraitsSchemas().GetByID(sr.IdentitySchemaID)
+	if err != nil {
+		return err
+	}
+
+	// The compiler must know the schema extension so that the identifier fields can be marked as usernames.
+	runner, err := schema.NewExtensionRunner(schema.ExtensionRunnerIdentityMetaSchema)
+	if err != nil {
+		return err
+	}
+	compiler := jsonschema.NewCompiler()
+	runner.Register(compiler)
 
-	htmlf, err := form.NewHTMLFormFromJSONSchema(action.String(), s.c.DefaultIdentityTraitsSchemaURL().String(), "", nil)
+	htmlf, err := form.NewHTMLFormFromJSONSchema(action.String(), traitsSchema.URL.String(), "", compiler)
 	if err != nil {
 		return err
 	}
 
 	htmlf.Method = "POST"
 	htmlf.SetCSRF(s.d.GenerateCSRFToken(r))
-	htmlf.SetField(form.Field{Name: "password", Type: "password", Required: true})
+	htmlf.SetField(form.Field{Name: "password", Type: "password", Required: true, Autocomplete: form.AutocompleteNewPassword})
 
-	if err := htmlf.SortFields(s.c.DefaultIdentityTraitsSchemaURL().String()); err != nil {
+	if err := htmlf.SortFields(traitsSchema.URL.String()); err != nil {
 		return err
 	}
 
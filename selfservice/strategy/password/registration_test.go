@@ -431,6 +431,60 @@ func TestRegistration(t *testing.T) {
 			})
 		})
 
+		t.Run("case=should only return the selected identity fields", func(t *testing.T) {
+			conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/registration.schema.json")
+			conf.MustSet(config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter, identity.CredentialsTypePassword.String()), []config.SelfServiceHook{{Name: "session"}})
+			t.Cleanup(func() {
+				conf.MustSet(config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter, identity.CredentialsTypePassword.String()), nil)
+			})
+
+			f := testhelpers.InitializeRegistrationFlowViaAPI(t, apiClient, publicTS)
+			c := testhelpers.GetRegistrationFlowMethodConfig(t, f.Payload, identity.CredentialsTypePassword.String())
+			c.Action = pointerx.String(pointerx.StringR(c.Action) + "&fields=id,traits.username")
+
+			values := testhelpers.SDKFormFieldsToURLValues(c.Fields)
+			values.Set("traits.username", "registration-identifier-fields-api")
+			values.Set("password", x.NewUUID().String())
+			values.Set("traits.foobar", "bar")
+
+			body, res := testhelpers.RegistrationMakeRequest(t, true, c, apiClient, testhelpers.EncodeFormAsJSON(t, true, values))
+			assert.EqualValues(t, http.StatusOK, res.StatusCode, "%s", body)
+			assert.NotEmpty(t, gjson.Get(body, "session_token").String(), "%s", body)
+			for _, path := range []string{"identity", "session.identity"} {
+				assert.NotEmpty(t, gjson.Get(body, path+".id").String(), "%s", body)
+				assert.Equal(t, "registration-identifier-fields-api", gjson.Get(body, path+".traits.username").String(), "%s", body)
+				assert.False(t, gjson.Get(body, path+".traits.foobar").Exists(), "%s", body)
+				assert.False(t, gjson.Get(body, path+".schema_id").Exists(), "%s", body)
+			}
+		})
+
+		t.Run("case=should register the identity with the schema chosen when initializing the flow", func(t *testing.T) {
+			conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/registration.schema.json")
+			conf.MustSet(config.ViperKeyIdentitySchemas, []config.SchemaConfig{{ID: "employee", URL: "file://./stub/profile.schema.json"}})
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeyIdentitySchemas, []config.SchemaConfig{})
+			})
+
+			res, err := apiClient.Get(publicTS.URL + registration.RouteInitAPIFlow + "?schema=employee")
+			require.NoError(t, err)
+			defer res.Body.Close()
+			f := ioutilx.MustReadAll(res.Body)
+			require.EqualValues(t, http.StatusOK, res.StatusCode, "%s", f)
+			assert.Equal(t, "employee", gjson.GetBytes(f, "identity_schema_id").String(), "%s", f)
+			fieldNameSet(t, f, []string{"traits.email", "password"})
+
+			values := url.Values{}
+			values.Set("traits.email", "registration-employee@ory.sh")
+			values.Set("password", x.NewUUID().String())
+
+			action := gjson.GetBytes(f, "methods.password.config.action").String()
+			body, res := testhelpers.RegistrationMakeRequest(t, true, &models.RegistrationFlowMethodConfig{Action: pointerx.String(action)},
+				apiClient, testhelpers.EncodeFormAsJSON(t, true, values))
+			assert.EqualValues(t, http.StatusOK, res.StatusCode, "%s", body)
+			assert.Equal(t, "employee", gjson.Get(body, "identity.schema_id").String(), "%s", body)
+			assert.Equal(t, "registration-employee@ory.sh", gjson.Get(body, "identity.traits.email").String(), "%s", body)
+		})
+
 		t.Run("case=should fail to register the same user again", func(t *testing.T) {
 			conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/registration.schema.json")
 			conf.MustSet(config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter, identity.CredentialsTypePassword.String()), []config.SelfServiceHook{{Name: "session"}})
@@ -561,7 +615,7 @@ func TestRegistration(t *testing.T) {
 		conf.MustSet(config.ViperKeySelfServiceStrategyConfig+"."+string(identity.CredentialsTypePassword), map[string]interface{}{
 			"enabled": true})
 
-		sr := registration.NewFlow(time.Minute, "nosurf", &http.Request{URL: urlx.ParseOrPanic("/")}, flow.TypeBrowser)
+		sr := registration.NewFlow(time.Now(), time.Minute, "nosurf", &http.Request{URL: urlx.ParseOrPanic("/")}, flow.TypeBrowser)
 		require.NoError(t, reg.RegistrationStrategies().MustStrategy(identity.CredentialsTypePassword).(*password.Strategy).PopulateRegistrationMethod(&http.Request{}, sr))
 
 		expected := &registration.FlowMethod{
@@ -579,17 +633,19 @@ func TestRegistration(t *testing.T) {
 								Value:    x.FakeCSRFToken,
 							},
 							{
-								Name:     "password",
-								Type:     "password",
-								Required: true,
+								Name:         "password",
+								Type:         "password",
+								Required:     true,
+								Autocomplete: form.AutocompleteNewPassword,
 							},
 							{
 								Name: "traits.foobar",
 								Type: "text",
 							},
 							{
-								Name: "traits.username",
-								Type: "text",
+								Name:         "traits.username",
+								Type:         "text",
+								Autocomplete: form.AutocompleteUsername,
 							},
 						},
 					},
@@ -204,7 +204,7 @@ func (s *Strategy) continueSettingsFlow(
 	}
 
 	if err := s.d.SettingsHookExecutor().PostSettingsHook(w, r,
-		s.SettingsStrategyID(), ctxUpdate, i); err != nil {
+		s.SettingsStrategyID(), ctxUpdate, i, settings.WithSessionRotation()); err != nil {
 		s.handleSettingsError(w, r, ctxUpdate, p, err)
 		return
 	}
@@ -213,7 +213,7 @@ func (s *Strategy) continueSettingsFlow(
 func (s *Strategy) PopulateSettingsMethod(r *http.Request, _ *identity.Identity, f *settings.Flow) error {
 	hf := &form.HTMLForm{Action: urlx.CopyWithQuery(urlx.AppendPaths(s.c.SelfPublicURL(), RouteSettings),
 		url.Values{"flow": {f.ID.String()}}).String(), Fields: form.Fields{{Name: "password",
-		Type: "password", Required: true}}, Method: "POST"}
+		Type: "password", Required: true, Autocomplete: form.AutocompleteNewPassword}}, Method: "POST"}
 	hf.SetCSRF(s.d.GenerateCSRFToken(r))
 
 	f.Methods[string(s.ID())] = &settings.FlowMethod{
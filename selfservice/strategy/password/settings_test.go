@@ -22,6 +22,7 @@ import (
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/strategy/password"
 	"github.com/ory/kratos/selfservice/strategy/profile"
+	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 	"github.com/ory/x/assertx"
 	"github.com/ory/x/httpx"
@@ -83,6 +84,19 @@ func TestSettings(t *testing.T) {
 
 	adminClient := testhelpers.NewSDKClient(adminTS)
 
+	// Changing the password rotates the session, so API clients continue with the session token of the response.
+	var rotatedAPIClient = func(t *testing.T, previous *http.Client, actual string) *http.Client {
+		token := gjson.Get(actual, "session_token").String()
+		require.NotEmpty(t, token, "%s", actual)
+
+		res, err := previous.Get(publicTS.URL + session.RouteWhoami)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "the previous session token was revoked")
+
+		return &http.Client{Transport: x.NewTransportWithHeader(http.Header{"Authorization": {"Bearer " + token}})}
+	}
+
 	t.Run("description=not authorized to call endpoints without a session", func(t *testing.T) {
 		c := testhelpers.NewDebugClient(t)
 		t.Run("type=browser", func(t *testing.T) {
@@ -193,6 +207,7 @@ func TestSettings(t *testing.T) {
 			actual := testhelpers.SubmitSettingsForm(t, true, apiUser1, publicTS, payload,
 				identity.CredentialsTypePassword.String(), http.StatusOK, publicTS.URL+password.RouteSettings)
 			check(t, gjson.Get(actual, "flow").Raw)
+			apiUser1 = rotatedAPIClient(t, apiUser1, actual)
 		})
 
 		t.Run("type=browser", func(t *testing.T) {
@@ -226,6 +241,7 @@ func TestSettings(t *testing.T) {
 
 		assert.Contains(t, res.Request.URL.String(), publicTS.URL+password.RouteSettings)
 		assert.NotEmpty(t, gjson.Get(actual, "identity.id").String(), "%s", actual)
+		apiUser1 = rotatedAPIClient(t, apiUser1, actual)
 	})
 
 	t.Run("case=should fail with correct CSRF error cause/type=api", func(t *testing.T) {
@@ -292,6 +308,7 @@ func TestSettings(t *testing.T) {
 		t.Run("type=api", func(t *testing.T) {
 			actual := expectSuccess(t, true, apiUser2, payload)
 			check(t, gjson.Get(actual, "flow").Raw, apiIdentity2)
+			apiUser2 = rotatedAPIClient(t, apiUser2, actual)
 		})
 
 		t.Run("type=browser", func(t *testing.T) {
@@ -8,11 +8,13 @@ import (
 	"gopkg.in/go-playground/validator.v9"
 
 	"github.com/ory/x/decoderx"
+	"github.com/ory/x/fetcher"
 
 	"github.com/ory/kratos/continuity"
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/hash"
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/flow/registration"
@@ -30,6 +32,7 @@ type registrationStrategyDependencies interface {
 	x.WriterProvider
 	x.CSRFTokenGeneratorProvider
 	x.CSRFProvider
+	x.ClockProvider
 
 	continuity.ManagementProvider
 
@@ -56,9 +59,12 @@ type registrationStrategyDependencies interface {
 
 	identity.PrivilegedPoolProvider
 	identity.ValidationProvider
+	identity.ManagementProvider
 
 	session.HandlerProvider
 	session.ManagementProvider
+
+	IdentityTraitsSchemas() schema.Schemas
 }
 
 type Strategy struct {
@@ -66,6 +72,7 @@ type Strategy struct {
 	c  *config.Provider
 	v  *validator.Validate
 	hd *decoderx.HTTP
+	f  *fetcher.Fetcher
 }
 
 func (s *Strategy) CountActiveCredentials(cc map[identity.CredentialsType]identity.Credentials) (count int, err error) {
@@ -94,6 +101,7 @@ func NewStrategy(
 		d:  d,
 		v:  validator.New(),
 		hd: decoderx.NewHTTP(),
+		f:  fetcher.NewFetcher(),
 	}
 }
 
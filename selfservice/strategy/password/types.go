@@ -19,6 +19,9 @@ type (
 
 		// Sending the anti-csrf token is only required for browser login flows.
 		CSRFToken string `form:"csrf_token" json:"csrf_token"`
+
+		// Remember keeps the user signed in for longer if `session.remember_me.enabled` is set.
+		Remember bool `form:"remember" json:"remember,omitempty"`
 	}
 )
 
@@ -43,6 +43,7 @@ type (
 		x.CSRFTokenGeneratorProvider
 		x.WriterProvider
 		x.LoggingProvider
+		x.ClockProvider
 
 		continuity.ManagementProvider
 
@@ -0,0 +1,11 @@
+package saml
+
+import "github.com/ory/herodot"
+
+var (
+	ErrAPIFlowNotSupported = herodot.ErrBadRequest.WithError("API-based flows are not supported for this method").
+				WithReasonf("SAML is only supported for flows initiated using the Browser endpoint.")
+
+	ErrInvalidResponse = herodot.ErrBadRequest.WithError("invalid SAML response").
+				WithReasonf("The SAML Response of the Identity Provider is invalid. Please try again.")
+)
@@ -0,0 +1,240 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/pkg/errors"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIdentityProvider issues SAML Responses signed like a real Identity Provider would. It is exported for the
+// tests of package saml_test.
+type TestIdentityProvider struct {
+	EntityID string
+	SSOURL   string
+
+	key         *rsa.PrivateKey
+	certificate []byte
+}
+
+func newTestCertificate(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "saml"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return key, der
+}
+
+func NewTestIdentityProvider(t *testing.T, entityID string) *TestIdentityProvider {
+	key, certificate := newTestCertificate(t)
+	return &TestIdentityProvider{
+		EntityID:    entityID,
+		SSOURL:      entityID + "/sso",
+		key:         key,
+		certificate: certificate,
+	}
+}
+
+func (p *TestIdentityProvider) Metadata() []byte {
+	return []byte(`<?xml version="1.0"?>
+<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="` + p.EntityID + `">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="signing">
+      <ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">
+        <ds:X509Data>
+          <ds:X509Certificate>` + base64.StdEncoding.EncodeToString(p.certificate) + `</ds:X509Certificate>
+        </ds:X509Data>
+      </ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="` + p.SSOURL + `/post"/>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="` + p.SSOURL + `"/>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`)
+}
+
+// Configuration returns the configuration of a provider trusting this Identity Provider. The key pair of the
+// Service Provider is generated.
+func (p *TestIdentityProvider) Configuration(t *testing.T, id, mapper string) Configuration {
+	key, certificate := newTestCertificate(t)
+	encode := func(kind string, der []byte) string {
+		return "base64://" + base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}))
+	}
+
+	return Configuration{
+		ID:                          id,
+		IdentityProviderMetadataURL: "base64://" + base64.StdEncoding.EncodeToString(p.Metadata()),
+		PrivateKeyURL:               encode("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+		CertificateURL:              encode("CERTIFICATE", certificate),
+		Mapper:                      mapper,
+	}
+}
+
+// TestResponse describes the SAML Response issued by the TestIdentityProvider.
+type TestResponse struct {
+	Issuer       string
+	Destination  string
+	Audience     string
+	InResponseTo string
+	NameID       string
+	NameIDFormat string
+	Email        string
+	IssuedAt     time.Time
+	Lifespan     time.Duration
+
+	SignResponse  bool
+	SignAssertion bool
+}
+
+var testResponseTemplate = template.Must(template.New("").Parse(`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" ID="id-response" Version="2.0" IssueInstant="{{.IssuedAt}}" Destination="{{.Destination}}" InResponseTo="{{.InResponseTo}}">
+  <saml:Issuer>{{.Issuer}}</saml:Issuer>
+  <samlp:Status>
+    <samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/>
+  </samlp:Status>
+  <saml:Assertion ID="id-assertion" Version="2.0" IssueInstant="{{.IssuedAt}}">
+    <saml:Issuer>{{.Issuer}}</saml:Issuer>
+    <saml:Subject>
+      <saml:NameID Format="{{.NameIDFormat}}">{{.NameID}}</saml:NameID>
+      <saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+        <saml:SubjectConfirmationData InResponseTo="{{.InResponseTo}}" NotOnOrAfter="{{.ExpiresAt}}" Recipient="{{.Destination}}"/>
+      </saml:SubjectConfirmation>
+    </saml:Subject>
+    <saml:Conditions NotBefore="{{.IssuedAt}}" NotOnOrAfter="{{.ExpiresAt}}">
+      <saml:AudienceRestriction>
+        <saml:Audience>{{.Audience}}</saml:Audience>
+      </saml:AudienceRestriction>
+    </saml:Conditions>
+    <saml:AttributeStatement>
+      <saml:Attribute Name="email">
+        <saml:AttributeValue xsi:type="xs:string">{{.Email}}</saml:AttributeValue>
+      </saml:Attribute>
+    </saml:AttributeStatement>
+  </saml:Assertion>
+</samlp:Response>`))
+
+// Response returns the base64 encoded SAML Response as posted to the Assertion Consumer Service.
+func (p *TestIdentityProvider) Response(t *testing.T, r TestResponse) string {
+	if r.Issuer == "" {
+		r.Issuer = p.EntityID
+	}
+	if r.NameIDFormat == "" {
+		r.NameIDFormat = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+	}
+	if r.IssuedAt.IsZero() {
+		r.IssuedAt = time.Now()
+	}
+	if r.Lifespan == 0 {
+		r.Lifespan = time.Minute * 5
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, testResponseTemplate.Execute(&b, map[string]interface{}{
+		"Issuer":       r.Issuer,
+		"Destination":  r.Destination,
+		"Audience":     r.Audience,
+		"InResponseTo": r.InResponseTo,
+		"NameID":       r.NameID,
+		"NameIDFormat": r.NameIDFormat,
+		"Email":        r.Email,
+		"IssuedAt":     r.IssuedAt.UTC().Format(time.RFC3339),
+		"ExpiresAt":    r.IssuedAt.Add(r.Lifespan).UTC().Format(time.RFC3339),
+	}))
+
+	response, err := parseXML(b.Bytes())
+	require.NoError(t, err)
+
+	if r.SignAssertion {
+		p.sign(t, element(response, namespaceAssertion, "Assertion"))
+	}
+	if r.SignResponse {
+		p.sign(t, response)
+	}
+
+	doc := etree.NewDocument()
+	doc.SetRoot(response)
+	document, err := doc.WriteToBytes()
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(document)
+}
+
+// sign adds an enveloped signature to the element, right after its Issuer.
+func (p *TestIdentityProvider) sign(t *testing.T, e *etree.Element) {
+	s, err := dsig.NewSigningContext(p.key, [][]byte{p.certificate})
+	require.NoError(t, err)
+	s.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+
+	// The element is signed with the namespace declarations of its ancestors, like a verifier sees it.
+	ctx, err := etreeutils.NSBuildParentContext(e)
+	require.NoError(t, err)
+	detached, err := etreeutils.NSDetatch(ctx, e)
+	require.NoError(t, err)
+
+	signed, err := s.SignEnveloped(detached)
+	require.NoError(t, err)
+	signature := signed.ChildElements()[len(signed.ChildElements())-1]
+
+	issuer := element(e, namespaceAssertion, "Issuer")
+	require.NotNil(t, issuer, "the element has no Issuer")
+	e.InsertChildAt(issuer.Index()+1, signature)
+}
+
+// InflateAuthnRequest decodes the SAMLRequest query parameter of the HTTP-Redirect binding.
+func InflateAuthnRequest(encoded string) (string, error) {
+	deflated, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	request, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(request), nil
+}
+
+// VerifyRedirectSignature verifies the signature of the HTTP-Redirect binding query with the Service Provider's
+// certificate.
+func (p *Provider) VerifyRedirectSignature(query string) error {
+	i := strings.Index(query, "&Signature=")
+	if i < 0 {
+		return errors.New("the query is not signed")
+	}
+
+	encoded, err := url.QueryUnescape(query[i+len("&Signature="):])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	digest := crypto.SHA256.New()
+	_, _ = digest.Write([]byte(query[:i]))
+	return errors.WithStack(rsa.VerifyPKCS1v15(p.certificate.PublicKey.(*rsa.PublicKey), crypto.SHA256, digest.Sum(nil), signature))
+}
@@ -0,0 +1,294 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	dsig "github.com/russellhaering/goxmldsig"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/fetcher"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/x"
+)
+
+const (
+	namespaceMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+	namespaceProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	namespaceAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+
+	bindingHTTPRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	bindingHTTPPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+)
+
+// Provider is a SAML 2.0 Identity Provider.
+type Provider struct {
+	config      *Configuration
+	entityID    string
+	acsURL      string
+	metadata    *identityProviderMetadata
+	key         *rsa.PrivateKey
+	certificate *x509.Certificate
+}
+
+type identityProviderMetadata struct {
+	entityID     string
+	ssoURL       string
+	certificates []*x509.Certificate
+}
+
+func NewProvider(config *Configuration, public *url.URL, f *fetcher.Fetcher) (*Provider, error) {
+	p := &Provider{
+		config:   config,
+		entityID: config.entityID(public),
+		acsURL:   urlx.AppendPaths(urlx.Copy(public), RouteACS).String(),
+	}
+
+	metadata, err := f.Fetch(config.IdentityProviderMetadataURL)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to fetch the metadata of SAML Identity Provider %s: %s", config.ID, err))
+	}
+
+	if p.metadata, err = parseIdentityProviderMetadata(metadata.Bytes()); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the metadata of SAML Identity Provider %s: %s", config.ID, err))
+	}
+
+	key, err := fetchPEM(f, config.PrivateKeyURL)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to load the private key configured for SAML Identity Provider %s: %s", config.ID, err))
+	}
+
+	if p.key, err = parsePrivateKey(key); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the private key configured for SAML Identity Provider %s: %s", config.ID, err))
+	}
+
+	certificate, err := fetchPEM(f, config.CertificateURL)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to load the certificate configured for SAML Identity Provider %s: %s", config.ID, err))
+	}
+
+	if p.certificate, err = x509.ParseCertificate(certificate); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the certificate configured for SAML Identity Provider %s: %s", config.ID, err))
+	}
+
+	return p, nil
+}
+
+func (p *Provider) Config() *Configuration {
+	return p.config
+}
+
+func fetchPEM(f *fetcher.Fetcher, location string) ([]byte, error) {
+	b, err := f.Fetch(location)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b.Bytes())
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	return block.Bytes, nil
+}
+
+func parsePrivateKey(der []byte) (*rsa.PrivateKey, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the private key must be an RSA key")
+	}
+	return rsaKey, nil
+}
+
+func parseIdentityProviderMetadata(document []byte) (*identityProviderMetadata, error) {
+	root, err := parseXML(document)
+	if err != nil {
+		return nil, err
+	}
+
+	if !is(root, namespaceMetadata, "EntityDescriptor") {
+		return nil, errors.New("the metadata must have an EntityDescriptor root element")
+	}
+
+	descriptor := element(root, namespaceMetadata, "IDPSSODescriptor")
+	if descriptor == nil {
+		return nil, errors.New("the metadata does not describe an Identity Provider")
+	}
+
+	m := &identityProviderMetadata{entityID: root.SelectAttrValue("entityID", "")}
+	if m.entityID == "" {
+		return nil, errors.New("the metadata does not contain an entity ID")
+	}
+
+	for _, service := range elements(descriptor, namespaceMetadata, "SingleSignOnService") {
+		if service.SelectAttrValue("Binding", "") == bindingHTTPRedirect {
+			m.ssoURL = service.SelectAttrValue("Location", "")
+			break
+		}
+	}
+	if m.ssoURL == "" {
+		return nil, errors.New("the Identity Provider does not support the HTTP-Redirect binding for single sign-on")
+	}
+
+	for _, key := range elements(descriptor, namespaceMetadata, "KeyDescriptor") {
+		if use := key.SelectAttrValue("use", ""); use != "" && use != "signing" {
+			continue
+		}
+
+		info := element(key, namespaceXMLDSig, "KeyInfo")
+		if info == nil {
+			continue
+		}
+
+		for _, data := range elements(info, namespaceXMLDSig, "X509Data") {
+			for _, c := range elements(data, namespaceXMLDSig, "X509Certificate") {
+				der, err := decodeBase64Value(c)
+				if err != nil {
+					return nil, errors.Wrap(err, "unable to decode a signing certificate")
+				}
+
+				certificate, err := x509.ParseCertificate(der)
+				if err != nil {
+					return nil, errors.Wrap(err, "unable to parse a signing certificate")
+				}
+				m.certificates = append(m.certificates, certificate)
+			}
+		}
+	}
+	if len(m.certificates) == 0 {
+		return nil, errors.New("the metadata does not contain a signing certificate")
+	}
+
+	return m, nil
+}
+
+type authnRequest struct {
+	XMLName                     xml.Name `xml:"samlp:AuthnRequest"`
+	NamespaceProtocol           string   `xml:"xmlns:samlp,attr"`
+	NamespaceAssertion          string   `xml:"xmlns:saml,attr"`
+	ID                          string   `xml:"ID,attr"`
+	Version                     string   `xml:"Version,attr"`
+	IssueInstant                string   `xml:"IssueInstant,attr"`
+	Destination                 string   `xml:"Destination,attr"`
+	AssertionConsumerServiceURL string   `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding             string   `xml:"ProtocolBinding,attr"`
+	Issuer                      string   `xml:"saml:Issuer"`
+	NameIDPolicy                struct {
+		AllowCreate bool `xml:"AllowCreate,attr"`
+	} `xml:"samlp:NameIDPolicy"`
+}
+
+// AuthnRequestURL returns the URL which sends the browser to the Identity Provider with a signed AuthnRequest
+// using the HTTP-Redirect binding, and the ID of the AuthnRequest.
+func (p *Provider) AuthnRequestURL(relayState string, now time.Time) (string, string, error) {
+	request := authnRequest{
+		NamespaceProtocol:           namespaceProtocol,
+		NamespaceAssertion:          namespaceAssertion,
+		ID:                          "id-" + x.NewUUID().String(),
+		Version:                     "2.0",
+		IssueInstant:                now.UTC().Format(time.RFC3339),
+		Destination:                 p.metadata.ssoURL,
+		AssertionConsumerServiceURL: p.acsURL,
+		ProtocolBinding:             bindingHTTPPost,
+		Issuer:                      p.entityID,
+	}
+	request.NameIDPolicy.AllowCreate = true
+
+	encoded, err := xml.Marshal(&request)
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+
+	var deflated bytes.Buffer
+	w, err := flate.NewWriter(&deflated, flate.DefaultCompression)
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+	if _, err := w.Write(encoded); err != nil {
+		return "", "", errors.WithStack(err)
+	}
+	if err := w.Close(); err != nil {
+		return "", "", errors.WithStack(err)
+	}
+
+	// The signature of the HTTP-Redirect binding covers the URL encoded query parameters in this exact order.
+	query := "SAMLRequest=" + url.QueryEscape(base64.StdEncoding.EncodeToString(deflated.Bytes())) +
+		"&RelayState=" + url.QueryEscape(relayState) +
+		"&SigAlg=" + url.QueryEscape(dsig.RSASHA256SignatureMethod)
+
+	digest := crypto.SHA256.New()
+	_, _ = digest.Write([]byte(query))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest.Sum(nil))
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+	query += "&Signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(signature))
+
+	separator := "?"
+	if strings.Contains(p.metadata.ssoURL, "?") {
+		separator = "&"
+	}
+
+	return p.metadata.ssoURL + separator + query, request.ID, nil
+}
+
+type serviceProviderMetadata struct {
+	XMLName         xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID        string   `xml:"entityID,attr"`
+	SPSSODescriptor struct {
+		AuthnRequestsSigned        bool   `xml:"AuthnRequestsSigned,attr"`
+		WantAssertionsSigned       bool   `xml:"WantAssertionsSigned,attr"`
+		ProtocolSupportEnumeration string `xml:"protocolSupportEnumeration,attr"`
+		KeyDescriptor              struct {
+			Use     string `xml:"use,attr"`
+			KeyInfo struct {
+				XMLName         xml.Name `xml:"http://www.w3.org/2000/09/xmldsig# KeyInfo"`
+				X509Certificate string   `xml:"X509Data>X509Certificate"`
+			}
+		}
+		AssertionConsumerService struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+			Index    int    `xml:"index,attr"`
+		}
+	}
+}
+
+// Metadata returns ORY Kratos' SAML 2.0 Service Provider Metadata for this Identity Provider.
+func (p *Provider) Metadata() ([]byte, error) {
+	var m serviceProviderMetadata
+	m.EntityID = p.entityID
+	m.SPSSODescriptor.AuthnRequestsSigned = true
+	m.SPSSODescriptor.WantAssertionsSigned = true
+	m.SPSSODescriptor.ProtocolSupportEnumeration = namespaceProtocol
+	m.SPSSODescriptor.KeyDescriptor.Use = "signing"
+	m.SPSSODescriptor.KeyDescriptor.KeyInfo.X509Certificate = base64.StdEncoding.EncodeToString(p.certificate.Raw)
+	m.SPSSODescriptor.AssertionConsumerService.Binding = bindingHTTPPost
+	m.SPSSODescriptor.AssertionConsumerService.Location = p.acsURL
+
+	out, err := xml.MarshalIndent(&m, "", "  ")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return append([]byte(xml.Header), out...), nil
+}
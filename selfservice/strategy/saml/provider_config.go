@@ -0,0 +1,72 @@
+package saml
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/selfservice/form"
+)
+
+type Configuration struct {
+	// ID is the provider's ID
+	ID string `json:"id"`
+
+	// IdentityProviderMetadataURL is the location of the Identity Provider's SAML 2.0 Metadata document which
+	// contains its entity ID, single sign-on endpoint, and signing certificates.
+	//
+	// It can be a file://, http(s)://, or base64:// URL.
+	IdentityProviderMetadataURL string `json:"idp_metadata_url"`
+
+	// EntityID is ORY Kratos' entity ID at the Identity Provider. Defaults to the URL of the Service Provider
+	// Metadata endpoint.
+	EntityID string `json:"entity_id"`
+
+	// PrivateKeyURL is the location of the PEM encoded RSA private key used to sign AuthnRequests.
+	//
+	// It can be a file://, http(s)://, or base64:// URL.
+	PrivateKeyURL string `json:"private_key_url"`
+
+	// CertificateURL is the location of the PEM encoded X.509 certificate of the private key. It is published in
+	// the Service Provider Metadata.
+	//
+	// It can be a file://, http(s)://, or base64:// URL.
+	CertificateURL string `json:"certificate_url"`
+
+	// Mapper specifies the JSONNet code snippet which uses the SAML Assertion's NameID and attributes to hydrate
+	// the identity's data.
+	//
+	// It can be either a URL (file://, http(s)://, base64://) or an inline JSONNet code snippet.
+	Mapper string `json:"mapper_url"`
+
+	// UI contains the label, icon, and order of the provider's button in login and registration flows.
+	UI *form.UI `json:"ui,omitempty"`
+}
+
+func (p Configuration) metadataURL(public *url.URL) string {
+	return urlx.AppendPaths(urlx.Copy(public), strings.Replace(RouteMetadata, ":provider", p.ID, 1)).String()
+}
+
+func (p Configuration) entityID(public *url.URL) string {
+	if p.EntityID != "" {
+		return p.EntityID
+	}
+	return p.metadataURL(public)
+}
+
+type ConfigurationCollection struct {
+	Providers []Configuration `json:"providers"`
+}
+
+func (c ConfigurationCollection) configuration(id string) (*Configuration, error) {
+	for k := range c.Providers {
+		if c.Providers[k].ID == id {
+			return &c.Providers[k], nil
+		}
+	}
+	return nil, errors.WithStack(herodot.ErrNotFound.WithReasonf(`SAML Identity Provider "%s" is unknown or has not been configured`, id))
+}
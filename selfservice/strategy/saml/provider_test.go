@@ -0,0 +1,88 @@
+package saml
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/fetcher"
+	"github.com/ory/x/urlx"
+)
+
+func TestProvider_AuthnRequestURL(t *testing.T) {
+	idp := NewTestIdentityProvider(t, "https://idp.example.com")
+	conf := idp.Configuration(t, "idp", "")
+
+	p, err := NewProvider(&conf, urlx.ParseOrPanic("https://kratos.example.com/"), fetcher.NewFetcher())
+	require.NoError(t, err)
+
+	location, id, err := p.AuthnRequestURL("some-state", time.Now())
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(id, "id-"))
+
+	u, err := url.Parse(location)
+	require.NoError(t, err)
+	assert.Equal(t, idp.SSOURL, u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "some-state", u.Query().Get("RelayState"))
+	assert.Equal(t, dsig.RSASHA256SignatureMethod, u.Query().Get("SigAlg"))
+	assert.NotEmpty(t, u.Query().Get("Signature"))
+
+	request, err := InflateAuthnRequest(u.Query().Get("SAMLRequest"))
+	require.NoError(t, err)
+	assert.Contains(t, request, `ID="`+id+`"`)
+	assert.Contains(t, request, `AssertionConsumerServiceURL="https://kratos.example.com/self-service/methods/saml/acs"`)
+	assert.Contains(t, request, `<saml:Issuer>https://kratos.example.com/self-service/methods/saml/metadata/idp</saml:Issuer>`)
+
+	require.NoError(t, p.VerifyRedirectSignature(u.RawQuery))
+}
+
+func TestProvider_Metadata(t *testing.T) {
+	idp := NewTestIdentityProvider(t, "https://idp.example.com")
+	conf := idp.Configuration(t, "idp", "")
+	conf.EntityID = "urn:kratos"
+
+	p, err := NewProvider(&conf, urlx.ParseOrPanic("https://kratos.example.com/"), fetcher.NewFetcher())
+	require.NoError(t, err)
+
+	metadata, err := p.Metadata()
+	require.NoError(t, err)
+
+	root, err := parseXML(metadata)
+	require.NoError(t, err)
+	assert.True(t, is(root, namespaceMetadata, "EntityDescriptor"))
+	assert.Equal(t, "urn:kratos", root.SelectAttrValue("entityID", ""))
+
+	descriptor := element(root, namespaceMetadata, "SPSSODescriptor")
+	require.NotNil(t, descriptor)
+	assert.Equal(t, "true", descriptor.SelectAttrValue("AuthnRequestsSigned", ""))
+	assert.Equal(t, "https://kratos.example.com/self-service/methods/saml/acs",
+		element(descriptor, namespaceMetadata, "AssertionConsumerService").SelectAttrValue("Location", ""))
+
+	certificate := element(element(descriptor, namespaceMetadata, "KeyDescriptor"), namespaceXMLDSig, "KeyInfo")
+	require.NotNil(t, certificate)
+}
+
+func TestParseIdentityProviderMetadata(t *testing.T) {
+	idp := NewTestIdentityProvider(t, "https://idp.example.com")
+
+	m, err := parseIdentityProviderMetadata(idp.Metadata())
+	require.NoError(t, err)
+	assert.Equal(t, idp.EntityID, m.entityID)
+	assert.Equal(t, idp.SSOURL, m.ssoURL, "the HTTP-Redirect binding is used")
+	assert.Len(t, m.certificates, 1)
+
+	for _, tc := range []string{
+		`<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://idp.example.com"></md:EntityDescriptor>`,
+		strings.Replace(string(idp.Metadata()), "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect", "urn:oasis:names:tc:SAML:2.0:bindings:SOAP", 1),
+		strings.Replace(string(idp.Metadata()), `entityID="https://idp.example.com"`, "", 1),
+		strings.Replace(string(idp.Metadata()), `use="signing"`, `use="encryption"`, 1),
+	} {
+		_, err := parseIdentityProviderMetadata([]byte(tc))
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,209 @@
+package saml
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	statusSuccess              = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	nameIDFormatTransient      = "urn:oasis:names:tc:SAML:2.0:nameid-format:transient"
+	subjectConfirmationBearer  = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	maxClockSkew               = time.Minute
+	maxEncodedResponseByteSize = 1024 * 1024
+)
+
+// Claims are the parts of a validated SAML Assertion which are passed to the Jsonnet mapper.
+type Claims struct {
+	// Subject is the NameID of the Assertion's subject.
+	Subject string `json:"subject"`
+
+	// NameIDFormat is the format of the NameID, for example `urn:oasis:names:tc:SAML:2.0:nameid-format:persistent`.
+	NameIDFormat string `json:"name_id_format,omitempty"`
+
+	// Attributes are the values of the Assertion's attributes, keyed by the attribute names.
+	Attributes map[string][]string `json:"attributes"`
+}
+
+type assertion struct {
+	XMLName xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:assertion Assertion"`
+	Issuer  string   `xml:"Issuer"`
+	Subject struct {
+		NameID struct {
+			Format string `xml:"Format,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"NameID"`
+		Confirmations []struct {
+			Method string `xml:"Method,attr"`
+			Data   struct {
+				Recipient    string    `xml:"Recipient,attr"`
+				InResponseTo string    `xml:"InResponseTo,attr"`
+				NotOnOrAfter time.Time `xml:"NotOnOrAfter,attr"`
+			} `xml:"SubjectConfirmationData"`
+		} `xml:"SubjectConfirmation"`
+	} `xml:"Subject"`
+	Conditions struct {
+		NotBefore            time.Time `xml:"NotBefore,attr"`
+		NotOnOrAfter         time.Time `xml:"NotOnOrAfter,attr"`
+		AudienceRestrictions []struct {
+			Audiences []string `xml:"Audience"`
+		} `xml:"AudienceRestriction"`
+	} `xml:"Conditions"`
+	Attributes []struct {
+		Name   string   `xml:"Name,attr"`
+		Values []string `xml:"AttributeValue"`
+	} `xml:"AttributeStatement>Attribute"`
+}
+
+// ValidateResponse validates the base64 encoded SAML Response received by the Assertion Consumer Service and
+// returns the claims of its Assertion. The Response must answer the AuthnRequest with the given ID and either
+// the Response or the Assertion must be signed by the Identity Provider.
+func (p *Provider) ValidateResponse(encoded, requestID string, now time.Time) (*Claims, error) {
+	if len(encoded) > maxEncodedResponseByteSize {
+		return nil, errors.New("the SAML Response is too large")
+	}
+
+	document, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode the SAML Response")
+	}
+
+	response, err := parseXML(document)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse the SAML Response")
+	}
+
+	if !is(response, namespaceProtocol, "Response") {
+		return nil, errors.New("the document is not a SAML Response")
+	}
+
+	if destination := response.SelectAttrValue("Destination", ""); destination != "" && destination != p.acsURL {
+		return nil, errors.Errorf("the SAML Response was sent to %s instead of this Assertion Consumer Service", destination)
+	}
+
+	if response.SelectAttrValue("InResponseTo", "") != requestID {
+		return nil, errors.New("the SAML Response does not answer the AuthnRequest of this flow")
+	}
+
+	var status string
+	if s := element(response, namespaceProtocol, "Status"); s != nil {
+		if code := element(s, namespaceProtocol, "StatusCode"); code != nil {
+			status = code.SelectAttrValue("Value", "")
+		}
+	}
+	if status != statusSuccess {
+		return nil, errors.Errorf("the Identity Provider returned the status %s", status)
+	}
+
+	if len(elements(response, namespaceAssertion, "EncryptedAssertion")) > 0 {
+		return nil, errors.New("encrypted assertions are not supported")
+	}
+
+	assertions := elements(response, namespaceAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, errors.New("the SAML Response must contain exactly one Assertion")
+	}
+
+	// The Assertion is trusted if either the Response which contains it or the Assertion itself is signed. Only
+	// the verified elements returned by verifySignature are decoded to make sure that the claims are exactly what
+	// was signed.
+	var a assertion
+	if verified, err := verifySignature(response, p.metadata.certificates, now); errors.Is(err, errSignatureMissing) {
+		verified, err := verifySignature(assertions[0], p.metadata.certificates, now)
+		if errors.Is(err, errSignatureMissing) {
+			return nil, errors.New("neither the SAML Response nor the Assertion are signed")
+		} else if err != nil {
+			return nil, errors.Wrap(err, "unable to verify the signature of the Assertion")
+		}
+
+		if err := unmarshalElement(verified, &a); err != nil {
+			return nil, errors.Wrap(err, "unable to decode the Assertion")
+		}
+	} else if err != nil {
+		return nil, errors.Wrap(err, "unable to verify the signature of the SAML Response")
+	} else {
+		var r struct {
+			Assertions []assertion `xml:"urn:oasis:names:tc:SAML:2.0:assertion Assertion"`
+		}
+		if err := unmarshalElement(verified, &r); err != nil {
+			return nil, errors.Wrap(err, "unable to decode the Assertion")
+		} else if len(r.Assertions) != 1 {
+			return nil, errors.New("the signed SAML Response must contain exactly one Assertion")
+		}
+		a = r.Assertions[0]
+	}
+
+	if err := p.validateAssertion(&a, requestID, now); err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{
+		Subject:      strings.TrimSpace(a.Subject.NameID.Value),
+		NameIDFormat: a.Subject.NameID.Format,
+		Attributes:   map[string][]string{},
+	}
+	for _, attribute := range a.Attributes {
+		claims.Attributes[attribute.Name] = append(claims.Attributes[attribute.Name], attribute.Values...)
+	}
+
+	return claims, nil
+}
+
+func (p *Provider) validateAssertion(a *assertion, requestID string, now time.Time) error {
+	if strings.TrimSpace(a.Issuer) != p.metadata.entityID {
+		return errors.Errorf("the Assertion was issued by %s instead of the configured Identity Provider", a.Issuer)
+	}
+
+	if !a.Conditions.NotBefore.IsZero() && now.Add(maxClockSkew).Before(a.Conditions.NotBefore) {
+		return errors.New("the Assertion is not valid yet")
+	}
+
+	if !a.Conditions.NotOnOrAfter.IsZero() && !now.Add(-maxClockSkew).Before(a.Conditions.NotOnOrAfter) {
+		return errors.New("the Assertion has expired")
+	}
+
+	if len(a.Conditions.AudienceRestrictions) == 0 {
+		return errors.New("the Assertion has no audience restriction")
+	}
+
+	for _, restriction := range a.Conditions.AudienceRestrictions {
+		var found bool
+		for _, audience := range restriction.Audiences {
+			if strings.TrimSpace(audience) == p.entityID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("the Assertion is not intended for this Service Provider")
+		}
+	}
+
+	var confirmed bool
+	for _, c := range a.Subject.Confirmations {
+		if c.Method == subjectConfirmationBearer &&
+			c.Data.Recipient == p.acsURL &&
+			c.Data.InResponseTo == requestID &&
+			now.Add(-maxClockSkew).Before(c.Data.NotOnOrAfter) {
+			confirmed = true
+			break
+		}
+	}
+	if !confirmed {
+		return errors.New("the Assertion has no valid bearer subject confirmation for this flow")
+	}
+
+	if strings.TrimSpace(a.Subject.NameID.Value) == "" {
+		return errors.New("the Assertion has no NameID")
+	}
+
+	if a.Subject.NameID.Format == nameIDFormatTransient {
+		return errors.New("transient NameIDs can not be used to identify users, please configure the Identity Provider to use a persistent NameID")
+	}
+
+	return nil
+}
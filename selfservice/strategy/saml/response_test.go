@@ -0,0 +1,154 @@
+package saml
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/fetcher"
+	"github.com/ory/x/urlx"
+)
+
+func TestProvider_ValidateResponse(t *testing.T) {
+	public := urlx.ParseOrPanic("https://kratos.example.com/")
+	idp := NewTestIdentityProvider(t, "https://idp.example.com")
+	conf := idp.Configuration(t, "idp", "")
+
+	p, err := NewProvider(&conf, public, fetcher.NewFetcher())
+	require.NoError(t, err)
+
+	valid := func() TestResponse {
+		return TestResponse{
+			Destination:   "https://kratos.example.com" + RouteACS,
+			Audience:      "https://kratos.example.com/self-service/methods/saml/metadata/idp",
+			InResponseTo:  "id-request",
+			NameID:        "foo@example.com",
+			Email:         "foo@example.com",
+			SignAssertion: true,
+		}
+	}
+
+	t.Run("case=accepts a response with a signed assertion", func(t *testing.T) {
+		claims, err := p.ValidateResponse(idp.Response(t, valid()), "id-request", time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, "foo@example.com", claims.Subject)
+		assert.Equal(t, []string{"foo@example.com"}, claims.Attributes["email"])
+	})
+
+	t.Run("case=accepts a signed response", func(t *testing.T) {
+		r := valid()
+		r.SignAssertion, r.SignResponse = false, true
+		_, err := p.ValidateResponse(idp.Response(t, r), "id-request", time.Now())
+		require.NoError(t, err)
+	})
+
+	t.Run("case=accepts a signed response with a signed assertion", func(t *testing.T) {
+		r := valid()
+		r.SignResponse = true
+		_, err := p.ValidateResponse(idp.Response(t, r), "id-request", time.Now())
+		require.NoError(t, err)
+	})
+
+	for _, tc := range []struct {
+		d      string
+		modify func(r *TestResponse)
+	}{
+		{d: "unsigned", modify: func(r *TestResponse) { r.SignAssertion = false }},
+		{d: "other issuer", modify: func(r *TestResponse) { r.Issuer = "https://evil.example.com" }},
+		{d: "other destination", modify: func(r *TestResponse) { r.Destination = "https://evil.example.com/acs" }},
+		{d: "other audience", modify: func(r *TestResponse) { r.Audience = "https://evil.example.com" }},
+		{d: "other request", modify: func(r *TestResponse) { r.InResponseTo = "id-other" }},
+		{d: "transient name id", modify: func(r *TestResponse) { r.NameIDFormat = nameIDFormatTransient }},
+		{d: "expired assertion", modify: func(r *TestResponse) { r.IssuedAt = time.Now().Add(-time.Hour) }},
+		{d: "future assertion", modify: func(r *TestResponse) { r.IssuedAt = time.Now().Add(time.Hour) }},
+	} {
+		t.Run("case=rejects a response because of "+tc.d, func(t *testing.T) {
+			r := valid()
+			tc.modify(&r)
+			_, err := p.ValidateResponse(idp.Response(t, r), "id-request", time.Now())
+			require.Error(t, err)
+		})
+	}
+
+	t.Run("case=rejects a response signed by another identity provider", func(t *testing.T) {
+		other := NewTestIdentityProvider(t, idp.EntityID)
+		_, err := p.ValidateResponse(other.Response(t, valid()), "id-request", time.Now())
+		require.Error(t, err)
+	})
+
+	t.Run("case=rejects a modified response", func(t *testing.T) {
+		document, err := base64.StdEncoding.DecodeString(idp.Response(t, valid()))
+		require.NoError(t, err)
+		modified := strings.Replace(string(document), ">foo@example.com</saml:NameID>", ">admin@example.com</saml:NameID>", 1)
+		require.NotEqual(t, string(document), modified)
+
+		_, err = p.ValidateResponse(base64.StdEncoding.EncodeToString([]byte(modified)), "id-request", time.Now())
+		require.Error(t, err)
+	})
+
+	t.Run("case=rejects a signed assertion wrapped next to an unsigned one", func(t *testing.T) {
+		document, err := base64.StdEncoding.DecodeString(idp.Response(t, valid()))
+		require.NoError(t, err)
+
+		evil := valid()
+		evil.NameID = "admin@example.com"
+		evil.SignAssertion = false
+		evilDocument, err := base64.StdEncoding.DecodeString(idp.Response(t, evil))
+		require.NoError(t, err)
+		evilAssertion := string(evilDocument)[strings.Index(string(evilDocument), "<saml:Assertion"):strings.Index(string(evilDocument), "</samlp:Response>")]
+
+		wrapped := strings.Replace(string(document), "</samlp:Response>", evilAssertion+"</samlp:Response>", 1)
+		_, err = p.ValidateResponse(base64.StdEncoding.EncodeToString([]byte(wrapped)), "id-request", time.Now())
+		require.Error(t, err)
+	})
+	decode := func(t *testing.T, r TestResponse) string {
+		document, err := base64.StdEncoding.DecodeString(idp.Response(t, r))
+		require.NoError(t, err)
+		return string(document)
+	}
+
+	// wrap moves the signed Assertion of a valid response into the Extensions of a response with a forged,
+	// unsigned Assertion.
+	wrap := func(t *testing.T, forgedID string) string {
+		document := decode(t, valid())
+		signed := document[strings.Index(document, "<saml:Assertion") : strings.Index(document, "</saml:Assertion>")+len("</saml:Assertion>")]
+
+		forged := valid()
+		forged.NameID = "admin@example.com"
+		forged.SignAssertion = false
+		wrapped := strings.Replace(decode(t, forged), `<saml:Assertion ID="id-assertion"`, `<saml:Assertion ID="`+forgedID+`"`, 1)
+		wrapped = strings.Replace(wrapped, "</saml:Issuer>", "</saml:Issuer><samlp:Extensions>"+signed+"</samlp:Extensions>", 1)
+		require.Contains(t, wrapped, `<samlp:Extensions><saml:Assertion ID="id-assertion"`)
+		return base64.StdEncoding.EncodeToString([]byte(wrapped))
+	}
+
+	t.Run("case=rejects a signed assertion moved into an unsigned response", func(t *testing.T) {
+		_, err := p.ValidateResponse(wrap(t, "id-forged"), "id-request", time.Now())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "neither the SAML Response nor the Assertion are signed")
+	})
+
+	t.Run("case=rejects elements with duplicate IDs", func(t *testing.T) {
+		_, err := p.ValidateResponse(wrap(t, "id-assertion"), "id-request", time.Now())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "more than one element with the ID id-assertion")
+	})
+
+	t.Run("case=reads the whole NameID if a comment was injected", func(t *testing.T) {
+		r := valid()
+		r.NameID = "admin@example.com.evil.com"
+		document := decode(t, r)
+
+		// Comments are not signed, so the signature remains valid but the NameID must not be truncated.
+		injected := strings.Replace(document, ">admin@example.com.evil.com<", ">admin@example.com<!---->.evil.com<", 1)
+		require.NotEqual(t, document, injected)
+
+		claims, err := p.ValidateResponse(base64.StdEncoding.EncodeToString([]byte(injected)), "id-request", time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, "admin@example.com.evil.com", claims.Subject)
+	})
+}
@@ -0,0 +1,461 @@
+package saml
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/google/go-jsonnet"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/fetcher"
+	"github.com/ory/x/jsonx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteBase = "/self-service/methods/saml"
+
+	RouteAuth     = RouteBase + "/auth/:flow"
+	RouteACS      = RouteBase + "/acs"
+	RouteMetadata = RouteBase + "/metadata/:provider"
+
+	sessionName = "ory_kratos_saml_authn_request_session"
+)
+
+var _ identity.ActiveCredentialsCounter = new(Strategy)
+
+// The continuity cookie uses SameSite=Lax by default and is therefore not sent along with the Identity Provider's
+// cross-site POST request to the Assertion Consumer Service. The browser is asked to resubmit the SAML Response from
+// this origin instead, which includes the cookie.
+var resubmitPage = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+<body onload="document.forms[0].submit()">
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="SAMLResponse" value="{{.SAMLResponse}}">
+<input type="hidden" name="RelayState" value="{{.RelayState}}">
+<input type="hidden" name="resubmitted" value="true">
+<noscript><button type="submit">Continue</button></noscript>
+</form>
+</body>
+</html>`))
+
+type dependencies interface {
+	errorx.ManagementProvider
+
+	x.LoggingProvider
+	x.WriterProvider
+	x.CookieProvider
+	x.CSRFProvider
+	x.CSRFTokenGeneratorProvider
+	x.ClockProvider
+
+	identity.ValidationProvider
+	identity.PrivilegedPoolProvider
+
+	session.ManagementProvider
+	session.HandlerProvider
+
+	login.HookExecutorProvider
+	login.FlowPersistenceProvider
+	login.HandlerProvider
+	login.ErrorHandlerProvider
+
+	registration.HookExecutorProvider
+	registration.FlowPersistenceProvider
+	registration.HandlerProvider
+	registration.ErrorHandlerProvider
+
+	continuity.ManagementProvider
+}
+
+// Strategy implements selfservice.LoginStrategy, selfservice.RegistrationStrategy. It supports both login
+// and registration via SAML 2.0 Identity Providers.
+type Strategy struct {
+	c *config.Provider
+	d dependencies
+	f *fetcher.Fetcher
+}
+
+type authnRequestContainer struct {
+	FlowID    string `json:"flow_id"`
+	State     string `json:"state"`
+	Provider  string `json:"provider"`
+	RequestID string `json:"request_id"`
+}
+
+func NewStrategy(
+	d dependencies,
+	c *config.Provider,
+) *Strategy {
+	return &Strategy{
+		c: c,
+		d: d,
+		f: fetcher.NewFetcher(),
+	}
+}
+
+func (s *Strategy) ID() identity.CredentialsType {
+	return identity.CredentialsTypeSAML
+}
+
+func (s *Strategy) CountActiveCredentials(cc map[identity.CredentialsType]identity.Credentials) (count int, err error) {
+	for _, c := range cc {
+		if c.Type == s.ID() && gjson.ValidBytes(c.Config) {
+			var conf CredentialsConfig
+			if err = json.Unmarshal(c.Config, &conf); err != nil {
+				return 0, errors.WithStack(err)
+			}
+
+			for _, ider := range c.Identifiers {
+				for _, prov := range conf.Providers {
+					if ider == uid(prov.Provider, prov.Subject) && len(prov.Subject) > 0 && len(prov.Provider) > 0 {
+						count++
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+func (s *Strategy) setRoutes(r *x.RouterPublic) {
+	if handle, _, _ := r.Lookup("POST", RouteAuth); handle == nil {
+		r.POST(RouteAuth, s.handleAuth)
+	}
+
+	if handle, _, _ := r.Lookup("GET", RouteAuth); handle == nil {
+		r.GET(RouteAuth, s.handleAuth)
+	}
+
+	if handle, _, _ := r.Lookup("POST", RouteACS); handle == nil {
+		// The Identity Provider posts the SAML Response without an anti-CSRF token. The response is bound to the
+		// browser using the continuity cookie instead.
+		s.d.CSRFHandler().IgnorePath(RouteACS)
+		r.POST(RouteACS, s.handleACS)
+	}
+
+	if handle, _, _ := r.Lookup("GET", RouteMetadata); handle == nil {
+		r.GET(RouteMetadata, s.handleMetadata)
+	}
+}
+
+func (s *Strategy) handleAuth(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	rid := x.ParseUUID(ps.ByName("flow"))
+	if err := r.ParseForm(); err != nil {
+		s.handleError(w, r, rid, errors.WithStack(herodot.ErrBadRequest.WithDebug(err.Error()).WithReasonf("Unable to parse HTTP form request: %s", err.Error())))
+		return
+	}
+
+	var pid = r.Form.Get("provider") // this can come from both url query and post body
+	if pid == "" {
+		s.handleError(w, r, rid, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The HTTP request did not contain the required "provider" form field`)))
+		return
+	}
+
+	provider, err := s.provider(pid)
+	if err != nil {
+		s.handleError(w, r, rid, err)
+		return
+	}
+
+	req, err := s.validateFlow(r.Context(), rid)
+	if err != nil {
+		s.handleError(w, r, rid, err)
+		return
+	}
+
+	if s.alreadyAuthenticated(w, r, req) {
+		return
+	}
+
+	state := x.NewUUID().String()
+	location, requestID, err := provider.AuthnRequestURL(state, time.Now())
+	if err != nil {
+		s.handleError(w, r, rid, err)
+		return
+	}
+
+	if err := s.d.ContinuityManager().Pause(r.Context(), w, r, sessionName,
+		continuity.WithPayload(&authnRequestContainer{
+			FlowID:    req.GetID().String(),
+			State:     state,
+			Provider:  pid,
+			RequestID: requestID,
+		}),
+		continuity.WithLifespan(time.Minute*30)); err != nil {
+		s.handleError(w, r, rid, err)
+		return
+	}
+
+	http.Redirect(w, r, location, http.StatusFound)
+}
+
+func (s *Strategy) validateFlow(ctx context.Context, rid uuid.UUID) (ider, error) {
+	if x.IsZeroUUID(rid) {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The session cookie contains invalid values and the flow could not be executed. Please try again."))
+	}
+
+	if ar, err := s.d.RegistrationFlowPersister().GetRegistrationFlow(ctx, rid); err == nil {
+		if ar.Type != flow.TypeBrowser {
+			return ar, ErrAPIFlowNotSupported
+		}
+
+		if err := ar.Valid(s.d.Clock().Now(), s.c.SelfServiceFlowExpiredGracePeriod()); err != nil {
+			return ar, err
+		}
+		return ar, nil
+	}
+
+	ar, err := s.d.LoginFlowPersister().GetLoginFlow(ctx, rid)
+	if err != nil {
+		return nil, err
+	}
+
+	if ar.Type != flow.TypeBrowser {
+		return ar, ErrAPIFlowNotSupported
+	}
+
+	if err := ar.Valid(s.d.Clock().Now(), s.c.SelfServiceFlowExpiredGracePeriod()); err != nil {
+		return ar, err
+	}
+	return ar, nil
+}
+
+func (s *Strategy) alreadyAuthenticated(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	// we assume an error means the user has no session
+	if _, err := s.d.SessionManager().FetchFromRequest(r.Context(), r); err == nil {
+		if f, ok := req.(*login.Flow); !ok || !f.IsForced() {
+			http.Redirect(w, r, s.c.SelfServiceBrowserDefaultReturnTo().String(), http.StatusFound)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Strategy) handleACS(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if err := r.ParseForm(); err != nil {
+		s.handleError(w, r, x.EmptyUUID, errors.WithStack(herodot.ErrBadRequest.WithDebug(err.Error()).WithReasonf("Unable to parse HTTP form request: %s", err.Error())))
+		return
+	}
+
+	if r.PostForm.Get("resubmitted") != "true" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := resubmitPage.Execute(w, map[string]string{
+			"Action":       urlx.AppendPaths(urlx.Copy(s.c.SelfPublicURL()), RouteACS).String(),
+			"SAMLResponse": r.PostForm.Get("SAMLResponse"),
+			"RelayState":   r.PostForm.Get("RelayState"),
+		}); err != nil {
+			s.d.Logger().WithError(err).Error("Unable to render the SAML Response resubmission page.")
+		}
+		return
+	}
+
+	var container authnRequestContainer
+	if _, err := s.d.ContinuityManager().Continue(r.Context(), w, r, sessionName, continuity.WithPayload(&container)); err != nil {
+		s.handleError(w, r, x.EmptyUUID, err)
+		return
+	}
+
+	if r.PostForm.Get("RelayState") != container.State {
+		s.handleError(w, r, x.EmptyUUID, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Unable to complete the SAML flow because the RelayState does not match the state from the session cookie.`)))
+		return
+	}
+
+	req, err := s.validateFlow(r.Context(), x.ParseUUID(container.FlowID))
+	if err != nil {
+		s.handleError(w, r, x.ParseUUID(container.FlowID), err)
+		return
+	}
+
+	if s.alreadyAuthenticated(w, r, req) {
+		return
+	}
+
+	provider, err := s.provider(container.Provider)
+	if err != nil {
+		s.handleError(w, r, req.GetID(), err)
+		return
+	}
+
+	claims, err := provider.ValidateResponse(r.PostForm.Get("SAMLResponse"), container.RequestID, time.Now())
+	if err != nil {
+		s.d.Logger().WithRequest(r).WithError(err).WithField("provider", container.Provider).Info("Received an invalid SAML Response.")
+		s.handleError(w, r, req.GetID(), errors.WithStack(ErrInvalidResponse.WithDebug(err.Error())))
+		return
+	}
+
+	switch a := req.(type) {
+	case *login.Flow:
+		s.processLogin(w, r, a, claims, provider)
+		return
+	case *registration.Flow:
+		s.processRegistration(w, r, a, claims, provider)
+		return
+	default:
+		s.handleError(w, r, req.GetID(), errors.WithStack(x.PseudoPanic.
+			WithDetailf("cause", "Unexpected type in SAML flow: %T", a)))
+		return
+	}
+}
+
+// swagger:parameters getSelfServiceSAMLServiceProviderMetadata
+// nolint:deadcode,unused
+type getSelfServiceSAMLServiceProviderMetadataParameters struct {
+	// The ID of the SAML Identity Provider
+	//
+	// required: true
+	// in: path
+	Provider string `json:"provider"`
+}
+
+// swagger:route GET /self-service/methods/saml/metadata/{provider} public getSelfServiceSAMLServiceProviderMetadata
+//
+// Get SAML 2.0 Service Provider Metadata
+//
+// This endpoint returns the SAML 2.0 Service Provider Metadata of ORY Kratos for the given Identity Provider.
+// It is imported by the Identity Provider to trust ORY Kratos' signed AuthnRequests and to learn the
+// Assertion Consumer Service URL.
+//
+//     Produces:
+//     - application/samlmetadata+xml
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: emptyResponse
+//       404: genericError
+//       500: genericError
+func (s *Strategy) handleMetadata(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	provider, err := s.provider(ps.ByName("provider"))
+	if err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	metadata, err := provider.Metadata()
+	if err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	_, _ = w.Write(metadata)
+}
+
+func (s *Strategy) authURL(flowID uuid.UUID) string {
+	return urlx.AppendPaths(
+		urlx.Copy(s.c.SelfPublicURL()),
+		strings.Replace(
+			RouteAuth, ":flow", flowID.String(), 1,
+		),
+	).String()
+}
+
+func (s *Strategy) populateMethod(r *http.Request, flowID uuid.UUID) (*FlowMethod, error) {
+	conf, err := s.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	f := form.NewHTMLForm(s.authURL(flowID))
+	f.SetCSRF(s.d.GenerateCSRFToken(r))
+	// does not need sorting because there is only one field
+
+	return NewFlowMethod(f).AddProviders(conf.Providers), nil
+}
+
+func (s *Strategy) Config() (*ConfigurationCollection, error) {
+	var c ConfigurationCollection
+
+	config := s.c.SelfServiceStrategy(string(s.ID())).Config
+	if err := jsonx.
+		NewStrictDecoder(bytes.NewBuffer(config)).
+		Decode(&c); err != nil {
+		s.d.Logger().WithError(err).WithField("config", config)
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode SAML Identity Provider configuration: %s", err))
+	}
+
+	return &c, nil
+}
+
+func (s *Strategy) provider(id string) (*Provider, error) {
+	c, err := s.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	conf, err := c.configuration(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewProvider(conf, s.c.SelfPublicURL(), s.f)
+}
+
+func (s *Strategy) handleError(w http.ResponseWriter, r *http.Request, rid uuid.UUID, err error) {
+	if x.IsZeroUUID(rid) {
+		s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+		return
+	}
+
+	if lr, rerr := s.d.LoginFlowPersister().GetLoginFlow(r.Context(), rid); rerr == nil {
+		s.d.LoginFlowErrorHandler().WriteFlowError(w, r, s.ID(), lr, err)
+		return
+	} else if rr, rerr := s.d.RegistrationFlowPersister().GetRegistrationFlow(r.Context(), rid); rerr == nil {
+		s.d.RegistrationFlowErrorHandler().WriteFlowError(w, r, s.ID(), rr, err)
+		return
+	}
+
+	s.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+}
+
+// evaluateMapper runs the provider's Jsonnet mapper with the claims of the Assertion and returns its output.
+func (s *Strategy) evaluateMapper(r *http.Request, claims *Claims, provider *Provider) (string, error) {
+	jn, err := s.f.Fetch(provider.Config().Mapper)
+	if err != nil {
+		return "", err
+	}
+
+	var jsonClaims bytes.Buffer
+	if err := json.NewEncoder(&jsonClaims).Encode(claims); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	vm := jsonnet.MakeVM()
+	vm.ExtCode("claims", jsonClaims.String())
+	evaluated, err := vm.EvaluateSnippet(provider.Config().Mapper, jn.String())
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	s.d.Logger().
+		WithRequest(r).
+		WithField("saml_provider", provider.Config().ID).
+		WithSensitiveField("saml_claims", claims).
+		WithField("mapper_jsonnet_output", evaluated).
+		WithField("mapper_jsonnet_url", provider.Config().Mapper).
+		Debug("SAML Jsonnet mapper completed.")
+	return evaluated, nil
+}
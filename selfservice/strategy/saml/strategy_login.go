@@ -0,0 +1,78 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/x"
+)
+
+var _ login.Strategy = new(Strategy)
+
+func (s *Strategy) RegisterLoginRoutes(r *x.RouterPublic) {
+	s.setRoutes(r)
+}
+
+func (s *Strategy) PopulateLoginMethod(r *http.Request, sr *login.Flow) error {
+	if sr.Type != flow.TypeBrowser {
+		// SAML relies on browser redirects and is not offered to API flows.
+		return nil
+	}
+
+	config, err := s.populateMethod(r, sr.ID)
+	if err != nil {
+		return err
+	}
+	sr.Methods[s.ID()] = &login.FlowMethod{Method: s.ID(),
+		Config: &login.FlowMethodConfig{FlowMethodConfigurator: config}}
+	return nil
+}
+
+func (s *Strategy) processLogin(w http.ResponseWriter, r *http.Request, a *login.Flow, claims *Claims, provider *Provider) {
+	i, c, err := s.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(r.Context(), identity.CredentialsTypeSAML, uid(provider.Config().ID, claims.Subject))
+	if err != nil {
+		if errors.Is(err, herodot.ErrNotFound) {
+			// If no account was found we're "manually" creating a new registration flow and continue with it,
+			// just like the OpenID Connect strategy does.
+			s.d.Logger().WithField("provider", provider.Config().ID).WithField("subject", claims.Subject).Debug("Received a valid SAML Response but user is not registered. Re-initializing registration flow now.")
+
+			aa, err := s.d.RegistrationHandler().NewRegistrationFlow(w, r, flow.TypeBrowser)
+			if err != nil {
+				s.handleError(w, r, a.GetID(), err)
+				return
+			}
+
+			s.processRegistration(w, r, aa, claims, provider)
+			return
+		}
+
+		s.handleError(w, r, a.GetID(), err)
+		return
+	}
+
+	var o CredentialsConfig
+	if err := json.NewDecoder(bytes.NewBuffer(c.Config)).Decode(&o); err != nil {
+		s.handleError(w, r, a.GetID(), errors.WithStack(herodot.ErrInternalServerError.WithReason("The SAML credentials could not be decoded properly").WithDebug(err.Error())))
+		return
+	}
+
+	for _, c := range o.Providers {
+		if c.Subject == claims.Subject && c.Provider == provider.Config().ID {
+			if err = s.d.LoginHookExecutor().PostLoginHook(w, r, identity.CredentialsTypeSAML, a, i); err != nil {
+				s.handleError(w, r, a.GetID(), err)
+				return
+			}
+			return
+		}
+	}
+
+	s.handleError(w, r, a.GetID(), errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to find matching SAML Credentials.").WithDebugf(`Unable to find credentials that match the given provider "%s" and subject "%s".`, provider.Config().ID, claims.Subject)))
+}
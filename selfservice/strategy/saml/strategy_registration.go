@@ -0,0 +1,95 @@
+package saml
+
+import (
+	"net/http"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/x"
+)
+
+var _ registration.Strategy = new(Strategy)
+
+func (s *Strategy) RegisterRegistrationRoutes(r *x.RouterPublic) {
+	s.setRoutes(r)
+}
+
+func (s *Strategy) PopulateRegistrationMethod(r *http.Request, sr *registration.Flow) error {
+	if sr.Type != flow.TypeBrowser {
+		// SAML relies on browser redirects and is not offered to API flows.
+		return nil
+	}
+
+	config, err := s.populateMethod(r, sr.ID)
+	if err != nil {
+		return err
+	}
+	sr.Methods[s.ID()] = &registration.FlowMethod{
+		Method: s.ID(),
+		Config: &registration.FlowMethodConfig{FlowMethodConfigurator: config},
+	}
+	return nil
+}
+
+func (s *Strategy) processRegistration(w http.ResponseWriter, r *http.Request, a *registration.Flow, claims *Claims, provider *Provider) {
+	if _, _, err := s.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(r.Context(), identity.CredentialsTypeSAML, uid(provider.Config().ID, claims.Subject)); err == nil {
+		// If the identity already exists, we perform the login flow instead.
+		s.d.Logger().WithRequest(r).WithField("provider", provider.Config().ID).
+			WithField("subject", claims.Subject).
+			Debug("Received a valid SAML Response but user is already registered. Re-initializing login flow now.")
+
+		ar, err := s.d.LoginHandler().NewLoginFlow(w, r, flow.TypeBrowser)
+		if err != nil {
+			s.handleError(w, r, a.GetID(), err)
+			return
+		}
+
+		s.processLogin(w, r, ar, claims, provider)
+		return
+	}
+
+	evaluated, err := s.evaluateMapper(r, claims, provider)
+	if err != nil {
+		s.handleError(w, r, a.GetID(), err)
+		return
+	}
+
+	i := identity.NewIdentity(a.IdentitySchemaID)
+	if traits := gjson.Get(evaluated, "identity.traits"); !traits.IsObject() {
+		i.Traits = []byte{'{', '}'}
+		s.d.Logger().
+			WithRequest(r).
+			WithField("saml_provider", provider.Config().ID).
+			WithSensitiveField("saml_claims", claims).
+			WithField("mapper_jsonnet_output", evaluated).
+			WithField("mapper_jsonnet_url", provider.Config().Mapper).
+			Error("SAML Jsonnet mapper did not return an object for key identity.traits. Please check your Jsonnet code!")
+	} else {
+		i.Traits = []byte(traits.Raw)
+	}
+
+	if metadata := gjson.Get(evaluated, "identity.metadata_public"); metadata.IsObject() {
+		i.MetadataPublic = identity.Metadata(metadata.Raw)
+	}
+
+	// Validate the identity itself
+	if err := s.d.IdentityValidator().Validate(i); err != nil {
+		s.handleError(w, r, a.GetID(), err)
+		return
+	}
+
+	creds, err := NewCredentials(provider.Config().ID, claims.Subject)
+	if err != nil {
+		s.handleError(w, r, a.GetID(), err)
+		return
+	}
+
+	i.SetCredentials(s.ID(), *creds)
+	if err := s.d.RegistrationExecutor().PostRegistrationHook(w, r, identity.CredentialsTypeSAML, a, i); err != nil {
+		s.handleError(w, r, a.GetID(), err)
+		return
+	}
+}
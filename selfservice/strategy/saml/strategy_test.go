@@ -0,0 +1,163 @@
+package saml_test
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/ioutilx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/strategy/saml"
+	"github.com/ory/kratos/x"
+)
+
+func TestStrategy(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	idp := saml.NewTestIdentityProvider(t, "https://idp.example.com")
+	conf.MustSet(config.ViperKeySelfServiceStrategyConfig+"."+string(identity.CredentialsTypeSAML),
+		map[string]interface{}{"enabled": true, "config": &saml.ConfigurationCollection{
+			Providers: []saml.Configuration{idp.Configuration(t, "idp", "file://./stub/saml.jsonnet")}}})
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/registration.schema.json")
+	conf.MustSet(config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter,
+		identity.CredentialsTypeSAML.String()), []config.SelfServiceHook{{Name: "session"}})
+
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+	_ = testhelpers.NewRegistrationUIFlowEchoServer(t, reg)
+	_ = testhelpers.NewLoginUIFlowEchoServer(t, reg)
+	returnTS := testhelpers.NewRedirSessionEchoTS(t, reg)
+	errTS := testhelpers.NewErrorTestServer(t, reg)
+
+	noRedirects := func(c *http.Client) *http.Client {
+		cc := *c
+		cc.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		return &cc
+	}
+
+	// initFlow initializes a browser flow and returns the action of the SAML method.
+	initFlow := func(t *testing.T, client *http.Client, route string) string {
+		res, err := client.Get(publicTS.URL + route)
+		require.NoError(t, err)
+		body := ioutilx.MustReadAll(res.Body)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+
+		action := gjson.GetBytes(body, "methods.saml.config.action").String()
+		require.NotEmpty(t, action, "%s", body)
+		assert.Equal(t, "idp", gjson.GetBytes(body, `methods.saml.config.fields.#(name=="provider").value`).String(), "%s", body)
+		return action
+	}
+
+	// authenticate starts the flow at the Identity Provider and returns the ID of the AuthnRequest and the RelayState.
+	authenticate := func(t *testing.T, client *http.Client, action string) (string, string) {
+		res, err := noRedirects(client).PostForm(action, url.Values{"provider": {"idp"}})
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusFound, res.StatusCode)
+
+		location, err := url.Parse(res.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, idp.SSOURL, location.Scheme+"://"+location.Host+location.Path)
+
+		request, err := saml.InflateAuthnRequest(location.Query().Get("SAMLRequest"))
+		require.NoError(t, err)
+		id := regexp.MustCompile(` ID="([^"]+)"`).FindStringSubmatch(request)
+		require.Len(t, id, 2, "%s", request)
+
+		return id[1], location.Query().Get("RelayState")
+	}
+
+	response := func(t *testing.T, requestID, subject string) string {
+		return idp.Response(t, saml.TestResponse{
+			Destination:   publicTS.URL + saml.RouteACS,
+			Audience:      publicTS.URL + strings.Replace(saml.RouteMetadata, ":provider", "idp", 1),
+			InResponseTo:  requestID,
+			NameID:        subject,
+			Email:         subject,
+			SignAssertion: true,
+		})
+	}
+
+	// post posts the SAML Response to the Assertion Consumer Service like the browser would after resubmitting it.
+	post := func(t *testing.T, client *http.Client, samlResponse, relayState string) (*http.Response, []byte) {
+		res, err := client.PostForm(publicTS.URL+saml.RouteACS, url.Values{"SAMLResponse": {samlResponse}, "RelayState": {relayState}})
+		require.NoError(t, err)
+		body := ioutilx.MustReadAll(res.Body)
+		require.NoError(t, res.Body.Close())
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, string(body), `action="`+publicTS.URL+saml.RouteACS+`"`)
+		assert.Contains(t, string(body), `name="resubmitted" value="true"`)
+
+		res, err = client.PostForm(publicTS.URL+saml.RouteACS, url.Values{"SAMLResponse": {samlResponse}, "RelayState": {relayState}, "resubmitted": {"true"}})
+		require.NoError(t, err)
+		body = ioutilx.MustReadAll(res.Body)
+		require.NoError(t, res.Body.Close())
+		return res, body
+	}
+
+	subject := x.NewUUID().String() + "@ory.sh"
+
+	t.Run("case=should register the identity using a registration flow", func(t *testing.T) {
+		client := testhelpers.NewClientWithCookies(t)
+		id, state := authenticate(t, client, initFlow(t, client, registration.RouteInitBrowserFlow))
+
+		res, body := post(t, client, response(t, id, subject), state)
+		assert.Contains(t, res.Request.URL.String(), returnTS.URL, "%s", body)
+		assert.Equal(t, subject, gjson.GetBytes(body, "identity.traits.subject").String(), "%s", body)
+		assert.Equal(t, subject, gjson.GetBytes(body, "identity.traits.email").String(), "%s", body)
+
+		_, _, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(res.Request.Context(), identity.CredentialsTypeSAML, "idp:"+subject)
+		require.NoError(t, err)
+	})
+
+	t.Run("case=should sign in the registered identity using a login flow and reject replays", func(t *testing.T) {
+		client := testhelpers.NewClientWithCookies(t)
+		id, state := authenticate(t, client, initFlow(t, client, login.RouteInitBrowserFlow))
+
+		samlResponse := response(t, id, subject)
+		res, body := post(t, client, samlResponse, state)
+		assert.Contains(t, res.Request.URL.String(), returnTS.URL, "%s", body)
+		assert.Equal(t, subject, gjson.GetBytes(body, "identity.traits.subject").String(), "%s", body)
+
+		res, body = post(t, testhelpers.NewClientWithCookies(t), samlResponse, state)
+		assert.Contains(t, res.Request.URL.String(), errTS.URL, "%s", body)
+	})
+
+	t.Run("case=should reject a response for another authn request", func(t *testing.T) {
+		client := testhelpers.NewClientWithCookies(t)
+		_, state := authenticate(t, client, initFlow(t, client, login.RouteInitBrowserFlow))
+
+		res, body := post(t, client, response(t, "id-other", subject), state)
+		assert.NotContains(t, res.Request.URL.String(), returnTS.URL, "%s", body)
+		assert.Contains(t, gjson.GetBytes(body, "methods.saml.config.messages.0.text").String(), "SAML Response", "%s", body)
+	})
+
+	t.Run("case=should serve the service provider metadata", func(t *testing.T) {
+		res, err := http.Get(publicTS.URL + strings.Replace(saml.RouteMetadata, ":provider", "idp", 1))
+		require.NoError(t, err)
+		body := ioutilx.MustReadAll(res.Body)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/samlmetadata+xml", res.Header.Get("Content-Type"))
+		assert.Contains(t, string(body), publicTS.URL+saml.RouteACS)
+
+		res, err = http.Get(publicTS.URL + strings.Replace(saml.RouteMetadata, ":provider", "unknown", 1))
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
@@ -0,0 +1,78 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/form"
+	"github.com/ory/kratos/x"
+)
+
+type CredentialsConfig struct {
+	Providers []ProviderCredentialsConfig `json:"providers"`
+}
+
+func NewCredentials(provider, subject string) (*identity.Credentials, error) {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(CredentialsConfig{
+		Providers: []ProviderCredentialsConfig{{Subject: subject, Provider: provider}},
+	}); err != nil {
+		return nil, errors.WithStack(x.PseudoPanic.
+			WithDebugf("Unable to encode SAML credentials to JSON: %s", err))
+	}
+
+	return &identity.Credentials{
+		Type:        identity.CredentialsTypeSAML,
+		Identifiers: []string{uid(provider, subject)},
+		Config:      b.Bytes(),
+	}, nil
+}
+
+type ProviderCredentialsConfig struct {
+	Subject  string `json:"subject"`
+	Provider string `json:"provider"`
+}
+
+func uid(provider, subject string) string {
+	return fmt.Sprintf("%s:%s", provider, subject)
+}
+
+type FlowMethod struct {
+	*form.HTMLForm
+}
+
+// AddProviders adds a submit button for each provider. The buttons are sorted by the order of the providers'
+// user interface hints and otherwise keep the order of the configuration.
+func (r *FlowMethod) AddProviders(providers []Configuration) *FlowMethod {
+	order := func(p Configuration) int {
+		if p.UI == nil {
+			return 0
+		}
+		return p.UI.Order
+	}
+
+	sorted := make([]Configuration, len(providers))
+	copy(sorted, providers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return order(sorted[i]) < order(sorted[j])
+	})
+
+	for _, p := range sorted {
+		r.Fields = append(r.Fields, form.Field{Name: "provider", Type: "submit", Value: p.ID, UI: p.UI})
+	}
+	return r
+}
+
+func NewFlowMethod(f *form.HTMLForm) *FlowMethod {
+	return &FlowMethod{HTMLForm: f}
+}
+
+type ider interface {
+	GetID() uuid.UUID
+}
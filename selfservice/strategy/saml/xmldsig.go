@@ -0,0 +1,154 @@
+package saml
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/pkg/errors"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+)
+
+// Signatures are verified by goxmldsig (https://github.com/russellhaering/goxmldsig). This file only contains the
+// glue between the etree DOM and the SAML specific checks which protect against signature wrapping attacks.
+
+const namespaceXMLDSig = "http://www.w3.org/2000/09/xmldsig#"
+
+var errSignatureMissing = dsig.ErrMissingSignature
+
+// parseXML parses a document and returns its root element. Documents with a document type definition, more than
+// one root element or more than one element with the same ID are rejected.
+func parseXML(document []byte) (*etree.Element, error) {
+	// etree does not check that end elements match their start elements.
+	d := xml.NewDecoder(bytes.NewReader(document))
+	for {
+		if _, err := d.Token(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(document); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var root *etree.Element
+	for _, token := range doc.Child {
+		switch t := token.(type) {
+		case *etree.Directive:
+			return nil, errors.New("the document must not contain a document type definition")
+		case *etree.Element:
+			if root != nil {
+				return nil, errors.New("the document has more than one root element")
+			}
+			root = t
+		}
+	}
+	if root == nil {
+		return nil, errors.New("the document is incomplete")
+	}
+
+	// References are resolved by ID. Unique IDs make sure that a signature can not be attributed to another
+	// element than the one which was signed.
+	ids := map[string]bool{}
+	for _, e := range append([]*etree.Element{root}, root.FindElements(".//*")...) {
+		if id := e.SelectAttrValue("ID", ""); id != "" {
+			if ids[id] {
+				return nil, errors.Errorf("the document contains more than one element with the ID %s", id)
+			}
+			ids[id] = true
+		}
+	}
+
+	return root, nil
+}
+
+func is(e *etree.Element, namespace, tag string) bool {
+	return e.Tag == tag && e.NamespaceURI() == namespace
+}
+
+// elements returns the child elements with the given namespace and tag.
+func elements(e *etree.Element, namespace, tag string) (found []*etree.Element) {
+	for _, c := range e.ChildElements() {
+		if is(c, namespace, tag) {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// element returns the first child element with the given namespace and tag or nil.
+func element(e *etree.Element, namespace, tag string) *etree.Element {
+	if found := elements(e, namespace, tag); len(found) > 0 {
+		return found[0]
+	}
+	return nil
+}
+
+func decodeBase64Value(e *etree.Element) ([]byte, error) {
+	if e == nil {
+		return nil, errors.New("the value is missing")
+	}
+
+	b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(e.Text()), ""))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return b, nil
+}
+
+// verifySignature verifies the enveloped signature of the element using one of the certificates and returns the
+// signed element without its signature. Only the returned element may be trusted. It returns errSignatureMissing
+// if the element is not signed at all.
+func verifySignature(e *etree.Element, certificates []*x509.Certificate, now time.Time) (*etree.Element, error) {
+	if e.SelectAttrValue("ID", "") == "" {
+		return nil, errors.New("the signed element has no ID")
+	}
+
+	// The element is detached from its parent, keeping the namespace declarations which are in scope.
+	ctx, err := etreeutils.NSBuildParentContext(e)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	detached, err := etreeutils.NSDetatch(ctx, e)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	err = errors.New("the metadata contains no certificate")
+	for _, certificate := range certificates {
+		// goxmldsig requires a KeyInfo if it trusts more than one certificate, which not all Identity Providers
+		// include. Each certificate is therefore tried on its own.
+		v := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: []*x509.Certificate{certificate}})
+		v.Clock = dsig.NewFakeClockAt(now)
+
+		var verified *etree.Element
+		verified, err = v.Validate(detached)
+		if err == nil {
+			return verified, nil
+		} else if errors.Is(err, dsig.ErrMissingSignature) {
+			return nil, errSignatureMissing
+		}
+	}
+
+	return nil, errors.Wrap(err, "the signature was not created by a trusted certificate")
+}
+
+// unmarshalElement decodes the element, which must contain all namespace declarations it uses, with encoding/xml.
+func unmarshalElement(e *etree.Element, v interface{}) error {
+	doc := etree.NewDocument()
+	doc.SetRoot(e.Copy())
+	b, err := doc.WriteToBytes()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(xml.Unmarshal(b, v))
+}
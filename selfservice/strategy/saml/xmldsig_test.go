@@ -0,0 +1,96 @@
+package saml
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseXML(t *testing.T) {
+	for _, tc := range []string{
+		`<!DOCTYPE r [<!ENTITY e "e">]><r>&e;</r>`,
+		`<r><a></b></r>`,
+		`<r></r><r></r>`,
+		`<r>`,
+		``,
+		`<r ID="a"><c ID="a"/></r>`,
+	} {
+		t.Run("input="+tc, func(t *testing.T) {
+			_, err := parseXML([]byte(tc))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	idp := NewTestIdentityProvider(t, "https://idp.example.com")
+	other := NewTestIdentityProvider(t, "https://idp.example.com")
+
+	parse := func(t *testing.T, signer *TestIdentityProvider) *etree.Element {
+		e, err := parseXML([]byte(`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:xs="urn:xs" ID="id-1"><saml:Issuer>https://idp.example.com</saml:Issuer><saml:Subject>foo</saml:Subject></saml:Assertion>`))
+		require.NoError(t, err)
+		if signer != nil {
+			signer.sign(t, e)
+		}
+
+		// Serialize and parse the element again like a SAML Response sent over the wire.
+		doc := etree.NewDocument()
+		doc.SetRoot(e)
+		b, err := doc.WriteToBytes()
+		require.NoError(t, err)
+		e, err = parseXML(b)
+		require.NoError(t, err)
+		return e
+	}
+
+	t.Run("case=verifies a valid signature", func(t *testing.T) {
+		verified, err := verifySignature(parse(t, idp), idp.metadataCertificates(t), time.Now())
+		require.NoError(t, err)
+		assert.Nil(t, element(verified, namespaceXMLDSig, "Signature"), "the signature is removed from the verified element")
+		assert.Equal(t, "foo", element(verified, namespaceAssertion, "Subject").Text())
+	})
+
+	t.Run("case=reports a missing signature", func(t *testing.T) {
+		_, err := verifySignature(parse(t, nil), idp.metadataCertificates(t), time.Now())
+		assert.Equal(t, errSignatureMissing, err)
+	})
+
+	t.Run("case=rejects signatures of untrusted keys", func(t *testing.T) {
+		_, err := verifySignature(parse(t, other), idp.metadataCertificates(t), time.Now())
+		require.Error(t, err)
+	})
+
+	t.Run("case=accepts one of several trusted keys", func(t *testing.T) {
+		_, err := verifySignature(parse(t, idp), append(other.metadataCertificates(t), idp.metadataCertificates(t)...), time.Now())
+		require.NoError(t, err)
+	})
+
+	t.Run("case=rejects signatures of expired certificates", func(t *testing.T) {
+		_, err := verifySignature(parse(t, idp), idp.metadataCertificates(t), time.Now().Add(time.Hour*2))
+		require.Error(t, err)
+	})
+
+	t.Run("case=rejects modified elements", func(t *testing.T) {
+		e := parse(t, idp)
+		element(e, namespaceAssertion, "Subject").SetText("bar")
+		_, err := verifySignature(e, idp.metadataCertificates(t), time.Now())
+		require.Error(t, err)
+	})
+
+	t.Run("case=rejects signatures referencing another element", func(t *testing.T) {
+		e := parse(t, idp)
+		e.CreateAttr("ID", "id-2")
+		_, err := verifySignature(e, idp.metadataCertificates(t), time.Now())
+		require.Error(t, err)
+	})
+}
+
+func (p *TestIdentityProvider) metadataCertificates(t *testing.T) []*x509.Certificate {
+	m, err := parseIdentityProviderMetadata(p.Metadata())
+	require.NoError(t, err)
+	return m.certificates
+}
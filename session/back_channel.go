@@ -0,0 +1,223 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+// HeaderBackChannelSignature is the HTTP header carrying the signature of a back-channel logout notification. It has
+// the form `t=<unix timestamp>,v1=<hex encoded HMAC-SHA256 of "<timestamp>.<body>">`.
+const HeaderBackChannelSignature = "X-Kratos-Signature"
+
+const EventSessionRevoked = "session.revoked"
+
+const (
+	RevocationReasonLogout              = "logout"
+	RevocationReasonLogoutEverywhere    = "logout_everywhere"
+	RevocationReasonRevoked             = "revoked"
+	RevocationReasonAdminRevoked        = "admin_revoked"
+	RevocationReasonIdentityDeactivated = "identity_deactivated"
+	RevocationReasonIdentityExpired     = "identity_expired"
+	RevocationReasonIdentityDeleted     = identity.SessionRevocationReasonIdentityDeleted
+	RevocationReasonSessionExpired      = "session_expired"
+)
+
+// maxBackChannelRetryWait caps the exponential backoff between delivery attempts.
+const maxBackChannelRetryWait = time.Minute
+
+// BackChannelEvent is sent to the back-channel logout URLs when sessions are revoked.
+type BackChannelEvent struct {
+	// ID identifies the event. It is the same for all delivery attempts, which allows receivers to ignore
+	// duplicates.
+	ID uuid.UUID `json:"id"`
+
+	// Type is always `session.revoked`.
+	Type string `json:"type"`
+
+	// Reason is one of `logout`, `logout_everywhere`, `revoked`, `admin_revoked`, `identity_deactivated`,
+	// `identity_expired`, and `session_expired`.
+	Reason string `json:"reason"`
+
+	// Issuer is the public URL of ORY Kratos.
+	Issuer string `json:"iss"`
+
+	IdentityID uuid.UUID `json:"identity_id"`
+
+	// SessionID is the ID of the revoked session. It is omitted if all sessions of the identity were revoked.
+	SessionID *uuid.UUID `json:"session_id,omitempty"`
+
+	Time time.Time `json:"time"`
+}
+
+type (
+	backChannelDependencies interface {
+		x.LoggingProvider
+		x.ClockProvider
+		PersistenceProvider
+	}
+	BackChannelNotifierProvider interface {
+		SessionBackChannelNotifier() *BackChannelNotifier
+	}
+
+	// BackChannelNotifier notifies the back-channel logout URLs of applications, for example to clear their own
+	// sessions or caches, when sessions are revoked. Notifications are delivered in the background and retried with
+	// exponential backoff if the URL can not be reached or responds with a server error.
+	BackChannelNotifier struct {
+		d      backChannelDependencies
+		c      *config.Provider
+		client *http.Client
+	}
+)
+
+func NewBackChannelNotifier(d backChannelDependencies, c *config.Provider) *BackChannelNotifier {
+	return &BackChannelNotifier{d: d, c: c, client: new(http.Client)}
+}
+
+var _ identity.SessionRevocationNotifier = new(BackChannelNotifier)
+
+// SessionRevoked notifies the back-channel logout URLs that the session was revoked.
+func (n *BackChannelNotifier) SessionRevoked(s *Session, reason string) {
+	sid := s.ID
+	n.notify(s.IdentityID, &sid, reason)
+}
+
+// IdentitySessionsRevoked notifies the back-channel logout URLs that all sessions of the identity were revoked.
+func (n *BackChannelNotifier) IdentitySessionsRevoked(identityID uuid.UUID, reason string) {
+	n.notify(identityID, nil, reason)
+}
+
+// RevokeSessionByToken revokes the session and notifies the back-channel logout URLs if it was active. Unknown
+// tokens are ignored.
+func (n *BackChannelNotifier) RevokeSessionByToken(ctx context.Context, token, reason string) error {
+	s, err := n.d.SessionPersister().GetSessionByToken(ctx, token)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := n.d.SessionPersister().RevokeSessionByToken(ctx, token); err != nil {
+		return err
+	}
+
+	if s.Active {
+		n.SessionRevoked(s, reason)
+	}
+	return nil
+}
+
+func (n *BackChannelNotifier) notify(identityID uuid.UUID, sid *uuid.UUID, reason string) {
+	urls := n.c.SelfServiceFlowLogoutBackChannelURLs()
+	if len(urls) == 0 {
+		return
+	}
+
+	e := &BackChannelEvent{
+		ID:         x.NewUUID(),
+		Type:       EventSessionRevoked,
+		Reason:     reason,
+		Issuer:     n.c.SelfPublicURL().String(),
+		IdentityID: identityID,
+		SessionID:  sid,
+		Time:       n.d.Clock().Now(),
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		n.d.Logger().WithError(err).Error("Unable to encode the back-channel logout notification.")
+		return
+	}
+
+	// The notifications outlive the request which revoked the sessions.
+	for _, u := range urls {
+		go n.deliver(context.Background(), u, e, body)
+	}
+}
+
+func (n *BackChannelNotifier) deliver(ctx context.Context, u config.SelfServiceLogoutBackChannelURL, e *BackChannelEvent, body []byte) {
+	retries := n.c.SelfServiceFlowLogoutBackChannelMaxRetries()
+	wait := n.c.SelfServiceFlowLogoutBackChannelRetryWait()
+
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			if wait *= 2; wait > maxBackChannelRetryWait {
+				wait = maxBackChannelRetryWait
+			}
+		}
+
+		var retry bool
+		if retry, err = n.send(ctx, u, body); err == nil || !retry {
+			break
+		}
+	}
+
+	if err != nil {
+		n.d.Logger().
+			WithError(err).
+			WithField("event_id", e.ID).
+			WithField("identity_id", e.IdentityID).
+			WithField("url", u.URL).
+			Error("Unable to deliver the back-channel logout notification.")
+	}
+}
+
+// send posts the signed body to the URL and returns whether a failed delivery should be retried.
+func (n *BackChannelNotifier) send(ctx context.Context, u config.SelfServiceLogoutBackChannelURL, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, n.c.SelfServiceFlowLogoutBackChannelTimeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", u.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderBackChannelSignature, SignBackChannelBody([]byte(u.Secret), n.d.Clock().Now(), body))
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return true, errors.WithStack(err)
+	}
+
+	payload, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	_ = res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		retry := res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests
+		return retry, errors.Errorf("the back-channel logout URL responded with status code %d: %s", res.StatusCode, payload)
+	}
+	return false, nil
+}
+
+// SignBackChannelBody returns the value of the X-Kratos-Signature HTTP header for the body. Receivers should compute
+// the signature themselves, compare it in constant time, and reject old timestamps to prevent replays.
+func SignBackChannelBody(secret []byte, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
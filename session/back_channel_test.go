@@ -0,0 +1,190 @@
+package session_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+func TestBackChannelNotifier(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+	conf.MustSet(config.ViperKeySelfServiceLogoutBackChannelRetryWait, "10ms")
+
+	const secret = "a-very-secret-value"
+	events := make(chan session.BackChannelEvent, 10)
+	var failures, attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		signature := r.Header.Get(session.HeaderBackChannelSignature)
+		timestamp, err := strconv.ParseInt(strings.TrimPrefix(strings.Split(signature, ",")[0], "t="), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, session.SignBackChannelBody([]byte(secret), time.Unix(timestamp, 0), body), signature)
+
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var e session.BackChannelEvent
+		require.NoError(t, json.Unmarshal(body, &e))
+		events <- e
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(ts.Close)
+	conf.MustSet(config.ViperKeySelfServiceLogoutBackChannelURLs, []map[string]interface{}{{"url": ts.URL, "secret": secret}})
+
+	next := func(t *testing.T) session.BackChannelEvent {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "expected a back-channel logout notification")
+		}
+		return session.BackChannelEvent{}
+	}
+
+	expectNone := func(t *testing.T) {
+		select {
+		case e := <-events:
+			assert.Failf(t, "expected no back-channel logout notification", "%+v", e)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	newSession := func(t *testing.T, expiresAt *time.Time) *session.Session {
+		i := identity.Identity{Traits: []byte("{}"), ExpiresAt: expiresAt}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+		s := session.NewActiveSession(&i, conf, time.Now())
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+		return s
+	}
+
+	t.Run("case=notifies when a session is revoked", func(t *testing.T) {
+		s := newSession(t, nil)
+		require.NoError(t, reg.SessionBackChannelNotifier().RevokeSessionByToken(context.Background(), s.Token, session.RevocationReasonLogout))
+
+		e := next(t)
+		assert.Equal(t, session.EventSessionRevoked, e.Type)
+		assert.Equal(t, session.RevocationReasonLogout, e.Reason)
+		assert.Equal(t, conf.SelfPublicURL().String(), e.Issuer)
+		assert.Equal(t, s.IdentityID, e.IdentityID)
+		require.NotNil(t, e.SessionID)
+		assert.Equal(t, s.ID, *e.SessionID)
+
+		actual, err := reg.SessionPersister().GetSession(context.Background(), s.ID)
+		require.NoError(t, err)
+		assert.False(t, actual.Active)
+	})
+
+	t.Run("case=does not notify if the session was not active", func(t *testing.T) {
+		s := newSession(t, nil)
+		require.NoError(t, reg.SessionPersister().RevokeSessionByToken(context.Background(), s.Token))
+		require.NoError(t, reg.SessionBackChannelNotifier().RevokeSessionByToken(context.Background(), s.Token, session.RevocationReasonLogout))
+		require.NoError(t, reg.SessionBackChannelNotifier().RevokeSessionByToken(context.Background(), x.NewUUID().String(), session.RevocationReasonLogout))
+		expectNone(t)
+	})
+
+	t.Run("case=notifies when all sessions of an identity are revoked", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute)
+		s := newSession(t, &past)
+		require.NoError(t, reg.SessionJanitor().RevokeExpired(context.Background()))
+
+		e := next(t)
+		assert.Equal(t, session.RevocationReasonIdentityExpired, e.Reason)
+		assert.Equal(t, s.IdentityID, e.IdentityID)
+		assert.Nil(t, e.SessionID)
+	})
+
+	t.Run("case=notifies when an identity is deleted", func(t *testing.T) {
+		_, adminTS := testhelpers.NewKratosServer(t, reg)
+
+		for _, tc := range []struct {
+			name   string
+			delete func(t *testing.T, s *session.Session) *http.Request
+		}{
+			{
+				name: "hard",
+				delete: func(t *testing.T, s *session.Session) *http.Request {
+					req, err := http.NewRequest("DELETE", adminTS.URL+"/identities/"+s.IdentityID.String(), nil)
+					require.NoError(t, err)
+					return req
+				},
+			},
+			{
+				name: "soft",
+				delete: func(t *testing.T, s *session.Session) *http.Request {
+					req, err := http.NewRequest("DELETE", adminTS.URL+"/identities/"+s.IdentityID.String()+"?soft=true", nil)
+					require.NoError(t, err)
+					return req
+				},
+			},
+			{
+				name: "bulk",
+				delete: func(t *testing.T, s *session.Session) *http.Request {
+					req, err := http.NewRequest("DELETE", adminTS.URL+"/identities?ids="+s.IdentityID.String(), nil)
+					require.NoError(t, err)
+					return req
+				},
+			},
+		} {
+			t.Run("deletion="+tc.name, func(t *testing.T) {
+				s := newSession(t, nil)
+				res, err := adminTS.Client().Do(tc.delete(t, s))
+				require.NoError(t, err)
+				require.NoError(t, res.Body.Close())
+				require.True(t, res.StatusCode < 300, "%d", res.StatusCode)
+
+				e := next(t)
+				assert.Equal(t, session.RevocationReasonIdentityDeleted, e.Reason)
+				assert.Equal(t, s.IdentityID, e.IdentityID)
+				assert.Nil(t, e.SessionID)
+			})
+		}
+	})
+
+	t.Run("case=retries failed deliveries", func(t *testing.T) {
+		atomic.StoreInt32(&failures, 2)
+		atomic.StoreInt32(&attempts, 0)
+
+		identityID := x.NewUUID()
+		reg.SessionBackChannelNotifier().IdentitySessionsRevoked(identityID, session.RevocationReasonAdminRevoked)
+		e := next(t)
+		assert.Equal(t, identityID, e.IdentityID)
+		assert.EqualValues(t, 3, atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("case=gives up after the maximum number of retries", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySelfServiceLogoutBackChannelMaxRetries, 1)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySelfServiceLogoutBackChannelMaxRetries, 5)
+		})
+		atomic.StoreInt32(&failures, 2)
+		atomic.StoreInt32(&attempts, 0)
+
+		reg.SessionBackChannelNotifier().IdentitySessionsRevoked(x.NewUUID(), session.RevocationReasonAdminRevoked)
+		expectNone(t)
+		assert.EqualValues(t, 2, atomic.LoadInt32(&attempts))
+		atomic.StoreInt32(&failures, 0)
+	})
+}
@@ -0,0 +1,349 @@
+package session
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/randx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteDisclosures          = "/sessions/disclosures"
+	RouteDisclosureIntrospect = "/sessions/disclosures/introspect"
+)
+
+var disclosureTraitPath = regexp.MustCompile(`^[a-zA-Z0-9_-]+(\.[a-zA-Z0-9_-]+)*$`)
+
+type (
+	disclosureHandlerDependencies interface {
+		ManagementProvider
+		PersistenceProvider
+		x.WriterProvider
+		x.LoggingProvider
+		x.CSRFProvider
+		x.ClockProvider
+	}
+	DisclosureHandlerProvider interface {
+		SessionDisclosureHandler() *DisclosureHandler
+	}
+
+	// DisclosureHandler lets session owners create short-lived tokens which expose selected traits of their
+	// identity to third parties. Third parties introspect the tokens without learning anything else about the
+	// identity or the session.
+	DisclosureHandler struct {
+		d  disclosureHandlerDependencies
+		c  *config.Provider
+		dx *decoderx.HTTP
+	}
+
+	// Disclosure is a token exposing the claims selected by the session owner.
+	Disclosure struct {
+		ID uuid.UUID `db:"id"`
+
+		// Token is the opaque value given to third parties.
+		Token string `db:"token"`
+
+		SessionID uuid.UUID `db:"session_id"`
+
+		// Claims is the JSON encoded DisclosureClaims, computed when the token is created.
+		Claims sqlxx.JSONRawMessage `db:"claims"`
+
+		ExpiresAt time.Time `db:"expires_at"`
+		CreatedAt time.Time `db:"created_at"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+
+	// DisclosureClaims are the parts of an identity exposed by a disclosure token.
+	DisclosureClaims struct {
+		// Traits contains the selected traits only.
+		Traits json.RawMessage `json:"traits"`
+
+		// VerifiedAddresses contains the values of the verified addresses if the session owner chose to disclose
+		// them.
+		VerifiedAddresses []string `json:"verified_addresses,omitempty"`
+	}
+)
+
+func (d Disclosure) TableName() string {
+	return "session_disclosures"
+}
+
+func NewDisclosureHandler(d disclosureHandlerDependencies, c *config.Provider) *DisclosureHandler {
+	return &DisclosureHandler{d: d, c: c, dx: decoderx.NewHTTP()}
+}
+
+func (h *DisclosureHandler) RegisterPublicRoutes(public *x.RouterPublic) {
+	h.d.CSRFHandler().ExemptPath(RouteDisclosures)
+	h.d.CSRFHandler().ExemptPath(RouteDisclosureIntrospect)
+
+	public.POST(RouteDisclosures, h.create)
+	public.POST(RouteDisclosureIntrospect, h.introspect)
+}
+
+// swagger:parameters createSessionDisclosure
+// nolint:deadcode,unused
+type createSessionDisclosureParameters struct {
+	// in: header
+	Cookie string `json:"Cookie"`
+
+	// in: authorization
+	Authorization string `json:"Authorization"`
+
+	// in: body
+	// required: true
+	Body createSessionDisclosure
+}
+
+type createSessionDisclosure struct {
+	// Traits are the paths of the traits to disclose, for example `email` or `age.over_18`. Traits which do not
+	// exist are omitted.
+	Traits []string `json:"traits"`
+
+	// VerifiedAddresses discloses the values of the identity's verified addresses.
+	VerifiedAddresses bool `json:"verified_addresses"`
+}
+
+// Session Disclosure
+//
+// swagger:model sessionDisclosure
+type sessionDisclosure struct {
+	// Token is the disclosure token which third parties introspect.
+	//
+	// required: true
+	Token string `json:"token"`
+
+	// ExpiresAt is the time at which the token expires.
+	//
+	// required: true
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// swagger:route POST /sessions/disclosures public createSessionDisclosure
+//
+// Disclose Selected Traits of the Current HTTP Session
+//
+// Creates a short-lived token which exposes only the selected traits and, optionally, the verified addresses of
+// the authenticated identity. Third parties introspect the token at `/sessions/disclosures/introspect` and learn
+// nothing else about the identity or its session. The lifespan of the token is set by
+// `session.disclosure.lifespan`, but tokens never outlive their session.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Security:
+//       sessionToken:
+//
+//     Responses:
+//       201: sessionDisclosure
+//       400: genericError
+//       401: genericError
+//       500: genericError
+func (h *DisclosureHandler) create(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s, err := h.d.SessionManager().FetchFromRequest(r.Context(), r)
+	if err != nil {
+		h.d.Audit().WithRequest(r).WithError(err).Info("No valid session cookie found.")
+		h.d.Writer().WriteError(w, r,
+			errors.WithStack(herodot.ErrUnauthorized.WithReasonf("No valid session cookie found.")))
+		return
+	}
+
+	var p createSessionDisclosure
+	if err := h.dx.Decode(r, &p,
+		decoderx.HTTPJSONDecoder(),
+		decoderx.HTTPDecoderAllowedMethods("POST")); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if len(p.Traits) == 0 && !p.VerifiedAddresses {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("At least one trait or the verified addresses must be disclosed.")))
+		return
+	}
+
+	claims := DisclosureClaims{Traits: json.RawMessage("{}")}
+	for _, path := range p.Traits {
+		if !disclosureTraitPath.MatchString(path) {
+			h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Trait "%s" is not a valid trait path.`, path)))
+			return
+		}
+
+		value := gjson.GetBytes(s.Identity.Traits, path)
+		if !value.Exists() {
+			continue
+		}
+
+		claims.Traits, err = sjson.SetRawBytes(claims.Traits, path, []byte(value.Raw))
+		if err != nil {
+			h.d.Writer().WriteError(w, r, errors.WithStack(err))
+			return
+		}
+	}
+
+	if p.VerifiedAddresses {
+		claims.VerifiedAddresses = []string{}
+		for _, a := range s.Identity.VerifiableAddresses {
+			if a.Verified {
+				claims.VerifiedAddresses = append(claims.VerifiedAddresses, a.Value)
+			}
+		}
+	}
+
+	raw, err := json.Marshal(claims)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(err))
+		return
+	}
+
+	expiresAt := h.d.Clock().Now().Add(h.c.SessionDisclosureLifespan())
+	if s.ExpiresAt.Before(expiresAt) {
+		expiresAt = s.ExpiresAt
+	}
+
+	d := &Disclosure{
+		ID:        x.NewUUID(),
+		Token:     randx.MustString(32, randx.AlphaNum),
+		SessionID: s.ID,
+		Claims:    raw,
+		ExpiresAt: expiresAt,
+	}
+	if err := h.d.SessionPersister().CreateSessionDisclosure(r.Context(), d); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Audit().
+		WithRequest(r).
+		WithField("identity_id", s.Identity.ID).
+		WithField("traits", p.Traits).
+		WithField("verified_addresses", p.VerifiedAddresses).
+		Info("The owner of a session created a disclosure token.")
+	h.d.Writer().WriteCode(w, r, http.StatusCreated, &sessionDisclosure{Token: d.Token, ExpiresAt: d.ExpiresAt})
+}
+
+// swagger:parameters introspectSessionDisclosure
+// nolint:deadcode,unused
+type introspectSessionDisclosureParameters struct {
+	// in: body
+	// required: true
+	Body introspectSessionDisclosure
+}
+
+type introspectSessionDisclosure struct {
+	// The Disclosure Token
+	//
+	// required: true
+	Token string `json:"token"`
+}
+
+// Session Disclosure Introspection
+//
+// swagger:model sessionDisclosureIntrospection
+type sessionDisclosureIntrospection struct {
+	// Active is true if the token is valid and its session is still active. All other fields are omitted
+	// otherwise.
+	//
+	// required: true
+	Active bool `json:"active"`
+
+	*DisclosureClaims
+
+	// ExpiresAt is the time at which the token expires.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// swagger:route POST /sessions/disclosures/introspect public introspectSessionDisclosure
+//
+// Introspect a Session Disclosure Token
+//
+// Returns the claims disclosed by a token created at `/sessions/disclosures`. Similar to OAuth2 Token
+// Introspection, unknown and expired tokens as well as tokens of revoked sessions are not an error but result in
+// `{"active": false}`.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: sessionDisclosureIntrospection
+//       400: genericError
+//       500: genericError
+func (h *DisclosureHandler) introspect(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var p introspectSessionDisclosure
+	if err := h.dx.Decode(r, &p,
+		decoderx.HTTPJSONDecoder(),
+		decoderx.HTTPDecoderAllowedMethods("POST")); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if p.Token == "" {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The disclosure token must be set.")))
+		return
+	}
+
+	d, err := h.d.SessionPersister().GetSessionDisclosure(r.Context(), p.Token)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		h.d.Writer().Write(w, r, &sessionDisclosureIntrospection{Active: false})
+		return
+	} else if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	now := h.d.Clock().Now()
+	if !d.ExpiresAt.After(now) {
+		h.d.Writer().Write(w, r, &sessionDisclosureIntrospection{Active: false})
+		return
+	}
+
+	// Tokens are only valid as long as the session which created them.
+	s, err := h.d.SessionPersister().GetSession(r.Context(), d.SessionID)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		h.d.Writer().Write(w, r, &sessionDisclosureIntrospection{Active: false})
+		return
+	} else if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if !s.IsActive(now) || (s.Identity != nil && (!s.Identity.IsActive() || s.Identity.IsExpired(now))) {
+		h.d.Writer().Write(w, r, &sessionDisclosureIntrospection{Active: false})
+		return
+	}
+
+	var claims DisclosureClaims
+	if err := json.Unmarshal(d.Claims, &claims); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(err))
+		return
+	}
+
+	h.d.Writer().Write(w, r, &sessionDisclosureIntrospection{
+		Active:           true,
+		DisclosureClaims: &claims,
+		ExpiresAt:        &d.ExpiresAt,
+	})
+}
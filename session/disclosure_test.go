@@ -0,0 +1,107 @@
+package session_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	. "github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+func TestSessionDisclosure(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	clock := new(x.TestClock)
+	reg.WithClock(clock)
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+
+	i := &identity.Identity{Traits: identity.Traits(`{"email":"foo@ory.sh","name":"Foo","age":{"over_18":true,"years":42}}`)}
+	verified := identity.NewVerifiableEmailAddress("foo@ory.sh", i.ID)
+	verified.Verified = true
+	verified.Status = identity.VerifiableAddressStatusCompleted
+	i.VerifiableAddresses = []identity.VerifiableAddress{*verified, *identity.NewVerifiableEmailAddress("bar@ory.sh", i.ID)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+	sess := NewActiveSession(i, conf, time.Now())
+	require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), sess))
+
+	send := func(t *testing.T, route, token, body string, expectedStatus int) string {
+		req, err := http.NewRequest("POST", publicTS.URL+route, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("X-Session-Token", token)
+		}
+		res, err := publicTS.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, expectedStatus, res.StatusCode, "%s", raw)
+		return string(raw)
+	}
+
+	disclose := func(t *testing.T, body string) string {
+		token := gjson.Get(send(t, RouteDisclosures, sess.Token, body, http.StatusCreated), "token").String()
+		require.NotEmpty(t, token)
+		return token
+	}
+
+	introspect := func(t *testing.T, token string) string {
+		return send(t, RouteDisclosureIntrospect, "", fmt.Sprintf(`{"token":"%s"}`, token), http.StatusOK)
+	}
+
+	t.Run("case=requires a session", func(t *testing.T) {
+		send(t, RouteDisclosures, "", `{"traits":["email"]}`, http.StatusUnauthorized)
+		send(t, RouteDisclosures, "invalid", `{"traits":["email"]}`, http.StatusUnauthorized)
+	})
+
+	t.Run("case=rejects invalid requests", func(t *testing.T) {
+		send(t, RouteDisclosures, sess.Token, `{}`, http.StatusBadRequest)
+		send(t, RouteDisclosures, sess.Token, `{"traits":["age.*"]}`, http.StatusBadRequest)
+		send(t, RouteDisclosureIntrospect, "", `{}`, http.StatusBadRequest)
+	})
+
+	t.Run("case=discloses only the selected traits", func(t *testing.T) {
+		body := introspect(t, disclose(t, `{"traits":["age.over_18","unknown"]}`))
+		assert.True(t, gjson.Get(body, "active").Bool(), "%s", body)
+		assert.JSONEq(t, `{"age":{"over_18":true}}`, gjson.Get(body, "traits").Raw, "%s", body)
+		assert.False(t, gjson.Get(body, "verified_addresses").Exists(), "%s", body)
+		assert.True(t, gjson.Get(body, "expires_at").Exists(), "%s", body)
+		assert.False(t, gjson.Get(body, "identity").Exists(), "%s", body)
+	})
+
+	t.Run("case=discloses the verified addresses", func(t *testing.T) {
+		body := introspect(t, disclose(t, `{"traits":["email"],"verified_addresses":true}`))
+		assert.True(t, gjson.Get(body, "active").Bool(), "%s", body)
+		assert.JSONEq(t, `{"email":"foo@ory.sh"}`, gjson.Get(body, "traits").Raw, "%s", body)
+		assert.JSONEq(t, `["foo@ory.sh"]`, gjson.Get(body, "verified_addresses").Raw, "%s", body)
+	})
+
+	t.Run("case=tokens expire", func(t *testing.T) {
+		token := disclose(t, `{"traits":["email"]}`)
+		clock.Advance(6 * time.Minute)
+		t.Cleanup(clock.Reset)
+		assert.JSONEq(t, `{"active":false}`, introspect(t, token))
+	})
+
+	t.Run("case=unknown tokens and tokens of revoked sessions are not active", func(t *testing.T) {
+		assert.JSONEq(t, `{"active":false}`, introspect(t, "unknown"))
+
+		token := disclose(t, `{"traits":["email"]}`)
+		require.NoError(t, reg.SessionPersister().RevokeSessionByToken(context.Background(), sess.Token))
+		assert.JSONEq(t, `{"active":false}`, introspect(t, token))
+	})
+}
@@ -1,18 +1,29 @@
 package session
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
 
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/context"
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
 
 	"github.com/ory/x/decoderx"
+	"github.com/ory/x/urlx"
 
 	"github.com/ory/x/errorsx"
 
 	"github.com/ory/herodot"
+	"github.com/ory/nosurf"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/x"
 )
 
@@ -20,31 +31,51 @@ type (
 	handlerDependencies interface {
 		ManagementProvider
 		PersistenceProvider
+		identity.PrivilegedPoolProvider
 		x.WriterProvider
 		x.LoggingProvider
 		x.CSRFProvider
+		x.CSRFTokenGeneratorProvider
+		JWTSignerProvider
+		x.ClockProvider
+		BackChannelNotifierProvider
 	}
 	HandlerProvider interface {
 		SessionHandler() *Handler
 	}
 	Handler struct {
 		r  handlerDependencies
+		c  *config.Provider
 		dx *decoderx.HTTP
 	}
 )
 
 func NewHandler(
 	r handlerDependencies,
+	c *config.Provider,
 ) *Handler {
 	return &Handler{
 		r:  r,
+		c:  c,
 		dx: decoderx.NewHTTP(),
 	}
 }
 
 const (
-	RouteWhoami = "/sessions/whoami"
-	RouteRevoke = "/sessions"
+	RouteWhoami    = "/sessions/whoami"
+	RouteRevoke    = "/sessions"
+	RouteToken     = "/sessions/token"
+	RouteJWKS      = "/.well-known/jwks.json"
+	RouteListOwn   = "/sessions"
+	RouteRevokeOwn = "/sessions/:id"
+
+	RouteList       = "/sessions"
+	RouteIntrospect = "/sessions/introspect"
+	RouteCount      = "/sessions/count"
+
+	RouteExtend = "/sessions/:id/extend"
+
+	RouteIdentitySessions = "/identities/:id/sessions"
 	// SessionsWhoisPath  = "/sessions/whois"
 )
 
@@ -52,32 +83,53 @@ func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 	h.r.CSRFHandler().ExemptPath(RouteWhoami)
 	h.r.CSRFHandler().ExemptPath(RouteRevoke)
 
+	// DELETE /sessions/whoami is served by revokeOwn because the router does not allow it next to /sessions/:id.
 	for _, m := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
-		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace} {
+		http.MethodConnect, http.MethodOptions, http.MethodTrace} {
 		public.Handle(m, RouteWhoami, h.whoami)
 	}
 
 	public.DELETE(RouteRevoke, h.revoke)
+	public.GET(RouteListOwn, h.listOwn)
+
+	// Sessions are revoked by their owner using a session token or the session cookie. Browsers can not send
+	// cross-site DELETE requests without CORS, which is why no CSRF token is required. Other requests to the
+	// same path are still checked.
+	h.r.CSRFHandler().ExemptFunc(isRevokeOwnRequest)
+	public.DELETE(RouteRevokeOwn, h.revokeOwn)
+
+	h.r.CSRFHandler().ExemptPath(RouteToken)
+	public.GET(RouteToken, h.token)
+	public.POST(RouteToken, h.exchange)
+	public.GET(RouteJWKS, h.jwks)
 }
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 	// admin.GET(SessionsWhoisPath, h.fromPath)
+	admin.GET(RouteList, h.list)
+	admin.POST(RouteIntrospect, h.introspect)
+	admin.GET(RouteCount, h.count)
+	admin.DELETE(RouteIdentitySessions, h.revokeIdentitySessions)
+	admin.PATCH(RouteExtend, h.extend)
 }
 
 // swagger:parameters revokeSession
 // nolint:deadcode,unused
 type revokeSessionParameters struct {
+	// The session token to revoke, sent as `Bearer <session_token>`. The `X-Session-Token` HTTP Header can be used
+	// instead.
+	//
+	// in: header
+	Authorization string `json:"Authorization"`
+
 	// in: body
-	// required: true
 	Body revokeSession
 }
 
 type revokeSession struct {
 	// The Session Token
 	//
-	// Invalidate this session token.
-	//
-	// required: true
+	// Invalidate this session token. It is only used if the session token is not sent in the HTTP Headers.
 	SessionToken string `json:"session_token"`
 }
 
@@ -86,7 +138,8 @@ type revokeSession struct {
 // Revoke and Invalidate a Session
 //
 // Use this endpoint to revoke a session using its token. This endpoint is particularly useful for API clients
-// such as mobile apps to log the user out of the system and invalidate the session.
+// such as mobile apps to log the user out of the system and invalidate the session. The session token is sent as
+// the bearer token in the `Authorization` HTTP Header, as the `X-Session-Token` HTTP Header, or in the request body.
 //
 // This endpoint does not remove any HTTP Cookies - use the Self-Service Logout Flow instead.
 //
@@ -103,19 +156,273 @@ type revokeSession struct {
 //       400: genericError
 //       500: genericError
 func (h *Handler) revoke(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	var p revokeSession
-	if err := h.dx.Decode(r, &p,
-		decoderx.HTTPJSONDecoder(),
-		decoderx.HTTPDecoderAllowedMethods("DELETE")); err != nil {
+	token, ok := sessionTokenFromRequest(r)
+	if !ok {
+		var p revokeSession
+		if err := h.dx.Decode(r, &p,
+			decoderx.HTTPJSONDecoder(),
+			decoderx.HTTPDecoderAllowedMethods("DELETE")); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+		token = p.SessionToken
+	}
+
+	if len(token) == 0 {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The session token must be set.")))
+		return
+	}
+
+	if err := h.r.SessionBackChannelNotifier().RevokeSessionByToken(r.Context(), token, RevocationReasonRevoked); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:parameters revokeIdentitySessions
+// nolint:deadcode,unused
+type revokeIdentitySessionsParameters struct {
+	// ID is the ID of the identity whose sessions are revoked.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /identities/{id}/sessions admin revokeIdentitySessions
+//
+// Revoke All Sessions of an Identity
+//
+// Immediately revokes all sessions of the identity, for example because its account was compromised. Revoked
+// sessions are rejected no matter whether their token is sent as a cookie, as the `X-Session-Token` header, or
+// as the bearer token in the `Authorization` header. Session JWTs which were already issued stay valid until
+// they expire, so services relying on them should keep their lifespan short.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       404: genericError
+//       500: genericError
+func (h *Handler) revokeIdentitySessions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	i, err := h.r.PrivilegedIdentityPool().GetIdentity(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	count, err := h.r.SessionPersister().RevokeSessionsByIdentity(r.Context(), i.ID)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", i.ID).
+		WithField("revoked_sessions", count).
+		Info("An administrator revoked all sessions of an identity.")
+	h.r.SessionBackChannelNotifier().IdentitySessionsRevoked(i.ID, RevocationReasonAdminRevoked)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:parameters extendSession
+// nolint:deadcode,unused
+type extendSessionParameters struct {
+	// ID is the ID of the session to extend.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// in: body
+	Body extendSession
+}
+
+type extendSession struct {
+	// ExpiresIn sets the expiry of the session to this duration after now, for example `72h`. Defaults to the
+	// lifespan of the session.
+	ExpiresIn string `json:"expires_in,omitempty"`
+
+	// ExpiresAt sets the expiry of the session to this time. It must be in the future and can not be combined with
+	// `expires_in`.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// swagger:route PATCH /sessions/{id}/extend admin extendSession
+//
+// Extend or Adjust the Expiry of a Session
+//
+// Moves the expiry of an active session, for example to let a custom refresh endpoint renew the session token of a
+// native app without asking the user to sign in again. The session expires at `expires_at` if set, otherwise
+// `expires_in` after now. If neither is set, the session is renewed by its lifespan (`session.lifespan`, or
+// `session.remember_me.lifespan` for remembered sessions). The expiry may also be moved closer to shorten the
+// session. `session.sliding_expiration.max_lifespan` does not apply.
+//
+// Revoked and expired sessions can not be extended.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: session
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) extend(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var p extendSession
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+		return
+	}
+
+	s, err := h.r.SessionPersister().GetSession(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	now := h.r.Clock().Now()
+	if !s.IsActive(now) {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The session was revoked or has expired and can not be extended.")))
+		return
+	}
+
+	expiresAt, err := p.expiresAt(now, s.Lifespan(h.c))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.r.SessionPersister().UpdateSessionExpiry(r.Context(), s.ID, expiresAt); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", s.IdentityID).
+		WithField("session_id", s.ID).
+		WithField("previous_expires_at", s.ExpiresAt).
+		WithField("expires_at", expiresAt).
+		Info("An administrator changed the expiry of a session.")
+
+	s.ExpiresAt = expiresAt
+	h.r.Writer().Write(w, r, s.Declassify())
+}
+
+func (p *extendSession) expiresAt(now time.Time, lifespan time.Duration) (time.Time, error) {
+	if p.ExpiresAt != nil {
+		if p.ExpiresIn != "" {
+			return time.Time{}, errors.WithStack(herodot.ErrBadRequest.WithReason("Only one of expires_at and expires_in can be set."))
+		}
+		if !p.ExpiresAt.After(now) {
+			return time.Time{}, errors.WithStack(herodot.ErrBadRequest.WithReason("The value of expires_at must be in the future."))
+		}
+		return *p.ExpiresAt, nil
+	}
+
+	if p.ExpiresIn != "" {
+		d, err := time.ParseDuration(p.ExpiresIn)
+		if err != nil || d <= 0 {
+			return time.Time{}, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The duration "%s" is invalid. It must be a positive duration such as "72h" or "30m".`, p.ExpiresIn))
+		}
+		lifespan = d
+	}
+
+	return now.Add(lifespan), nil
+}
+
+// swagger:parameters revokeOwnSession
+// nolint:deadcode,unused
+type revokeOwnSessionParameters struct {
+	// ID is the ID of the session to revoke.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /sessions/{id} public revokeOwnSession
+//
+// Revoke One of the Caller's Other Sessions
+//
+// Revokes a session of the authenticated identity, for example to log out a lost device, while the session used
+// to call this endpoint stays active. The IDs of the other sessions are listed by `GET /sessions`. The session
+// used to call this endpoint can not be revoked here - use the Self-Service Logout Flow or `DELETE /sessions`
+// instead.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Security:
+//       sessionToken:
+//
+//     Responses:
+//       204: emptyResponse
+//       400: genericError
+//       401: genericError
+//       404: genericError
+//       500: genericError
+// isRevokeOwnRequest returns true if the request is served by revokeOwn.
+func isRevokeOwnRequest(r *http.Request) bool {
+	matched, err := path.Match("/sessions/*", r.URL.Path)
+	return r.Method == http.MethodDelete && matched && err == nil
+}
+
+func (h *Handler) revokeOwn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if ps.ByName("id") == "whoami" {
+		h.whoami(w, r, ps)
+		return
+	}
+
+	s, err := h.r.SessionManager().FetchFromRequest(r.Context(), r)
+	if err != nil {
+		h.r.Writer().WriteError(w, r,
+			errors.WithStack(herodot.ErrUnauthorized.WithReasonf("No valid session cookie found.")))
+		return
+	}
+
+	sid := x.ParseUUID(ps.ByName("id"))
+	if sid == s.ID {
+		h.r.Writer().WriteError(w, r,
+			errors.WithStack(herodot.ErrBadRequest.WithReasonf("The session used to call this endpoint can not be revoked here, use the logout flow instead.")))
+		return
+	}
+
+	revoked, err := h.r.SessionPersister().GetSession(r.Context(), sid)
+	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
-	if err := h.r.SessionPersister().RevokeSessionByToken(r.Context(), p.SessionToken); err != nil {
+	if err := h.r.SessionPersister().RevokeSessionOfIdentity(r.Context(), s.IdentityID, sid); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", s.IdentityID).
+		WithField("session_id", sid).
+		Info("An identity revoked one of its sessions.")
+	if revoked.Active && revoked.IdentityID == s.IdentityID {
+		h.r.SessionBackChannelNotifier().SessionRevoked(revoked, RevocationReasonRevoked)
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -127,6 +434,12 @@ type whoamiParameters struct {
 
 	// in: authorization
 	Authorization string `json:"Authorization"`
+
+	// IfNoneMatch is the ETag of a previous response. If the session did not change since, the response is 304
+	// without a body.
+	//
+	// in: header
+	IfNoneMatch string `json:"If-None-Match"`
 }
 
 // swagger:route GET /sessions/whoami public whoami
@@ -139,6 +452,14 @@ type whoamiParameters struct {
 //
 // This endpoint is useful for reverse proxies and API Gateways.
 //
+// If `session.sliding_expiration.enabled` is set, every check extends the session to expire `session.lifespan`
+// later, but not past `session.sliding_expiration.max_lifespan` after the identity authenticated.
+//
+// The ETag header of the response changes whenever the session or its identity changes, but not when only the
+// expiry of the session moved. Send it in the `If-None-Match` header to receive 304 without a body if nothing
+// changed. If `session.cache.enabled` is set,
+// sessions are cached in memory for `session.cache.ttl`.
+//
 //     Produces:
 //     - application/json
 //
@@ -149,6 +470,7 @@ type whoamiParameters struct {
 //
 //     Responses:
 //       200: session
+//       304: emptyResponse
 //       401: genericError
 //       500: genericError
 func (h *Handler) whoami(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -160,71 +482,714 @@ func (h *Handler) whoami(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		return
 	}
 
-	// s.Devices = nil
-	s.Identity = s.Identity.CopyWithoutCredentials()
+	if s.ImpersonatedBy != "" {
+		h.r.Audit().
+			WithRequest(r).
+			WithField("identity_id", s.Identity.ID).
+			WithField("session_id", s.ID).
+			WithSensitiveField("impersonated_by", string(s.ImpersonatedBy)).
+			Info("An impersonation session was used.")
+	}
+
+	if h.c.SessionSlidingExpirationEnabled() &&
+		s.Extend(h.r.Clock().Now(), s.Lifespan(h.c), h.c.SessionMaxLifespan()) {
+		if err := h.r.SessionPersister().UpdateSessionExpiry(r.Context(), s.ID, s.ExpiresAt); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+	}
 
 	// Set userId as the X-Kratos-Authenticated-Identity-Id header.
 	w.Header().Set("X-Kratos-Authenticated-Identity-Id", s.Identity.ID.String())
 
+	version := s.Version()
+	w.Header().Set("ETag", version)
+	if ifNoneMatch(r, version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	h.r.Writer().Write(w, r, s)
 }
 
-func (h *Handler) IsAuthenticated(wrap httprouter.Handle, onUnauthenticated httprouter.Handle) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		if _, err := h.r.SessionManager().FetchFromRequest(r.Context(), r); err != nil {
-			if onUnauthenticated != nil {
-				onUnauthenticated(w, r, ps)
-				return
-			}
-
-			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrForbidden.WithReason("This endpoint can only be accessed with a valid session. Please log in and try again.").WithDebugf("%+v", err)))
-			return
-		}
+// Session JWT
+//
+// swagger:model sessionJWT
+type sessionJWT struct {
+	// Token is a signed JSON Web Token representing the session.
+	//
+	// required: true
+	Token string `json:"token"`
 
-		wrap(w, r, ps)
-	}
+	// ExpiresAt is the time at which the token expires.
+	//
+	// required: true
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
-func (h *Handler) IsNotAuthenticated(wrap httprouter.Handle, onAuthenticated httprouter.Handle) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		if _, err := h.r.SessionManager().FetchFromRequest(r.Context(), r); err != nil {
-			if errorsx.Cause(err).Error() == ErrNoActiveSessionFound.Error() {
-				wrap(w, r, ps)
-				return
-			}
-			h.r.Writer().WriteError(w, r, err)
-			return
-		}
-
-		if onAuthenticated != nil {
-			onAuthenticated(w, r, ps)
-			return
-		}
+// nolint:deadcode,unused
+// swagger:parameters getSessionJWT
+type getSessionJWTParameters struct {
+	// in: header
+	Cookie string `json:"Cookie"`
 
-		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrForbidden.WithReason("This endpoint can only be accessed without a login session. Please log out and try again.")))
-	}
+	// in: authorization
+	Authorization string `json:"Authorization"`
 }
 
-func RedirectOnAuthenticated(c *config.Provider) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		returnTo, err := x.SecureRedirectTo(r, c.SelfServiceBrowserDefaultReturnTo(), x.SecureRedirectAllowSelfServiceURLs(c.SelfPublicURL()))
-		if err != nil {
-			http.Redirect(w, r, c.SelfServiceBrowserDefaultReturnTo().String(), http.StatusFound)
-			return
-		}
-
-		http.Redirect(w, r, returnTo.String(), http.StatusFound)
+// swagger:route GET /sessions/token public getSessionJWT
+//
+// Get a JSON Web Token for the Current HTTP Session
+//
+// Uses the HTTP Headers in the GET request to determine (e.g. by using checking the cookies) who is authenticated
+// and returns a short-lived JSON Web Token representing the session. Other services can verify the token using
+// the keys published at `/.well-known/jwks.json` without asking ORY Kratos. This endpoint requires
+// `session.jwt.jwks_url` to be set.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Security:
+//       sessionToken:
+//
+//     Responses:
+//       200: sessionJWT
+//       401: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) token(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s, err := h.r.SessionManager().FetchFromRequest(r.Context(), r)
+	if err != nil {
+		h.r.Audit().WithRequest(r).WithError(err).Info("No valid session cookie found.")
+		h.r.Writer().WriteError(w, r,
+			errors.WithStack(herodot.ErrUnauthorized.WithReasonf("No valid session cookie found.")))
+		return
 	}
-}
 
-func RedirectOnUnauthenticated(to string) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		http.Redirect(w, r, to, http.StatusFound)
+	token, expiresAt, err := h.r.SessionJWTSigner().Sign(s)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
 	}
+
+	h.r.Writer().Write(w, r, &sessionJWT{Token: token, ExpiresAt: expiresAt})
 }
 
-func RespondWithJSONErrorOnAuthenticated(h herodot.Writer, err error) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		h.WriteError(w, r, err)
-	}
+// The Response for Exchanging a Session Cookie
+//
+// swagger:model exchangedSessionToken
+type exchangedSessionToken struct {
+	// The Session Token
+	//
+	// A session token is equivalent to a session cookie, but it can be sent in the HTTP Authorization
+	// Header:
+	//
+	// 		Authorization: bearer ${session-token}
+	//
+	// required: true
+	Token string `json:"session_token"`
+
+	// The Session
+	//
+	// The session of the session token. It is independent of the session of the cookie.
+	//
+	// required: true
+	Session *Session `json:"session"`
+}
+
+// swagger:parameters exchangeSessionCookie
+// nolint:deadcode,unused
+type exchangeSessionCookieParameters struct {
+	// in: header
+	Cookie string `json:"Cookie"`
+
+	// in: body
+	// required: true
+	Body exchangeSessionCookie
+}
+
+type exchangeSessionCookie struct {
+	// The Anti-CSRF Token
+	//
+	// This token is included in the forms of all self-service browser flows.
+	//
+	// required: true
+	CSRFToken string `json:"csrf_token"`
+}
+
+// swagger:route POST /sessions/token public exchangeSessionCookie
+//
+// Exchange a Session Cookie for a Session Token
+//
+// Issues a session token for the identity of the session cookie, for example to hand over a session from a WebView
+// to the native part of an app. The session token belongs to a new session which expires together with the session of
+// the cookie and can be revoked independently of it. Requests authenticated with a session token are rejected.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       201: exchangedSessionToken
+//       400: genericError
+//       401: genericError
+//       403: genericError
+//       500: genericError
+func (h *Handler) exchange(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if isTokenAuthenticated(r) {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("Only session cookies can be exchanged for a session token.")))
+		return
+	}
+
+	var p exchangeSessionCookie
+	if err := h.dx.Decode(r, &p,
+		decoderx.HTTPJSONDecoder(),
+		decoderx.HTTPDecoderAllowedMethods("POST")); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if !nosurf.VerifyToken(h.r.GenerateCSRFToken(r), p.CSRFToken) {
+		h.r.Writer().WriteError(w, r, errors.WithStack(x.ErrInvalidCSRFToken))
+		return
+	}
+
+	s, err := h.r.SessionManager().FetchFromRequest(r.Context(), r)
+	if err != nil {
+		h.r.Audit().WithRequest(r).WithError(err).Info("No valid session cookie found.")
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrUnauthorized.WithReasonf("No valid session cookie found.")))
+		return
+	}
+
+	exchanged := NewActiveSession(s.Identity, h.c, s.AuthenticatedAt)
+	exchanged.ExpiresAt = s.ExpiresAt
+	exchanged.Remembered = s.Remembered
+	exchanged.ImpersonatedBy = s.ImpersonatedBy
+	exchanged.SetDevice(r, h.c)
+	if err := h.r.SessionPersister().CreateSession(r.Context(), exchanged); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Audit().
+		WithRequest(r).
+		WithField("identity_id", s.IdentityID).
+		WithField("session_id", s.ID).
+		WithField("exchanged_session_id", exchanged.ID).
+		Info("A session cookie was exchanged for a session token.")
+
+	h.r.Writer().WriteCreated(w, r, RouteWhoami, &exchangedSessionToken{Token: exchanged.Token, Session: exchanged.Declassify()})
+}
+
+// JSON Web Key Set
+//
+// swagger:model jsonWebKeySet
+// nolint:deadcode,unused
+type jsonWebKeySet struct {
+	// The keys of the set.
+	//
+	// required: true
+	Keys []interface{} `json:"keys"`
+}
+
+// swagger:route GET /.well-known/jwks.json public getSessionJWKS
+//
+// Get the Keys for Verifying Session JSON Web Tokens
+//
+// Returns the public keys for verifying the tokens issued by `/sessions/token`. This endpoint requires
+// `session.jwt.jwks_url` to be set.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: jsonWebKeySet
+//       404: genericError
+//       500: genericError
+func (h *Handler) jwks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	keys, err := h.r.SessionJWTSigner().PublicKeys()
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, keys)
+}
+
+func (h *Handler) IsAuthenticated(wrap httprouter.Handle, onUnauthenticated httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if _, err := h.r.SessionManager().FetchFromRequest(r.Context(), r); err != nil {
+			if onUnauthenticated != nil {
+				onUnauthenticated(w, r, ps)
+				return
+			}
+
+			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrForbidden.WithReason("This endpoint can only be accessed with a valid session. Please log in and try again.").WithDebugf("%+v", err)))
+			return
+		}
+
+		wrap(w, r, ps)
+	}
+}
+
+func (h *Handler) IsNotAuthenticated(wrap httprouter.Handle, onAuthenticated httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if _, err := h.r.SessionManager().FetchFromRequest(r.Context(), r); err != nil {
+			if errorsx.Cause(err).Error() == ErrNoActiveSessionFound.Error() {
+				wrap(w, r, ps)
+				return
+			}
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+
+		if onAuthenticated != nil {
+			onAuthenticated(w, r, ps)
+			return
+		}
+
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrForbidden.WithReason("This endpoint can only be accessed without a login session. Please log out and try again.")))
+	}
+}
+
+func RedirectOnAuthenticated(c *config.Provider) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		returnTo, err := x.SecureRedirectTo(r, c.SelfServiceBrowserDefaultReturnTo(), x.SecureRedirectAllowSelfServiceURLs(c.SelfPublicURL()))
+		if err != nil {
+			http.Redirect(w, r, c.SelfServiceBrowserDefaultReturnTo().String(), http.StatusFound)
+			return
+		}
+
+		http.Redirect(w, r, returnTo.String(), http.StatusFound)
+	}
+}
+
+func RedirectOnUnauthenticated(to string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		http.Redirect(w, r, to, http.StatusFound)
+	}
+}
+
+func RespondWithJSONErrorOnAuthenticated(h herodot.Writer, err error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		h.WriteError(w, r, err)
+	}
+}
+
+// swagger:parameters introspectSession
+// nolint:deadcode,unused
+type introspectSessionParameters struct {
+	// in: body
+	// required: true
+	Body introspectSession
+}
+
+type introspectSession struct {
+	// The Session Token
+	//
+	// Either the session token or the value of the session cookie must be set.
+	SessionToken string `json:"session_token"`
+
+	// The Session Cookie
+	//
+	// The value of the `ory_kratos_session` cookie without the cookie name.
+	SessionCookie string `json:"session_cookie"`
+}
+
+// Session Introspection
+//
+// swagger:model sessionIntrospection
+type sessionIntrospection struct {
+	// Active is true if the token or cookie belongs to an active session. All other fields are omitted otherwise.
+	//
+	// required: true
+	Active bool `json:"active"`
+
+	// Session is the session including its identity.
+	Session *Session `json:"session,omitempty"`
+
+	// AuthenticatorAssuranceLevel is the authenticator assurance level of the session.
+	AuthenticatorAssuranceLevel AuthenticatorAssuranceLevel `json:"aal,omitempty"`
+
+	// ExpiresAt is the time at which the session expires.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// The number of active sessions.
+//
+// swagger:model sessionCount
+type sessionCount struct {
+	// Count is the number of sessions which are active and not expired.
+	//
+	// required: true
+	Count int `json:"count"`
+}
+
+// swagger:route GET /sessions/count admin countSessions
+//
+// Count Active Sessions
+//
+// Counts the sessions which are active and not expired without listing them.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: sessionCount
+//       500: genericError
+func (h *Handler) count(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	count, err := h.r.SessionPersister().CountActiveSessions(r.Context(), h.r.Clock().Now())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, &sessionCount{Count: count})
+}
+
+// swagger:route POST /sessions/introspect admin introspectSession
+//
+// Introspect a Session
+//
+// Validates a session token or the value of a session cookie and returns the session, its identity, its
+// authenticator assurance level, and its expiry. Similar to OAuth2 Token Introspection, unknown, revoked, and
+// expired sessions are not an error but result in `{"active": false}`.
+//
+// This endpoint is useful for API Gateways which validate sessions without forwarding cookies to ORY Kratos.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: sessionIntrospection
+//       400: genericError
+//       500: genericError
+func (h *Handler) introspect(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var p introspectSession
+	if err := h.dx.Decode(r, &p,
+		decoderx.HTTPJSONDecoder(),
+		decoderx.HTTPDecoderAllowedMethods("POST")); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if p.SessionToken == "" && p.SessionCookie == "" {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("Either the session token or the session cookie must be set.")))
+		return
+	}
+
+	// The session manager resolves the token and decrypts the cookie the same way it does for requests to the
+	// public API.
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, "/", nil)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errors.WithStack(err))
+		return
+	}
+	// The cookie store keeps per-request state which is only cleared for requests served by the HTTP server.
+	defer context.Clear(req)
+	if p.SessionToken != "" {
+		req.Header.Set("X-Session-Token", p.SessionToken)
+	} else {
+		req.AddCookie(&http.Cookie{Name: DefaultSessionCookieName, Value: p.SessionCookie})
+	}
+
+	s, err := h.r.SessionManager().FetchFromRequest(r.Context(), req)
+	if errors.Is(err, ErrNoActiveSessionFound) {
+		h.r.Writer().Write(w, r, &sessionIntrospection{Active: false})
+		return
+	} else if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, &sessionIntrospection{
+		Active:                      true,
+		Session:                     s,
+		AuthenticatorAssuranceLevel: s.AuthenticatorAssuranceLevel(),
+		ExpiresAt:                   &s.ExpiresAt,
+	})
+}
+
+// swagger:parameters listSessions
+// nolint:deadcode,unused
+type listSessionsParameters struct {
+	// Items per Page
+	//
+	// This is the number of items per page.
+	//
+	// required: false
+	// in: query
+	// default: 100
+	// min: 1
+	// max: 500
+	PerPage int `json:"per_page"`
+
+	// Pagination Page
+	//
+	// required: false
+	// in: query
+	// default: 0
+	// min: 0
+	Page int `json:"page"`
+
+	// Identity ID
+	//
+	// Only lists sessions issued to this identity.
+	//
+	// required: false
+	// in: query
+	IdentityID string `json:"identity_id"`
+
+	// Active
+	//
+	// Only lists sessions which are active and not expired if `true` and sessions which were revoked or expired if
+	// `false`.
+	//
+	// required: false
+	// in: query
+	Active string `json:"active"`
+
+	// IP Address
+	//
+	// Only lists sessions issued to this IP address.
+	//
+	// required: false
+	// in: query
+	IPAddress string `json:"ip_address"`
+
+	// Issued Since
+	//
+	// Only lists sessions issued at or after this time, formatted as RFC 3339, for example `2021-01-01T00:00:00Z`.
+	//
+	// required: false
+	// in: query
+	IssuedSince string `json:"issued_since"`
+}
+
+// A list of sessions.
+// swagger:response sessionList
+// nolint:deadcode,unused
+type sessionListResponse struct {
+	// in: body
+	// required: true
+	// type: array
+	Body []Session
+}
+
+// swagger:route GET /sessions admin listSessions
+//
+// List Sessions
+//
+// Lists the sessions of all identities, most recently issued first. Sessions can be filtered by their identity using
+// `?identity_id=...`, by whether they are active using `?active=true`, by the IP address they were issued to using
+// `?ip_address=...`, and by when they were issued using `?issued_since=2021-01-01T00:00:00Z`. All filters must match.
+//
+// Sessions are paginated using `page` and `per_page`. The credentials of the identities are never returned.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: sessionList
+//       400: genericError
+//       500: genericError
+func (h *Handler) list(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	filter, err := h.parseListSessionsFilter(r.URL.Query())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	page, itemsPerPage := x.ParsePagination(r)
+	ss, err := h.r.SessionPersister().ListSessions(r.Context(), filter, page, itemsPerPage)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	total, err := h.r.SessionPersister().CountSessions(r.Context(), filter)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	for k := range ss {
+		ss[k].Declassify()
+	}
+
+	// The links of the pagination header keep the filters.
+	x.PaginationHeader(w, urlx.CopyWithQuery(urlx.AppendPaths(h.c.SelfAdminURL(), RouteList), r.URL.Query()), int64(total), page, itemsPerPage)
+	h.r.Writer().Write(w, r, ss)
+}
+
+func (h *Handler) parseListSessionsFilter(query url.Values) (ListSessionsFilter, error) {
+	filter := ListSessionsFilter{
+		Now:       h.r.Clock().Now(),
+		IPAddress: query.Get("ip_address"),
+	}
+
+	if raw := query.Get("identity_id"); raw != "" {
+		id, err := uuid.FromString(raw)
+		if err != nil {
+			return filter, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The identity_id filter "%s" is not a valid UUID.`, raw))
+		}
+		filter.IdentityID = id
+	}
+
+	if raw := query.Get("active"); raw != "" {
+		active, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filter, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The active filter "%s" is invalid, expected "true" or "false".`, raw))
+		}
+		filter.Active = &active
+	}
+
+	if raw := query.Get("issued_since"); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The issued_since filter "%s" is invalid, expected a time formatted as RFC 3339.`, raw))
+		}
+		filter.IssuedSince = since
+	}
+
+	return filter, nil
+}
+
+// An active session of the caller.
+//
+// swagger:model ownSession
+type ownSession struct {
+	// ID is the ID of the session.
+	//
+	// required: true
+	ID uuid.UUID `json:"id"`
+
+	// AuthenticatedAt is the time the session was authenticated at.
+	//
+	// required: true
+	AuthenticatedAt time.Time `json:"authenticated_at"`
+
+	// IssuedAt is the time the session was issued at.
+	//
+	// required: true
+	IssuedAt time.Time `json:"issued_at"`
+
+	// ExpiresAt is the time at which the session expires.
+	//
+	// required: true
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// LastActiveAt is the time the session was last used.
+	LastActiveAt *time.Time `json:"last_active_at,omitempty"`
+
+	// IPAddress is the IP address of the client the session was issued to.
+	IPAddress string `json:"ip_address,omitempty"`
+
+	// UserAgent is the user agent of the client the session was issued to, which describes its device and browser.
+	UserAgent string `json:"user_agent,omitempty"`
+
+	// Location is the location of the client the session was issued to, for example its country.
+	Location string `json:"location,omitempty"`
+
+	// Browser is the browser and its major version the session was issued to.
+	Browser string `json:"browser,omitempty"`
+
+	// OperatingSystem is the operating system of the device the session was issued to.
+	OperatingSystem string `json:"operating_system,omitempty"`
+
+	// DeviceType is the type of the device the session was issued to.
+	DeviceType string `json:"device_type,omitempty"`
+}
+
+// A list of the caller's sessions.
+// swagger:response ownSessionList
+// nolint:deadcode,unused
+type ownSessionListResponse struct {
+	// in: body
+	// required: true
+	// type: array
+	Body []ownSession
+}
+
+// swagger:route GET /sessions public listOwnSessions
+//
+// List the Caller's Other Sessions
+//
+// Lists the active sessions of the authenticated identity except the session used to call this endpoint, most
+// recently issued first. Every session includes the IP address, user agent, browser, operating system, and
+// location of the client it was issued to and when it was last used, which allows applications to show where the
+// user is signed in. Sessions are paginated using `page` and `per_page`.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Security:
+//       sessionToken:
+//
+//     Responses:
+//       200: ownSessionList
+//       401: genericError
+//       500: genericError
+func (h *Handler) listOwn(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	s, err := h.r.SessionManager().FetchFromRequest(r.Context(), r)
+	if err != nil {
+		h.r.Writer().WriteError(w, r,
+			errors.WithStack(herodot.ErrUnauthorized.WithReasonf("No valid session cookie found.")))
+		return
+	}
+
+	active := true
+	filter := ListSessionsFilter{IdentityID: s.IdentityID, Active: &active, Now: h.r.Clock().Now(), ExceptID: s.ID}
+
+	page, itemsPerPage := x.ParsePagination(r)
+	ss, err := h.r.SessionPersister().ListSessions(r.Context(), filter, page, itemsPerPage)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	total, err := h.r.SessionPersister().CountSessions(r.Context(), filter)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	own := make([]ownSession, len(ss))
+	for k, se := range ss {
+		own[k] = ownSession{
+			ID:              se.ID,
+			AuthenticatedAt: se.AuthenticatedAt,
+			IssuedAt:        se.IssuedAt,
+			ExpiresAt:       se.ExpiresAt,
+			LastActiveAt:    se.LastActiveAt,
+			IPAddress:       string(se.IPAddress),
+			UserAgent:       string(se.UserAgent),
+			Location:        string(se.Location),
+			Browser:         string(se.Browser),
+			OperatingSystem: string(se.OperatingSystem),
+			DeviceType:      string(se.DeviceType),
+		}
+	}
+
+	x.PaginationHeader(w, urlx.AppendPaths(h.c.SelfPublicURL(), RouteListOwn), int64(total), page, itemsPerPage)
+	h.r.Writer().Write(w, r, own)
 }
@@ -3,14 +3,20 @@ package session_test
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	gcontext "github.com/gorilla/context"
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
 
 	"github.com/ory/x/pointerx"
 
@@ -46,7 +52,7 @@ func TestSessionWhoAmI(t *testing.T) {
 		h, _ := testhelpers.MockSessionCreateHandler(t, reg)
 		r.GET("/set", h)
 
-		NewHandler(reg).RegisterPublicRoutes(r)
+		NewHandler(reg, conf).RegisterPublicRoutes(r)
 		ts := httptest.NewServer(r)
 		defer ts.Close()
 
@@ -81,24 +87,293 @@ func TestSessionWhoAmI(t *testing.T) {
 	})
 }
 
+func TestSessionWhoAmISlidingExpiration(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+	conf.MustSet(config.ViperKeySessionLifespan, "1h")
+	conf.MustSet(config.ViperKeySessionSlidingExpirationMaxLifespan, "3h")
+	clock := new(x.TestClock)
+	reg.WithClock(clock)
+
+	i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+	whoami := func(t *testing.T, s *Session, expectCode int) {
+		req, err := http.NewRequest("GET", publicTS.URL+RouteWhoami, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Session-Token", s.Token)
+		res, err := publicTS.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, expectCode, res.StatusCode)
+	}
+
+	expiresAt := func(t *testing.T, s *Session) time.Time {
+		actual, err := reg.SessionPersister().GetSession(context.Background(), s.ID)
+		require.NoError(t, err)
+		return actual.ExpiresAt
+	}
+
+	t.Run("case=fixed lifespan by default", func(t *testing.T) {
+		s := NewActiveSession(i, conf, clock.Now())
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+
+		clock.Advance(30 * time.Minute)
+		whoami(t, s, http.StatusOK)
+		assert.Equal(t, s.ExpiresAt.Unix(), expiresAt(t, s).Unix())
+
+		clock.Advance(31 * time.Minute)
+		whoami(t, s, http.StatusUnauthorized)
+	})
+
+	t.Run("case=extends sessions which are used", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySessionSlidingExpirationEnabled, true)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySessionSlidingExpirationEnabled, false)
+		})
+
+		s := NewActiveSession(i, conf, clock.Now())
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+
+		for k := 0; k < 3; k++ {
+			clock.Advance(40 * time.Minute)
+			whoami(t, s, http.StatusOK)
+			assert.WithinDuration(t, clock.Now().Add(time.Hour), expiresAt(t, s), time.Second)
+		}
+
+		clock.Advance(40 * time.Minute)
+		whoami(t, s, http.StatusOK)
+		assert.Equal(t, s.AuthenticatedAt.Add(3*time.Hour).Unix(), expiresAt(t, s).Unix(), "sessions are not extended past the maximum lifespan")
+
+		clock.Advance(21 * time.Minute)
+		whoami(t, s, http.StatusUnauthorized)
+	})
+
+	t.Run("case=does not extend unused sessions", func(t *testing.T) {
+		conf.MustSet(config.ViperKeySessionSlidingExpirationEnabled, true)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeySessionSlidingExpirationEnabled, false)
+		})
+
+		s := NewActiveSession(i, conf, clock.Now())
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+
+		clock.Advance(61 * time.Minute)
+		whoami(t, s, http.StatusUnauthorized)
+	})
+}
+
+func TestSessionWhoAmIETag(t *testing.T) {
+	for _, cache := range []bool{false, true} {
+		t.Run(fmt.Sprintf("cache=%t", cache), func(t *testing.T) {
+			conf, reg := internal.NewFastRegistryWithMocks(t)
+			publicTS, _ := testhelpers.NewKratosServer(t, reg)
+			conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+			conf.MustSet(config.ViperKeySessionCacheEnabled, cache)
+			clock := new(x.TestClock)
+			reg.WithClock(clock)
+
+			i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+			require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+			s := NewActiveSession(i, conf, time.Now())
+			require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+
+			whoami := func(t *testing.T, etag string, expectCode int) (string, string) {
+				req, err := http.NewRequest("GET", publicTS.URL+RouteWhoami, nil)
+				require.NoError(t, err)
+				req.Header.Set("X-Session-Token", s.Token)
+				if etag != "" {
+					req.Header.Set("If-None-Match", etag)
+				}
+				res, err := publicTS.Client().Do(req)
+				require.NoError(t, err)
+				defer res.Body.Close()
+				body, err := ioutil.ReadAll(res.Body)
+				require.NoError(t, err)
+				require.Equal(t, expectCode, res.StatusCode, "%s", body)
+				return res.Header.Get("ETag"), string(body)
+			}
+
+			etag, body := whoami(t, "", http.StatusOK)
+			require.NotEmpty(t, etag)
+			assert.Equal(t, s.ID.String(), gjson.Get(body, "id").String(), "%s", body)
+
+			t.Run("case=not modified", func(t *testing.T) {
+				actual, body := whoami(t, etag, http.StatusNotModified)
+				assert.Equal(t, etag, actual)
+				assert.Empty(t, body)
+
+				_, _ = whoami(t, `"unknown", `+etag, http.StatusNotModified)
+				_, _ = whoami(t, `"unknown"`, http.StatusOK)
+			})
+
+			t.Run("case=not modified after sliding expiration extended the session", func(t *testing.T) {
+				conf.MustSet(config.ViperKeySessionSlidingExpirationEnabled, true)
+				t.Cleanup(func() {
+					conf.MustSet(config.ViperKeySessionSlidingExpirationEnabled, false)
+				})
+
+				clock.Advance(time.Hour)
+				actual, _ := whoami(t, etag, http.StatusNotModified)
+				assert.Equal(t, etag, actual)
+
+				extended, err := reg.SessionPersister().GetSession(context.Background(), s.ID)
+				require.NoError(t, err)
+				assert.True(t, extended.ExpiresAt.After(s.ExpiresAt), "the session was extended")
+			})
+
+			t.Run("case=modified after the identity was updated", func(t *testing.T) {
+				time.Sleep(time.Millisecond)
+				i.Traits = identity.Traits(`{"baz":"updated"}`)
+				require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentity(context.Background(), i))
+
+				actual, body := whoami(t, etag, http.StatusOK)
+				assert.NotEqual(t, etag, actual)
+				assert.Equal(t, "updated", gjson.Get(body, "identity.traits.baz").String(), "%s", body)
+				etag = actual
+			})
+
+			t.Run("case=unauthorized after logout", func(t *testing.T) {
+				require.NoError(t, reg.SessionPersister().RevokeSessionByToken(context.Background(), s.Token))
+				_, _ = whoami(t, etag, http.StatusUnauthorized)
+			})
+		})
+	}
+}
+
+func TestSessionExchangeCookie(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+
+	i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+	s := NewActiveSession(i, conf, time.Now())
+	s.Remembered = true
+
+	exchange := func(t *testing.T, c *http.Client, body string, expectCode int) string {
+		req, err := http.NewRequest("POST", publicTS.URL+RouteToken, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		res, err := c.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		actual, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, expectCode, res.StatusCode, "%s", actual)
+		return string(actual)
+	}
+
+	validBody := fmt.Sprintf(`{"csrf_token":"%s"}`, x.FakeCSRFToken)
+
+	t.Run("case=issues a new session token", func(t *testing.T) {
+		body := exchange(t, testhelpers.NewHTTPClientWithSessionCookie(t, reg, s), validBody, http.StatusCreated)
+
+		token := gjson.Get(body, "session_token").String()
+		require.NotEmpty(t, token, "%s", body)
+		assert.NotEqual(t, s.Token, token)
+		assert.NotEqual(t, s.ID.String(), gjson.Get(body, "session.id").String(), "%s", body)
+		assert.Equal(t, i.ID.String(), gjson.Get(body, "session.identity.id").String(), "%s", body)
+		assert.True(t, gjson.Get(body, "session.remembered").Bool(), "%s", body)
+		assert.True(t, s.ExpiresAt.Equal(gjson.Get(body, "session.expires_at").Time()), "%s", body)
+
+		req, err := http.NewRequest("GET", publicTS.URL+RouteWhoami, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		t.Run("case=the sessions are independent", func(t *testing.T) {
+			require.NoError(t, reg.SessionPersister().RevokeSessionByToken(context.Background(), token))
+			actual, err := reg.SessionPersister().GetSession(context.Background(), s.ID)
+			require.NoError(t, err)
+			assert.True(t, actual.Active)
+		})
+	})
+
+	t.Run("case=rejects requests without a valid anti-CSRF token", func(t *testing.T) {
+		c := testhelpers.NewHTTPClientWithSessionCookie(t, reg, NewActiveSession(i, conf, time.Now()))
+		body := exchange(t, c, `{"csrf_token":"invalid"}`, http.StatusForbidden)
+		assert.Contains(t, body, "csrf_token", "%s", body)
+		_ = exchange(t, c, `{}`, http.StatusForbidden)
+	})
+
+	t.Run("case=rejects requests without a session cookie", func(t *testing.T) {
+		_ = exchange(t, http.DefaultClient, validBody, http.StatusUnauthorized)
+	})
+
+	t.Run("case=rejects requests with a session token", func(t *testing.T) {
+		c := testhelpers.NewHTTPClientWithSessionToken(t, reg, NewActiveSession(i, conf, time.Now()))
+		body := exchange(t, c, validBody, http.StatusBadRequest)
+		assert.Contains(t, body, "Only session cookies", "%s", body)
+	})
+}
+
 func TestSessionRevoke(t *testing.T) {
 	conf, reg := internal.NewFastRegistryWithMocks(t)
 	publicTS, _ := testhelpers.NewKratosServer(t, reg)
 	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
 	i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
 	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
-	sess := NewActiveSession(i, conf, time.Now())
-	require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), sess))
 
-	sdk := testhelpers.NewSDKClient(publicTS)
-	_, err := sdk.Public.RevokeSession(public.NewRevokeSessionParams().WithBody(&models.RevokeSession{
-		SessionToken: pointerx.String(sess.Token)}))
-	require.NoError(t, err)
+	newSession := func(t *testing.T) *Session {
+		sess := NewActiveSession(i, conf, time.Now())
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), sess))
+		return sess
+	}
 
-	actual, err := reg.SessionPersister().GetSession(context.Background(), sess.ID)
-	require.NoError(t, err)
-	assert.False(t, actual.Active)
-	assert.False(t, actual.IsActive())
+	assertRevoked := func(t *testing.T, sess *Session) {
+		actual, err := reg.SessionPersister().GetSession(context.Background(), sess.ID)
+		require.NoError(t, err)
+		assert.False(t, actual.Active)
+		assert.False(t, actual.IsActive(time.Now()))
+	}
+
+	revoke := func(t *testing.T, header, value string, expectCode int) {
+		req, err := http.NewRequest("DELETE", publicTS.URL+RouteRevoke, nil)
+		require.NoError(t, err)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		res, err := publicTS.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, expectCode, res.StatusCode, "%s", raw)
+	}
+
+	t.Run("case=revokes the session token in the body", func(t *testing.T) {
+		sess := newSession(t)
+		sdk := testhelpers.NewSDKClient(publicTS)
+		_, err := sdk.Public.RevokeSession(public.NewRevokeSessionParams().WithBody(&models.RevokeSession{
+			SessionToken: pointerx.String(sess.Token)}))
+		require.NoError(t, err)
+		assertRevoked(t, sess)
+	})
+
+	t.Run("case=revokes the bearer token", func(t *testing.T) {
+		sess, other := newSession(t), newSession(t)
+		revoke(t, "Authorization", "Bearer "+sess.Token, http.StatusNoContent)
+		assertRevoked(t, sess)
+
+		actual, err := reg.SessionPersister().GetSession(context.Background(), other.ID)
+		require.NoError(t, err)
+		assert.True(t, actual.Active, "other sessions are not revoked")
+	})
+
+	t.Run("case=revokes the session token header", func(t *testing.T) {
+		sess := newSession(t)
+		revoke(t, "X-Session-Token", sess.Token, http.StatusNoContent)
+		assertRevoked(t, sess)
+	})
+
+	t.Run("case=requires a session token", func(t *testing.T) {
+		revoke(t, "", "", http.StatusBadRequest)
+	})
 }
 
 func TestIsNotAuthenticatedSecurecookie(t *testing.T) {
@@ -234,3 +509,537 @@ func TestIsAuthenticated(t *testing.T) {
 		})
 	}
 }
+
+func TestSessionIntrospect(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	_, adminTS := testhelpers.NewKratosServer(t, reg)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+
+	i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+	sess := NewActiveSession(i, conf, time.Now())
+	require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), sess))
+
+	introspect := func(t *testing.T, body string, expectedStatus int) string {
+		res, err := adminTS.Client().Post(adminTS.URL+RouteIntrospect, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, expectedStatus, res.StatusCode, "%s", raw)
+		return string(raw)
+	}
+
+	t.Run("case=requires a token or cookie", func(t *testing.T) {
+		introspect(t, `{}`, http.StatusBadRequest)
+	})
+
+	t.Run("case=introspects a session token", func(t *testing.T) {
+		body := introspect(t, fmt.Sprintf(`{"session_token":"%s"}`, sess.Token), http.StatusOK)
+		assert.True(t, gjson.Get(body, "active").Bool(), "%s", body)
+		assert.Equal(t, sess.ID.String(), gjson.Get(body, "session.id").String(), "%s", body)
+		assert.Equal(t, i.ID.String(), gjson.Get(body, "session.identity.id").String(), "%s", body)
+		assert.Equal(t, "aal1", gjson.Get(body, "aal").String(), "%s", body)
+		assert.True(t, gjson.Get(body, "expires_at").Exists(), "%s", body)
+		assert.False(t, gjson.Get(body, "session.identity.credentials").Exists(), "%s", body)
+	})
+
+	t.Run("case=introspects a session cookie", func(t *testing.T) {
+		c := testhelpers.NewHTTPClientWithSessionCookie(t, reg, NewActiveSession(i, conf, time.Now()))
+		var cookie string
+		for _, ck := range c.Jar.Cookies(urlx.ParseOrPanic(adminTS.URL)) {
+			if ck.Name == DefaultSessionCookieName {
+				cookie = ck.Value
+			}
+		}
+		require.NotEmpty(t, cookie)
+
+		body := introspect(t, fmt.Sprintf(`{"session_cookie":"%s"}`, cookie), http.StatusOK)
+		assert.True(t, gjson.Get(body, "active").Bool(), "%s", body)
+		assert.Equal(t, i.ID.String(), gjson.Get(body, "session.identity.id").String(), "%s", body)
+	})
+
+	t.Run("case=inactive sessions are not active", func(t *testing.T) {
+		assert.JSONEq(t, `{"active":false}`, introspect(t, `{"session_token":"unknown"}`, http.StatusOK))
+		assert.JSONEq(t, `{"active":false}`, introspect(t, `{"session_cookie":"invalid"}`, http.StatusOK))
+
+		require.NoError(t, reg.SessionPersister().RevokeSessionByToken(context.Background(), sess.Token))
+		assert.JSONEq(t, `{"active":false}`, introspect(t, fmt.Sprintf(`{"session_token":"%s"}`, sess.Token), http.StatusOK))
+	})
+}
+
+func TestSessionCount(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	_, adminTS := testhelpers.NewKratosServer(t, reg)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+
+	count := func(t *testing.T) int64 {
+		res, err := adminTS.Client().Get(adminTS.URL + RouteCount)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", raw)
+		return gjson.GetBytes(raw, "count").Int()
+	}
+
+	assert.EqualValues(t, 0, count(t))
+
+	i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+	active, revoked := NewActiveSession(i, conf, time.Now()), NewActiveSession(i, conf, time.Now())
+	require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), active))
+	require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), revoked))
+	require.NoError(t, reg.SessionPersister().RevokeSessionByToken(context.Background(), revoked.Token))
+
+	assert.EqualValues(t, 1, count(t))
+}
+
+func TestSessionList(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	_, adminTS := testhelpers.NewKratosServer(t, reg)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+
+	list := func(t *testing.T, query string, expectCode int) (gjson.Result, *http.Response) {
+		res, err := adminTS.Client().Get(adminTS.URL + RouteList + "?" + query)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, expectCode, res.StatusCode, "%s", raw)
+		return gjson.ParseBytes(raw), res
+	}
+
+	i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+	other := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), other))
+
+	active, revoked := NewActiveSession(i, conf, time.Now()), NewActiveSession(i, conf, time.Now())
+	active.IPAddress = "203.0.113.1"
+	revoked.IssuedAt = time.Now().UTC().Add(-time.Hour)
+	foreign := NewActiveSession(other, conf, time.Now())
+	for _, s := range []*Session{active, revoked, foreign} {
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+	}
+	require.NoError(t, reg.SessionPersister().RevokeSessionByToken(context.Background(), revoked.Token))
+
+	ids := func(res gjson.Result) (ids []string) {
+		for _, s := range res.Array() {
+			ids = append(ids, s.Get("id").String())
+		}
+		return ids
+	}
+
+	t.Run("case=lists all sessions", func(t *testing.T) {
+		res, hres := list(t, "", http.StatusOK)
+		assert.ElementsMatch(t, []string{active.ID.String(), revoked.ID.String(), foreign.ID.String()}, ids(res))
+		assert.Contains(t, hres.Header.Get("Link"), "rel=\"first\"")
+		assert.False(t, res.Get("0.identity.credentials").Exists(), "%s", res.Raw)
+		assert.NotEmpty(t, res.Get("0.identity.id").String(), "%s", res.Raw)
+	})
+
+	for _, tc := range []struct {
+		query    string
+		expected []string
+	}{
+		{query: "identity_id=" + i.ID.String(), expected: []string{active.ID.String(), revoked.ID.String()}},
+		{query: "identity_id=" + i.ID.String() + "&active=true", expected: []string{active.ID.String()}},
+		{query: "identity_id=" + i.ID.String() + "&active=false", expected: []string{revoked.ID.String()}},
+		{query: "ip_address=203.0.113.1", expected: []string{active.ID.String()}},
+		{query: "identity_id=" + i.ID.String() + "&issued_since=" + url.QueryEscape(time.Now().UTC().Add(-time.Minute).Format(time.RFC3339)), expected: []string{active.ID.String()}},
+	} {
+		t.Run("case=filters by "+tc.query, func(t *testing.T) {
+			res, _ := list(t, tc.query, http.StatusOK)
+			assert.Equal(t, tc.expected, ids(res))
+		})
+	}
+
+	t.Run("case=rejects invalid filters", func(t *testing.T) {
+		list(t, "identity_id=not-a-uuid", http.StatusBadRequest)
+		list(t, "active=maybe", http.StatusBadRequest)
+		list(t, "issued_since=yesterday", http.StatusBadRequest)
+	})
+}
+
+func TestSessionRevokeByIdentity(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	publicTS, adminTS := testhelpers.NewKratosServer(t, reg)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+
+	revoke := func(t *testing.T, id string, expectCode int) {
+		req, err := http.NewRequest("DELETE", adminTS.URL+strings.Replace(RouteIdentitySessions, ":id", id, 1), nil)
+		require.NoError(t, err)
+		res, err := adminTS.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, expectCode, res.StatusCode, "%s", raw)
+	}
+
+	whoami := func(t *testing.T, c *http.Client, header, value string) int {
+		req, err := http.NewRequest("GET", publicTS.URL+RouteWhoami, nil)
+		require.NoError(t, err)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		res, err := c.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res.StatusCode
+	}
+
+	t.Run("case=unknown identity", func(t *testing.T) {
+		revoke(t, x.NewUUID().String(), http.StatusNotFound)
+	})
+
+	t.Run("case=revokes sessions of all token kinds", func(t *testing.T) {
+		i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+		other := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), other))
+
+		bearer, header := NewActiveSession(i, conf, time.Now()), NewActiveSession(i, conf, time.Now())
+		foreign := NewActiveSession(other, conf, time.Now())
+		for _, s := range []*Session{bearer, header, foreign} {
+			require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+		}
+		cookie := testhelpers.NewHTTPClientWithSessionCookie(t, reg, NewActiveSession(i, conf, time.Now()))
+
+		assert.Equal(t, http.StatusOK, whoami(t, publicTS.Client(), "Authorization", "Bearer "+bearer.Token))
+		assert.Equal(t, http.StatusOK, whoami(t, publicTS.Client(), "X-Session-Token", header.Token))
+		assert.Equal(t, http.StatusOK, whoami(t, cookie, "", ""))
+
+		revoke(t, i.ID.String(), http.StatusNoContent)
+
+		assert.Equal(t, http.StatusUnauthorized, whoami(t, publicTS.Client(), "Authorization", "Bearer "+bearer.Token))
+		assert.Equal(t, http.StatusUnauthorized, whoami(t, publicTS.Client(), "X-Session-Token", header.Token))
+		assert.Equal(t, http.StatusUnauthorized, whoami(t, cookie, "", ""))
+		assert.Equal(t, http.StatusOK, whoami(t, publicTS.Client(), "X-Session-Token", foreign.Token), "sessions of other identities are not revoked")
+
+		// Revoking again is a no-op.
+		revoke(t, i.ID.String(), http.StatusNoContent)
+	})
+}
+
+func TestSessionAdminExtend(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	publicTS, adminTS := testhelpers.NewKratosServer(t, reg)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+	conf.MustSet(config.ViperKeySessionLifespan, "1h")
+
+	i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+	newSession := func(t *testing.T) *Session {
+		s := NewActiveSession(i, conf, time.Now())
+		s.ExpiresAt = time.Now().UTC().Add(time.Minute)
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+		return s
+	}
+
+	extend := func(t *testing.T, id, body string, expectCode int) string {
+		req, err := http.NewRequest("PATCH", adminTS.URL+strings.Replace(RouteExtend, ":id", id, 1), strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		res, err := adminTS.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, expectCode, res.StatusCode, "%s", raw)
+		return string(raw)
+	}
+
+	expiresAt := func(t *testing.T, s *Session) time.Time {
+		actual, err := reg.SessionPersister().GetSession(context.Background(), s.ID)
+		require.NoError(t, err)
+		return actual.ExpiresAt
+	}
+
+	t.Run("case=unknown session", func(t *testing.T) {
+		extend(t, x.NewUUID().String(), "", http.StatusNotFound)
+	})
+
+	t.Run("case=extends by the session lifespan by default", func(t *testing.T) {
+		s := newSession(t)
+		body := extend(t, s.ID.String(), "", http.StatusOK)
+		assert.Equal(t, s.ID.String(), gjson.Get(body, "id").String(), "%s", body)
+		assert.False(t, gjson.Get(body, "identity.credentials").Exists(), "%s", body)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), gjson.Get(body, "expires_at").Time(), time.Minute, "%s", body)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt(t, s), time.Minute)
+	})
+
+	t.Run("case=extends by the given duration", func(t *testing.T) {
+		s := newSession(t)
+		extend(t, s.ID.String(), `{"expires_in":"72h"}`, http.StatusOK)
+		assert.WithinDuration(t, time.Now().Add(72*time.Hour), expiresAt(t, s), time.Minute)
+	})
+
+	t.Run("case=sets the given expiry", func(t *testing.T) {
+		s := newSession(t)
+		at := time.Now().UTC().Add(10 * time.Minute).Round(time.Second)
+		extend(t, s.ID.String(), fmt.Sprintf(`{"expires_at":"%s"}`, at.Format(time.RFC3339)), http.StatusOK)
+		assert.WithinDuration(t, at, expiresAt(t, s), time.Second)
+
+		res, err := publicTS.Client().Do(func() *http.Request {
+			req, _ := http.NewRequest("GET", publicTS.URL+RouteWhoami, nil)
+			req.Header.Set("X-Session-Token", s.Token)
+			return req
+		}())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("case=rejects invalid requests", func(t *testing.T) {
+		s := newSession(t)
+		extend(t, s.ID.String(), `{"expires_in":"-1h"}`, http.StatusBadRequest)
+		extend(t, s.ID.String(), `{"expires_in":"tomorrow"}`, http.StatusBadRequest)
+		extend(t, s.ID.String(), fmt.Sprintf(`{"expires_at":"%s"}`, time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)), http.StatusBadRequest)
+		extend(t, s.ID.String(), fmt.Sprintf(`{"expires_at":"%s","expires_in":"1h"}`, time.Now().UTC().Add(time.Hour).Format(time.RFC3339)), http.StatusBadRequest)
+		extend(t, s.ID.String(), `not json`, http.StatusBadRequest)
+		assert.WithinDuration(t, s.ExpiresAt, expiresAt(t, s), time.Second)
+	})
+
+	t.Run("case=does not extend revoked sessions", func(t *testing.T) {
+		s := newSession(t)
+		require.NoError(t, reg.SessionPersister().RevokeSessionByToken(context.Background(), s.Token))
+		extend(t, s.ID.String(), "", http.StatusBadRequest)
+		assert.WithinDuration(t, s.ExpiresAt, expiresAt(t, s), time.Second)
+	})
+}
+
+func TestSessionListOwn(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+
+	listOwn := func(t *testing.T, token string, expectCode int) gjson.Result {
+		req, err := http.NewRequest("GET", publicTS.URL+RouteListOwn, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("X-Session-Token", token)
+		}
+		res, err := publicTS.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, expectCode, res.StatusCode, "%s", raw)
+		return gjson.ParseBytes(raw)
+	}
+
+	i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+	other := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), other))
+
+	current, phone, revoked := NewActiveSession(i, conf, time.Now()), NewActiveSession(i, conf, time.Now()), NewActiveSession(i, conf, time.Now())
+	phone.IPAddress, phone.UserAgent, phone.Location = "203.0.113.1", "Mozilla/5.0 (iPhone)", "DE"
+	phone.Browser, phone.OperatingSystem, phone.DeviceType = "Safari 14", "iOS 14.3", DeviceTypeMobile
+	foreign := NewActiveSession(other, conf, time.Now())
+	for _, s := range []*Session{current, phone, revoked, foreign} {
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+	}
+	require.NoError(t, reg.SessionPersister().RevokeSessionByToken(context.Background(), revoked.Token))
+
+	t.Run("case=requires a session", func(t *testing.T) {
+		listOwn(t, "", http.StatusUnauthorized)
+		listOwn(t, revoked.Token, http.StatusUnauthorized)
+	})
+
+	t.Run("case=lists the other active sessions of the caller", func(t *testing.T) {
+		res := listOwn(t, current.Token, http.StatusOK)
+		require.Len(t, res.Array(), 1, "%s", res.Raw)
+		assert.Equal(t, phone.ID.String(), res.Get("0.id").String(), "%s", res.Raw)
+		assert.Equal(t, "203.0.113.1", res.Get("0.ip_address").String(), "%s", res.Raw)
+		assert.Equal(t, "Mozilla/5.0 (iPhone)", res.Get("0.user_agent").String(), "%s", res.Raw)
+		assert.Equal(t, "DE", res.Get("0.location").String(), "%s", res.Raw)
+		assert.Equal(t, "Safari 14", res.Get("0.browser").String(), "%s", res.Raw)
+		assert.Equal(t, "iOS 14.3", res.Get("0.operating_system").String(), "%s", res.Raw)
+		assert.Equal(t, DeviceTypeMobile, res.Get("0.device_type").String(), "%s", res.Raw)
+		assert.False(t, res.Get("0.last_active_at").Exists(), "%s", res.Raw)
+		assert.False(t, res.Get("0.identity").Exists(), "%s", res.Raw)
+	})
+
+	t.Run("case=records the last activity", func(t *testing.T) {
+		listOwn(t, phone.Token, http.StatusOK)
+
+		res := listOwn(t, current.Token, http.StatusOK)
+		assert.WithinDuration(t, time.Now(), res.Get("0.last_active_at").Time(), time.Minute, "%s", res.Raw)
+	})
+}
+
+func TestSessionRevokeOwn(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+
+	revokeOwn := func(t *testing.T, token, id string, expectCode int) {
+		req, err := http.NewRequest("DELETE", publicTS.URL+strings.Replace(RouteRevokeOwn, ":id", id, 1), nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("X-Session-Token", token)
+		}
+		res, err := publicTS.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, expectCode, res.StatusCode, "%s", raw)
+	}
+
+	isActive := func(t *testing.T, s *Session) bool {
+		actual, err := reg.SessionPersister().GetSession(context.Background(), s.ID)
+		require.NoError(t, err)
+		return actual.Active
+	}
+
+	i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+	other := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), other))
+
+	current, phone := NewActiveSession(i, conf, time.Now()), NewActiveSession(i, conf, time.Now())
+	foreign := NewActiveSession(other, conf, time.Now())
+	for _, s := range []*Session{current, phone, foreign} {
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+	}
+
+	t.Run("case=requires a session", func(t *testing.T) {
+		revokeOwn(t, "", phone.ID.String(), http.StatusUnauthorized)
+		assert.True(t, isActive(t, phone))
+	})
+
+	t.Run("case=does not revoke the current session", func(t *testing.T) {
+		revokeOwn(t, current.Token, current.ID.String(), http.StatusBadRequest)
+		assert.True(t, isActive(t, current))
+	})
+
+	t.Run("case=does not revoke sessions of other identities", func(t *testing.T) {
+		revokeOwn(t, current.Token, foreign.ID.String(), http.StatusNotFound)
+		revokeOwn(t, current.Token, x.NewUUID().String(), http.StatusNotFound)
+		assert.True(t, isActive(t, foreign))
+	})
+
+	t.Run("case=revokes another session of the caller", func(t *testing.T) {
+		revokeOwn(t, current.Token, phone.ID.String(), http.StatusNoContent)
+		assert.False(t, isActive(t, phone))
+		assert.True(t, isActive(t, current))
+
+		req, err := http.NewRequest("GET", publicTS.URL+RouteWhoami, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Session-Token", phone.Token)
+		res, err := publicTS.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		revokeOwn(t, phone.Token, current.ID.String(), http.StatusUnauthorized)
+	})
+
+	t.Run("case=still checks the session on delete whoami", func(t *testing.T) {
+		revokeOwn(t, current.Token, "whoami", http.StatusOK)
+	})
+
+	t.Run("case=only exempts revoking sessions from the CSRF check", func(t *testing.T) {
+		conf, reg := internal.NewFastRegistryWithMocks(t)
+		csrfTS, _ := testhelpers.NewKratosServerWithCSRF(t, reg)
+		conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+
+		i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+		s, revoked := NewActiveSession(i, conf, time.Now()), NewActiveSession(i, conf, time.Now())
+		for _, s := range []*Session{s, revoked} {
+			require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+		}
+
+		for _, tc := range []struct {
+			method string
+			code   int
+		}{
+			{method: http.MethodDelete, code: http.StatusNoContent},
+			{method: http.MethodPost, code: http.StatusBadRequest},
+			{method: http.MethodPut, code: http.StatusBadRequest},
+		} {
+			t.Run("method="+tc.method, func(t *testing.T) {
+				req, err := http.NewRequest(tc.method, csrfTS.URL+strings.Replace(RouteRevokeOwn, ":id", revoked.ID.String(), 1), nil)
+				require.NoError(t, err)
+				req.Header.Set("X-Session-Token", s.Token)
+				res, err := csrfTS.Client().Do(req)
+				require.NoError(t, err)
+				defer res.Body.Close()
+				raw, err := ioutil.ReadAll(res.Body)
+				require.NoError(t, err)
+				require.Equal(t, tc.code, res.StatusCode, "%s", raw)
+				if tc.code == http.StatusBadRequest {
+					assert.Contains(t, string(raw), "CSRF token is missing or invalid")
+				}
+			})
+		}
+	})
+}
+
+// BenchmarkSessionWhoAmI measures checking a session token or cookie, which API gateways do on every request. Besides
+// the mean, it reports the 99th percentile latency.
+func BenchmarkSessionWhoAmI(b *testing.B) {
+	conf, reg := internal.NewFastRegistryWithMocks(b)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+	r := x.NewRouterPublic()
+	NewHandler(reg, conf).RegisterPublicRoutes(r)
+	// The per-request state of the cookie store and the session manager is cleared the same way `kratos serve` does.
+	ts := httptest.NewServer(gcontext.ClearHandler(r))
+	b.Cleanup(ts.Close)
+	conf.MustSet(config.ViperKeyPublicBaseURL, ts.URL)
+
+	i := &identity.Identity{Traits: identity.Traits(`{"baz":"bar"}`)}
+	require.NoError(b, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+	s := NewActiveSession(i, conf, time.Now())
+	require.NoError(b, reg.SessionPersister().CreateSession(context.Background(), s))
+
+	rec := httptest.NewRecorder()
+	require.NoError(b, reg.SessionManager().IssueCookie(context.Background(), rec, httptest.NewRequest("GET", "/", nil), s))
+	cookies := rec.Result().Cookies()
+	require.NotEmpty(b, cookies)
+
+	for _, tc := range []struct {
+		name    string
+		prepare func(req *http.Request)
+	}{
+		{name: "token", prepare: func(req *http.Request) { req.Header.Set("X-Session-Token", s.Token) }},
+		{name: "cookie", prepare: func(req *http.Request) {
+			for _, c := range cookies {
+				req.AddCookie(c)
+			}
+		}},
+	} {
+		b.Run("case="+tc.name, func(b *testing.B) {
+			req, err := http.NewRequest("GET", ts.URL+RouteWhoami, nil)
+			require.NoError(b, err)
+			tc.prepare(req)
+
+			latencies := make([]time.Duration, b.N)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for k := 0; k < b.N; k++ {
+				start := time.Now()
+				res, err := ts.Client().Do(req)
+				if err != nil {
+					b.Fatal(err)
+				}
+				_, _ = ioutil.ReadAll(res.Body)
+				_ = res.Body.Close()
+				latencies[k] = time.Since(start)
+				if res.StatusCode != http.StatusOK {
+					b.Fatalf("expected status code 200 but got %d", res.StatusCode)
+				}
+			}
+			b.StopTimer()
+
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+			b.ReportMetric(float64(latencies[(len(latencies)-1)*99/100].Nanoseconds()), "p99-ns/op")
+		})
+	}
+}
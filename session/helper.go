@@ -14,3 +14,22 @@ func bearerTokenFromRequest(r *http.Request) (string, bool) {
 
 	return "", false
 }
+
+// sessionTokenFromRequest returns the session token sent as the bearer token or in the `X-Session-Token` HTTP Header.
+func sessionTokenFromRequest(r *http.Request) (string, bool) {
+	if token, ok := bearerTokenFromRequest(r); ok {
+		return token, true
+	}
+
+	if token := r.Header.Get("X-Session-Token"); len(token) > 0 {
+		return token, true
+	}
+
+	return "", false
+}
+
+// isTokenAuthenticated returns true if the request carries a session token instead of relying on the session cookie.
+func isTokenAuthenticated(r *http.Request) bool {
+	_, ok := sessionTokenFromRequest(r)
+	return ok
+}
@@ -0,0 +1,186 @@
+package session
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+const RouteImpersonate = "/identities/:id/sessions"
+
+type (
+	impersonationHandlerDependencies interface {
+		ManagementProvider
+		PersistenceProvider
+		identity.PrivilegedPoolProvider
+		x.WriterProvider
+		x.LoggingProvider
+		x.ClockProvider
+		x.CookieProvider
+	}
+	ImpersonationHandlerProvider interface {
+		SessionImpersonationHandler() *ImpersonationHandler
+	}
+
+	// ImpersonationHandler lets administrators, for example support staff, sign in as an identity. Sessions issued
+	// this way are marked with who requested them.
+	ImpersonationHandler struct {
+		d  impersonationHandlerDependencies
+		c  *config.Provider
+		dx *decoderx.HTTP
+	}
+)
+
+func NewImpersonationHandler(d impersonationHandlerDependencies, c *config.Provider) *ImpersonationHandler {
+	return &ImpersonationHandler{d: d, c: c, dx: decoderx.NewHTTP()}
+}
+
+func (h *ImpersonationHandler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.POST(RouteImpersonate, h.impersonate)
+}
+
+// swagger:parameters impersonateIdentity
+// nolint:deadcode,unused
+type impersonateIdentityParameters struct {
+	// ID must be set to the ID of identity to impersonate.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// in: body
+	// required: true
+	Body impersonateIdentity
+}
+
+type impersonateIdentity struct {
+	// ImpersonatedBy identifies who requested the session, for example the email address of a support agent. It is
+	// returned as `impersonated_by` by `/sessions/whoami`.
+	//
+	// required: true
+	ImpersonatedBy string `json:"impersonated_by"`
+}
+
+// Impersonation Session
+//
+// swagger:model impersonationSession
+type impersonationSession struct {
+	// SessionToken is used as the `X-Session-Token` header or as the bearer token.
+	//
+	// required: true
+	SessionToken string `json:"session_token"`
+
+	// SessionCookie is the value of the `ory_kratos_session` cookie without the cookie name.
+	//
+	// required: true
+	SessionCookie string `json:"session_cookie"`
+
+	// Session is the issued session.
+	//
+	// required: true
+	Session *Session `json:"session"`
+}
+
+// swagger:route POST /identities/{id}/sessions admin impersonateIdentity
+//
+// Issue a Session to Impersonate an Identity
+//
+// Issues a session for the identity without requiring its credentials, so that for example support staff are
+// able to see what the identity sees. The session is marked with `impersonated_by`, which is returned by
+// `/sessions/whoami` so that the application is able to show a banner and audit the access. The session is
+// returned as a session token and as the value of the session cookie.
+//
+// Learn how identities work in [ORY Kratos' User And Identity Model Documentation](https://www.ory.sh/docs/next/kratos/concepts/identity-user-model).
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       201: impersonationSession
+//       400: genericError
+//       404: genericError
+//       500: genericError
+func (h *ImpersonationHandler) impersonate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var p impersonateIdentity
+	if err := h.dx.Decode(r, &p,
+		decoderx.HTTPJSONDecoder(),
+		decoderx.HTTPDecoderAllowedMethods("POST")); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	p.ImpersonatedBy = strings.TrimSpace(p.ImpersonatedBy)
+	if p.ImpersonatedBy == "" {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The impersonated_by field must be set.")))
+		return
+	}
+
+	i, err := h.d.PrivilegedIdentityPool().GetIdentity(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if !i.IsActive() || i.IsExpired(h.d.Clock().Now()) {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("Inactive or expired identities can not be impersonated.")))
+		return
+	}
+
+	s := NewActiveSession(i, h.c, h.d.Clock().Now())
+	s.ImpersonatedBy = sqlxx.NullString(p.ImpersonatedBy)
+	if err := h.d.SessionPersister().CreateSession(r.Context(), s); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	cookie, err := h.encodeCookie(s)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Audit().
+		WithRequest(r).
+		WithField("identity_id", i.ID).
+		WithField("session_id", s.ID).
+		WithSensitiveField("impersonated_by", p.ImpersonatedBy).
+		Info("An administrator issued a session to impersonate an identity.")
+
+	s.Declassify()
+	h.d.Writer().WriteCode(w, r, http.StatusCreated, &impersonationSession{
+		SessionToken:  s.Token,
+		SessionCookie: cookie,
+		Session:       s,
+	})
+}
+
+// encodeCookie returns the value of the session cookie the public API would set for the session.
+func (h *ImpersonationHandler) encodeCookie(s *Session) (string, error) {
+	store, ok := h.d.CookieManager().(*sessions.CookieStore)
+	if !ok {
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to encode the session cookie."))
+	}
+
+	cookie, err := securecookie.EncodeMulti(DefaultSessionCookieName, map[interface{}]interface{}{"session_token": s.Token}, store.Codecs...)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return cookie, nil
+}
@@ -0,0 +1,103 @@
+package session_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	. "github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+func TestSessionImpersonation(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	publicTS, adminTS := testhelpers.NewKratosServer(t, reg)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+
+	i := &identity.Identity{Traits: identity.Traits(`{"email":"impersonated@ory.sh"}`)}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+	impersonate := func(t *testing.T, id, body string, expectedStatus int) string {
+		res, err := adminTS.Client().Post(adminTS.URL+strings.Replace(RouteImpersonate, ":id", id, 1), "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, expectedStatus, res.StatusCode, "%s", raw)
+		return string(raw)
+	}
+
+	whoami := func(t *testing.T, set func(r *http.Request)) string {
+		req, err := http.NewRequest("GET", publicTS.URL+RouteWhoami, nil)
+		require.NoError(t, err)
+		set(req)
+		res, err := publicTS.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", raw)
+		return string(raw)
+	}
+
+	t.Run("case=rejects invalid requests", func(t *testing.T) {
+		impersonate(t, i.ID.String(), `{}`, http.StatusBadRequest)
+		impersonate(t, i.ID.String(), `{"impersonated_by":"  "}`, http.StatusBadRequest)
+		impersonate(t, x.NewUUID().String(), `{"impersonated_by":"support@ory.sh"}`, http.StatusNotFound)
+	})
+
+	t.Run("case=issues a marked session", func(t *testing.T) {
+		body := impersonate(t, i.ID.String(), `{"impersonated_by":"support@ory.sh"}`, http.StatusCreated)
+		assert.Equal(t, "support@ory.sh", gjson.Get(body, "session.impersonated_by").String(), "%s", body)
+		assert.Equal(t, i.ID.String(), gjson.Get(body, "session.identity.id").String(), "%s", body)
+		assert.False(t, gjson.Get(body, "session.identity.credentials").Exists(), "%s", body)
+
+		token := gjson.Get(body, "session_token").String()
+		require.NotEmpty(t, token)
+		cookie := gjson.Get(body, "session_cookie").String()
+		require.NotEmpty(t, cookie)
+
+		t.Run("using the token", func(t *testing.T) {
+			actual := whoami(t, func(r *http.Request) {
+				r.Header.Set("X-Session-Token", token)
+			})
+			assert.Equal(t, i.ID.String(), gjson.Get(actual, "identity.id").String(), "%s", actual)
+			assert.Equal(t, "support@ory.sh", gjson.Get(actual, "impersonated_by").String(), "%s", actual)
+		})
+
+		t.Run("using the cookie", func(t *testing.T) {
+			actual := whoami(t, func(r *http.Request) {
+				r.AddCookie(&http.Cookie{Name: DefaultSessionCookieName, Value: cookie})
+			})
+			assert.Equal(t, "support@ory.sh", gjson.Get(actual, "impersonated_by").String(), "%s", actual)
+		})
+	})
+
+	t.Run("case=regular sessions are not marked", func(t *testing.T) {
+		s := NewActiveSession(i, conf, time.Now())
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+
+		actual := whoami(t, func(r *http.Request) {
+			r.Header.Set("X-Session-Token", s.Token)
+		})
+		assert.False(t, gjson.Get(actual, "impersonated_by").Exists(), "%s", actual)
+	})
+
+	t.Run("case=inactive identities can not be impersonated", func(t *testing.T) {
+		inactive := &identity.Identity{Traits: identity.Traits(fmt.Sprintf(`{"email":"%s@ory.sh"}`, x.NewUUID())), State: identity.StateInactive}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), inactive))
+		impersonate(t, inactive.ID.String(), `{"impersonated_by":"support@ory.sh"}`, http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+// janitorBatchSize is the number of expired sessions revoked at once.
+const janitorBatchSize = 500
+
+type (
+	janitorDependencies interface {
+		x.LoggingProvider
+		PersistenceProvider
+		x.ClockProvider
+		BackChannelNotifierProvider
+	}
+	JanitorProvider interface {
+		SessionJanitor() *Janitor
+	}
+
+	// Janitor revokes expired sessions and the sessions of identities which have expired, and deletes expired
+	// disclosure tokens. Expired sessions, expired identities, and expired disclosure tokens are rejected even if
+	// the janitor did not run yet. The janitor makes sure that the back-channel logout URLs are notified.
+	Janitor struct {
+		d janitorDependencies
+		c *config.Provider
+	}
+)
+
+func NewJanitor(d janitorDependencies, c *config.Provider) *Janitor {
+	return &Janitor{d: d, c: c}
+}
+
+// RevokeExpired revokes the active sessions of all identities which have expired and records an
+// `identity_expired` event in the audit log for each of them.
+func (j *Janitor) RevokeExpired(ctx context.Context) error {
+	is, err := j.d.SessionPersister().ListExpiredIdentitiesWithActiveSessions(ctx, j.d.Clock().Now())
+	if err != nil {
+		return err
+	}
+
+	for _, i := range is {
+		count, err := j.d.SessionPersister().RevokeSessionsByIdentity(ctx, i.ID)
+		if err != nil {
+			return err
+		}
+
+		j.d.Audit().
+			WithField("event", "identity_expired").
+			WithField("identity_id", i.ID).
+			WithField("expires_at", i.ExpiresAt).
+			WithField("revoked_sessions", count).
+			Info("The sessions of an expired identity were revoked.")
+		j.d.SessionBackChannelNotifier().IdentitySessionsRevoked(i.ID, RevocationReasonIdentityExpired)
+	}
+
+	return nil
+}
+
+// RevokeExpiredSessions revokes the active sessions which have expired and notifies the back-channel logout URLs
+// with the reason `session_expired`.
+func (j *Janitor) RevokeExpiredSessions(ctx context.Context) error {
+	for {
+		ss, err := j.d.SessionPersister().RevokeExpiredSessions(ctx, j.d.Clock().Now(), janitorBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for k := range ss {
+			j.d.SessionBackChannelNotifier().SessionRevoked(&ss[k], RevocationReasonSessionExpired)
+		}
+
+		if len(ss) > 0 {
+			j.d.Logger().WithField("revoked_sessions", len(ss)).Debug("Revoked expired sessions.")
+		}
+		if len(ss) < janitorBatchSize {
+			return nil
+		}
+	}
+}
+
+// DeleteExpiredDisclosures deletes the disclosure tokens which have expired.
+func (j *Janitor) DeleteExpiredDisclosures(ctx context.Context) error {
+	count, err := j.d.SessionPersister().DeleteExpiredSessionDisclosures(ctx, j.d.Clock().Now())
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		j.d.Logger().WithField("deleted_disclosures", count).Debug("Deleted expired session disclosures.")
+	}
+	return nil
+}
+
+// Watch revokes expired sessions and the sessions of expired identities, and deletes expired disclosure tokens
+// until the context is canceled.
+func (j *Janitor) Watch(ctx context.Context) {
+	for {
+		if err := j.RevokeExpired(ctx); err != nil {
+			j.d.Logger().WithError(err).Error("Unable to revoke the sessions of expired identities.")
+		}
+
+		if err := j.RevokeExpiredSessions(ctx); err != nil {
+			j.d.Logger().WithError(err).Error("Unable to revoke expired sessions.")
+		}
+
+		if err := j.DeleteExpiredDisclosures(ctx); err != nil {
+			j.d.Logger().WithError(err).Error("Unable to delete expired session disclosures.")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(j.c.SessionJanitorInterval()):
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package session_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+func TestJanitor(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/identity.schema.json")
+
+	newSession := func(t *testing.T, expiresAt *time.Time) *session.Session {
+		i := identity.Identity{Traits: []byte("{}"), ExpiresAt: expiresAt}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+		s := session.NewActiveSession(&i, conf, time.Now())
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+		return s
+	}
+
+	isActive := func(t *testing.T, s *session.Session) bool {
+		actual, err := reg.SessionPersister().GetSession(context.Background(), s.ID)
+		require.NoError(t, err)
+		return actual.Active
+	}
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	expired := newSession(t, &past)
+	temporary := newSession(t, &future)
+	permanent := newSession(t, nil)
+
+	require.NoError(t, reg.SessionJanitor().RevokeExpired(context.Background()))
+	assert.False(t, isActive(t, expired))
+	assert.True(t, isActive(t, temporary))
+	assert.True(t, isActive(t, permanent))
+
+	// Running the janitor again is a no-op.
+	require.NoError(t, reg.SessionJanitor().RevokeExpired(context.Background()))
+	assert.False(t, isActive(t, expired))
+
+	t.Run("case=revokes expired sessions", func(t *testing.T) {
+		s := newSession(t, nil)
+		require.NoError(t, reg.SessionPersister().UpdateSessionExpiry(context.Background(), s.ID, time.Now().Add(-time.Minute)))
+
+		require.NoError(t, reg.SessionJanitor().RevokeExpiredSessions(context.Background()))
+		assert.False(t, isActive(t, s))
+		assert.True(t, isActive(t, permanent))
+	})
+
+	t.Run("case=uses the clock of the registry", func(t *testing.T) {
+		clock := new(x.TestClock)
+		reg.WithClock(clock)
+		t.Cleanup(func() { reg.WithClock(x.SystemClock{}) })
+
+		clock.Advance(2 * time.Hour)
+		require.NoError(t, reg.SessionJanitor().RevokeExpired(context.Background()))
+		assert.False(t, isActive(t, temporary))
+		assert.True(t, isActive(t, permanent))
+	})
+}
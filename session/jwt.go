@@ -0,0 +1,150 @@
+package session
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/fetcher"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+var ErrJWTNotConfigured = herodot.ErrNotFound.WithReason("Session JWTs are disabled. Set `session.jwt.jwks_url` to enable them.")
+
+type (
+	jwtSignerDependencies interface {
+		x.ClockProvider
+	}
+	JWTSignerProvider interface {
+		SessionJWTSigner() *JWTSigner
+	}
+
+	// JWTSigner signs short-lived JSON Web Tokens representing sessions. Services can verify these tokens using
+	// the published keys without asking ORY Kratos.
+	JWTSigner struct {
+		d jwtSignerDependencies
+		c *config.Provider
+		f *fetcher.Fetcher
+
+		sync.Mutex
+		keysURL string
+		keys    *jose.JSONWebKeySet
+	}
+
+	// JWTClaims are the claims of a session JWT.
+	JWTClaims struct {
+		jwt.Claims
+
+		// SessionID is the ID of the session.
+		SessionID string `json:"sid"`
+
+		// AuthenticatedAt is the time the session was authenticated at.
+		AuthenticatedAt *jwt.NumericDate `json:"auth_time"`
+	}
+)
+
+func NewJWTSigner(d jwtSignerDependencies, c *config.Provider) *JWTSigner {
+	return &JWTSigner{d: d, c: c, f: fetcher.NewFetcher()}
+}
+
+// Sign returns a JWT representing the session and its expiry. The JWT never outlives the session.
+func (s *JWTSigner) Sign(session *Session) (string, time.Time, error) {
+	keys, err := s.signingKeys()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	key := keys.Keys[0]
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.SignatureAlgorithm(key.Algorithm), Key: &key},
+		new(jose.SignerOptions).WithType("JWT"),
+	)
+	if err != nil {
+		return "", time.Time{}, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to create the session JWT signer: %s", err))
+	}
+
+	now := s.d.Clock().Now()
+	expiresAt := now.Add(s.c.SessionJWTLifespan())
+	if session.ExpiresAt.Before(expiresAt) {
+		expiresAt = session.ExpiresAt
+	}
+
+	token, err := jwt.Signed(signer).Claims(&JWTClaims{
+		Claims: jwt.Claims{
+			ID:        x.NewUUID().String(),
+			Issuer:    s.c.SelfPublicURL().String(),
+			Subject:   session.IdentityID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Expiry:    jwt.NewNumericDate(expiresAt),
+		},
+		SessionID:       session.ID.String(),
+		AuthenticatedAt: jwt.NewNumericDate(session.AuthenticatedAt),
+	}).CompactSerialize()
+	if err != nil {
+		return "", time.Time{}, errors.WithStack(err)
+	}
+
+	return token, expiresAt, nil
+}
+
+// PublicKeys returns the keys for verifying session JWTs.
+func (s *JWTSigner) PublicKeys() (*jose.JSONWebKeySet, error) {
+	keys, err := s.signingKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	public := &jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, len(keys.Keys))}
+	for k, key := range keys.Keys {
+		public.Keys[k] = key.Public()
+	}
+	return public, nil
+}
+
+func (s *JWTSigner) signingKeys() (*jose.JSONWebKeySet, error) {
+	source := s.c.SessionJWTJWKSURL()
+	if source == "" {
+		return nil, errors.WithStack(ErrJWTNotConfigured)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.keys != nil && s.keysURL == source {
+		return s.keys, nil
+	}
+
+	raw, err := s.f.Fetch(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := json.NewDecoder(raw).Decode(&keys); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode the session JWT signing keys: %s", err))
+	}
+
+	if len(keys.Keys) == 0 {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("The session JWT signing keys are empty."))
+	}
+
+	for _, key := range keys.Keys {
+		if _, symmetric := key.Key.([]byte); symmetric {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The session JWT signing key %q must be an asymmetric key because its public key is published.", key.KeyID))
+		}
+		if key.IsPublic() || key.Algorithm == "" || key.KeyID == "" {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The session JWT signing key %q must be a private key and set alg and kid.", key.KeyID))
+		}
+	}
+
+	s.keys, s.keysURL = &keys, source
+	return s.keys, nil
+}
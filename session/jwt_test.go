@@ -0,0 +1,86 @@
+package session_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/session"
+)
+
+func TestSessionJWT(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://stub/identity.schema.json")
+	publicTS, _ := testhelpers.NewKratosServer(t, reg)
+
+	get := func(t *testing.T, c *http.Client, path string, expectedStatus int, out interface{}) {
+		res, err := c.Get(publicTS.URL + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, expectedStatus, res.StatusCode)
+		if out != nil {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(out))
+		}
+	}
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	c := testhelpers.NewHTTPClientWithIdentitySessionToken(t, reg, i)
+
+	t.Run("case=is disabled per default", func(t *testing.T) {
+		get(t, c, session.RouteToken, http.StatusNotFound, nil)
+		get(t, http.DefaultClient, session.RouteJWKS, http.StatusNotFound, nil)
+	})
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keys, err := json.Marshal(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key, KeyID: "session", Algorithm: "RS256", Use: "sig"}}})
+	require.NoError(t, err)
+	conf.MustSet(config.ViperKeySessionJWTJWKSURL, "base64://"+base64.StdEncoding.EncodeToString(keys))
+
+	t.Run("case=requires a session", func(t *testing.T) {
+		get(t, http.DefaultClient, session.RouteToken, http.StatusUnauthorized, nil)
+	})
+
+	t.Run("case=issues a verifiable token", func(t *testing.T) {
+		var res struct {
+			Token     string    `json:"token"`
+			ExpiresAt time.Time `json:"expires_at"`
+		}
+		get(t, c, session.RouteToken, http.StatusOK, &res)
+		assert.WithinDuration(t, time.Now().Add(conf.SessionJWTLifespan()), res.ExpiresAt, time.Minute)
+
+		var published jose.JSONWebKeySet
+		get(t, http.DefaultClient, session.RouteJWKS, http.StatusOK, &published)
+		require.Len(t, published.Keys, 1)
+		assert.True(t, published.Keys[0].IsPublic())
+
+		token, err := jwt.ParseSigned(res.Token)
+		require.NoError(t, err)
+
+		var claims session.JWTClaims
+		require.NoError(t, token.Claims(published.Key("session")[0].Key, &claims))
+		require.NoError(t, claims.Validate(jwt.Expected{Issuer: conf.SelfPublicURL().String(), Time: time.Now()}))
+		assert.Equal(t, i.ID.String(), claims.Subject)
+		assert.NotEmpty(t, claims.SessionID)
+	})
+
+	t.Run("case=rejects symmetric keys", func(t *testing.T) {
+		keys, err := json.Marshal(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: []byte("secret"), KeyID: "symmetric", Algorithm: "HS256"}}})
+		require.NoError(t, err)
+		conf.MustSet(config.ViperKeySessionJWTJWKSURL, "base64://"+base64.StdEncoding.EncodeToString(keys))
+		get(t, http.DefaultClient, session.RouteJWKS, http.StatusInternalServerError, nil)
+	})
+}
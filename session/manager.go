@@ -27,6 +27,11 @@ type Manager interface {
 	// Also regenerates CSRF tokens due to assumed principal change.
 	IssueCookie(context.Context, http.ResponseWriter, *http.Request, *Session) error
 
+	// RotateSession replaces the session by a new one with a new ID and token and revokes it, which defeats session
+	// fixation once the privileges of the session changed. The session cookie is reissued unless the request was
+	// authenticated using a session token. The session is returned unchanged if session rotation is disabled.
+	RotateSession(context.Context, http.ResponseWriter, *http.Request, *Session) (*Session, error)
+
 	// FetchFromRequest creates an HTTP session using cookies.
 	FetchFromRequest(context.Context, *http.Request) (*Session, error)
 
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/urfave/negroni"
 
 	"github.com/ory/x/sqlcon"
 
@@ -15,20 +16,61 @@ import (
 	"github.com/ory/kratos/x"
 )
 
+// lastActiveInterval is how often the time a session was last used is updated.
+const lastActiveInterval = time.Minute
+
+// resolvedSessionKey stores the session resolved for a request in the request context, which releases it together
+// with the request.
+const resolvedSessionKey resolvedSessionContextKey = 0
+
+type (
+	resolvedSessionContextKey int
+
+	resolvedSession struct {
+		token   string
+		session *Session
+	}
+)
+
+// CacheResolvedSessions lets the session manager reuse the session it resolved for a request when it is asked for
+// the session of the same request again. Requests which did not pass this middleware are always resolved from the
+// store.
+var CacheResolvedSessions negroni.HandlerFunc = func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	next(rw, r.WithContext(context.WithValue(r.Context(), resolvedSessionKey, new(resolvedSession))))
+}
+
+func resolvedSessionFromRequest(r *http.Request) *resolvedSession {
+	cached, _ := r.Context().Value(resolvedSessionKey).(*resolvedSession)
+	return cached
+}
+
+func forgetResolvedSession(r *http.Request) {
+	if cached := resolvedSessionFromRequest(r); cached != nil {
+		*cached = resolvedSession{}
+	}
+}
+
 type (
 	managerHTTPDependencies interface {
 		PersistenceProvider
 		x.CookieProvider
 		identity.PoolProvider
 		x.CSRFProvider
+		x.ClockProvider
+		BackChannelNotifierProvider
 	}
 	managerHTTPConfiguration interface {
 		SessionPersistentCookie() bool
 		SessionLifespan() time.Duration
+		SessionRememberMeEnabled() bool
+		SessionRememberMeLifespan() time.Duration
+		SessionSlidingExpirationEnabled() bool
+		SessionMaxLifespan() time.Duration
 		SecretsSession() [][]byte
 		SessionSameSiteMode() http.SameSite
 		SessionDomain() string
 		SessionPath() string
+		SessionRotationEnabled() bool
 	}
 	ManagerHTTP struct {
 		c          managerHTTPConfiguration
@@ -83,24 +125,64 @@ func (s *ManagerHTTP) IssueCookie(ctx context.Context, w http.ResponseWriter, r
 		cookie.Options.SameSite = s.c.SessionSameSiteMode()
 	}
 
+	persistent := s.c.SessionPersistentCookie()
+	if s.c.SessionRememberMeEnabled() {
+		// Users choose whether the cookie outlives the browser session.
+		persistent = session.Remembered
+	}
+
 	cookie.Options.MaxAge = 0
-	if s.c.SessionPersistentCookie() {
-		cookie.Options.MaxAge = int(s.c.SessionLifespan().Seconds())
+	if persistent {
+		cookie.Options.MaxAge = int(session.Lifespan(s.c).Seconds())
+		if s.c.SessionSlidingExpirationEnabled() {
+			// The cookie is not renewed when the session is extended.
+			cookie.Options.MaxAge = int(s.c.SessionMaxLifespan().Seconds())
+		}
 	}
 
 	cookie.Values["session_token"] = session.Token
 	if err := cookie.Save(r, w); err != nil {
 		return errors.WithStack(err)
 	}
+
+	// The session the cookie held before would otherwise stay active even though the browser no longer uses it, for
+	// example a session planted by an attacker.
+	if old != nil && old.ID != session.ID && !isTokenAuthenticated(r) && s.c.SessionRotationEnabled() {
+		forgetResolvedSession(r)
+		if err := s.r.SessionPersister().RevokeSessionByToken(ctx, old.Token); err != nil {
+			return errors.WithStack(err)
+		}
+	}
 	return nil
 }
 
-func (s *ManagerHTTP) extractToken(r *http.Request) string {
-	if token, ok := bearerTokenFromRequest(r); ok {
-		return token
+func (s *ManagerHTTP) RotateSession(ctx context.Context, w http.ResponseWriter, r *http.Request, session *Session) (*Session, error) {
+	if !s.c.SessionRotationEnabled() {
+		return session, nil
 	}
 
-	if token := r.Header.Get("X-Session-Token"); len(token) > 0 {
+	rotated := session.rotate(s.r.Clock().Now())
+	if err := s.r.SessionPersister().CreateSession(ctx, rotated); err != nil {
+		return nil, err
+	}
+
+	if isTokenAuthenticated(r) {
+		if err := s.r.SessionPersister().RevokeSessionByToken(ctx, session.Token); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		forgetResolvedSession(r)
+		return rotated, nil
+	}
+
+	// Issuing the cookie revokes the session it replaces.
+	if err := s.IssueCookie(ctx, w, r, rotated); err != nil {
+		return nil, err
+	}
+	return rotated, nil
+}
+
+func (s *ManagerHTTP) extractToken(r *http.Request) string {
+	if token, ok := sessionTokenFromRequest(r); ok {
 		return token
 	}
 
@@ -123,6 +205,13 @@ func (s *ManagerHTTP) FetchFromRequest(ctx context.Context, r *http.Request) (*S
 		return nil, errors.WithStack(ErrNoActiveSessionFound)
 	}
 
+	// Middleware and handlers often resolve the session of the same request several times, which would otherwise
+	// query the store every time.
+	cached := resolvedSessionFromRequest(r)
+	if cached != nil && cached.session != nil && cached.token == token {
+		return cached.session.copy(), nil
+	}
+
 	se, err := s.r.SessionPersister().GetSessionByToken(ctx, token)
 	if err != nil {
 		if errors.Is(err, herodot.ErrNotFound) || errors.Is(err, sqlcon.ErrNoRows) {
@@ -131,17 +220,37 @@ func (s *ManagerHTTP) FetchFromRequest(ctx context.Context, r *http.Request) (*S
 		return nil, err
 	}
 
-	if !se.IsActive() {
+	now := s.r.Clock().Now()
+	if !se.IsActive(now) {
+		return nil, errors.WithStack(ErrNoActiveSessionFound)
+	}
+
+	// Sessions of deactivated or expired identities are rejected even if they were issued before and were not
+	// revoked yet.
+	if se.Identity != nil && (!se.Identity.IsActive() || se.Identity.IsExpired(now)) {
 		return nil, errors.WithStack(ErrNoActiveSessionFound)
 	}
 
-	se.Identity = se.Identity.CopyWithoutCredentials()
-	return se, nil
+	// The last activity is only recorded once a minute so that checking sessions, which API gateways do on every
+	// request, does not write to the database every time.
+	if se.LastActiveAt == nil || now.Sub(*se.LastActiveAt) >= lastActiveInterval {
+		if err := s.r.SessionPersister().UpdateSessionLastActive(ctx, se.ID, now); err != nil {
+			return nil, err
+		}
+		se.LastActiveAt = &now
+	}
+
+	if cached != nil {
+		*cached = resolvedSession{token: token, session: se}
+	}
+	return se.copy(), nil
 }
 
 func (s *ManagerHTTP) PurgeFromRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	if token, ok := bearerTokenFromRequest(r); ok {
-		return errors.WithStack(s.r.SessionPersister().RevokeSessionByToken(ctx, token))
+	forgetResolvedSession(r)
+
+	if token, ok := sessionTokenFromRequest(r); ok {
+		return errors.WithStack(s.r.SessionBackChannelNotifier().RevokeSessionByToken(ctx, token, RevocationReasonLogout))
 	}
 
 	cookie, _ := s.r.CookieManager().Get(r, s.cookieName)
@@ -150,7 +259,7 @@ func (s *ManagerHTTP) PurgeFromRequest(ctx context.Context, w http.ResponseWrite
 		return nil
 	}
 
-	if err := s.r.SessionPersister().RevokeSessionByToken(ctx, token); err != nil {
+	if err := s.r.SessionBackChannelNotifier().RevokeSessionByToken(ctx, token, RevocationReasonLogout); err != nil {
 		return errors.WithStack(err)
 	}
 
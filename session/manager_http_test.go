@@ -2,12 +2,14 @@ package session_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/gofrs/uuid"
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -32,6 +34,12 @@ func (f *mockCSRFHandler) ExemptPath(s string) {
 func (f *mockCSRFHandler) IgnorePath(s string) {
 }
 
+func (f *mockCSRFHandler) ExemptGlob(s string) {
+}
+
+func (f *mockCSRFHandler) ExemptFunc(fn func(r *http.Request) bool) {
+}
+
 func (f *mockCSRFHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
@@ -50,6 +58,93 @@ func TestManagerHTTP(t *testing.T) {
 		assert.Equal(t, 1, mock.c)
 	})
 
+	t.Run("case=remember me controls whether the cookie is persistent", func(t *testing.T) {
+		conf, reg := internal.NewFastRegistryWithMocks(t)
+		conf.MustSet(config.ViperKeySessionPersistentCookie, true)
+		conf.MustSet(config.ViperKeySessionLifespan, "1h")
+		conf.MustSet(config.ViperKeySessionRememberMeLifespan, "720h")
+
+		maxAge := func(t *testing.T, s *session.Session) int {
+			w := httptest.NewRecorder()
+			require.NoError(t, reg.SessionManager().IssueCookie(context.Background(), w, httptest.NewRequest("GET", "/", nil), s))
+			require.Len(t, w.Result().Cookies(), 1)
+			return w.Result().Cookies()[0].MaxAge
+		}
+
+		i := &identity.Identity{ID: x.NewUUID()}
+		s := session.NewActiveSession(i, conf, time.Now())
+		assert.Equal(t, int(time.Hour.Seconds()), maxAge(t, s), "without remember me the persistent cookie setting applies")
+
+		conf.MustSet(config.ViperKeySessionRememberMeEnabled, true)
+		assert.Equal(t, 0, maxAge(t, s), "sessions which are not remembered end with the browser session")
+
+		s.Remember(conf)
+		assert.Equal(t, int((720 * time.Hour).Seconds()), maxAge(t, s))
+	})
+
+	t.Run("case=records the last activity once a minute", func(t *testing.T) {
+		conf, reg := internal.NewFastRegistryWithMocks(t)
+		conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/fake-session.schema.json")
+		clock := new(x.TestClock)
+		reg.WithClock(clock)
+
+		i := identity.Identity{Traits: []byte("{}")}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+		s := session.NewActiveSession(&i, conf, clock.Now())
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+
+		lastActive := func(t *testing.T) time.Time {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-Session-Token", s.Token)
+			_, err := reg.SessionManager().FetchFromRequest(context.Background(), req)
+			require.NoError(t, err)
+
+			actual, err := reg.SessionPersister().GetSession(context.Background(), s.ID)
+			require.NoError(t, err)
+			require.NotNil(t, actual.LastActiveAt)
+			return *actual.LastActiveAt
+		}
+
+		first := lastActive(t)
+		assert.WithinDuration(t, clock.Now(), first, time.Second)
+
+		clock.Advance(30 * time.Second)
+		assert.Equal(t, first.Unix(), lastActive(t).Unix(), "the last activity is not updated within a minute")
+
+		clock.Advance(time.Minute)
+		assert.WithinDuration(t, clock.Now(), lastActive(t), time.Second)
+	})
+
+	t.Run("case=resolves the session once per request", func(t *testing.T) {
+		conf, reg := internal.NewFastRegistryWithMocks(t)
+		conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/fake-session.schema.json")
+
+		i := identity.Identity{Traits: []byte("{}")}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+		s := session.NewActiveSession(&i, conf, time.Now())
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Session-Token", s.Token)
+		var served bool
+		session.CacheResolvedSessions(httptest.NewRecorder(), req, func(_ http.ResponseWriter, r *http.Request) {
+			served = true
+			first, err := reg.SessionManager().FetchFromRequest(context.Background(), r)
+			require.NoError(t, err)
+			first.Identity.Traits = identity.Traits(`{"changed":true}`)
+
+			require.NoError(t, reg.SessionPersister().RevokeSessionByToken(context.Background(), s.Token))
+			second, err := reg.SessionManager().FetchFromRequest(context.Background(), r)
+			require.NoError(t, err, "the session resolved before is reused within the same request")
+			assert.Equal(t, s.ID, second.ID)
+			assert.JSONEq(t, "{}", string(second.Identity.Traits), "changes to a resolved session do not leak into the next lookup")
+		})
+		require.True(t, served)
+
+		_, err := reg.SessionManager().FetchFromRequest(context.Background(), req)
+		assert.True(t, errors.Is(err, session.ErrNoActiveSessionFound), "requests which did not pass the middleware are resolved from the store: %+v", err)
+	})
+
 	t.Run("suite=lifecycle", func(t *testing.T) {
 		conf, reg := internal.NewFastRegistryWithMocks(t)
 		conf.MustSet(config.ViperKeySelfServiceLoginUI, "https://www.ory.sh")
@@ -67,6 +162,14 @@ func TestManagerHTTP(t *testing.T) {
 			w.WriteHeader(http.StatusOK)
 		})
 
+		rp.GET("/session/rotate", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+			sess, err := reg.SessionManager().FetchFromRequest(r.Context(), r)
+			require.NoError(t, err)
+			rotated, err := reg.SessionManager().RotateSession(r.Context(), w, r, sess)
+			require.NoError(t, err)
+			reg.Writer().Write(w, r, rotated)
+		})
+
 		rp.GET("/session/get", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 			sess, err := reg.SessionManager().FetchFromRequest(r.Context(), r)
 			if err != nil {
@@ -117,6 +220,64 @@ func TestManagerHTTP(t *testing.T) {
 			assert.EqualValues(t, http.StatusUnauthorized, res.StatusCode)
 		})
 
+		t.Run("case=expired after advancing the test clock", func(t *testing.T) {
+			clock := new(x.TestClock)
+			reg.WithClock(clock)
+			t.Cleanup(func() {
+				reg.WithClock(new(x.TestClock))
+			})
+
+			i := identity.Identity{Traits: []byte("{}")}
+			require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+			s = session.NewActiveSession(&i, conf, clock.Now())
+
+			c := testhelpers.NewClientWithCookies(t)
+			testhelpers.MockHydrateCookieClient(t, c, pts.URL+"/session/set")
+
+			res, err := c.Get(pts.URL + "/session/get")
+			require.NoError(t, err)
+			assert.EqualValues(t, http.StatusOK, res.StatusCode)
+
+			clock.Advance(2 * time.Minute)
+			res, err = c.Get(pts.URL + "/session/get")
+			require.NoError(t, err)
+			assert.EqualValues(t, http.StatusUnauthorized, res.StatusCode)
+		})
+
+		t.Run("case=inactive identity", func(t *testing.T) {
+			i := identity.Identity{Traits: []byte("{}")}
+			require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+			s = session.NewActiveSession(&i, conf, time.Now())
+
+			c := testhelpers.NewClientWithCookies(t)
+			testhelpers.MockHydrateCookieClient(t, c, pts.URL+"/session/set")
+
+			require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentityState(context.Background(), i.ID, identity.StateInactive))
+			res, err := c.Get(pts.URL + "/session/get")
+			require.NoError(t, err)
+			assert.EqualValues(t, http.StatusUnauthorized, res.StatusCode)
+
+			require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentityState(context.Background(), i.ID, identity.StateActive))
+			res, err = c.Get(pts.URL + "/session/get")
+			require.NoError(t, err)
+			assert.EqualValues(t, http.StatusOK, res.StatusCode, "reactivating the identity restores its sessions")
+		})
+
+		t.Run("case=expired identity", func(t *testing.T) {
+			i := identity.Identity{Traits: []byte("{}")}
+			require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+			s = session.NewActiveSession(&i, conf, time.Now())
+
+			c := testhelpers.NewClientWithCookies(t)
+			testhelpers.MockHydrateCookieClient(t, c, pts.URL+"/session/set")
+
+			expiresAt := time.Now().Add(-time.Second)
+			require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentityExpiry(context.Background(), i.ID, &expiresAt))
+			res, err := c.Get(pts.URL + "/session/get")
+			require.NoError(t, err)
+			assert.EqualValues(t, http.StatusUnauthorized, res.StatusCode)
+		})
+
 		t.Run("case=revoked", func(t *testing.T) {
 			i := identity.Identity{Traits: []byte("{}")}
 			require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
@@ -135,5 +296,105 @@ func TestManagerHTTP(t *testing.T) {
 			require.NoError(t, err)
 			assert.EqualValues(t, http.StatusUnauthorized, res.StatusCode)
 		})
+
+		getID := func(t *testing.T, c *http.Client, path string) uuid.UUID {
+			res, err := c.Get(pts.URL + path)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.EqualValues(t, http.StatusOK, res.StatusCode)
+
+			var actual session.Session
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+			return actual.ID
+		}
+
+		isActive := func(t *testing.T, id uuid.UUID) bool {
+			actual, err := reg.SessionPersister().GetSession(context.Background(), id)
+			require.NoError(t, err)
+			return actual.Active
+		}
+
+		t.Run("case=rotated", func(t *testing.T) {
+			i := identity.Identity{Traits: []byte("{}")}
+			require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+			s = session.NewActiveSession(&i, conf, time.Now())
+
+			c := testhelpers.NewClientWithCookies(t)
+			testhelpers.MockHydrateCookieClient(t, c, pts.URL+"/session/set")
+
+			rotated := getID(t, c, "/session/rotate")
+			assert.NotEqual(t, s.ID, rotated)
+			assert.Equal(t, rotated, getID(t, c, "/session/get"), "the cookie holds the rotated session")
+			assert.False(t, isActive(t, s.ID))
+		})
+
+		t.Run("case=replaced session is revoked", func(t *testing.T) {
+			i := identity.Identity{Traits: []byte("{}")}
+			require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+			replaced := session.NewActiveSession(&i, conf, time.Now())
+			s = replaced
+
+			c := testhelpers.NewClientWithCookies(t)
+			testhelpers.MockHydrateCookieClient(t, c, pts.URL+"/session/set")
+
+			s = session.NewActiveSession(&i, conf, time.Now())
+			testhelpers.MockHydrateCookieClient(t, c, pts.URL+"/session/set")
+
+			assert.Equal(t, s.ID, getID(t, c, "/session/get"))
+			assert.False(t, isActive(t, replaced.ID))
+		})
+
+		t.Run("case=rotation disabled", func(t *testing.T) {
+			conf.MustSet(config.ViperKeySessionRotationEnabled, false)
+			t.Cleanup(func() {
+				conf.MustSet(config.ViperKeySessionRotationEnabled, true)
+			})
+
+			i := identity.Identity{Traits: []byte("{}")}
+			require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+			replaced := session.NewActiveSession(&i, conf, time.Now())
+			s = replaced
+
+			c := testhelpers.NewClientWithCookies(t)
+			testhelpers.MockHydrateCookieClient(t, c, pts.URL+"/session/set")
+
+			s = session.NewActiveSession(&i, conf, time.Now())
+			testhelpers.MockHydrateCookieClient(t, c, pts.URL+"/session/set")
+
+			assert.True(t, isActive(t, replaced.ID))
+			assert.Equal(t, s.ID, getID(t, c, "/session/rotate"))
+			assert.True(t, isActive(t, s.ID))
+		})
+	})
+
+	t.Run("case=rotates sessions authenticated by token", func(t *testing.T) {
+		conf, reg := internal.NewFastRegistryWithMocks(t)
+		conf.MustSet(config.ViperKeyDefaultIdentitySchemaURL, "file://./stub/fake-session.schema.json")
+
+		i := identity.Identity{Traits: []byte("{}")}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+		s := session.NewActiveSession(&i, conf, time.Now())
+		require.NoError(t, reg.SessionPersister().CreateSession(context.Background(), s))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Session-Token", s.Token)
+
+		w := httptest.NewRecorder()
+		rotated, err := reg.SessionManager().RotateSession(context.Background(), w, req, s)
+		require.NoError(t, err)
+		assert.NotEqual(t, s.ID, rotated.ID)
+		assert.NotEqual(t, s.Token, rotated.Token)
+		assert.Equal(t, s.AuthenticatedAt.Unix(), rotated.AuthenticatedAt.Unix())
+		assert.Equal(t, s.ExpiresAt.Unix(), rotated.ExpiresAt.Unix())
+		assert.Empty(t, w.Result().Cookies(), "no cookie is issued for token authenticated requests")
+
+		_, err = reg.SessionManager().FetchFromRequest(context.Background(), req)
+		assert.True(t, errors.Is(err, session.ErrNoActiveSessionFound), "%+v", err)
+
+		next := httptest.NewRequest("GET", "/", nil)
+		next.Header.Set("X-Session-Token", rotated.Token)
+		actual, err := reg.SessionManager().FetchFromRequest(context.Background(), next)
+		require.NoError(t, err)
+		assert.Equal(t, rotated.ID, actual.ID)
 	})
 }
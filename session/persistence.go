@@ -2,13 +2,19 @@ package session
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/bxcodec/faker/v3"
 	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/x"
@@ -18,6 +24,29 @@ type PersistenceProvider interface {
 	SessionPersister() Persister
 }
 
+// ListSessionsFilter narrows down the sessions listed and counted by the persister. The zero value matches all
+// sessions.
+type ListSessionsFilter struct {
+	// IdentityID matches sessions issued to this identity.
+	IdentityID uuid.UUID
+
+	// Active matches sessions which are active and did not expire before Now if true and sessions which were revoked
+	// or expired if false.
+	Active *bool
+
+	// Now is the time used to tell whether a session expired.
+	Now time.Time
+
+	// IPAddress matches sessions issued to this IP address.
+	IPAddress string
+
+	// IssuedSince matches sessions issued at or after this time.
+	IssuedSince time.Time
+
+	// ExceptID excludes the session with this ID, for example the session of the caller.
+	ExceptID uuid.UUID
+}
+
 type Persister interface {
 	// GetSession retrieves a session from the store.
 	GetSession(ctx context.Context, sid uuid.UUID) (*Session, error)
@@ -45,6 +74,48 @@ type Persister interface {
 
 	// RevokeSessionByToken marks a session inactive with the given token.
 	RevokeSessionByToken(ctx context.Context, token string) error
+
+	// RevokeSessionOfIdentity marks the session with the given ID inactive if it was issued to the identity, and
+	// returns sqlcon.ErrNoRows otherwise.
+	RevokeSessionOfIdentity(ctx context.Context, identity, sid uuid.UUID) error
+
+	// RevokeSessionsByIdentity marks all active sessions of the identity inactive and returns their number.
+	RevokeSessionsByIdentity(ctx context.Context, identity uuid.UUID) (int, error)
+
+	// UpdateSessionLastActive sets the time the session was last used.
+	UpdateSessionLastActive(ctx context.Context, id uuid.UUID, at time.Time) error
+
+	// UpdateSessionExpiry sets the time the session expires at.
+	UpdateSessionExpiry(ctx context.Context, id uuid.UUID, expiresAt time.Time) error
+
+	// CountSessionsByIdentity returns the number of sessions, including inactive ones, issued to the identity.
+	CountSessionsByIdentity(ctx context.Context, identity uuid.UUID) (int, error)
+
+	// CountActiveSessions returns the number of sessions which are active and did not expire before now.
+	CountActiveSessions(ctx context.Context, now time.Time) (int, error)
+
+	// ListSessions lists the sessions matching the filter including their identities, most recently issued first.
+	ListSessions(ctx context.Context, filter ListSessionsFilter, page, perPage int) ([]Session, error)
+
+	// CountSessions returns the number of sessions matching the filter.
+	CountSessions(ctx context.Context, filter ListSessionsFilter) (int, error)
+
+	// RevokeExpiredSessions marks at most limit active sessions which expired before now inactive and returns
+	// them. Sessions revoked concurrently by another call are not returned.
+	RevokeExpiredSessions(ctx context.Context, now time.Time, limit int) ([]Session, error)
+
+	// ListExpiredIdentitiesWithActiveSessions lists the identities which expired before now but still have active
+	// sessions. Only the ID and the expiry of the identities are loaded.
+	ListExpiredIdentitiesWithActiveSessions(ctx context.Context, now time.Time) ([]identity.Identity, error)
+
+	// CreateSessionDisclosure persists a disclosure token.
+	CreateSessionDisclosure(ctx context.Context, d *Disclosure) error
+
+	// GetSessionDisclosure returns a disclosure by its token or sqlcon.ErrNoRows.
+	GetSessionDisclosure(ctx context.Context, token string) (*Disclosure, error)
+
+	// DeleteExpiredSessionDisclosures deletes the disclosures which expired before now and returns their number.
+	DeleteExpiredSessionDisclosures(ctx context.Context, now time.Time) (int, error)
 }
 
 func TestPersister(conf *config.Provider, p interface {
@@ -131,6 +202,279 @@ func TestPersister(conf *config.Provider, p interface {
 			assert.False(t, actual.Active)
 		})
 
+		t.Run("case=revoke sessions of expired identities", func(t *testing.T) {
+			var expired, other Session
+			require.NoError(t, faker.FakeData(&expired))
+			expired.Active = true
+			expiresAt := time.Now().UTC().Add(-time.Minute)
+			expired.Identity.ExpiresAt = &expiresAt
+			require.NoError(t, p.CreateIdentity(context.Background(), expired.Identity))
+			require.NoError(t, p.CreateSession(context.Background(), &expired))
+
+			require.NoError(t, faker.FakeData(&other))
+			other.Active = true
+			expiresAt = time.Now().UTC().Add(time.Hour)
+			other.Identity.ExpiresAt = &expiresAt
+			require.NoError(t, p.CreateIdentity(context.Background(), other.Identity))
+			require.NoError(t, p.CreateSession(context.Background(), &other))
+
+			ids := func() []uuid.UUID {
+				is, err := p.ListExpiredIdentitiesWithActiveSessions(context.Background(), time.Now())
+				require.NoError(t, err)
+				ids := make([]uuid.UUID, len(is))
+				for k := range is {
+					ids[k] = is[k].ID
+				}
+				return ids
+			}
+
+			assert.Contains(t, ids(), expired.Identity.ID)
+			assert.NotContains(t, ids(), other.Identity.ID)
+
+			count, err := p.RevokeSessionsByIdentity(context.Background(), expired.Identity.ID)
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+			assert.NotContains(t, ids(), expired.Identity.ID)
+
+			actual, err := p.GetSession(context.Background(), expired.ID)
+			require.NoError(t, err)
+			assert.False(t, actual.Active)
+
+			actual, err = p.GetSession(context.Background(), other.ID)
+			require.NoError(t, err)
+			assert.True(t, actual.Active)
+		})
+
+		t.Run("case=revoke expired sessions", func(t *testing.T) {
+			var expired, valid Session
+			for _, s := range []*Session{&expired, &valid} {
+				require.NoError(t, faker.FakeData(s))
+				s.Active = true
+				s.ExpiresAt = time.Now().UTC().Add(time.Hour)
+				require.NoError(t, p.CreateIdentity(context.Background(), s.Identity))
+			}
+			expired.ExpiresAt = time.Now().UTC().Add(-time.Minute)
+			for _, s := range []*Session{&expired, &valid} {
+				require.NoError(t, p.CreateSession(context.Background(), s))
+			}
+
+			revoked := func() map[uuid.UUID]uuid.UUID {
+				ss, err := p.RevokeExpiredSessions(context.Background(), time.Now(), 100000)
+				require.NoError(t, err)
+				ids := map[uuid.UUID]uuid.UUID{}
+				for _, s := range ss {
+					ids[s.ID] = s.IdentityID
+				}
+				return ids
+			}
+
+			ids := revoked()
+			assert.Equal(t, expired.Identity.ID, ids[expired.ID])
+			assert.NotContains(t, ids, valid.ID)
+			assert.NotContains(t, revoked(), expired.ID, "sessions are only revoked once")
+
+			actual, err := p.GetSession(context.Background(), expired.ID)
+			require.NoError(t, err)
+			assert.False(t, actual.Active)
+
+			actual, err = p.GetSession(context.Background(), valid.ID)
+			require.NoError(t, err)
+			assert.True(t, actual.Active)
+		})
+
+		t.Run("case=revoke a session of an identity", func(t *testing.T) {
+			var expected, other Session
+			require.NoError(t, faker.FakeData(&expected))
+			expected.Active = true
+			require.NoError(t, p.CreateIdentity(context.Background(), expected.Identity))
+			require.NoError(t, p.CreateSession(context.Background(), &expected))
+
+			require.NoError(t, faker.FakeData(&other))
+			other.Active = true
+			require.NoError(t, p.CreateIdentity(context.Background(), other.Identity))
+			require.NoError(t, p.CreateSession(context.Background(), &other))
+
+			err := p.RevokeSessionOfIdentity(context.Background(), other.Identity.ID, expected.ID)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+			require.True(t, errors.Is(p.RevokeSessionOfIdentity(context.Background(), expected.Identity.ID, x.NewUUID()), sqlcon.ErrNoRows))
+
+			require.NoError(t, p.RevokeSessionOfIdentity(context.Background(), expected.Identity.ID, expected.ID))
+			actual, err := p.GetSession(context.Background(), expected.ID)
+			require.NoError(t, err)
+			assert.False(t, actual.Active)
+
+			actual, err = p.GetSession(context.Background(), other.ID)
+			require.NoError(t, err)
+			assert.True(t, actual.Active)
+
+			require.NoError(t, p.RevokeSessionOfIdentity(context.Background(), expected.Identity.ID, expected.ID), "revoking a session twice is not an error")
+		})
+
+		t.Run("case=count sessions by identity", func(t *testing.T) {
+			var sess Session
+			require.NoError(t, faker.FakeData(&sess))
+			require.NoError(t, p.CreateIdentity(context.Background(), sess.Identity))
+
+			count, err := p.CountSessionsByIdentity(context.Background(), sess.Identity.ID)
+			require.NoError(t, err)
+			assert.Equal(t, 0, count)
+
+			require.NoError(t, p.CreateSession(context.Background(), &sess))
+			require.NoError(t, p.RevokeSessionByToken(context.Background(), sess.Token))
+
+			count, err = p.CountSessionsByIdentity(context.Background(), sess.Identity.ID)
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+		})
+
+		t.Run("case=count active sessions", func(t *testing.T) {
+			before, err := p.CountActiveSessions(context.Background(), time.Now())
+			require.NoError(t, err)
+
+			var active, expired, revoked Session
+			for _, s := range []*Session{&active, &expired, &revoked} {
+				require.NoError(t, faker.FakeData(s))
+				s.Active = true
+				s.ExpiresAt = time.Now().UTC().Add(time.Hour)
+				require.NoError(t, p.CreateIdentity(context.Background(), s.Identity))
+			}
+			expired.ExpiresAt = time.Now().UTC().Add(-time.Minute)
+			for _, s := range []*Session{&active, &expired, &revoked} {
+				require.NoError(t, p.CreateSession(context.Background(), s))
+			}
+			require.NoError(t, p.RevokeSessionByToken(context.Background(), revoked.Token))
+
+			count, err := p.CountActiveSessions(context.Background(), time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, before+1, count)
+		})
+
+		t.Run("case=list sessions", func(t *testing.T) {
+			var active, expired, revoked Session
+			require.NoError(t, faker.FakeData(&active))
+			require.NoError(t, p.CreateIdentity(context.Background(), active.Identity))
+
+			now := time.Now().UTC()
+			for k, s := range []*Session{&active, &expired, &revoked} {
+				if s != &active {
+					require.NoError(t, faker.FakeData(s))
+					s.Identity = active.Identity
+				}
+				s.IdentityID = active.Identity.ID
+				s.Active = true
+				s.IssuedAt = now.Add(-time.Duration(k) * time.Hour)
+				s.ExpiresAt = now.Add(time.Hour)
+			}
+			active.IPAddress = "203.0.113.1"
+			expired.ExpiresAt = now.Add(-time.Minute)
+			for _, s := range []*Session{&active, &expired, &revoked} {
+				require.NoError(t, p.CreateSession(context.Background(), s))
+			}
+			require.NoError(t, p.RevokeSessionByToken(context.Background(), revoked.Token))
+
+			yes, no := true, false
+			for k, tc := range []struct {
+				filter   ListSessionsFilter
+				expected []uuid.UUID
+			}{
+				{filter: ListSessionsFilter{}, expected: []uuid.UUID{active.ID, expired.ID, revoked.ID}},
+				{filter: ListSessionsFilter{Active: &yes, Now: now}, expected: []uuid.UUID{active.ID}},
+				{filter: ListSessionsFilter{Active: &no, Now: now}, expected: []uuid.UUID{expired.ID, revoked.ID}},
+				{filter: ListSessionsFilter{IPAddress: "203.0.113.1"}, expected: []uuid.UUID{active.ID}},
+				{filter: ListSessionsFilter{IPAddress: "203.0.113.2"}, expected: []uuid.UUID{}},
+				{filter: ListSessionsFilter{IssuedSince: now.Add(-90 * time.Minute)}, expected: []uuid.UUID{active.ID, expired.ID}},
+				{filter: ListSessionsFilter{Active: &no, Now: now, IssuedSince: now.Add(-90 * time.Minute)}, expected: []uuid.UUID{expired.ID}},
+				{filter: ListSessionsFilter{ExceptID: expired.ID}, expected: []uuid.UUID{active.ID, revoked.ID}},
+			} {
+				t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+					tc.filter.IdentityID = active.Identity.ID
+
+					ss, err := p.ListSessions(context.Background(), tc.filter, 0, 100)
+					require.NoError(t, err)
+					actual := make([]uuid.UUID, len(ss))
+					for i := range ss {
+						actual[i] = ss[i].ID
+						assert.Equal(t, active.Identity.ID, ss[i].Identity.ID)
+					}
+					assert.Equal(t, tc.expected, actual, "sessions are listed most recently issued first")
+
+					count, err := p.CountSessions(context.Background(), tc.filter)
+					require.NoError(t, err)
+					assert.Equal(t, len(tc.expected), count)
+				})
+			}
+
+			t.Run("case=paginates", func(t *testing.T) {
+				ss, err := p.ListSessions(context.Background(), ListSessionsFilter{IdentityID: active.Identity.ID}, 2, 2)
+				require.NoError(t, err)
+				require.Len(t, ss, 1)
+				assert.Equal(t, revoked.ID, ss[0].ID)
+			})
+
+			t.Run("case=updates the last activity", func(t *testing.T) {
+				require.NoError(t, p.UpdateSessionLastActive(context.Background(), active.ID, now))
+
+				actual, err := p.GetSession(context.Background(), active.ID)
+				require.NoError(t, err)
+				require.NotNil(t, actual.LastActiveAt)
+				assert.Equal(t, now.Unix(), actual.LastActiveAt.Unix())
+			})
+
+			t.Run("case=updates the expiry", func(t *testing.T) {
+				expiresAt := now.Add(2 * time.Hour)
+				require.NoError(t, p.UpdateSessionExpiry(context.Background(), active.ID, expiresAt))
+
+				actual, err := p.GetSession(context.Background(), active.ID)
+				require.NoError(t, err)
+				assert.Equal(t, expiresAt.Unix(), actual.ExpiresAt.Unix())
+			})
+		})
+
+		t.Run("case=session disclosures", func(t *testing.T) {
+			var sess Session
+			require.NoError(t, faker.FakeData(&sess))
+			require.NoError(t, p.CreateIdentity(context.Background(), sess.Identity))
+			require.NoError(t, p.CreateSession(context.Background(), &sess))
+
+			_, err := p.GetSessionDisclosure(context.Background(), "does-not-exist")
+			assert.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+
+			newDisclosure := func(expiresAt time.Time) *Disclosure {
+				return &Disclosure{
+					ID:        x.NewUUID(),
+					Token:     x.NewUUID().String(),
+					SessionID: sess.ID,
+					Claims:    sqlxx.JSONRawMessage(`{"traits":{"email":"foo@ory.sh"}}`),
+					ExpiresAt: expiresAt,
+				}
+			}
+
+			valid, expired := newDisclosure(time.Now().UTC().Add(time.Hour)), newDisclosure(time.Now().UTC().Add(-time.Minute))
+			require.NoError(t, p.CreateSessionDisclosure(context.Background(), valid))
+			require.NoError(t, p.CreateSessionDisclosure(context.Background(), expired))
+
+			actual, err := p.GetSessionDisclosure(context.Background(), valid.Token)
+			require.NoError(t, err)
+			assert.Equal(t, valid.ID, actual.ID)
+			assert.Equal(t, sess.ID, actual.SessionID)
+			assert.JSONEq(t, string(valid.Claims), string(actual.Claims))
+			assert.EqualValues(t, valid.ExpiresAt.Unix(), actual.ExpiresAt.Unix())
+
+			count, err := p.DeleteExpiredSessionDisclosures(context.Background(), time.Now())
+			require.NoError(t, err)
+			assert.True(t, count >= 1)
+
+			_, err = p.GetSessionDisclosure(context.Background(), expired.Token)
+			assert.True(t, errors.Is(err, sqlcon.ErrNoRows), "%+v", err)
+			_, err = p.GetSessionDisclosure(context.Background(), valid.Token)
+			require.NoError(t, err)
+
+			require.NoError(t, p.DeleteSession(context.Background(), sess.ID))
+			_, err = p.GetSessionDisclosure(context.Background(), valid.Token)
+			assert.True(t, errors.Is(err, sqlcon.ErrNoRows), "disclosures are deleted with their session: %+v", err)
+		})
+
 		t.Run("case=delete session for", func(t *testing.T) {
 			var expected1 Session
 			var expected2 Session
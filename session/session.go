@@ -1,11 +1,13 @@
 package session
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/gofrs/uuid"
 
 	"github.com/ory/x/randx"
+	"github.com/ory/x/sqlxx"
 
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/x"
@@ -38,8 +40,54 @@ type Session struct {
 	UpdatedAt time.Time `json:"-" faker:"-" db:"updated_at"`
 
 	Token string `json:"-" db:"token"`
+
+	// ImpersonatedBy is set if the session was issued by an administrator to act on behalf of the identity, for
+	// example by support staff. It identifies who requested the session so that applications are able to show a
+	// banner and audit the access.
+	ImpersonatedBy sqlxx.NullString `json:"impersonated_by,omitempty" faker:"-" db:"impersonated_by"`
+
+	// FirstTimeLogin is true if this is the first session issued to the identity, which applications can use to
+	// show an onboarding experience. Sessions issued by the login flow are only marked if a browser return rule
+	// depends on `first_login`.
+	FirstTimeLogin bool `json:"first_time_login" faker:"-" db:"first_time_login"`
+
+	// JustRegistered is true if the session was issued by the registration flow which created the identity.
+	JustRegistered bool `json:"just_registered" faker:"-" db:"just_registered"`
+
+	// Remembered is true if the user asked to stay signed in when the session was issued. Remembered sessions last
+	// for `session.remember_me.lifespan` and use a persistent cookie.
+	Remembered bool `json:"remembered" faker:"-" db:"remembered"`
+
+	// IPAddress is the IP address of the client the session was issued to.
+	IPAddress sqlxx.NullString `json:"ip_address,omitempty" faker:"-" db:"ip_address"`
+
+	// UserAgent is the user agent of the client the session was issued to, which describes its device and browser.
+	UserAgent sqlxx.NullString `json:"user_agent,omitempty" faker:"-" db:"user_agent"`
+
+	// Location is the location of the client the session was issued to as reported by the reverse proxy or CDN in
+	// front of ORY Kratos, for example its country.
+	Location sqlxx.NullString `json:"location,omitempty" faker:"-" db:"location"`
+
+	// Browser is the browser and its major version the session was issued to, parsed from the user agent.
+	Browser sqlxx.NullString `json:"browser,omitempty" faker:"-" db:"browser"`
+
+	// OperatingSystem is the operating system of the device the session was issued to, parsed from the user agent.
+	OperatingSystem sqlxx.NullString `json:"operating_system,omitempty" faker:"-" db:"operating_system"`
+
+	// DeviceType is the type of the device the session was issued to, parsed from the user agent. One of
+	// `desktop`, `mobile`, `tablet`, or `bot`.
+	DeviceType sqlxx.NullString `json:"device_type,omitempty" faker:"-" db:"device_type"`
+
+	// LastActiveAt is the time the session was last used. It is updated at most once a minute.
+	LastActiveAt *time.Time `json:"last_active_at,omitempty" faker:"-" db:"last_active_at"`
 }
 
+// AuthenticatorAssuranceLevel as defined in NIST SP 800-63B.
+type AuthenticatorAssuranceLevel string
+
+// AuthenticatorAssuranceLevel1 requires a single authentication factor.
+const AuthenticatorAssuranceLevel1 AuthenticatorAssuranceLevel = "aal1"
+
 func (s Session) TableName() string {
 	return "sessions"
 }
@@ -51,7 +99,7 @@ func NewActiveSession(i *identity.Identity, c interface {
 		ID:              x.NewUUID(),
 		ExpiresAt:       authenticatedAt.Add(c.SessionLifespan()),
 		AuthenticatedAt: authenticatedAt,
-		IssuedAt:        time.Now().UTC(),
+		IssuedAt:        authenticatedAt,
 		Identity:        i,
 		IdentityID:      i.ID,
 		Token:           randx.MustString(32, randx.AlphaNum),
@@ -64,11 +112,98 @@ type Device struct {
 	SeenAt    []time.Time `json:"seen_at" faker:"time_types"`
 }
 
+// SetDevice records the IP address, the user agent, and the location of the client which sent the request the
+// session is issued for, as well as the browser, operating system, and device type described by its user agent.
+func (s *Session) SetDevice(r *http.Request, c interface {
+	SessionLocationHeader() string
+}) {
+	s.IPAddress = sqlxx.NullString(x.ClientIP(r))
+	s.UserAgent = sqlxx.NullString(truncate(r.UserAgent(), 512))
+
+	browser, os, deviceType := parseUserAgent(r.UserAgent())
+	s.Browser = sqlxx.NullString(truncate(browser, 64))
+	s.OperatingSystem = sqlxx.NullString(truncate(os, 64))
+	s.DeviceType = sqlxx.NullString(deviceType)
+	if header := c.SessionLocationHeader(); header != "" {
+		s.Location = sqlxx.NullString(truncate(r.Header.Get(header), 255))
+	}
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
+
+// Remember marks the session as remembered, which lets it last for the remembered session lifespan.
+func (s *Session) Remember(c interface {
+	SessionRememberMeLifespan() time.Duration
+}) {
+	s.Remembered = true
+	s.ExpiresAt = s.AuthenticatedAt.Add(c.SessionRememberMeLifespan())
+}
+
+// Lifespan returns the lifespan of the session, which depends on whether it is remembered.
+func (s *Session) Lifespan(c interface {
+	SessionLifespan() time.Duration
+	SessionRememberMeLifespan() time.Duration
+}) time.Duration {
+	if s.Remembered {
+		return c.SessionRememberMeLifespan()
+	}
+	return c.SessionLifespan()
+}
+
+// expiryExtensionInterval is the minimum duration a session is extended by when using sliding expiration.
+const expiryExtensionInterval = time.Minute
+
+// Extend moves the expiry of the session to lifespan after now, but not past maxLifespan after the identity
+// authenticated. It returns false and leaves the session unchanged if that would extend the session by less than
+// expiryExtensionInterval, which avoids storing the session on every request.
+func (s *Session) Extend(now time.Time, lifespan, maxLifespan time.Duration) bool {
+	expiresAt := now.Add(lifespan)
+	if max := s.AuthenticatedAt.Add(maxLifespan); expiresAt.After(max) {
+		expiresAt = max
+	}
+
+	if expiresAt.Sub(s.ExpiresAt) < expiryExtensionInterval {
+		return false
+	}
+	s.ExpiresAt = expiresAt
+	return true
+}
+
+// copy returns a copy of the session whose identity can be changed without affecting the original.
+func (s *Session) copy() *Session {
+	cp := *s
+	cp.Identity = s.Identity.CopyWithoutCredentials()
+	return &cp
+}
+
+// rotate returns a copy of the session issued at now which has a new ID and token.
+func (s *Session) rotate(now time.Time) *Session {
+	rotated := s.copy()
+	rotated.ID = x.NewUUID()
+	rotated.Token = randx.MustString(32, randx.AlphaNum)
+	rotated.IssuedAt = now
+	rotated.CreatedAt = time.Time{}
+	rotated.UpdatedAt = time.Time{}
+	rotated.LastActiveAt = nil
+	return rotated
+}
+
 func (s *Session) Declassify() *Session {
 	s.Identity = s.Identity.CopyWithoutCredentials()
 	return s
 }
 
-func (s *Session) IsActive() bool {
-	return s.Active && s.ExpiresAt.After(time.Now())
+func (s *Session) IsActive(now time.Time) bool {
+	return s.Active && s.ExpiresAt.After(now)
+}
+
+// AuthenticatorAssuranceLevel returns the assurance level of the session. Sessions are always established using a
+// single authentication factor.
+func (s *Session) AuthenticatorAssuranceLevel() AuthenticatorAssuranceLevel {
+	return AuthenticatorAssuranceLevel1
 }
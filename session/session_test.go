@@ -1,11 +1,14 @@
 package session_test
 
 import (
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
 	"github.com/ory/kratos/session"
@@ -16,8 +19,65 @@ func TestSession(t *testing.T) {
 	authAt := time.Now()
 
 	s := session.NewActiveSession(new(identity.Identity), conf, authAt)
-	assert.True(t, s.IsActive())
+	assert.True(t, s.IsActive(time.Now()))
 
-	assert.False(t, (&session.Session{ExpiresAt: time.Now().Add(time.Hour)}).IsActive())
-	assert.False(t, (&session.Session{Active: true}).IsActive())
+	assert.False(t, (&session.Session{ExpiresAt: time.Now().Add(time.Hour)}).IsActive(time.Now()))
+	assert.False(t, (&session.Session{Active: true}).IsActive(time.Now()))
+}
+
+func TestSessionSetDevice(t *testing.T) {
+	conf, _ := internal.NewFastRegistryWithMocks(t)
+
+	r := &http.Request{RemoteAddr: "203.0.113.1:1234", Header: http.Header{}}
+	r.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7; rv:84.0) Gecko/20100101 Firefox/84.0")
+	r.Header.Set("CF-IPCountry", "DE")
+
+	s := session.NewActiveSession(new(identity.Identity), conf, time.Now())
+	s.SetDevice(r, conf)
+	assert.EqualValues(t, "203.0.113.1", s.IPAddress)
+	assert.EqualValues(t, "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7; rv:84.0) Gecko/20100101 Firefox/84.0", s.UserAgent)
+	assert.EqualValues(t, "Firefox 84", s.Browser)
+	assert.EqualValues(t, "macOS 10.15.7", s.OperatingSystem)
+	assert.EqualValues(t, session.DeviceTypeDesktop, s.DeviceType)
+	assert.Empty(t, s.Location, "the location is only recorded if the header is configured")
+
+	conf.MustSet(config.ViperKeySessionLocationHeader, "CF-IPCountry")
+	r.Header.Set("User-Agent", strings.Repeat("a", 1000))
+	s.SetDevice(r, conf)
+	assert.EqualValues(t, "DE", s.Location)
+	assert.Len(t, s.UserAgent, 512)
+}
+
+func TestSessionRemember(t *testing.T) {
+	conf, _ := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeySessionLifespan, "1h")
+	conf.MustSet(config.ViperKeySessionRememberMeLifespan, "720h")
+
+	authAt := time.Now().UTC()
+	s := session.NewActiveSession(new(identity.Identity), conf, authAt)
+	assert.False(t, s.Remembered)
+	assert.Equal(t, time.Hour, s.Lifespan(conf))
+	assert.Equal(t, authAt.Add(time.Hour), s.ExpiresAt)
+
+	s.Remember(conf)
+	assert.True(t, s.Remembered)
+	assert.Equal(t, 720*time.Hour, s.Lifespan(conf))
+	assert.Equal(t, authAt.Add(720*time.Hour), s.ExpiresAt)
+}
+
+func TestSessionExtend(t *testing.T) {
+	authAt := time.Now().UTC().Truncate(time.Second)
+	s := &session.Session{AuthenticatedAt: authAt, ExpiresAt: authAt.Add(time.Hour)}
+
+	assert.False(t, s.Extend(authAt.Add(30*time.Second), time.Hour, 24*time.Hour), "extensions of less than a minute are skipped")
+	assert.Equal(t, authAt.Add(time.Hour), s.ExpiresAt)
+
+	assert.True(t, s.Extend(authAt.Add(30*time.Minute), time.Hour, 24*time.Hour))
+	assert.Equal(t, authAt.Add(90*time.Minute), s.ExpiresAt)
+
+	assert.True(t, s.Extend(authAt.Add(23*time.Hour+30*time.Minute), time.Hour, 24*time.Hour))
+	assert.Equal(t, authAt.Add(24*time.Hour), s.ExpiresAt, "sessions are not extended past the maximum lifespan")
+
+	assert.False(t, s.Extend(authAt.Add(23*time.Hour+50*time.Minute), time.Hour, 24*time.Hour))
+	assert.Equal(t, authAt.Add(24*time.Hour), s.ExpiresAt)
 }
@@ -0,0 +1,102 @@
+package session
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	DeviceTypeDesktop = "desktop"
+	DeviceTypeMobile  = "mobile"
+	DeviceTypeTablet  = "tablet"
+	DeviceTypeBot     = "bot"
+)
+
+var (
+	userAgentBrowsers = []struct {
+		name    string
+		version *regexp.Regexp
+	}{
+		// Order matters because most browsers also claim to be Chrome or Safari.
+		{name: "Edge", version: regexp.MustCompile(`Edg(?:e|A|iOS)?/(\d+)`)},
+		{name: "Opera", version: regexp.MustCompile(`(?:OPR|Opera)/(\d+)`)},
+		{name: "Samsung Internet", version: regexp.MustCompile(`SamsungBrowser/(\d+)`)},
+		{name: "Firefox", version: regexp.MustCompile(`(?:Firefox|FxiOS)/(\d+)`)},
+		{name: "Chrome", version: regexp.MustCompile(`(?:Chrome|CriOS)/(\d+)`)},
+		{name: "Safari", version: regexp.MustCompile(`Version/(\d+).*Safari/`)},
+		{name: "Internet Explorer", version: regexp.MustCompile(`(?:MSIE |Trident/.*rv:)(\d+)`)},
+	}
+
+	userAgentWindows = regexp.MustCompile(`Windows NT (\d+\.\d+)`)
+	userAgentIOS     = regexp.MustCompile(`(?:iPhone|CPU) OS (\d+(?:_\d+)?)`)
+	userAgentMacOS   = regexp.MustCompile(`Mac OS X (\d+(?:[_.]\d+)*)`)
+	userAgentAndroid = regexp.MustCompile(`Android (\d+(?:\.\d+)?)`)
+	userAgentBot     = regexp.MustCompile(`(?i)bot|crawler|spider|curl/|wget/`)
+
+	windowsVersions = map[string]string{
+		"10.0": "10",
+		"6.3":  "8.1",
+		"6.2":  "8",
+		"6.1":  "7",
+		"6.0":  "Vista",
+		"5.1":  "XP",
+	}
+)
+
+// parseUserAgent describes the browser, operating system, and type of device which sent a user agent. Values which
+// can not be determined are left empty.
+func parseUserAgent(ua string) (browser, os, deviceType string) {
+	if ua == "" {
+		return "", "", ""
+	}
+
+	for _, b := range userAgentBrowsers {
+		if m := b.version.FindStringSubmatch(ua); m != nil {
+			browser = b.name + " " + m[1]
+			break
+		}
+	}
+
+	switch {
+	case strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad") || strings.Contains(ua, "iPod"):
+		os = "iOS"
+		if m := userAgentIOS.FindStringSubmatch(ua); m != nil {
+			os += " " + strings.ReplaceAll(m[1], "_", ".")
+		}
+	case strings.Contains(ua, "Android"):
+		os = "Android"
+		if m := userAgentAndroid.FindStringSubmatch(ua); m != nil {
+			os += " " + m[1]
+		}
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+		if m := userAgentWindows.FindStringSubmatch(ua); m != nil {
+			if v, ok := windowsVersions[m[1]]; ok {
+				os += " " + v
+			}
+		}
+	case strings.Contains(ua, "Mac OS X"):
+		os = "macOS"
+		if m := userAgentMacOS.FindStringSubmatch(ua); m != nil {
+			os += " " + strings.ReplaceAll(m[1], "_", ".")
+		}
+	case strings.Contains(ua, "CrOS"):
+		os = "Chrome OS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	}
+
+	switch {
+	case userAgentBot.MatchString(ua):
+		deviceType = DeviceTypeBot
+	case strings.Contains(ua, "iPad") || strings.Contains(ua, "Tablet") ||
+		(strings.Contains(ua, "Android") && !strings.Contains(ua, "Mobile")):
+		deviceType = DeviceTypeTablet
+	case strings.Contains(ua, "Mobi") || strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPod"):
+		deviceType = DeviceTypeMobile
+	case os != "":
+		deviceType = DeviceTypeDesktop
+	}
+
+	return browser, os, deviceType
+}
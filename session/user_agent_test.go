@@ -0,0 +1,77 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	for k, tc := range []struct {
+		ua, browser, os, deviceType string
+	}{
+		{ua: ""},
+		{
+			ua:         "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36",
+			browser:    "Chrome 87",
+			os:         "Windows 10",
+			deviceType: DeviceTypeDesktop,
+		},
+		{
+			ua:         "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36 Edg/87.0.664.66",
+			browser:    "Edge 87",
+			os:         "Windows 10",
+			deviceType: DeviceTypeDesktop,
+		},
+		{
+			ua:         "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.2 Safari/605.1.15",
+			browser:    "Safari 14",
+			os:         "macOS 10.15.7",
+			deviceType: DeviceTypeDesktop,
+		},
+		{
+			ua:         "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:84.0) Gecko/20100101 Firefox/84.0",
+			browser:    "Firefox 84",
+			os:         "Linux",
+			deviceType: DeviceTypeDesktop,
+		},
+		{
+			ua:         "Mozilla/5.0 (iPhone; CPU iPhone OS 14_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Mobile/15E148 Safari/604.1",
+			browser:    "Safari 14",
+			os:         "iOS 14.3",
+			deviceType: DeviceTypeMobile,
+		},
+		{
+			ua:         "Mozilla/5.0 (iPad; CPU OS 14_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/87.0.4280.77 Mobile/15E148 Safari/604.1",
+			browser:    "Chrome 87",
+			os:         "iOS 14.2",
+			deviceType: DeviceTypeTablet,
+		},
+		{
+			ua:         "Mozilla/5.0 (Linux; Android 11; SM-G991B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/13.2 Chrome/83.0.4103.106 Mobile Safari/537.36",
+			browser:    "Samsung Internet 13",
+			os:         "Android 11",
+			deviceType: DeviceTypeMobile,
+		},
+		{
+			ua:         "Mozilla/5.0 (Linux; Android 10; SM-T510) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.101 Safari/537.36",
+			browser:    "Chrome 87",
+			os:         "Android 10",
+			deviceType: DeviceTypeTablet,
+		},
+		{
+			ua:         "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			deviceType: DeviceTypeBot,
+		},
+		{
+			ua:         "curl/7.64.1",
+			deviceType: DeviceTypeBot,
+		},
+		{ua: "my-app/1.0"},
+	} {
+		browser, os, deviceType := parseUserAgent(tc.ua)
+		assert.Equal(t, tc.browser, browser, "%d: %s", k, tc.ua)
+		assert.Equal(t, tc.os, os, "%d: %s", k, tc.ua)
+		assert.Equal(t, tc.deviceType, deviceType, "%d: %s", k, tc.ua)
+	}
+}
@@ -0,0 +1,40 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Version returns the version of the session as a weak HTTP entity tag. It changes whenever the session is revoked or
+// its identity is updated. Responses of the same version may differ in when the session was last active and when it
+// expires, because sliding expiration would otherwise change the version on almost every check.
+func (s *Session) Version() string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s|%t", s.ID, s.Active)
+	if s.Identity != nil {
+		// Verifying addresses and labelling identities does not change the version of the identity.
+		_, _ = fmt.Fprintf(h, "|%s", s.Identity.Version())
+		for _, a := range s.Identity.VerifiableAddresses {
+			_, _ = fmt.Fprintf(h, "|%s:%t:%s", a.ID, a.Verified, a.Status)
+		}
+		for _, l := range s.Identity.Labels {
+			_, _ = fmt.Fprintf(h, "|%s=%s", l.Name, l.Value)
+		}
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// ifNoneMatch returns true if the If-None-Match header of the request matches the version. Entity tags are compared
+// using the weak comparison.
+func ifNoneMatch(r *http.Request, version string) bool {
+	for _, v := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == strings.TrimPrefix(version, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,261 @@
+// Command load runs a load test of the whoami, password login, or password registration path against a running
+// ORY Kratos instance and reports the throughput and latency. It exits with a non-zero code if the results are
+// outside of the budget given by the flags, which allows running it in CI.
+//
+//	go run ./test/load -endpoint http://127.0.0.1:4433 -scenario whoami -concurrency 16 -duration 30s -max-p95 50ms
+//
+// All scenarios register identities using the password method and expect an identity schema with an email identifier.
+// The registration form fields sent can be changed using -fields.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/x/randx"
+)
+
+type (
+	runner struct {
+		endpoint string
+		fields   string
+		password string
+		client   *http.Client
+	}
+
+	result struct {
+		latencies []time.Duration
+		errors    int
+		lastError error
+	}
+)
+
+func main() {
+	endpoint := flag.String("endpoint", "http://127.0.0.1:4433", "The URL of the public API of ORY Kratos.")
+	scenario := flag.String("scenario", "whoami", "The path to test, one of whoami, login, or registration.")
+	concurrency := flag.Int("concurrency", 8, "The number of concurrent clients.")
+	duration := flag.Duration("duration", 30*time.Second, "The duration of the test.")
+	fields := flag.String("fields", `{"traits.email":"%s"}`, "The registration form fields except the password as JSON. %s is replaced by a unique email address.")
+	maxP95 := flag.Duration("max-p95", 0, "Fail if the 95th percentile latency exceeds this duration. Disabled if zero.")
+	minRPS := flag.Float64("min-rps", 0, "Fail if fewer requests per second succeed. Disabled if zero.")
+	maxErrorRate := flag.Float64("max-error-rate", 0, "Fail if a larger fraction of the requests fails.")
+	flag.Parse()
+
+	r := &runner{
+		endpoint: strings.TrimRight(*endpoint, "/"),
+		fields:   *fields,
+		password: randx.MustString(16, randx.AlphaNum) + "-Load!",
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+
+	op, err := r.operation(*scenario)
+	if err != nil {
+		fail(err)
+	}
+
+	res := run(op, *concurrency, *duration)
+	if res.requests() == 0 {
+		fail(errors.Errorf("no requests were sent: %+v", res.lastError))
+	}
+
+	rps := float64(res.requests()-res.errors) / duration.Seconds()
+	errorRate := float64(res.errors) / float64(res.requests())
+	fmt.Printf("scenario:    %s\n", *scenario)
+	fmt.Printf("concurrency: %d\n", *concurrency)
+	fmt.Printf("requests:    %d (%d failed)\n", res.requests(), res.errors)
+	fmt.Printf("throughput:  %.1f req/s\n", rps)
+	fmt.Printf("latency:     p50 %s, p95 %s, p99 %s\n", res.percentile(50), res.percentile(95), res.percentile(99))
+	if res.lastError != nil {
+		fmt.Printf("last error:  %s\n", res.lastError)
+	}
+
+	var violations []string
+	if *maxP95 > 0 && res.percentile(95) > *maxP95 {
+		violations = append(violations, fmt.Sprintf("p95 latency %s exceeds %s", res.percentile(95), *maxP95))
+	}
+	if *minRPS > 0 && rps < *minRPS {
+		violations = append(violations, fmt.Sprintf("throughput %.1f req/s is below %.1f req/s", rps, *minRPS))
+	}
+	if errorRate > *maxErrorRate {
+		violations = append(violations, fmt.Sprintf("error rate %.4f exceeds %.4f", errorRate, *maxErrorRate))
+	}
+	if len(violations) > 0 {
+		fail(errors.Errorf("the performance budget was exceeded: %s", strings.Join(violations, "; ")))
+	}
+}
+
+func fail(err error) {
+	_, _ = fmt.Fprintf(os.Stderr, "%s\n", err)
+	os.Exit(1)
+}
+
+// operation prepares the scenario and returns the operation which is repeated by every client.
+func (r *runner) operation(scenario string) (func() error, error) {
+	switch scenario {
+	case "registration":
+		return func() error {
+			_, err := r.register(r.newEmail())
+			return err
+		}, nil
+	case "login":
+		email := r.newEmail()
+		if _, err := r.register(email); err != nil {
+			return nil, err
+		}
+		return func() error {
+			_, err := r.login(email)
+			return err
+		}, nil
+	case "whoami":
+		email := r.newEmail()
+		if _, err := r.register(email); err != nil {
+			return nil, err
+		}
+		token, err := r.login(email)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			return r.whoami(token)
+		}, nil
+	}
+	return nil, errors.Errorf(`unknown scenario "%s", expected one of whoami, login, or registration`, scenario)
+}
+
+func (r *runner) newEmail() string {
+	return fmt.Sprintf("load-%s@example.org", strings.ToLower(randx.MustString(16, randx.AlphaNum)))
+}
+
+// register completes a registration flow for API clients and returns the session token if one was issued.
+func (r *runner) register(email string) (string, error) {
+	body, err := sjson.Set(fmt.Sprintf(r.fields, email), "password", r.password)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	res, err := r.completeFlow("/self-service/registration/api", "methods.password.config.action", body)
+	if err != nil {
+		return "", err
+	}
+	return res.Get("session_token").String(), nil
+}
+
+// login completes a password login flow for API clients and returns the session token.
+func (r *runner) login(email string) (string, error) {
+	body := fmt.Sprintf(`{"identifier":%q,"password":%q}`, email, r.password)
+	res, err := r.completeFlow("/self-service/login/api", "methods.password.config.action", body)
+	if err != nil {
+		return "", err
+	}
+	return res.Get("session_token").String(), nil
+}
+
+func (r *runner) whoami(token string) error {
+	req, err := http.NewRequest("GET", r.endpoint+"/sessions/whoami", nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("X-Session-Token", token)
+	_, err = r.do(req, http.StatusOK)
+	return err
+}
+
+// completeFlow initializes a flow for API clients and submits body to the action of the form at actionPath.
+func (r *runner) completeFlow(initPath, actionPath, body string) (gjson.Result, error) {
+	req, err := http.NewRequest("GET", r.endpoint+initPath, nil)
+	if err != nil {
+		return gjson.Result{}, errors.WithStack(err)
+	}
+	f, err := r.do(req, http.StatusOK)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+
+	req, err = http.NewRequest("POST", f.Get(actionPath).String(), bytes.NewBufferString(body))
+	if err != nil {
+		return gjson.Result{}, errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := f.Get("flow_token").String(); token != "" {
+		req.Header.Set("X-Kratos-Flow-Token", token)
+	}
+	return r.do(req, http.StatusOK)
+}
+
+func (r *runner) do(req *http.Request, expectCode int) (gjson.Result, error) {
+	req.Header.Set("Accept", "application/json")
+	res, err := r.client.Do(req)
+	if err != nil {
+		return gjson.Result{}, errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	raw, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return gjson.Result{}, errors.WithStack(err)
+	}
+	if res.StatusCode != expectCode {
+		return gjson.Result{}, errors.Errorf("%s %s: expected status code %d but got %d: %s", req.Method, req.URL.Path, expectCode, res.StatusCode, raw)
+	}
+	return gjson.ParseBytes(raw), nil
+}
+
+// run repeats op using the given number of concurrent clients until the duration passed.
+func run(op func() error, concurrency int, duration time.Duration) *result {
+	var (
+		res result
+		l   sync.Mutex
+		wg  sync.WaitGroup
+	)
+
+	deadline := time.Now().Add(duration)
+	wg.Add(concurrency)
+	for k := 0; k < concurrency; k++ {
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				start := time.Now()
+				err := op()
+				took := time.Since(start)
+
+				l.Lock()
+				res.latencies = append(res.latencies, took)
+				if err != nil {
+					res.errors++
+					res.lastError = err
+				}
+				l.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(res.latencies, func(i, j int) bool {
+		return res.latencies[i] < res.latencies[j]
+	})
+	return &res
+}
+
+func (r *result) requests() int {
+	return len(r.latencies)
+}
+
+// percentile returns the latency below which p percent of the requests completed.
+func (r *result) percentile(p int) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	return r.latencies[(len(r.latencies)-1)*p/100].Round(time.Microsecond)
+}
@@ -10,7 +10,30 @@ const (
 	InfoSelfServiceMFA ID = 1030000 + iota
 )
 
+const (
+	InfoSelfServiceErrorAction      ID = 1080000 + iota // 1080000
+	InfoSelfServiceErrorActionRetry                     // 1080001
+	InfoSelfServiceErrorActionLogin                     // 1080002
+	InfoSelfServiceErrorActionHome                      // 1080003
+)
+
 const (
 	ErrorSystem ID = 5000000 + iota
 	ErrorSystemGeneric
 )
+
+const (
+	ErrorSystemUserFacing                        ID = 5010000 + iota // 5010000
+	ErrorSystemUserFacingBadRequestTitle                             // 5010001
+	ErrorSystemUserFacingBadRequestDescription                       // 5010002
+	ErrorSystemUserFacingUnauthorizedTitle                           // 5010003
+	ErrorSystemUserFacingUnauthorizedDescription                     // 5010004
+	ErrorSystemUserFacingForbiddenTitle                              // 5010005
+	ErrorSystemUserFacingForbiddenDescription                        // 5010006
+	ErrorSystemUserFacingNotFoundTitle                               // 5010007
+	ErrorSystemUserFacingNotFoundDescription                         // 5010008
+	ErrorSystemUserFacingGoneTitle                                   // 5010009
+	ErrorSystemUserFacingGoneDescription                             // 5010010
+	ErrorSystemUserFacingInternalTitle                               // 5010011
+	ErrorSystemUserFacingInternalDescription                         // 5010012
+)
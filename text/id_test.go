@@ -8,6 +8,7 @@ import (
 
 func TestIDs(t *testing.T) {
 	assert.Equal(t, 1010000, int(InfoSelfServiceLogin))
+	assert.Equal(t, 1010001, int(InfoSelfServiceLoginLinkCredentials))
 
 	assert.Equal(t, 1020000, int(InfoSelfServiceLogout))
 
@@ -23,13 +24,21 @@ func TestIDs(t *testing.T) {
 	assert.Equal(t, 1060002, int(InfoSelfServiceRecoveryEmailSent))
 
 	assert.Equal(t, 1070000, int(InfoSelfServiceVerification))
+	assert.Equal(t, 1070003, int(InfoSelfServiceVerificationCodeSent))
 
 	assert.Equal(t, 4000000, int(ErrorValidation))
 	assert.Equal(t, 4000001, int(ErrorValidationGeneric))
 	assert.Equal(t, 4000002, int(ErrorValidationRequired))
+	assert.Equal(t, 4000008, int(ErrorValidationEmailUndeliverable))
 
 	assert.Equal(t, 4010000, int(ErrorValidationLogin))
 	assert.Equal(t, 4010001, int(ErrorValidationLoginFlowExpired))
+	assert.Equal(t, 4010002, int(ErrorValidationLoginNegotiateUnavailable))
+	assert.Equal(t, 4010003, int(ErrorValidationLoginClientCertificate))
+	assert.Equal(t, 4010004, int(ErrorValidationLoginIdentityInactive))
+	assert.Equal(t, 4010005, int(ErrorValidationLoginIdentityExpired))
+	assert.Equal(t, 4010006, int(ErrorValidationLoginMaintenance))
+	assert.Equal(t, 4010007, int(ErrorValidationLoginOutsideAllowedHours))
 
 	assert.Equal(t, 4040000, int(ErrorValidationRegistration))
 	assert.Equal(t, 4040001, int(ErrorValidationRegistrationFlowExpired))
@@ -43,6 +52,14 @@ func TestIDs(t *testing.T) {
 
 	assert.Equal(t, 4070000, int(ErrorValidationVerification))
 	assert.Equal(t, 4070001, int(ErrorValidationVerificationTokenInvalidOrAlreadyUsed))
+	assert.Equal(t, 4070006, int(ErrorValidationVerificationCodeInvalid))
+	assert.Equal(t, 4070007, int(ErrorValidationVerificationCodeInvalidOrExpired))
+
+	assert.Equal(t, 1080000, int(InfoSelfServiceErrorAction))
+	assert.Equal(t, 1080003, int(InfoSelfServiceErrorActionHome))
 
 	assert.Equal(t, 5000000, int(ErrorSystem))
+
+	assert.Equal(t, 5010000, int(ErrorSystemUserFacing))
+	assert.Equal(t, 5010012, int(ErrorSystemUserFacingInternalDescription))
 }
@@ -6,14 +6,32 @@ import (
 )
 
 const (
-	InfoSelfServiceLogin ID = 1010000 + iota
+	InfoSelfServiceLogin                ID = 1010000 + iota // 1010000
+	InfoSelfServiceLoginLinkCredentials                     // 1010001
 )
 
 const (
-	ErrorValidationLogin            ID = 4010000 + iota // 4010000
-	ErrorValidationLoginFlowExpired                     // 4010001
+	ErrorValidationLogin                     ID = 4010000 + iota // 4010000
+	ErrorValidationLoginFlowExpired                              // 4010001
+	ErrorValidationLoginNegotiateUnavailable                     // 4010002
+	ErrorValidationLoginClientCertificate                        // 4010003
+	ErrorValidationLoginIdentityInactive                         // 4010004
+	ErrorValidationLoginIdentityExpired                          // 4010005
+	ErrorValidationLoginMaintenance                              // 4010006
+	ErrorValidationLoginOutsideAllowedHours                      // 4010007
 )
 
+func NewInfoLoginLinkCredentials(provider string) *Message {
+	return &Message{
+		ID:   InfoSelfServiceLoginLinkCredentials,
+		Text: fmt.Sprintf("An account with the same email address exists already. Sign in to that account to link it with %s.", provider),
+		Type: Info,
+		Context: context(map[string]interface{}{
+			"provider": provider,
+		}),
+	}
+}
+
 func NewErrorValidationLoginFlowExpired(ago time.Duration) *Message {
 	return &Message{
 		ID:   ErrorValidationLoginFlowExpired,
@@ -24,3 +42,63 @@ func NewErrorValidationLoginFlowExpired(ago time.Duration) *Message {
 		}),
 	}
 }
+
+func NewErrorValidationLoginNegotiateUnavailable() *Message {
+	return &Message{
+		ID:      ErrorValidationLoginNegotiateUnavailable,
+		Text:    "Signing in with your desktop account is not available on this device. Please use another method to sign in.",
+		Type:    Error,
+		Context: context(nil),
+	}
+}
+
+func NewErrorValidationLoginClientCertificate() *Message {
+	return &Message{
+		ID:      ErrorValidationLoginClientCertificate,
+		Text:    "No valid client certificate was presented or the certificate is not linked to an account.",
+		Type:    Error,
+		Context: context(nil),
+	}
+}
+
+func NewErrorValidationLoginIdentityInactive() *Message {
+	return &Message{
+		ID:      ErrorValidationLoginIdentityInactive,
+		Text:    "This account was deactivated. Please contact support to reactivate it.",
+		Type:    Error,
+		Context: context(nil),
+	}
+}
+
+func NewErrorValidationLoginIdentityExpired(at time.Time) *Message {
+	return &Message{
+		ID:   ErrorValidationLoginIdentityExpired,
+		Text: fmt.Sprintf("This account expired at %s. Please contact support to extend it.", at.Format(time.RFC3339)),
+		Type: Error,
+		Context: context(map[string]interface{}{
+			"expired_at": at,
+		}),
+	}
+}
+
+func NewErrorValidationLoginMaintenance(until time.Time) *Message {
+	return &Message{
+		ID:   ErrorValidationLoginMaintenance,
+		Text: fmt.Sprintf("Signing in is not possible during maintenance. Please try again after %s.", until.Format(time.RFC3339)),
+		Type: Error,
+		Context: context(map[string]interface{}{
+			"until": until,
+		}),
+	}
+}
+
+func NewErrorValidationLoginOutsideAllowedHours(next time.Time) *Message {
+	return &Message{
+		ID:   ErrorValidationLoginOutsideAllowedHours,
+		Text: fmt.Sprintf("Signing in is not possible at this time. Please try again after %s.", next.Format(time.RFC3339)),
+		Type: Error,
+		Context: context(map[string]interface{}{
+			"next_allowed_at": next,
+		}),
+	}
+}
@@ -1,5 +1,7 @@
 package text
 
+import "net/http"
+
 func NewErrorSystemGeneric(reason string) *Message {
 	return &Message{
 		ID:      ErrorSystemGeneric,
@@ -8,3 +10,66 @@ func NewErrorSystemGeneric(reason string) *Message {
 		Context: context(nil),
 	}
 }
+
+// NewErrorSystemUserFacing returns the user-facing title and description of an error with the given HTTP status
+// code. Error user interfaces can translate them using their IDs.
+func NewErrorSystemUserFacing(statusCode int) (title *Message, description *Message) {
+	pair := func(titleID ID, titleText string, descriptionID ID, descriptionText string) (*Message, *Message) {
+		ctx := context(map[string]interface{}{"status_code": statusCode})
+		return &Message{ID: titleID, Text: titleText, Type: Error, Context: ctx},
+			&Message{ID: descriptionID, Text: descriptionText, Type: Error, Context: ctx}
+	}
+
+	switch {
+	case statusCode == http.StatusBadRequest:
+		return pair(ErrorSystemUserFacingBadRequestTitle, "The request could not be processed",
+			ErrorSystemUserFacingBadRequestDescription, "Something about the request was not right. Please go back and try again.")
+	case statusCode == http.StatusUnauthorized:
+		return pair(ErrorSystemUserFacingUnauthorizedTitle, "You are not signed in",
+			ErrorSystemUserFacingUnauthorizedDescription, "Please sign in to continue.")
+	case statusCode == http.StatusForbidden:
+		return pair(ErrorSystemUserFacingForbiddenTitle, "The request was not allowed",
+			ErrorSystemUserFacingForbiddenDescription, "The request could not be verified, for example because it was sent from another tab or browser. Please try again.")
+	case statusCode == http.StatusNotFound:
+		return pair(ErrorSystemUserFacingNotFoundTitle, "The page could not be found",
+			ErrorSystemUserFacingNotFoundDescription, "The link you followed may be broken or the page may have been removed.")
+	case statusCode == http.StatusGone:
+		return pair(ErrorSystemUserFacingGoneTitle, "The page expired",
+			ErrorSystemUserFacingGoneDescription, "You took too long to complete this step. Please start again.")
+	case statusCode >= 400 && statusCode < 500:
+		return pair(ErrorSystemUserFacingBadRequestTitle, "The request could not be processed",
+			ErrorSystemUserFacingBadRequestDescription, "Something about the request was not right. Please go back and try again.")
+	}
+
+	return pair(ErrorSystemUserFacingInternalTitle, "Something went wrong",
+		ErrorSystemUserFacingInternalDescription, "An unexpected error occurred on our side. Please try again later.")
+}
+
+func NewInfoSelfServiceErrorActionRetry(flow string) *Message {
+	return &Message{
+		ID:   InfoSelfServiceErrorActionRetry,
+		Text: "Try again",
+		Type: Info,
+		Context: context(map[string]interface{}{
+			"flow": flow,
+		}),
+	}
+}
+
+func NewInfoSelfServiceErrorActionLogin() *Message {
+	return &Message{
+		ID:      InfoSelfServiceErrorActionLogin,
+		Text:    "Sign in",
+		Type:    Info,
+		Context: context(nil),
+	}
+}
+
+func NewInfoSelfServiceErrorActionHome() *Message {
+	return &Message{
+		ID:      InfoSelfServiceErrorActionHome,
+		Text:    "Go back to the start page",
+		Type:    Info,
+		Context: context(nil),
+	}
+}
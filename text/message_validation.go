@@ -13,6 +13,7 @@ const (
 	ErrorValidationPasswordPolicyViolation
 	ErrorValidationInvalidCredentials
 	ErrorValidationDuplicateCredentials
+	ErrorValidationEmailUndeliverable
 )
 
 func NewValidationErrorGeneric(reason string) *Message {
@@ -87,3 +88,14 @@ func NewErrorValidationDuplicateCredentials() *Message {
 		Context: context(nil),
 	}
 }
+
+func NewErrorValidationEmailUndeliverable(address string) *Message {
+	return &Message{
+		ID:   ErrorValidationEmailUndeliverable,
+		Text: fmt.Sprintf("The email address %s can not receive mail, check for spelling mistakes.", address),
+		Type: Error,
+		Context: context(map[string]interface{}{
+			"address": address,
+		}),
+	}
+}
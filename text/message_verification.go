@@ -6,9 +6,11 @@ import (
 )
 
 const (
-	InfoSelfServiceVerification           ID = 1070000 + iota
-	InfoSelfServiceVerificationSuccessful    // 1060001
-	InfoSelfServiceVerificationEmailSent     // 1060002
+	InfoSelfServiceVerification            ID = 1070000 + iota
+	InfoSelfServiceVerificationSuccessful     // 1060001
+	InfoSelfServiceVerificationEmailSent      // 1060002
+	InfoSelfServiceVerificationCodeSent       // 1070003
+	InfoSelfServiceVerificationSMSCodeSent    // 1070004
 )
 
 const (
@@ -18,6 +20,8 @@ const (
 	ErrorValidationVerificationStateFailure
 	ErrorValidationVerificationMissingVerificationToken
 	ErrorValidationVerificationFlowExpired
+	ErrorValidationVerificationCodeInvalid
+	ErrorValidationVerificationCodeInvalidOrExpired
 )
 
 func NewErrorValidationVerificationFlowExpired(ago time.Duration) *Message {
@@ -40,6 +44,42 @@ func NewVerificationEmailSent() *Message {
 	}
 }
 
+func NewVerificationCodeSent() *Message {
+	return &Message{
+		ID:      InfoSelfServiceVerificationCodeSent,
+		Type:    Info,
+		Text:    "An email containing a verification code has been sent to the email address you provided.",
+		Context: context(nil),
+	}
+}
+
+func NewVerificationSMSCodeSent() *Message {
+	return &Message{
+		ID:      InfoSelfServiceVerificationSMSCodeSent,
+		Type:    Info,
+		Text:    "A text message containing a verification code has been sent to the phone number you provided.",
+		Context: context(nil),
+	}
+}
+
+func NewErrorValidationVerificationCodeInvalid() *Message {
+	return &Message{
+		ID:      ErrorValidationVerificationCodeInvalid,
+		Text:    "The verification code is invalid. Please try again.",
+		Type:    Error,
+		Context: context(nil),
+	}
+}
+
+func NewErrorValidationVerificationCodeInvalidOrExpired() *Message {
+	return &Message{
+		ID:      ErrorValidationVerificationCodeInvalidOrExpired,
+		Text:    "The verification code is invalid, expired, or was entered incorrectly too often. Please request a new code.",
+		Type:    Error,
+		Context: context(nil),
+	}
+}
+
 func NewErrorValidationVerificationTokenInvalidOrAlreadyUsed() *Message {
 	return &Message{
 		ID:      ErrorValidationVerificationTokenInvalidOrAlreadyUsed,
@@ -0,0 +1,119 @@
+package x
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+type botScoreContextKey struct{}
+
+var ErrTooManyRequests = herodot.DefaultError{
+	CodeField:   http.StatusTooManyRequests,
+	StatusField: http.StatusText(http.StatusTooManyRequests),
+	ErrorField:  "Too many requests were sent, please try again later.",
+}
+
+type (
+	botScoreDependencies interface {
+		LoggingProvider
+		WriterProvider
+	}
+	BotScoreMiddlewareProvider interface {
+		BotScoreMiddleware() *BotScoreMiddleware
+	}
+
+	// BotScoreMiddleware reads the bot score a CDN computed for the request and rate limits the self-service flow
+	// submissions of likely automated clients.
+	BotScoreMiddleware struct {
+		d botScoreDependencies
+		c *config.Provider
+
+		sync.Mutex
+		submissions map[string][]time.Time
+	}
+)
+
+func NewBotScoreMiddleware(d botScoreDependencies, c *config.Provider) *BotScoreMiddleware {
+	return &BotScoreMiddleware{d: d, c: c, submissions: map[string][]time.Time{}}
+}
+
+// BotScore returns the bot score of the request, where low scores indicate automated traffic, and false if the
+// request has no trusted bot score.
+func BotScore(r *http.Request) (int, bool) {
+	score, ok := r.Context().Value(botScoreContextKey{}).(int)
+	return score, ok
+}
+
+func (m *BotScoreMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	c := m.c.BotScore()
+	if c.Header == "" {
+		next(w, r)
+		return
+	}
+
+	raw := r.Header.Get(c.Header)
+	if raw == "" {
+		next(w, r)
+		return
+	}
+
+	if trusted, err := FromTrustedProxy(r, c.TrustedProxies); err != nil {
+		m.d.Writer().WriteError(w, r, err)
+		return
+	} else if !trusted {
+		next(w, r)
+		return
+	}
+
+	score, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		m.d.Logger().WithRequest(r).WithError(err).Debug("Ignoring the bot score because it is not an integer.")
+		next(w, r)
+		return
+	}
+
+	if score < c.RateLimitBelow && r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/self-service/") &&
+		m.track(ClientIP(r), time.Now(), c.RateLimitWindow) > c.RateLimitMaxRequests {
+		m.d.Audit().
+			WithRequest(r).
+			WithField("bot_score", score).
+			Info("Rate limited a self-service flow submission of a likely automated client.")
+		m.d.Writer().WriteError(w, r, errors.WithStack(ErrTooManyRequests))
+		return
+	}
+
+	next(w, r.WithContext(context.WithValue(r.Context(), botScoreContextKey{}, score)))
+}
+
+// track records a submission from ip and returns the number of submissions within the window.
+func (m *BotScoreMiddleware) track(ip string, now time.Time, window time.Duration) int {
+	m.Lock()
+	defer m.Unlock()
+
+	for key, submissions := range m.submissions {
+		var kept []time.Time
+		for _, at := range submissions {
+			if now.Sub(at) < window {
+				kept = append(kept, at)
+			}
+		}
+		if len(kept) == 0 {
+			delete(m.submissions, key)
+		} else {
+			m.submissions[key] = kept
+		}
+	}
+
+	m.submissions[ip] = append(m.submissions[ip], now)
+	return len(m.submissions[ip])
+}
@@ -0,0 +1,87 @@
+package x_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/x"
+)
+
+func TestBotScoreMiddleware(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	serve := func(method, path, remote, score string) (int, int, bool) {
+		r := httptest.NewRequest(method, path, nil)
+		r.RemoteAddr = remote + ":1234"
+		if score != "" {
+			r.Header.Set("CF-Bot-Score", score)
+		}
+
+		var actual int
+		var ok bool
+		w := httptest.NewRecorder()
+		x.NewBotScoreMiddleware(reg, conf).ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {
+			actual, ok = x.BotScore(r)
+		})
+		return w.Code, actual, ok
+	}
+
+	t.Run("case=is disabled per default", func(t *testing.T) {
+		_, _, ok := serve("GET", "/", "192.0.2.1", "12")
+		assert.False(t, ok)
+	})
+
+	conf.MustSet(config.ViperKeyBotScoreHeader, "CF-Bot-Score")
+
+	t.Run("case=reads the bot score", func(t *testing.T) {
+		code, score, ok := serve("GET", "/", "192.0.2.1", " 12 ")
+		assert.Equal(t, http.StatusOK, code)
+		assert.True(t, ok)
+		assert.Equal(t, 12, score)
+
+		_, _, ok = serve("GET", "/", "192.0.2.1", "")
+		assert.False(t, ok, "requests without the header have no bot score")
+
+		_, _, ok = serve("GET", "/", "192.0.2.1", "human")
+		assert.False(t, ok, "invalid scores are ignored")
+	})
+
+	t.Run("case=ignores untrusted peers", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyBotScoreTrustedProxies, []string{"192.0.2.0/24"})
+		defer conf.MustSet(config.ViperKeyBotScoreTrustedProxies, []string{})
+
+		_, _, ok := serve("GET", "/", "198.51.100.1", "12")
+		assert.False(t, ok)
+
+		_, _, ok = serve("GET", "/", "192.0.2.1", "12")
+		assert.True(t, ok)
+	})
+
+	t.Run("case=rate limits likely automated submissions", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyBotScoreRateLimitBelow, 30)
+		conf.MustSet(config.ViperKeyBotScoreRateLimitMaxRequests, 2)
+		m := x.NewBotScoreMiddleware(reg, conf)
+
+		submit := func(path, remote, score string) int {
+			r := httptest.NewRequest("POST", path, nil)
+			r.RemoteAddr = remote + ":1234"
+			r.Header.Set("CF-Bot-Score", score)
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, r, func(w http.ResponseWriter, r *http.Request) {})
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusOK, submit("/self-service/login/methods/password", "192.0.2.1", "12"))
+		assert.Equal(t, http.StatusOK, submit("/self-service/login/methods/password", "192.0.2.1", "12"))
+		assert.Equal(t, http.StatusTooManyRequests, submit("/self-service/login/methods/password", "192.0.2.1", "12"))
+
+		assert.Equal(t, http.StatusOK, submit("/self-service/login/methods/password", "192.0.2.1", "80"), "likely human clients are not limited")
+		assert.Equal(t, http.StatusOK, submit("/self-service/login/methods/password", "192.0.2.2", "12"), "other addresses are counted separately")
+		assert.Equal(t, http.StatusOK, submit("/sessions", "192.0.2.1", "12"), "only self-service flows are limited")
+	})
+}
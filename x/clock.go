@@ -0,0 +1,211 @@
+package x
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+)
+
+// ClockPath is the admin path of the test clock. It is only served in development mode.
+const ClockPath = "/clock"
+
+type (
+	// Clock tells the time used to issue and expire flows, sessions, and tokens.
+	Clock interface {
+		Now() time.Time
+	}
+	ClockProvider interface {
+		Clock() Clock
+	}
+
+	// SystemClock tells the time of the system.
+	SystemClock struct{}
+
+	// TestClock tells the time of the system advanced by an offset. It allows testing the expiry of flows,
+	// sessions, and tokens without waiting for their lifespan to pass.
+	TestClock struct {
+		offset int64
+	}
+
+	clockHandlerDependencies interface {
+		WriterProvider
+		ClockProvider
+	}
+
+	// ClockHandler lets integrators advance the test clock. It must only be registered in development mode.
+	ClockHandler struct {
+		d clockHandlerDependencies
+	}
+
+	// The current time of the test clock.
+	//
+	// swagger:model testClock
+	clockStatus struct {
+		// Now is the current time of the clock.
+		//
+		// required: true
+		Now time.Time `json:"now"`
+
+		// Offset is the duration the clock was advanced by, for example `1h30m0s`.
+		//
+		// required: true
+		Offset string `json:"offset"`
+	}
+)
+
+// swagger:parameters advanceTestClock
+// nolint:deadcode,unused
+type advanceTestClock struct {
+	// in: body
+	// required: true
+	Body AdvanceTestClockRequest
+}
+
+// swagger:model advanceTestClockRequest
+type AdvanceTestClockRequest struct {
+	// Duration the clock is advanced by, for example `1h` or `30m`.
+	//
+	// required: true
+	Duration string `json:"duration"`
+}
+
+func (SystemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+func (c *TestClock) Now() time.Time {
+	return time.Now().UTC().Add(c.Offset())
+}
+
+// Offset returns the duration the clock was advanced by.
+func (c *TestClock) Offset() time.Duration {
+	return time.Duration(atomic.LoadInt64(&c.offset))
+}
+
+// Advance moves the clock forward by d.
+func (c *TestClock) Advance(d time.Duration) {
+	atomic.AddInt64(&c.offset, int64(d))
+}
+
+// Reset sets the clock back to the time of the system.
+func (c *TestClock) Reset() {
+	atomic.StoreInt64(&c.offset, 0)
+}
+
+func NewClockHandler(d clockHandlerDependencies) *ClockHandler {
+	return &ClockHandler{d: d}
+}
+
+func (h *ClockHandler) RegisterAdminRoutes(admin *RouterAdmin) {
+	admin.GET(ClockPath, h.get)
+	admin.POST(ClockPath, h.advance)
+	admin.DELETE(ClockPath, h.reset)
+}
+
+func (h *ClockHandler) testClock() (*TestClock, error) {
+	c, ok := h.d.Clock().(*TestClock)
+	if !ok {
+		return nil, errors.WithStack(herodot.ErrNotFound.WithReason("The clock can only be adjusted in development mode."))
+	}
+	return c, nil
+}
+
+func (h *ClockHandler) writeStatus(w http.ResponseWriter, r *http.Request, c *TestClock) {
+	h.d.Writer().Write(w, r, &clockStatus{Now: c.Now(), Offset: c.Offset().String()})
+}
+
+// swagger:route GET /clock admin getTestClock
+//
+// Get the Test Clock
+//
+// Returns the current time of the test clock. This endpoint is only available in development mode (`--dev`).
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: testClock
+//       404: genericError
+func (h *ClockHandler) get(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c, err := h.testClock()
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	h.writeStatus(w, r, c)
+}
+
+// swagger:route POST /clock admin advanceTestClock
+//
+// Advance the Test Clock
+//
+// Advances the clock used to issue and expire flows, sessions, and tokens, so that their expiry can be tested
+// without waiting for their lifespan to pass. This endpoint is only available in development mode (`--dev`).
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: testClock
+//       400: genericError
+//       404: genericError
+func (h *ClockHandler) advance(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c, err := h.testClock()
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	var body AdvanceTestClockRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("Unable to decode the request body.").WithDebug(err.Error())))
+		return
+	}
+
+	d, err := time.ParseDuration(body.Duration)
+	if err != nil || d < 0 {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The duration "%s" is invalid. It must be a positive duration such as "1h" or "30m".`, body.Duration)))
+		return
+	}
+
+	c.Advance(d)
+	h.writeStatus(w, r, c)
+}
+
+// swagger:route DELETE /clock admin resetTestClock
+//
+// Reset the Test Clock
+//
+// Sets the test clock back to the time of the system. This endpoint is only available in development mode (`--dev`).
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: testClock
+//       404: genericError
+func (h *ClockHandler) reset(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c, err := h.testClock()
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	c.Reset()
+	h.writeStatus(w, r, c)
+}
@@ -0,0 +1,84 @@
+package x_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/x"
+)
+
+func TestTestClock(t *testing.T) {
+	var c x.TestClock
+	assert.WithinDuration(t, time.Now(), c.Now(), time.Second)
+
+	c.Advance(time.Hour)
+	c.Advance(30 * time.Minute)
+	assert.Equal(t, 90*time.Minute, c.Offset())
+	assert.WithinDuration(t, time.Now().Add(90*time.Minute), c.Now(), time.Second)
+
+	c.Reset()
+	assert.Equal(t, time.Duration(0), c.Offset())
+	assert.WithinDuration(t, time.Now(), c.Now(), time.Second)
+}
+
+func TestClockHandler(t *testing.T) {
+	_, reg := internal.NewFastRegistryWithMocks(t)
+
+	router := x.NewRouterAdmin()
+	x.NewClockHandler(reg).RegisterAdminRoutes(router)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	send := func(t *testing.T, method, body string, expectCode int) gjson.Result {
+		req, err := http.NewRequest(method, ts.URL+x.ClockPath, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		res, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		raw, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, expectCode, res.StatusCode, "%s", raw)
+		return gjson.ParseBytes(raw)
+	}
+
+	t.Run("case=uses a test clock in development mode", func(t *testing.T) {
+		_, ok := reg.Clock().(*x.TestClock)
+		require.True(t, ok)
+		assert.Equal(t, "0s", send(t, "GET", "", http.StatusOK).Get("offset").String())
+	})
+
+	t.Run("case=advances the clock", func(t *testing.T) {
+		res := send(t, "POST", `{"duration":"2h"}`, http.StatusOK)
+		assert.Equal(t, "2h0m0s", res.Get("offset").String())
+		assert.WithinDuration(t, time.Now().Add(2*time.Hour), res.Get("now").Time(), time.Second)
+		assert.WithinDuration(t, time.Now().Add(2*time.Hour), reg.Clock().Now(), time.Second)
+	})
+
+	t.Run("case=rejects invalid durations", func(t *testing.T) {
+		send(t, "POST", `{"duration":"soon"}`, http.StatusBadRequest)
+		send(t, "POST", `{"duration":"-1h"}`, http.StatusBadRequest)
+		send(t, "POST", `not json`, http.StatusBadRequest)
+		assert.Equal(t, "2h0m0s", send(t, "GET", "", http.StatusOK).Get("offset").String())
+	})
+
+	t.Run("case=resets the clock", func(t *testing.T) {
+		assert.Equal(t, "0s", send(t, "DELETE", "", http.StatusOK).Get("offset").String())
+		assert.WithinDuration(t, time.Now(), reg.Clock().Now(), time.Second)
+	})
+
+	t.Run("case=is unavailable for the system clock", func(t *testing.T) {
+		reg.WithClock(x.SystemClock{})
+		send(t, "GET", "", http.StatusNotFound)
+		send(t, "POST", `{"duration":"1h"}`, http.StatusNotFound)
+	})
+}
@@ -1,6 +1,8 @@
 package x
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"net/http"
 
 	"github.com/gorilla/sessions"
@@ -68,3 +70,25 @@ func SessionUnsetKey(w http.ResponseWriter, r *http.Request, s sessions.Store, i
 	delete(cookie.Values, key)
 	return errors.WithStack(cookie.Save(r, w))
 }
+
+// CookieKeyPairs returns the key pairs used by the cookie stores. Without encryption, the secrets are used as they
+// are. With encryption, a signing and an AES-256 encryption key are derived from every secret so that rotated
+// secrets are still able to decrypt older cookies. The secrets are appended as they are for cookies which were
+// issued before encryption was enabled.
+func CookieKeyPairs(secrets [][]byte, encrypt bool) [][]byte {
+	if !encrypt {
+		return secrets
+	}
+
+	pairs := make([][]byte, 0, len(secrets)*3)
+	for _, secret := range secrets {
+		pairs = append(pairs, deriveCookieKey(secret, "signing"), deriveCookieKey(secret, "encryption"))
+	}
+	return append(pairs, secrets...)
+}
+
+func deriveCookieKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte("ory-kratos-cookie-" + purpose))
+	return mac.Sum(nil)
+}
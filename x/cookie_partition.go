@@ -0,0 +1,91 @@
+package x
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/urfave/negroni"
+)
+
+type partitioningResponseWriter struct {
+	http.ResponseWriter
+	names       []string
+	partitioned bool
+}
+
+// PartitionCookies returns a middleware which adds the `Partitioned` attribute (CHIPS) to the secure cookies named
+// by names. Browsers store partitioned cookies separately for every top-level site and keep them in third-party
+// contexts, such as iframes, where unpartitioned cookies are blocked. The names are resolved for every request so
+// that configuration changes apply without a restart.
+func PartitionCookies(names func() []string) negroni.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		n := names()
+		if len(n) == 0 {
+			next(rw, r)
+			return
+		}
+
+		w := &partitioningResponseWriter{ResponseWriter: rw, names: n}
+		next(w, r)
+
+		// Handlers which do not write a response still have their headers sent.
+		w.partition()
+	}
+}
+
+func (w *partitioningResponseWriter) WriteHeader(code int) {
+	w.partition()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *partitioningResponseWriter) Write(b []byte) (int, error) {
+	w.partition()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *partitioningResponseWriter) Flush() {
+	w.partition()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *partitioningResponseWriter) partition() {
+	if w.partitioned {
+		return
+	}
+	w.partitioned = true
+
+	cookies := w.Header()["Set-Cookie"]
+	for k, c := range cookies {
+		if !w.matches(c) {
+			continue
+		}
+
+		attributes := strings.Split(strings.ToLower(c), ";")
+		var secure, partitioned bool
+		for _, a := range attributes[1:] {
+			switch strings.TrimSpace(a) {
+			case "secure":
+				secure = true
+			case "partitioned":
+				partitioned = true
+			}
+		}
+
+		// Browsers reject partitioned cookies which are not secure.
+		if secure && !partitioned {
+			cookies[k] = c + "; Partitioned"
+		}
+	}
+}
+
+func (w *partitioningResponseWriter) matches(cookie string) bool {
+	name := strings.TrimSpace(strings.SplitN(cookie, "=", 2)[0])
+	for _, n := range w.names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package x
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni"
+)
+
+func TestPartitionCookies(t *testing.T) {
+	setCookies := func(w http.ResponseWriter) {
+		http.SetCookie(w, &http.Cookie{Name: "partitioned", Value: "a", Secure: true, SameSite: http.SameSiteNoneMode})
+		http.SetCookie(w, &http.Cookie{Name: "partitioned_insecure", Value: "b"})
+		http.SetCookie(w, &http.Cookie{Name: "other", Value: "c", Secure: true})
+		w.Header().Add("Set-Cookie", "already=d; Secure; Partitioned")
+	}
+
+	for name, handler := range map[string]http.HandlerFunc{
+		"write header": func(w http.ResponseWriter, r *http.Request) {
+			setCookies(w)
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"write": func(w http.ResponseWriter, r *http.Request) {
+			setCookies(w)
+			_, _ = w.Write([]byte("ok"))
+		},
+		"redirect": func(w http.ResponseWriter, r *http.Request) {
+			setCookies(w)
+			http.Redirect(w, r, "/foo", http.StatusFound)
+		},
+		"implicit": func(w http.ResponseWriter, r *http.Request) {
+			setCookies(w)
+		},
+	} {
+		t.Run("case="+name, func(t *testing.T) {
+			n := negroni.New(PartitionCookies(func() []string {
+				return []string{"partitioned", "partitioned_insecure", "already"}
+			}))
+			n.UseHandlerFunc(handler)
+
+			rec := httptest.NewRecorder()
+			n.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+			assert.Equal(t, []string{
+				"partitioned=a; Secure; SameSite=None; Partitioned",
+				"partitioned_insecure=b",
+				"other=c; Secure",
+				"already=d; Secure; Partitioned",
+			}, rec.Result().Header["Set-Cookie"])
+		})
+	}
+
+	t.Run("case=does not change cookies if none are partitioned", func(t *testing.T) {
+		n := negroni.New(PartitionCookies(func() []string { return nil }))
+		n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.SetCookie(w, &http.Cookie{Name: "partitioned", Value: "a", Secure: true})
+		})
+
+		rec := httptest.NewRecorder()
+		n.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, []string{"partitioned=a; Secure"}, rec.Result().Header["Set-Cookie"])
+	})
+}
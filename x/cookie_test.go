@@ -1,11 +1,14 @@
 package x
 
 import (
+	"bytes"
+	"encoding/base64"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
@@ -82,3 +85,66 @@ func TestSession(t *testing.T) {
 		mr(t, id)
 	})
 }
+
+func TestCookieKeyPairs(t *testing.T) {
+	const name = "ory_kratos_session"
+	values := map[interface{}]interface{}{"identity_id": "b7a9c3e2-identifier"}
+
+	store := func(encrypt bool, secrets ...string) *sessions.CookieStore {
+		var keys [][]byte
+		for _, s := range secrets {
+			keys = append(keys, []byte(s))
+		}
+		return sessions.NewCookieStore(CookieKeyPairs(keys, encrypt)...)
+	}
+
+	encode := func(t *testing.T, s *sessions.CookieStore) string {
+		encoded, err := securecookie.EncodeMulti(name, values, s.Codecs...)
+		require.NoError(t, err)
+		return encoded
+	}
+
+	decode := func(s *sessions.CookieStore, encoded string) error {
+		decoded := map[interface{}]interface{}{}
+		return securecookie.DecodeMulti(name, encoded, &decoded, s.Codecs...)
+	}
+
+	// payload returns the value part of the cookie which is only base64 encoded if the cookie is not encrypted.
+	payload := func(t *testing.T, encoded string) []byte {
+		outer, err := base64.URLEncoding.DecodeString(encoded)
+		require.NoError(t, err)
+		parts := bytes.SplitN(outer, []byte("|"), 3)
+		require.Len(t, parts, 3)
+		inner, err := base64.URLEncoding.DecodeString(string(parts[1]))
+		require.NoError(t, err)
+		return inner
+	}
+
+	t.Run("case=uses the secrets as they are without encryption", func(t *testing.T) {
+		secrets := [][]byte{[]byte("cyan cat walking over keyboard")}
+		assert.Equal(t, secrets, CookieKeyPairs(secrets, false))
+
+		encoded := encode(t, store(false, "cyan cat walking over keyboard"))
+		assert.Contains(t, string(payload(t, encoded)), "b7a9c3e2-identifier")
+	})
+
+	t.Run("case=encrypts the cookie contents", func(t *testing.T) {
+		s := store(true, "cyan cat walking over keyboard")
+		encoded := encode(t, s)
+		assert.NotContains(t, string(payload(t, encoded)), "b7a9c3e2-identifier")
+		require.NoError(t, decode(s, encoded))
+		require.Error(t, decode(store(false, "cyan cat walking over keyboard"), encoded))
+	})
+
+	t.Run("case=decrypts cookies encrypted with rotated secrets", func(t *testing.T) {
+		encoded := encode(t, store(true, "cyan cat walking over keyboard"))
+		require.NoError(t, decode(store(true, "purple dog sitting on the mouse", "cyan cat walking over keyboard"), encoded))
+		require.Error(t, decode(store(true, "purple dog sitting on the mouse"), encoded))
+	})
+
+	t.Run("case=accepts cookies signed before encryption was enabled", func(t *testing.T) {
+		encoded := encode(t, store(false, "cyan cat walking over keyboard"))
+		require.NoError(t, decode(store(true, "cyan cat walking over keyboard"), encoded))
+		require.Error(t, decode(store(true, "purple dog sitting on the mouse"), encoded))
+	})
+}
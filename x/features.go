@@ -0,0 +1,70 @@
+package x
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+// FeaturesPath is the admin path listing the experimental features and deprecated configuration keys.
+const FeaturesPath = "/features"
+
+type (
+	featuresHandlerDependencies interface {
+		WriterProvider
+	}
+
+	// FeaturesHandler lets operators audit which experimental features an instance enabled and which deprecated
+	// configuration keys it uses, for example before upgrading.
+	FeaturesHandler struct {
+		d featuresHandlerDependencies
+		c *config.Provider
+	}
+
+	// The experimental features and deprecated configuration keys of an instance.
+	//
+	// swagger:model features
+	features struct {
+		// Experimental lists all experimental features and whether they are enabled.
+		//
+		// required: true
+		Experimental []config.ExperimentalFeature `json:"experimental"`
+
+		// Deprecated lists all deprecated configuration keys and whether they are set.
+		//
+		// required: true
+		Deprecated []config.DeprecatedOption `json:"deprecated"`
+	}
+)
+
+func NewFeaturesHandler(d featuresHandlerDependencies, c *config.Provider) *FeaturesHandler {
+	return &FeaturesHandler{d: d, c: c}
+}
+
+func (h *FeaturesHandler) RegisterAdminRoutes(admin *RouterAdmin) {
+	admin.GET(FeaturesPath, h.get)
+}
+
+// swagger:route GET /features admin getFeatures
+//
+// Get Experimental Features and Deprecated Configuration Keys
+//
+// Lists the experimental features and whether the instance which handled the request enabled them, as well as the
+// deprecated configuration keys and whether it uses them. Features and keys are reported for the configuration the
+// instance is running with, which may differ between instances until they synced.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: features
+func (h *FeaturesHandler) get(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.d.Writer().Write(w, r, &features{
+		Experimental: h.c.ExperimentalFeatures(),
+		Deprecated:   h.c.DeprecatedOptions(),
+	})
+}
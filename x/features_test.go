@@ -0,0 +1,40 @@
+package x_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/x"
+)
+
+func TestFeaturesHandler(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(config.ViperKeySCIMEnabled, true)
+
+	router := x.NewRouterAdmin()
+	x.NewFeaturesHandler(reg, conf).RegisterAdminRoutes(router)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	res, err := ts.Client().Get(ts.URL + x.FeaturesPath)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	raw, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode, "%s", raw)
+
+	body := gjson.ParseBytes(raw)
+	assert.True(t, body.Get(`experimental.#(key=="scim.enabled").enabled`).Bool(), "%s", raw)
+	assert.False(t, body.Get(`experimental.#(key=="config_versions.enabled").enabled`).Bool(), "%s", raw)
+	assert.Equal(t, config.ViperKeyURLsAllowedReturnToDomains,
+		body.Get(`deprecated.#(key=="selfservice.whitelisted_return_urls").replacement`).String(), "%s", raw)
+	assert.True(t, body.Get(`deprecated.#(key=="selfservice.whitelisted_return_urls").in_use`).Exists(), "%s", raw)
+}
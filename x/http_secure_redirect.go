@@ -18,6 +18,7 @@ import (
 type secureRedirectOptions struct {
 	whitelist       []url.URL
 	defaultReturnTo *url.URL
+	forceReturnTo   *url.URL
 	sourceURL       string
 }
 
@@ -55,6 +56,13 @@ func SecureRedirectOverrideDefaultReturnTo(defaultReturnTo *url.URL) SecureRedir
 	}
 }
 
+// SecureRedirectForceReturnTo redirects to the given address regardless of the requested `?return_to=` value.
+func SecureRedirectForceReturnTo(returnTo *url.URL) SecureRedirectOption {
+	return func(o *secureRedirectOptions) {
+		o.forceReturnTo = returnTo
+	}
+}
+
 // SecureRedirectTo implements a HTTP redirector who mitigates open redirect vulnerabilities by
 // working with whitelisting.
 func SecureRedirectTo(r *http.Request, defaultReturnTo *url.URL, opts ...SecureRedirectOption) (returnTo *url.URL, err error) {
@@ -63,6 +71,10 @@ func SecureRedirectTo(r *http.Request, defaultReturnTo *url.URL, opts ...SecureR
 		opt(o)
 	}
 
+	if o.forceReturnTo != nil {
+		return o.forceReturnTo, nil
+	}
+
 	if len(o.whitelist) == 0 {
 		return o.defaultReturnTo, nil
 	}
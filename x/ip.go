@@ -0,0 +1,61 @@
+package x
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+)
+
+// ClientIP returns the IP address of the client which sent the request. If the request passed through
+// reverse proxies, the first address of the X-Forwarded-For header is used. ORY Kratos must therefore only
+// be exposed through proxies which set or sanitize this header.
+func ClientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// FromTrustedProxy reports whether the request was sent by one of the proxies, given as IP addresses or CIDR ranges.
+// The peer address is used instead of forwarding headers because those could be set by the client as well. All
+// requests are trusted if no proxies are given.
+func FromTrustedProxy(r *http.Request, proxies []string) (bool, error) {
+	if len(proxies) == 0 {
+		return true, nil
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if trusted := net.ParseIP(proxy); trusted != nil {
+				if trusted.Equal(ip) {
+					return true, nil
+				}
+				continue
+			}
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return false, errors.WithStack(herodot.ErrInternalServerError.WithReasonf(`The trusted proxy "%s" is neither an IP address nor a CIDR range.`, proxy))
+		}
+		if ip != nil && network.Contains(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,47 @@
+package x
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIP(t *testing.T) {
+	for k, tc := range []struct {
+		remote, forwarded, expected string
+	}{
+		{remote: "192.0.2.1:1234", expected: "192.0.2.1"},
+		{remote: "[2001:db8::1]:1234", expected: "2001:db8::1"},
+		{remote: "192.0.2.1", expected: "192.0.2.1"},
+		{remote: "10.0.0.1:1234", forwarded: "192.0.2.1, 10.0.0.2", expected: "192.0.2.1"},
+	} {
+		r := &http.Request{RemoteAddr: tc.remote, Header: http.Header{}}
+		if tc.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tc.forwarded)
+		}
+		assert.Equal(t, tc.expected, ClientIP(r), "%d", k)
+	}
+}
+
+func TestFromTrustedProxy(t *testing.T) {
+	for k, tc := range []struct {
+		remote   string
+		proxies  []string
+		expected bool
+	}{
+		{remote: "192.0.2.1:1234", expected: true},
+		{remote: "192.0.2.1:1234", proxies: []string{"192.0.2.1"}, expected: true},
+		{remote: "192.0.2.1:1234", proxies: []string{"192.0.2.0/24"}, expected: true},
+		{remote: "192.0.2.1:1234", proxies: []string{"198.51.100.1", "198.51.100.0/24"}, expected: false},
+		{remote: "[2001:db8::1]:1234", proxies: []string{"2001:db8::/32"}, expected: true},
+	} {
+		trusted, err := FromTrustedProxy(&http.Request{RemoteAddr: tc.remote}, tc.proxies)
+		require.NoError(t, err, "%d", k)
+		assert.Equal(t, tc.expected, trusted, "%d", k)
+	}
+
+	_, err := FromTrustedProxy(&http.Request{RemoteAddr: "192.0.2.1:1234"}, []string{"not-a-proxy"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,334 @@
+package x
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+)
+
+// JSONPatch is a JSON Patch document as defined by RFC 6902.
+type JSONPatch []JSONPatchOperation
+
+// JSONPatchOperation is a single operation of a JSON Patch document.
+//
+// swagger:model jsonPatch
+type JSONPatchOperation struct {
+	// Op is the operation: `add`, `remove`, `replace`, `move`, `copy`, or `test`.
+	//
+	// required: true
+	Op string `json:"op"`
+
+	// Path is the JSON Pointer of the value the operation is applied to, for example `/traits/email`.
+	//
+	// required: true
+	Path string `json:"path"`
+
+	// From is the JSON Pointer of the value moved or copied by `move` and `copy` operations.
+	From string `json:"from,omitempty"`
+
+	// Value is the value used by `add`, `replace`, and `test` operations.
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ErrJSONPatchTestFailed is returned if a `test` operation of a JSON Patch did not match.
+var ErrJSONPatchTestFailed = herodot.ErrConflict.
+	WithError("json patch test failed").
+	WithReason("A test operation of the JSON Patch did not match the current value.")
+
+// Paths returns the paths of all values the patch changes or reads from.
+func (p JSONPatch) Paths() []string {
+	paths := make([]string, 0, len(p))
+	for _, op := range p {
+		paths = append(paths, op.Path)
+		if op.From != "" {
+			paths = append(paths, op.From)
+		}
+	}
+	return paths
+}
+
+// Apply applies the patch to the JSON document. All operations are applied or none.
+func (p JSONPatch) Apply(document []byte) ([]byte, error) {
+	var doc interface{}
+	if err := decodeJSONNumbers(document, &doc); err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The document to patch is not valid JSON.").WithDebug(err.Error()))
+	}
+
+	for k, op := range p {
+		var err error
+		if doc, err = op.apply(doc); err != nil {
+			return nil, errors.WithStack(withJSONPatchOperation(err, k, op))
+		}
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return out, nil
+}
+
+func withJSONPatchOperation(err error, index int, op JSONPatchOperation) error {
+	var de *herodot.DefaultError
+	if errors.As(err, &de) {
+		return de.WithDetail("operation", index).WithDetail("op", op.Op).WithDetail("path", op.Path)
+	}
+	return err
+}
+
+func (op JSONPatchOperation) apply(doc interface{}) (interface{}, error) {
+	path, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := func() (interface{}, error) {
+		if len(op.Value) == 0 {
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The JSON Patch operation "%s" requires a value.`, op.Op))
+		}
+		var v interface{}
+		if err := decodeJSONNumbers(op.Value, &v); err != nil {
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The value of the JSON Patch operation is not valid JSON.").WithDebug(err.Error()))
+		}
+		return v, nil
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerSet(doc, path, v, true)
+	case "remove":
+		doc, _, err := jsonPointerRemove(doc, path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerSet(doc, path, v, false)
+	case "move", "copy":
+		from, err := parseJSONPointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		var v interface{}
+		if op.Op == "move" {
+			if op.From != op.Path && strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("A value can not be moved into one of its children."))
+			}
+			if doc, v, err = jsonPointerRemove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			if v, err = jsonPointerGet(doc, from); err != nil {
+				return nil, err
+			}
+			// Copies must not share maps or slices with the original value.
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if err := decodeJSONNumbers(raw, &v); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		return jsonPointerSet(doc, path, v, true)
+	case "test":
+		expected, err := value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := jsonPointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(expected, actual) {
+			return nil, errors.WithStack(ErrJSONPatchTestFailed)
+		}
+		return doc, nil
+	}
+
+	return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The JSON Patch operation "%s" is not supported.`, op.Op))
+}
+
+func decodeJSONNumbers(raw []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// jsonEqual compares two decoded JSON values. Numbers are equal if their values are equal, regardless of how they
+// were written.
+func jsonEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aerr := av.Float64()
+		bf, berr := bv.Float64()
+		if aerr != nil || berr != nil {
+			return av == bv
+		}
+		return af == bf
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if other, ok := bv[k]; !ok || !jsonEqual(v, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k := range av {
+			if !jsonEqual(av[k], bv[k]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The JSON Pointer "%s" must start with a slash.`, pointer))
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for k, t := range tokens {
+		tokens[k] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+	return tokens, nil
+}
+
+func errJSONPointerNotFound(path []string) error {
+	return errors.WithStack(herodot.ErrBadRequest.WithReasonf(`The path "/%s" does not exist.`, strings.Join(path, "/")))
+}
+
+func jsonPointerIndex(token string, length int, allowEnd bool) (int, bool) {
+	if allowEnd && token == "-" {
+		return length, true
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, false
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > length || (!allowEnd && i == length) {
+		return 0, false
+	}
+	return i, true
+}
+
+func jsonPointerGet(doc interface{}, path []string) (interface{}, error) {
+	node := doc
+	for k, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, errJSONPointerNotFound(path[:k+1])
+			}
+			node = child
+		case []interface{}:
+			i, ok := jsonPointerIndex(token, len(n), false)
+			if !ok {
+				return nil, errJSONPointerNotFound(path[:k+1])
+			}
+			node = n[i]
+		default:
+			return nil, errJSONPointerNotFound(path[:k+1])
+		}
+	}
+	return node, nil
+}
+
+// jsonPointerSet adds or, if insert is false, replaces the value at the path and returns the updated document.
+func jsonPointerSet(doc interface{}, path []string, value interface{}, insert bool) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	token := path[len(path)-1]
+	switch n := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := n[token]; !ok && !insert {
+			return nil, errJSONPointerNotFound(path)
+		}
+		n[token] = value
+		return doc, nil
+	case []interface{}:
+		i, ok := jsonPointerIndex(token, len(n), insert)
+		if !ok {
+			return nil, errJSONPointerNotFound(path)
+		}
+		if insert {
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+		}
+		n[i] = value
+		return jsonPointerSet(doc, path[:len(path)-1], n, false)
+	}
+
+	return nil, errJSONPointerNotFound(path)
+}
+
+// jsonPointerRemove removes the value at the path and returns the updated document and the removed value.
+func jsonPointerRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The whole document can not be removed."))
+	}
+
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token := path[len(path)-1]
+	switch n := parent.(type) {
+	case map[string]interface{}:
+		v, ok := n[token]
+		if !ok {
+			return nil, nil, errJSONPointerNotFound(path)
+		}
+		delete(n, token)
+		return doc, v, nil
+	case []interface{}:
+		i, ok := jsonPointerIndex(token, len(n), false)
+		if !ok {
+			return nil, nil, errJSONPointerNotFound(path)
+		}
+		v := n[i]
+		n = append(n[:i:i], n[i+1:]...)
+		doc, err := jsonPointerSet(doc, path[:len(path)-1], n, false)
+		return doc, v, err
+	}
+
+	return nil, nil, errJSONPointerNotFound(path)
+}
@@ -0,0 +1,59 @@
+package x
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPatch(t *testing.T) {
+	for k, tc := range []struct {
+		doc      string
+		patch    string
+		expected string
+		err      bool
+	}{
+		{doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":"qux"}]`, expected: `{"baz":"qux","foo":"bar"}`},
+		{doc: `{"foo":["bar","baz"]}`, patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`, expected: `{"foo":["bar","qux","baz"]}`},
+		{doc: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, expected: `{"foo":["bar",["abc","def"]]}`},
+		{doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, expected: `{"foo":"bar"}`},
+		{doc: `{"foo":["bar","qux","baz"]}`, patch: `[{"op":"remove","path":"/foo/1"}]`, expected: `{"foo":["bar","baz"]}`},
+		{doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"replace","path":"/baz","value":"boo"}]`, expected: `{"baz":"boo","foo":"bar"}`},
+		{doc: `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, expected: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{doc: `{"foo":["all","grass","cows","eat"]}`, patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, expected: `{"foo":["all","cows","eat","grass"]}`},
+		{doc: `{"foo":{"bar":[1]}}`, patch: `[{"op":"copy","from":"/foo/bar","path":"/baz"},{"op":"add","path":"/baz/-","value":2}]`, expected: `{"foo":{"bar":[1]},"baz":[1,2]}`},
+		{doc: `{"baz":"qux","foo":["a",2,"c"]}`, patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`, expected: `{"baz":"qux","foo":["a",2,"c"]}`},
+		{doc: `{"a/b":1,"m~n":2}`, patch: `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, expected: `{"a/b":3}`},
+		{doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`, err: true},
+		{doc: `{"foo":"bar"}`, patch: `[{"op":"replace","path":"/baz","value":"qux"}]`, err: true},
+		{doc: `{"foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, err: true},
+		{doc: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/2","value":"qux"}]`, err: true},
+		{doc: `{"foo":["bar"]}`, patch: `[{"op":"remove","path":"/foo/01"}]`, err: true},
+		{doc: `{"foo":{"bar":1}}`, patch: `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`, err: true},
+		{doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"foo","value":"qux"}]`, err: true},
+		{doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/foo"}]`, err: true},
+		{doc: `{"foo":"bar"}`, patch: `[{"op":"unknown","path":"/foo"}]`, err: true},
+	} {
+		var p JSONPatch
+		require.NoError(t, json.Unmarshal([]byte(tc.patch), &p), "%d", k)
+
+		actual, err := p.Apply([]byte(tc.doc))
+		if tc.err {
+			require.Error(t, err, "%d: %s", k, actual)
+			continue
+		}
+		require.NoError(t, err, "%d", k)
+		assert.JSONEq(t, tc.expected, string(actual), "%d", k)
+	}
+
+	t.Run("case=failed tests are conflicts", func(t *testing.T) {
+		var p JSONPatch
+		require.NoError(t, json.Unmarshal([]byte(`[{"op":"replace","path":"/foo","value":"qux"},{"op":"test","path":"/foo","value":"bar"}]`), &p))
+		_, err := p.Apply([]byte(`{"foo":"bar"}`))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrJSONPatchTestFailed), "%+v", err)
+	})
+}
@@ -0,0 +1,75 @@
+package x
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// JSONArrayStream writes a JSON array to a response one element at a time instead of encoding a slice at once,
+// which keeps the memory used by large lists bounded. The response uses chunked transfer encoding. Writes block
+// while the client does not read, so a caller loading the elements in batches only loads the next batch once
+// the client caught up.
+type JSONArrayStream struct {
+	w       http.ResponseWriter
+	e       *json.Encoder
+	written int
+}
+
+func NewJSONArrayStream(w http.ResponseWriter) *JSONArrayStream {
+	return &JSONArrayStream{w: w, e: json.NewEncoder(w)}
+}
+
+// Started returns true once the status code and the start of the array were written. Errors can only be written
+// as a regular error response before that.
+func (s *JSONArrayStream) Started() bool {
+	return s.written > 0
+}
+
+// Written returns the number of elements written.
+func (s *JSONArrayStream) Written() int {
+	return s.written
+}
+
+// Write appends an element to the array. The response header is written with the first element.
+func (s *JSONArrayStream) Write(v interface{}) error {
+	sep := ","
+	if s.written == 0 {
+		s.w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		s.w.WriteHeader(http.StatusOK)
+		sep = "["
+	}
+	s.written++
+
+	if _, err := s.w.Write([]byte(sep)); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(s.e.Encode(v))
+}
+
+// Flush sends the elements written so far to the client.
+func (s *JSONArrayStream) Flush() {
+	if f, ok := s.w.(http.Flusher); ok && s.written > 0 {
+		f.Flush()
+	}
+}
+
+// Close ends the array. If no element was written, an empty array is written.
+func (s *JSONArrayStream) Close() error {
+	if s.written == 0 {
+		s.w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		s.w.WriteHeader(http.StatusOK)
+		_, err := s.w.Write([]byte("[]\n"))
+		return errors.WithStack(err)
+	}
+
+	_, err := s.w.Write([]byte("]\n"))
+	return errors.WithStack(err)
+}
+
+// Abort ends the response without completing the array, so that clients notice the list is incomplete instead
+// of parsing a truncated but valid array. It must only be called after the stream started.
+func (s *JSONArrayStream) Abort() {
+	panic(http.ErrAbortHandler)
+}
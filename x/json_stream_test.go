@@ -0,0 +1,45 @@
+package x_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/x"
+)
+
+func TestJSONArrayStream(t *testing.T) {
+	t.Run("case=writes an empty array", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		s := x.NewJSONArrayStream(rec)
+		require.NoError(t, s.Close())
+		assert.False(t, s.Started())
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.JSONEq(t, `[]`, rec.Body.String())
+	})
+
+	t.Run("case=writes the elements", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		s := x.NewJSONArrayStream(rec)
+		require.NoError(t, s.Write(map[string]int{"a": 1}))
+		assert.True(t, s.Started())
+		s.Flush()
+		assert.True(t, rec.Flushed)
+		require.NoError(t, s.Write("<b>"))
+		require.NoError(t, s.Close())
+
+		assert.Equal(t, 2, s.Written())
+		assert.JSONEq(t, `[{"a":1},"<b>"]`, rec.Body.String())
+	})
+
+	t.Run("case=aborts the response", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		s := x.NewJSONArrayStream(rec)
+		require.NoError(t, s.Write(1))
+		assert.PanicsWithValue(t, http.ErrAbortHandler, s.Abort)
+	})
+}
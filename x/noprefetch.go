@@ -0,0 +1,56 @@
+package x
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// linkPreviewAgents are user agent tokens of bots which fetch links posted in chats and social networks to render a
+// preview.
+var linkPreviewAgents = []string{
+	"slackbot-linkexpanding",
+	"facebookexternalhit",
+	"twitterbot",
+	"linkedinbot",
+	"discordbot",
+	"telegrambot",
+	"whatsapp",
+	"skypeuripreview",
+	"microsoftpreview",
+	"google-pagerenderer",
+}
+
+// IsPrefetchRequest returns true if the request was sent by a browser prefetching or prerendering the page or by a
+// bot rendering a link preview instead of a user navigating to the page.
+func IsPrefetchRequest(r *http.Request) bool {
+	for _, h := range []string{"Sec-Purpose", "Purpose", "X-Purpose", "X-Moz"} {
+		v := strings.ToLower(r.Header.Get(h))
+		if strings.Contains(v, "prefetch") || strings.Contains(v, "prerender") || strings.Contains(v, "preview") {
+			return true
+		}
+	}
+
+	ua := strings.ToLower(r.UserAgent())
+	for _, a := range linkPreviewAgents {
+		if strings.Contains(ua, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// NoPrefetchHandler wraps httprouter.Handle and answers prefetch requests with `503 Service Unavailable` without
+// calling the handler. Browsers discard failed prefetches and request the page again once the user navigates to it.
+func NoPrefetchHandler(handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if IsPrefetchRequest(r) {
+			NoCache(w)
+			w.Header().Add("Vary", "Sec-Purpose, Purpose, User-Agent")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		handle(w, r, ps)
+	}
+}
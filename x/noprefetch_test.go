@@ -0,0 +1,44 @@
+package x
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoPrefetchHandler(t *testing.T) {
+	h := NoPrefetchHandler(func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusFound)
+	})
+
+	for k, tc := range []struct {
+		header   http.Header
+		expected int
+	}{
+		{header: http.Header{}, expected: http.StatusFound},
+		{header: http.Header{"User-Agent": {"Mozilla/5.0 (X11; Linux x86_64) Chrome/87.0"}}, expected: http.StatusFound},
+		{header: http.Header{"Sec-Purpose": {"prefetch"}}, expected: http.StatusServiceUnavailable},
+		{header: http.Header{"Sec-Purpose": {"prefetch;prerender"}}, expected: http.StatusServiceUnavailable},
+		{header: http.Header{"Purpose": {"prefetch"}}, expected: http.StatusServiceUnavailable},
+		{header: http.Header{"X-Purpose": {"preview"}}, expected: http.StatusServiceUnavailable},
+		{header: http.Header{"X-Moz": {"prefetch"}}, expected: http.StatusServiceUnavailable},
+		{header: http.Header{"User-Agent": {"Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"}}, expected: http.StatusServiceUnavailable},
+		{header: http.Header{"User-Agent": {"facebookexternalhit/1.1"}}, expected: http.StatusServiceUnavailable},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header = tc.header
+
+			h(w, r, nil)
+			assert.Equal(t, tc.expected, w.Code)
+			if tc.expected == http.StatusServiceUnavailable {
+				assert.Contains(t, w.Header().Get("Cache-Control"), "no-store")
+			}
+		})
+	}
+}
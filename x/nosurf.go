@@ -63,6 +63,12 @@ func (f *FakeCSRFHandler) ExemptPath(s string) {
 func (f *FakeCSRFHandler) IgnorePath(s string) {
 }
 
+func (f *FakeCSRFHandler) ExemptGlob(s string) {
+}
+
+func (f *FakeCSRFHandler) ExemptFunc(fn func(r *http.Request) bool) {
+}
+
 func (f *FakeCSRFHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
@@ -78,6 +84,9 @@ type CSRFHandler interface {
 	http.Handler
 	RegenerateToken(w http.ResponseWriter, r *http.Request) string
 	ExemptPath(string)
+	ExemptGlob(string)
+	// ExemptFunc exempts the requests for which fn returns true. Only one function can be set.
+	ExemptFunc(fn func(r *http.Request) bool)
 	IgnorePath(string)
 }
 
@@ -88,12 +97,16 @@ func NewCSRFHandler(
 	path string,
 	domain string,
 	secure bool,
+	sameSite http.SameSite,
 ) *nosurf.CSRFHandler {
 	n := nosurf.New(router)
 
-	samesiteattribute := http.SameSiteNoneMode
-	if !secure {
-		samesiteattribute = http.SameSiteLaxMode
+	samesiteattribute := sameSite
+	if samesiteattribute == 0 {
+		samesiteattribute = http.SameSiteNoneMode
+		if !secure {
+			samesiteattribute = http.SameSiteLaxMode
+		}
 	}
 
 	n.SetBaseCookie(http.Cookie{
@@ -122,7 +135,7 @@ func NewTestCSRFHandler(router http.Handler, reg interface {
 	WriterProvider
 	LoggingProvider
 }) *nosurf.CSRFHandler {
-	n := NewCSRFHandler(router, reg.Writer(), reg.Logger(), "/", "", false)
+	n := NewCSRFHandler(router, reg.Writer(), reg.Logger(), "/", "", false, 0)
 	reg.WithCSRFHandler(n)
 	reg.WithCSRFTokenGenerator(nosurf.Token)
 	return n
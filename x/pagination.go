@@ -111,3 +111,47 @@ func PaginationHeader(w http.ResponseWriter, u *url.URL, total int64, page, item
 		header(u, "last", itemsPerPage64, lastOffset),
 	}, ","))
 }
+
+// ParseKeysetPagination parses the page token and page size of keyset pagination from *http.Request. It returns
+// false if neither `page_token` nor `page_size` is set, in which case offset pagination should be used.
+func ParseKeysetPagination(r *http.Request) (pageToken string, pageSize int, ok bool) {
+	query := r.URL.Query()
+	_, hasToken := query["page_token"]
+	_, hasSize := query["page_size"]
+	if !hasToken && !hasSize {
+		return "", 0, false
+	}
+
+	pageSize = paginationDefaultItems
+	if size, err := strconv.ParseInt(query.Get("page_size"), 10, 64); err == nil {
+		pageSize = int(size)
+	}
+
+	if pageSize > paginationMaxItems {
+		pageSize = paginationMaxItems
+	}
+
+	if pageSize < 1 {
+		pageSize = 1
+	}
+
+	return query.Get("page_token"), pageSize, true
+}
+
+// KeysetPaginationHeader sets the Link header of a page listed using keyset pagination. The next link is only set
+// if nextPageToken is not empty.
+func KeysetPaginationHeader(w http.ResponseWriter, u *url.URL, pageSize int, nextPageToken string) {
+	link := func(rel, pageToken string) string {
+		q := u.Query()
+		q.Set("page_size", fmt.Sprintf("%d", pageSize))
+		q.Set("page_token", pageToken)
+		u.RawQuery = q.Encode()
+		return fmt.Sprintf("<%s>; rel=\"%s\"", u.String(), rel)
+	}
+
+	links := []string{link("first", "")}
+	if nextPageToken != "" {
+		links = append(links, link("next", nextPageToken))
+	}
+	w.Header().Set("Link", strings.Join(links, ","))
+}
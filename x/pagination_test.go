@@ -131,3 +131,48 @@ func TestParsePagination(t *testing.T) {
 		})
 	}
 }
+
+func TestParseKeysetPagination(t *testing.T) {
+	for _, tc := range []struct {
+		d                 string
+		url               string
+		expectedPageToken string
+		expectedPageSize  int
+		expectedKeyset    bool
+	}{
+		{"offset", "http://localhost/foo?per_page=10&page=10", "", 0, false},
+		{"first_page", "http://localhost/foo?page_size=10", "", 10, true},
+		{"next_page", "http://localhost/foo?page_size=10&page_token=abc", "abc", 10, true},
+		{"defaults", "http://localhost/foo?page_token=", "", paginationDefaultItems, true},
+		{"limits", "http://localhost/foo?page_size=2000", "", paginationMaxItems, true},
+		{"negatives", "http://localhost/foo?page_size=-1", "", 1, true},
+		{"invalid_params", "http://localhost/foo?page_size=a", "", paginationDefaultItems, true},
+	} {
+		t.Run(fmt.Sprintf("case=%s", tc.d), func(t *testing.T) {
+			u, _ := url.Parse(tc.url)
+			pageToken, pageSize, keyset := ParseKeysetPagination(&http.Request{URL: u})
+			assert.Equal(t, tc.expectedKeyset, keyset)
+			assert.Equal(t, tc.expectedPageToken, pageToken)
+			assert.Equal(t, tc.expectedPageSize, pageSize)
+		})
+	}
+}
+
+func TestKeysetPaginationHeader(t *testing.T) {
+	t.Run("case=with next page", func(t *testing.T) {
+		r := httptest.NewRecorder()
+		KeysetPaginationHeader(r, urlx.ParseOrPanic("http://example.com?traits.foo=bar"), 50, "next-token")
+
+		assert.EqualValues(t, strings.Join([]string{
+			"<http://example.com?page_size=50&page_token=&traits.foo=bar>; rel=\"first\"",
+			"<http://example.com?page_size=50&page_token=next-token&traits.foo=bar>; rel=\"next\"",
+		}, ","), r.Result().Header.Get("Link"))
+	})
+
+	t.Run("case=last page", func(t *testing.T) {
+		r := httptest.NewRecorder()
+		KeysetPaginationHeader(r, urlx.ParseOrPanic("http://example.com"), 50, "")
+
+		assert.EqualValues(t, "<http://example.com?page_size=50&page_token=>; rel=\"first\"", r.Result().Header.Get("Link"))
+	})
+}
@@ -0,0 +1,116 @@
+package x
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/logrusx"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+// ProblemJSONContentType is the content type of RFC 7807 problem details.
+const ProblemJSONContentType = "application/problem+json"
+
+type (
+	// ProblemJSONWriter writes responses like herodot.JSONWriter but writes errors as RFC 7807 problem details if
+	// `serve.errors.problem_json.enabled` is set.
+	ProblemJSONWriter struct {
+		*herodot.JSONWriter
+		l *logrusx.Logger
+		c *config.Provider
+	}
+
+	// problemJSON are the problem details of an error as defined in RFC 7807.
+	problemJSON struct {
+		// Type identifies the kind of error. It is the type base URL with the error code as the fragment.
+		Type string `json:"type"`
+
+		// Title is the HTTP status text of the error, for example "Not Found".
+		Title string `json:"title"`
+
+		// Status is the HTTP status code of the response.
+		Status int `json:"status"`
+
+		// Detail explains the error in more detail.
+		Detail string `json:"detail,omitempty"`
+
+		// Code is the stable error code, for example `not_found`.
+		Code string `json:"code"`
+
+		// RequestID is the ID of the request which failed, if known.
+		RequestID string `json:"request_id,omitempty"`
+
+		// Details contains further information on the error.
+		Details map[string]interface{} `json:"details,omitempty"`
+
+		// Debug contains debug information which is only set in development environments.
+		Debug string `json:"debug,omitempty"`
+	}
+)
+
+func NewProblemJSONWriter(l *logrusx.Logger, c *config.Provider) *ProblemJSONWriter {
+	return &ProblemJSONWriter{JSONWriter: herodot.NewJSONWriter(l), l: l, c: c}
+}
+
+// WriteError writes the error using the status code carried by the error, or 500 if it carries none.
+func (h *ProblemJSONWriter) WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if !h.c.ErrorsProblemJSONEnabled() {
+		h.JSONWriter.WriteError(w, r, err)
+		return
+	}
+
+	h.WriteErrorCode(w, r, herodot.ToDefaultError(err, "").StatusCode(), err)
+}
+
+// WriteErrorCode writes the error using the given status code.
+func (h *ProblemJSONWriter) WriteErrorCode(w http.ResponseWriter, r *http.Request, code int, err error) {
+	if !h.c.ErrorsProblemJSONEnabled() {
+		h.JSONWriter.WriteErrorCode(w, r, code, err)
+		return
+	}
+
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+
+	h.Reporter(h.l, "An error occurred while handling a request")(w, r, code, err)
+
+	e := herodot.ToDefaultError(err, r.Header.Get("X-Request-ID"))
+	problemCode := ProblemCode(code)
+	typeURL := *h.c.ErrorsProblemJSONTypeBaseURL()
+	typeURL.Fragment = problemCode
+
+	detail := e.Reason()
+	if detail == "" {
+		detail = e.Error()
+	}
+
+	w.Header().Set("Content-Type", ProblemJSONContentType)
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(&problemJSON{
+		Type:      typeURL.String(),
+		Title:     http.StatusText(code),
+		Status:    code,
+		Detail:    detail,
+		Code:      problemCode,
+		RequestID: e.RequestID(),
+		Details:   e.Details(),
+		Debug:     e.Debug(),
+	}); err != nil {
+		h.Reporter(h.l, "Could not write problem details to response writer")(w, r, code, errors.WithStack(err))
+	}
+}
+
+// ProblemCode returns the stable error code of the HTTP status code, for example `not_found` for 404.
+func ProblemCode(code int) string {
+	text := http.StatusText(code)
+	if text == "" {
+		return "unknown_error"
+	}
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
@@ -0,0 +1,99 @@
+package x_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/x"
+)
+
+func TestProblemJSONWriter(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	write := func(t *testing.T, write func(w http.ResponseWriter, r *http.Request)) (*http.Response, gjson.Result) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Request-ID", "request-id")
+		write(rec, req)
+		res := rec.Result()
+		raw, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, gjson.ParseBytes(raw)
+	}
+
+	notFound := errors.WithStack(herodot.ErrNotFound.WithReason("The session does not exist.").WithDetail("id", "foo"))
+
+	t.Run("case=writes the default format if disabled", func(t *testing.T) {
+		res, body := write(t, func(w http.ResponseWriter, r *http.Request) {
+			reg.Writer().WriteError(w, r, notFound)
+		})
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		assert.EqualValues(t, http.StatusNotFound, body.Get("error.code").Int(), "%s", body.Raw)
+	})
+
+	t.Run("case=writes problem details if enabled", func(t *testing.T) {
+		conf.MustSet(config.ViperKeyErrorsProblemJSONEnabled, true)
+		t.Cleanup(func() {
+			conf.MustSet(config.ViperKeyErrorsProblemJSONEnabled, false)
+		})
+
+		res, body := write(t, func(w http.ResponseWriter, r *http.Request) {
+			reg.Writer().WriteError(w, r, notFound)
+		})
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, x.ProblemJSONContentType, res.Header.Get("Content-Type"))
+		assert.Equal(t, "https://www.ory.sh/kratos/docs/reference/errors#not_found", body.Get("type").String(), "%s", body.Raw)
+		assert.Equal(t, "Not Found", body.Get("title").String(), "%s", body.Raw)
+		assert.EqualValues(t, http.StatusNotFound, body.Get("status").Int(), "%s", body.Raw)
+		assert.Equal(t, "The session does not exist.", body.Get("detail").String(), "%s", body.Raw)
+		assert.Equal(t, "not_found", body.Get("code").String(), "%s", body.Raw)
+		assert.Equal(t, "request-id", body.Get("request_id").String(), "%s", body.Raw)
+		assert.Equal(t, "foo", body.Get("details.id").String(), "%s", body.Raw)
+
+		t.Run("case=uses the given status code and type base URL", func(t *testing.T) {
+			conf.MustSet(config.ViperKeyErrorsProblemJSONTypeBaseURL, "https://errors.example.org/kratos")
+
+			res, body := write(t, func(w http.ResponseWriter, r *http.Request) {
+				reg.Writer().WriteErrorCode(w, r, http.StatusTooManyRequests, errors.New("slow down"))
+			})
+			assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+			assert.Equal(t, "https://errors.example.org/kratos#too_many_requests", body.Get("type").String(), "%s", body.Raw)
+			assert.EqualValues(t, http.StatusTooManyRequests, body.Get("status").Int(), "%s", body.Raw)
+			assert.Equal(t, "slow down", body.Get("detail").String(), "%s", body.Raw)
+		})
+
+		t.Run("case=does not change successful responses", func(t *testing.T) {
+			res, body := write(t, func(w http.ResponseWriter, r *http.Request) {
+				reg.Writer().Write(w, r, map[string]string{"foo": "bar"})
+			})
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Contains(t, res.Header.Get("Content-Type"), "application/json")
+			assert.Equal(t, "bar", body.Get("foo").String(), "%s", body.Raw)
+		})
+	})
+}
+
+func TestProblemCode(t *testing.T) {
+	for code, expected := range map[int]string{
+		http.StatusBadRequest:          "bad_request",
+		http.StatusUnauthorized:        "unauthorized",
+		http.StatusRequestURITooLong:   "request_uri_too_long",
+		http.StatusTeapot:              "im_a_teapot",
+		http.StatusInternalServerError: "internal_server_error",
+		999:                            "unknown_error",
+	} {
+		assert.Equal(t, expected, x.ProblemCode(code))
+	}
+}
@@ -1,16 +1,82 @@
 package x
 
 import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	db "github.com/gofrs/uuid"
 	"github.com/google/uuid"
 )
 
 var EmptyUUID db.UUID
 
+var useUUIDv7 int32
+
+// UseUUIDv7 toggles whether NewUUID returns time-ordered version 7 UUIDs instead of random version 4 UUIDs.
+//
+// Both versions share the same format, which is why existing version 4 IDs remain valid.
+func UseUUIDv7(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&useUUIDv7, v)
+}
+
 func NewUUID() db.UUID {
+	if atomic.LoadInt32(&useUUIDv7) == 1 {
+		return NewUUIDv7()
+	}
 	return db.UUID(uuid.New())
 }
 
+var uuidv7 = struct {
+	sync.Mutex
+	ms  int64
+	seq uint16
+}{}
+
+// NewUUIDv7 returns a version 7 UUID as defined in RFC 9562. The first 48 bits contain the Unix timestamp in
+// milliseconds, followed by a 12 bit counter which keeps UUIDs generated within the same millisecond ordered,
+// and 62 random bits.
+func NewUUIDv7() db.UUID {
+	var id db.UUID
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+
+	uuidv7.Lock()
+	ms := time.Now().UnixNano() / int64(time.Millisecond)
+	if ms > uuidv7.ms {
+		uuidv7.ms = ms
+		// Start with a random counter below 2048 so there is room left for incrementing it.
+		uuidv7.seq = binary.BigEndian.Uint16(id[6:8]) & 0x07ff
+	} else {
+		uuidv7.seq++
+		if uuidv7.seq > 0x0fff {
+			// The counter overflowed, borrow the next millisecond to stay monotonic.
+			uuidv7.ms++
+			uuidv7.seq = 0
+		}
+	}
+	ms, seq := uuidv7.ms, uuidv7.seq
+	uuidv7.Unlock()
+
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	binary.BigEndian.PutUint16(id[6:8], 0x7000|seq)
+	id[8] = (id[8] & 0x3f) | 0x80
+
+	return id
+}
+
 func ParseUUID(in string) db.UUID {
 	id, _ := uuid.Parse(in)
 	return db.UUID(id)
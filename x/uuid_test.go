@@ -1,9 +1,12 @@
 package x
 
 import (
+	"bytes"
 	"testing"
 
+	db "github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUUID(t *testing.T) {
@@ -12,3 +15,30 @@ func TestUUID(t *testing.T) {
 	assert.True(t, IsZeroUUID(ParseUUID("asfdt4ifgdsl")))
 	assert.False(t, IsZeroUUID(NewUUID()))
 }
+
+func TestUUIDv7(t *testing.T) {
+	t.Run("case=has version and variant", func(t *testing.T) {
+		id := NewUUIDv7()
+		assert.EqualValues(t, 7, id.Version())
+		assert.EqualValues(t, db.VariantRFC4122, id.Variant())
+		assert.Equal(t, id, ParseUUID(id.String()))
+	})
+
+	t.Run("case=is ordered", func(t *testing.T) {
+		prev := NewUUIDv7()
+		for i := 0; i < 10000; i++ {
+			next := NewUUIDv7()
+			require.True(t, bytes.Compare(prev[:], next[:]) < 0, "%s must sort before %s", prev, next)
+			prev = next
+		}
+	})
+
+	t.Run("case=is used by NewUUID when enabled", func(t *testing.T) {
+		UseUUIDv7(true)
+		defer UseUUIDv7(false)
+		assert.EqualValues(t, 7, NewUUID().Version())
+
+		UseUUIDv7(false)
+		assert.EqualValues(t, 4, NewUUID().Version())
+	})
+}